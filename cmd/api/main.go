@@ -3,16 +3,35 @@ package main
 import (
 	"context"
 	"doctors/config"
+	"doctors/internal/cli"
+	"doctors/internal/delivery/event"
 	"doctors/internal/delivery/http"
+	"doctors/internal/domain"
 	"doctors/internal/infrastracture/database"
 	"doctors/internal/infrastracture/messaging"
 	"doctors/internal/repository"
 	"doctors/internal/usecase"
+	"doctors/pkg/clearinghouse"
 	"doctors/pkg/email"
+	"doctors/pkg/encryption"
+	"doctors/pkg/gateway"
+	"doctors/pkg/lab"
+	"doctors/pkg/mllp"
+	"doctors/pkg/ratelimit"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
 )
 
+// eventTopic is the Kafka topic the API consumes commands from and
+// publishes outbox messages to.
+const eventTopic = "doctor_saas_topic"
+
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -26,33 +45,350 @@ func main() {
 		log.Fatalf("Failed to setup database: %v", err)
 	}
 
-	kafkaClient, err := messaging.NewKafkaClient(cfg.KafkaBrokers, "doctor_saas_topic", cfg.KafkaGroupID)
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := cli.Migrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := migrator.EnsureUpToDate(context.Background()); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+
+	kafkaClient, err := messaging.NewKafkaClient(cfg.KafkaBrokers, eventTopic, cfg.KafkaGroupID)
 	if err != nil {
 		log.Fatalf("Failed to create Kafka client: %v", err)
 	}
 	defer kafkaClient.Close()
 
-	patientRepo := repository.NewPatientRepository(db)
+	cipher, err := encryption.NewLocalEnvelope(cfg.EncryptionMasterKeys, cfg.EncryptionActiveKeyID, cfg.EncryptionIndexKey)
+	if err != nil {
+		log.Fatalf("Failed to configure encryption: %v", err)
+	}
+
+	patientRepo := repository.NewPatientRepository(db, cipher)
 	appointmentRepo := repository.NewAppointmentRepository(db)
 	doctorRepo := repository.NewDoctorRepository(db)
+	mergeRepo := repository.NewPatientMergeRepository(db)
+	relationshipRepo := repository.NewRelationshipRepository(db)
+	patientImportRepo := repository.NewPatientImportRepository(db, cipher)
+	insuranceRepo := repository.NewInsuranceRepository(db, cipher)
+	encounterRepo := repository.NewEncounterRepository(db, cipher)
+	vitalRepo := repository.NewVitalRepository(db)
+	codeRepo := repository.NewCodeRepository(db)
+	allergyRepo := repository.NewAllergyRepository(db)
+	medicationRepo := repository.NewMedicationRepository(db)
+	problemRepo := repository.NewProblemRepository(db)
+	drugRepo := repository.NewDrugRepository(db)
+	prescriptionRepo := repository.NewPrescriptionRepository(db)
+	labRepo := repository.NewLabRepository(db, cipher)
+	codingRepo := repository.NewCodingRepository(db)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	paymentIntentRepo := repository.NewPaymentIntentRepository(db)
+	claimRepo := repository.NewClaimRepository(db, cipher)
+	transactor := repository.NewTransactor(db)
+	bookingHorizon := time.Duration(cfg.BookingHorizonDays) * 24 * time.Hour
+
+	retention := time.Duration(cfg.ArchiveRetentionDays) * 24 * time.Hour
+
+	userRepo := repository.NewUserRepository(db)
+	userUseCase := usecase.NewUserUseCase(userRepo, cfg.AdminAPIKey)
+	if err := usecase.ValidateMRNFormat(cfg.MRNFormat); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := usecase.ValidateInvoiceNumberFormat(cfg.InvoiceNumberFormat); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	patientUseCase := usecase.NewPatientUseCase(transactor, patientRepo, appointmentRepo, mergeRepo, relationshipRepo, patientImportRepo,
+		cfg.PatientDeletePolicy, cfg.PatientDuplicatePolicy, cfg.MRNFormat, insuranceRepo, encounterRepo, vitalRepo,
+		allergyRepo, medicationRepo, problemRepo, prescriptionRepo, labRepo, invoiceRepo, paymentIntentRepo, claimRepo)
+	billingUseCase := usecase.NewBillingUseCase(transactor, invoiceRepo, repository.NewFeeRepository(db), patientRepo, appointmentRepo,
+		paymentIntentRepo, usecase.BillingSettings{
+			Currency:     cfg.BillingCurrency,
+			NumberFormat: cfg.InvoiceNumberFormat,
+			DueDays:      cfg.InvoiceDueDays,
+			ProviderName: cfg.ProviderName,
+			ProviderNPI:  cfg.ProviderNPI,
+		})
+	appointmentUseCase := usecase.NewAppointmentUseCase(transactor, appointmentRepo, patientRepo, doctorRepo, relationshipRepo,
+		emailSender, billingUseCase, bookingHorizon)
+	relationshipUseCase := usecase.NewRelationshipUseCase(transactor, relationshipRepo, patientRepo)
+	portalUseCase := usecase.NewPortalUseCase(patientRepo, appointmentRepo, relationshipRepo, appointmentUseCase)
+	clearinghouseClient, err := clearinghouse.New(cfg.ClearinghouseClient, cfg.ClearinghouseDir)
+	if err != nil {
+		log.Fatalf("Failed to configure clearinghouse: %v", err)
+	}
+	insuranceUseCase := usecase.NewInsuranceUseCase(transactor, insuranceRepo, patientRepo, appointmentRepo,
+		clearinghouseClient, usecase.EligibilitySettings{
+			SenderID:     cfg.X12SenderID,
+			ReceiverID:   cfg.X12ReceiverID,
+			ProviderName: cfg.ProviderName,
+			ProviderNPI:  cfg.ProviderNPI,
+			Production:   cfg.X12Production,
+		})
+	encounterUseCase := usecase.NewEncounterUseCase(encounterRepo, appointmentRepo, patientRepo)
+	codeCatalogUseCase := usecase.NewCodeCatalogUseCase(transactor, codeRepo)
+	codingUseCase := usecase.NewCodingUseCase(transactor, codingRepo, codeRepo, appointmentRepo, encounterRepo)
+	vitalUseCase := usecase.NewVitalUseCase(vitalRepo, patientRepo, appointmentRepo)
+	historyUseCase := usecase.NewClinicalHistoryUseCase(allergyRepo, medicationRepo, problemRepo, patientRepo, encounterRepo, codeRepo)
+	drugCatalogUseCase := usecase.NewDrugCatalogUseCase(transactor, drugRepo)
+	prescriptionUseCase := usecase.NewPrescriptionUseCase(transactor, prescriptionRepo, drugRepo, encounterRepo, patientRepo, doctorRepo,
+		allergyRepo, medicationRepo, usecase.PrescriptionSettings{ProviderName: cfg.ProviderName, ProviderNPI: cfg.ProviderNPI})
+	labClient, err := lab.New(cfg.LabClient, cfg.LabAddr, cfg.LabResultsAddr)
+	if err != nil {
+		log.Fatalf("Failed to configure laboratory: %v", err)
+	}
+	labUseCase := usecase.NewLabUseCase(transactor, labRepo, appointmentRepo, patientRepo, doctorRepo, labClient, emailSender,
+		usecase.LabSettings{
+			Application:    cfg.HL7Application,
+			Facility:       cfg.HL7Facility,
+			LabApplication: cfg.LabApplication,
+			LabFacility:    cfg.LabFacility,
+			Production:     cfg.HL7Production,
+		})
+	doctorUseCase := usecase.NewDoctorUseCase(doctorRepo)
+	signingKey, err := bulkExportSigningKey(cfg.BulkExportSigningKey)
+	if err != nil {
+		log.Fatalf("Failed to configure bulk export: %v", err)
+	}
+	bulkExportUseCase := usecase.NewBulkExportUseCase(repository.NewBulkExportRepository(db), patientRepo, appointmentRepo,
+		doctorRepo, usecase.BulkExportSettings{
+			Dir:        cfg.BulkExportDir,
+			Retention:  time.Duration(cfg.BulkExportRetentionHours) * time.Hour,
+			LinkTTL:    time.Duration(cfg.BulkExportLinkMinutes) * time.Minute,
+			SigningKey: signingKey,
+		})
+	fakeWebhookURL := cfg.PaymentFakeWebhookURL
+	if fakeWebhookURL == "" {
+		fakeWebhookURL = fmt.Sprintf("http://127.0.0.1:%d/api/v1/payments/webhook", cfg.ServerPort)
+	}
+	paymentProvider, err := gateway.New(cfg.PaymentProvider, cfg.PaymentAPIURL, cfg.PaymentAPIKey, cfg.PaymentWebhookSecret, fakeWebhookURL)
+	if err != nil {
+		log.Fatalf("Failed to configure payments: %v", err)
+	}
+	paymentUseCase := usecase.NewPaymentUseCase(transactor, paymentIntentRepo, invoiceRepo, appointmentRepo, billingUseCase,
+		paymentProvider, usecase.PaymentSettings{Currency: cfg.BillingCurrency, DepositCents: cfg.DepositCents})
+	claimUseCase := usecase.NewClaimUseCase(transactor, claimRepo, invoiceRepo, appointmentRepo, patientRepo, insuranceRepo,
+		codingRepo, billingUseCase, usecase.ClaimSettings{
+			SenderID:      cfg.X12SenderID,
+			ReceiverID:    cfg.X12ReceiverID,
+			Production:    cfg.X12Production,
+			ProviderName:  cfg.ProviderName,
+			ProviderNPI:   cfg.ProviderNPI,
+			ProviderTaxID: cfg.ProviderTaxID,
+			ProviderAddress: domain.Address{
+				Line1:      cfg.ProviderAddress,
+				City:       cfg.ProviderCity,
+				Region:     cfg.ProviderState,
+				PostalCode: cfg.ProviderPostalCode,
+			},
+			ProviderPhone:   cfg.ProviderPhone,
+			PlaceOfService:  cfg.ClaimPlaceOfService,
+			FilingIndicator: cfg.ClaimFilingIndicator,
+		})
+	retentionUseCase := usecase.NewRetentionUseCase(patientRepo, appointmentRepo, retention)
+	outboxUseCase := usecase.NewOutboxUseCase(transactor, repository.NewOutboxRepository(db, cipher))
 
-	patientUseCase := usecase.NewPatientUseCase(patientRepo)
-	appointmentUseCase := usecase.NewAppointmentUseCase(appointmentRepo, patientRepo, doctorRepo, emailSender)
+	limiter, err := newRateLimiter(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to configure rate limiting: %v", err)
+	}
 
-	router := http.NewRouter(patientUseCase, appointmentUseCase)
+	router := http.NewRouter(patientUseCase, appointmentUseCase, relationshipUseCase, portalUseCase, insuranceUseCase, encounterUseCase,
+		codeCatalogUseCase, codingUseCase, vitalUseCase, historyUseCase, drugCatalogUseCase, prescriptionUseCase, labUseCase,
+		doctorUseCase, bulkExportUseCase, billingUseCase, paymentUseCase, claimUseCase, userUseCase,
+		limiter)
 
 	go func() {
+		eventHandler := event.NewHandler(eventTopic, patientUseCase, appointmentUseCase, outboxUseCase)
 		err := kafkaClient.ConsumeMessages(context.Background(), func(msg []byte) error {
-			log.Printf("Received message: %s", string(msg))
-			return nil
+			return eventHandler.Handle(context.Background(), msg)
 		})
 		if err != nil {
 			log.Printf("Error consuming Kafka messages: %v", err)
 		}
 	}()
+
+	// Publish outbox messages, such as replayed dead letters
+	go runPeriodically(context.Background(), 5*time.Second, func(ctx context.Context) {
+		published, err := outboxUseCase.PublishPending(ctx, func(ctx context.Context, message *domain.OutboxMessage) error {
+			if message.Topic != eventTopic {
+				return fmt.Errorf("no producer for topic %q", message.Topic)
+			}
+			var key []byte
+			if message.Key != "" {
+				key = []byte(message.Key)
+			}
+			return kafkaClient.ProduceMessage(ctx, key, []byte(message.Payload))
+		})
+		if err != nil {
+			log.Printf("Failed to publish outbox messages: %v", err)
+			return
+		}
+		if published > 0 {
+			log.Printf("Published %d outbox messages", published)
+		}
+	})
+
+	// Receive laboratory results over MLLP
+	go func() {
+		log.Printf("Lab results listener starting on %s", cfg.LabResultsAddr)
+		if err := mllp.ListenAndServe(context.Background(), cfg.LabResultsAddr, labUseCase.ReceiveMessage); err != nil {
+			log.Printf("Lab results listener stopped: %v", err)
+		}
+	}()
+
+	// Purge archived records past the retention period once a day across all servers
+	go runPeriodically(context.Background(), time.Hour, func(ctx context.Context) {
+		var patients, appointments int64
+		day := time.Now().Format("2006-01-02")
+		ran, err := database.RunDaily(ctx, db, database.RetentionLockID, database.RetentionJob, day, func(ctx context.Context) error {
+			var err error
+			patients, appointments, err = retentionUseCase.PurgeArchived(ctx)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to purge archived records: %v", err)
+			return
+		}
+		if !ran {
+			return
+		}
+		log.Printf("Purged %d archived patients and %d archived appointments", patients, appointments)
+	})
+
+	// Check tomorrow's patients' insurance once a day across all servers
+	go runPeriodically(context.Background(), time.Hour, func(ctx context.Context) {
+		var checked int
+		now := time.Now()
+		ran, err := database.RunDaily(ctx, db, database.EligibilityLockID, database.EligibilityJob, now.Format("2006-01-02"),
+			func(ctx context.Context) error {
+				var err error
+				checked, err = insuranceUseCase.CheckEligibilityForDate(ctx, now.AddDate(0, 0, 1))
+				return err
+			})
+		if err != nil {
+			log.Printf("Failed to check eligibility: %v", err)
+			return
+		}
+		if !ran {
+			return
+		}
+		log.Printf("Checked insurance eligibility for %d patients", checked)
+	})
+
+	// Run requested bulk exports
+	go runPeriodically(context.Background(), 5*time.Second, func(ctx context.Context) {
+		ran, err := bulkExportUseCase.RunPending(ctx)
+		if err != nil {
+			log.Printf("Failed to run bulk exports: %v", err)
+			return
+		}
+		if ran > 0 {
+			log.Printf("Ran %d bulk exports", ran)
+		}
+	})
+
+	// Run queued patient imports
+	go runPeriodically(context.Background(), 5*time.Second, func(ctx context.Context) {
+		ran, err := patientUseCase.RunPendingImports(ctx)
+		if err != nil {
+			log.Printf("Failed to run patient imports: %v", err)
+			return
+		}
+		if ran > 0 {
+			log.Printf("Ran %d patient imports", ran)
+		}
+	})
+
+	// Delete expired bulk exports once an hour
+	go runPeriodically(context.Background(), time.Hour, func(ctx context.Context) {
+		purged, err := bulkExportUseCase.PurgeExpired(ctx)
+		if err != nil {
+			log.Printf("Failed to purge bulk exports: %v", err)
+			return
+		}
+		if purged > 0 {
+			log.Printf("Purged %d expired bulk exports", purged)
+		}
+	})
+
+	// Catch up on payments whose webhooks were lost
+	go runPeriodically(context.Background(), 5*time.Minute, func(ctx context.Context) {
+		changed, err := paymentUseCase.ReconcilePending(ctx)
+		if err != nil {
+			log.Printf("Failed to reconcile payments: %v", err)
+			return
+		}
+		if changed > 0 {
+			log.Printf("Reconciled %d payment intents", changed)
+		}
+	})
+
 	serverAddr := fmt.Sprintf("0.0.0.0:%d", cfg.ServerPort)
 	log.Printf("Server starting on %s", serverAddr)
 	if err := router.Run(serverAddr); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+func newRateLimiter(cfg config.Config, db *gorm.DB) (*ratelimit.Limiter, error) {
+	if !cfg.RateLimitEnabled {
+		return nil, nil
+	}
+
+	def, err := ratelimit.ParseLimit(cfg.RateLimitDefault)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := ratelimit.ParseRoutes(cfg.RateLimitRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	var store ratelimit.Store
+	switch cfg.RateLimitStore {
+	case "postgres":
+		store = ratelimit.NewPostgresStore(db)
+	case "memory", "":
+		store = ratelimit.NewMemoryStore()
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.RateLimitStore)
+	}
+
+	return ratelimit.NewLimiter(store, def, routes), nil
+}
+
+// bulkExportSigningKey decodes the key download links of bulk exports are
+// signed with. Every server must share it, so there is no default.
+func bulkExportSigningKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, errors.New("BULK_EXPORT_SIGNING_KEY is required")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("BULK_EXPORT_SIGNING_KEY: %w", err)
+	}
+	if len(key) < 32 {
+		return nil, errors.New("BULK_EXPORT_SIGNING_KEY must be at least 32 bytes")
+	}
+	return key, nil
+}
+
+// runPeriodically calls fn immediately and then every interval until ctx is done.
+func runPeriodically(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"doctors/pkg/codeset"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (a *app) seed(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	count := fs.Int("patients", 10, "number of demo patients to create")
+	tenant := fs.String("tenant", "default", "slug of the tenant the patients belong to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx, err := a.actAs(ctx, *tenant)
+	if err != nil {
+		return err
+	}
+
+	firstNames := []string{"Ana", "Bruno", "Carla", "David", "Elena", "Felipe", "Gabriela", "Hugo", "Irene", "Jorge"}
+	lastNames := []string{"Smith", "Garcia", "Johnson", "Lopez", "Brown", "Martinez", "Davis", "Perez"}
+	start := time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour).Add(9 * time.Hour)
+
+	for i := 0; i < *count; i++ {
+		first, last := firstNames[i%len(firstNames)], lastNames[i%len(lastNames)]
+		patient := domain.Patient{
+			Name:  first + " " + last,
+			Email: fmt.Sprintf("%s.%s.%d@example.com", first, last, time.Now().UnixNano()%100000+int64(i)),
+			Phone: fmt.Sprintf("+1415555%04d", i),
+		}
+		if _, err := a.patientUseCase.CreatePatient(ctx, &patient); err != nil {
+			return fmt.Errorf("patient %d: %w", i+1, err)
+		}
+
+		appointment := domain.Appointment{
+			PatientID: patient.ID,
+			DateTime:  start.AddDate(0, 0, i/8).Add(time.Duration(i%8) * time.Hour),
+			Notes:     "Demo appointment",
+		}
+		if err := a.appointmentUseCase.CreateAppointment(ctx, &appointment); err != nil {
+			return fmt.Errorf("appointment for patient %d: %w", patient.ID, err)
+		}
+	}
+
+	fmt.Printf("created %d patients with one appointment each\n", *count)
+	return nil
+}
+
+func (a *app) user(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected \"user create\" or \"user list\"")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+		name := fs.String("name", "", "full name")
+		emailAddr := fs.String("email", "", "email address")
+		role := fs.String("role", domain.RoleAdmin, "admin, doctor, nurse, receptionist or patient")
+		patientID := fs.Uint("patient-id", 0, "patient the portal user acts for (patient role only)")
+		tenant := fs.String("tenant", "default", "slug of the tenant the user belongs to")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		ctx, err := a.actAs(ctx, *tenant)
+		if err != nil {
+			return err
+		}
+
+		user := domain.User{Name: *name, Email: *emailAddr, Role: *role}
+		if *patientID != 0 {
+			id := *patientID
+			user.PatientID = &id
+		}
+		apiKey, err := a.userUseCase.CreateUser(ctx, &user)
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("created %s user %d (%s)\n", user.Role, user.ID, user.Email)
+		fmt.Printf("API key (shown only once): %s\n", apiKey)
+		return nil
+
+	case "list":
+		users, err := a.userUseCase.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLE\tTENANT")
+		for _, u := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\n", u.ID, u.Name, u.Email, u.Role, u.TenantID)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown user command %q", args[0])
+	}
+}
+
+// actAs returns a context acting for the tenant with the given slug.
+func (a *app) actAs(ctx context.Context, slug string) (context.Context, error) {
+	tenant, err := a.tenantUseCase.GetTenantBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return usecase.WithTenant(ctx, tenant.ID), nil
+}
+
+func (a *app) tenants(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected \"tenants create\" or \"tenants list\"")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("tenants create", flag.ContinueOnError)
+		slug := fs.String("slug", "", "short name, e.g. northside")
+		name := fs.String("name", "", "display name")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		tenant := domain.Tenant{Slug: *slug, Name: *name}
+		if err := a.tenantUseCase.CreateTenant(ctx, &tenant); err != nil {
+			return describe(err)
+		}
+		fmt.Printf("created tenant %d (%s)\n", tenant.ID, tenant.Slug)
+		return nil
+
+	case "list":
+		tenants, err := a.tenantUseCase.ListTenants(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSLUG\tNAME\tCREATED")
+		for _, t := range tenants {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", t.ID, t.Slug, t.Name, t.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown tenants command %q", args[0])
+	}
+}
+
+func (a *app) outbox(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("expected \"outbox list\"")
+	}
+	fs := flag.NewFlagSet("outbox list", flag.ContinueOnError)
+	pending := fs.Bool("pending", false, "only messages not published yet")
+	limit := fs.Int("limit", 50, "number of messages to show, newest first")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	messages, err := a.outboxUseCase.ListOutbox(ctx, *pending, *limit)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTOPIC\tCREATED\tPUBLISHED\tATTEMPTS\tLAST ERROR")
+	for _, m := range messages {
+		published := "-"
+		if m.PublishedAt != nil {
+			published = m.PublishedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", m.ID, m.Topic, m.CreatedAt.Format(time.RFC3339), published, m.Attempts, m.LastError)
+	}
+	return w.Flush()
+}
+
+func (a *app) dlq(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected \"dlq list\", \"dlq show ID\" or \"dlq replay ID\"")
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("dlq list", flag.ContinueOnError)
+		pending := fs.Bool("pending", false, "only messages not replayed yet")
+		limit := fs.Int("limit", 50, "number of messages to show, newest first")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		letters, err := a.outboxUseCase.ListDeadLetters(ctx, *pending, *limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTOPIC\tTYPE\tCREATED\tREPLAYED\tERROR")
+		for _, l := range letters {
+			replayed := "-"
+			if l.ReplayedAt != nil {
+				replayed = l.ReplayedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", l.ID, l.Topic, l.Type, l.CreatedAt.Format(time.RFC3339), replayed, l.Error)
+		}
+		return w.Flush()
+
+	case "show", "replay":
+		if len(args) != 2 {
+			return fmt.Errorf("expected \"dlq %s ID\"", args[0])
+		}
+		id, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid dead letter ID %q", args[1])
+		}
+
+		if args[0] == "show" {
+			letter, err := a.outboxUseCase.GetDeadLetter(ctx, uint(id))
+			if err != nil {
+				return describe(err)
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(struct {
+				*domain.DeadLetter
+				Payload json.RawMessage `json:"payload"`
+			}{letter, rawOrString(letter.Payload)})
+		}
+
+		letter, err := a.outboxUseCase.ReplayDeadLetter(ctx, uint(id))
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("dead letter %d queued for publishing as outbox message %d\n", letter.ID, *letter.OutboxID)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown dlq command %q", args[0])
+	}
+}
+
+// rawOrString returns payload as JSON, quoted when it isn't valid JSON.
+func rawOrString(payload string) json.RawMessage {
+	if json.Valid([]byte(payload)) {
+		return json.RawMessage(payload)
+	}
+	quoted, _ := json.Marshal(payload)
+	return quoted
+}
+
+func (a *app) reminders(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "send" {
+		return fmt.Errorf("expected \"reminders send\"")
+	}
+	fs := flag.NewFlagSet("reminders send", flag.ContinueOnError)
+	dateStr := fs.String("date", time.Now().AddDate(0, 0, 1).Format("2006-01-02"), "appointment date (YYYY-MM-DD)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	date, err := time.Parse("2006-01-02", *dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date %q", *dateStr)
+	}
+
+	sent, err := a.appointmentUseCase.SendRemindersForDate(ctx, date)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("sent %d reminders for %s\n", sent, date.Format("2006-01-02"))
+	return nil
+}
+
+func (a *app) eligibility(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("expected \"eligibility check\"")
+	}
+	fs := flag.NewFlagSet("eligibility check", flag.ContinueOnError)
+	dateStr := fs.String("date", time.Now().AddDate(0, 0, 1).Format("2006-01-02"), "appointment date (YYYY-MM-DD)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	date, err := time.Parse("2006-01-02", *dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date %q", *dateStr)
+	}
+
+	checked, err := a.insuranceUseCase.CheckEligibilityForDate(ctx, date)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("checked insurance of %d patients with appointments on %s\n", checked, date.Format("2006-01-02"))
+	return nil
+}
+
+func (a *app) appointment(ctx context.Context, args []string) error {
+	if len(args) != 2 || args[0] != "resend-confirmation" {
+		return fmt.Errorf("expected \"appointment resend-confirmation ID\"")
+	}
+	id, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid appointment ID %q", args[1])
+	}
+	if err := a.appointmentUseCase.ResendConfirmation(ctx, uint(id)); err != nil {
+		return describe(err)
+	}
+	fmt.Printf("confirmation for appointment %d sent\n", id)
+	return nil
+}
+
+func (a *app) patients(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected \"patients export\", \"patients import\", \"patients reindex\" or \"patients assign-mrns\"")
+	}
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("patients export", flag.ContinueOnError)
+		out := fs.String("out", "", "output file (default stdout)")
+		includeArchived := fs.Bool("include-archived", false, "also export archived patients")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := a.exportPatients(ctx, w, *includeArchived)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %d patients\n", n)
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("patients import", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "validate only, don't create patients")
+		tenant := fs.String("tenant", "default", "slug of the tenant the patients belong to")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("expected a file to import")
+		}
+		ctx, err := a.actAs(ctx, *tenant)
+		if err != nil {
+			return err
+		}
+		return a.importPatients(ctx, fs.Arg(0), *dryRun)
+
+	case "assign-mrns":
+		fs := flag.NewFlagSet("patients assign-mrns", flag.ContinueOnError)
+		batchSize := fs.Int("batch-size", 500, "patients per batch")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		n, err := a.patientUseCase.AssignMissingMRNs(ctx, *batchSize)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("assigned %d medical record numbers\n", n)
+		return nil
+
+	case "reindex":
+		fs := flag.NewFlagSet("patients reindex", flag.ContinueOnError)
+		batchSize := fs.Int("batch-size", 500, "patients per transaction")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		n, err := a.patientRepo.ReindexSearch(ctx, *batchSize)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reindexed %d patients\n", n)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown patients command %q", args[0])
+	}
+}
+
+func (a *app) codes(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "import" {
+		return fmt.Errorf("expected \"codes import\"")
+	}
+	fs := flag.NewFlagSet("codes import", flag.ContinueOnError)
+	system := fs.String("system", "", "ICD-10-CM, CPT or HCPCS")
+	release := fs.String("release", "", "code set release, e.g. 2025")
+	format := fs.String("format", "", "icd10cm, hcpcs or tsv (default: the usual file of the system)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected a file to import")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := a.codeCatalogUseCase.ImportCodes(ctx, *system, *release, codeset.Format(*format), f)
+	if err != nil {
+		return describe(err)
+	}
+	fmt.Printf("imported %d %s codes (release %s), deactivated %d\n", result.Imported, result.System, result.Release, result.Deactivated)
+	return nil
+}
+
+func (a *app) exportPatients(ctx context.Context, w io.Writer, includeArchived bool) (int, error) {
+	const pageSize = 500
+	enc := json.NewEncoder(w)
+	exported := 0
+	for page := 1; ; page++ {
+		patients, total, err := a.patientUseCase.ListPatients(ctx, page, pageSize, includeArchived)
+		if err != nil {
+			return exported, err
+		}
+		for _, p := range patients {
+			if err := enc.Encode(p); err != nil {
+				return exported, err
+			}
+		}
+		exported += len(patients)
+		if len(patients) < pageSize || int64(exported) >= total {
+			return exported, nil
+		}
+	}
+}
+
+// importPatients reads one JSON patient per line. IDs, versions and
+// timestamps in the input are ignored; every row goes through the normal
+// validation.
+func (a *app) importPatients(ctx context.Context, path string, dryRun bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line, created, failed := 0, 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var in domain.Patient
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: invalid JSON: %v\n", line, err)
+			failed++
+			continue
+		}
+		// Keep the demographics and MRN, drop what this database assigns.
+		patient := in
+		patient.ID, patient.Version, patient.MergedIntoID, patient.TenantID = 0, 0, nil, 0
+		patient.CreatedAt, patient.UpdatedAt, patient.DeletedAt = time.Time{}, time.Time{}, gorm.DeletedAt{}
+
+		var duplicates []domain.DuplicateMatch
+		if dryRun {
+			err = a.patientUseCase.ValidatePatient(&patient)
+		} else {
+			duplicates, err = a.patientUseCase.CreatePatient(ctx, &patient)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, describe(err))
+			failed++
+			continue
+		}
+		for _, d := range duplicates {
+			fmt.Fprintf(os.Stderr, "line %d: warning: may duplicate patient %d (%s, score %.2f)\n", line, d.PatientID, d.Name, d.Score)
+		}
+		created++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	verb := "imported"
+	if dryRun {
+		verb = "validated"
+	}
+	fmt.Printf("%s %d patients, %d failed\n", verb, created, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d rows failed", failed)
+	}
+	return nil
+}
+
+// describe expands validation errors into their field messages.
+func describe(err error) error {
+	de, ok := domain.AsError(err)
+	if !ok || len(de.Fields) == 0 {
+		return err
+	}
+	msg := de.Message
+	for _, f := range de.Fields {
+		msg += fmt.Sprintf("; %s %s", f.Field, f.Message)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func (a *app) drugs(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected \"drugs import\" or \"drugs interactions\"")
+	}
+
+	switch args[0] {
+	case "import":
+		fs := flag.NewFlagSet("drugs import", flag.ContinueOnError)
+		release := fs.String("release", "", "catalog release, e.g. 2025-06")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("expected a file to import")
+		}
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		result, err := a.drugCatalogUseCase.ImportDrugs(ctx, *release, f)
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("imported %d drugs (release %s), deactivated %d\n", result.Imported, result.Release, result.Deactivated)
+		return nil
+
+	case "interactions":
+		if len(args) != 2 {
+			return fmt.Errorf("expected an interaction file")
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		count, err := a.drugCatalogUseCase.ImportInteractions(ctx, f)
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Printf("loaded %d drug interactions\n", count)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown drugs command %q", args[0])
+	}
+}
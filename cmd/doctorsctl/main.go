@@ -0,0 +1,205 @@
+// doctorsctl runs operational tasks against the Doctor SaaS database using
+// the same configuration and use cases as the API.
+package main
+
+import (
+	"context"
+	"doctors/config"
+	"doctors/internal/cli"
+	"doctors/internal/infrastracture/database"
+	"doctors/internal/repository"
+	"doctors/internal/usecase"
+	"doctors/pkg/clearinghouse"
+	"doctors/pkg/email"
+	"doctors/pkg/encryption"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const usage = `usage: doctorsctl <command> [arguments]
+
+commands:
+  migrate <up|down [N]|status|to VERSION>    manage the database schema
+  seed [-patients N] [-tenant SLUG]          create demo patients and appointments
+  tenants create -slug SLUG -name NAME       create a tenant
+  tenants list                               list tenants
+  user create -name NAME -email EMAIL -role ROLE [-patient-id ID] [-tenant SLUG]
+                                             create a user and print their API key
+  user list                                  list users
+  reminders send [-date YYYY-MM-DD]          send appointment reminders for a day (default tomorrow)
+  appointment resend-confirmation ID         email an appointment confirmation again
+  eligibility check [-date YYYY-MM-DD]       check insurance of patients with appointments on a day (default tomorrow)
+  patients export [-out FILE] [-include-archived]
+                                             write patients as JSON lines
+  patients import [-dry-run] [-tenant SLUG] FILE
+                                             create patients from JSON lines
+  patients reindex [-batch-size N]           rebuild the patient search index
+  patients assign-mrns [-batch-size N]       give medical record numbers to older patients
+  codes import -system SYSTEM -release RELEASE [-format FORMAT] FILE
+                                             load an ICD-10-CM, CPT or HCPCS release into the code catalog
+  drugs import -release RELEASE FILE         load a release of the drug catalog
+  drugs interactions FILE                    replace the drug interaction dataset
+  outbox list [-pending] [-limit N]          list messages queued for Kafka
+  dlq list [-pending] [-limit N]             list Kafka messages that could not be processed
+  dlq show ID                                print a dead letter with its payload
+  dlq replay ID                              queue a dead letter to be published and consumed again`
+
+// app holds the dependencies shared by the commands.
+type app struct {
+	migrator           *database.Migrator
+	patientRepo        repository.PatientRepository
+	tenantUseCase      usecase.TenantUseCase
+	userUseCase        usecase.UserUseCase
+	patientUseCase     usecase.PatientUseCase
+	appointmentUseCase usecase.AppointmentUseCase
+	insuranceUseCase   usecase.InsuranceUseCase
+	codeCatalogUseCase usecase.CodeCatalogUseCase
+	drugCatalogUseCase usecase.DrugCatalogUseCase
+	outboxUseCase      usecase.OutboxUseCase
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// seed must never email the made-up addresses it creates.
+	var emailSender email.Sender = email.NewMailtrapAPISender()
+	if os.Args[1] == "seed" {
+		emailSender = email.NewLogSender()
+	}
+
+	a, err := newApp(cfg, emailSender)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cmd, args := os.Args[1], os.Args[2:]
+	if cmd != "migrate" {
+		if err := a.migrator.EnsureUpToDate(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch cmd {
+	case "migrate":
+		err = cli.Migrate(ctx, a.migrator, args)
+	case "seed":
+		err = a.seed(ctx, args)
+	case "tenants":
+		err = a.tenants(ctx, args)
+	case "user":
+		err = a.user(ctx, args)
+	case "reminders":
+		err = a.reminders(ctx, args)
+	case "appointment":
+		err = a.appointment(ctx, args)
+	case "eligibility":
+		err = a.eligibility(ctx, args)
+	case "patients":
+		err = a.patients(ctx, args)
+	case "codes":
+		err = a.codes(ctx, args)
+	case "drugs":
+		err = a.drugs(ctx, args)
+	case "outbox":
+		err = a.outbox(ctx, args)
+	case "dlq":
+		err = a.dlq(ctx, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatalf("doctorsctl %s: %v", cmd, err)
+	}
+}
+
+func newApp(cfg config.Config, emailSender email.Sender) (*app, error) {
+	db, err := database.NewPostgresDB(cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup database: %w", err)
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	cipher, err := encryption.NewLocalEnvelope(cfg.EncryptionMasterKeys, cfg.EncryptionActiveKeyID, cfg.EncryptionIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure encryption: %w", err)
+	}
+
+	transactor := repository.NewTransactor(db)
+	patientRepo := repository.NewPatientRepository(db, cipher)
+	appointmentRepo := repository.NewAppointmentRepository(db)
+	doctorRepo := repository.NewDoctorRepository(db)
+	mergeRepo := repository.NewPatientMergeRepository(db)
+	relationshipRepo := repository.NewRelationshipRepository(db)
+	patientImportRepo := repository.NewPatientImportRepository(db, cipher)
+	insuranceRepo := repository.NewInsuranceRepository(db, cipher)
+	encounterRepo := repository.NewEncounterRepository(db, cipher)
+	vitalRepo := repository.NewVitalRepository(db)
+	allergyRepo := repository.NewAllergyRepository(db)
+	medicationRepo := repository.NewMedicationRepository(db)
+	problemRepo := repository.NewProblemRepository(db)
+	prescriptionRepo := repository.NewPrescriptionRepository(db)
+	labRepo := repository.NewLabRepository(db, cipher)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	paymentIntentRepo := repository.NewPaymentIntentRepository(db)
+	claimRepo := repository.NewClaimRepository(db, cipher)
+	userRepo := repository.NewUserRepository(db)
+	bookingHorizon := time.Duration(cfg.BookingHorizonDays) * 24 * time.Hour
+	if err := usecase.ValidateMRNFormat(cfg.MRNFormat); err != nil {
+		return nil, err
+	}
+	if err := usecase.ValidateInvoiceNumberFormat(cfg.InvoiceNumberFormat); err != nil {
+		return nil, err
+	}
+	clearinghouseClient, err := clearinghouse.New(cfg.ClearinghouseClient, cfg.ClearinghouseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure clearinghouse: %w", err)
+	}
+	billingUseCase := usecase.NewBillingUseCase(transactor, invoiceRepo, repository.NewFeeRepository(db), patientRepo, appointmentRepo,
+		paymentIntentRepo, usecase.BillingSettings{
+			Currency:     cfg.BillingCurrency,
+			NumberFormat: cfg.InvoiceNumberFormat,
+			DueDays:      cfg.InvoiceDueDays,
+			ProviderName: cfg.ProviderName,
+			ProviderNPI:  cfg.ProviderNPI,
+		})
+
+	return &app{
+		migrator:      migrator,
+		patientRepo:   patientRepo,
+		tenantUseCase: usecase.NewTenantUseCase(repository.NewTenantRepository(db)),
+		userUseCase:   usecase.NewUserUseCase(userRepo, cfg.AdminAPIKey),
+		patientUseCase: usecase.NewPatientUseCase(transactor, patientRepo, appointmentRepo, mergeRepo, relationshipRepo, patientImportRepo,
+			cfg.PatientDeletePolicy, cfg.PatientDuplicatePolicy, cfg.MRNFormat, insuranceRepo, encounterRepo, vitalRepo,
+			allergyRepo, medicationRepo, problemRepo, prescriptionRepo, labRepo, invoiceRepo, paymentIntentRepo, claimRepo),
+		appointmentUseCase: usecase.NewAppointmentUseCase(transactor, appointmentRepo, patientRepo, doctorRepo, relationshipRepo,
+			emailSender, billingUseCase, bookingHorizon),
+		insuranceUseCase: usecase.NewInsuranceUseCase(transactor, insuranceRepo, patientRepo, appointmentRepo,
+			clearinghouseClient, usecase.EligibilitySettings{
+				SenderID:     cfg.X12SenderID,
+				ReceiverID:   cfg.X12ReceiverID,
+				ProviderName: cfg.ProviderName,
+				ProviderNPI:  cfg.ProviderNPI,
+				Production:   cfg.X12Production,
+			}),
+		codeCatalogUseCase: usecase.NewCodeCatalogUseCase(transactor, repository.NewCodeRepository(db)),
+		drugCatalogUseCase: usecase.NewDrugCatalogUseCase(transactor, repository.NewDrugRepository(db)),
+		outboxUseCase:      usecase.NewOutboxUseCase(transactor, repository.NewOutboxRepository(db, cipher)),
+	}, nil
+}
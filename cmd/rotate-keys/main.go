@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"doctors/config"
+	"doctors/internal/infrastracture/database"
+	"doctors/internal/repository"
+	"doctors/pkg/encryption"
+	"flag"
+	"log"
+)
+
+// rotate-keys re-encrypts patient PII, encounter notes, insurance details,
+// HL7 messages, claim files, remittances, outbox messages, dead letters
+// and the rows of unfinished patient imports under the active master key.
+// Run it after adding a new key to ENCRYPTION_MASTER_KEYS and switching
+// ENCRYPTION_ACTIVE_KEY_ID; keep the old key configured until it finishes.
+func main() {
+	batchSize := flag.Int("batch-size", 500, "number of rows re-encrypted per transaction")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	cipher, err := encryption.NewLocalEnvelope(cfg.EncryptionMasterKeys, cfg.EncryptionActiveKeyID, cfg.EncryptionIndexKey)
+	if err != nil {
+		log.Fatalf("Failed to configure encryption: %v", err)
+	}
+
+	db, err := database.NewPostgresDB(cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+	if err != nil {
+		log.Fatalf("Failed to setup database: %v", err)
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.EnsureUpToDate(context.Background()); err != nil {
+		log.Fatalf("Refusing to run: %v", err)
+	}
+
+	patientRepo := repository.NewPatientRepository(db, cipher)
+	rotated, err := patientRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d patients with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	encounterRepo := repository.NewEncounterRepository(db, cipher)
+	rotated, err = encounterRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d encounters with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	insuranceRepo := repository.NewInsuranceRepository(db, cipher)
+	rotated, err = insuranceRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d insurance policies and eligibility checks with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	labRepo := repository.NewLabRepository(db, cipher)
+	rotated, err = labRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d HL7 messages with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	claimRepo := repository.NewClaimRepository(db, cipher)
+	rotated, err = claimRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d claim files and remittances with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	outboxRepo := repository.NewOutboxRepository(db, cipher)
+	rotated, err = outboxRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d outbox messages and dead letters with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+
+	importRepo := repository.NewPatientImportRepository(db, cipher)
+	rotated, err = importRepo.RotateKeys(context.Background(), *batchSize)
+	log.Printf("Re-encrypted %d patient import uploads with key %q", rotated, cipher.ActiveKeyID())
+	if err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+}
@@ -16,12 +16,153 @@ type Config struct {
 	KafkaGroupID  string   `mapstructure:"KAFKA_GROUP_ID"`
 	EmailAPIToken string   `mapstructure:"EMAIL_API_TOKEN"`
 	EmailFrom     string   `mapstructure:"EMAIL_FROM"`
+
+	// Rate limiting: limits are "rate:burst" (tokens per second : bucket size),
+	// per-route overrides are "METHOD /path=rate:burst" separated by commas.
+	RateLimitEnabled bool   `mapstructure:"RATE_LIMIT_ENABLED"`
+	RateLimitStore   string `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitDefault string `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitRoutes  string `mapstructure:"RATE_LIMIT_ROUTES"`
+
+	// Field-level encryption: master keys are "id:base64key" pairs separated by
+	// commas; new data keys are wrapped with the active one.
+	EncryptionMasterKeys  string `mapstructure:"ENCRYPTION_MASTER_KEYS"`
+	EncryptionActiveKeyID string `mapstructure:"ENCRYPTION_ACTIVE_KEY_ID"`
+	EncryptionIndexKey    string `mapstructure:"ENCRYPTION_BLIND_INDEX_KEY"`
+
+	// BookingHorizonDays is how far ahead appointments may be booked.
+	BookingHorizonDays int `mapstructure:"BOOKING_HORIZON_DAYS"`
+
+	// PatientDeletePolicy decides what happens to a deleted patient's
+	// upcoming appointments: "block" the deletion or "cancel" them.
+	PatientDeletePolicy string `mapstructure:"PATIENT_DELETE_POLICY"`
+	// PatientDuplicatePolicy decides whether creating a probable duplicate
+	// patient is allowed with a warning ("warn") or rejected ("block").
+	PatientDuplicatePolicy string `mapstructure:"PATIENT_DUPLICATE_POLICY"`
+	// MRNFormat shapes generated medical record numbers, e.g. "MRN-{yyyy}-{seq:6}".
+	MRNFormat string `mapstructure:"MRN_FORMAT"`
+	// ArchiveRetentionDays is how long archived records are kept before purging.
+	ArchiveRetentionDays int    `mapstructure:"ARCHIVE_RETENTION_DAYS"`
+	AdminAPIKey          string `mapstructure:"ADMIN_API_KEY"`
+
+	// Insurance eligibility: the clearinghouse client ("file" is a local
+	// stand-in writing to ClearinghouseDir) and how this practice is
+	// identified in X12 interchanges.
+	ClearinghouseClient string `mapstructure:"CLEARINGHOUSE_CLIENT"`
+	ClearinghouseDir    string `mapstructure:"CLEARINGHOUSE_DIR"`
+	X12SenderID         string `mapstructure:"X12_SENDER_ID"`
+	X12ReceiverID       string `mapstructure:"X12_RECEIVER_ID"`
+	X12Production       bool   `mapstructure:"X12_PRODUCTION"`
+	ProviderName        string `mapstructure:"PROVIDER_NAME"`
+	ProviderNPI         string `mapstructure:"PROVIDER_NPI"`
+
+	// Laboratory: the client ("mllp" sends orders to LabAddr, "loopback"
+	// is a local stand-in laboratory), the MLLP listener results arrive
+	// on, and how both ends are named in HL7 messages.
+	LabClient      string `mapstructure:"LAB_CLIENT"`
+	LabAddr        string `mapstructure:"LAB_ADDR"`
+	LabResultsAddr string `mapstructure:"LAB_RESULTS_ADDR"`
+	LabApplication string `mapstructure:"LAB_APPLICATION"`
+	LabFacility    string `mapstructure:"LAB_FACILITY"`
+	HL7Application string `mapstructure:"HL7_APPLICATION"`
+	HL7Facility    string `mapstructure:"HL7_FACILITY"`
+	HL7Production  bool   `mapstructure:"HL7_PRODUCTION"`
+
+	// FHIR bulk export: where files are written, how long finished exports
+	// are kept, and how long signed download links stay valid. Links are
+	// signed with BulkExportSigningKey (base64); without one a random key
+	// is used and links stop working on restart.
+	BulkExportDir            string `mapstructure:"BULK_EXPORT_DIR"`
+	BulkExportRetentionHours int    `mapstructure:"BULK_EXPORT_RETENTION_HOURS"`
+	BulkExportLinkMinutes    int    `mapstructure:"BULK_EXPORT_LINK_MINUTES"`
+	BulkExportSigningKey     string `mapstructure:"BULK_EXPORT_SIGNING_KEY"`
+
+	// Billing: the ISO 4217 currency of all amounts, how invoice numbers
+	// look (same placeholders as MRN_FORMAT), and how many days after
+	// issue an invoice is due.
+	BillingCurrency     string `mapstructure:"BILLING_CURRENCY"`
+	InvoiceNumberFormat string `mapstructure:"INVOICE_NUMBER_FORMAT"`
+	InvoiceDueDays      int    `mapstructure:"INVOICE_DUE_DAYS"`
+
+	// Card payments: the provider ("fake" is a local stand-in, "stripe"
+	// calls PAYMENT_API_URL), its secret API key, the secret webhooks are
+	// signed with, where the fake provider posts its webhooks (this server
+	// when empty), and the default appointment deposit.
+	PaymentProvider       string `mapstructure:"PAYMENT_PROVIDER"`
+	PaymentAPIURL         string `mapstructure:"PAYMENT_API_URL"`
+	PaymentAPIKey         string `mapstructure:"PAYMENT_API_KEY"`
+	PaymentWebhookSecret  string `mapstructure:"PAYMENT_WEBHOOK_SECRET"`
+	PaymentFakeWebhookURL string `mapstructure:"PAYMENT_FAKE_WEBHOOK_URL"`
+	DepositCents          int64  `mapstructure:"DEPOSIT_CENTS"`
+
+	// Insurance claims: the billing provider details 837P files need on
+	// top of PROVIDER_NAME and PROVIDER_NPI (the address is where payers
+	// write to, the state a two-letter code), the CMS place of service of
+	// visits, and the claim filing indicator of the plans billed.
+	ProviderTaxID        string `mapstructure:"PROVIDER_TAX_ID"`
+	ProviderAddress      string `mapstructure:"PROVIDER_ADDRESS"`
+	ProviderCity         string `mapstructure:"PROVIDER_CITY"`
+	ProviderState        string `mapstructure:"PROVIDER_STATE"`
+	ProviderPostalCode   string `mapstructure:"PROVIDER_POSTAL_CODE"`
+	ProviderPhone        string `mapstructure:"PROVIDER_PHONE"`
+	ClaimPlaceOfService  string `mapstructure:"CLAIM_PLACE_OF_SERVICE"`
+	ClaimFilingIndicator string `mapstructure:"CLAIM_FILING_INDICATOR"`
 }
 
 func LoadConfig() (config Config, err error) {
 	viper.SetConfigFile(".env") // Use .env file
 	viper.AddConfigPath(".")    // Look for .env in the root directory
 
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
+	viper.SetDefault("RATE_LIMIT_DEFAULT", "10:20")
+	viper.SetDefault("RATE_LIMIT_ROUTES", "")
+	viper.SetDefault("ENCRYPTION_MASTER_KEYS", "")
+	viper.SetDefault("ENCRYPTION_ACTIVE_KEY_ID", "")
+	viper.SetDefault("ENCRYPTION_BLIND_INDEX_KEY", "")
+	viper.SetDefault("BOOKING_HORIZON_DAYS", 180)
+	viper.SetDefault("PATIENT_DELETE_POLICY", "block")
+	viper.SetDefault("PATIENT_DUPLICATE_POLICY", "warn")
+	viper.SetDefault("MRN_FORMAT", "{seq:8}")
+	viper.SetDefault("ARCHIVE_RETENTION_DAYS", 2555)
+	viper.SetDefault("ADMIN_API_KEY", "")
+	viper.SetDefault("CLEARINGHOUSE_CLIENT", "file")
+	viper.SetDefault("CLEARINGHOUSE_DIR", "clearinghouse")
+	viper.SetDefault("X12_SENDER_ID", "DOCTORSAAS")
+	viper.SetDefault("X12_RECEIVER_ID", "CLEARINGHOUSE")
+	viper.SetDefault("X12_PRODUCTION", false)
+	viper.SetDefault("PROVIDER_NAME", "Doctor SaaS")
+	viper.SetDefault("PROVIDER_NPI", "")
+	viper.SetDefault("LAB_CLIENT", "loopback")
+	viper.SetDefault("LAB_ADDR", "")
+	viper.SetDefault("LAB_RESULTS_ADDR", "127.0.0.1:2575")
+	viper.SetDefault("LAB_APPLICATION", "LAB")
+	viper.SetDefault("LAB_FACILITY", "LAB")
+	viper.SetDefault("HL7_APPLICATION", "DOCTORSAAS")
+	viper.SetDefault("HL7_FACILITY", "DOCTORSAAS")
+	viper.SetDefault("HL7_PRODUCTION", false)
+	viper.SetDefault("BULK_EXPORT_DIR", "exports")
+	viper.SetDefault("BULK_EXPORT_RETENTION_HOURS", 24)
+	viper.SetDefault("BULK_EXPORT_LINK_MINUTES", 60)
+	viper.SetDefault("BULK_EXPORT_SIGNING_KEY", "")
+	viper.SetDefault("BILLING_CURRENCY", "USD")
+	viper.SetDefault("INVOICE_NUMBER_FORMAT", "INV-{yyyy}-{seq:6}")
+	viper.SetDefault("INVOICE_DUE_DAYS", 30)
+	viper.SetDefault("PAYMENT_PROVIDER", "")
+	viper.SetDefault("PAYMENT_API_URL", "https://api.stripe.com")
+	viper.SetDefault("PAYMENT_API_KEY", "")
+	viper.SetDefault("PAYMENT_WEBHOOK_SECRET", "")
+	viper.SetDefault("PAYMENT_FAKE_WEBHOOK_URL", "")
+	viper.SetDefault("DEPOSIT_CENTS", 0)
+	viper.SetDefault("PROVIDER_TAX_ID", "")
+	viper.SetDefault("PROVIDER_ADDRESS", "")
+	viper.SetDefault("PROVIDER_CITY", "")
+	viper.SetDefault("PROVIDER_STATE", "")
+	viper.SetDefault("PROVIDER_POSTAL_CODE", "")
+	viper.SetDefault("PROVIDER_PHONE", "")
+	viper.SetDefault("CLAIM_PLACE_OF_SERVICE", "11")
+	viper.SetDefault("CLAIM_FILING_INDICATOR", "CI")
+
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
@@ -0,0 +1,87 @@
+// internal/cli/migrate.go
+package cli
+
+import (
+	"context"
+	"doctors/internal/infrastracture/database"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [N]        roll back the last N migrations (default 1)
+  status          list migrations and whether they are applied
+  to <version>    migrate up or down to the given version`
+
+// Migrate implements the "migrate" subcommand shared by the API binary and doctorsctl.
+func Migrate(ctx context.Context, migrator *database.Migrator, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), migrateUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing migrate command")
+	}
+
+	var done []database.Migration
+	var err error
+	switch cmd := fs.Arg(0); cmd {
+	case "up":
+		done, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", fs.Arg(1))
+			}
+		}
+		done, err = migrator.Down(ctx, steps)
+	case "to":
+		if fs.NArg() < 2 {
+			return fmt.Errorf("missing target version")
+		}
+		version, perr := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if perr != nil {
+			return fmt.Errorf("invalid version %q", fs.Arg(1))
+		}
+		done, err = migrator.To(ctx, version)
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", cmd)
+	}
+
+	for _, m := range done {
+		fmt.Printf("migrated %04d_%s\n", m.Version, m.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Println("nothing to migrate")
+	}
+	return err
+}
+
+func printMigrationStatus(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,88 @@
+// internal/delivery/event/handler.go
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+)
+
+// Message is the envelope of commands consumed from Kafka, e.g.
+// {"type": "appointment.create", "payload": {"patient_id": 1, "date_time": "..."}}.
+type Message struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Handler dispatches Kafka messages to the same use cases the HTTP API uses,
+// so validation and business rules apply identically.
+type Handler struct {
+	topic              string
+	patientUseCase     usecase.PatientUseCase
+	appointmentUseCase usecase.AppointmentUseCase
+	outboxUseCase      usecase.OutboxUseCase
+}
+
+// NewHandler returns a handler of the messages consumed from topic.
+func NewHandler(topic string, patientUseCase usecase.PatientUseCase, appointmentUseCase usecase.AppointmentUseCase,
+	outboxUseCase usecase.OutboxUseCase) *Handler {
+	return &Handler{
+		topic:              topic,
+		patientUseCase:     patientUseCase,
+		appointmentUseCase: appointmentUseCase,
+		outboxUseCase:      outboxUseCase,
+	}
+}
+
+// Handle processes one message. Messages that are malformed, rejected or
+// fail are logged and kept as dead letters rather than returned, since an
+// error stops the consumer loop.
+func (h *Handler) Handle(ctx context.Context, raw []byte) error {
+	var msg Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		log.Printf("Skipping malformed Kafka message: %v", err)
+		h.deadLetter(ctx, raw, "", "malformed message: "+err.Error())
+		return nil
+	}
+
+	var err error
+	switch msg.Type {
+	case "patient.create":
+		var patient domain.Patient
+		if err = json.Unmarshal(msg.Payload, &patient); err == nil {
+			var duplicates []domain.DuplicateMatch
+			duplicates, err = h.patientUseCase.CreatePatient(ctx, &patient)
+			for _, d := range duplicates {
+				log.Printf("Patient %d created from Kafka may duplicate patient %d (score %.2f)", patient.ID, d.PatientID, d.Score)
+			}
+		}
+	case "appointment.create":
+		var appointment domain.Appointment
+		if err = json.Unmarshal(msg.Payload, &appointment); err == nil {
+			err = h.appointmentUseCase.CreateAppointment(ctx, &appointment)
+		}
+	default:
+		log.Printf("Received message: %s", string(raw))
+		return nil
+	}
+
+	if de, ok := domain.AsError(err); ok {
+		log.Printf("Rejected %s message: %s %v", msg.Type, de.Message, de.Fields)
+		h.deadLetter(ctx, raw, msg.Type, fmt.Sprintf("rejected: %s %v", de.Message, de.Fields))
+	} else if err != nil {
+		log.Printf("Failed to process %s message: %v", msg.Type, err)
+		h.deadLetter(ctx, raw, msg.Type, err.Error())
+	}
+	return nil
+}
+
+func (h *Handler) deadLetter(ctx context.Context, raw []byte, msgType, reason string) {
+	letter := &domain.DeadLetter{Topic: h.topic, Type: msgType, Payload: string(raw), Error: reason}
+	if err := h.outboxUseCase.RecordDeadLetter(ctx, letter); err != nil {
+		log.Printf("Failed to keep dead letter of %s message: %v", msgType, err)
+	}
+}
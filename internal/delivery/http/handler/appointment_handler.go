@@ -5,7 +5,6 @@ import (
 	"doctors/internal/domain"
 	"doctors/internal/usecase"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,79 +18,120 @@ func NewAppointmentHandler(appointmentUseCase usecase.AppointmentUseCase) *Appoi
 	return &AppointmentHandler{appointmentUseCase: appointmentUseCase}
 }
 
-func (h *AppointmentHandler) CreateAppointment(c *gin.Context) {
-	var appointmentRequest struct {
-		PatientID uint      `json:"patient_id" binding:"required"`
-		DateTime  time.Time `json:"date_time" binding:"required"`
-		Notes     string    `json:"notes"`
-	}
+// appointmentRequest holds the client-editable fields of an appointment.
+type appointmentRequest struct {
+	PatientID uint      `json:"patient_id"`
+	DateTime  time.Time `json:"date_time"`
+	Notes     string    `json:"notes"`
+	Status    string    `json:"status"`
+	Type      string    `json:"type"`
+}
 
-	if err := c.ShouldBindJSON(&appointmentRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *AppointmentHandler) CreateAppointment(c *gin.Context) {
+	var req appointmentRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	appointment := domain.Appointment{
-		PatientID: appointmentRequest.PatientID,
-		DateTime:  appointmentRequest.DateTime,
-		Notes:     appointmentRequest.Notes,
+		PatientID: req.PatientID,
+		DateTime:  req.DateTime,
+		Notes:     req.Notes,
+		Type:      req.Type,
 	}
 
 	if err := h.appointmentUseCase.CreateAppointment(c.Request.Context(), &appointment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+		_ = c.Error(err)
 		return
 	}
 
+	setETag(c, appointment.Version)
 	c.JSON(http.StatusCreated, appointment)
 }
 
 func (h *AppointmentHandler) GetAppointment(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+	id, ok := parseID(c, "appointment")
+	if !ok {
 		return
 	}
 
-	appointment, err := h.appointmentUseCase.GetAppointment(c.Request.Context(), uint(id))
+	appointment, err := h.appointmentUseCase.GetAppointment(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+		_ = c.Error(err)
 		return
 	}
 
+	setETag(c, appointment.Version)
 	c.JSON(http.StatusOK, appointment)
 }
 
+// UpdateAppointment replaces an appointment (PUT). Omitted fields are cleared.
 func (h *AppointmentHandler) UpdateAppointment(c *gin.Context) {
-	var appointment domain.Appointment
-	if err := c.ShouldBindJSON(&appointment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
 		return
 	}
 
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+	var req appointmentRequest
+	if !bindJSON(c, &req) {
 		return
 	}
-	appointment.ID = uint(id)
 
-	if err := h.appointmentUseCase.UpdateAppointment(c.Request.Context(), &appointment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment"})
+	appointment := domain.Appointment{
+		ID:        id,
+		PatientID: req.PatientID,
+		DateTime:  req.DateTime,
+		Notes:     req.Notes,
+		Status:    req.Status,
+		Type:      req.Type,
+	}
+
+	if err := h.appointmentUseCase.UpdateAppointment(c.Request.Context(), &appointment, ifMatch); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
+	setETag(c, appointment.Version)
 	c.JSON(http.StatusOK, appointment)
 }
 
-func (h *AppointmentHandler) DeleteAppointment(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+// PatchAppointment applies a JSON Merge Patch; omitted fields are left unchanged.
+func (h *AppointmentHandler) PatchAppointment(c *gin.Context) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+	patch, ok := readPatch(c)
+	if !ok {
+		return
+	}
+
+	appointment, err := h.appointmentUseCase.PatchAppointment(c.Request.Context(), id, patch, ifMatch)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		_ = c.Error(err)
 		return
 	}
 
-	if err := h.appointmentUseCase.DeleteAppointment(c.Request.Context(), uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete appointment"})
+	setETag(c, appointment.Version)
+	c.JSON(http.StatusOK, appointment)
+}
+
+func (h *AppointmentHandler) DeleteAppointment(c *gin.Context) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	if err := h.appointmentUseCase.DeleteAppointment(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
@@ -102,15 +142,33 @@ func (h *AppointmentHandler) GetAppointmentsByDate(c *gin.Context) {
 	dateStr := c.Query("date")
 	date, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "date", Message: "must be a date in YYYY-MM-DD format"}))
 		return
 	}
 
-	appointments, err := h.appointmentUseCase.GetAppointmentsByDate(c.Request.Context(), date)
+	includeArchived := c.Query("include_archived") == "true"
+	appointments, err := h.appointmentUseCase.GetAppointmentsByDate(c.Request.Context(), date, includeArchived)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, appointments)
 }
+
+// RestoreAppointment brings back an archived appointment (admin only).
+func (h *AppointmentHandler) RestoreAppointment(c *gin.Context) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	appointment, err := h.appointmentUseCase.RestoreAppointment(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, appointment.Version)
+	c.JSON(http.StatusOK, appointment)
+}
@@ -0,0 +1,345 @@
+// internal/delivery/http/handler/billing_handler.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type BillingHandler struct {
+	billingUseCase usecase.BillingUseCase
+}
+
+func NewBillingHandler(billingUseCase usecase.BillingUseCase) *BillingHandler {
+	return &BillingHandler{billingUseCase: billingUseCase}
+}
+
+type feeRequest struct {
+	Description    string  `json:"description"`
+	Code           string  `json:"code"`
+	UnitPriceCents int64   `json:"unit_price_cents"`
+	TaxRate        float64 `json:"tax_rate"`
+}
+
+type invoiceLineRequest struct {
+	Description    string  `json:"description"`
+	Code           string  `json:"code"`
+	Quantity       int     `json:"quantity"`
+	UnitPriceCents int64   `json:"unit_price_cents"`
+	DiscountCents  int64   `json:"discount_cents"`
+	TaxRate        float64 `json:"tax_rate"`
+}
+
+type invoiceRequest struct {
+	PatientID     uint                 `json:"patient_id"`
+	AppointmentID *uint                `json:"appointment_id"`
+	DueDate       string               `json:"due_date"`
+	Notes         string               `json:"notes"`
+	Lines         []invoiceLineRequest `json:"lines"`
+}
+
+func (r invoiceRequest) invoice() domain.Invoice {
+	invoice := domain.Invoice{
+		PatientID:     r.PatientID,
+		AppointmentID: r.AppointmentID,
+		DueDate:       r.DueDate,
+		Notes:         r.Notes,
+		Lines:         make([]domain.InvoiceLine, len(r.Lines)),
+	}
+	for i, line := range r.Lines {
+		invoice.Lines[i] = domain.InvoiceLine{
+			Description:    line.Description,
+			Code:           line.Code,
+			Quantity:       line.Quantity,
+			UnitPriceCents: line.UnitPriceCents,
+			DiscountCents:  line.DiscountCents,
+			TaxRate:        line.TaxRate,
+		}
+	}
+	return invoice
+}
+
+type voidInvoiceRequest struct {
+	Reason string `json:"reason"`
+}
+
+type paymentRequest struct {
+	AmountCents int64      `json:"amount_cents"`
+	Method      string     `json:"method"`
+	Reference   string     `json:"reference"`
+	Note        string     `json:"note"`
+	ReceivedAt  *time.Time `json:"received_at"`
+}
+
+func (r paymentRequest) payment(invoiceID uint) domain.Payment {
+	payment := domain.Payment{
+		InvoiceID:   invoiceID,
+		AmountCents: r.AmountCents,
+		Method:      r.Method,
+		Reference:   r.Reference,
+		Note:        r.Note,
+	}
+	if r.ReceivedAt != nil {
+		payment.ReceivedAt = *r.ReceivedAt
+	}
+	return payment
+}
+
+type refundRequest struct {
+	paymentRequest
+	PaymentID *uint `json:"payment_id"`
+}
+
+func (h *BillingHandler) ListFees(c *gin.Context) {
+	fees, err := h.billingUseCase.ListFees(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fees": fees, "total": len(fees)})
+}
+
+// SetFee creates or replaces the fee of the appointment type in the path (admin only).
+func (h *BillingHandler) SetFee(c *gin.Context) {
+	var req feeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	fee := domain.Fee{
+		AppointmentType: c.Param("type"),
+		Description:     req.Description,
+		Code:            req.Code,
+		UnitPriceCents:  req.UnitPriceCents,
+		TaxRate:         req.TaxRate,
+	}
+
+	if err := h.billingUseCase.SetFee(c.Request.Context(), &fee); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fee)
+}
+
+func (h *BillingHandler) DeleteFee(c *gin.Context) {
+	if err := h.billingUseCase.DeleteFee(c.Request.Context(), c.Param("type")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateInvoice creates a draft invoice billed by hand.
+func (h *BillingHandler) CreateInvoice(c *gin.Context) {
+	var req invoiceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	invoice := req.invoice()
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.billingUseCase.CreateInvoice(c.Request.Context(), &invoice, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// ListInvoices lists invoices, newest first: ?patient_id= and ?status=
+// (comma-separated) filter, ?limit= and ?offset= page.
+func (h *BillingHandler) ListInvoices(c *gin.Context) {
+	var search domain.InvoiceSearch
+	if raw := c.Query("patient_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "patient_id", Message: "must be a patient ID"}))
+			return
+		}
+		patientID := uint(id)
+		search.PatientID = &patientID
+	}
+	if raw := c.Query("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if !contains([]string{domain.InvoiceDraft, domain.InvoiceIssued, domain.InvoicePaid, domain.InvoiceVoid}, status) {
+				_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "status", Message: "must be draft, issued, paid or void"}))
+				return
+			}
+			search.Statuses = append(search.Statuses, status)
+		}
+	}
+	search.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	search.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	invoices, total, err := h.billingUseCase.ListInvoices(c.Request.Context(), search)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invoices": invoices, "total": total})
+}
+
+func (h *BillingHandler) GetInvoice(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	invoice, err := h.billingUseCase.GetInvoice(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// UpdateInvoice replaces the lines, due date and notes of a draft invoice.
+func (h *BillingHandler) UpdateInvoice(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	var req invoiceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	invoice := req.invoice()
+	invoice.ID = id
+
+	if err := h.billingUseCase.UpdateInvoice(c.Request.Context(), &invoice); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+func (h *BillingHandler) IssueInvoice(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	invoice, err := h.billingUseCase.IssueInvoice(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+func (h *BillingHandler) VoidInvoice(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	var req voidInvoiceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	invoice, err := h.billingUseCase.VoidInvoice(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// RecordPayment records a payment and returns the updated invoice.
+func (h *BillingHandler) RecordPayment(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	var req paymentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	payment := req.payment(id)
+
+	user, _ := middleware.CurrentUser(c)
+	invoice, err := h.billingUseCase.RecordPayment(c.Request.Context(), &payment, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// RefundPayment refunds some or all of a payment and returns the updated
+// invoice. The method defaults to that of the payment.
+func (h *BillingHandler) RefundPayment(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	var req refundRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	refund := req.payment(id)
+	refund.RefundOfID = req.PaymentID
+
+	user, _ := middleware.CurrentUser(c)
+	invoice, err := h.billingUseCase.RefundPayment(c.Request.Context(), &refund, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// GetInvoicePDF serves the invoice for printing.
+func (h *BillingHandler) GetInvoicePDF(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+
+	document, err := h.billingUseCase.RenderInvoice(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"invoice-%d.pdf\"", id))
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+// GetPatientBalance returns what a patient owes and on which invoices.
+func (h *BillingHandler) GetPatientBalance(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	balance, err := h.billingUseCase.PatientBalance(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
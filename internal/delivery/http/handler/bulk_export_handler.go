@@ -0,0 +1,173 @@
+// internal/delivery/http/handler/bulk_export_handler.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"doctors/pkg/fhir"
+	"github.com/gin-gonic/gin"
+)
+
+// BulkExportHandler serves the FHIR Bulk Data $export operation: a
+// kickoff request, a status URL to poll, and signed file downloads.
+type BulkExportHandler struct {
+	exportUseCase usecase.BulkExportUseCase
+}
+
+func NewBulkExportHandler(exportUseCase usecase.BulkExportUseCase) *BulkExportHandler {
+	return &BulkExportHandler{exportUseCase: exportUseCase}
+}
+
+// bulkRetryAfter is how often clients are asked to poll an unfinished export.
+const bulkRetryAfter = 5 * time.Second
+
+// outputFormats are the accepted values of _outputFormat.
+var outputFormats = []string{fhir.NDJSONContentType, "application/ndjson", "ndjson"}
+
+// ExportSystem starts an export of every patient, practitioner and appointment.
+func (h *BulkExportHandler) ExportSystem(c *gin.Context) {
+	h.kickoff(c, domain.BulkExportSystem)
+}
+
+// ExportPatients starts an export of every patient and their appointments.
+func (h *BulkExportHandler) ExportPatients(c *gin.Context) {
+	h.kickoff(c, domain.BulkExportPatient)
+}
+
+func (h *BulkExportHandler) kickoff(c *gin.Context, level string) {
+	if !strings.Contains(c.GetHeader("Prefer"), "respond-async") {
+		_ = c.Error(domain.NewBadRequestError("respond_async_required", "$export requires the header Prefer: respond-async"))
+		return
+	}
+
+	export := domain.BulkExport{Level: level, Request: fhirSelfURL(c)}
+	var fields []domain.FieldError
+	if format := c.Query("_outputFormat"); format != "" && !contains(outputFormats, format) {
+		fields = append(fields, domain.FieldError{Field: "_outputFormat", Message: "must be " + fhir.NDJSONContentType})
+	}
+	if types := c.Query("_type"); types != "" {
+		for _, resourceType := range strings.Split(types, ",") {
+			export.Types = append(export.Types, strings.TrimSpace(resourceType))
+		}
+	}
+	if value := c.Query("_since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fields = append(fields, domain.FieldError{Field: "_since", Message: "must be an instant such as 2030-09-01T00:00:00Z"})
+		} else {
+			export.Since = &since
+		}
+	}
+	if len(fields) > 0 {
+		_ = c.Error(domain.NewValidationError(fields...))
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.exportUseCase.StartExport(c.Request.Context(), &export, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Location", fmt.Sprintf("%s/bulk-status/%d", fhirBaseURL(c), export.ID))
+	c.Status(http.StatusAccepted)
+}
+
+// ExportStatus answers a poll of the status URL: 202 with X-Progress
+// while the export runs, the manifest once it completed, or an
+// OperationOutcome if it failed.
+func (h *BulkExportHandler) ExportStatus(c *gin.Context) {
+	id, ok := parseID(c, "bulk export")
+	if !ok {
+		return
+	}
+
+	export, err := h.exportUseCase.GetExport(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	switch export.Status {
+	case domain.BulkExportCompleted:
+		manifest := fhir.BulkManifest{
+			TransactionTime: export.TransactionTime.UTC(),
+			Request:         export.Request,
+			Output:          []fhir.BulkOutput{},
+			Error:           []fhir.BulkOutput{},
+		}
+		for _, file := range export.Files {
+			expires, signature := h.exportUseCase.SignFile(export, file.Name)
+			query := url.Values{"expires": {strconv.FormatInt(expires.Unix(), 10)}, "signature": {signature}}
+			manifest.Output = append(manifest.Output, fhir.BulkOutput{
+				Type:  file.Type,
+				URL:   fmt.Sprintf("%s/bulk-files/%d/%s?%s", fhirBaseURL(c), export.ID, file.Name, query.Encode()),
+				Count: file.Count,
+			})
+		}
+		if export.ExpiresAt != nil {
+			c.Header("Expires", export.ExpiresAt.UTC().Format(http.TimeFormat))
+		}
+		c.JSON(http.StatusOK, manifest)
+	case domain.BulkExportFailed:
+		middleware.WriteProblem(c, http.StatusInternalServerError, "bulk_export_failed", "The export failed: "+export.Error, nil)
+	default:
+		c.Header("X-Progress", fmt.Sprintf("%d%% (%d of %d resources)", export.Progress(), export.Written, export.Total))
+		c.Header("Retry-After", strconv.Itoa(int(bulkRetryAfter/time.Second)))
+		c.Status(http.StatusAccepted)
+	}
+}
+
+// CancelExport stops an export, or deletes the files of a finished one.
+func (h *BulkExportHandler) CancelExport(c *gin.Context) {
+	id, ok := parseID(c, "bulk export")
+	if !ok {
+		return
+	}
+
+	if err := h.exportUseCase.CancelExport(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// DownloadFile serves an export file to anyone holding a valid signed link.
+func (h *BulkExportHandler) DownloadFile(c *gin.Context) {
+	id, ok := parseID(c, "bulk export")
+	if !ok {
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		_ = c.Error(domain.NewForbiddenError("the download link is not valid"))
+		return
+	}
+
+	path, err := h.exportUseCase.OpenFile(c.Request.Context(), id, c.Param("name"), expires, c.Query("signature"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", fhir.NDJSONContentType)
+	c.File(path)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+// internal/delivery/http/handler/claim_handler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRemittanceFileSize bounds uploaded 835 files.
+const maxRemittanceFileSize = 10 << 20
+
+type ClaimHandler struct {
+	claimUseCase usecase.ClaimUseCase
+}
+
+func NewClaimHandler(claimUseCase usecase.ClaimUseCase) *ClaimHandler {
+	return &ClaimHandler{claimUseCase: claimUseCase}
+}
+
+type claimRequest struct {
+	AppointmentID uint  `json:"appointment_id"`
+	PolicyID      *uint `json:"policy_id"`
+}
+
+type claimStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// CreateClaim claims a completed appointment from the patient's primary
+// insurance, or from policy_id when given.
+func (h *ClaimHandler) CreateClaim(c *gin.Context) {
+	var req claimRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.AppointmentID == 0 {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "appointment_id", Message: "is required"}))
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	claim, err := h.claimUseCase.CreateClaim(c.Request.Context(), req.AppointmentID, req.PolicyID, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, claim)
+}
+
+// ListClaims takes ?patient_id=, ?appointment_id= and ?status=
+// (comma-separated) filters, ?limit= and ?offset= page.
+func (h *ClaimHandler) ListClaims(c *gin.Context) {
+	var search domain.ClaimSearch
+	var ok bool
+	if search.PatientID, ok = queryID(c, "patient_id"); !ok {
+		return
+	}
+	if search.AppointmentID, ok = queryID(c, "appointment_id"); !ok {
+		return
+	}
+	if raw := c.Query("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if !contains(claimStatuses, status) {
+				_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "status", Message: "must be one of " + strings.Join(claimStatuses, ", ")}))
+				return
+			}
+			search.Statuses = append(search.Statuses, status)
+		}
+	}
+	search.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	search.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	claims, total, err := h.claimUseCase.ListClaims(c.Request.Context(), search)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"claims": claims, "total": total})
+}
+
+var claimStatuses = []string{
+	domain.ClaimReady, domain.ClaimSubmitted, domain.ClaimAccepted, domain.ClaimRejected,
+	domain.ClaimPaid, domain.ClaimDenied, domain.ClaimVoid,
+}
+
+func (h *ClaimHandler) GetClaim(c *gin.Context) {
+	id, ok := parseID(c, "claim")
+	if !ok {
+		return
+	}
+
+	claim, err := h.claimUseCase.GetClaim(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// SetClaimStatus records a status reported by the clearinghouse or payer.
+func (h *ClaimHandler) SetClaimStatus(c *gin.Context) {
+	id, ok := parseID(c, "claim")
+	if !ok {
+		return
+	}
+	var req claimStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !contains(claimStatuses, req.Status) {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "status", Message: "must be one of " + strings.Join(claimStatuses, ", ")}))
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	claim, err := h.claimUseCase.SetClaimStatus(c.Request.Context(), id, req.Status, req.Note, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// ExportClaims writes the ready claims to a new 837P file.
+func (h *ClaimHandler) ExportClaims(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+	file, err := h.claimUseCase.ExportClaims(c.Request.Context(), user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/claim-files/%d/download", file.ID))
+	c.JSON(http.StatusCreated, file)
+}
+
+func (h *ClaimHandler) ListClaimFiles(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	files, total, err := h.claimUseCase.ListClaimFiles(c.Request.Context(), limit, offset)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"claim_files": files, "total": total})
+}
+
+func (h *ClaimHandler) GetClaimFile(c *gin.Context) {
+	id, ok := parseID(c, "claim_file")
+	if !ok {
+		return
+	}
+
+	file, err := h.claimUseCase.GetClaimFile(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, file)
+}
+
+// DownloadClaimFile serves the 837P for upload to the clearinghouse.
+func (h *ClaimHandler) DownloadClaimFile(c *gin.Context) {
+	id, ok := parseID(c, "claim_file")
+	if !ok {
+		return
+	}
+
+	file, err := h.claimUseCase.GetClaimFile(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.FileName()))
+	c.Data(http.StatusOK, "application/edi-x12", []byte(file.Content))
+}
+
+// ImportRemittance takes a multipart upload of an 835 in "file" and posts it.
+func (h *ClaimHandler) ImportRemittance(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRemittanceFileSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "file", Message: fmt.Sprintf("must be at most %d MB", maxRemittanceFileSize>>20)}))
+			return
+		}
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "file", Message: "is required"}))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxRemittanceFileSize+1))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if len(data) > maxRemittanceFileSize {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "file", Message: fmt.Sprintf("must be at most %d MB", maxRemittanceFileSize>>20)}))
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	remittance, err := h.claimUseCase.ImportRemittance(c.Request.Context(), data, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, remittance)
+}
+
+func (h *ClaimHandler) ListRemittances(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	remittances, total, err := h.claimUseCase.ListRemittances(c.Request.Context(), limit, offset)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"remittances": remittances, "total": total})
+}
+
+func (h *ClaimHandler) GetRemittance(c *gin.Context) {
+	id, ok := parseID(c, "remittance")
+	if !ok {
+		return
+	}
+
+	remittance, err := h.claimUseCase.GetRemittance(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, remittance)
+}
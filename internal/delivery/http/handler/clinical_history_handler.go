@@ -0,0 +1,358 @@
+// internal/delivery/http/handler/clinical_history_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type ClinicalHistoryHandler struct {
+	historyUseCase usecase.ClinicalHistoryUseCase
+}
+
+func NewClinicalHistoryHandler(historyUseCase usecase.ClinicalHistoryUseCase) *ClinicalHistoryHandler {
+	return &ClinicalHistoryHandler{historyUseCase: historyUseCase}
+}
+
+// provenanceRequest holds the client-editable provenance of a history entry.
+type provenanceRequest struct {
+	Source      string `json:"source"`
+	EncounterID *uint  `json:"encounter_id"`
+}
+
+func (r provenanceRequest) provenance() domain.Provenance {
+	return domain.Provenance{Source: r.Source, EncounterID: r.EncounterID}
+}
+
+type allergyRequest struct {
+	Substance  string `json:"substance"`
+	Category   string `json:"category"`
+	Reaction   string `json:"reaction"`
+	Severity   string `json:"severity"`
+	Status     string `json:"status"`
+	OnsetDate  string `json:"onset_date"`
+	ResolvedOn string `json:"resolved_on"`
+	provenanceRequest
+}
+
+func (r allergyRequest) allergy() domain.Allergy {
+	return domain.Allergy{
+		Substance:  r.Substance,
+		Category:   r.Category,
+		Reaction:   r.Reaction,
+		Severity:   r.Severity,
+		Status:     r.Status,
+		OnsetDate:  r.OnsetDate,
+		ResolvedOn: r.ResolvedOn,
+		Provenance: r.provenance(),
+	}
+}
+
+type medicationRequest struct {
+	Name       string `json:"name"`
+	RxNormCode string `json:"rxnorm_code"`
+	Dose       string `json:"dose"`
+	Route      string `json:"route"`
+	Frequency  string `json:"frequency"`
+	Status     string `json:"status"`
+	OnsetDate  string `json:"onset_date"`
+	ResolvedOn string `json:"resolved_on"`
+	provenanceRequest
+}
+
+func (r medicationRequest) medication() domain.Medication {
+	return domain.Medication{
+		Name:       r.Name,
+		RxNormCode: r.RxNormCode,
+		Dose:       r.Dose,
+		Route:      r.Route,
+		Frequency:  r.Frequency,
+		Status:     r.Status,
+		OnsetDate:  r.OnsetDate,
+		ResolvedOn: r.ResolvedOn,
+		Provenance: r.provenance(),
+	}
+}
+
+type problemRequest struct {
+	Description string `json:"description"`
+	Code        string `json:"code"`
+	Status      string `json:"status"`
+	OnsetDate   string `json:"onset_date"`
+	ResolvedOn  string `json:"resolved_on"`
+	provenanceRequest
+}
+
+func (r problemRequest) problem() domain.Problem {
+	return domain.Problem{
+		Description: r.Description,
+		Code:        r.Code,
+		Status:      r.Status,
+		OnsetDate:   r.OnsetDate,
+		ResolvedOn:  r.ResolvedOn,
+		Provenance:  r.provenance(),
+	}
+}
+
+func (h *ClinicalHistoryHandler) CreateAllergy(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req allergyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	allergy := req.allergy()
+	allergy.PatientID = patientID
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.historyUseCase.CreateAllergy(c.Request.Context(), &allergy, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, allergy)
+}
+
+// ListAllergies returns the patient's allergies; ?status=active|resolved filters them.
+func (h *ClinicalHistoryHandler) ListAllergies(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	allergies, err := h.historyUseCase.ListAllergies(c.Request.Context(), patientID, c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"allergies": allergies, "total": len(allergies)})
+}
+
+// UpdateAllergy replaces an allergy (PUT). Omitted fields are cleared.
+func (h *ClinicalHistoryHandler) UpdateAllergy(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	allergyID, ok := parseIDParam(c, "allergyId", "allergy")
+	if !ok {
+		return
+	}
+
+	var req allergyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	allergy := req.allergy()
+	allergy.ID = allergyID
+
+	if err := h.historyUseCase.UpdateAllergy(c.Request.Context(), patientID, &allergy); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, allergy)
+}
+
+func (h *ClinicalHistoryHandler) DeleteAllergy(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	allergyID, ok := parseIDParam(c, "allergyId", "allergy")
+	if !ok {
+		return
+	}
+
+	if err := h.historyUseCase.DeleteAllergy(c.Request.Context(), patientID, allergyID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Allergy marked as entered in error"})
+}
+
+func (h *ClinicalHistoryHandler) CreateMedication(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req medicationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	medication := req.medication()
+	medication.PatientID = patientID
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.historyUseCase.CreateMedication(c.Request.Context(), &medication, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, medication)
+}
+
+func (h *ClinicalHistoryHandler) ListMedications(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	medications, err := h.historyUseCase.ListMedications(c.Request.Context(), patientID, c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"medications": medications, "total": len(medications)})
+}
+
+func (h *ClinicalHistoryHandler) UpdateMedication(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	medicationID, ok := parseIDParam(c, "medicationId", "medication")
+	if !ok {
+		return
+	}
+
+	var req medicationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	medication := req.medication()
+	medication.ID = medicationID
+
+	if err := h.historyUseCase.UpdateMedication(c.Request.Context(), patientID, &medication); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, medication)
+}
+
+func (h *ClinicalHistoryHandler) DeleteMedication(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	medicationID, ok := parseIDParam(c, "medicationId", "medication")
+	if !ok {
+		return
+	}
+
+	if err := h.historyUseCase.DeleteMedication(c.Request.Context(), patientID, medicationID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Medication marked as entered in error"})
+}
+
+func (h *ClinicalHistoryHandler) CreateProblem(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req problemRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	problem := req.problem()
+	problem.PatientID = patientID
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.historyUseCase.CreateProblem(c.Request.Context(), &problem, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, problem)
+}
+
+func (h *ClinicalHistoryHandler) ListProblems(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	problems, err := h.historyUseCase.ListProblems(c.Request.Context(), patientID, c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"problems": problems, "total": len(problems)})
+}
+
+func (h *ClinicalHistoryHandler) UpdateProblem(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	problemID, ok := parseIDParam(c, "problemId", "problem")
+	if !ok {
+		return
+	}
+
+	var req problemRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	problem := req.problem()
+	problem.ID = problemID
+
+	if err := h.historyUseCase.UpdateProblem(c.Request.Context(), patientID, &problem); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, problem)
+}
+
+func (h *ClinicalHistoryHandler) DeleteProblem(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	problemID, ok := parseIDParam(c, "problemId", "problem")
+	if !ok {
+		return
+	}
+
+	if err := h.historyUseCase.DeleteProblem(c.Request.Context(), patientID, problemID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Problem marked as entered in error"})
+}
+
+// GetSummary returns the patient's active allergies, medications and
+// problems with their last encounter.
+func (h *ClinicalHistoryHandler) GetSummary(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	summary, err := h.historyUseCase.GetSummary(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
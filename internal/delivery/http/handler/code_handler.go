@@ -0,0 +1,50 @@
+// internal/delivery/http/handler/code_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type CodeHandler struct {
+	codeCatalogUseCase usecase.CodeCatalogUseCase
+}
+
+func NewCodeHandler(codeCatalogUseCase usecase.CodeCatalogUseCase) *CodeHandler {
+	return &CodeHandler{codeCatalogUseCase: codeCatalogUseCase}
+}
+
+// SearchCodes serves autocomplete: GET /codes?system=ICD-10-CM&q=diab mell.
+func (h *CodeHandler) SearchCodes(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	codes, err := h.codeCatalogUseCase.SearchCodes(c.Request.Context(), domain.CodeSearch{
+		System:          c.Query("system"),
+		Query:           c.Query("q"),
+		IncludeInactive: c.Query("include_inactive") == "true",
+		BillableOnly:    c.Query("billable") == "true",
+	}, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"codes": codes, "total": len(codes)})
+}
+
+func (h *CodeHandler) GetCode(c *gin.Context) {
+	code, err := h.codeCatalogUseCase.GetCode(c.Request.Context(), c.Param("system"), c.Param("code"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, code)
+}
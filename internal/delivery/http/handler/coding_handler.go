@@ -0,0 +1,155 @@
+// internal/delivery/http/handler/coding_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// CodingHandler serves the diagnoses and procedures of a visit, addressed
+// either by appointment or by the encounter documenting it.
+type CodingHandler struct {
+	codingUseCase usecase.CodingUseCase
+	// appointmentID resolves the :id path parameter to an appointment.
+	appointmentID func(c *gin.Context) (uint, bool)
+}
+
+func NewAppointmentCodingHandler(codingUseCase usecase.CodingUseCase) *CodingHandler {
+	return &CodingHandler{
+		codingUseCase: codingUseCase,
+		appointmentID: func(c *gin.Context) (uint, bool) { return parseID(c, "appointment") },
+	}
+}
+
+func NewEncounterCodingHandler(codingUseCase usecase.CodingUseCase) *CodingHandler {
+	return &CodingHandler{
+		codingUseCase: codingUseCase,
+		appointmentID: func(c *gin.Context) (uint, bool) {
+			encounterID, ok := parseID(c, "encounter")
+			if !ok {
+				return 0, false
+			}
+			appointmentID, err := codingUseCase.EncounterAppointment(c.Request.Context(), encounterID)
+			if err != nil {
+				_ = c.Error(err)
+				return 0, false
+			}
+			return appointmentID, true
+		},
+	}
+}
+
+type diagnosisRequest struct {
+	Code string `json:"code"`
+	Rank int    `json:"rank"`
+}
+
+type procedureRequest struct {
+	System    string   `json:"system"`
+	Code      string   `json:"code"`
+	Modifiers []string `json:"modifiers"`
+	Units     int      `json:"units"`
+}
+
+func (h *CodingHandler) GetCoding(c *gin.Context) {
+	appointmentID, ok := h.appointmentID(c)
+	if !ok {
+		return
+	}
+
+	coding, err := h.codingUseCase.GetVisitCoding(c.Request.Context(), appointmentID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coding)
+}
+
+func (h *CodingHandler) AddDiagnosis(c *gin.Context) {
+	appointmentID, ok := h.appointmentID(c)
+	if !ok {
+		return
+	}
+
+	var req diagnosisRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	diagnosis := domain.Diagnosis{AppointmentID: appointmentID, Code: req.Code, Rank: req.Rank}
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.codingUseCase.AddDiagnosis(c.Request.Context(), &diagnosis, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, diagnosis)
+}
+
+func (h *CodingHandler) RemoveDiagnosis(c *gin.Context) {
+	appointmentID, ok := h.appointmentID(c)
+	if !ok {
+		return
+	}
+	diagnosisID, ok := parseIDParam(c, "diagnosisId", "diagnosis")
+	if !ok {
+		return
+	}
+
+	if err := h.codingUseCase.RemoveDiagnosis(c.Request.Context(), appointmentID, diagnosisID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Diagnosis removed successfully"})
+}
+
+func (h *CodingHandler) AddProcedure(c *gin.Context) {
+	appointmentID, ok := h.appointmentID(c)
+	if !ok {
+		return
+	}
+
+	var req procedureRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	procedure := domain.Procedure{
+		AppointmentID: appointmentID,
+		System:        req.System,
+		Code:          req.Code,
+		Modifiers:     req.Modifiers,
+		Units:         req.Units,
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.codingUseCase.AddProcedure(c.Request.Context(), &procedure, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, procedure)
+}
+
+func (h *CodingHandler) RemoveProcedure(c *gin.Context) {
+	appointmentID, ok := h.appointmentID(c)
+	if !ok {
+		return
+	}
+	procedureID, ok := parseIDParam(c, "procedureId", "procedure")
+	if !ok {
+		return
+	}
+
+	if err := h.codingUseCase.RemoveProcedure(c.Request.Context(), appointmentID, procedureID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Procedure removed successfully"})
+}
@@ -0,0 +1,44 @@
+// internal/delivery/http/handler/drug_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type DrugHandler struct {
+	drugCatalogUseCase usecase.DrugCatalogUseCase
+}
+
+func NewDrugHandler(drugCatalogUseCase usecase.DrugCatalogUseCase) *DrugHandler {
+	return &DrugHandler{drugCatalogUseCase: drugCatalogUseCase}
+}
+
+// SearchDrugs serves autocomplete: GET /drugs?q=amox.
+func (h *DrugHandler) SearchDrugs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	drugs, err := h.drugCatalogUseCase.SearchDrugs(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drugs": drugs, "total": len(drugs)})
+}
+
+func (h *DrugHandler) GetDrug(c *gin.Context) {
+	drug, err := h.drugCatalogUseCase.GetDrug(c.Request.Context(), c.Param("rxcui"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, drug)
+}
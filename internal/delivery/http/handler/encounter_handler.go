@@ -0,0 +1,180 @@
+// internal/delivery/http/handler/encounter_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type EncounterHandler struct {
+	encounterUseCase usecase.EncounterUseCase
+}
+
+func NewEncounterHandler(encounterUseCase usecase.EncounterUseCase) *EncounterHandler {
+	return &EncounterHandler{encounterUseCase: encounterUseCase}
+}
+
+// encounterRequest holds the client-editable SOAP sections of an encounter.
+type encounterRequest struct {
+	Subjective string `json:"subjective"`
+	Objective  string `json:"objective"`
+	Assessment string `json:"assessment"`
+	Plan       string `json:"plan"`
+}
+
+func (r encounterRequest) encounter() domain.Encounter {
+	return domain.Encounter{
+		Subjective: r.Subjective,
+		Objective:  r.Objective,
+		Assessment: r.Assessment,
+		Plan:       r.Plan,
+	}
+}
+
+type addendumRequest struct {
+	Text string `json:"text"`
+}
+
+func (h *EncounterHandler) CreateEncounter(c *gin.Context) {
+	appointmentID, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	var req encounterRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	encounter := req.encounter()
+	encounter.AppointmentID = appointmentID
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.encounterUseCase.CreateEncounter(c.Request.Context(), &encounter, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, encounter.Version)
+	c.JSON(http.StatusCreated, encounter)
+}
+
+func (h *EncounterHandler) GetAppointmentEncounter(c *gin.Context) {
+	appointmentID, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	encounter, err := h.encounterUseCase.GetAppointmentEncounter(c.Request.Context(), appointmentID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, encounter.Version)
+	c.JSON(http.StatusOK, encounter)
+}
+
+func (h *EncounterHandler) ListPatientEncounters(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	encounters, err := h.encounterUseCase.ListPatientEncounters(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"encounters": encounters, "total": len(encounters)})
+}
+
+func (h *EncounterHandler) GetEncounter(c *gin.Context) {
+	id, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+
+	encounter, err := h.encounterUseCase.GetEncounter(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, encounter.Version)
+	c.JSON(http.StatusOK, encounter)
+}
+
+// UpdateEncounter replaces the SOAP sections of a draft (PUT). Omitted
+// sections are cleared.
+func (h *EncounterHandler) UpdateEncounter(c *gin.Context) {
+	id, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req encounterRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	encounter := req.encounter()
+	encounter.ID = id
+
+	if err := h.encounterUseCase.UpdateEncounter(c.Request.Context(), &encounter, ifMatch); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, encounter.Version)
+	c.JSON(http.StatusOK, encounter)
+}
+
+func (h *EncounterHandler) SignEncounter(c *gin.Context) {
+	id, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	encounter, err := h.encounterUseCase.SignEncounter(c.Request.Context(), id, ifMatch, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, encounter.Version)
+	c.JSON(http.StatusOK, encounter)
+}
+
+func (h *EncounterHandler) AddAddendum(c *gin.Context) {
+	id, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+
+	var req addendumRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	addendum := domain.EncounterAddendum{EncounterID: id, Text: req.Text}
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.encounterUseCase.AddAddendum(c.Request.Context(), &addendum, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, addendum)
+}
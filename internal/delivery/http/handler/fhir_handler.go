@@ -0,0 +1,559 @@
+// internal/delivery/http/handler/fhir_handler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"doctors/pkg/fhir"
+	"github.com/gin-gonic/gin"
+)
+
+// FHIRHandler serves patients, doctors and appointments as FHIR R4
+// Patient, Practitioner, Appointment and Slot resources.
+type FHIRHandler struct {
+	patientUseCase     usecase.PatientUseCase
+	appointmentUseCase usecase.AppointmentUseCase
+	doctorUseCase      usecase.DoctorUseCase
+	startedAt          time.Time
+}
+
+func NewFHIRHandler(
+	patientUseCase usecase.PatientUseCase,
+	appointmentUseCase usecase.AppointmentUseCase,
+	doctorUseCase usecase.DoctorUseCase,
+) *FHIRHandler {
+	return &FHIRHandler{
+		patientUseCase:     patientUseCase,
+		appointmentUseCase: appointmentUseCase,
+		doctorUseCase:      doctorUseCase,
+		startedAt:          time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Metadata returns the CapabilityStatement.
+func (h *FHIRHandler) Metadata(c *gin.Context) {
+	reference := func(name, target string) fhir.CapabilitySearchParam {
+		return fhir.CapabilitySearchParam{Name: name, Type: "reference", Documentation: "A " + target + " reference or ID"}
+	}
+	date := func(name string) fhir.CapabilitySearchParam {
+		return fhir.CapabilitySearchParam{Name: name, Type: "date", Documentation: "Prefixes eq, gt, ge, lt, le, sa and eb; repeat for a range"}
+	}
+	paging := []fhir.CapabilitySearchParam{
+		{Name: "_count", Type: "number", Documentation: "Page size, at most 100"},
+		{Name: "_page", Type: "number", Documentation: "Page number, starting at 1"},
+	}
+
+	writeFHIR(c, http.StatusOK, fhir.CapabilityStatement{
+		ResourceType:   "CapabilityStatement",
+		Status:         "active",
+		Date:           h.startedAt,
+		Kind:           "instance",
+		Software:       &fhir.CapabilitySoftware{Name: "Doctor SaaS API"},
+		Implementation: &fhir.CapabilityImplementation{Description: "Doctor SaaS FHIR API", URL: fhirBaseURL(c)},
+		FHIRVersion:    fhir.Version,
+		Format:         []string{"json"},
+		Rest: []fhir.CapabilityRest{{
+			Mode:     "server",
+			Security: &fhir.CapabilitySecurity{Description: "Send an API key in the X-API-Key header."},
+			Resource: []fhir.CapabilityResource{
+				{
+					Type:        "Patient",
+					Interaction: fhir.Interactions("read", "search-type", "create"),
+					SearchParam: append([]fhir.CapabilitySearchParam{
+						{Name: "_id", Type: "token"},
+						{Name: "identifier", Type: "token", Documentation: "Medical record number, optionally as " + usecase.MRNSystem + "|<mrn>"},
+						{Name: "name", Type: "string", Documentation: "Fuzzy name match"},
+						{Name: "email", Type: "token"},
+						{Name: "phone", Type: "token", Documentation: "At least three digits of the number"},
+						{Name: "birthdate", Type: "date", Documentation: "A day, YYYY-MM-DD"},
+					}, paging[0]),
+				},
+				{
+					Type:        "Practitioner",
+					Interaction: fhir.Interactions("read", "search-type"),
+					SearchParam: []fhir.CapabilitySearchParam{
+						{Name: "_id", Type: "token"},
+						{Name: "name", Type: "string"},
+					},
+				},
+				{
+					Type:        "Appointment",
+					Interaction: fhir.Interactions("read", "search-type", "create"),
+					SearchParam: append([]fhir.CapabilitySearchParam{
+						reference("patient", "Patient"),
+						reference("practitioner", "Practitioner"),
+						date("date"),
+						{Name: "status", Type: "token"},
+					}, paging...),
+				},
+				{
+					Type:        "Slot",
+					Interaction: fhir.Interactions("read", "search-type"),
+					SearchParam: append([]fhir.CapabilitySearchParam{
+						reference("schedule", "Schedule"),
+						date("start"),
+						{Name: "status", Type: "token"},
+					}, paging...),
+				},
+			},
+		}},
+	})
+}
+
+func (h *FHIRHandler) ReadPatient(c *gin.Context) {
+	id, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	patient, err := h.patientUseCase.GetPatient(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setFHIRVersion(c, patient.Version)
+	writeFHIR(c, http.StatusOK, usecase.FHIRPatient(patient))
+}
+
+// SearchPatients finds patients by _id, identifier (MRN), name, email,
+// phone and birthdate. Without any of them it lists all patients.
+func (h *FHIRHandler) SearchPatients(c *gin.Context) {
+	ctx := c.Request.Context()
+	count, page, ok := fhirPaging(c)
+	if !ok {
+		return
+	}
+
+	if id := c.Query("_id"); id != "" {
+		var patients []domain.Patient
+		if n, err := strconv.ParseUint(id, 10, 32); err == nil {
+			patient, err := h.patientUseCase.GetPatient(ctx, uint(n))
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				_ = c.Error(err)
+				return
+			}
+			if patient != nil {
+				patients = append(patients, *patient)
+			}
+		}
+		h.writePatients(c, patients, len(patients), 1, count)
+		return
+	}
+
+	query := usecase.PatientQuery{Limit: count}
+	for _, param := range []string{"name", "email", "phone"} {
+		if value := c.Query(param); value != "" {
+			query.Query = value
+			break
+		}
+	}
+	if identifier := c.Query("identifier"); identifier != "" {
+		system, code := fhir.Token(identifier)
+		if system != "" && system != usecase.MRNSystem {
+			h.writePatients(c, nil, 0, 1, count)
+			return
+		}
+		query.MRN = code
+	}
+	if birthdate := c.Query("birthdate"); birthdate != "" {
+		query.DateOfBirth = strings.TrimPrefix(birthdate, "eq")
+	}
+
+	if query.Query == "" && query.MRN == "" && query.DateOfBirth == "" {
+		patients, total, err := h.patientUseCase.ListPatients(ctx, page, count, false)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		h.writePatients(c, patients, int(total), page, count)
+		return
+	}
+
+	matches, err := h.patientUseCase.SearchPatients(ctx, query)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	patients := make([]domain.Patient, len(matches))
+	for i, match := range matches {
+		patients[i] = match.Patient
+	}
+	h.writePatients(c, patients, len(patients), 1, count)
+}
+
+func (h *FHIRHandler) writePatients(c *gin.Context, patients []domain.Patient, total, page, count int) {
+	bundle := newFHIRSearchset(c, total, page, count)
+	for i := range patients {
+		resource := usecase.FHIRPatient(&patients[i])
+		bundle.Add(fhirResourceURL(c, "Patient", resource.ID), resource)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// CreatePatient creates a patient from a FHIR Patient. Elements the
+// practice doesn't record are dropped.
+func (h *FHIRHandler) CreatePatient(c *gin.Context) {
+	var resource fhir.Patient
+	if !bindJSON(c, &resource) {
+		return
+	}
+	if !checkResourceType(c, resource.ResourceType, "Patient") {
+		return
+	}
+	patient := usecase.PatientFromFHIR(resource)
+
+	if _, err := h.patientUseCase.CreatePatient(c.Request.Context(), &patient); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	created := usecase.FHIRPatient(&patient)
+	setFHIRVersion(c, patient.Version)
+	c.Header("Location", fmt.Sprintf("%s/_history/%d", fhirResourceURL(c, "Patient", created.ID), patient.Version))
+	writeFHIR(c, http.StatusCreated, created)
+}
+
+func (h *FHIRHandler) ReadPractitioner(c *gin.Context) {
+	id, ok := parseID(c, "practitioner")
+	if !ok {
+		return
+	}
+
+	doctor, err := h.doctorUseCase.GetDoctor(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	writeFHIR(c, http.StatusOK, usecase.FHIRPractitioner(doctor))
+}
+
+// SearchPractitioners finds doctors by _id or by a part of their name.
+func (h *FHIRHandler) SearchPractitioners(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var doctors []domain.Doctor
+	if id := c.Query("_id"); id != "" {
+		if n, err := strconv.ParseUint(id, 10, 32); err == nil {
+			doctor, err := h.doctorUseCase.GetDoctor(ctx, uint(n))
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				_ = c.Error(err)
+				return
+			}
+			if doctor != nil {
+				doctors = append(doctors, *doctor)
+			}
+		}
+	} else {
+		var err error
+		doctors, err = h.doctorUseCase.ListDoctors(ctx, c.Query("name"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
+	bundle := fhir.NewSearchset(fhirSelfURL(c), len(doctors))
+	for i := range doctors {
+		resource := usecase.FHIRPractitioner(&doctors[i])
+		bundle.Add(fhirResourceURL(c, "Practitioner", resource.ID), resource)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+func (h *FHIRHandler) ReadAppointment(c *gin.Context) {
+	appointment, ok := h.appointment(c)
+	if !ok {
+		return
+	}
+	setFHIRVersion(c, appointment.Version)
+	writeFHIR(c, http.StatusOK, usecase.FHIRAppointment(appointment))
+}
+
+// SearchAppointments finds appointments by patient, practitioner, date
+// and status, earliest first.
+func (h *FHIRHandler) SearchAppointments(c *gin.Context) {
+	search, page, count, ok := appointmentSearch(c, "date", usecase.AppointmentStatusesForFHIR)
+	if !ok {
+		return
+	}
+	if search.PatientID, ok = referenceParam(c, "patient", "Patient"); !ok {
+		return
+	}
+	if search.DoctorID, ok = referenceParam(c, "practitioner", "Practitioner"); !ok {
+		return
+	}
+
+	appointments, total, ok := h.searchAppointments(c, search)
+	if !ok {
+		return
+	}
+
+	bundle := newFHIRSearchset(c, total, page, count)
+	for i := range appointments {
+		resource := usecase.FHIRAppointment(&appointments[i])
+		bundle.Add(fhirResourceURL(c, "Appointment", resource.ID), resource)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// CreateAppointment books an appointment from a FHIR Appointment with a
+// Patient participant and a start. The practice's doctor is assigned as
+// for any other booking.
+func (h *FHIRHandler) CreateAppointment(c *gin.Context) {
+	var resource fhir.Appointment
+	if !bindJSON(c, &resource) {
+		return
+	}
+	if !checkResourceType(c, resource.ResourceType, "Appointment") {
+		return
+	}
+
+	var fields []domain.FieldError
+	if resource.Status != "" && resource.Status != fhir.AppointmentBooked && resource.Status != fhir.AppointmentProposed {
+		fields = append(fields, domain.FieldError{Field: "status", Message: "must be booked or proposed"})
+	}
+	if resource.Start == nil {
+		fields = append(fields, domain.FieldError{Field: "start", Message: "is required"})
+	}
+	var patientID uint
+	for _, participant := range resource.Participant {
+		if participant.Actor == nil || !strings.Contains(participant.Actor.Reference, "Patient/") {
+			continue
+		}
+		id, err := fhir.ReferenceID(participant.Actor.Reference, "Patient")
+		if err == nil {
+			n, err := strconv.ParseUint(id, 10, 32)
+			if err == nil {
+				patientID = uint(n)
+			}
+		}
+	}
+	if patientID == 0 {
+		fields = append(fields, domain.FieldError{Field: "participant", Message: "must include a Patient actor"})
+	}
+	if len(fields) > 0 {
+		_ = c.Error(domain.NewValidationError(fields...))
+		return
+	}
+
+	notes := resource.Comment
+	if notes == "" {
+		notes = resource.Description
+	}
+	appointment := domain.Appointment{PatientID: patientID, DateTime: *resource.Start, Notes: notes}
+	if err := h.appointmentUseCase.CreateAppointment(c.Request.Context(), &appointment); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	created := usecase.FHIRAppointment(&appointment)
+	setFHIRVersion(c, appointment.Version)
+	c.Header("Location", fmt.Sprintf("%s/_history/%d", fhirResourceURL(c, "Appointment", created.ID), appointment.Version))
+	writeFHIR(c, http.StatusCreated, created)
+}
+
+// ReadSlot returns the slot of the appointment with the same ID.
+func (h *FHIRHandler) ReadSlot(c *gin.Context) {
+	appointment, ok := h.appointment(c)
+	if !ok {
+		return
+	}
+	setFHIRVersion(c, appointment.Version)
+	writeFHIR(c, http.StatusOK, usecase.FHIRSlot(appointment))
+}
+
+// SearchSlots finds slots by schedule (one per doctor, with the doctor's
+// ID), start and status.
+func (h *FHIRHandler) SearchSlots(c *gin.Context) {
+	search, page, count, ok := appointmentSearch(c, "start", usecase.SlotStatusesForFHIR)
+	if !ok {
+		return
+	}
+	if search.DoctorID, ok = referenceParam(c, "schedule", "Schedule"); !ok {
+		return
+	}
+
+	appointments, total, ok := h.searchAppointments(c, search)
+	if !ok {
+		return
+	}
+
+	bundle := newFHIRSearchset(c, total, page, count)
+	for i := range appointments {
+		resource := usecase.FHIRSlot(&appointments[i])
+		bundle.Add(fhirResourceURL(c, "Slot", resource.ID), resource)
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+func (h *FHIRHandler) appointment(c *gin.Context) (*domain.Appointment, bool) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return nil, false
+	}
+	appointment, err := h.appointmentUseCase.GetAppointment(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, false
+	}
+	return appointment, true
+}
+
+func (h *FHIRHandler) searchAppointments(c *gin.Context, search *domain.AppointmentSearch) ([]domain.Appointment, int, bool) {
+	// A status that no appointment can have matches nothing.
+	if search.Statuses != nil && len(search.Statuses) == 0 {
+		return nil, 0, true
+	}
+	appointments, total, err := h.appointmentUseCase.SearchAppointments(c.Request.Context(), *search)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, 0, false
+	}
+	return appointments, int(total), true
+}
+
+// appointmentSearch reads the paging, the date parameter and the status
+// parameter shared by Appointment and Slot searches. Statuses is non-nil
+// but empty when the requested statuses match no appointment.
+func appointmentSearch(c *gin.Context, dateParam string, statuses func(string) []string) (*domain.AppointmentSearch, int, int, bool) {
+	count, page, ok := fhirPaging(c)
+	if !ok {
+		return nil, 0, 0, false
+	}
+	search := &domain.AppointmentSearch{Limit: count, Offset: (page - 1) * count}
+
+	if values := c.QueryArray(dateParam); len(values) > 0 {
+		from, to, err := fhir.DateRange(values)
+		if err != nil {
+			_ = c.Error(invalidSearchParam(dateParam, err))
+			return nil, 0, 0, false
+		}
+		search.From, search.To = from, to
+	}
+
+	if value := c.Query("status"); value != "" {
+		search.Statuses = []string{}
+		for _, status := range strings.Split(value, ",") {
+			search.Statuses = append(search.Statuses, statuses(strings.TrimSpace(status))...)
+		}
+	}
+	return search, page, count, true
+}
+
+// referenceParam reads a reference search parameter to a numeric ID. It
+// returns nil when the parameter is absent.
+func referenceParam(c *gin.Context, param, resourceType string) (*uint, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	id, err := fhir.ReferenceID(value, resourceType)
+	if err != nil {
+		_ = c.Error(invalidSearchParam(param, err))
+		return nil, false
+	}
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		_ = c.Error(invalidSearchParam(param, fmt.Errorf("%q is not a %s ID", id, resourceType)))
+		return nil, false
+	}
+	v := uint(n)
+	return &v, true
+}
+
+// fhirPaging reads _count and _page.
+func fhirPaging(c *gin.Context) (count, page int, ok bool) {
+	count, page = 10, 1
+	if value := c.Query("_count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			_ = c.Error(invalidSearchParam("_count", errors.New("must be a positive number")))
+			return 0, 0, false
+		}
+		count = n
+	}
+	if count > maxPageSize {
+		count = maxPageSize
+	}
+	if value := c.Query("_page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			_ = c.Error(invalidSearchParam("_page", errors.New("must be a positive number")))
+			return 0, 0, false
+		}
+		page = n
+	}
+	return count, page, true
+}
+
+func invalidSearchParam(param string, err error) error {
+	return domain.NewValidationError(domain.FieldError{Field: param, Message: strings.TrimPrefix(err.Error(), fhir.ErrInvalidParam.Error()+": ")})
+}
+
+func checkResourceType(c *gin.Context, got, want string) bool {
+	if got == want {
+		return true
+	}
+	_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "resourceType", Message: "must be " + want}))
+	return false
+}
+
+// newFHIRSearchset returns a search bundle with a "next" link when there
+// are matches after this page.
+func newFHIRSearchset(c *gin.Context, total, page, count int) *fhir.Bundle {
+	bundle := fhir.NewSearchset(fhirSelfURL(c), total)
+	if page*count < total {
+		query := c.Request.URL.Query()
+		query.Set("_page", strconv.Itoa(page+1))
+		query.Set("_count", strconv.Itoa(count))
+		next := *c.Request.URL
+		next.RawQuery = query.Encode()
+		bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "next", URL: fhirOrigin(c) + next.RequestURI()})
+	}
+	return bundle
+}
+
+// fhirOrigin is the scheme and host the client used, honoring a proxy's
+// X-Forwarded-Proto.
+func fhirOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func fhirBaseURL(c *gin.Context) string {
+	return fhirOrigin(c) + middleware.FHIRBasePath
+}
+
+func fhirSelfURL(c *gin.Context) string {
+	return fhirOrigin(c) + c.Request.URL.RequestURI()
+}
+
+func fhirResourceURL(c *gin.Context, resourceType, id string) string {
+	return fhirBaseURL(c) + "/" + resourceType + "/" + id
+}
+
+// setFHIRVersion exposes a resource version as a weak ETag, as FHIR does.
+func setFHIRVersion(c *gin.Context, version uint) {
+	c.Header("ETag", fmt.Sprintf(`W/"%d"`, version))
+}
+
+func writeFHIR(c *gin.Context, status int, resource interface{}) {
+	c.Header("Content-Type", fhir.ContentType)
+	c.JSON(status, resource)
+}
@@ -0,0 +1,178 @@
+// internal/delivery/http/handler/insurance_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type InsuranceHandler struct {
+	insuranceUseCase usecase.InsuranceUseCase
+}
+
+func NewInsuranceHandler(insuranceUseCase usecase.InsuranceUseCase) *InsuranceHandler {
+	return &InsuranceHandler{insuranceUseCase: insuranceUseCase}
+}
+
+// policyRequest holds the client-editable fields of an insurance policy.
+type policyRequest struct {
+	PayerName              string `json:"payer_name"`
+	PayerID                string `json:"payer_id"`
+	MemberID               string `json:"member_id"`
+	GroupNumber            string `json:"group_number"`
+	SubscriberRelationship string `json:"subscriber_relationship"`
+	SubscriberName         string `json:"subscriber_name"`
+	SubscriberDateOfBirth  string `json:"subscriber_date_of_birth"`
+	EffectiveFrom          string `json:"effective_from"`
+	EffectiveTo            string `json:"effective_to"`
+	Priority               string `json:"priority"`
+}
+
+func (r policyRequest) policy() domain.InsurancePolicy {
+	return domain.InsurancePolicy{
+		PayerName:              r.PayerName,
+		PayerID:                r.PayerID,
+		MemberID:               r.MemberID,
+		GroupNumber:            r.GroupNumber,
+		SubscriberRelationship: r.SubscriberRelationship,
+		SubscriberName:         r.SubscriberName,
+		SubscriberDateOfBirth:  r.SubscriberDateOfBirth,
+		EffectiveFrom:          r.EffectiveFrom,
+		EffectiveTo:            r.EffectiveTo,
+		Priority:               r.Priority,
+	}
+}
+
+type eligibilityRequest struct {
+	ServiceDate string `json:"service_date"`
+}
+
+func (h *InsuranceHandler) CreatePolicy(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req policyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	policy := req.policy()
+	policy.PatientID = patientID
+
+	if err := h.insuranceUseCase.CreatePolicy(c.Request.Context(), &policy); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+func (h *InsuranceHandler) ListPolicies(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	policies, err := h.insuranceUseCase.ListPolicies(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies, "total": len(policies)})
+}
+
+// UpdatePolicy replaces a policy (PUT). Omitted fields are cleared.
+func (h *InsuranceHandler) UpdatePolicy(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	policyID, ok := parseIDParam(c, "policyId", "insurance_policy")
+	if !ok {
+		return
+	}
+
+	var req policyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	policy := req.policy()
+	policy.ID = policyID
+
+	if err := h.insuranceUseCase.UpdatePolicy(c.Request.Context(), patientID, &policy); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *InsuranceHandler) DeletePolicy(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	policyID, ok := parseIDParam(c, "policyId", "insurance_policy")
+	if !ok {
+		return
+	}
+
+	if err := h.insuranceUseCase.DeletePolicy(c.Request.Context(), patientID, policyID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Insurance policy deleted successfully"})
+}
+
+// CheckEligibility sends an eligibility inquiry for a policy. The body is optional.
+func (h *InsuranceHandler) CheckEligibility(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	policyID, ok := parseIDParam(c, "policyId", "insurance_policy")
+	if !ok {
+		return
+	}
+
+	var req eligibilityRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	check, err := h.insuranceUseCase.CheckEligibility(c.Request.Context(), patientID, policyID, req.ServiceDate, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, check)
+}
+
+func (h *InsuranceHandler) ListEligibilityChecks(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	checks, err := h.insuranceUseCase.ListEligibilityChecks(c.Request.Context(), patientID, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"eligibility_checks": checks, "total": len(checks)})
+}
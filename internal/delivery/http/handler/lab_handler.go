@@ -0,0 +1,118 @@
+// internal/delivery/http/handler/lab_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type LabHandler struct {
+	labUseCase usecase.LabUseCase
+}
+
+func NewLabHandler(labUseCase usecase.LabUseCase) *LabHandler {
+	return &LabHandler{labUseCase: labUseCase}
+}
+
+type labOrderRequest struct {
+	Tests        []domain.LabTest `json:"tests"`
+	Priority     string           `json:"priority"`
+	ClinicalInfo string           `json:"clinical_info"`
+}
+
+func (r labOrderRequest) order(appointmentID uint) domain.LabOrder {
+	return domain.LabOrder{
+		AppointmentID: appointmentID,
+		Tests:         r.Tests,
+		Priority:      r.Priority,
+		ClinicalInfo:  r.ClinicalInfo,
+	}
+}
+
+// CreateLabOrder orders tests for an appointment. The order is created
+// even when the laboratory can't be reached; its status says so.
+func (h *LabHandler) CreateLabOrder(c *gin.Context) {
+	appointmentID, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	var req labOrderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	order := req.order(appointmentID)
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.labUseCase.CreateOrder(c.Request.Context(), &order, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+func (h *LabHandler) ListAppointmentLabOrders(c *gin.Context) {
+	appointmentID, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	orders, err := h.labUseCase.ListAppointmentOrders(c.Request.Context(), appointmentID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lab_orders": orders, "total": len(orders)})
+}
+
+func (h *LabHandler) ListPatientLabOrders(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	orders, err := h.labUseCase.ListPatientOrders(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lab_orders": orders, "total": len(orders)})
+}
+
+func (h *LabHandler) GetLabOrder(c *gin.Context) {
+	id, ok := parseID(c, "lab order")
+	if !ok {
+		return
+	}
+
+	order, err := h.labUseCase.GetOrder(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// SendLabOrder sends a pending or failed order to the laboratory again.
+func (h *LabHandler) SendLabOrder(c *gin.Context) {
+	id, ok := parseID(c, "lab order")
+	if !ok {
+		return
+	}
+
+	order, err := h.labUseCase.SendOrder(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// maxPageSize caps page_size so a single request can't pull the whole table.
+const maxPageSize = 100
+
 type PatientHandler struct {
 	patientUseCase usecase.PatientUseCase
 }
@@ -22,66 +25,98 @@ func NewPatientHandler(patientUseCase usecase.PatientUseCase) *PatientHandler {
 
 func (h *PatientHandler) CreatePatient(c *gin.Context) {
 	var patient domain.Patient
-	if err := c.ShouldBindJSON(&patient); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &patient) {
 		return
 	}
 
-	if err := h.patientUseCase.CreatePatient(c.Request.Context(), &patient); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create patient"})
+	duplicates, err := h.patientUseCase.CreatePatient(c.Request.Context(), &patient)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, patient)
+	setETag(c, patient.Version)
+	c.JSON(http.StatusCreated, struct {
+		domain.Patient
+		PossibleDuplicates []domain.DuplicateMatch `json:"possible_duplicates,omitempty"`
+	}{patient, duplicates})
 }
 
 func (h *PatientHandler) GetPatient(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+	id, ok := parseID(c, "patient")
+	if !ok {
 		return
 	}
 
-	patient, err := h.patientUseCase.GetPatient(c.Request.Context(), uint(id))
+	patient, err := h.patientUseCase.GetPatient(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		_ = c.Error(err)
 		return
 	}
 
+	setETag(c, patient.Version)
 	c.JSON(http.StatusOK, patient)
 }
 
+// UpdatePatient replaces a patient (PUT). Omitted fields are cleared.
 func (h *PatientHandler) UpdatePatient(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+	id, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
 		return
 	}
 
 	var patient domain.Patient
-	if err := c.ShouldBindJSON(&patient); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &patient) {
 		return
 	}
-	patient.ID = uint(id)
+	patient.ID = id
 
-	if err := h.patientUseCase.UpdatePatient(c.Request.Context(), &patient); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update patient"})
+	if err := h.patientUseCase.UpdatePatient(c.Request.Context(), &patient, ifMatch); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
+	setETag(c, patient.Version)
 	c.JSON(http.StatusOK, patient)
 }
 
-func (h *PatientHandler) DeletePatient(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+// PatchPatient applies a JSON Merge Patch; omitted fields are left unchanged.
+func (h *PatientHandler) PatchPatient(c *gin.Context) {
+	id, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	ifMatch, ok := parseIfMatch(c)
+	if !ok {
+		return
+	}
+	patch, ok := readPatch(c)
+	if !ok {
+		return
+	}
+
+	patient, err := h.patientUseCase.PatchPatient(c.Request.Context(), id, patch, ifMatch)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, patient.Version)
+	c.JSON(http.StatusOK, patient)
+}
+
+func (h *PatientHandler) DeletePatient(c *gin.Context) {
+	id, ok := parseID(c, "patient")
+	if !ok {
 		return
 	}
 
-	if err := h.patientUseCase.DeletePatient(c.Request.Context(), uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete patient"})
+	if err := h.patientUseCase.DeletePatient(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
@@ -89,12 +124,38 @@ func (h *PatientHandler) DeletePatient(c *gin.Context) {
 }
 
 func (h *PatientHandler) ListPatients(c *gin.Context) {
+	if c.Query("q") != "" || c.Query("mrn") != "" || c.Query("dob") != "" {
+		h.searchPatients(c)
+		return
+	}
+
+	// Emails are encrypted at rest, so lookups go through the blind index.
+	if email := c.Query("email"); email != "" {
+		patients, err := h.patientUseCase.FindPatientsByEmail(c.Request.Context(), email)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"patients": patients, "total": len(patients)})
+		return
+	}
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
 
-	patients, totalCount, err := h.patientUseCase.ListPatients(c.Request.Context(), page, pageSize)
+	includeArchived := c.Query("include_archived") == "true"
+	patients, totalCount, err := h.patientUseCase.ListPatients(c.Request.Context(), page, pageSize, includeArchived)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list patients"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -105,3 +166,38 @@ func (h *PatientHandler) ListPatients(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+// searchPatients serves GET /patients?q=...&mrn=...&dob=YYYY-MM-DD, ranked by relevance.
+func (h *PatientHandler) searchPatients(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	matches, err := h.patientUseCase.SearchPatients(c.Request.Context(), usecase.PatientQuery{
+		Query:           c.Query("q"),
+		MRN:             c.Query("mrn"),
+		DateOfBirth:     c.Query("dob"),
+		IncludeArchived: c.Query("include_archived") == "true",
+		Limit:           limit,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": matches, "total": len(matches)})
+}
+
+// RestorePatient brings back an archived patient (admin only).
+func (h *PatientHandler) RestorePatient(c *gin.Context) {
+	id, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	patient, err := h.patientUseCase.RestorePatient(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, patient.Version)
+	c.JSON(http.StatusOK, patient)
+}
@@ -0,0 +1,249 @@
+// internal/delivery/http/handler/patient_import_handler.go
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"doctors/pkg/xlsx"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize bounds an uploaded import file.
+const maxImportFileSize = 20 << 20
+
+// ImportPatients starts an import of a CSV or XLSX file of patients
+// (admin only). The multipart form holds the file and optionally columns
+// (a JSON object of header to patient field), dry_run, on_duplicate and
+// date_format. The import runs in the background; poll its Location.
+func (h *PatientHandler) ImportPatients(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "file", Message: fmt.Sprintf("must be at most %d MB", maxImportFileSize>>20)}))
+			return
+		}
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "file", Message: "is required"}))
+		return
+	}
+
+	var fields []domain.FieldError
+	opts := usecase.PatientImportOptions{
+		FileName:    filepath.Base(header.Filename),
+		OnDuplicate: c.PostForm("on_duplicate"),
+		DateFormat:  c.PostForm("date_format"),
+	}
+	if raw := c.PostForm("dry_run"); raw != "" {
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			fields = append(fields, domain.FieldError{Field: "dry_run", Message: "must be true or false"})
+		}
+	}
+	if raw := c.PostForm("columns"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Columns); err != nil {
+			fields = append(fields, domain.FieldError{Field: "columns", Message: "must be a JSON object of column header to patient field"})
+		}
+	}
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	if format != "csv" && format != "xlsx" {
+		fields = append(fields, domain.FieldError{Field: "format", Message: "must be csv or xlsx"})
+	}
+	if len(fields) > 0 {
+		_ = c.Error(domain.NewValidationError(fields...))
+		return
+	}
+
+	table, err := readImportFile(header, format)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	imp, err := h.patientUseCase.StartImport(c.Request.Context(), table, opts, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/admin/patient-imports/%d", imp.ID))
+	c.JSON(http.StatusAccepted, imp)
+}
+
+// readImportFile reads an uploaded file into rows of cells.
+func readImportFile(header *multipart.FileHeader, format string) ([][]string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportFileSize {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "file", Message: fmt.Sprintf("must be at most %d MB", maxImportFileSize>>20)})
+	}
+
+	if format == "xlsx" {
+		table, err := xlsx.Read(data)
+		if err != nil {
+			return nil, domain.NewValidationError(domain.FieldError{Field: "file", Message: err.Error()})
+		}
+		return table, nil
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	// Spreadsheets set to European locales save CSV with semicolons.
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	table, err := reader.ReadAll()
+	if err != nil {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "file", Message: "is not valid CSV: " + err.Error()})
+	}
+	return table, nil
+}
+
+// GetImport reports the progress or outcome of a patient import (admin only).
+func (h *PatientHandler) GetImport(c *gin.Context) {
+	id, ok := parseID(c, "patient_import")
+	if !ok {
+		return
+	}
+
+	imp, err := h.patientUseCase.GetImport(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, imp)
+}
+
+// ListImports lists patient imports, most recent first (admin only).
+func (h *PatientHandler) ListImports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		pageSize = 10
+	}
+
+	imports, total, err := h.patientUseCase.ListImports(c.Request.Context(), page, pageSize)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imports": imports, "total": total, "page": page, "page_size": pageSize})
+}
+
+// ExportPatients streams active patients as CSV or XLSX (admin only):
+// ?format=csv|xlsx&columns=mrn,name,... chooses the file type and columns.
+func (h *PatientHandler) ExportPatients(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "format", Message: "must be csv or xlsx"}))
+		return
+	}
+	var columns []string
+	if raw := c.Query("columns"); raw != "" {
+		for _, column := range strings.Split(raw, ",") {
+			columns = append(columns, strings.TrimSpace(column))
+		}
+	}
+
+	// Headers go out with the first row, so a bad column still gets a
+	// proper error response.
+	var writeRow func(row []string) error
+	var finish func() error
+	started := false
+	start := func() error {
+		started = true
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"patients.%s\"", format))
+		if format == "xlsx" {
+			c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			c.Status(http.StatusOK)
+			w, err := xlsx.NewWriter(c.Writer, "Patients")
+			if err != nil {
+				return err
+			}
+			writeRow, finish = w.WriteRow, w.Close
+			return nil
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		writeRow = func(row []string) error {
+			for i := range row {
+				row[i] = csvSafe(row[i])
+			}
+			return w.Write(row)
+		}
+		finish = func() error {
+			w.Flush()
+			return w.Error()
+		}
+		return nil
+	}
+
+	err := h.patientUseCase.ExportPatients(c.Request.Context(), columns, func(row []string) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writeRow(row)
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		if !started {
+			_ = c.Error(err)
+			return
+		}
+		// The response is under way; all that is left is to cut it short.
+		fmt.Printf("Failed to export patients: %v\n", err)
+	}
+}
+
+// csvSafe keeps spreadsheet programs from reading a cell as a formula.
+// Phone numbers such as "+15551234567" are left alone.
+func csvSafe(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + value
+	case '+', '-':
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "'" + value
+		}
+	}
+	return value
+}
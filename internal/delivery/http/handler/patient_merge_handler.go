@@ -0,0 +1,100 @@
+// internal/delivery/http/handler/patient_merge_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type mergeRequest struct {
+	DuplicateID uint `json:"duplicate_id"`
+}
+
+// ListDuplicates reports probable duplicate patients (admin only).
+func (h *PatientHandler) ListDuplicates(c *gin.Context) {
+	minScore, err := strconv.ParseFloat(c.DefaultQuery("min_score", "0.6"), 64)
+	if err != nil || minScore < 0 || minScore > 1 {
+		_ = c.Error(domain.NewBadRequestError("invalid_min_score", "min_score must be a number between 0 and 1"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	pairs, err := h.patientUseCase.FindDuplicatePairs(c.Request.Context(), minScore, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": pairs, "total": len(pairs)})
+}
+
+// MergePatient merges the patient in the body into the one in the path (admin only).
+func (h *PatientHandler) MergePatient(c *gin.Context) {
+	id, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req mergeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.DuplicateID == 0 {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "duplicate_id", Message: "is required"}))
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	merge, err := h.patientUseCase.MergePatients(c.Request.Context(), id, req.DuplicateID, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, merge)
+}
+
+// ListMerges lists merges, optionally only those involving ?patient_id= (admin only).
+func (h *PatientHandler) ListMerges(c *gin.Context) {
+	var patientID uint
+	if raw := c.Query("patient_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			_ = c.Error(domain.NewBadRequestError("invalid_patient_id", "Invalid patient ID"))
+			return
+		}
+		patientID = uint(id)
+	}
+
+	merges, err := h.patientUseCase.ListMerges(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"merges": merges, "total": len(merges)})
+}
+
+// RevertMerge undoes a merge (admin only).
+func (h *PatientHandler) RevertMerge(c *gin.Context) {
+	id, ok := parseID(c, "patient_merge")
+	if !ok {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	merge, err := h.patientUseCase.RevertMerge(c.Request.Context(), id, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, merge)
+}
@@ -0,0 +1,185 @@
+// internal/delivery/http/handler/payment_handler.go
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"doctors/pkg/gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookSize bounds a webhook delivery.
+const maxWebhookSize = 1 << 20
+
+type PaymentHandler struct {
+	paymentUseCase usecase.PaymentUseCase
+}
+
+func NewPaymentHandler(paymentUseCase usecase.PaymentUseCase) *PaymentHandler {
+	return &PaymentHandler{paymentUseCase: paymentUseCase}
+}
+
+type paymentIntentRequest struct {
+	AmountCents int64 `json:"amount_cents"`
+}
+
+// idempotencyKey reads the Idempotency-Key header clients send so that a
+// retried request doesn't charge twice.
+func idempotencyKey(c *gin.Context) (string, bool) {
+	key := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+	if len(key) > 255 {
+		_ = c.Error(domain.NewBadRequestError("invalid_idempotency_key", "Idempotency-Key must be at most 255 characters"))
+		return "", false
+	}
+	return key, true
+}
+
+// PayInvoice starts a card payment of an invoice. The intent's client
+// secret is handed to the provider's checkout.
+func (h *PaymentHandler) PayInvoice(c *gin.Context) {
+	id, ok := parseID(c, "invoice")
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(c)
+	if !ok {
+		return
+	}
+
+	var req paymentIntentRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	intent, err := h.paymentUseCase.PayInvoice(c.Request.Context(), id, req.AmountCents, key, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, intent)
+}
+
+// PayDeposit starts a card payment of a deposit for an appointment.
+func (h *PaymentHandler) PayDeposit(c *gin.Context) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(c)
+	if !ok {
+		return
+	}
+
+	var req paymentIntentRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	intent, err := h.paymentUseCase.PayDeposit(c.Request.Context(), id, req.AmountCents, key, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, intent)
+}
+
+// ListIntents lists payment intents, newest first: ?patient_id=,
+// ?invoice_id= and ?appointment_id= filter, ?limit= and ?offset= page.
+func (h *PaymentHandler) ListIntents(c *gin.Context) {
+	var search domain.PaymentIntentSearch
+	var ok bool
+	if search.PatientID, ok = queryID(c, "patient_id"); !ok {
+		return
+	}
+	if search.InvoiceID, ok = queryID(c, "invoice_id"); !ok {
+		return
+	}
+	if search.AppointmentID, ok = queryID(c, "appointment_id"); !ok {
+		return
+	}
+	search.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	search.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	intents, total, err := h.paymentUseCase.ListIntents(c.Request.Context(), search)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment_intents": intents, "total": total})
+}
+
+// queryID reads an optional numeric query parameter, recording a domain
+// error when invalid.
+func queryID(c *gin.Context, param string) (*uint, bool) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: param, Message: "must be an ID"}))
+		return nil, false
+	}
+	value := uint(id)
+	return &value, true
+}
+
+func (h *PaymentHandler) GetIntent(c *gin.Context) {
+	id, ok := parseID(c, "payment_intent")
+	if !ok {
+		return
+	}
+
+	intent, err := h.paymentUseCase.GetIntent(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, intent)
+}
+
+func (h *PaymentHandler) CancelIntent(c *gin.Context) {
+	id, ok := parseID(c, "payment_intent")
+	if !ok {
+		return
+	}
+
+	intent, err := h.paymentUseCase.CancelIntent(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, intent)
+}
+
+// Webhook receives the payment provider's events. It is public; the
+// signature proves the sender. An error response makes the provider
+// deliver the event again later.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		_ = c.Error(domain.NewBadRequestError("invalid_event", "The webhook body could not be read"))
+		return
+	}
+
+	err = h.paymentUseCase.HandleWebhook(c.Request.Context(), payload, c.GetHeader(gateway.SignatureHeader))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
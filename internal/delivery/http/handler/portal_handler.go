@@ -0,0 +1,122 @@
+// internal/delivery/http/handler/portal_handler.go
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// PortalHandler serves patient portal users. Every route acts as the
+// calling user, so it must sit behind RequireRole(domain.RolePatient).
+type PortalHandler struct {
+	portalUseCase usecase.PortalUseCase
+}
+
+func NewPortalHandler(portalUseCase usecase.PortalUseCase) *PortalHandler {
+	return &PortalHandler{portalUseCase: portalUseCase}
+}
+
+// portalBookingRequest books for the caller, or for a dependent when
+// patient_id is set.
+type portalBookingRequest struct {
+	PatientID uint      `json:"patient_id"`
+	DateTime  time.Time `json:"date_time"`
+	Notes     string    `json:"notes"`
+}
+
+type rescheduleRequest struct {
+	DateTime time.Time `json:"date_time"`
+}
+
+// GetAccount returns the caller's patient record and their dependents.
+func (h *PortalHandler) GetAccount(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+	account, err := h.portalUseCase.GetAccount(c.Request.Context(), user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *PortalHandler) ListAppointments(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+	appointments, err := h.portalUseCase.ListAppointments(c.Request.Context(), user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appointments": appointments, "total": len(appointments)})
+}
+
+func (h *PortalHandler) BookAppointment(c *gin.Context) {
+	var req portalBookingRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	appointment := domain.Appointment{
+		PatientID: req.PatientID,
+		DateTime:  req.DateTime,
+		Notes:     req.Notes,
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.portalUseCase.BookAppointment(c.Request.Context(), user, &appointment); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, appointment.Version)
+	c.JSON(http.StatusCreated, appointment)
+}
+
+func (h *PortalHandler) CancelAppointment(c *gin.Context) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	appointment, err := h.portalUseCase.CancelAppointment(c.Request.Context(), user, id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, appointment.Version)
+	c.JSON(http.StatusOK, appointment)
+}
+
+func (h *PortalHandler) RescheduleAppointment(c *gin.Context) {
+	id, ok := parseID(c, "appointment")
+	if !ok {
+		return
+	}
+
+	var req rescheduleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.DateTime.IsZero() {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{Field: "date_time", Message: "is required"}))
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	appointment, err := h.portalUseCase.RescheduleAppointment(c.Request.Context(), user, id, req.DateTime)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setETag(c, appointment.Version)
+	c.JSON(http.StatusOK, appointment)
+}
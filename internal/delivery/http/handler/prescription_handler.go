@@ -0,0 +1,173 @@
+// internal/delivery/http/handler/prescription_handler.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type PrescriptionHandler struct {
+	prescriptionUseCase usecase.PrescriptionUseCase
+}
+
+func NewPrescriptionHandler(prescriptionUseCase usecase.PrescriptionUseCase) *PrescriptionHandler {
+	return &PrescriptionHandler{prescriptionUseCase: prescriptionUseCase}
+}
+
+type prescriptionRequest struct {
+	RxCUI          string `json:"rxcui"`
+	Dosage         string `json:"dosage"`
+	Quantity       int    `json:"quantity"`
+	QuantityUnit   string `json:"quantity_unit"`
+	Refills        int    `json:"refills"`
+	Instructions   string `json:"instructions"`
+	OverrideReason string `json:"override_reason"`
+}
+
+func (r prescriptionRequest) prescription(encounterID uint) domain.Prescription {
+	return domain.Prescription{
+		EncounterID:    encounterID,
+		RxCUI:          r.RxCUI,
+		Dosage:         r.Dosage,
+		Quantity:       r.Quantity,
+		QuantityUnit:   r.QuantityUnit,
+		Refills:        r.Refills,
+		Instructions:   r.Instructions,
+		OverrideReason: r.OverrideReason,
+	}
+}
+
+type cancelPrescriptionRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *PrescriptionHandler) CreatePrescription(c *gin.Context) {
+	encounterID, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+
+	var req prescriptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	prescription := req.prescription(encounterID)
+
+	user, _ := middleware.CurrentUser(c)
+	if err := h.prescriptionUseCase.CreatePrescription(c.Request.Context(), &prescription, user); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, prescription)
+}
+
+// CheckPrescription returns the warnings a prescription would get, without issuing it.
+func (h *PrescriptionHandler) CheckPrescription(c *gin.Context) {
+	encounterID, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+
+	var req prescriptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	prescription := req.prescription(encounterID)
+
+	warnings, err := h.prescriptionUseCase.CheckPrescription(c.Request.Context(), &prescription)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warnings": warnings, "total": len(warnings)})
+}
+
+func (h *PrescriptionHandler) ListEncounterPrescriptions(c *gin.Context) {
+	encounterID, ok := parseID(c, "encounter")
+	if !ok {
+		return
+	}
+
+	prescriptions, err := h.prescriptionUseCase.ListEncounterPrescriptions(c.Request.Context(), encounterID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prescriptions": prescriptions, "total": len(prescriptions)})
+}
+
+func (h *PrescriptionHandler) ListPatientPrescriptions(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	prescriptions, err := h.prescriptionUseCase.ListPatientPrescriptions(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prescriptions": prescriptions, "total": len(prescriptions)})
+}
+
+func (h *PrescriptionHandler) GetPrescription(c *gin.Context) {
+	id, ok := parseID(c, "prescription")
+	if !ok {
+		return
+	}
+
+	prescription, err := h.prescriptionUseCase.GetPrescription(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prescription)
+}
+
+// GetPrescriptionPDF serves the prescription for printing.
+func (h *PrescriptionHandler) GetPrescriptionPDF(c *gin.Context) {
+	id, ok := parseID(c, "prescription")
+	if !ok {
+		return
+	}
+
+	document, err := h.prescriptionUseCase.RenderPrescription(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"prescription-%d.pdf\"", id))
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+func (h *PrescriptionHandler) CancelPrescription(c *gin.Context) {
+	id, ok := parseID(c, "prescription")
+	if !ok {
+		return
+	}
+
+	var req cancelPrescriptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	prescription, err := h.prescriptionUseCase.CancelPrescription(c.Request.Context(), id, req.Reason, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prescription)
+}
@@ -0,0 +1,120 @@
+// internal/delivery/http/handler/relationship_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type RelationshipHandler struct {
+	relationshipUseCase usecase.RelationshipUseCase
+}
+
+func NewRelationshipHandler(relationshipUseCase usecase.RelationshipUseCase) *RelationshipHandler {
+	return &RelationshipHandler{relationshipUseCase: relationshipUseCase}
+}
+
+// relationshipRequest holds the client-editable fields of a relationship.
+// RelatedPatientID is only read on create.
+type relationshipRequest struct {
+	RelatedPatientID      uint   `json:"related_patient_id"`
+	Type                  string `json:"type"`
+	CanBook               bool   `json:"can_book"`
+	CanViewRecords        bool   `json:"can_view_records"`
+	ReceivesNotifications bool   `json:"receives_notifications"`
+}
+
+func (r relationshipRequest) relationship() domain.PatientRelationship {
+	return domain.PatientRelationship{
+		RelatedPatientID:      r.RelatedPatientID,
+		Type:                  r.Type,
+		CanBook:               r.CanBook,
+		CanViewRecords:        r.CanViewRecords,
+		ReceivesNotifications: r.ReceivesNotifications,
+	}
+}
+
+// CreateRelationship makes the patient in the body the guardian or
+// caregiver of the patient in the path.
+func (h *RelationshipHandler) CreateRelationship(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req relationshipRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	rel := req.relationship()
+	rel.PatientID = patientID
+
+	if err := h.relationshipUseCase.CreateRelationship(c.Request.Context(), &rel); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rel)
+}
+
+func (h *RelationshipHandler) ListRelationships(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	related, err := h.relationshipUseCase.ListRelationships(c.Request.Context(), patientID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"relationships": related, "total": len(related)})
+}
+
+// UpdateRelationship replaces the type and permission flags of a relationship.
+func (h *RelationshipHandler) UpdateRelationship(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "relationshipId", "relationship")
+	if !ok {
+		return
+	}
+
+	var req relationshipRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	rel := req.relationship()
+	rel.ID = id
+
+	if err := h.relationshipUseCase.UpdateRelationship(c.Request.Context(), patientID, &rel); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rel)
+}
+
+func (h *RelationshipHandler) DeleteRelationship(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "relationshipId", "relationship")
+	if !ok {
+		return
+	}
+
+	if err := h.relationshipUseCase.DeleteRelationship(c.Request.Context(), patientID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Relationship deleted successfully"})
+}
@@ -0,0 +1,87 @@
+// internal/delivery/http/handler/request.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"doctors/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON binds the request body into v. On failure it records a domain
+// error on the context and returns false.
+func bindJSON(c *gin.Context, v interface{}) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+
+	// Field rules are enforced by the use cases; only decoding fails here.
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		_ = c.Error(domain.NewValidationError(domain.FieldError{
+			Field:   typeErr.Field,
+			Message: "must be of type " + typeErr.Type.String(),
+		}))
+		return false
+	}
+	_ = c.Error(domain.NewBadRequestError("malformed_request", "Request body is not valid JSON: "+err.Error()))
+	return false
+}
+
+// parseID reads the numeric :id path parameter, recording a domain error when invalid.
+func parseID(c *gin.Context, resource string) (uint, bool) {
+	return parseIDParam(c, "id", resource)
+}
+
+// parseIDParam reads a numeric path parameter, recording a domain error when invalid.
+func parseIDParam(c *gin.Context, param, resource string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		_ = c.Error(domain.NewBadRequestError("invalid_"+resource+"_id", "Invalid "+resource+" ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseIfMatch reads the version from an If-Match header such as `"3"` or
+// `W/"3"`. It returns nil when the header is absent or "*".
+func parseIfMatch(c *gin.Context) (*uint, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil {
+		_ = c.Error(domain.NewBadRequestError("invalid_if_match", "If-Match must be an ETag returned by this API"))
+		return nil, false
+	}
+	v := uint(version)
+	return &v, true
+}
+
+// setETag exposes a resource version as its entity tag.
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// readPatch reads a JSON Merge Patch request body.
+func readPatch(c *gin.Context) ([]byte, bool) {
+	contentType := c.ContentType()
+	if contentType != "application/merge-patch+json" && contentType != "application/json" {
+		_ = c.Error(domain.NewBadRequestError("unsupported_media_type", "PATCH requires Content-Type application/merge-patch+json"))
+		return nil, false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		_ = c.Error(domain.NewBadRequestError("malformed_request", "Failed to read request body"))
+		return nil, false
+	}
+	return body, true
+}
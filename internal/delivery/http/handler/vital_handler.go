@@ -0,0 +1,143 @@
+// internal/delivery/http/handler/vital_handler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type VitalHandler struct {
+	vitalUseCase usecase.VitalUseCase
+}
+
+func NewVitalHandler(vitalUseCase usecase.VitalUseCase) *VitalHandler {
+	return &VitalHandler{vitalUseCase: vitalUseCase}
+}
+
+// vitalsRequest is a set of measurements taken together, e.g. at check-in.
+type vitalsRequest struct {
+	AppointmentID *uint                `json:"appointment_id"`
+	MeasuredAt    time.Time            `json:"measured_at"`
+	Measurements  []measurementRequest `json:"measurements"`
+}
+
+type measurementRequest struct {
+	Type  string  `json:"type"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+func (h *VitalHandler) RecordVitals(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var req vitalsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	vitals := make([]domain.VitalSign, len(req.Measurements))
+	for i, m := range req.Measurements {
+		vitals[i] = domain.VitalSign{Type: m.Type, EnteredValue: m.Value, EnteredUnit: m.Unit}
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	err := h.vitalUseCase.RecordVitals(c.Request.Context(), patientID, req.AppointmentID, req.MeasuredAt, vitals, user)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"vitals": vitals, "total": len(vitals)})
+}
+
+// ListVitals returns the latest measurements, newest first; appointment_id
+// narrows them to one visit.
+func (h *VitalHandler) ListVitals(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > maxPageSize {
+		limit = maxPageSize
+	}
+	var appointmentID *uint
+	if raw := c.Query("appointment_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			_ = c.Error(domain.NewBadRequestError("invalid_appointment_id", "Invalid appointment ID"))
+			return
+		}
+		v := uint(id)
+		appointmentID = &v
+	}
+
+	vitals, err := h.vitalUseCase.ListVitals(c.Request.Context(), patientID, appointmentID, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vitals": vitals, "total": len(vitals)})
+}
+
+// VitalTrends serves chart data: GET /patients/:id/vitals/trends?types=weight,systolic_bp
+// &from=2030-01-01&to=2030-07-01&units=imperial. to is exclusive.
+func (h *VitalHandler) VitalTrends(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+
+	var query domain.VitalQuery
+	if types := c.Query("types"); types != "" {
+		query.Types = strings.Split(types, ",")
+	}
+	for param, dst := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			_ = c.Error(domain.NewBadRequestError("invalid_date", param+" must be a date in YYYY-MM-DD format"))
+			return
+		}
+		*dst = date
+	}
+
+	trends, err := h.vitalUseCase.VitalTrends(c.Request.Context(), patientID, query, c.Query("units"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"patient_id": patientID, "trends": trends})
+}
+
+func (h *VitalHandler) DeleteVital(c *gin.Context) {
+	patientID, ok := parseID(c, "patient")
+	if !ok {
+		return
+	}
+	vitalID, ok := parseIDParam(c, "vitalId", "vital_sign")
+	if !ok {
+		return
+	}
+
+	if err := h.vitalUseCase.DeleteVital(c.Request.Context(), patientID, vitalID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Measurement marked as entered in error"})
+}
@@ -0,0 +1,85 @@
+// internal/delivery/http/middleware/auth.go
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"doctors/internal/domain"
+	"doctors/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userContextKey       = "user"
+	invalidKeyContextKey = "invalid_api_key"
+)
+
+// Authenticate resolves the X-API-Key header to a user and makes the
+// request act for the user's tenant. Requests without a key continue
+// anonymously; routes that need a user add RequireRole. Requests with an
+// invalid key continue anonymously too, so they can be rate limited by IP,
+// until RejectInvalidAPIKey turns them away.
+func Authenticate(userUseCase usecase.UserUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			c.Next()
+			return
+		}
+
+		user, err := userUseCase.Authenticate(c.Request.Context(), apiKey)
+		if errors.Is(err, usecase.ErrInvalidAPIKey) {
+			c.Set(invalidKeyContextKey, true)
+			c.Next()
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(userContextKey, user)
+		c.Request = c.Request.WithContext(usecase.WithTenant(c.Request.Context(), user.TenantID))
+		c.Next()
+	}
+}
+
+// RejectInvalidAPIKey fails requests whose API key Authenticate could not
+// resolve.
+func RejectInvalidAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(invalidKeyContextKey) {
+			WriteProblem(c, http.StatusUnauthorized, "invalid_api_key", "The API key is not valid", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests from anonymous callers or users without one of roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			WriteProblem(c, http.StatusUnauthorized, "unauthorized", "An API key is required", nil)
+			return
+		}
+		if !user.HasRole(roles...) {
+			WriteProblem(c, http.StatusForbidden, "forbidden", "Your role does not allow this operation", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser returns the authenticated user, if any.
+func CurrentUser(c *gin.Context) (*domain.User, bool) {
+	v, ok := c.Get(userContextKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := v.(*domain.User)
+	return user, ok
+}
@@ -0,0 +1,77 @@
+// internal/delivery/http/middleware/errors.go
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"doctors/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []domain.FieldError `json:"errors,omitempty"`
+}
+
+var statusByKind = map[domain.ErrorKind]int{
+	domain.KindNotFound:           http.StatusNotFound,
+	domain.KindConflict:           http.StatusConflict,
+	domain.KindValidation:         http.StatusUnprocessableEntity,
+	domain.KindForbidden:          http.StatusForbidden,
+	domain.KindBadRequest:         http.StatusBadRequest,
+	domain.KindPreconditionFailed: http.StatusPreconditionFailed,
+	domain.KindUnavailable:        http.StatusServiceUnavailable,
+}
+
+// ErrorHandler renders the last error attached with c.Error as problem+json.
+// Errors that are not domain errors are logged and reported as a generic 500.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		de, ok := domain.AsError(err)
+		if !ok {
+			log.Printf("Internal error on %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			WriteProblem(c, http.StatusInternalServerError, "internal_error", "An unexpected error occurred", nil)
+			return
+		}
+
+		status, ok := statusByKind[de.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		WriteProblem(c, status, de.Code, de.Message, de.Fields)
+	}
+}
+
+// WriteProblem aborts the request with a problem+json response, or an
+// OperationOutcome for FHIR requests.
+func WriteProblem(c *gin.Context, status int, code, detail string, fields []domain.FieldError) {
+	if isFHIRRequest(c) {
+		writeOperationOutcome(c, status, code, detail, fields)
+		return
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(status, Problem{
+		Type:     "/problems/" + strings.ReplaceAll(code, "_", "-"),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	})
+}
@@ -0,0 +1,125 @@
+// internal/delivery/http/middleware/errors_test.go
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"doctors/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serveError answers a request to path with err attached to the context.
+func serveError(path string, err error) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET(path, func(c *gin.Context) {
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestErrorHandler(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(out) })
+
+	fields := []domain.FieldError{{Field: "address.country", Message: "must be a two-letter country code"}}
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "not found",
+			err:  domain.NewNotFoundError("patient", 7),
+			want: Problem{Type: "/problems/patient-not-found", Title: "Not Found", Status: 404,
+				Detail: "patient 7 not found", Instance: "/api/v1/patients/7", Code: "patient_not_found"},
+		},
+		{
+			name: "validation keeps field errors",
+			err:  domain.NewValidationError(fields...),
+			want: Problem{Type: "/problems/validation-failed", Title: "Unprocessable Entity", Status: 422,
+				Detail: "Validation failed", Instance: "/api/v1/patients/7", Code: "validation_failed", Errors: fields},
+		},
+		{
+			name: "wrapped conflict",
+			err:  fmt.Errorf("failed to save: %w", domain.NewConflictError("patient_mrn_taken", "MRN is taken")),
+			want: Problem{Type: "/problems/patient-mrn-taken", Title: "Conflict", Status: 409,
+				Detail: "MRN is taken", Instance: "/api/v1/patients/7", Code: "patient_mrn_taken"},
+		},
+		{
+			name: "forbidden",
+			err:  domain.NewForbiddenError("staff only"),
+			want: Problem{Type: "/problems/forbidden", Title: "Forbidden", Status: 403,
+				Detail: "staff only", Instance: "/api/v1/patients/7", Code: "forbidden"},
+		},
+		{
+			name: "bad request",
+			err:  domain.NewBadRequestError("invalid_id", "id must be a number"),
+			want: Problem{Type: "/problems/invalid-id", Title: "Bad Request", Status: 400,
+				Detail: "id must be a number", Instance: "/api/v1/patients/7", Code: "invalid_id"},
+		},
+		{
+			name: "stale version",
+			err:  domain.NewPreconditionFailedError("patient"),
+			want: Problem{Type: "/problems/patient-modified", Title: "Precondition Failed", Status: 412,
+				Detail:   "patient was modified by another request; fetch the latest version and retry",
+				Instance: "/api/v1/patients/7", Code: "patient_modified"},
+		},
+		{
+			name: "unavailable dependency",
+			err:  domain.NewUnavailableError("gateway_unavailable", "payment provider failed", errors.New("timeout")),
+			want: Problem{Type: "/problems/gateway-unavailable", Title: "Service Unavailable", Status: 503,
+				Detail: "payment provider failed", Instance: "/api/v1/patients/7", Code: "gateway_unavailable"},
+		},
+		{
+			name: "other errors are hidden",
+			err:  errors.New("pq: connection refused"),
+			want: Problem{Type: "/problems/internal-error", Title: "Internal Server Error", Status: 500,
+				Detail: "An unexpected error occurred", Instance: "/api/v1/patients/7", Code: "internal_error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveError("/api/v1/patients/7", tt.err)
+			if w.Code != tt.want.Status {
+				t.Errorf("status = %d, want %d", w.Code, tt.want.Status)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("content type = %q, want application/problem+json", ct)
+			}
+			var got Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body %s: %v", w.Body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problem = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerLeavesSuccessAlone(t *testing.T) {
+	if w := serveError("/api/v1/patients/7", nil); w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want an empty 204", w.Code, w.Body)
+	}
+}
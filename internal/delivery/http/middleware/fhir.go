@@ -0,0 +1,60 @@
+// internal/delivery/http/middleware/fhir.go
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"doctors/internal/domain"
+	"doctors/pkg/fhir"
+	"github.com/gin-gonic/gin"
+)
+
+// FHIRBasePath is where the FHIR API is served. Errors under it are
+// rendered as OperationOutcome resources instead of problem+json.
+const FHIRBasePath = "/fhir/r4"
+
+var issueByStatus = map[int]string{
+	http.StatusBadRequest:           fhir.IssueInvalid,
+	http.StatusUnauthorized:         fhir.IssueLogin,
+	http.StatusForbidden:            fhir.IssueForbidden,
+	http.StatusNotFound:             fhir.IssueNotFound,
+	http.StatusConflict:             fhir.IssueConflict,
+	http.StatusPreconditionFailed:   fhir.IssueConflict,
+	http.StatusUnprocessableEntity:  fhir.IssueInvalid,
+	http.StatusUnsupportedMediaType: fhir.IssueNotSupported,
+	http.StatusTooManyRequests:      fhir.IssueThrottled,
+}
+
+func isFHIRRequest(c *gin.Context) bool {
+	path := c.Request.URL.Path
+	return path == FHIRBasePath || strings.HasPrefix(path, FHIRBasePath+"/")
+}
+
+// writeOperationOutcome aborts the request with an OperationOutcome. The
+// problem code is kept as the issue's details; each field error becomes
+// an issue of its own.
+func writeOperationOutcome(c *gin.Context, status int, code, detail string, fields []domain.FieldError) {
+	issueCode, ok := issueByStatus[status]
+	if !ok {
+		issueCode = fhir.IssueException
+	}
+	details := &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: code}}, Text: detail}
+
+	var issues []fhir.Issue
+	for _, field := range fields {
+		issues = append(issues, fhir.Issue{
+			Severity:    fhir.SeverityError,
+			Code:        issueCode,
+			Details:     details,
+			Diagnostics: field.Field + " " + field.Message,
+			Expression:  []string{field.Field},
+		})
+	}
+	if len(issues) == 0 {
+		issues = append(issues, fhir.Issue{Severity: fhir.SeverityError, Code: issueCode, Details: details, Diagnostics: detail})
+	}
+
+	c.Header("Content-Type", fhir.ContentType)
+	c.AbortWithStatusJSON(status, fhir.NewOperationOutcome(issues...))
+}
@@ -0,0 +1,59 @@
+// internal/delivery/http/middleware/rate_limit.go
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"doctors/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit throttles requests per client and route using a token bucket.
+// Clients are identified by their user, which Authenticate resolves first,
+// otherwise by IP: requests without a key or with an invalid one share the
+// limit of their address.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		route = c.Request.Method + " " + route
+
+		res, err := limiter.Allow(c.Request.Context(), clientKey(c), route)
+		if err != nil {
+			// Fail open: an unavailable store must not take the API down with it.
+			log.Printf("Rate limiter error: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			WriteProblem(c, http.StatusTooManyRequests, "rate_limited", "Rate limit exceeded", nil)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func clientKey(c *gin.Context) string {
+	if user, ok := CurrentUser(c); ok {
+		// The bootstrap admin, the only user without an ID, is user 0.
+		return "user:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -3,7 +3,10 @@ package http
 
 import (
 	"doctors/internal/delivery/http/handler"
+	"doctors/internal/delivery/http/middleware"
+	"doctors/internal/domain"
 	"doctors/internal/usecase"
+	"doctors/pkg/ratelimit"
 	"fmt"
 	"net/http"
 	"time"
@@ -11,7 +14,28 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(patientUseCase usecase.PatientUseCase, appointmentUseCase usecase.AppointmentUseCase) *gin.Engine {
+func NewRouter(
+	patientUseCase usecase.PatientUseCase,
+	appointmentUseCase usecase.AppointmentUseCase,
+	relationshipUseCase usecase.RelationshipUseCase,
+	portalUseCase usecase.PortalUseCase,
+	insuranceUseCase usecase.InsuranceUseCase,
+	encounterUseCase usecase.EncounterUseCase,
+	codeCatalogUseCase usecase.CodeCatalogUseCase,
+	codingUseCase usecase.CodingUseCase,
+	vitalUseCase usecase.VitalUseCase,
+	historyUseCase usecase.ClinicalHistoryUseCase,
+	drugCatalogUseCase usecase.DrugCatalogUseCase,
+	prescriptionUseCase usecase.PrescriptionUseCase,
+	labUseCase usecase.LabUseCase,
+	doctorUseCase usecase.DoctorUseCase,
+	bulkExportUseCase usecase.BulkExportUseCase,
+	billingUseCase usecase.BillingUseCase,
+	paymentUseCase usecase.PaymentUseCase,
+	claimUseCase usecase.ClaimUseCase,
+	userUseCase usecase.UserUseCase,
+	limiter *ratelimit.Limiter,
+) *gin.Engine {
 	router := gin.New()
 
 	// Add logging middleware
@@ -29,6 +53,12 @@ func NewRouter(patientUseCase usecase.PatientUseCase, appointmentUseCase usecase
 		)
 	}))
 	router.Use(gin.Recovery())
+	router.Use(middleware.ErrorHandler())
+	router.Use(middleware.Authenticate(userUseCase))
+	if limiter != nil {
+		router.Use(middleware.RateLimit(limiter))
+	}
+	router.Use(middleware.RejectInvalidAPIKey())
 
 	// Add a root route for basic testing
 	router.GET("/", func(c *gin.Context) {
@@ -37,31 +67,245 @@ func NewRouter(patientUseCase usecase.PatientUseCase, appointmentUseCase usecase
 
 	patientHandler := handler.NewPatientHandler(patientUseCase)
 	appointmentHandler := handler.NewAppointmentHandler(appointmentUseCase)
+	relationshipHandler := handler.NewRelationshipHandler(relationshipUseCase)
+	portalHandler := handler.NewPortalHandler(portalUseCase)
+	insuranceHandler := handler.NewInsuranceHandler(insuranceUseCase)
+	encounterHandler := handler.NewEncounterHandler(encounterUseCase)
+	codeHandler := handler.NewCodeHandler(codeCatalogUseCase)
+	appointmentCoding := handler.NewAppointmentCodingHandler(codingUseCase)
+	encounterCoding := handler.NewEncounterCodingHandler(codingUseCase)
+	vitalHandler := handler.NewVitalHandler(vitalUseCase)
+	historyHandler := handler.NewClinicalHistoryHandler(historyUseCase)
+	drugHandler := handler.NewDrugHandler(drugCatalogUseCase)
+	prescriptionHandler := handler.NewPrescriptionHandler(prescriptionUseCase)
+	labHandler := handler.NewLabHandler(labUseCase)
+	fhirHandler := handler.NewFHIRHandler(patientUseCase, appointmentUseCase, doctorUseCase)
+	bulkExportHandler := handler.NewBulkExportHandler(bulkExportUseCase)
+	billingHandler := handler.NewBillingHandler(billingUseCase)
+	paymentHandler := handler.NewPaymentHandler(paymentUseCase)
+	claimHandler := handler.NewClaimHandler(claimUseCase)
+
+	// Clinical documentation is only for the care team.
+	clinical := middleware.RequireRole(domain.RoleDoctor, domain.RoleNurse)
+	// Billing and insurance are for the front desk.
+	billing := middleware.RequireRole(domain.RoleAdmin, domain.RoleReceptionist)
+	// Guardianships are granted by the front desk and read by all staff,
+	// never by portal users: the portal trusts them to act for others.
+	frontDesk := billing
+	// Patient and appointment records are for staff; portal users go
+	// through /portal, which checks what they may see and book.
+	staff := middleware.RequireRole(domain.RoleAdmin, domain.RoleReceptionist, domain.RoleDoctor, domain.RoleNurse)
 
 	v1 := router.Group("/api/v1")
 	{
-		patients := v1.Group("/patients")
+		patients := v1.Group("/patients", staff)
 		{
 			patients.POST("/", patientHandler.CreatePatient)
 			patients.GET("/:id", patientHandler.GetPatient)
 			patients.PUT("/:id", patientHandler.UpdatePatient)
+			patients.PATCH("/:id", patientHandler.PatchPatient)
 			patients.DELETE("/:id", patientHandler.DeletePatient)
 			patients.GET("/", patientHandler.ListPatients) // Add this line
+			patients.POST("/:id/relationships", frontDesk, relationshipHandler.CreateRelationship)
+			patients.GET("/:id/relationships", relationshipHandler.ListRelationships)
+			patients.PUT("/:id/relationships/:relationshipId", frontDesk, relationshipHandler.UpdateRelationship)
+			patients.DELETE("/:id/relationships/:relationshipId", frontDesk, relationshipHandler.DeleteRelationship)
+			patients.POST("/:id/insurance", billing, insuranceHandler.CreatePolicy)
+			patients.GET("/:id/insurance", billing, insuranceHandler.ListPolicies)
+			patients.PUT("/:id/insurance/:policyId", billing, insuranceHandler.UpdatePolicy)
+			patients.DELETE("/:id/insurance/:policyId", billing, insuranceHandler.DeletePolicy)
+			patients.POST("/:id/insurance/:policyId/eligibility", billing, insuranceHandler.CheckEligibility)
+			patients.GET("/:id/eligibility-checks", billing, insuranceHandler.ListEligibilityChecks)
+			patients.GET("/:id/encounters", clinical, encounterHandler.ListPatientEncounters)
+			patients.POST("/:id/vitals", clinical, vitalHandler.RecordVitals)
+			patients.GET("/:id/vitals", clinical, vitalHandler.ListVitals)
+			patients.GET("/:id/vitals/trends", clinical, vitalHandler.VitalTrends)
+			patients.DELETE("/:id/vitals/:vitalId", clinical, vitalHandler.DeleteVital)
+			patients.POST("/:id/allergies", clinical, historyHandler.CreateAllergy)
+			patients.GET("/:id/allergies", clinical, historyHandler.ListAllergies)
+			patients.PUT("/:id/allergies/:allergyId", clinical, historyHandler.UpdateAllergy)
+			patients.DELETE("/:id/allergies/:allergyId", clinical, historyHandler.DeleteAllergy)
+			patients.POST("/:id/medications", clinical, historyHandler.CreateMedication)
+			patients.GET("/:id/medications", clinical, historyHandler.ListMedications)
+			patients.PUT("/:id/medications/:medicationId", clinical, historyHandler.UpdateMedication)
+			patients.DELETE("/:id/medications/:medicationId", clinical, historyHandler.DeleteMedication)
+			patients.POST("/:id/problems", clinical, historyHandler.CreateProblem)
+			patients.GET("/:id/problems", clinical, historyHandler.ListProblems)
+			patients.PUT("/:id/problems/:problemId", clinical, historyHandler.UpdateProblem)
+			patients.DELETE("/:id/problems/:problemId", clinical, historyHandler.DeleteProblem)
+			patients.GET("/:id/summary", clinical, historyHandler.GetSummary)
+			patients.GET("/:id/prescriptions", clinical, prescriptionHandler.ListPatientPrescriptions)
+			patients.GET("/:id/lab-orders", clinical, labHandler.ListPatientLabOrders)
+			patients.GET("/:id/balance", billing, billingHandler.GetPatientBalance)
 		}
 
-		appointments := v1.Group("/appointments")
+		appointments := v1.Group("/appointments", staff)
 		{
 			appointments.POST("/", appointmentHandler.CreateAppointment)
 			appointments.GET("/:id", appointmentHandler.GetAppointment)
 			appointments.PUT("/:id", appointmentHandler.UpdateAppointment) // Changed from patients to appointments
+			appointments.PATCH("/:id", appointmentHandler.PatchAppointment)
 			appointments.DELETE("/:id", appointmentHandler.DeleteAppointment)
 			appointments.GET("/", appointmentHandler.GetAppointmentsByDate)
+			appointments.POST("/:id/encounter", clinical, encounterHandler.CreateEncounter)
+			appointments.GET("/:id/encounter", clinical, encounterHandler.GetAppointmentEncounter)
+			appointments.GET("/:id/coding", clinical, appointmentCoding.GetCoding)
+			appointments.POST("/:id/diagnoses", clinical, appointmentCoding.AddDiagnosis)
+			appointments.DELETE("/:id/diagnoses/:diagnosisId", clinical, appointmentCoding.RemoveDiagnosis)
+			appointments.POST("/:id/procedures", clinical, appointmentCoding.AddProcedure)
+			appointments.DELETE("/:id/procedures/:procedureId", clinical, appointmentCoding.RemoveProcedure)
+			appointments.POST("/:id/lab-orders", clinical, labHandler.CreateLabOrder)
+			appointments.GET("/:id/lab-orders", clinical, labHandler.ListAppointmentLabOrders)
+			appointments.POST("/:id/deposits", billing, paymentHandler.PayDeposit)
+		}
+
+		encounters := v1.Group("/encounters", clinical)
+		{
+			encounters.GET("/:id", encounterHandler.GetEncounter)
+			encounters.PUT("/:id", encounterHandler.UpdateEncounter)
+			encounters.POST("/:id/sign", middleware.RequireRole(domain.RoleDoctor), encounterHandler.SignEncounter)
+			encounters.POST("/:id/addenda", encounterHandler.AddAddendum)
+			encounters.GET("/:id/coding", encounterCoding.GetCoding)
+			encounters.POST("/:id/diagnoses", encounterCoding.AddDiagnosis)
+			encounters.DELETE("/:id/diagnoses/:diagnosisId", encounterCoding.RemoveDiagnosis)
+			encounters.POST("/:id/procedures", encounterCoding.AddProcedure)
+			encounters.DELETE("/:id/procedures/:procedureId", encounterCoding.RemoveProcedure)
+			encounters.POST("/:id/prescriptions", middleware.RequireRole(domain.RoleDoctor), prescriptionHandler.CreatePrescription)
+			encounters.POST("/:id/prescriptions/check", prescriptionHandler.CheckPrescription)
+			encounters.GET("/:id/prescriptions", prescriptionHandler.ListEncounterPrescriptions)
+		}
+
+		prescriptions := v1.Group("/prescriptions", clinical)
+		{
+			prescriptions.GET("/:id", prescriptionHandler.GetPrescription)
+			prescriptions.GET("/:id/pdf", prescriptionHandler.GetPrescriptionPDF)
+			prescriptions.POST("/:id/cancel", middleware.RequireRole(domain.RoleDoctor), prescriptionHandler.CancelPrescription)
+		}
+
+		labOrders := v1.Group("/lab-orders", clinical)
+		{
+			labOrders.GET("/:id", labHandler.GetLabOrder)
+			labOrders.POST("/:id/send", labHandler.SendLabOrder)
+		}
+
+		codes := v1.Group("/codes", middleware.RequireRole(domain.RoleAdmin, domain.RoleDoctor, domain.RoleNurse, domain.RoleReceptionist))
+		{
+			codes.GET("/", codeHandler.SearchCodes)
+			codes.GET("/:system/:code", codeHandler.GetCode)
+		}
+
+		drugs := v1.Group("/drugs", clinical)
+		{
+			drugs.GET("/", drugHandler.SearchDrugs)
+			drugs.GET("/:rxcui", drugHandler.GetDrug)
+		}
+
+		v1.GET("/fees", billing, billingHandler.ListFees)
+
+		invoices := v1.Group("/invoices", billing)
+		{
+			invoices.POST("/", billingHandler.CreateInvoice)
+			invoices.GET("/", billingHandler.ListInvoices)
+			invoices.GET("/:id", billingHandler.GetInvoice)
+			invoices.PUT("/:id", billingHandler.UpdateInvoice)
+			invoices.POST("/:id/issue", billingHandler.IssueInvoice)
+			invoices.POST("/:id/void", billingHandler.VoidInvoice)
+			invoices.POST("/:id/payments", billingHandler.RecordPayment)
+			invoices.POST("/:id/refunds", billingHandler.RefundPayment)
+			invoices.GET("/:id/pdf", billingHandler.GetInvoicePDF)
+			invoices.POST("/:id/payment-intents", paymentHandler.PayInvoice)
+		}
+
+		paymentIntents := v1.Group("/payment-intents", billing)
+		{
+			paymentIntents.GET("/", paymentHandler.ListIntents)
+			paymentIntents.GET("/:id", paymentHandler.GetIntent)
+			paymentIntents.POST("/:id/cancel", paymentHandler.CancelIntent)
 		}
+
+		// The payment provider's webhooks are signed instead of authenticated.
+		v1.POST("/payments/webhook", paymentHandler.Webhook)
+
+		claims := v1.Group("/claims", billing)
+		{
+			claims.POST("/", claimHandler.CreateClaim)
+			claims.GET("/", claimHandler.ListClaims)
+			claims.GET("/:id", claimHandler.GetClaim)
+			claims.POST("/:id/status", claimHandler.SetClaimStatus)
+		}
+
+		claimFiles := v1.Group("/claim-files", billing)
+		{
+			claimFiles.POST("/", claimHandler.ExportClaims)
+			claimFiles.GET("/", claimHandler.ListClaimFiles)
+			claimFiles.GET("/:id", claimHandler.GetClaimFile)
+			claimFiles.GET("/:id/download", claimHandler.DownloadClaimFile)
+		}
+
+		remittances := v1.Group("/remittances", billing)
+		{
+			remittances.POST("/", claimHandler.ImportRemittance)
+			remittances.GET("/", claimHandler.ListRemittances)
+			remittances.GET("/:id", claimHandler.GetRemittance)
+		}
+
+		portal := v1.Group("/portal", middleware.RequireRole(domain.RolePatient))
+		{
+			portal.GET("/me", portalHandler.GetAccount)
+			portal.GET("/appointments", portalHandler.ListAppointments)
+			portal.POST("/appointments", portalHandler.BookAppointment)
+			portal.POST("/appointments/:id/cancel", portalHandler.CancelAppointment)
+			portal.POST("/appointments/:id/reschedule", portalHandler.RescheduleAppointment)
+		}
+
+		admin := v1.Group("/admin", middleware.RequireRole(domain.RoleAdmin))
+		{
+			admin.POST("/patients/:id/restore", patientHandler.RestorePatient)
+			admin.GET("/patients/export", patientHandler.ExportPatients)
+			admin.POST("/patient-imports", patientHandler.ImportPatients)
+			admin.GET("/patient-imports", patientHandler.ListImports)
+			admin.GET("/patient-imports/:id", patientHandler.GetImport)
+			admin.GET("/patients/duplicates", patientHandler.ListDuplicates)
+			admin.POST("/patients/:id/merge", patientHandler.MergePatient)
+			admin.GET("/patient-merges", patientHandler.ListMerges)
+			admin.POST("/patient-merges/:id/revert", patientHandler.RevertMerge)
+			admin.POST("/appointments/:id/restore", appointmentHandler.RestoreAppointment)
+			admin.PUT("/fees/:type", billingHandler.SetFee)
+			admin.DELETE("/fees/:type", billingHandler.DeleteFee)
+		}
+	}
+
+	// The capability statement is public so clients can discover the API.
+	// Export files are too, behind expiring signed links.
+	fhirAPI := router.Group(middleware.FHIRBasePath)
+	fhirAPI.GET("/metadata", fhirHandler.Metadata)
+	fhirAPI.GET("/bulk-files/:id/:name", bulkExportHandler.DownloadFile)
+
+	fhirResources := fhirAPI.Group("", middleware.RequireRole(domain.RoleAdmin, domain.RoleDoctor, domain.RoleNurse, domain.RoleReceptionist))
+	{
+		fhirResources.GET("/Patient", fhirHandler.SearchPatients)
+		fhirResources.POST("/Patient", fhirHandler.CreatePatient)
+		fhirResources.GET("/Patient/:id", fhirHandler.ReadPatient)
+		fhirResources.GET("/Practitioner", fhirHandler.SearchPractitioners)
+		fhirResources.GET("/Practitioner/:id", fhirHandler.ReadPractitioner)
+		fhirResources.GET("/Appointment", fhirHandler.SearchAppointments)
+		fhirResources.POST("/Appointment", fhirHandler.CreateAppointment)
+		fhirResources.GET("/Appointment/:id", fhirHandler.ReadAppointment)
+		fhirResources.GET("/Slot", fhirHandler.SearchSlots)
+		fhirResources.GET("/Slot/:id", fhirHandler.ReadSlot)
+	}
+
+	bulkExport := fhirAPI.Group("", middleware.RequireRole(domain.RoleAdmin))
+	{
+		bulkExport.GET("/$export", bulkExportHandler.ExportSystem)
+		bulkExport.GET("/Patient/$export", bulkExportHandler.ExportPatients)
+		bulkExport.GET("/bulk-status/:id", bulkExportHandler.ExportStatus)
+		bulkExport.DELETE("/bulk-status/:id", bulkExportHandler.CancelExport)
 	}
 
 	// Add a catch-all route for debugging
 	router.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Route not found", "path": c.Request.URL.Path})
+		middleware.WriteProblem(c, http.StatusNotFound, "route_not_found", "Route not found", nil)
 	})
 
 	return router
@@ -1,13 +1,49 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AppointmentStatusScheduled = "scheduled"
+	AppointmentStatusCancelled = "cancelled"
+	// AppointmentStatusCompleted marks a visit that took place; only
+	// completed appointments can be documented with an encounter.
+	AppointmentStatusCompleted = "completed"
+)
 
 type Appointment struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	PatientID uint      `json:"patient_id"`
+	PatientID uint      `gorm:"index" json:"patient_id" validate:"required"`
 	DoctorID  uint      `json:"doctor_id"`
-	DateTime  time.Time `json:"date_time"`
-	Notes     string    `json:"notes"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	DateTime  time.Time `json:"date_time" validate:"required"`
+	Notes     string    `json:"notes" validate:"max=2000"`
+	Status    string    `gorm:"not null;default:scheduled" json:"status" validate:"oneof=scheduled cancelled completed"`
+	// Type is the kind of visit, e.g. "new_patient" or "follow_up"; the
+	// fee schedule prices completed appointments by type.
+	Type string `json:"type,omitempty" validate:"max=50"`
+	// EligibilityCheckID is the latest eligibility result for the
+	// patient's primary insurance, set while the appointment is upcoming.
+	EligibilityCheckID *uint     `json:"eligibility_check_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	// DeletedAt marks the appointment as archived; archived rows are
+	// hidden from normal queries and purged after the retention period.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	// Version increases on every update and backs ETag/If-Match checks.
+	Version uint `gorm:"not null;default:1" json:"version"`
+}
+
+// AppointmentSearch filters appointments. Zero fields don't filter; the
+// time range is [From, To).
+type AppointmentSearch struct {
+	PatientID *uint
+	DoctorID  *uint
+	From      time.Time
+	To        time.Time
+	Statuses  []string
+	Limit     int
+	Offset    int
 }
@@ -0,0 +1,154 @@
+// internal/domain/billing.go
+package domain
+
+import "time"
+
+// Invoice states. A draft can be edited; issuing it fixes its lines and
+// gives it a number. An issued invoice becomes paid once nothing is due,
+// and is voided instead of deleted.
+const (
+	InvoiceDraft  = "draft"
+	InvoiceIssued = "issued"
+	InvoicePaid   = "paid"
+	InvoiceVoid   = "void"
+)
+
+// Kinds of movement on an invoice.
+const (
+	PaymentKindPayment = "payment"
+	PaymentKindRefund  = "refund"
+	// PaymentKindAdjustment writes off part of the balance that an
+	// insurer's contract or decision says won't be collected.
+	PaymentKindAdjustment = "adjustment"
+)
+
+// Payment methods.
+const (
+	PaymentMethodCash         = "cash"
+	PaymentMethodCard         = "card"
+	PaymentMethodCheck        = "check"
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodInsurance    = "insurance"
+	PaymentMethodOther        = "other"
+)
+
+// Fee is the fee schedule entry billed for an appointment type. Amounts
+// are in cents of the billing currency throughout.
+type Fee struct {
+	ID              uint   `gorm:"primaryKey" json:"id"`
+	AppointmentType string `gorm:"not null;uniqueIndex" json:"appointment_type" validate:"required,max=50"`
+	Description     string `gorm:"not null" json:"description" validate:"required,max=200"`
+	// Code is the CPT or HCPCS code of the service, e.g. 99213.
+	Code           string `json:"code,omitempty" validate:"max=10"`
+	UnitPriceCents int64  `gorm:"not null" json:"unit_price_cents" validate:"min=0"`
+	// TaxRate is a percentage, e.g. 8.25.
+	TaxRate   float64   `gorm:"not null" json:"tax_rate" validate:"min=0,max=100"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Fee) TableName() string {
+	return "fee_schedule"
+}
+
+// Invoice bills a patient, usually for a completed appointment.
+type Invoice struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Number is assigned when the invoice is issued, e.g. "INV-2030-000042".
+	Number        string `json:"number,omitempty"`
+	PatientID     uint   `gorm:"not null;index" json:"patient_id" validate:"required"`
+	AppointmentID *uint  `json:"appointment_id,omitempty"`
+	Status        string `gorm:"not null" json:"status"`
+	Currency      string `gorm:"not null" json:"currency"`
+	// Totals are kept up to date from the lines and payments. Subtotal is
+	// before discounts and taxes; Paid is net of refunds; Adjusted is
+	// written off by insurers.
+	SubtotalCents int64         `json:"subtotal_cents"`
+	DiscountCents int64         `json:"discount_cents"`
+	TaxCents      int64         `json:"tax_cents"`
+	TotalCents    int64         `json:"total_cents"`
+	PaidCents     int64         `json:"paid_cents"`
+	AdjustedCents int64         `json:"adjusted_cents"`
+	BalanceCents  int64         `json:"balance_cents"`
+	DueDate       string        `json:"due_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Notes         string        `json:"notes,omitempty" validate:"max=2000"`
+	Lines         []InvoiceLine `gorm:"-" json:"lines" validate:"max=100,dive"`
+	Payments      []Payment     `gorm:"-" json:"payments"`
+	VoidReason    string        `json:"void_reason,omitempty"`
+	// CreatedByUserID is nil for drafts created when an appointment is completed.
+	CreatedByUserID *uint      `json:"created_by_user_id,omitempty"`
+	IssuedAt        *time.Time `json:"issued_at,omitempty"`
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+	VoidedAt        *time.Time `json:"voided_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// InvoiceLine is a billed item. Its discount is taken off before tax.
+type InvoiceLine struct {
+	ID             uint    `gorm:"primaryKey" json:"id"`
+	InvoiceID      uint    `gorm:"not null;index" json:"invoice_id"`
+	Position       int     `gorm:"not null" json:"position"`
+	Description    string  `gorm:"not null" json:"description" validate:"required,max=200"`
+	Code           string  `json:"code,omitempty" validate:"max=10"`
+	Quantity       int     `gorm:"not null" json:"quantity" validate:"min=1,max=999"`
+	UnitPriceCents int64   `gorm:"not null" json:"unit_price_cents" validate:"min=0"`
+	DiscountCents  int64   `gorm:"not null" json:"discount_cents" validate:"min=0"`
+	TaxRate        float64 `gorm:"not null" json:"tax_rate" validate:"min=0,max=100"`
+	// TaxCents and TotalCents are computed.
+	TaxCents   int64 `gorm:"not null" json:"tax_cents"`
+	TotalCents int64 `gorm:"not null" json:"total_cents"`
+}
+
+// Payment is money received for an invoice, a refund of some of it, or
+// an insurer's adjustment of what is due.
+type Payment struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	InvoiceID   uint   `gorm:"not null;index" json:"invoice_id"`
+	PatientID   uint   `gorm:"not null;index" json:"patient_id"`
+	Kind        string `gorm:"not null" json:"kind"`
+	AmountCents int64  `gorm:"not null" json:"amount_cents" validate:"min=1"`
+	Method      string `gorm:"not null" json:"method" validate:"required,oneof=cash card check bank_transfer insurance other"`
+	// Reference is the receipt, check or transaction number.
+	Reference string `json:"reference,omitempty" validate:"max=100"`
+	// RefundOfID is the payment a refund gives money back from.
+	RefundOfID       *uint     `json:"refund_of_id,omitempty"`
+	Note             string    `json:"note,omitempty" validate:"max=500"`
+	ReceivedAt       time.Time `gorm:"not null" json:"received_at"`
+	RecordedByUserID *uint     `json:"recorded_by_user_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (Payment) TableName() string {
+	return "invoice_payments"
+}
+
+// InsurancePosting is what an insurer paid on an invoice and what it
+// adjusted off. Posting records each up to the balance left and sets them
+// to the amounts recorded.
+type InsurancePosting struct {
+	InvoiceID     uint
+	PaidCents     int64
+	AdjustedCents int64
+	// Reference is the check or EFT number.
+	Reference  string
+	Note       string
+	ReceivedAt time.Time
+}
+
+// InvoiceSearch filters invoices. Zero fields don't filter.
+type InvoiceSearch struct {
+	PatientID *uint
+	Statuses  []string
+	Limit     int
+	Offset    int
+}
+
+// PatientBalance is what a patient owes: the balance of their issued
+// invoices, oldest due first.
+type PatientBalance struct {
+	PatientID    uint      `json:"patient_id"`
+	Currency     string    `json:"currency"`
+	BalanceCents int64     `json:"balance_cents"`
+	Invoices     []Invoice `json:"invoices"`
+}
@@ -0,0 +1,73 @@
+// internal/domain/bulk_export.go
+package domain
+
+import "time"
+
+// Bulk export statuses.
+const (
+	// BulkExportAccepted: requested and waiting for the export worker.
+	BulkExportAccepted = "accepted"
+	// BulkExportInProgress: files are being written; Written counts the
+	// resources exported so far out of Total.
+	BulkExportInProgress = "in_progress"
+	// BulkExportCompleted: the files can be downloaded until ExpiresAt.
+	BulkExportCompleted = "completed"
+	// BulkExportFailed: the export stopped; Error says why.
+	BulkExportFailed = "failed"
+)
+
+// Bulk export levels: a system export covers every exported resource
+// type, a patient export only patients and their appointments.
+const (
+	BulkExportSystem  = "system"
+	BulkExportPatient = "patient"
+)
+
+// BulkExportFile is an NDJSON file of an export, holding Count resources
+// of one FHIR resource type.
+type BulkExportFile struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// BulkExport is a FHIR Bulk Data export: every resource of Types changed
+// after Since (all of them when nil) and up to TransactionTime, written
+// as one NDJSON file per type.
+type BulkExport struct {
+	ID    uint       `gorm:"primaryKey" json:"id"`
+	Level string     `gorm:"not null" json:"level"`
+	Types []string   `gorm:"serializer:json" json:"types"`
+	Since *time.Time `json:"since,omitempty"`
+	// TransactionTime is when the export was requested. A later export
+	// with it as Since picks up where this one ended.
+	TransactionTime time.Time        `json:"transaction_time"`
+	Status          string           `gorm:"not null" json:"status"`
+	Total           int              `json:"total"`
+	Written         int              `json:"written"`
+	Files           []BulkExportFile `gorm:"serializer:json" json:"files"`
+	Error           string           `json:"error,omitempty"`
+	// Request is the kickoff request URL, echoed in the manifest.
+	Request           string `json:"request"`
+	RequestedByUserID *uint  `json:"requested_by_user_id,omitempty"`
+	// TenantID is the tenant of the requester; only its patients and
+	// their appointments are exported.
+	TenantID    uint       `gorm:"not null;default:1" json:"tenant_id"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when a completed export's files are deleted.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Progress returns the share of resources exported, from 0 to 100.
+func (e *BulkExport) Progress() int {
+	if e.Status == BulkExportCompleted {
+		return 100
+	}
+	if e.Total == 0 {
+		return 0
+	}
+	return e.Written * 100 / e.Total
+}
@@ -0,0 +1,179 @@
+// internal/domain/claim.go
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Claim states. A claim is ready until it is exported in an 837P file,
+// then submitted until the payer acknowledges or rejects it. Remittances
+// mark it paid or denied. Rejected, denied and void claims free the visit
+// to be claimed again.
+const (
+	ClaimReady     = "ready"
+	ClaimSubmitted = "submitted"
+	ClaimAccepted  = "accepted"
+	// ClaimRejected means the clearinghouse or payer returned the claim
+	// unprocessed, e.g. for a wrong member ID.
+	ClaimRejected = "rejected"
+	ClaimPaid     = "paid"
+	ClaimDenied   = "denied"
+	ClaimVoid     = "void"
+)
+
+// Claim bills a completed appointment to the patient's insurance. It is a
+// snapshot: the codes, coverage and charges are copied in when it is
+// created. Amounts are in cents of the billing currency.
+type Claim struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Number is the patient control number sent to the payer, who quotes
+	// it back in remittances, e.g. "CLM00000042".
+	Number    string `gorm:"not null" json:"number"`
+	PatientID uint   `gorm:"not null;index" json:"patient_id"`
+	// AppointmentID is nil once the appointment is purged; the claim
+	// keeps what it billed.
+	AppointmentID *uint  `json:"appointment_id,omitempty"`
+	InvoiceID     uint   `gorm:"not null" json:"invoice_id"`
+	PolicyID      uint   `gorm:"not null" json:"policy_id"`
+	PayerName     string `gorm:"not null" json:"payer_name"`
+	PayerID       string `gorm:"not null" json:"payer_id"`
+	// ServiceDate is the YYYY-MM-DD date of the visit.
+	ServiceDate string `gorm:"not null" json:"service_date"`
+	// Diagnoses are ICD-10-CM codes, principal first, written with their dot.
+	Diagnoses   []string    `gorm:"serializer:json" json:"diagnoses"`
+	Lines       []ClaimLine `gorm:"serializer:json" json:"lines"`
+	ChargeCents int64       `gorm:"not null" json:"charge_cents"`
+	// PaidCents and AdjustedCents are what remittances posted to the
+	// invoice; PatientResponsibilityCents is what the payer left to the
+	// patient, such as co-payments and deductibles.
+	PaidCents                  int64  `gorm:"not null" json:"paid_cents"`
+	AdjustedCents              int64  `gorm:"not null" json:"adjusted_cents"`
+	PatientResponsibilityCents int64  `gorm:"not null" json:"patient_responsibility_cents"`
+	PayerClaimNumber           string `json:"payer_claim_number,omitempty"`
+	Status                     string `gorm:"not null" json:"status"`
+	// FileID is the 837P file the claim was last exported in.
+	FileID          *uint               `json:"file_id,omitempty"`
+	History         []ClaimStatusChange `gorm:"-" json:"history,omitempty"`
+	CreatedByUserID *uint               `json:"created_by_user_id,omitempty"`
+	SubmittedAt     *time.Time          `json:"submitted_at,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// ClaimLine is a procedure billed on a claim.
+type ClaimLine struct {
+	Code        string   `json:"code"`
+	Description string   `json:"description,omitempty"`
+	Modifiers   []string `json:"modifiers,omitempty"`
+	Units       int      `json:"units"`
+	ChargeCents int64    `json:"charge_cents"`
+	// DiagnosisPointers are the 1-based positions in Claim.Diagnoses the
+	// procedure treats.
+	DiagnosisPointers []int `json:"diagnosis_pointers"`
+}
+
+// ClaimStatusChange records a claim entering a status and why.
+type ClaimStatusChange struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	ClaimID uint   `gorm:"not null;index" json:"claim_id"`
+	Status  string `gorm:"not null" json:"status"`
+	Note    string `json:"note,omitempty"`
+	// RemittanceID is set on changes made by a remittance.
+	RemittanceID    *uint     `json:"remittance_id,omitempty"`
+	ChangedByUserID *uint     `json:"changed_by_user_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (ClaimStatusChange) TableName() string {
+	return "claim_status_history"
+}
+
+// ClaimSearch filters claims. Zero fields don't filter.
+type ClaimSearch struct {
+	PatientID     *uint
+	AppointmentID *uint
+	Statuses      []string
+	Limit         int
+	Offset        int
+}
+
+// ClaimFile is an exported 837P batch of claims.
+type ClaimFile struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// ControlNumber is the interchange control number of the file.
+	ControlNumber int   `gorm:"not null" json:"control_number"`
+	ClaimCount    int   `gorm:"not null" json:"claim_count"`
+	ChargeCents   int64 `gorm:"not null" json:"charge_cents"`
+	// TenantID is the tenant whose ready claims the file exports.
+	TenantID uint `gorm:"not null;default:1" json:"tenant_id"`
+	// Content names patients and is encrypted with DataKey, identified by KeyID.
+	Content string `json:"-"`
+	DataKey string `json:"-"`
+	KeyID   string `gorm:"index" json:"-"`
+	// CreatedByUserID is nil for the bootstrap admin.
+	CreatedByUserID *uint     `json:"created_by_user_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// FileName is the name the file is downloaded as.
+func (f *ClaimFile) FileName() string {
+	return fmt.Sprintf("claims-%09d.837", f.ControlNumber)
+}
+
+// Remittance is an ingested 835: one payment from a payer and what it
+// posted to the claims it covers.
+type Remittance struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// TraceNumber is the check or EFT number; with PayerID it identifies
+	// the payment, so a file can't be posted twice.
+	TraceNumber  string `gorm:"not null" json:"trace_number"`
+	PayerID      string `gorm:"not null" json:"payer_id"`
+	PayerName    string `json:"payer_name"`
+	PaymentCents int64  `gorm:"not null" json:"payment_cents"`
+	// PaymentMethod is e.g. "ACH", "CHK", or "NON" when nothing was paid.
+	PaymentMethod string `json:"payment_method"`
+	// PaymentDate is YYYY-MM-DD.
+	PaymentDate string            `json:"payment_date,omitempty"`
+	Claims      []RemittanceClaim `gorm:"serializer:json" json:"claims"`
+	// TenantID is the tenant of the user who posted the remittance; it is
+	// posted to that tenant's claims only.
+	TenantID uint `gorm:"not null;default:1" json:"tenant_id"`
+	// Content names patients and is encrypted with DataKey, identified by KeyID.
+	Content string `json:"-"`
+	DataKey string `json:"-"`
+	KeyID   string `gorm:"index" json:"-"`
+	// ReceivedByUserID is nil for the bootstrap admin.
+	ReceivedByUserID *uint     `json:"received_by_user_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// RemittanceClaim is what a remittance says about one claim, and what
+// was posted because of it.
+type RemittanceClaim struct {
+	Number string `json:"number"`
+	// ClaimID is nil when no claim has the number.
+	ClaimID *uint `json:"claim_id,omitempty"`
+	// Status is the payer's claim status code and Description says what it means.
+	Status                     string            `json:"status"`
+	Description                string            `json:"description"`
+	ChargeCents                int64             `json:"charge_cents"`
+	PaidCents                  int64             `json:"paid_cents"`
+	PatientResponsibilityCents int64             `json:"patient_responsibility_cents"`
+	PayerClaimNumber           string            `json:"payer_claim_number,omitempty"`
+	Adjustments                []ClaimAdjustment `json:"adjustments,omitempty"`
+	// PostedPaymentCents and PostedAdjustmentCents are what was recorded
+	// on the invoice; Note explains anything that wasn't.
+	PostedPaymentCents    int64  `json:"posted_payment_cents"`
+	PostedAdjustmentCents int64  `json:"posted_adjustment_cents"`
+	Note                  string `json:"note,omitempty"`
+}
+
+// ClaimAdjustment is a reason the payer paid other than the charge. Group
+// is who bears it: CO (contractual) and OA/PI are written off the
+// invoice, PR is left for the patient to pay.
+type ClaimAdjustment struct {
+	Group       string `json:"group"`
+	Reason      string `json:"reason"`
+	AmountCents int64  `json:"amount_cents"`
+}
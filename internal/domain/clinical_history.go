@@ -0,0 +1,122 @@
+// internal/domain/clinical_history.go
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Clinical statuses of allergies, medications and problems. A resolved
+// medication is one the patient no longer takes.
+const (
+	ClinicalStatusActive   = "active"
+	ClinicalStatusResolved = "resolved"
+)
+
+// Where a history entry came from.
+const (
+	// SourcePatientReported entries were told by the patient or a relative.
+	SourcePatientReported = "patient_reported"
+	// SourceClinician entries were observed or decided by a clinician here.
+	SourceClinician = "clinician"
+	// SourceExternal entries were copied from another provider's records.
+	SourceExternal = "external"
+)
+
+// Allergy severities.
+const (
+	SeverityMild     = "mild"
+	SeverityModerate = "moderate"
+	SeveritySevere   = "severe"
+)
+
+// Provenance records who added a history entry, from what source and,
+// when it came up during a visit, in which encounter.
+type Provenance struct {
+	Source           string `gorm:"not null" json:"source" validate:"required,oneof=patient_reported clinician external"`
+	EncounterID      *uint  `json:"encounter_id,omitempty"`
+	RecordedByUserID *uint  `json:"recorded_by_user_id,omitempty"`
+}
+
+// Allergy is an allergy or intolerance. Dates are YYYY-MM-DD.
+type Allergy struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	PatientID uint   `gorm:"not null;index" json:"patient_id"`
+	Substance string `json:"substance" validate:"required,max=200"`
+	// Category tells drug allergies, which prescriptions are checked
+	// against, from others.
+	Category string `json:"category" validate:"required,oneof=medication food environment other"`
+	Reaction string `json:"reaction,omitempty" validate:"max=500"`
+	Severity string `json:"severity,omitempty" validate:"omitempty,oneof=mild moderate severe"`
+	Status   string `gorm:"not null" json:"status" validate:"required,oneof=active resolved"`
+	// OnsetDate may be left out when unknown.
+	OnsetDate  string `json:"onset_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	ResolvedOn string `json:"resolved_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Provenance
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt marks an entry made in error.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Allergy) TableName() string {
+	return "patient_allergies"
+}
+
+// Medication is a drug the patient takes, prescribed here or elsewhere.
+type Medication struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	PatientID uint   `gorm:"not null;index" json:"patient_id"`
+	Name      string `json:"name" validate:"required,max=200"`
+	// RxNormCode identifies the drug when known.
+	RxNormCode string `json:"rxnorm_code,omitempty" validate:"max=20"`
+	Dose       string `json:"dose,omitempty" validate:"max=100"`
+	Route      string `json:"route,omitempty" validate:"max=50"`
+	Frequency  string `json:"frequency,omitempty" validate:"max=100"`
+	Status     string `gorm:"not null" json:"status" validate:"required,oneof=active resolved"`
+	OnsetDate  string `json:"onset_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	ResolvedOn string `json:"resolved_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Provenance
+	// PrescriptionID is set on medications prescribed here.
+	PrescriptionID *uint          `json:"prescription_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Medication) TableName() string {
+	return "patient_medications"
+}
+
+// Problem is an entry of the problem list: a condition, diagnosis or
+// concern that matters beyond a single visit.
+type Problem struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	PatientID   uint   `gorm:"not null;index" json:"patient_id"`
+	Description string `json:"description" validate:"required,max=500"`
+	// Code is an optional ICD-10-CM code from the catalog.
+	Code       string `json:"code,omitempty" validate:"max=10"`
+	Status     string `gorm:"not null" json:"status" validate:"required,oneof=active resolved"`
+	OnsetDate  string `json:"onset_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	ResolvedOn string `json:"resolved_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Provenance
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Problem) TableName() string {
+	return "patient_problems"
+}
+
+// PatientSummary is the longitudinal view of a patient: what they are
+// allergic to, take and have, and their most recent encounter.
+type PatientSummary struct {
+	Patient     Patient      `json:"patient"`
+	Allergies   []Allergy    `json:"allergies"`
+	Medications []Medication `json:"medications"`
+	Problems    []Problem    `json:"problems"`
+	// LastEncounter is nil when the patient has never been seen.
+	LastEncounter *Encounter `json:"last_encounter"`
+}
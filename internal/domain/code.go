@@ -0,0 +1,88 @@
+// internal/domain/code.go
+package domain
+
+import "time"
+
+// Code systems of the catalog.
+const (
+	// CodeSystemICD10CM codes diagnoses, e.g. E11.9.
+	CodeSystemICD10CM = "ICD-10-CM"
+	// CodeSystemCPT and CodeSystemHCPCS code procedures and services:
+	// CPT (HCPCS level I) for physician services such as 99213, HCPCS
+	// level II for supplies, drugs and other services such as J1100.
+	CodeSystemCPT   = "CPT"
+	CodeSystemHCPCS = "HCPCS"
+)
+
+// MedicalCode is one entry of an imported code set. Codes missing from a
+// newer release are deactivated rather than deleted, so records that use
+// them keep their description.
+type MedicalCode struct {
+	System           string `gorm:"primaryKey" json:"system"`
+	Code             string `gorm:"primaryKey" json:"code"`
+	Description      string `json:"description"`
+	ShortDescription string `json:"short_description,omitempty"`
+	// Billable is false for ICD-10-CM category headers such as E11, which
+	// are too unspecific to be recorded as a diagnosis.
+	Billable bool `json:"billable"`
+	Active   bool `json:"active"`
+	// Release is the code set release that last contained the code, e.g. "2025".
+	Release   string    `json:"release"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CodeSearch filters a catalog lookup.
+type CodeSearch struct {
+	System string
+	// Query is a code prefix ("E11", "E11.6") or words of the description
+	// ("diab mell"); each word matches as a prefix.
+	Query           string
+	IncludeInactive bool
+	BillableOnly    bool
+}
+
+// Diagnosis is an ICD-10-CM code recorded for a visit.
+type Diagnosis struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	AppointmentID uint   `gorm:"not null;index" json:"appointment_id"`
+	System        string `gorm:"not null" json:"system"`
+	Code          string `gorm:"not null" json:"code" validate:"required,max=10"`
+	// Description is read from the catalog.
+	Description string `gorm:"->" json:"description"`
+	// Rank orders the diagnoses; 1 is the primary diagnosis. Claims carry
+	// at most 12.
+	Rank            int       `gorm:"not null" json:"rank" validate:"min=0,max=12"`
+	CreatedByUserID *uint     `json:"created_by_user_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (Diagnosis) TableName() string {
+	return "appointment_diagnoses"
+}
+
+// Procedure is a CPT or HCPCS code recorded for a visit.
+type Procedure struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	AppointmentID uint   `gorm:"not null;index" json:"appointment_id"`
+	System        string `gorm:"not null" json:"system" validate:"omitempty,oneof=CPT HCPCS"`
+	Code          string `gorm:"not null" json:"code" validate:"required,max=10"`
+	// Description is read from the catalog.
+	Description string `gorm:"->" json:"description"`
+	// Modifiers refine the procedure, e.g. 25 (significant, separately
+	// identifiable service) or LT (left side). Claims carry at most four.
+	Modifiers       []string  `gorm:"serializer:json" json:"modifiers" validate:"max=4,dive,len=2,alphanum"`
+	Units           int       `gorm:"not null" json:"units" validate:"min=0,max=999"`
+	CreatedByUserID *uint     `json:"created_by_user_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (Procedure) TableName() string {
+	return "appointment_procedures"
+}
+
+// VisitCoding is everything coded for one appointment.
+type VisitCoding struct {
+	AppointmentID uint        `json:"appointment_id"`
+	Diagnoses     []Diagnosis `json:"diagnoses"`
+	Procedures    []Procedure `json:"procedures"`
+}
@@ -0,0 +1,58 @@
+// internal/domain/encounter.go
+package domain
+
+import "time"
+
+// Encounter states. A signed encounter can no longer be edited; corrections
+// are added as addenda.
+const (
+	EncounterDraft  = "draft"
+	EncounterSigned = "signed"
+)
+
+// Encounter is the clinical documentation of a completed appointment in
+// SOAP format. Unlike Appointment.Notes it is never shown to patients.
+type Encounter struct {
+	ID            uint `gorm:"primaryKey" json:"id"`
+	AppointmentID uint `gorm:"not null;uniqueIndex" json:"appointment_id"`
+	PatientID     uint `gorm:"not null;index" json:"patient_id"`
+	DoctorID      uint `gorm:"not null" json:"doctor_id"`
+	// Subjective is what the patient reports: complaints and history.
+	Subjective string `json:"subjective" validate:"max=20000"`
+	// Objective holds findings: examination, measurements and results.
+	Objective  string `json:"objective" validate:"max=20000"`
+	Assessment string `json:"assessment" validate:"max=20000"`
+	Plan       string `json:"plan" validate:"max=20000"`
+	Status     string `gorm:"not null" json:"status"`
+	// AuthorUserID and SignedByUserID are nil for the bootstrap admin.
+	AuthorUserID   *uint               `json:"author_user_id,omitempty"`
+	SignedByUserID *uint               `json:"signed_by_user_id,omitempty"`
+	SignedAt       *time.Time          `json:"signed_at,omitempty"`
+	Addenda        []EncounterAddendum `gorm:"-" json:"addenda"`
+	// DataKey is the wrapped key encrypting the SOAP sections and addenda.
+	DataKey   string    `json:"-"`
+	KeyID     string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version increases on every update and backs ETag/If-Match checks.
+	Version uint `gorm:"not null;default:1" json:"version"`
+}
+
+// Empty reports whether none of the SOAP sections has been written.
+func (e *Encounter) Empty() bool {
+	return e.Subjective == "" && e.Objective == "" && e.Assessment == "" && e.Plan == ""
+}
+
+// EncounterAddendum is a correction or late entry appended to a signed
+// encounter. Addenda are never edited or deleted.
+type EncounterAddendum struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	EncounterID  uint      `gorm:"not null;index" json:"encounter_id"`
+	Text         string    `gorm:"not null" json:"text" validate:"required,max=10000"`
+	AuthorUserID *uint     `json:"author_user_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (EncounterAddendum) TableName() string {
+	return "encounter_addenda"
+}
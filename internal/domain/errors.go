@@ -0,0 +1,113 @@
+// internal/domain/errors.go
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies domain errors so the delivery layer can map them to
+// transport status codes without knowing about storage details.
+type ErrorKind string
+
+const (
+	KindNotFound   ErrorKind = "not_found"
+	KindConflict   ErrorKind = "conflict"
+	KindValidation ErrorKind = "validation"
+	KindForbidden  ErrorKind = "forbidden"
+	KindBadRequest ErrorKind = "bad_request"
+	// KindPreconditionFailed means the client's If-Match version is stale.
+	KindPreconditionFailed ErrorKind = "precondition_failed"
+	// KindUnavailable means a service we depend on failed; the request may
+	// be retried.
+	KindUnavailable ErrorKind = "unavailable"
+)
+
+// FieldError describes a problem with a single input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is the typed error returned by use cases. Code is a stable,
+// machine-readable identifier such as "patient_not_found".
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, domain.ErrNotFound) and friends match on kind.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == "" && t.Kind == e.Kind
+}
+
+// Sentinels for errors.Is checks against a kind.
+var (
+	ErrNotFound           = &Error{Kind: KindNotFound}
+	ErrConflict           = &Error{Kind: KindConflict}
+	ErrValidation         = &Error{Kind: KindValidation}
+	ErrForbidden          = &Error{Kind: KindForbidden}
+	ErrBadRequest         = &Error{Kind: KindBadRequest}
+	ErrPreconditionFailed = &Error{Kind: KindPreconditionFailed}
+	ErrUnavailable        = &Error{Kind: KindUnavailable}
+)
+
+func NewNotFoundError(resource string, id interface{}) *Error {
+	return &Error{
+		Kind:    KindNotFound,
+		Code:    resource + "_not_found",
+		Message: fmt.Sprintf("%s %v not found", resource, id),
+	}
+}
+
+func NewConflictError(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func NewValidationError(fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: "Validation failed", Fields: fields}
+}
+
+func NewForbiddenError(message string) *Error {
+	return &Error{Kind: KindForbidden, Code: "forbidden", Message: message}
+}
+
+func NewBadRequestError(code, message string) *Error {
+	return &Error{Kind: KindBadRequest, Code: code, Message: message}
+}
+
+func NewPreconditionFailedError(resource string) *Error {
+	return &Error{
+		Kind:    KindPreconditionFailed,
+		Code:    resource + "_modified",
+		Message: resource + " was modified by another request; fetch the latest version and retry",
+	}
+}
+
+func NewUnavailableError(code, message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+// AsError extracts a domain error from err's chain.
+func AsError(err error) (*Error, bool) {
+	var de *Error
+	if errors.As(err, &de) {
+		return de, true
+	}
+	return nil, false
+}
@@ -0,0 +1,116 @@
+// internal/domain/insurance.go
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Order in which policies pay (coordination of benefits).
+const (
+	InsurancePrimary   = "primary"
+	InsuranceSecondary = "secondary"
+	InsuranceTertiary  = "tertiary"
+)
+
+// How the patient relates to the policy's subscriber.
+const (
+	SubscriberSelf   = "self"
+	SubscriberSpouse = "spouse"
+	SubscriberChild  = "child"
+	SubscriberOther  = "other"
+)
+
+// InsurancePolicy is a patient's coverage with a payer. Dates are
+// YYYY-MM-DD; an empty EffectiveTo means the policy has no end date.
+type InsurancePolicy struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	PatientID uint   `gorm:"not null;index" json:"patient_id"`
+	PayerName string `json:"payer_name" validate:"required,max=100"`
+	// PayerID is the payer's identifier at the clearinghouse.
+	PayerID     string `json:"payer_id" validate:"required,max=80"`
+	MemberID    string `json:"member_id" validate:"required,max=80"`
+	GroupNumber string `json:"group_number,omitempty" validate:"max=50"`
+	// SubscriberRelationship is the patient's relationship to the
+	// subscriber; the subscriber's name and birth date are needed unless
+	// it is self.
+	SubscriberRelationship string    `json:"subscriber_relationship" validate:"required,oneof=self spouse child other"`
+	SubscriberName         string    `json:"subscriber_name,omitempty" validate:"max=100"`
+	SubscriberDateOfBirth  string    `json:"subscriber_date_of_birth,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	EffectiveFrom          string    `json:"effective_from" validate:"required,datetime=2006-01-02"`
+	EffectiveTo            string    `json:"effective_to,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Priority               string    `gorm:"not null" json:"priority" validate:"required,oneof=primary secondary tertiary"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+	// DeletedAt archives a policy while keeping its eligibility history.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	// DataKey is the wrapped key encrypting the member ID and the
+	// subscriber's name and birth date, identified by KeyID.
+	DataKey string `json:"-"`
+	KeyID   string `gorm:"index" json:"-"`
+}
+
+// ActiveOn reports whether the policy is in effect on date (YYYY-MM-DD).
+func (p InsurancePolicy) ActiveOn(date string) bool {
+	return p.EffectiveFrom <= date && (p.EffectiveTo == "" || date <= p.EffectiveTo)
+}
+
+// Overlaps reports whether the two policies are in effect on a common day.
+func (p InsurancePolicy) Overlaps(other InsurancePolicy) bool {
+	return (p.EffectiveTo == "" || other.EffectiveFrom <= p.EffectiveTo) &&
+		(other.EffectiveTo == "" || p.EffectiveFrom <= other.EffectiveTo)
+}
+
+// Outcomes of an eligibility check.
+const (
+	// EligibilityPending is a check waiting for the clearinghouse.
+	EligibilityPending  = "pending"
+	EligibilityActive   = "active"
+	EligibilityInactive = "inactive"
+	// EligibilityRejected means the payer could not answer, e.g. an
+	// unknown member ID; Rejections says why.
+	EligibilityRejected = "rejected"
+	// EligibilityUnknown means the payer answered without stating
+	// whether the coverage is active.
+	EligibilityUnknown = "unknown"
+	// EligibilityError means no answer was received from the clearinghouse.
+	EligibilityError = "error"
+)
+
+// EligibilityCheck records one 270 inquiry about a policy and what its
+// 271 response said.
+type EligibilityCheck struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	PolicyID uint `gorm:"not null;index" json:"policy_id"`
+	// ServiceDate is the YYYY-MM-DD date coverage was asked about.
+	ServiceDate string               `json:"service_date"`
+	Status      string               `gorm:"not null" json:"status"`
+	PlanBegin   string               `json:"plan_begin,omitempty"`
+	PlanEnd     string               `json:"plan_end,omitempty"`
+	Benefits    []EligibilityBenefit `gorm:"serializer:json" json:"benefits"`
+	Rejections  []string             `gorm:"serializer:json" json:"rejections,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	// Request and Response are the raw X12 interchanges, kept for audit
+	// and encrypted with DataKey, identified by KeyID.
+	Request         string    `json:"-"`
+	Response        string    `json:"-"`
+	DataKey         string    `json:"-"`
+	KeyID           string    `gorm:"index" json:"-"`
+	CheckedByUserID *uint     `json:"checked_by_user_id,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// EligibilityBenefit is one benefit line of a 271, e.g. a co-payment.
+type EligibilityBenefit struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	ServiceType string   `json:"service_type,omitempty"`
+	Coverage    string   `json:"coverage_level,omitempty"`
+	Plan        string   `json:"plan,omitempty"`
+	Period      string   `json:"period,omitempty"`
+	Amount      *float64 `json:"amount,omitempty"`
+	Percent     *float64 `json:"percent,omitempty"`
+	InNetwork   string   `json:"in_network,omitempty"`
+	Messages    []string `json:"messages,omitempty"`
+}
@@ -0,0 +1,124 @@
+// internal/domain/lab.go
+package domain
+
+import "time"
+
+// Lab order statuses.
+const (
+	// LabOrderPending: stored but not yet sent to the laboratory.
+	LabOrderPending = "pending"
+	// LabOrderFailed: the laboratory couldn't be reached or rejected the
+	// order; LastError says why and the order can be sent again.
+	LabOrderFailed = "failed"
+	// LabOrderSent: the laboratory acknowledged the order.
+	LabOrderSent = "sent"
+	// LabOrderPreliminary: some results arrived, not all of them final.
+	LabOrderPreliminary = "preliminary"
+	// LabOrderFinal: final results arrived for every test.
+	LabOrderFinal = "final"
+)
+
+// Lab order priorities.
+const (
+	LabPriorityRoutine = "routine"
+	LabPriorityStat    = "stat"
+)
+
+// Lab result statuses, from HL7 v2 OBX-11.
+const (
+	LabResultPreliminary = "preliminary"
+	LabResultFinal       = "final"
+	LabResultCorrected   = "corrected"
+)
+
+// LabTest is a test ordered from the laboratory, identified by its LOINC code.
+type LabTest struct {
+	Code string `json:"code" validate:"required,max=10"`
+	Name string `json:"name" validate:"required,max=200"`
+}
+
+// LabOrder is a request for laboratory tests made for an appointment.
+// The laboratory refers to it by PlacerOrderNumber in its results.
+type LabOrder struct {
+	ID            uint `gorm:"primaryKey" json:"id"`
+	PatientID     uint `gorm:"not null;index" json:"patient_id"`
+	AppointmentID uint `gorm:"not null;index" json:"appointment_id"`
+	// DoctorID is the ordering doctor, notified when results arrive.
+	DoctorID          uint      `gorm:"not null" json:"doctor_id"`
+	PlacerOrderNumber string    `gorm:"uniqueIndex" json:"placer_order_number"`
+	Tests             []LabTest `gorm:"serializer:json" json:"tests" validate:"required,min=1,max=20,dive"`
+	Priority          string    `gorm:"not null" json:"priority" validate:"oneof=routine stat"`
+	// ClinicalInfo is sent to the laboratory with the order, e.g. "fasting".
+	ClinicalInfo    string `json:"clinical_info,omitempty" validate:"max=500"`
+	Status          string `gorm:"not null" json:"status"`
+	LastError       string `json:"last_error,omitempty"`
+	OrderedByUserID *uint  `json:"ordered_by_user_id,omitempty"`
+	// FillerOrderNumber is the laboratory's own reference, from its results.
+	FillerOrderNumber string `json:"filler_order_number,omitempty"`
+	// ReportedTests are the codes of the tests with final results; the
+	// order is final once every test is reported.
+	ReportedTests []string    `gorm:"serializer:json" json:"reported_tests"`
+	SentAt        *time.Time  `json:"sent_at,omitempty"`
+	ResultedAt    *time.Time  `json:"resulted_at,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Results       []LabResult `gorm:"-" json:"results"`
+}
+
+// LabResult is one observation reported by the laboratory. A corrected
+// result replaces the earlier one for the same code.
+type LabResult struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	LabOrderID uint   `gorm:"not null" json:"lab_order_id"`
+	PatientID  uint   `gorm:"not null;index" json:"patient_id"`
+	Code       string `gorm:"not null" json:"code"`
+	Name       string `json:"name"`
+	// ValueType is the HL7 value type of OBX-2, e.g. NM for numbers.
+	ValueType      string `json:"value_type"`
+	Value          string `json:"value"`
+	Units          string `json:"units,omitempty"`
+	ReferenceRange string `json:"reference_range,omitempty"`
+	// Flag is empty or one of N, L, H, LL, HH, A and AA.
+	Flag       string     `json:"flag,omitempty"`
+	Status     string     `gorm:"not null" json:"status"`
+	ObservedAt *time.Time `json:"observed_at,omitempty"`
+	ReceivedAt time.Time  `json:"received_at"`
+}
+
+// Abnormal reports whether the laboratory flagged the result.
+func (r LabResult) Abnormal() bool {
+	return r.Flag != "" && r.Flag != FlagNormal
+}
+
+// Critical reports whether the result is flagged critical.
+func (r LabResult) Critical() bool {
+	return r.Flag == FlagCriticalLow || r.Flag == FlagCriticalHigh || r.Flag == FlagCriticalAbnormal
+}
+
+// Directions of an HL7 message.
+const (
+	HL7Inbound  = "inbound"
+	HL7Outbound = "outbound"
+)
+
+// HL7Message logs a message exchanged with the laboratory and how it was
+// acknowledged. Inbound messages are looked up by control ID so a
+// message the laboratory sends again is not processed twice. The payload
+// names the patient and is encrypted with DataKey, identified by KeyID.
+type HL7Message struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Direction  string    `gorm:"not null" json:"direction"`
+	Type       string    `gorm:"not null" json:"type"`
+	ControlID  string    `gorm:"not null" json:"control_id"`
+	LabOrderID *uint     `json:"lab_order_id,omitempty"`
+	Payload    string    `gorm:"not null" json:"payload"`
+	AckCode    string    `json:"ack_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	DataKey    string    `json:"-"`
+	KeyID      string    `gorm:"index" json:"-"`
+}
+
+func (HL7Message) TableName() string {
+	return "hl7_messages"
+}
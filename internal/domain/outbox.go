@@ -0,0 +1,39 @@
+// internal/domain/outbox.go
+package domain
+
+import "time"
+
+// OutboxMessage is a message to publish to Kafka. The API publishes
+// pending messages in order, retrying failed ones. The payload may name
+// patients and is encrypted with DataKey, identified by KeyID.
+type OutboxMessage struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Topic   string `gorm:"not null" json:"topic"`
+	Key     string `json:"key,omitempty"`
+	Payload string `gorm:"not null" json:"payload"`
+	// Attempts counts failed publishes; LastError is the latest failure.
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DataKey     string     `json:"-"`
+	KeyID       string     `gorm:"index" json:"-"`
+}
+
+// DeadLetter is a Kafka message the consumer could not process: it was
+// malformed, rejected by validation or failed. Replaying it puts it back
+// on the outbox as OutboxID. The payload is encrypted like an outbox
+// message's.
+type DeadLetter struct {
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	Topic string `gorm:"not null" json:"topic"`
+	// Type is the message type, when the message could be parsed.
+	Type       string     `json:"type,omitempty"`
+	Payload    string     `gorm:"not null" json:"payload"`
+	Error      string     `gorm:"not null" json:"error"`
+	ReplayedAt *time.Time `json:"replayed_at,omitempty"`
+	OutboxID   *uint      `json:"outbox_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DataKey    string     `json:"-"`
+	KeyID      string     `gorm:"index" json:"-"`
+}
@@ -1,13 +1,119 @@
 // internal/domain/patient.go
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sex recorded at birth, as used for clinical reference ranges.
+const (
+	SexFemale   = "female"
+	SexMale     = "male"
+	SexIntersex = "intersex"
+	SexUnknown  = "unknown"
+)
+
+// Channels a patient can prefer to be contacted through.
+const (
+	ContactChannelEmail = "email"
+	ContactChannelSMS   = "sms"
+	ContactChannelPhone = "phone"
+	ContactChannelMail  = "mail"
+	ContactChannelNone  = "none"
+)
 
 type Patient struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone"`
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `json:"name" validate:"required,min=2,max=100"`
+	// Email is optional for patients, such as young children, whose
+	// notifications go to a guardian.
+	Email string `json:"email" validate:"omitempty,email,max=254"`
+	Phone string `json:"phone" validate:"omitempty,e164"`
+	// DateOfBirth is a calendar date in YYYY-MM-DD form.
+	DateOfBirth string `json:"date_of_birth,omitempty" validate:"omitempty,datetime=2006-01-02"`
+
+	// MRN is the medical record number, generated on create unless given.
+	MRN        string `gorm:"column:mrn" json:"mrn"`
+	NationalID string `json:"national_id,omitempty" validate:"max=50"`
+
+	PreferredName  string `json:"preferred_name,omitempty" validate:"max=100"`
+	Pronouns       string `json:"pronouns,omitempty" validate:"max=30"`
+	Sex            string `json:"sex,omitempty" validate:"omitempty,oneof=female male intersex unknown"`
+	GenderIdentity string `json:"gender_identity,omitempty" validate:"max=50"`
+	// PreferredLanguage is a BCP 47 tag such as "en" or "es-MX".
+	PreferredLanguage       string             `json:"preferred_language,omitempty" validate:"omitempty,bcp47_language_tag"`
+	PreferredContactChannel string             `json:"preferred_contact_channel" validate:"omitempty,oneof=email sms phone mail none"`
+	Address                 Address            `gorm:"embedded;embeddedPrefix:address_" json:"address"`
+	EmergencyContacts       []EmergencyContact `gorm:"-" json:"emergency_contacts" validate:"max=5,dive"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt marks the patient as archived; archived rows are hidden
+	// from normal queries and purged after the retention period.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	// Version increases on every update and backs ETag/If-Match checks.
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// MergedIntoID is set on a duplicate archived by a merge.
+	MergedIntoID *uint `json:"merged_into_id,omitempty"`
+	// TenantID is the tenant of the user who created the patient.
+	TenantID uint `gorm:"not null;default:1" json:"tenant_id"`
+
+	// Contact details, identifiers and the street address are stored
+	// encrypted with a per-row data key, itself wrapped by the master key
+	// identified by KeyID. EmergencyContacts are kept as encrypted JSON.
+	DataKey                 string `json:"-"`
+	KeyID                   string `gorm:"index" json:"-"`
+	EmailIndex              string `gorm:"index" json:"-"`
+	PhoneIndex              string `gorm:"index" json:"-"`
+	DOBIndex                string `gorm:"column:dob_index;index" json:"-"`
+	NationalIDIndex         string `gorm:"index" json:"-"`
+	EmergencyContactsSealed string `gorm:"column:emergency_contacts" json:"-"`
+}
+
+// Address is a postal address. Country is an ISO 3166-1 alpha-2 code.
+type Address struct {
+	Line1      string `json:"line1,omitempty" validate:"max=200"`
+	Line2      string `json:"line2,omitempty" validate:"max=200"`
+	City       string `json:"city,omitempty" validate:"max=100"`
+	Region     string `json:"region,omitempty" validate:"max=100"`
+	PostalCode string `json:"postal_code,omitempty" validate:"max=20"`
+	Country    string `json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+}
+
+// EmergencyContact is someone to call on the patient's behalf.
+type EmergencyContact struct {
+	Name         string `json:"name" validate:"required,min=2,max=100"`
+	Relationship string `json:"relationship,omitempty" validate:"max=50"`
+	Phone        string `json:"phone" validate:"required,e164"`
+	Email        string `json:"email,omitempty" validate:"omitempty,email,max=254"`
+}
+
+// PatientSearch describes a front-desk patient search. Name matches
+// fuzzily, MRN, Email, Phone and DateOfBirth exactly, and PhoneFragment matches
+// any run of at least three digits of the phone number.
+type PatientSearch struct {
+	Name            string
+	MRN             string
+	Email           string
+	Phone           string
+	PhoneFragment   string
+	DateOfBirth     string
+	IncludeArchived bool
+	Limit           int
+}
+
+// Highlight marks the runes [Start, End) of a field that matched the query.
+type Highlight struct {
+	Field string `json:"field"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// PatientMatch is a search result. Score ranges from 0 to 1, best first.
+type PatientMatch struct {
+	Patient    Patient     `json:"patient"`
+	Score      float64     `json:"score"`
+	Highlights []Highlight `json:"highlights,omitempty"`
 }
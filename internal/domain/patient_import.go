@@ -0,0 +1,88 @@
+// internal/domain/patient_import.go
+package domain
+
+import "time"
+
+// Patient import statuses.
+const (
+	// PatientImportQueued: stored and waiting for an import worker.
+	PatientImportQueued    = "queued"
+	PatientImportRunning   = "running"
+	PatientImportCompleted = "completed"
+	PatientImportFailed    = "failed"
+)
+
+// What an import does with rows that probably are an existing patient or
+// repeat an earlier row.
+const (
+	ImportDuplicateSkip   = "skip"
+	ImportDuplicateCreate = "create"
+)
+
+// PatientImportIssue is a problem with a row of an import file. Row is
+// the row number in the file, the header being row 1.
+type PatientImportIssue struct {
+	Row int `json:"row"`
+	// Errors keep the row from being imported.
+	Errors []FieldError `json:"errors,omitempty"`
+	// Duplicates are existing patients the row probably is. Their names
+	// are left out of stored issues.
+	Duplicates []DuplicateMatch `json:"duplicates,omitempty"`
+	// DuplicateOfRow is an earlier row of the file the row repeats.
+	DuplicateOfRow int `json:"duplicate_of_row,omitempty"`
+}
+
+// PatientImport is a spreadsheet of patients being imported in the
+// background, or only checked when DryRun is set. Rows are checked and
+// inserted in chunks, each in its own transaction with the progress it
+// makes, so an import whose worker went away resumes where it stopped.
+type PatientImport struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	FileName    string `json:"file_name"`
+	DryRun      bool   `json:"dry_run"`
+	OnDuplicate string `gorm:"not null" json:"on_duplicate"`
+	// Columns maps the file's column headers to patient fields.
+	Columns map[string]string `gorm:"serializer:json" json:"columns"`
+	// IgnoredColumns are headers that map to no field.
+	IgnoredColumns []string `gorm:"serializer:json" json:"ignored_columns"`
+	Status         string   `gorm:"not null" json:"status"`
+	Rows           int      `json:"rows"`
+	Processed      int      `json:"processed"`
+	// Imported rows were created (or would be, in a dry run); Skipped ones
+	// were duplicates; Failed ones had errors.
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+	// Issues lists rows with errors or duplicates, up to a limit; the
+	// counts above are always complete.
+	Issues            []PatientImportIssue `gorm:"serializer:json" json:"issues"`
+	Error             string               `json:"error,omitempty"`
+	RequestedByUserID *uint                `json:"requested_by_user_id,omitempty"`
+	TenantID          uint                 `gorm:"not null;default:1" json:"tenant_id"`
+	CompletedAt       *time.Time           `json:"completed_at,omitempty"`
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
+
+// PatientImportRow is a data row of an import file. Number is its row
+// number in the file, the header being row 1.
+type PatientImportRow struct {
+	Number int      `json:"number"`
+	Values []string `json:"values"`
+}
+
+// PatientImportUpload holds the rows of an import until it finishes. The
+// rows are patient data and are stored encrypted, as RowsSealed, with
+// DataKey, identified by KeyID.
+type PatientImportUpload struct {
+	// ID is the ID of the import.
+	ID uint `gorm:"primaryKey;autoIncrement:false"`
+	// Fields are the patient fields of the columns, "" for ignored ones.
+	Fields []string `gorm:"serializer:json"`
+	// DateLayout is the Go layout of dates of birth.
+	DateLayout string
+	Rows       []PatientImportRow `gorm:"-"`
+	RowsSealed string             `gorm:"column:rows"`
+	DataKey    string
+	KeyID      string `gorm:"index"`
+}
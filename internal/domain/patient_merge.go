@@ -0,0 +1,44 @@
+// internal/domain/patient_merge.go
+package domain
+
+import "time"
+
+// Reasons a pair of patients looks like the same person.
+const (
+	DuplicateSameEmail       = "same_email"
+	DuplicateSamePhone       = "same_phone"
+	DuplicateSameDateOfBirth = "same_date_of_birth"
+	DuplicateSimilarName     = "similar_name"
+)
+
+// DuplicateMatch is an existing patient that probably is the same person as
+// the one being checked. Score ranges from 0 to 1.
+type DuplicateMatch struct {
+	PatientID uint     `json:"patient_id"`
+	Name      string   `json:"name,omitempty"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// DuplicatePair is an entry of the probable duplicates report. Patient is
+// the older record, the usual survivor of a merge.
+type DuplicatePair struct {
+	Patient   Patient  `json:"patient"`
+	Duplicate Patient  `json:"duplicate"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// PatientMerge records that DuplicateID was merged into SurvivorID, with
+// enough detail to revert it: the duplicate is archived rather than
+// deleted, and the records moved to the survivor are listed by table.
+type PatientMerge struct {
+	ID               uint              `gorm:"primaryKey" json:"id"`
+	SurvivorID       uint              `gorm:"not null;index" json:"survivor_id"`
+	DuplicateID      uint              `gorm:"not null;index" json:"duplicate_id"`
+	MovedRecords     map[string][]uint `gorm:"serializer:json" json:"moved_records"`
+	MergedByUserID   *uint             `json:"merged_by_user_id,omitempty"`
+	MergedAt         time.Time         `json:"merged_at"`
+	RevertedByUserID *uint             `json:"reverted_by_user_id,omitempty"`
+	RevertedAt       *time.Time        `json:"reverted_at,omitempty"`
+}
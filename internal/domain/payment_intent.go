@@ -0,0 +1,62 @@
+// internal/domain/payment_intent.go
+package domain
+
+import "time"
+
+// What a payment intent pays for.
+const (
+	// IntentPurposeInvoice pays an issued invoice.
+	IntentPurposeInvoice = "invoice"
+	// IntentPurposeDeposit pays ahead for a scheduled appointment. The
+	// deposit is posted to the appointment's invoice when it is issued.
+	IntentPurposeDeposit = "deposit"
+)
+
+// Final payment intent states. Intents in any other state are still
+// waiting on the patient or the provider.
+const (
+	PaymentIntentSucceeded = "succeeded"
+	PaymentIntentCanceled  = "canceled"
+)
+
+// PaymentIntent is a card payment collected through the payment provider.
+// The patient pays it in the provider's checkout with ClientSecret; the
+// provider's webhooks then report whether it succeeded.
+type PaymentIntent struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	Purpose       string `gorm:"not null" json:"purpose"`
+	PatientID     uint   `gorm:"not null;index" json:"patient_id"`
+	InvoiceID     *uint  `json:"invoice_id,omitempty"`
+	AppointmentID *uint  `json:"appointment_id,omitempty"`
+	AmountCents   int64  `gorm:"not null" json:"amount_cents"`
+	Currency      string `gorm:"not null" json:"currency"`
+	// Provider and ProviderIntentID identify the intent at the provider,
+	// e.g. "stripe" and "pi_3PZ...".
+	Provider         string `gorm:"not null" json:"provider"`
+	ProviderIntentID string `gorm:"not null" json:"provider_intent_id"`
+	ClientSecret     string `json:"client_secret,omitempty"`
+	// Status is the provider's: requires_payment_method, requires_action,
+	// processing, succeeded or canceled.
+	Status string `gorm:"not null" json:"status"`
+	// FailureReason explains the last failed attempt to pay, or why a
+	// successful payment could not be posted to the invoice.
+	FailureReason string `json:"failure_reason,omitempty"`
+	// IdempotencyKey makes a retried request return the intent the first
+	// request created.
+	IdempotencyKey string `gorm:"not null" json:"-"`
+	// PaymentID is the invoice payment the intent was posted as.
+	PaymentID       *uint      `json:"payment_id,omitempty"`
+	CreatedByUserID *uint      `json:"created_by_user_id,omitempty"`
+	SucceededAt     *time.Time `json:"succeeded_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// PaymentIntentSearch filters payment intents. Zero fields don't filter.
+type PaymentIntentSearch struct {
+	PatientID     *uint
+	InvoiceID     *uint
+	AppointmentID *uint
+	Limit         int
+	Offset        int
+}
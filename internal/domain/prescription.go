@@ -0,0 +1,88 @@
+// internal/domain/prescription.go
+package domain
+
+import "time"
+
+// Prescription statuses.
+const (
+	PrescriptionActive    = "active"
+	PrescriptionCancelled = "cancelled"
+)
+
+// Kinds of prescription warning.
+const (
+	// WarningInteraction: an ingredient interacts with one of a medication
+	// the patient takes.
+	WarningInteraction = "interaction"
+	// WarningDuplicateTherapy: the patient already takes the ingredient.
+	WarningDuplicateTherapy = "duplicate_therapy"
+	// WarningAllergy: the patient is allergic to an ingredient or its class.
+	WarningAllergy = "allergy"
+)
+
+// Drug is a product of the drug catalog, identified by its RxNorm
+// concept. Ingredients and classes are normalized to lower case.
+type Drug struct {
+	RxCUI       string   `gorm:"column:rxcui;primaryKey" json:"rxcui"`
+	Name        string   `json:"name"`
+	Ingredients []string `gorm:"serializer:json" json:"ingredients"`
+	Strength    string   `json:"strength,omitempty"`
+	DoseForm    string   `json:"dose_form,omitempty"`
+	// Classes such as "penicillins" match allergies recorded by class.
+	Classes []string `gorm:"serializer:json" json:"classes"`
+	Active  bool     `json:"active"`
+	// Release is the catalog release that last contained the drug.
+	Release   string    `json:"release"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DrugInteraction is an entry of the interaction dataset between two
+// ingredients, stored in alphabetical order.
+type DrugInteraction struct {
+	IngredientA string `gorm:"primaryKey" json:"ingredient_a"`
+	IngredientB string `gorm:"primaryKey" json:"ingredient_b"`
+	// Severity is minor, moderate, major or contraindicated.
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// PrescriptionWarning is a problem found by the safety checks. The
+// medication or allergy it concerns is named by ID.
+type PrescriptionWarning struct {
+	Type         string `json:"type"`
+	Severity     string `json:"severity"`
+	Message      string `json:"message"`
+	MedicationID *uint  `json:"medication_id,omitempty"`
+	AllergyID    *uint  `json:"allergy_id,omitempty"`
+}
+
+// Prescription is a drug prescribed by a doctor during an encounter. The
+// drug's name, strength and form are copied from the catalog, so the
+// prescription reads the same after the catalog changes.
+type Prescription struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	PatientID   uint   `gorm:"not null;index" json:"patient_id"`
+	EncounterID uint   `gorm:"not null;index" json:"encounter_id"`
+	DoctorID    uint   `gorm:"not null" json:"doctor_id"`
+	RxCUI       string `gorm:"column:rxcui;not null" json:"rxcui" validate:"required,max=20"`
+	DrugName    string `gorm:"not null" json:"drug_name"`
+	Strength    string `json:"strength,omitempty"`
+	DoseForm    string `json:"dose_form,omitempty"`
+	// Dosage is the directions for use (the sig), e.g. "1 tablet by mouth twice daily".
+	Dosage       string `gorm:"not null" json:"dosage" validate:"required,max=100"`
+	Quantity     int    `gorm:"not null" json:"quantity" validate:"min=1,max=10000"`
+	QuantityUnit string `json:"quantity_unit,omitempty" validate:"max=30"`
+	Refills      int    `gorm:"not null" json:"refills" validate:"min=0,max=11"`
+	// Instructions are further notes for the patient or pharmacist.
+	Instructions string `json:"instructions,omitempty" validate:"max=1000"`
+	Status       string `gorm:"not null" json:"status"`
+	// Warnings are the safety check results when the prescription was
+	// issued. Major or contraindicated ones need an OverrideReason.
+	Warnings           []PrescriptionWarning `gorm:"serializer:json" json:"warnings"`
+	OverrideReason     string                `json:"override_reason,omitempty" validate:"max=500"`
+	PrescribedByUserID *uint                 `json:"prescribed_by_user_id,omitempty"`
+	CancelReason       string                `json:"cancel_reason,omitempty"`
+	CancelledAt        *time.Time            `json:"cancelled_at,omitempty"`
+	CreatedAt          time.Time             `json:"created_at"`
+	UpdatedAt          time.Time             `json:"updated_at"`
+}
@@ -0,0 +1,57 @@
+// internal/domain/relationship.go
+package domain
+
+import "time"
+
+// Kinds of relationship. The responsible person is the guardian or
+// caregiver; seen from their side, the patient is a dependent.
+const (
+	RelationshipGuardian  = "guardian"
+	RelationshipCaregiver = "caregiver"
+	RelationshipDependent = "dependent"
+)
+
+// PatientRelationship makes RelatedPatientID responsible for PatientID,
+// with flags for what they may do on the patient's behalf.
+type PatientRelationship struct {
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	PatientID             uint      `gorm:"not null;index" json:"patient_id" validate:"required"`
+	RelatedPatientID      uint      `gorm:"not null;index" json:"related_patient_id" validate:"required"`
+	Type                  string    `gorm:"not null" json:"type" validate:"required,oneof=guardian caregiver"`
+	CanBook               bool      `json:"can_book"`
+	CanViewRecords        bool      `json:"can_view_records"`
+	ReceivesNotifications bool      `json:"receives_notifications"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// RelatedPatient is a relationship seen from one patient's side. Role is
+// what the other patient is to them: guardian, caregiver or dependent.
+type RelatedPatient struct {
+	Relationship PatientRelationship `json:"relationship"`
+	Role         string              `json:"role"`
+	Patient      Patient             `json:"patient"`
+}
+
+// PortalAccount is what a patient portal user may act for: their own
+// record and their dependents.
+type PortalAccount struct {
+	Patient    Patient           `json:"patient"`
+	Dependents []PortalDependent `json:"dependents"`
+}
+
+// PortalDependent is a dependent as their guardian or caregiver sees them
+// in the portal. Patient, the full record, is only set when the
+// relationship grants CanViewRecords; booking alone only reveals Identity.
+type PortalDependent struct {
+	Relationship PatientRelationship `json:"relationship"`
+	Identity     PatientIdentity     `json:"identity"`
+	Patient      *Patient            `json:"patient,omitempty"`
+}
+
+// PatientIdentity is just enough of a patient to pick them when booking.
+type PatientIdentity struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	PreferredName string `json:"preferred_name,omitempty"`
+}
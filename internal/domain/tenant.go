@@ -0,0 +1,19 @@
+// internal/domain/tenant.go
+package domain
+
+import "time"
+
+// DefaultTenantID is the tenant of users and patients created without
+// one, including everything created before tenants existed.
+const DefaultTenantID uint = 1
+
+// Tenant is a practice sharing the deployment. Its users and patients
+// belong to it, and it numbers medical records and invoices on its own.
+type Tenant struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Slug names the tenant on the command line: lowercase letters, digits
+	// and dashes, e.g. "northside".
+	Slug      string    `json:"slug" validate:"required,min=2,max=50"`
+	Name      string    `json:"name" validate:"required,min=2,max=100"`
+	CreatedAt time.Time `json:"created_at"`
+}
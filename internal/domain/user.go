@@ -0,0 +1,37 @@
+// internal/domain/user.go
+package domain
+
+import "time"
+
+const (
+	RoleAdmin        = "admin"
+	RoleDoctor       = "doctor"
+	RoleNurse        = "nurse"
+	RoleReceptionist = "receptionist"
+	// RolePatient is a portal user acting for PatientID and their dependents.
+	RolePatient = "patient"
+)
+
+// User is a staff member, or a patient using the portal, calling the API
+// with a personal API key. Only a hash of the key is stored.
+type User struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `json:"name" validate:"required,min=2,max=100"`
+	Email      string    `json:"email" validate:"required,email,max=254"`
+	Role       string    `json:"role" validate:"required,oneof=admin doctor nurse receptionist patient"`
+	PatientID  *uint     `json:"patient_id,omitempty"`
+	TenantID   uint      `gorm:"not null;default:1" json:"tenant_id"`
+	APIKeyHash string    `gorm:"uniqueIndex" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// HasRole reports whether the user has any of the given roles.
+func (u *User) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+// internal/domain/vital.go
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Abnormal flags, as in HL7 v2 OBX-8.
+const (
+	FlagNormal       = "N"
+	FlagLow          = "L"
+	FlagHigh         = "H"
+	FlagCriticalLow  = "LL"
+	FlagCriticalHigh = "HH"
+	// FlagAbnormal and FlagCriticalAbnormal mark results without a
+	// direction, such as a positive culture.
+	FlagAbnormal         = "A"
+	FlagCriticalAbnormal = "AA"
+)
+
+// Kinds of vital sign.
+const (
+	VitalSystolic         = "systolic_bp"
+	VitalDiastolic        = "diastolic_bp"
+	VitalHeartRate        = "heart_rate"
+	VitalRespiratoryRate  = "respiratory_rate"
+	VitalTemperature      = "temperature"
+	VitalOxygenSaturation = "oxygen_saturation"
+	VitalWeight           = "weight"
+	VitalHeight           = "height"
+)
+
+// VitalDefinition describes a kind of vital sign: its LOINC code, the
+// UCUM unit values are stored in, the range of plausible values and the
+// adult reference ranges used for flags. A nil limit doesn't apply.
+type VitalDefinition struct {
+	Type    string `json:"type"`
+	LOINC   string `json:"loinc"`
+	Display string `json:"display"`
+	Unit    string `json:"unit"`
+	// ImperialUnit is the unit shown to clients asking for imperial units;
+	// empty when the measurement has no imperial form.
+	ImperialUnit string `json:"imperial_unit,omitempty"`
+	// Values outside Min and Max are rejected as entry errors.
+	Min          float64  `json:"min"`
+	Max          float64  `json:"max"`
+	Low          *float64 `json:"low,omitempty"`
+	High         *float64 `json:"high,omitempty"`
+	CriticalLow  *float64 `json:"critical_low,omitempty"`
+	CriticalHigh *float64 `json:"critical_high,omitempty"`
+}
+
+// bound makes a range limit of a VitalDefinition.
+func bound(v float64) *float64 {
+	return &v
+}
+
+// VitalDefinitions lists the vital signs that can be recorded, in display order.
+var VitalDefinitions = []VitalDefinition{
+	{Type: VitalSystolic, LOINC: "8480-6", Display: "Systolic blood pressure", Unit: "mm[Hg]",
+		Min: 30, Max: 300, Low: bound(90), High: bound(140), CriticalLow: bound(70), CriticalHigh: bound(180)},
+	{Type: VitalDiastolic, LOINC: "8462-4", Display: "Diastolic blood pressure", Unit: "mm[Hg]",
+		Min: 10, Max: 200, Low: bound(60), High: bound(90), CriticalLow: bound(40), CriticalHigh: bound(120)},
+	{Type: VitalHeartRate, LOINC: "8867-4", Display: "Heart rate", Unit: "/min",
+		Min: 10, Max: 300, Low: bound(60), High: bound(100), CriticalLow: bound(40), CriticalHigh: bound(130)},
+	{Type: VitalRespiratoryRate, LOINC: "9279-1", Display: "Respiratory rate", Unit: "/min",
+		Min: 2, Max: 80, Low: bound(12), High: bound(20), CriticalLow: bound(8), CriticalHigh: bound(30)},
+	{Type: VitalTemperature, LOINC: "8310-5", Display: "Body temperature", Unit: "Cel", ImperialUnit: "[degF]",
+		Min: 25, Max: 45, Low: bound(36.1), High: bound(37.8), CriticalLow: bound(35), CriticalHigh: bound(40)},
+	{Type: VitalOxygenSaturation, LOINC: "59408-5", Display: "Oxygen saturation by pulse oximetry", Unit: "%",
+		Min: 40, Max: 100, Low: bound(95), CriticalLow: bound(90)},
+	{Type: VitalWeight, LOINC: "29463-7", Display: "Body weight", Unit: "kg", ImperialUnit: "[lb_av]",
+		Min: 0.2, Max: 700},
+	{Type: VitalHeight, LOINC: "8302-2", Display: "Body height", Unit: "cm", ImperialUnit: "[in_i]",
+		Min: 20, Max: 280},
+}
+
+// LookupVital returns the definition of a vital sign type.
+func LookupVital(vitalType string) (VitalDefinition, bool) {
+	for _, def := range VitalDefinitions {
+		if def.Type == vitalType {
+			return def, true
+		}
+	}
+	return VitalDefinition{}, false
+}
+
+// Flag classifies a value in the definition's unit against its ranges.
+func (d VitalDefinition) Flag(value float64) string {
+	switch {
+	case d.CriticalLow != nil && value < *d.CriticalLow:
+		return FlagCriticalLow
+	case d.CriticalHigh != nil && value >= *d.CriticalHigh:
+		return FlagCriticalHigh
+	case d.Low != nil && value < *d.Low:
+		return FlagLow
+	case d.High != nil && value >= *d.High:
+		return FlagHigh
+	}
+	return FlagNormal
+}
+
+// VitalSign is one measurement. Value is in the definition's unit; the
+// value and unit as entered are kept alongside.
+type VitalSign struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	PatientID     uint      `gorm:"not null;index" json:"patient_id"`
+	AppointmentID *uint     `json:"appointment_id,omitempty"`
+	Type          string    `gorm:"not null" json:"type" validate:"required"`
+	LOINC         string    `gorm:"column:loinc;not null" json:"loinc"`
+	Value         float64   `gorm:"not null" json:"value"`
+	Unit          string    `gorm:"not null" json:"unit"`
+	EnteredValue  float64   `gorm:"not null" json:"entered_value"`
+	EnteredUnit   string    `gorm:"not null" json:"entered_unit"`
+	Flag          string    `gorm:"not null" json:"flag"`
+	MeasuredAt    time.Time `gorm:"not null" json:"measured_at"`
+	// RecordedByUserID is nil for the bootstrap admin.
+	RecordedByUserID *uint     `json:"recorded_by_user_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	// DeletedAt marks a measurement entered in error.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// VitalQuery selects measurements for a trend.
+type VitalQuery struct {
+	Types []string
+	From  time.Time
+	To    time.Time
+}
+
+// VitalTrend is the time series of one kind of vital sign, oldest first.
+type VitalTrend struct {
+	Type    string       `json:"type"`
+	LOINC   string       `json:"loinc"`
+	Display string       `json:"display"`
+	Unit    string       `json:"unit"`
+	Points  []VitalPoint `json:"points"`
+	Latest  *VitalPoint  `json:"latest,omitempty"`
+	Min     *float64     `json:"min,omitempty"`
+	Max     *float64     `json:"max,omitempty"`
+	// Change is the latest value minus the first one in the range.
+	Change *float64 `json:"change,omitempty"`
+}
+
+// VitalPoint is one measurement of a trend.
+type VitalPoint struct {
+	VitalID       uint      `json:"vital_id"`
+	MeasuredAt    time.Time `json:"measured_at"`
+	Value         float64   `json:"value"`
+	Flag          string    `json:"flag"`
+	AppointmentID *uint     `json:"appointment_id,omitempty"`
+}
@@ -0,0 +1,73 @@
+// internal/infrastracture/database/lock.go
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Keys of the Postgres advisory locks held by scheduled jobs that must not
+// run on several API servers at once.
+const (
+	EligibilityLockID int64 = 7262012
+	RetentionLockID   int64 = 7262013
+)
+
+// Names of the daily jobs in scheduled_job_runs.
+const (
+	EligibilityJob = "eligibility"
+	RetentionJob   = "retention"
+)
+
+// RunExclusive runs fn while holding the advisory lock key, and reports
+// false without running it when another session holds the lock. The lock
+// is held on a connection of its own and released when fn returns, or by
+// Postgres if the connection is lost.
+func RunExclusive(ctx context.Context, db *gorm.DB, key int64, fn func(ctx context.Context) error) (bool, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to acquire lock %d: %w", key, err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+
+	return true, fn(ctx)
+}
+
+// RunDaily runs fn under the advisory lock key unless job already
+// completed for day, a YYYY-MM-DD date, on any server, and records the
+// day once fn succeeds. It reports whether fn ran; a failed run is not
+// recorded, so the next attempt retries it.
+func RunDaily(ctx context.Context, db *gorm.DB, key int64, job, day string, fn func(ctx context.Context) error) (bool, error) {
+	ran := false
+	_, err := RunExclusive(ctx, db, key, func(ctx context.Context) error {
+		var done int64
+		err := db.WithContext(ctx).Raw("SELECT COUNT(*) FROM scheduled_job_runs WHERE job = ? AND last_run_on >= ?", job, day).
+			Scan(&done).Error
+		if err != nil || done > 0 {
+			return err
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		ran = true
+		return db.WithContext(ctx).Exec(`
+INSERT INTO scheduled_job_runs (job, last_run_on, updated_at) VALUES (?, ?, NOW())
+ON CONFLICT (job) DO UPDATE SET last_run_on = EXCLUDED.last_run_on, updated_at = EXCLUDED.updated_at`, job, day).Error
+	})
+	return ran, err
+}
@@ -0,0 +1,68 @@
+-- Baseline schema. Uses IF NOT EXISTS so databases previously created by
+-- GORM's AutoMigrate can adopt versioned migrations without data loss;
+-- columns those databases lack are added before anything indexes them.
+
+CREATE TABLE IF NOT EXISTS doctors (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT,
+    email      TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS patients (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT,
+    email       TEXT,
+    phone       TEXT,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    version     BIGINT NOT NULL DEFAULT 1,
+    data_key    TEXT,
+    key_id      TEXT,
+    email_index TEXT,
+    phone_index TEXT
+);
+
+ALTER TABLE patients
+    ADD COLUMN IF NOT EXISTS deleted_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS version     BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS data_key    TEXT,
+    ADD COLUMN IF NOT EXISTS key_id      TEXT,
+    ADD COLUMN IF NOT EXISTS email_index TEXT,
+    ADD COLUMN IF NOT EXISTS phone_index TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_patients_deleted_at ON patients (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_patients_key_id ON patients (key_id);
+CREATE INDEX IF NOT EXISTS idx_patients_email_index ON patients (email_index);
+CREATE INDEX IF NOT EXISTS idx_patients_phone_index ON patients (phone_index);
+
+CREATE TABLE IF NOT EXISTS appointments (
+    id         BIGSERIAL PRIMARY KEY,
+    patient_id BIGINT,
+    doctor_id  BIGINT,
+    date_time  TIMESTAMPTZ,
+    notes      TEXT,
+    status     TEXT NOT NULL DEFAULT 'scheduled',
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    version    BIGINT NOT NULL DEFAULT 1
+);
+
+-- The default fills status in for existing appointments, so they pass the
+-- status check added in 0002.
+ALTER TABLE appointments
+    ADD COLUMN IF NOT EXISTS status     TEXT NOT NULL DEFAULT 'scheduled',
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS version    BIGINT NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_appointments_patient_id ON appointments (patient_id);
+CREATE INDEX IF NOT EXISTS idx_appointments_deleted_at ON appointments (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key        VARCHAR(512) PRIMARY KEY,
+    tokens     DOUBLE PRECISION,
+    updated_at TIMESTAMPTZ
+);
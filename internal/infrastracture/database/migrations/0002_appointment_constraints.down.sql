@@ -0,0 +1,4 @@
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS chk_appointments_status;
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS fk_appointments_doctor;
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS fk_appointments_patient;
+DROP INDEX IF EXISTS idx_appointments_date_time;
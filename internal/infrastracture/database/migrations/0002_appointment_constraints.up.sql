@@ -0,0 +1,14 @@
+-- Constraints AutoMigrate never created. NOT VALID skips checking existing
+-- rows (older databases contain appointments for deleted patients) while
+-- still enforcing the constraints for every new or updated row.
+
+CREATE INDEX IF NOT EXISTS idx_appointments_date_time ON appointments (date_time);
+
+ALTER TABLE appointments
+    ADD CONSTRAINT fk_appointments_patient FOREIGN KEY (patient_id) REFERENCES patients (id) NOT VALID;
+
+ALTER TABLE appointments
+    ADD CONSTRAINT fk_appointments_doctor FOREIGN KEY (doctor_id) REFERENCES doctors (id) NOT VALID;
+
+ALTER TABLE appointments
+    ADD CONSTRAINT chk_appointments_status CHECK (status IN ('scheduled', 'cancelled')) NOT VALID;
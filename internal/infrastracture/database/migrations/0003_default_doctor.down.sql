@@ -0,0 +1,2 @@
+-- The default doctor may already have appointments; it is left in place.
+SELECT 1;
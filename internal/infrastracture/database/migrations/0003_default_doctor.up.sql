@@ -0,0 +1,4 @@
+-- Appointments are booked with the first doctor until doctor selection exists.
+INSERT INTO doctors (name, email, created_at, updated_at)
+SELECT 'Nicolas Asparria', 'mailtrap@demomailtrap.com', NOW(), NOW()
+WHERE NOT EXISTS (SELECT 1 FROM doctors);
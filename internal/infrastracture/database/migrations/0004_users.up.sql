@@ -0,0 +1,12 @@
+CREATE TABLE users (
+    id           BIGSERIAL PRIMARY KEY,
+    name         TEXT NOT NULL,
+    email        TEXT NOT NULL,
+    role         TEXT NOT NULL CHECK (role IN ('admin', 'doctor', 'nurse', 'receptionist')),
+    api_key_hash TEXT NOT NULL,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_users_api_key_hash ON users (api_key_hash);
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
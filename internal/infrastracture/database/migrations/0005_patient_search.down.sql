@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS patient_search_tokens;
+
+DROP INDEX IF EXISTS idx_patients_dob_index;
+ALTER TABLE patients DROP COLUMN IF EXISTS dob_index;
+ALTER TABLE patients DROP COLUMN IF EXISTS date_of_birth;
@@ -0,0 +1,15 @@
+-- Patient names are encrypted, so fuzzy search works on keyed hashes of
+-- name and phone trigrams instead of pg_trgm over plaintext. Existing rows
+-- are indexed by "doctorsctl patients reindex".
+
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS date_of_birth TEXT;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS dob_index TEXT;
+CREATE INDEX IF NOT EXISTS idx_patients_dob_index ON patients (dob_index);
+
+CREATE TABLE patient_search_tokens (
+    token      TEXT   NOT NULL,
+    patient_id BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    PRIMARY KEY (token, patient_id)
+);
+
+CREATE INDEX idx_patient_search_tokens_patient_id ON patient_search_tokens (patient_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS patient_merges;
+
+ALTER TABLE patients DROP COLUMN IF EXISTS merged_into_id;
@@ -0,0 +1,15 @@
+ALTER TABLE patients ADD COLUMN merged_into_id BIGINT;
+
+CREATE TABLE patient_merges (
+    id                    BIGSERIAL PRIMARY KEY,
+    survivor_id           BIGINT NOT NULL,
+    duplicate_id          BIGINT NOT NULL,
+    moved_appointment_ids JSONB NOT NULL DEFAULT '[]',
+    merged_by_user_id     BIGINT,
+    merged_at             TIMESTAMPTZ NOT NULL,
+    reverted_by_user_id   BIGINT,
+    reverted_at           TIMESTAMPTZ
+);
+
+CREATE INDEX idx_patient_merges_survivor_id ON patient_merges (survivor_id);
+CREATE INDEX idx_patient_merges_duplicate_id ON patient_merges (duplicate_id);
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS mrn_sequences;
+
+DROP INDEX IF EXISTS idx_patients_national_id_index;
+DROP INDEX IF EXISTS idx_patients_mrn;
+
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS mrn,
+    DROP COLUMN IF EXISTS national_id,
+    DROP COLUMN IF EXISTS national_id_index,
+    DROP COLUMN IF EXISTS preferred_name,
+    DROP COLUMN IF EXISTS pronouns,
+    DROP COLUMN IF EXISTS sex,
+    DROP COLUMN IF EXISTS gender_identity,
+    DROP COLUMN IF EXISTS preferred_language,
+    DROP COLUMN IF EXISTS preferred_contact_channel,
+    DROP COLUMN IF EXISTS address_line1,
+    DROP COLUMN IF EXISTS address_line2,
+    DROP COLUMN IF EXISTS address_city,
+    DROP COLUMN IF EXISTS address_region,
+    DROP COLUMN IF EXISTS address_postal_code,
+    DROP COLUMN IF EXISTS address_country,
+    DROP COLUMN IF EXISTS emergency_contacts;
@@ -0,0 +1,31 @@
+-- Identifying columns (national ID, preferred name, gender identity, street
+-- address, emergency contacts) hold ciphertext; see the patient repository.
+
+ALTER TABLE patients
+    ADD COLUMN mrn                       TEXT,
+    ADD COLUMN national_id               TEXT,
+    ADD COLUMN national_id_index         TEXT,
+    ADD COLUMN preferred_name            TEXT,
+    ADD COLUMN pronouns                  TEXT,
+    ADD COLUMN sex                       TEXT CONSTRAINT chk_patients_sex
+        CHECK (sex IS NULL OR sex IN ('', 'female', 'male', 'intersex', 'unknown')),
+    ADD COLUMN gender_identity           TEXT,
+    ADD COLUMN preferred_language        TEXT,
+    ADD COLUMN preferred_contact_channel TEXT NOT NULL DEFAULT 'email',
+    ADD COLUMN address_line1             TEXT,
+    ADD COLUMN address_line2             TEXT,
+    ADD COLUMN address_city              TEXT,
+    ADD COLUMN address_region            TEXT,
+    ADD COLUMN address_postal_code       TEXT,
+    ADD COLUMN address_country           TEXT,
+    ADD COLUMN emergency_contacts        TEXT;
+
+CREATE UNIQUE INDEX idx_patients_mrn ON patients (mrn) WHERE mrn IS NOT NULL AND mrn <> '';
+CREATE INDEX idx_patients_national_id_index ON patients (national_id_index);
+
+-- One counter per scope. There is a single "default" scope today; a
+-- per-tenant scope only needs a new row.
+CREATE TABLE mrn_sequences (
+    scope      TEXT PRIMARY KEY,
+    next_value BIGINT NOT NULL
+);
@@ -0,0 +1,10 @@
+ALTER TABLE patient_merges DROP COLUMN IF EXISTS moved_relationship_ids;
+
+DELETE FROM users WHERE role = 'patient';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_patient;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_role;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('admin', 'doctor', 'nurse', 'receptionist'));
+ALTER TABLE users DROP COLUMN IF EXISTS patient_id;
+
+DROP TABLE IF EXISTS patient_relationships;
@@ -0,0 +1,26 @@
+CREATE TABLE patient_relationships (
+    id                     BIGSERIAL PRIMARY KEY,
+    patient_id             BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    related_patient_id     BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    type                   TEXT NOT NULL CHECK (type IN ('guardian', 'caregiver')),
+    can_book               BOOLEAN NOT NULL DEFAULT FALSE,
+    can_view_records       BOOLEAN NOT NULL DEFAULT FALSE,
+    receives_notifications BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at             TIMESTAMPTZ,
+    updated_at             TIMESTAMPTZ,
+    CONSTRAINT chk_patient_relationships_distinct CHECK (patient_id <> related_patient_id)
+);
+
+CREATE UNIQUE INDEX idx_patient_relationships_pair ON patient_relationships (patient_id, related_patient_id);
+CREATE INDEX idx_patient_relationships_related_patient_id ON patient_relationships (related_patient_id);
+
+-- Portal users are patients signing in on their own (and their dependents') behalf.
+ALTER TABLE users ADD COLUMN patient_id BIGINT
+    CONSTRAINT fk_users_patient REFERENCES patients (id) ON DELETE CASCADE;
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT chk_users_role
+    CHECK (role IN ('admin', 'doctor', 'nurse', 'receptionist', 'patient'));
+ALTER TABLE users ADD CONSTRAINT chk_users_patient
+    CHECK ((role = 'patient') = (patient_id IS NOT NULL));
+
+ALTER TABLE patient_merges ADD COLUMN moved_relationship_ids JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,11 @@
+ALTER TABLE patient_merges
+    ADD COLUMN moved_appointment_ids JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN moved_relationship_ids JSONB NOT NULL DEFAULT '[]';
+UPDATE patient_merges SET
+    moved_appointment_ids = COALESCE(moved_records -> 'appointments', '[]'),
+    moved_relationship_ids = COALESCE(moved_records -> 'patient_relationships', '[]');
+ALTER TABLE patient_merges DROP COLUMN IF EXISTS moved_records;
+
+ALTER TABLE appointments DROP COLUMN IF EXISTS eligibility_check_id;
+DROP TABLE IF EXISTS eligibility_checks;
+DROP TABLE IF EXISTS insurance_policies;
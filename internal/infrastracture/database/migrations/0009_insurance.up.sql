@@ -0,0 +1,51 @@
+CREATE TABLE insurance_policies (
+    id                       BIGSERIAL PRIMARY KEY,
+    patient_id               BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    payer_name               TEXT NOT NULL,
+    payer_id                 TEXT NOT NULL,
+    member_id                TEXT NOT NULL,
+    group_number             TEXT,
+    subscriber_relationship  TEXT NOT NULL
+        CHECK (subscriber_relationship IN ('self', 'spouse', 'child', 'other')),
+    subscriber_name          TEXT,
+    subscriber_date_of_birth TEXT,
+    effective_from           TEXT NOT NULL,
+    effective_to             TEXT,
+    priority                 TEXT NOT NULL CHECK (priority IN ('primary', 'secondary', 'tertiary')),
+    created_at               TIMESTAMPTZ,
+    updated_at               TIMESTAMPTZ,
+    deleted_at               TIMESTAMPTZ
+);
+
+CREATE INDEX idx_insurance_policies_patient_id ON insurance_policies (patient_id);
+CREATE INDEX idx_insurance_policies_deleted_at ON insurance_policies (deleted_at);
+
+CREATE TABLE eligibility_checks (
+    id                 BIGSERIAL PRIMARY KEY,
+    policy_id          BIGINT NOT NULL REFERENCES insurance_policies (id) ON DELETE CASCADE,
+    service_date       TEXT NOT NULL,
+    status             TEXT NOT NULL
+        CHECK (status IN ('pending', 'active', 'inactive', 'rejected', 'unknown', 'error')),
+    plan_begin         TEXT,
+    plan_end           TEXT,
+    benefits           JSONB NOT NULL DEFAULT '[]',
+    rejections         JSONB,
+    error              TEXT,
+    request            TEXT,
+    response           TEXT,
+    checked_by_user_id BIGINT,
+    checked_at         TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_eligibility_checks_policy_id ON eligibility_checks (policy_id);
+
+-- The latest result for the patient's primary policy, shown with upcoming appointments.
+ALTER TABLE appointments ADD COLUMN eligibility_check_id BIGINT
+    REFERENCES eligibility_checks (id) ON DELETE SET NULL;
+
+-- Merges now record moved rows per table rather than a column per table.
+ALTER TABLE patient_merges ADD COLUMN moved_records JSONB NOT NULL DEFAULT '{}';
+UPDATE patient_merges SET moved_records = jsonb_build_object(
+    'appointments', moved_appointment_ids,
+    'patient_relationships', moved_relationship_ids);
+ALTER TABLE patient_merges DROP COLUMN moved_appointment_ids, DROP COLUMN moved_relationship_ids;
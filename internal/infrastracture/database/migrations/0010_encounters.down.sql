@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS encounter_addenda;
+DROP TABLE IF EXISTS encounters;
+
+UPDATE appointments SET status = 'scheduled' WHERE status = 'completed';
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS chk_appointments_status;
+ALTER TABLE appointments
+    ADD CONSTRAINT chk_appointments_status CHECK (status IN ('scheduled', 'cancelled')) NOT VALID;
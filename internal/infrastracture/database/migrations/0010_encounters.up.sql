@@ -0,0 +1,39 @@
+-- A visit that took place is marked completed before it can be documented.
+ALTER TABLE appointments DROP CONSTRAINT chk_appointments_status;
+ALTER TABLE appointments
+    ADD CONSTRAINT chk_appointments_status CHECK (status IN ('scheduled', 'cancelled', 'completed')) NOT VALID;
+
+-- The SOAP sections and addenda are encrypted with a per-encounter data key.
+CREATE TABLE encounters (
+    id                BIGSERIAL PRIMARY KEY,
+    appointment_id    BIGINT NOT NULL REFERENCES appointments (id) ON DELETE CASCADE,
+    patient_id        BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    doctor_id         BIGINT NOT NULL,
+    subjective        TEXT,
+    objective         TEXT,
+    assessment        TEXT,
+    plan              TEXT,
+    status            TEXT NOT NULL CHECK (status IN ('draft', 'signed')),
+    author_user_id    BIGINT,
+    signed_by_user_id BIGINT,
+    signed_at         TIMESTAMPTZ,
+    data_key          TEXT,
+    key_id            TEXT,
+    created_at        TIMESTAMPTZ,
+    updated_at        TIMESTAMPTZ,
+    version           BIGINT NOT NULL DEFAULT 1,
+    CONSTRAINT chk_encounters_signed CHECK ((status = 'signed') = (signed_at IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX idx_encounters_appointment_id ON encounters (appointment_id);
+CREATE INDEX idx_encounters_patient_id ON encounters (patient_id);
+
+CREATE TABLE encounter_addenda (
+    id             BIGSERIAL PRIMARY KEY,
+    encounter_id   BIGINT NOT NULL REFERENCES encounters (id) ON DELETE CASCADE,
+    text           TEXT NOT NULL,
+    author_user_id BIGINT,
+    created_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_encounter_addenda_encounter_id ON encounter_addenda (encounter_id);
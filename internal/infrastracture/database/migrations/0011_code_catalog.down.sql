@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS appointment_procedures;
+DROP TABLE IF EXISTS appointment_diagnoses;
+DROP TABLE IF EXISTS medical_codes;
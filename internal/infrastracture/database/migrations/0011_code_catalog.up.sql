@@ -0,0 +1,46 @@
+CREATE TABLE medical_codes (
+    system            TEXT NOT NULL CHECK (system IN ('ICD-10-CM', 'CPT', 'HCPCS')),
+    code              TEXT NOT NULL,
+    description       TEXT NOT NULL,
+    short_description TEXT,
+    billable          BOOLEAN NOT NULL DEFAULT TRUE,
+    active            BOOLEAN NOT NULL DEFAULT TRUE,
+    release           TEXT NOT NULL,
+    updated_at        TIMESTAMPTZ,
+    search            TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', description)) STORED,
+    PRIMARY KEY (system, code)
+);
+
+-- Autocomplete: code prefixes and description words.
+CREATE INDEX idx_medical_codes_code_prefix ON medical_codes (system, code text_pattern_ops);
+CREATE INDEX idx_medical_codes_search ON medical_codes USING GIN (search);
+
+-- Codes are never deleted from the catalog, only deactivated, so these
+-- foreign keys keep every recorded code resolvable.
+CREATE TABLE appointment_diagnoses (
+    id                 BIGSERIAL PRIMARY KEY,
+    appointment_id     BIGINT NOT NULL REFERENCES appointments (id) ON DELETE CASCADE,
+    system             TEXT NOT NULL CHECK (system = 'ICD-10-CM'),
+    code               TEXT NOT NULL,
+    rank               INTEGER NOT NULL CHECK (rank BETWEEN 1 AND 12),
+    created_by_user_id BIGINT,
+    created_at         TIMESTAMPTZ,
+    CONSTRAINT fk_appointment_diagnoses_code FOREIGN KEY (system, code) REFERENCES medical_codes (system, code)
+);
+
+CREATE UNIQUE INDEX idx_appointment_diagnoses_code ON appointment_diagnoses (appointment_id, code);
+CREATE UNIQUE INDEX idx_appointment_diagnoses_rank ON appointment_diagnoses (appointment_id, rank);
+
+CREATE TABLE appointment_procedures (
+    id                 BIGSERIAL PRIMARY KEY,
+    appointment_id     BIGINT NOT NULL REFERENCES appointments (id) ON DELETE CASCADE,
+    system             TEXT NOT NULL CHECK (system IN ('CPT', 'HCPCS')),
+    code               TEXT NOT NULL,
+    modifiers          JSONB NOT NULL DEFAULT '[]',
+    units              INTEGER NOT NULL DEFAULT 1 CHECK (units > 0),
+    created_by_user_id BIGINT,
+    created_at         TIMESTAMPTZ,
+    CONSTRAINT fk_appointment_procedures_code FOREIGN KEY (system, code) REFERENCES medical_codes (system, code)
+);
+
+CREATE INDEX idx_appointment_procedures_appointment_id ON appointment_procedures (appointment_id);
@@ -0,0 +1,21 @@
+CREATE TABLE vital_signs (
+    id                  BIGSERIAL PRIMARY KEY,
+    patient_id          BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    appointment_id      BIGINT REFERENCES appointments (id) ON DELETE SET NULL,
+    type                TEXT NOT NULL,
+    loinc               TEXT NOT NULL,
+    value               DOUBLE PRECISION NOT NULL,
+    unit                TEXT NOT NULL,
+    entered_value       DOUBLE PRECISION NOT NULL,
+    entered_unit        TEXT NOT NULL,
+    flag                TEXT NOT NULL CHECK (flag IN ('N', 'L', 'H', 'LL', 'HH')),
+    measured_at         TIMESTAMPTZ NOT NULL,
+    recorded_by_user_id BIGINT,
+    created_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+
+-- Trends read one patient's measurements of a type in time order.
+CREATE INDEX idx_vital_signs_patient_type ON vital_signs (patient_id, type, measured_at);
+CREATE INDEX idx_vital_signs_appointment_id ON vital_signs (appointment_id);
+CREATE INDEX idx_vital_signs_deleted_at ON vital_signs (deleted_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS patient_problems;
+DROP TABLE IF EXISTS patient_medications;
+DROP TABLE IF EXISTS patient_allergies;
@@ -0,0 +1,63 @@
+CREATE TABLE patient_allergies (
+    id                  BIGSERIAL PRIMARY KEY,
+    patient_id          BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    substance           TEXT NOT NULL,
+    category            TEXT NOT NULL CHECK (category IN ('medication', 'food', 'environment', 'other')),
+    reaction            TEXT,
+    severity            TEXT CHECK (severity IN ('', 'mild', 'moderate', 'severe')),
+    status              TEXT NOT NULL CHECK (status IN ('active', 'resolved')),
+    onset_date          TEXT,
+    resolved_on         TEXT,
+    source              TEXT NOT NULL CHECK (source IN ('patient_reported', 'clinician', 'external')),
+    encounter_id        BIGINT REFERENCES encounters (id) ON DELETE SET NULL,
+    recorded_by_user_id BIGINT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_patient_allergies_patient_id ON patient_allergies (patient_id);
+CREATE INDEX idx_patient_allergies_deleted_at ON patient_allergies (deleted_at);
+
+CREATE TABLE patient_medications (
+    id                  BIGSERIAL PRIMARY KEY,
+    patient_id          BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    name                TEXT NOT NULL,
+    rx_norm_code        TEXT,
+    dose                TEXT,
+    route               TEXT,
+    frequency           TEXT,
+    status              TEXT NOT NULL CHECK (status IN ('active', 'resolved')),
+    onset_date          TEXT,
+    resolved_on         TEXT,
+    source              TEXT NOT NULL CHECK (source IN ('patient_reported', 'clinician', 'external')),
+    encounter_id        BIGINT REFERENCES encounters (id) ON DELETE SET NULL,
+    recorded_by_user_id BIGINT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_patient_medications_patient_id ON patient_medications (patient_id);
+CREATE INDEX idx_patient_medications_deleted_at ON patient_medications (deleted_at);
+
+CREATE TABLE patient_problems (
+    id                  BIGSERIAL PRIMARY KEY,
+    patient_id          BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    description         TEXT NOT NULL,
+    -- An ICD-10-CM code of the catalog; not a foreign key so that
+    -- problems imported from elsewhere can keep codes this catalog lacks.
+    code                TEXT,
+    status              TEXT NOT NULL CHECK (status IN ('active', 'resolved')),
+    onset_date          TEXT,
+    resolved_on         TEXT,
+    source              TEXT NOT NULL CHECK (source IN ('patient_reported', 'clinician', 'external')),
+    encounter_id        BIGINT REFERENCES encounters (id) ON DELETE SET NULL,
+    recorded_by_user_id BIGINT,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_patient_problems_patient_id ON patient_problems (patient_id);
+CREATE INDEX idx_patient_problems_deleted_at ON patient_problems (deleted_at);
@@ -0,0 +1,4 @@
+ALTER TABLE patient_medications DROP COLUMN IF EXISTS prescription_id;
+DROP TABLE IF EXISTS prescriptions;
+DROP TABLE IF EXISTS drug_interactions;
+DROP TABLE IF EXISTS drugs;
@@ -0,0 +1,55 @@
+CREATE TABLE drugs (
+    rxcui       TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    ingredients JSONB NOT NULL DEFAULT '[]',
+    strength    TEXT,
+    dose_form   TEXT,
+    classes     JSONB NOT NULL DEFAULT '[]',
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    release     TEXT NOT NULL,
+    updated_at  TIMESTAMPTZ
+);
+
+-- Prescribers look drugs up by the start of their name.
+CREATE INDEX idx_drugs_name ON drugs (lower(name) text_pattern_ops);
+
+CREATE TABLE drug_interactions (
+    ingredient_a TEXT NOT NULL,
+    ingredient_b TEXT NOT NULL,
+    severity     TEXT NOT NULL CHECK (severity IN ('minor', 'moderate', 'major', 'contraindicated')),
+    description  TEXT NOT NULL,
+    PRIMARY KEY (ingredient_a, ingredient_b),
+    CONSTRAINT chk_drug_interactions_order CHECK (ingredient_a < ingredient_b)
+);
+
+CREATE INDEX idx_drug_interactions_ingredient_b ON drug_interactions (ingredient_b);
+
+CREATE TABLE prescriptions (
+    id                    BIGSERIAL PRIMARY KEY,
+    patient_id            BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    encounter_id          BIGINT NOT NULL REFERENCES encounters (id) ON DELETE CASCADE,
+    doctor_id             BIGINT NOT NULL,
+    rxcui                 TEXT NOT NULL REFERENCES drugs (rxcui),
+    drug_name             TEXT NOT NULL,
+    strength              TEXT,
+    dose_form             TEXT,
+    dosage                TEXT NOT NULL,
+    quantity              INTEGER NOT NULL CHECK (quantity > 0),
+    quantity_unit         TEXT,
+    refills               INTEGER NOT NULL CHECK (refills BETWEEN 0 AND 11),
+    instructions          TEXT,
+    status                TEXT NOT NULL CHECK (status IN ('active', 'cancelled')),
+    warnings              JSONB NOT NULL DEFAULT '[]',
+    override_reason       TEXT,
+    prescribed_by_user_id BIGINT,
+    cancel_reason         TEXT,
+    cancelled_at          TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ,
+    updated_at            TIMESTAMPTZ
+);
+
+CREATE INDEX idx_prescriptions_patient_id ON prescriptions (patient_id);
+CREATE INDEX idx_prescriptions_encounter_id ON prescriptions (encounter_id);
+
+ALTER TABLE patient_medications
+    ADD COLUMN prescription_id BIGINT REFERENCES prescriptions (id) ON DELETE SET NULL;
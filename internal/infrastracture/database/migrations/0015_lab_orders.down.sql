@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS hl7_messages;
+DROP TABLE IF EXISTS lab_results;
+DROP TABLE IF EXISTS lab_orders;
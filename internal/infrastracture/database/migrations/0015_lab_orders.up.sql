@@ -0,0 +1,56 @@
+CREATE TABLE lab_orders (
+    id                  BIGSERIAL PRIMARY KEY,
+    patient_id          BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    appointment_id      BIGINT NOT NULL REFERENCES appointments (id) ON DELETE CASCADE,
+    doctor_id           BIGINT NOT NULL,
+    placer_order_number TEXT UNIQUE,
+    tests               JSONB NOT NULL DEFAULT '[]',
+    priority            TEXT NOT NULL CHECK (priority IN ('routine', 'stat')),
+    clinical_info       TEXT,
+    status              TEXT NOT NULL CHECK (status IN ('pending', 'failed', 'sent', 'preliminary', 'final')),
+    last_error          TEXT,
+    ordered_by_user_id  BIGINT,
+    filler_order_number TEXT,
+    reported_tests      JSONB NOT NULL DEFAULT '[]',
+    sent_at             TIMESTAMPTZ,
+    resulted_at         TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_lab_orders_patient_id ON lab_orders (patient_id);
+CREATE INDEX idx_lab_orders_appointment_id ON lab_orders (appointment_id);
+
+CREATE TABLE lab_results (
+    id              BIGSERIAL PRIMARY KEY,
+    lab_order_id    BIGINT NOT NULL REFERENCES lab_orders (id) ON DELETE CASCADE,
+    patient_id      BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    code            TEXT NOT NULL,
+    name            TEXT,
+    value_type      TEXT,
+    value           TEXT,
+    units           TEXT,
+    reference_range TEXT,
+    flag            TEXT CHECK (flag IN ('', 'N', 'L', 'H', 'LL', 'HH', 'A', 'AA')),
+    status          TEXT NOT NULL CHECK (status IN ('preliminary', 'final', 'corrected')),
+    observed_at     TIMESTAMPTZ,
+    received_at     TIMESTAMPTZ NOT NULL,
+    -- A corrected result replaces the earlier one for the same test.
+    CONSTRAINT uq_lab_results_order_code UNIQUE (lab_order_id, code)
+);
+
+CREATE INDEX idx_lab_results_patient_id ON lab_results (patient_id);
+
+CREATE TABLE hl7_messages (
+    id           BIGSERIAL PRIMARY KEY,
+    direction    TEXT NOT NULL CHECK (direction IN ('inbound', 'outbound')),
+    type         TEXT NOT NULL,
+    control_id   TEXT NOT NULL,
+    lab_order_id BIGINT REFERENCES lab_orders (id) ON DELETE SET NULL,
+    payload      TEXT NOT NULL,
+    ack_code     TEXT,
+    error        TEXT,
+    created_at   TIMESTAMPTZ
+);
+
+CREATE INDEX idx_hl7_messages_control_id ON hl7_messages (direction, control_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_appointments_updated_at;
+DROP INDEX IF EXISTS idx_patients_updated_at;
+DROP TABLE IF EXISTS bulk_exports;
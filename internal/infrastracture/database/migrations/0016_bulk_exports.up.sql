@@ -0,0 +1,26 @@
+CREATE TABLE bulk_exports (
+    id                   BIGSERIAL PRIMARY KEY,
+    level                TEXT NOT NULL CHECK (level IN ('system', 'patient')),
+    types                JSONB NOT NULL DEFAULT '[]',
+    since                TIMESTAMPTZ,
+    transaction_time     TIMESTAMPTZ NOT NULL,
+    status               TEXT NOT NULL CHECK (status IN ('accepted', 'in_progress', 'completed', 'failed')),
+    total                INTEGER NOT NULL DEFAULT 0,
+    written              INTEGER NOT NULL DEFAULT 0,
+    files                JSONB NOT NULL DEFAULT '[]',
+    error                TEXT,
+    request              TEXT NOT NULL,
+    requested_by_user_id BIGINT,
+    started_at           TIMESTAMPTZ,
+    completed_at         TIMESTAMPTZ,
+    expires_at           TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ,
+    updated_at           TIMESTAMPTZ
+);
+
+-- The export worker looks for work by status.
+CREATE INDEX idx_bulk_exports_status ON bulk_exports (status);
+
+-- Incremental exports select rows changed since the previous one.
+CREATE INDEX idx_patients_updated_at ON patients (updated_at);
+CREATE INDEX idx_appointments_updated_at ON appointments (updated_at);
@@ -0,0 +1,21 @@
+CREATE TABLE patient_imports (
+    id                   BIGSERIAL PRIMARY KEY,
+    file_name            TEXT,
+    dry_run              BOOLEAN NOT NULL DEFAULT FALSE,
+    on_duplicate         TEXT NOT NULL CHECK (on_duplicate IN ('skip', 'create')),
+    columns              JSONB NOT NULL DEFAULT '{}',
+    ignored_columns      JSONB NOT NULL DEFAULT '[]',
+    status               TEXT NOT NULL CHECK (status IN ('running', 'completed', 'failed')),
+    rows                 INTEGER NOT NULL DEFAULT 0,
+    processed            INTEGER NOT NULL DEFAULT 0,
+    imported             INTEGER NOT NULL DEFAULT 0,
+    skipped              INTEGER NOT NULL DEFAULT 0,
+    failed               INTEGER NOT NULL DEFAULT 0,
+    -- Issues hold row numbers and field errors, never patient data.
+    issues               JSONB NOT NULL DEFAULT '[]',
+    error                TEXT,
+    requested_by_user_id BIGINT,
+    completed_at         TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ,
+    updated_at           TIMESTAMPTZ
+);
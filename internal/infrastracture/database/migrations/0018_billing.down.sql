@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS invoice_sequences;
+DROP TABLE IF EXISTS invoice_payments;
+DROP TABLE IF EXISTS invoice_lines;
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS fee_schedule;
+ALTER TABLE appointments DROP COLUMN IF EXISTS type;
@@ -0,0 +1,82 @@
+ALTER TABLE appointments ADD COLUMN type TEXT;
+
+CREATE TABLE fee_schedule (
+    id               BIGSERIAL PRIMARY KEY,
+    appointment_type TEXT NOT NULL UNIQUE,
+    description      TEXT NOT NULL,
+    code             TEXT,
+    unit_price_cents BIGINT NOT NULL CHECK (unit_price_cents >= 0),
+    tax_rate         NUMERIC(5, 2) NOT NULL CHECK (tax_rate BETWEEN 0 AND 100),
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ
+);
+
+CREATE TABLE invoices (
+    id                 BIGSERIAL PRIMARY KEY,
+    number             TEXT,
+    patient_id         BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    -- Invoices outlive the appointments they bill.
+    appointment_id     BIGINT REFERENCES appointments (id) ON DELETE SET NULL,
+    status             TEXT NOT NULL CHECK (status IN ('draft', 'issued', 'paid', 'void')),
+    currency           TEXT NOT NULL,
+    subtotal_cents     BIGINT NOT NULL DEFAULT 0,
+    discount_cents     BIGINT NOT NULL DEFAULT 0,
+    tax_cents          BIGINT NOT NULL DEFAULT 0,
+    total_cents        BIGINT NOT NULL DEFAULT 0,
+    paid_cents         BIGINT NOT NULL DEFAULT 0,
+    balance_cents      BIGINT NOT NULL DEFAULT 0,
+    due_date           TEXT,
+    notes              TEXT,
+    void_reason        TEXT,
+    created_by_user_id BIGINT,
+    issued_at          TIMESTAMPTZ,
+    paid_at            TIMESTAMPTZ,
+    voided_at          TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_invoices_number ON invoices (number) WHERE number IS NOT NULL AND number <> '';
+-- One invoice per appointment; voided ones don't count, so a visit can be billed again.
+CREATE UNIQUE INDEX idx_invoices_appointment_id ON invoices (appointment_id) WHERE status <> 'void';
+CREATE INDEX idx_invoices_patient_id ON invoices (patient_id);
+
+CREATE TABLE invoice_lines (
+    id               BIGSERIAL PRIMARY KEY,
+    invoice_id       BIGINT NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    position         INTEGER NOT NULL,
+    description      TEXT NOT NULL,
+    code             TEXT,
+    quantity         INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price_cents BIGINT NOT NULL CHECK (unit_price_cents >= 0),
+    discount_cents   BIGINT NOT NULL CHECK (discount_cents >= 0),
+    tax_rate         NUMERIC(5, 2) NOT NULL,
+    tax_cents        BIGINT NOT NULL,
+    total_cents      BIGINT NOT NULL
+);
+
+CREATE INDEX idx_invoice_lines_invoice_id ON invoice_lines (invoice_id);
+
+CREATE TABLE invoice_payments (
+    id                  BIGSERIAL PRIMARY KEY,
+    invoice_id          BIGINT NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    patient_id          BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    kind                TEXT NOT NULL CHECK (kind IN ('payment', 'refund')),
+    amount_cents        BIGINT NOT NULL CHECK (amount_cents > 0),
+    method              TEXT NOT NULL,
+    reference           TEXT,
+    refund_of_id        BIGINT REFERENCES invoice_payments (id),
+    note                TEXT,
+    received_at         TIMESTAMPTZ NOT NULL,
+    recorded_by_user_id BIGINT,
+    created_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_invoice_payments_invoice_id ON invoice_payments (invoice_id);
+CREATE INDEX idx_invoice_payments_patient_id ON invoice_payments (patient_id);
+
+-- One counter per scope, like mrn_sequences.
+CREATE TABLE invoice_sequences (
+    scope      TEXT PRIMARY KEY,
+    next_value BIGINT NOT NULL
+);
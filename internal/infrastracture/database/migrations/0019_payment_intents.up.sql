@@ -0,0 +1,38 @@
+CREATE TABLE payment_intents (
+    id                 BIGSERIAL PRIMARY KEY,
+    purpose            TEXT NOT NULL CHECK (purpose IN ('invoice', 'deposit')),
+    patient_id         BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    invoice_id         BIGINT REFERENCES invoices (id) ON DELETE SET NULL,
+    appointment_id     BIGINT REFERENCES appointments (id) ON DELETE SET NULL,
+    amount_cents       BIGINT NOT NULL CHECK (amount_cents > 0),
+    currency           TEXT NOT NULL,
+    provider           TEXT NOT NULL,
+    provider_intent_id TEXT NOT NULL,
+    client_secret      TEXT,
+    status             TEXT NOT NULL,
+    failure_reason     TEXT,
+    idempotency_key    TEXT NOT NULL,
+    payment_id         BIGINT REFERENCES invoice_payments (id) ON DELETE SET NULL,
+    created_by_user_id BIGINT,
+    succeeded_at       TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_payment_intents_provider_intent ON payment_intents (provider, provider_intent_id);
+CREATE UNIQUE INDEX idx_payment_intents_idempotency_key ON payment_intents (idempotency_key);
+CREATE INDEX idx_payment_intents_patient_id ON payment_intents (patient_id);
+CREATE INDEX idx_payment_intents_invoice_id ON payment_intents (invoice_id);
+CREATE INDEX idx_payment_intents_appointment_id ON payment_intents (appointment_id);
+-- Intents still waiting on the provider, for reconciliation.
+CREATE INDEX idx_payment_intents_pending ON payment_intents (created_at) WHERE status NOT IN ('succeeded', 'canceled');
+
+-- Webhook events already handled. Providers deliver an event at least
+-- once, so a repeated delivery is recognized here and ignored.
+CREATE TABLE payment_webhook_events (
+    provider    TEXT NOT NULL,
+    event_id    TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, event_id)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS remittances;
+DROP TABLE IF EXISTS claim_status_history;
+DROP TABLE IF EXISTS claims;
+DROP TABLE IF EXISTS claim_files;
+DELETE FROM invoice_payments WHERE kind = 'adjustment';
+ALTER TABLE invoice_payments DROP CONSTRAINT invoice_payments_kind_check;
+ALTER TABLE invoice_payments ADD CONSTRAINT invoice_payments_kind_check CHECK (kind IN ('payment', 'refund'));
+ALTER TABLE invoices DROP COLUMN IF EXISTS adjusted_cents;
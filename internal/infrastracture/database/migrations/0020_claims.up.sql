@@ -0,0 +1,81 @@
+ALTER TABLE invoices ADD COLUMN adjusted_cents BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE invoice_payments DROP CONSTRAINT invoice_payments_kind_check;
+ALTER TABLE invoice_payments ADD CONSTRAINT invoice_payments_kind_check
+    CHECK (kind IN ('payment', 'refund', 'adjustment'));
+
+CREATE TABLE claim_files (
+    id                 BIGSERIAL PRIMARY KEY,
+    control_number     INTEGER NOT NULL UNIQUE,
+    claim_count        INTEGER NOT NULL,
+    charge_cents       BIGINT NOT NULL,
+    content            TEXT,
+    created_by_user_id BIGINT,
+    created_at         TIMESTAMPTZ
+);
+
+CREATE TABLE claims (
+    id                           BIGSERIAL PRIMARY KEY,
+    number                       TEXT NOT NULL,
+    patient_id                   BIGINT NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    -- Claims outlive the appointments they bill, like invoices.
+    appointment_id               BIGINT REFERENCES appointments (id) ON DELETE SET NULL,
+    invoice_id                   BIGINT NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    policy_id                    BIGINT NOT NULL REFERENCES insurance_policies (id) ON DELETE CASCADE,
+    payer_name                   TEXT NOT NULL,
+    payer_id                     TEXT NOT NULL,
+    service_date                 TEXT NOT NULL,
+    diagnoses                    JSONB NOT NULL,
+    lines                        JSONB NOT NULL,
+    charge_cents                 BIGINT NOT NULL CHECK (charge_cents >= 0),
+    paid_cents                   BIGINT NOT NULL DEFAULT 0,
+    adjusted_cents               BIGINT NOT NULL DEFAULT 0,
+    patient_responsibility_cents BIGINT NOT NULL DEFAULT 0,
+    payer_claim_number           TEXT,
+    status                       TEXT NOT NULL
+        CHECK (status IN ('ready', 'submitted', 'accepted', 'rejected', 'paid', 'denied', 'void')),
+    file_id                      BIGINT REFERENCES claim_files (id) ON DELETE SET NULL,
+    created_by_user_id           BIGINT,
+    submitted_at                 TIMESTAMPTZ,
+    created_at                   TIMESTAMPTZ,
+    updated_at                   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_claims_number ON claims (number);
+-- One live claim per visit and policy; rejected, denied and void claims
+-- don't count, so the visit can be claimed again once corrected.
+CREATE UNIQUE INDEX idx_claims_appointment_policy ON claims (appointment_id, policy_id)
+    WHERE status NOT IN ('rejected', 'denied', 'void');
+CREATE INDEX idx_claims_patient_id ON claims (patient_id);
+CREATE INDEX idx_claims_invoice_id ON claims (invoice_id);
+-- Claims waiting for the next export.
+CREATE INDEX idx_claims_ready ON claims (id) WHERE status = 'ready';
+
+CREATE TABLE claim_status_history (
+    id                 BIGSERIAL PRIMARY KEY,
+    claim_id           BIGINT NOT NULL REFERENCES claims (id) ON DELETE CASCADE,
+    status             TEXT NOT NULL,
+    note               TEXT,
+    remittance_id      BIGINT,
+    changed_by_user_id BIGINT,
+    created_at         TIMESTAMPTZ
+);
+
+CREATE INDEX idx_claim_status_history_claim_id ON claim_status_history (claim_id);
+
+CREATE TABLE remittances (
+    id                  BIGSERIAL PRIMARY KEY,
+    trace_number        TEXT NOT NULL,
+    payer_id            TEXT NOT NULL,
+    payer_name          TEXT,
+    payment_cents       BIGINT NOT NULL,
+    payment_method      TEXT,
+    payment_date        TEXT,
+    claims              JSONB NOT NULL,
+    content             TEXT,
+    received_by_user_id BIGINT,
+    created_at          TIMESTAMPTZ
+);
+
+-- A payment is identified by its trace number and the payer that made
+-- it, so the same 835 can't be posted twice.
+CREATE UNIQUE INDEX idx_remittances_trace ON remittances (payer_id, trace_number);
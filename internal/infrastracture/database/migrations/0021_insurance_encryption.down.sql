@@ -0,0 +1,3 @@
+-- Encrypted values are left as they are; they can't be read without their keys.
+ALTER TABLE eligibility_checks DROP COLUMN IF EXISTS key_id, DROP COLUMN IF EXISTS data_key;
+ALTER TABLE insurance_policies DROP COLUMN IF EXISTS key_id, DROP COLUMN IF EXISTS data_key;
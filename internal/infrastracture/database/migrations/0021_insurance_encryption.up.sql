@@ -0,0 +1,8 @@
+-- Member IDs, subscriber details and raw X12 interchanges are encrypted
+-- with a data key per row, like patient PII. Existing rows stay readable
+-- as plaintext until rotate-keys encrypts them.
+ALTER TABLE insurance_policies ADD COLUMN data_key TEXT, ADD COLUMN key_id TEXT;
+ALTER TABLE eligibility_checks ADD COLUMN data_key TEXT, ADD COLUMN key_id TEXT;
+
+CREATE INDEX idx_insurance_policies_key_id ON insurance_policies (key_id);
+CREATE INDEX idx_eligibility_checks_key_id ON eligibility_checks (key_id);
@@ -0,0 +1,2 @@
+-- Encrypted payloads are left as they are; they can't be read without their keys.
+ALTER TABLE hl7_messages DROP COLUMN IF EXISTS key_id, DROP COLUMN IF EXISTS data_key;
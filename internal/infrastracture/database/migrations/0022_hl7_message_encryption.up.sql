@@ -0,0 +1,6 @@
+-- Logged HL7 messages name the patient and carry their results, so their
+-- payload is encrypted with a data key per row. Existing rows stay readable
+-- as plaintext until rotate-keys encrypts them.
+ALTER TABLE hl7_messages ADD COLUMN data_key TEXT, ADD COLUMN key_id TEXT;
+
+CREATE INDEX idx_hl7_messages_key_id ON hl7_messages (key_id);
@@ -0,0 +1,3 @@
+-- Encrypted content is left as it is; it can't be read without its keys.
+ALTER TABLE remittances DROP COLUMN IF EXISTS key_id, DROP COLUMN IF EXISTS data_key;
+ALTER TABLE claim_files DROP COLUMN IF EXISTS key_id, DROP COLUMN IF EXISTS data_key;
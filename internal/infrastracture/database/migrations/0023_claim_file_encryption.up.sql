@@ -0,0 +1,8 @@
+-- 837P files and 835 remittances name patients, so their content is
+-- encrypted with a data key per row. Existing rows stay readable as
+-- plaintext until rotate-keys encrypts them.
+ALTER TABLE claim_files ADD COLUMN data_key TEXT, ADD COLUMN key_id TEXT;
+ALTER TABLE remittances ADD COLUMN data_key TEXT, ADD COLUMN key_id TEXT;
+
+CREATE INDEX idx_claim_files_key_id ON claim_files (key_id);
+CREATE INDEX idx_remittances_key_id ON remittances (key_id);
@@ -0,0 +1,7 @@
+-- Counters of tenants other than the default one are left in
+-- mrn_sequences and invoice_sequences.
+ALTER TABLE patient_imports DROP COLUMN tenant_id;
+ALTER TABLE patients DROP COLUMN tenant_id;
+ALTER TABLE users DROP COLUMN tenant_id;
+
+DROP TABLE tenants;
@@ -0,0 +1,24 @@
+-- Every user and patient belongs to a tenant. Rows created before
+-- tenants existed, and rows created without one, go to the default
+-- tenant, which keeps the "default" MRN and invoice number counters.
+CREATE TABLE tenants (
+    id         BIGSERIAL PRIMARY KEY,
+    slug       TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_tenants_slug ON tenants (slug);
+
+INSERT INTO tenants (id, slug, name, created_at) VALUES (1, 'default', 'Default', NOW());
+SELECT setval('tenants_id_seq', 1);
+
+ALTER TABLE users ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1
+    CONSTRAINT fk_users_tenant REFERENCES tenants (id);
+ALTER TABLE patients ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1
+    CONSTRAINT fk_patients_tenant REFERENCES tenants (id);
+ALTER TABLE patient_imports ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1
+    CONSTRAINT fk_patient_imports_tenant REFERENCES tenants (id);
+
+CREATE INDEX idx_users_tenant_id ON users (tenant_id);
+CREATE INDEX idx_patients_tenant_id ON patients (tenant_id);
@@ -0,0 +1,35 @@
+-- Messages waiting to be published to Kafka. The API publishes them in
+-- order and records the error of each failed attempt. Payloads may name
+-- patients and are encrypted with a data key per row, like patient PII.
+CREATE TABLE outbox_messages (
+    id           BIGSERIAL PRIMARY KEY,
+    topic        TEXT NOT NULL,
+    key          TEXT,
+    payload      TEXT NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    published_at TIMESTAMPTZ,
+    data_key     TEXT,
+    key_id       TEXT,
+    created_at   TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_messages_pending ON outbox_messages (id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_messages_key_id ON outbox_messages (key_id);
+
+-- Kafka messages the consumer could not process, encrypted like the
+-- outbox. Replaying one puts it back on the outbox.
+CREATE TABLE dead_letters (
+    id          BIGSERIAL PRIMARY KEY,
+    topic       TEXT NOT NULL,
+    type        TEXT,
+    payload     TEXT NOT NULL,
+    error       TEXT NOT NULL,
+    replayed_at TIMESTAMPTZ,
+    outbox_id   BIGINT REFERENCES outbox_messages (id) ON DELETE SET NULL,
+    data_key    TEXT,
+    key_id      TEXT,
+    created_at  TIMESTAMPTZ
+);
+
+CREATE INDEX idx_dead_letters_key_id ON dead_letters (key_id);
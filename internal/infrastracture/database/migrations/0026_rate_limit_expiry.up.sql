@@ -0,0 +1,6 @@
+-- Buckets are deleted once they are full again. Existing ones get a day.
+ALTER TABLE rate_limit_buckets ADD COLUMN expires_at TIMESTAMPTZ;
+UPDATE rate_limit_buckets SET expires_at = COALESCE(updated_at, NOW()) + INTERVAL '1 day';
+ALTER TABLE rate_limit_buckets ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX idx_rate_limit_buckets_expires_at ON rate_limit_buckets (expires_at);
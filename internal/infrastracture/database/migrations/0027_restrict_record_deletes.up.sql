@@ -0,0 +1,44 @@
+-- Clinical and financial records outlive the retention purge: deleting a
+-- patient, appointment, encounter, invoice or insurance policy that still
+-- has them fails instead of deleting them too. The purge skips patients
+-- and appointments with such records.
+ALTER TABLE encounters DROP CONSTRAINT encounters_appointment_id_fkey,
+    ADD CONSTRAINT encounters_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE encounters DROP CONSTRAINT encounters_patient_id_fkey,
+    ADD CONSTRAINT encounters_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE appointment_diagnoses DROP CONSTRAINT appointment_diagnoses_appointment_id_fkey,
+    ADD CONSTRAINT appointment_diagnoses_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE appointment_procedures DROP CONSTRAINT appointment_procedures_appointment_id_fkey,
+    ADD CONSTRAINT appointment_procedures_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE vital_signs DROP CONSTRAINT vital_signs_patient_id_fkey,
+    ADD CONSTRAINT vital_signs_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE patient_allergies DROP CONSTRAINT patient_allergies_patient_id_fkey,
+    ADD CONSTRAINT patient_allergies_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE patient_medications DROP CONSTRAINT patient_medications_patient_id_fkey,
+    ADD CONSTRAINT patient_medications_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE patient_problems DROP CONSTRAINT patient_problems_patient_id_fkey,
+    ADD CONSTRAINT patient_problems_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE prescriptions DROP CONSTRAINT prescriptions_patient_id_fkey,
+    ADD CONSTRAINT prescriptions_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE prescriptions DROP CONSTRAINT prescriptions_encounter_id_fkey,
+    ADD CONSTRAINT prescriptions_encounter_id_fkey FOREIGN KEY (encounter_id) REFERENCES encounters (id) ON DELETE RESTRICT;
+ALTER TABLE lab_orders DROP CONSTRAINT lab_orders_patient_id_fkey,
+    ADD CONSTRAINT lab_orders_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE lab_orders DROP CONSTRAINT lab_orders_appointment_id_fkey,
+    ADD CONSTRAINT lab_orders_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE lab_results DROP CONSTRAINT lab_results_patient_id_fkey,
+    ADD CONSTRAINT lab_results_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE invoices DROP CONSTRAINT invoices_patient_id_fkey,
+    ADD CONSTRAINT invoices_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE invoice_payments DROP CONSTRAINT invoice_payments_invoice_id_fkey,
+    ADD CONSTRAINT invoice_payments_invoice_id_fkey FOREIGN KEY (invoice_id) REFERENCES invoices (id) ON DELETE RESTRICT;
+ALTER TABLE invoice_payments DROP CONSTRAINT invoice_payments_patient_id_fkey,
+    ADD CONSTRAINT invoice_payments_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE payment_intents DROP CONSTRAINT payment_intents_patient_id_fkey,
+    ADD CONSTRAINT payment_intents_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE claims DROP CONSTRAINT claims_patient_id_fkey,
+    ADD CONSTRAINT claims_patient_id_fkey FOREIGN KEY (patient_id) REFERENCES patients (id) ON DELETE RESTRICT;
+ALTER TABLE claims DROP CONSTRAINT claims_invoice_id_fkey,
+    ADD CONSTRAINT claims_invoice_id_fkey FOREIGN KEY (invoice_id) REFERENCES invoices (id) ON DELETE RESTRICT;
+ALTER TABLE claims DROP CONSTRAINT claims_policy_id_fkey,
+    ADD CONSTRAINT claims_policy_id_fkey FOREIGN KEY (policy_id) REFERENCES insurance_policies (id) ON DELETE RESTRICT;
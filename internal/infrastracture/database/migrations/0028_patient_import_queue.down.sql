@@ -0,0 +1,6 @@
+DROP TABLE patient_import_uploads;
+
+UPDATE patient_imports SET status = 'failed', error = 'the import was interrupted'
+WHERE status IN ('queued', 'running');
+ALTER TABLE patient_imports DROP CONSTRAINT patient_imports_status_check,
+    ADD CONSTRAINT patient_imports_status_check CHECK (status IN ('running', 'completed', 'failed'));
@@ -0,0 +1,18 @@
+-- Imports are queued with their rows and run by whichever API replica
+-- claims them, so they survive restarts. Rows are encrypted like patient
+-- PII and deleted when the import finishes.
+ALTER TABLE patient_imports DROP CONSTRAINT patient_imports_status_check,
+    ADD CONSTRAINT patient_imports_status_check CHECK (status IN ('queued', 'running', 'completed', 'failed'));
+
+CREATE INDEX idx_patient_imports_unfinished ON patient_imports (id) WHERE status IN ('queued', 'running');
+
+CREATE TABLE patient_import_uploads (
+    id          BIGINT PRIMARY KEY REFERENCES patient_imports (id) ON DELETE CASCADE,
+    fields      JSONB NOT NULL DEFAULT '[]',
+    date_layout TEXT NOT NULL,
+    rows        TEXT NOT NULL,
+    data_key    TEXT,
+    key_id      TEXT
+);
+
+CREATE INDEX idx_patient_import_uploads_key_id ON patient_import_uploads (key_id);
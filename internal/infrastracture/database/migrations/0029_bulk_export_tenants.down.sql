@@ -0,0 +1 @@
+ALTER TABLE bulk_exports DROP COLUMN tenant_id;
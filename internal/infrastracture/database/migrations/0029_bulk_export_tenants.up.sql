@@ -0,0 +1,6 @@
+-- Bulk exports belong to the tenant of the user who requested them and
+-- only export that tenant's patients and appointments.
+ALTER TABLE bulk_exports ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1
+    CONSTRAINT fk_bulk_exports_tenant REFERENCES tenants (id);
+
+CREATE INDEX idx_bulk_exports_tenant_id ON bulk_exports (tenant_id);
@@ -0,0 +1,7 @@
+-- Daily jobs record the last day they completed, so servers starting at
+-- different times, and restarts, don't run them again the same day.
+CREATE TABLE scheduled_job_runs (
+    job         TEXT PRIMARY KEY,
+    last_run_on DATE NOT NULL,
+    updated_at  TIMESTAMPTZ
+);
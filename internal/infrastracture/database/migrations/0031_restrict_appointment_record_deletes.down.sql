@@ -0,0 +1,8 @@
+ALTER TABLE claims DROP CONSTRAINT claims_appointment_id_fkey,
+    ADD CONSTRAINT claims_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL;
+ALTER TABLE payment_intents DROP CONSTRAINT payment_intents_appointment_id_fkey,
+    ADD CONSTRAINT payment_intents_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL;
+ALTER TABLE invoices DROP CONSTRAINT invoices_appointment_id_fkey,
+    ADD CONSTRAINT invoices_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL;
+ALTER TABLE vital_signs DROP CONSTRAINT vital_signs_appointment_id_fkey,
+    ADD CONSTRAINT vital_signs_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL;
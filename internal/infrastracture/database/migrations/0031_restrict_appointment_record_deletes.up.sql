@@ -0,0 +1,11 @@
+-- Vitals, invoices, payment intents and claims of an appointment outlive
+-- the retention purge too: deleting the appointment fails instead of
+-- unlinking them. The purge skips appointments with such records.
+ALTER TABLE vital_signs DROP CONSTRAINT vital_signs_appointment_id_fkey,
+    ADD CONSTRAINT vital_signs_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE invoices DROP CONSTRAINT invoices_appointment_id_fkey,
+    ADD CONSTRAINT invoices_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE payment_intents DROP CONSTRAINT payment_intents_appointment_id_fkey,
+    ADD CONSTRAINT payment_intents_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
+ALTER TABLE claims DROP CONSTRAINT claims_appointment_id_fkey,
+    ADD CONSTRAINT claims_appointment_id_fkey FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE RESTRICT;
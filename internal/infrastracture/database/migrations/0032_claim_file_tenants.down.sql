@@ -0,0 +1,5 @@
+DROP INDEX idx_remittances_trace;
+CREATE UNIQUE INDEX idx_remittances_trace ON remittances (payer_id, trace_number);
+
+ALTER TABLE remittances DROP COLUMN tenant_id;
+ALTER TABLE claim_files DROP COLUMN tenant_id;
@@ -0,0 +1,13 @@
+-- Claim files and remittances belong to the tenant of the user who
+-- exported or posted them, and only cover that tenant's claims.
+ALTER TABLE claim_files ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1
+    CONSTRAINT fk_claim_files_tenant REFERENCES tenants (id);
+ALTER TABLE remittances ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1
+    CONSTRAINT fk_remittances_tenant REFERENCES tenants (id);
+
+CREATE INDEX idx_claim_files_tenant_id ON claim_files (tenant_id);
+
+-- A trace number is unique per payer within a tenant; another tenant can
+-- post the same 835 only to its own claims.
+DROP INDEX idx_remittances_trace;
+CREATE UNIQUE INDEX idx_remittances_trace ON remittances (tenant_id, payer_id, trace_number);
@@ -2,13 +2,14 @@
 package database
 
 import (
-	"doctors/internal/domain"
 	"fmt"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// NewPostgresDB connects to Postgres. The schema is managed by versioned
+// migrations (see Migrator), not created here.
 func NewPostgresDB(host, user, password, dbname string, port int) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=UTC",
 		host, user, password, dbname, port)
@@ -18,24 +19,5 @@ func NewPostgresDB(host, user, password, dbname string, port int) (*gorm.DB, err
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Auto Migrate the schema
-	err = db.AutoMigrate(&domain.Patient{}, &domain.Appointment{}, &domain.Doctor{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to auto migrate: %w", err)
-	}
-
-	// Create a default doctor if not exists
-	var doctor domain.Doctor
-	result := db.First(&doctor)
-	if result.Error == gorm.ErrRecordNotFound {
-		defaultDoctor := domain.Doctor{
-			Name:  "Nicolas Asparria",
-			Email: "mailtrap@demomailtrap.com",
-		}
-		if err := db.Create(&defaultDoctor).Error; err != nil {
-			return nil, fmt.Errorf("failed to create default doctor: %w", err)
-		}
-	}
-
 	return db, nil
 }
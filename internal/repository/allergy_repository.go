@@ -0,0 +1,93 @@
+// internal/repository/allergy_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type AllergyRepository interface {
+	Create(ctx context.Context, allergy *domain.Allergy) error
+	GetByID(ctx context.Context, id uint) (*domain.Allergy, error)
+	// ListByPatient returns the patient's allergies, active ones first and
+	// then by substance; only those with status when it is set.
+	ListByPatient(ctx context.Context, patientID uint, status string) ([]domain.Allergy, error)
+	Update(ctx context.Context, allergy *domain.Allergy) error
+	// Delete marks an allergy as entered in error.
+	Delete(ctx context.Context, id uint) error
+	PatientRecords
+}
+
+type allergyRepository struct {
+	db *gorm.DB
+}
+
+func NewAllergyRepository(db *gorm.DB) AllergyRepository {
+	return &allergyRepository{db: db}
+}
+
+func (r *allergyRepository) Create(ctx context.Context, allergy *domain.Allergy) error {
+	return conn(ctx, r.db).Create(allergy).Error
+}
+
+func (r *allergyRepository) GetByID(ctx context.Context, id uint) (*domain.Allergy, error) {
+	var allergy domain.Allergy
+	if err := r.scoped(ctx).First(&allergy, id).Error; err != nil {
+		return nil, notFound(err, "allergy", id)
+	}
+	return &allergy, nil
+}
+
+func (r *allergyRepository) ListByPatient(ctx context.Context, patientID uint, status string) ([]domain.Allergy, error) {
+	query := r.scoped(ctx).Where("patient_id = ?", patientID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var allergies []domain.Allergy
+	err := query.Order("status").Order("lower(substance)").Order("id").Find(&allergies).Error
+	return allergies, err
+}
+
+func (r *allergyRepository) Update(ctx context.Context, allergy *domain.Allergy) error {
+	return conn(ctx, r.db).Save(allergy).Error
+}
+
+func (r *allergyRepository) Delete(ctx context.Context, id uint) error {
+	result := r.scoped(ctx).Delete(&domain.Allergy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("allergy", id)
+	}
+	return nil
+}
+
+func (r *allergyRepository) RecordType() string {
+	return "allergies"
+}
+
+func (r *allergyRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Unscoped().Model(&domain.Allergy{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Allergy{}).Where("id IN ?", movedIDs).
+		Update("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *allergyRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "patient_allergies")
+}
@@ -7,12 +7,37 @@ import (
 	"time"
 )
 
+// Lookups and listings only see the tenant the context acts for, if any;
+// see WithTenant.
 type AppointmentRepository interface {
 	Create(ctx context.Context, appointment *domain.Appointment) error
 	GetByID(ctx context.Context, id uint) (*domain.Appointment, error)
 	Update(ctx context.Context, appointment *domain.Appointment) error
+	// Delete archives the appointment; Restore brings it back.
 	Delete(ctx context.Context, id uint) error
-	GetByDate(ctx context.Context, date time.Time) ([]domain.Appointment, error)
+	Restore(ctx context.Context, id uint) (*domain.Appointment, error)
+	GetByDate(ctx context.Context, date time.Time, includeArchived bool) ([]domain.Appointment, error)
+	// GetUpcomingByPatient returns scheduled appointments at or after from.
+	GetUpcomingByPatient(ctx context.Context, patientID uint, from time.Time) ([]domain.Appointment, error)
+	// ListByPatients returns the appointments of any of the patients, oldest first.
+	ListByPatients(ctx context.Context, patientIDs []uint) ([]domain.Appointment, error)
+	// Search returns a page of the appointments matching search, by time,
+	// and the number of matches.
+	Search(ctx context.Context, search domain.AppointmentSearch) ([]domain.Appointment, int64, error)
+	// Purge permanently deletes appointments archived before the cutoff,
+	// except those with encounters, codes, lab orders, vitals, invoices,
+	// payment intents or claims.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	PatientRecords
+	// AttachEligibility links the patient's scheduled appointments at or
+	// after from to an eligibility check.
+	AttachEligibility(ctx context.Context, patientID, checkID uint, from time.Time) error
+	// CountChanged counts active appointments last updated in (since,
+	// until]; a zero since counts all of them up to until.
+	CountChanged(ctx context.Context, since, until time.Time) (int64, error)
+	// ListChanged returns up to limit of the appointments CountChanged
+	// counts, by ID, after afterID.
+	ListChanged(ctx context.Context, since, until time.Time, afterID uint, limit int) ([]domain.Appointment, error)
 }
 
 type appointmentRepository struct {
@@ -24,25 +49,141 @@ func NewAppointmentRepository(db *gorm.DB) AppointmentRepository {
 }
 
 func (r *appointmentRepository) Create(ctx context.Context, appointment *domain.Appointment) error {
-	return r.db.WithContext(ctx).Create(appointment).Error
+	appointment.Version = 1
+	return conn(ctx, r.db).Create(appointment).Error
 }
 
 func (r *appointmentRepository) GetByID(ctx context.Context, id uint) (*domain.Appointment, error) {
 	var appointment domain.Appointment
-	err := r.db.WithContext(ctx).First(&appointment, id).Error
-	return &appointment, err
+	if err := r.scoped(ctx).First(&appointment, id).Error; err != nil {
+		return nil, notFound(err, "appointment", id)
+	}
+	return &appointment, nil
 }
 
 func (r *appointmentRepository) Update(ctx context.Context, appointment *domain.Appointment) error {
-	return r.db.WithContext(ctx).Save(appointment).Error
+	return updateVersioned(conn(ctx, r.db), appointment, &appointment.Version, "appointment")
 }
 
 func (r *appointmentRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Appointment{}, id).Error
+	result := r.scoped(ctx).Delete(&domain.Appointment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("appointment", id)
+	}
+	return nil
 }
 
-func (r *appointmentRepository) GetByDate(ctx context.Context, date time.Time) ([]domain.Appointment, error) {
+func (r *appointmentRepository) Restore(ctx context.Context, id uint) (*domain.Appointment, error) {
+	if err := restore(r.scoped(ctx), &domain.Appointment{}, id, "appointment"); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *appointmentRepository) GetByDate(ctx context.Context, date time.Time, includeArchived bool) ([]domain.Appointment, error) {
+	var appointments []domain.Appointment
+	err := withArchived(r.scoped(ctx), includeArchived).
+		Where("DATE(date_time) = ?", date.Format("2006-01-02")).Order("date_time").Find(&appointments).Error
+	return appointments, err
+}
+
+func (r *appointmentRepository) GetUpcomingByPatient(ctx context.Context, patientID uint, from time.Time) ([]domain.Appointment, error) {
+	var appointments []domain.Appointment
+	err := r.scoped(ctx).
+		Where("patient_id = ? AND date_time >= ? AND status = ?", patientID, from, domain.AppointmentStatusScheduled).
+		Order("date_time").Find(&appointments).Error
+	return appointments, err
+}
+
+func (r *appointmentRepository) Search(ctx context.Context, search domain.AppointmentSearch) ([]domain.Appointment, int64, error) {
+	query := r.scoped(ctx).Model(&domain.Appointment{})
+	if search.PatientID != nil {
+		query = query.Where("patient_id = ?", *search.PatientID)
+	}
+	if search.DoctorID != nil {
+		query = query.Where("doctor_id = ?", *search.DoctorID)
+	}
+	if !search.From.IsZero() {
+		query = query.Where("date_time >= ?", search.From)
+	}
+	if !search.To.IsZero() {
+		query = query.Where("date_time < ?", search.To)
+	}
+	if len(search.Statuses) > 0 {
+		query = query.Where("status IN ?", search.Statuses)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var appointments []domain.Appointment
+	err := query.Order("date_time").Order("id").Limit(search.Limit).Offset(search.Offset).Find(&appointments).Error
+	return appointments, total, err
+}
+
+func (r *appointmentRepository) CountChanged(ctx context.Context, since, until time.Time) (int64, error) {
+	var count int64
+	err := changedBetween(r.scoped(ctx), since, until).Model(&domain.Appointment{}).Count(&count).Error
+	return count, err
+}
+
+func (r *appointmentRepository) ListChanged(ctx context.Context, since, until time.Time, afterID uint, limit int) ([]domain.Appointment, error) {
 	var appointments []domain.Appointment
-	err := r.db.WithContext(ctx).Where("DATE(date_time) = ?", date.Format("2006-01-02")).Find(&appointments).Error
+	err := changedBetween(r.scoped(ctx), since, until).Where("id > ?", afterID).
+		Order("id").Limit(limit).Find(&appointments).Error
 	return appointments, err
 }
+
+func (r *appointmentRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before)
+	result := withoutRecords(query, "appointments", "appointment_id", appointmentRecordTables).Delete(&domain.Appointment{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *appointmentRepository) RecordType() string {
+	return "appointments"
+}
+
+func (r *appointmentRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Unscoped().Model(&domain.Appointment{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Appointment{}).Where("id IN ?", movedIDs).
+		Updates(map[string]interface{}{"patient_id": toID, "version": gorm.Expr("version + 1")}).Error
+	return movedIDs, err
+}
+
+func (r *appointmentRepository) ListByPatients(ctx context.Context, patientIDs []uint) ([]domain.Appointment, error) {
+	var appointments []domain.Appointment
+	if len(patientIDs) == 0 {
+		return appointments, nil
+	}
+	err := r.scoped(ctx).Where("patient_id IN ?", patientIDs).Order("date_time").Find(&appointments).Error
+	return appointments, err
+}
+
+func (r *appointmentRepository) AttachEligibility(ctx context.Context, patientID, checkID uint, from time.Time) error {
+	return conn(ctx, r.db).Model(&domain.Appointment{}).
+		Where("patient_id = ? AND date_time >= ? AND status = ?", patientID, from, domain.AppointmentStatusScheduled).
+		Updates(map[string]interface{}{"eligibility_check_id": checkID, "version": gorm.Expr("version + 1")}).Error
+}
+
+// scoped returns the connection for ctx limited to the appointments of
+// patients of the tenant ctx acts for.
+func (r *appointmentRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "appointments")
+}
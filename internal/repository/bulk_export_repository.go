@@ -0,0 +1,120 @@
+// internal/repository/bulk_export_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BulkExportRepository interface {
+	Create(ctx context.Context, export *domain.BulkExport) error
+	GetByID(ctx context.Context, id uint) (*domain.BulkExport, error)
+	// Claim marks the oldest accepted export, or an in-progress one not
+	// updated since staleBefore (its worker died), as in progress and
+	// returns it. It returns nil when there is nothing to do. Concurrent
+	// workers never claim the same export.
+	Claim(ctx context.Context, staleBefore time.Time) (*domain.BulkExport, error)
+	// UpdateProgress records how many of an export's total resources are
+	// written. Like Finish, it returns a not found error once the export
+	// was cancelled.
+	UpdateProgress(ctx context.Context, id uint, total, written int) error
+	// Finish stores the outcome of an export in progress.
+	Finish(ctx context.Context, export *domain.BulkExport) error
+	Delete(ctx context.Context, id uint) error
+	// ListExpired returns exports that expired before the given time.
+	ListExpired(ctx context.Context, before time.Time) ([]domain.BulkExport, error)
+}
+
+type bulkExportRepository struct {
+	db *gorm.DB
+}
+
+func NewBulkExportRepository(db *gorm.DB) BulkExportRepository {
+	return &bulkExportRepository{db: db}
+}
+
+func (r *bulkExportRepository) Create(ctx context.Context, export *domain.BulkExport) error {
+	return conn(ctx, r.db).Create(export).Error
+}
+
+func (r *bulkExportRepository) GetByID(ctx context.Context, id uint) (*domain.BulkExport, error) {
+	var export domain.BulkExport
+	if err := forTenant(ctx, conn(ctx, r.db), "bulk_exports").First(&export, id).Error; err != nil {
+		return nil, notFound(err, "bulk export", id)
+	}
+	return &export, nil
+}
+
+func (r *bulkExportRepository) Claim(ctx context.Context, staleBefore time.Time) (*domain.BulkExport, error) {
+	var claimed *domain.BulkExport
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var exports []domain.BulkExport
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND updated_at < ?)",
+				domain.BulkExportAccepted, domain.BulkExportInProgress, staleBefore).
+			Order("id").Limit(1).Find(&exports).Error
+		if err != nil || len(exports) == 0 {
+			return err
+		}
+
+		export := &exports[0]
+		now := time.Now()
+		export.Status = domain.BulkExportInProgress
+		export.StartedAt = &now
+		export.Written = 0
+		if err := tx.Save(export).Error; err != nil {
+			return err
+		}
+		claimed = export
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *bulkExportRepository) UpdateProgress(ctx context.Context, id uint, total, written int) error {
+	result := conn(ctx, r.db).Model(&domain.BulkExport{}).
+		Where("id = ? AND status = ?", id, domain.BulkExportInProgress).
+		Updates(map[string]interface{}{"total": total, "written": written})
+	return inProgress(result, id)
+}
+
+func (r *bulkExportRepository) Finish(ctx context.Context, export *domain.BulkExport) error {
+	result := conn(ctx, r.db).Model(export).
+		Where("status = ?", domain.BulkExportInProgress).
+		Select("status", "total", "written", "files", "error", "completed_at", "expires_at").
+		Updates(export)
+	return inProgress(result, export.ID)
+}
+
+// inProgress reports a not found error when an update of an export in
+// progress matched nothing: the export was cancelled meanwhile.
+func inProgress(result *gorm.DB, id uint) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("bulk_export", id)
+	}
+	return nil
+}
+
+func (r *bulkExportRepository) Delete(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Delete(&domain.BulkExport{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("bulk_export", id)
+	}
+	return nil
+}
+
+func (r *bulkExportRepository) ListExpired(ctx context.Context, before time.Time) ([]domain.BulkExport, error) {
+	var exports []domain.BulkExport
+	err := conn(ctx, r.db).Where("expires_at < ?", before).Order("id").Find(&exports).Error
+	return exports, err
+}
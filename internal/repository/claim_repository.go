@@ -0,0 +1,245 @@
+// internal/repository/claim_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClaimRepository stores insurance claims, the 837P files they are
+// exported in, and the 835 remittances that pay them.
+type ClaimRepository interface {
+	Create(ctx context.Context, claim *domain.Claim) error
+	// GetByID returns a claim with its status history.
+	GetByID(ctx context.Context, id uint) (*domain.Claim, error)
+	// GetForUpdate is GetByID, locking the claim until the transaction ends.
+	GetForUpdate(ctx context.Context, id uint) (*domain.Claim, error)
+	// FindByNumber returns the claim with the number, locked until the
+	// transaction ends, or nil when there is none.
+	FindByNumber(ctx context.Context, number string) (*domain.Claim, error)
+	// List returns a page of matching claims without history, newest
+	// first, and the number of matches.
+	List(ctx context.Context, search domain.ClaimSearch) ([]domain.Claim, int64, error)
+	// ListReady returns up to limit claims waiting to be exported, oldest
+	// first, locked until the transaction ends.
+	ListReady(ctx context.Context, limit int) ([]domain.Claim, error)
+	Update(ctx context.Context, claim *domain.Claim) error
+	AddStatusChange(ctx context.Context, change *domain.ClaimStatusChange) error
+	PatientRecords
+
+	CreateFile(ctx context.Context, file *domain.ClaimFile) error
+	// GetFile returns a claim file with its content.
+	GetFile(ctx context.Context, id uint) (*domain.ClaimFile, error)
+	// ListFiles returns a page of claim files without content, newest first.
+	ListFiles(ctx context.Context, limit, offset int) ([]domain.ClaimFile, int64, error)
+
+	CreateRemittance(ctx context.Context, remittance *domain.Remittance) error
+	UpdateRemittance(ctx context.Context, remittance *domain.Remittance) error
+	GetRemittance(ctx context.Context, id uint) (*domain.Remittance, error)
+	// ListRemittances returns a page of remittances, newest first.
+	ListRemittances(ctx context.Context, limit, offset int) ([]domain.Remittance, int64, error)
+
+	// RotateKeys re-encrypts, in batches, every claim file and remittance
+	// whose data key is not wrapped by the active master key.
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+}
+
+// The content of claim files and remittances names patients and is
+// encrypted at rest like patient PII.
+type claimRepository struct {
+	db     *gorm.DB
+	cipher *encryption.Envelope
+}
+
+func NewClaimRepository(db *gorm.DB, cipher *encryption.Envelope) ClaimRepository {
+	return &claimRepository{db: db, cipher: cipher}
+}
+
+func claimFileRow(file *domain.ClaimFile) sealedRow {
+	return sealedRow{DataKey: &file.DataKey, KeyID: &file.KeyID, Fields: []*string{&file.Content}}
+}
+
+func remittanceRow(remittance *domain.Remittance) sealedRow {
+	return sealedRow{DataKey: &remittance.DataKey, KeyID: &remittance.KeyID, Fields: []*string{&remittance.Content}}
+}
+
+func (r *claimRepository) Create(ctx context.Context, claim *domain.Claim) error {
+	err := conn(ctx, r.db).Create(claim).Error
+	if isUniqueViolation(err, "idx_claims_appointment_policy") {
+		return domain.NewConflictError("appointment_already_claimed", "the visit is already claimed from this policy")
+	}
+	return err
+}
+
+func (r *claimRepository) GetByID(ctx context.Context, id uint) (*domain.Claim, error) {
+	return r.get(ctx, r.scoped(ctx), id)
+}
+
+func (r *claimRepository) GetForUpdate(ctx context.Context, id uint) (*domain.Claim, error) {
+	return r.get(ctx, r.scoped(ctx).Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func (r *claimRepository) get(ctx context.Context, query *gorm.DB, id uint) (*domain.Claim, error) {
+	var claim domain.Claim
+	if err := query.First(&claim, id).Error; err != nil {
+		return nil, notFound(err, "claim", id)
+	}
+	claim.History = []domain.ClaimStatusChange{}
+	err := conn(ctx, r.db).Where("claim_id = ?", id).Order("created_at").Order("id").Find(&claim.History).Error
+	return &claim, err
+}
+
+func (r *claimRepository) FindByNumber(ctx context.Context, number string) (*domain.Claim, error) {
+	var claims []domain.Claim
+	err := r.scoped(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("number = ?", number).Limit(1).Find(&claims).Error
+	if err != nil || len(claims) == 0 {
+		return nil, err
+	}
+	return &claims[0], nil
+}
+
+func (r *claimRepository) List(ctx context.Context, search domain.ClaimSearch) ([]domain.Claim, int64, error) {
+	query := r.scoped(ctx).Model(&domain.Claim{})
+	if search.PatientID != nil {
+		query = query.Where("patient_id = ?", *search.PatientID)
+	}
+	if search.AppointmentID != nil {
+		query = query.Where("appointment_id = ?", *search.AppointmentID)
+	}
+	if len(search.Statuses) > 0 {
+		query = query.Where("status IN ?", search.Statuses)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	claims := []domain.Claim{}
+	err := query.Order("created_at DESC").Order("id DESC").Limit(search.Limit).Offset(search.Offset).Find(&claims).Error
+	return claims, total, err
+}
+
+func (r *claimRepository) ListReady(ctx context.Context, limit int) ([]domain.Claim, error) {
+	claims := []domain.Claim{}
+	err := r.scoped(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ?", domain.ClaimReady).Order("id").Limit(limit).Find(&claims).Error
+	return claims, err
+}
+
+func (r *claimRepository) Update(ctx context.Context, claim *domain.Claim) error {
+	return conn(ctx, r.db).Save(claim).Error
+}
+
+func (r *claimRepository) AddStatusChange(ctx context.Context, change *domain.ClaimStatusChange) error {
+	return conn(ctx, r.db).Create(change).Error
+}
+
+func (r *claimRepository) RecordType() string {
+	return "claims"
+}
+
+func (r *claimRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.Claim{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+	err := conn(ctx, r.db).Model(&domain.Claim{}).Where("id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *claimRepository) CreateFile(ctx context.Context, file *domain.ClaimFile) error {
+	return withSealedRow(r.cipher, claimFileRow(file), func() error {
+		return conn(ctx, r.db).Create(file).Error
+	})
+}
+
+func (r *claimRepository) GetFile(ctx context.Context, id uint) (*domain.ClaimFile, error) {
+	var file domain.ClaimFile
+	if err := forTenant(ctx, conn(ctx, r.db), "claim_files").First(&file, id).Error; err != nil {
+		return nil, notFound(err, "claim_file", id)
+	}
+	if err := openRow(r.cipher, claimFileRow(&file)); err != nil {
+		return nil, fmt.Errorf("claim file %d: %w", file.ID, err)
+	}
+	return &file, nil
+}
+
+func (r *claimRepository) ListFiles(ctx context.Context, limit, offset int) ([]domain.ClaimFile, int64, error) {
+	query := forTenant(ctx, conn(ctx, r.db), "claim_files").Model(&domain.ClaimFile{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	files := []domain.ClaimFile{}
+	err := query.Omit("content").Order("id DESC").Limit(limit).Offset(offset).Find(&files).Error
+	return files, total, err
+}
+
+func (r *claimRepository) CreateRemittance(ctx context.Context, remittance *domain.Remittance) error {
+	err := withSealedRow(r.cipher, remittanceRow(remittance), func() error {
+		return conn(ctx, r.db).Create(remittance).Error
+	})
+	if isUniqueViolation(err, "idx_remittances_trace") {
+		return domain.NewConflictError("remittance_already_posted",
+			"a remittance with trace number "+remittance.TraceNumber+" from this payer was posted already")
+	}
+	return err
+}
+
+func (r *claimRepository) UpdateRemittance(ctx context.Context, remittance *domain.Remittance) error {
+	return withSealedRow(r.cipher, remittanceRow(remittance), func() error {
+		return conn(ctx, r.db).Save(remittance).Error
+	})
+}
+
+func (r *claimRepository) GetRemittance(ctx context.Context, id uint) (*domain.Remittance, error) {
+	var remittance domain.Remittance
+	if err := forTenant(ctx, conn(ctx, r.db), "remittances").First(&remittance, id).Error; err != nil {
+		return nil, notFound(err, "remittance", id)
+	}
+	if err := openRow(r.cipher, remittanceRow(&remittance)); err != nil {
+		return nil, fmt.Errorf("remittance %d: %w", remittance.ID, err)
+	}
+	return &remittance, nil
+}
+
+func (r *claimRepository) ListRemittances(ctx context.Context, limit, offset int) ([]domain.Remittance, int64, error) {
+	query := forTenant(ctx, conn(ctx, r.db), "remittances").Model(&domain.Remittance{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	remittances := []domain.Remittance{}
+	err := query.Omit("content").Order("id DESC").Limit(limit).Offset(offset).Find(&remittances).Error
+	return remittances, total, err
+}
+
+func (r *claimRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	files, err := rotateSealedRows(ctx, r.db, r.cipher, batchSize, []string{"content"},
+		func(f *domain.ClaimFile) uint { return f.ID }, claimFileRow)
+	if err != nil {
+		return files, err
+	}
+	remittances, err := rotateSealedRows(ctx, r.db, r.cipher, batchSize, []string{"content"},
+		func(m *domain.Remittance) uint { return m.ID }, remittanceRow)
+	return files + remittances, err
+}
+
+func (r *claimRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "claims")
+}
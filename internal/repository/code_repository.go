@@ -0,0 +1,116 @@
+// internal/repository/code_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CodeRepository interface {
+	// Upsert inserts catalog entries or refreshes existing ones, marking
+	// them active.
+	Upsert(ctx context.Context, codes []domain.MedicalCode) error
+	// DeactivateMissing deactivates the system's codes that are not part of
+	// release and returns how many were deactivated.
+	DeactivateMissing(ctx context.Context, system, release string) (int64, error)
+	Get(ctx context.Context, system, code string) (*domain.MedicalCode, error)
+	// Search returns up to limit codes, best matches first.
+	Search(ctx context.Context, search domain.CodeSearch, limit int) ([]domain.MedicalCode, error)
+}
+
+type codeRepository struct {
+	db *gorm.DB
+}
+
+func NewCodeRepository(db *gorm.DB) CodeRepository {
+	return &codeRepository{db: db}
+}
+
+func (r *codeRepository) Upsert(ctx context.Context, codes []domain.MedicalCode) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "system"}, {Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "short_description", "billable", "active", "release", "updated_at"}),
+	}).Create(&codes).Error
+}
+
+func (r *codeRepository) DeactivateMissing(ctx context.Context, system, release string) (int64, error) {
+	result := conn(ctx, r.db).Model(&domain.MedicalCode{}).
+		Where("system = ? AND release <> ? AND active", system, release).
+		Updates(map[string]interface{}{"active": false})
+	return result.RowsAffected, result.Error
+}
+
+func (r *codeRepository) Get(ctx context.Context, system, code string) (*domain.MedicalCode, error) {
+	var medicalCode domain.MedicalCode
+	if err := conn(ctx, r.db).Where("system = ? AND code = ?", system, code).First(&medicalCode).Error; err != nil {
+		return nil, notFound(err, "code", system+" "+code)
+	}
+	return &medicalCode, nil
+}
+
+func (r *codeRepository) Search(ctx context.Context, search domain.CodeSearch, limit int) ([]domain.MedicalCode, error) {
+	query := conn(ctx, r.db).Model(&domain.MedicalCode{})
+	if search.System != "" {
+		query = query.Where("system = ?", search.System)
+	}
+	if !search.IncludeInactive {
+		query = query.Where("active")
+	}
+	if search.BillableOnly {
+		query = query.Where("billable")
+	}
+
+	words := searchWords(search.Query)
+	switch {
+	case len(words) == 0:
+		query = query.Order("system").Order("code")
+	case len(words) == 1 && looksLikeCode(words[0]):
+		// ICD-10 codes are stored with their dot; accept them typed without.
+		prefixes := []string{words[0]}
+		if !strings.Contains(words[0], ".") && len(words[0]) > 3 {
+			prefixes = append(prefixes, words[0][:3]+"."+words[0][3:])
+		}
+		matches := conn(ctx, r.db)
+		for _, prefix := range prefixes {
+			matches = matches.Or("code LIKE ?", prefix+"%")
+		}
+		query = query.Where(matches).Order("length(code)").Order("code")
+	default:
+		var terms []string
+		for _, word := range words {
+			if word = strings.ReplaceAll(word, ".", ""); word != "" {
+				terms = append(terms, word+":*")
+			}
+		}
+		tsquery := gorm.Expr("to_tsquery('english', ?)", strings.Join(terms, " & "))
+		query = query.Where("search @@ ?", tsquery).
+			Order(clause.OrderBy{Expression: gorm.Expr("ts_rank(search, ?) DESC", tsquery)}).
+			Order("length(code)").Order("code")
+	}
+
+	var codes []domain.MedicalCode
+	err := query.Limit(limit).Find(&codes).Error
+	return codes, err
+}
+
+// searchWords splits a lookup into upper-case words of letters, digits
+// and dots, dropping anything that means something to tsquery or LIKE.
+func searchWords(q string) []string {
+	return strings.FieldsFunc(strings.ToUpper(q), func(ch rune) bool {
+		return !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != '.'
+	})
+}
+
+// looksLikeCode reports whether a word is a code rather than part of a
+// description: codes contain a digit, descriptions rarely do.
+func looksLikeCode(word string) bool {
+	return strings.ContainsAny(word, "0123456789")
+}
@@ -0,0 +1,90 @@
+// internal/repository/coding_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// CodingRepository stores the diagnoses and procedures coded for appointments.
+type CodingRepository interface {
+	CreateDiagnosis(ctx context.Context, diagnosis *domain.Diagnosis) error
+	// ListDiagnoses returns the appointment's diagnoses by rank, with their
+	// catalog descriptions.
+	ListDiagnoses(ctx context.Context, appointmentID uint) ([]domain.Diagnosis, error)
+	DeleteDiagnosis(ctx context.Context, appointmentID, id uint) error
+
+	CreateProcedure(ctx context.Context, procedure *domain.Procedure) error
+	// ListProcedures returns the appointment's procedures in the order they
+	// were added, with their catalog descriptions.
+	ListProcedures(ctx context.Context, appointmentID uint) ([]domain.Procedure, error)
+	DeleteProcedure(ctx context.Context, appointmentID, id uint) error
+}
+
+type codingRepository struct {
+	db *gorm.DB
+}
+
+func NewCodingRepository(db *gorm.DB) CodingRepository {
+	return &codingRepository{db: db}
+}
+
+func (r *codingRepository) CreateDiagnosis(ctx context.Context, diagnosis *domain.Diagnosis) error {
+	err := conn(ctx, r.db).Create(diagnosis).Error
+	switch {
+	case isUniqueViolation(err, "idx_appointment_diagnoses_code"):
+		return domain.NewConflictError("diagnosis_exists", diagnosis.Code+" is already recorded for this appointment")
+	case isUniqueViolation(err, "idx_appointment_diagnoses_rank"):
+		return domain.NewConflictError("diagnosis_rank_taken", "another diagnosis of this appointment has the same rank")
+	}
+	return err
+}
+
+func (r *codingRepository) ListDiagnoses(ctx context.Context, appointmentID uint) ([]domain.Diagnosis, error) {
+	var diagnoses []domain.Diagnosis
+	err := withDescription(conn(ctx, r.db), "appointment_diagnoses").
+		Where("appointment_id = ?", appointmentID).Order("rank").Find(&diagnoses).Error
+	return diagnoses, err
+}
+
+func (r *codingRepository) DeleteDiagnosis(ctx context.Context, appointmentID, id uint) error {
+	result := conn(ctx, r.db).Where("appointment_id = ?", appointmentID).Delete(&domain.Diagnosis{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("diagnosis", id)
+	}
+	return nil
+}
+
+func (r *codingRepository) CreateProcedure(ctx context.Context, procedure *domain.Procedure) error {
+	return conn(ctx, r.db).Create(procedure).Error
+}
+
+func (r *codingRepository) ListProcedures(ctx context.Context, appointmentID uint) ([]domain.Procedure, error) {
+	var procedures []domain.Procedure
+	err := withDescription(conn(ctx, r.db), "appointment_procedures").
+		Where("appointment_id = ?", appointmentID).Order("appointment_procedures.id").Find(&procedures).Error
+	return procedures, err
+}
+
+func (r *codingRepository) DeleteProcedure(ctx context.Context, appointmentID, id uint) error {
+	result := conn(ctx, r.db).Where("appointment_id = ?", appointmentID).Delete(&domain.Procedure{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("procedure", id)
+	}
+	return nil
+}
+
+// withDescription selects a coded table's rows together with the catalog
+// description of each code.
+func withDescription(db *gorm.DB, table string) *gorm.DB {
+	return db.Table(table).Select(table + ".*, medical_codes.description").
+		Joins("JOIN medical_codes ON medical_codes.system = " + table + ".system AND medical_codes.code = " + table + ".code")
+}
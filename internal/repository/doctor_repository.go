@@ -4,12 +4,17 @@ package repository
 import (
 	"context"
 	"doctors/internal/domain"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 type DoctorRepository interface {
 	GetDefaultDoctor(ctx context.Context) (*domain.Doctor, error)
+	GetByID(ctx context.Context, id uint) (*domain.Doctor, error)
+	// List returns the doctors whose name contains name, all of them when
+	// it is empty, by ID.
+	List(ctx context.Context, name string) ([]domain.Doctor, error)
 }
 
 type doctorRepository struct {
@@ -22,8 +27,28 @@ func NewDoctorRepository(db *gorm.DB) DoctorRepository {
 
 func (r *doctorRepository) GetDefaultDoctor(ctx context.Context) (*domain.Doctor, error) {
 	var doctor domain.Doctor
-	if err := r.db.WithContext(ctx).First(&doctor).Error; err != nil {
-		return nil, err
+	if err := conn(ctx, r.db).First(&doctor).Error; err != nil {
+		return nil, notFound(err, "doctor", "default")
 	}
 	return &doctor, nil
 }
+
+func (r *doctorRepository) GetByID(ctx context.Context, id uint) (*domain.Doctor, error) {
+	var doctor domain.Doctor
+	if err := conn(ctx, r.db).First(&doctor, id).Error; err != nil {
+		return nil, notFound(err, "doctor", id)
+	}
+	return &doctor, nil
+}
+
+func (r *doctorRepository) List(ctx context.Context, name string) ([]domain.Doctor, error) {
+	query := conn(ctx, r.db)
+	if name != "" {
+		// Drop LIKE wildcards; doctor names don't contain them.
+		name = strings.NewReplacer("%", "", "_", "", "\\", "").Replace(strings.ToLower(name))
+		query = query.Where("lower(name) LIKE ?", "%"+name+"%")
+	}
+	var doctors []domain.Doctor
+	err := query.Order("id").Find(&doctors).Error
+	return doctors, err
+}
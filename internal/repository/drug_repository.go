@@ -0,0 +1,117 @@
+// internal/repository/drug_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DrugRepository stores the drug catalog and the interaction dataset.
+type DrugRepository interface {
+	// Upsert inserts drugs or refreshes existing ones, marking them active.
+	Upsert(ctx context.Context, drugs []domain.Drug) error
+	// DeactivateMissing deactivates the drugs that are not part of release
+	// and returns how many were deactivated.
+	DeactivateMissing(ctx context.Context, release string) (int64, error)
+	Get(ctx context.Context, rxcui string) (*domain.Drug, error)
+	// FindByName returns an active drug with exactly this name, ignoring
+	// case, or nil when there is none.
+	FindByName(ctx context.Context, name string) (*domain.Drug, error)
+	// Search returns up to limit active drugs whose name starts with query,
+	// or the drug with that RxCUI when query is a number.
+	Search(ctx context.Context, query string, limit int) ([]domain.Drug, error)
+
+	// DeleteInteractions empties the interaction dataset before a reload.
+	DeleteInteractions(ctx context.Context) error
+	CreateInteractions(ctx context.Context, interactions []domain.DrugInteraction) error
+	// Interactions returns the dataset entries between an ingredient of
+	// ingredients and one of others.
+	Interactions(ctx context.Context, ingredients, others []string) ([]domain.DrugInteraction, error)
+}
+
+type drugRepository struct {
+	db *gorm.DB
+}
+
+func NewDrugRepository(db *gorm.DB) DrugRepository {
+	return &drugRepository{db: db}
+}
+
+func (r *drugRepository) Upsert(ctx context.Context, drugs []domain.Drug) error {
+	if len(drugs) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "rxcui"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "ingredients", "strength", "dose_form", "classes", "active", "release", "updated_at"}),
+	}).Create(&drugs).Error
+}
+
+func (r *drugRepository) DeactivateMissing(ctx context.Context, release string) (int64, error) {
+	result := conn(ctx, r.db).Model(&domain.Drug{}).
+		Where("release <> ? AND active", release).
+		Updates(map[string]interface{}{"active": false})
+	return result.RowsAffected, result.Error
+}
+
+func (r *drugRepository) Get(ctx context.Context, rxcui string) (*domain.Drug, error) {
+	var drug domain.Drug
+	if err := conn(ctx, r.db).Where("rxcui = ?", rxcui).First(&drug).Error; err != nil {
+		return nil, notFound(err, "drug", rxcui)
+	}
+	return &drug, nil
+}
+
+func (r *drugRepository) FindByName(ctx context.Context, name string) (*domain.Drug, error) {
+	var drugs []domain.Drug
+	err := conn(ctx, r.db).Where("lower(name) = ? AND active", strings.ToLower(strings.TrimSpace(name))).
+		Order("rxcui").Limit(1).Find(&drugs).Error
+	if err != nil || len(drugs) == 0 {
+		return nil, err
+	}
+	return &drugs[0], nil
+}
+
+func (r *drugRepository) Search(ctx context.Context, query string, limit int) ([]domain.Drug, error) {
+	db := conn(ctx, r.db).Where("active")
+	query = strings.TrimSpace(query)
+	switch {
+	case query != "" && strings.Trim(query, "0123456789") == "":
+		db = db.Where("rxcui = ?", query)
+	case query != "":
+		// Drop LIKE wildcards; drug names don't contain them.
+		prefix := strings.NewReplacer("%", "", "_", "", "\\", "").Replace(strings.ToLower(query))
+		db = db.Where("lower(name) LIKE ?", prefix+"%")
+	}
+
+	var drugs []domain.Drug
+	err := db.Order("lower(name)").Order("rxcui").Limit(limit).Find(&drugs).Error
+	return drugs, err
+}
+
+func (r *drugRepository) DeleteInteractions(ctx context.Context) error {
+	return conn(ctx, r.db).Where("1 = 1").Delete(&domain.DrugInteraction{}).Error
+}
+
+func (r *drugRepository) CreateInteractions(ctx context.Context, interactions []domain.DrugInteraction) error {
+	if len(interactions) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Create(&interactions).Error
+}
+
+func (r *drugRepository) Interactions(ctx context.Context, ingredients, others []string) ([]domain.DrugInteraction, error) {
+	if len(ingredients) == 0 || len(others) == 0 {
+		return nil, nil
+	}
+	var interactions []domain.DrugInteraction
+	err := conn(ctx, r.db).
+		Where("ingredient_a IN ? AND ingredient_b IN ?", ingredients, others).
+		Or("ingredient_a IN ? AND ingredient_b IN ?", others, ingredients).
+		Order("ingredient_a").Order("ingredient_b").Find(&interactions).Error
+	return interactions, err
+}
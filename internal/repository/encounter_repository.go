@@ -0,0 +1,308 @@
+// internal/repository/encounter_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EncounterRepository interface {
+	Create(ctx context.Context, encounter *domain.Encounter) error
+	// GetByID and GetByAppointment return the encounter with its addenda.
+	GetByID(ctx context.Context, id uint) (*domain.Encounter, error)
+	GetByAppointment(ctx context.Context, appointmentID uint) (*domain.Encounter, error)
+	// ListByPatient returns the patient's encounters with their addenda, newest first.
+	ListByPatient(ctx context.Context, patientID uint) ([]domain.Encounter, error)
+	// LatestByPatient returns the encounter of the patient's most recent
+	// appointment, or nil when the patient has none.
+	LatestByPatient(ctx context.Context, patientID uint) (*domain.Encounter, error)
+	Update(ctx context.Context, encounter *domain.Encounter) error
+	// AddAddendum appends an addendum, encrypted with the encounter's data key.
+	AddAddendum(ctx context.Context, encounter *domain.Encounter, addendum *domain.EncounterAddendum) error
+	// RotateKeys re-encrypts, in batches, every encounter whose data key is
+	// not wrapped by the active master key, addenda included.
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+	PatientRecords
+}
+
+type encounterRepository struct {
+	db     *gorm.DB
+	cipher *encryption.Envelope
+}
+
+func NewEncounterRepository(db *gorm.DB, cipher *encryption.Envelope) EncounterRepository {
+	return &encounterRepository{db: db, cipher: cipher}
+}
+
+func (r *encounterRepository) Create(ctx context.Context, encounter *domain.Encounter) error {
+	encounter.Version = 1
+	err := r.withSealed(encounter, func() error { return conn(ctx, r.db).Create(encounter).Error })
+	if isUniqueViolation(err, "idx_encounters_appointment_id") {
+		return domain.NewConflictError("encounter_exists", "the appointment already has an encounter")
+	}
+	return err
+}
+
+func (r *encounterRepository) GetByID(ctx context.Context, id uint) (*domain.Encounter, error) {
+	var encounter domain.Encounter
+	if err := r.scoped(ctx).First(&encounter, id).Error; err != nil {
+		return nil, notFound(err, "encounter", id)
+	}
+	return &encounter, r.load(ctx, []*domain.Encounter{&encounter})
+}
+
+func (r *encounterRepository) GetByAppointment(ctx context.Context, appointmentID uint) (*domain.Encounter, error) {
+	var encounter domain.Encounter
+	if err := r.scoped(ctx).Where("appointment_id = ?", appointmentID).First(&encounter).Error; err != nil {
+		return nil, notFound(err, "encounter", fmt.Sprintf("for appointment %d", appointmentID))
+	}
+	return &encounter, r.load(ctx, []*domain.Encounter{&encounter})
+}
+
+func (r *encounterRepository) ListByPatient(ctx context.Context, patientID uint) ([]domain.Encounter, error) {
+	var encounters []domain.Encounter
+	err := r.scoped(ctx).Where("patient_id = ?", patientID).
+		Order("created_at DESC").Order("id DESC").Find(&encounters).Error
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]*domain.Encounter, len(encounters))
+	for i := range encounters {
+		refs[i] = &encounters[i]
+	}
+	return encounters, r.load(ctx, refs)
+}
+
+func (r *encounterRepository) LatestByPatient(ctx context.Context, patientID uint) (*domain.Encounter, error) {
+	var encounters []domain.Encounter
+	err := r.scoped(ctx).Joins("JOIN appointments ON appointments.id = encounters.appointment_id").
+		Where("encounters.patient_id = ?", patientID).
+		Order("appointments.date_time DESC").Order("encounters.id DESC").Limit(1).Find(&encounters).Error
+	if err != nil || len(encounters) == 0 {
+		return nil, err
+	}
+	return &encounters[0], r.load(ctx, []*domain.Encounter{&encounters[0]})
+}
+
+func (r *encounterRepository) Update(ctx context.Context, encounter *domain.Encounter) error {
+	return r.withSealed(encounter, func() error {
+		return updateVersioned(conn(ctx, r.db), encounter, &encounter.Version, "encounter")
+	})
+}
+
+func (r *encounterRepository) AddAddendum(ctx context.Context, encounter *domain.Encounter, addendum *domain.EncounterAddendum) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Encrypt with the data key stored now, holding it against a
+		// concurrent key rotation until the addendum is written.
+		var current domain.Encounter
+		err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id", "data_key", "key_id").
+			First(&current, encounter.ID).Error
+		if err != nil {
+			return notFound(err, "encounter", encounter.ID)
+		}
+		key, err := r.dataKey(&current)
+		if err != nil {
+			return err
+		}
+		plain := addendum.Text
+		if addendum.Text, err = r.cipher.Encrypt(key, plain); err != nil {
+			addendum.Text = plain
+			return fmt.Errorf("failed to encrypt addendum: %w", err)
+		}
+		err = tx.Create(addendum).Error
+		addendum.Text = plain
+		return err
+	})
+}
+
+func (r *encounterRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	activeKeyID := r.cipher.ActiveKeyID()
+	rotated := 0
+	var lastID uint
+
+	for {
+		var batch []domain.Encounter
+		// The batch is read and locked in the transaction that rewrites it,
+		// so a draft saved meanwhile waits instead of being overwritten.
+		err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id > ? AND (key_id IS NULL OR key_id <> ?)", lastID, activeKeyID).
+				Order("id").Limit(batchSize).Find(&batch).Error
+			if err != nil {
+				return err
+			}
+			for i := range batch {
+				encounter := &batch[i]
+				if err := r.load(ctx, []*domain.Encounter{encounter}); err != nil {
+					return err
+				}
+				// Force a fresh data key under the active master key.
+				encounter.DataKey, encounter.KeyID = "", ""
+				key, err := r.dataKey(encounter)
+				if err != nil {
+					return err
+				}
+				if err := r.seal(encounter, key); err != nil {
+					return err
+				}
+				if err := tx.Model(encounter).Select(encounterSealedColumns).UpdateColumns(encounter).Error; err != nil {
+					return err
+				}
+				for _, addendum := range encounter.Addenda {
+					if addendum.Text, err = r.cipher.Encrypt(key, addendum.Text); err != nil {
+						return fmt.Errorf("encounter %d: %w", encounter.ID, err)
+					}
+					err := tx.Model(&addendum).UpdateColumn("text", addendum.Text).Error
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return rotated, err
+		}
+		if len(batch) == 0 {
+			return rotated, nil
+		}
+
+		rotated += len(batch)
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+func (r *encounterRepository) RecordType() string {
+	return "encounters"
+}
+
+func (r *encounterRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.Encounter{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	// UpdateColumn keeps updated_at: moving an encounter is not an edit of it.
+	err := conn(ctx, r.db).Model(&domain.Encounter{}).Where("id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	return movedIDs, err
+}
+
+// load decrypts the encounters and attaches their addenda, oldest first.
+func (r *encounterRepository) load(ctx context.Context, encounters []*domain.Encounter) error {
+	if len(encounters) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(encounters))
+	byID := make(map[uint]*domain.Encounter, len(encounters))
+	for i, encounter := range encounters {
+		ids[i] = encounter.ID
+		byID[encounter.ID] = encounter
+		encounter.Addenda = []domain.EncounterAddendum{}
+	}
+
+	var addenda []domain.EncounterAddendum
+	if err := conn(ctx, r.db).Where("encounter_id IN ?", ids).Order("id").Find(&addenda).Error; err != nil {
+		return err
+	}
+	for _, addendum := range addenda {
+		encounter := byID[addendum.EncounterID]
+		encounter.Addenda = append(encounter.Addenda, addendum)
+	}
+
+	for _, encounter := range encounters {
+		if err := r.open(encounter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withSealed encrypts the encounter in place for the duration of fn and
+// restores the plaintext sections afterwards.
+func (r *encounterRepository) withSealed(encounter *domain.Encounter, fn func() error) error {
+	fields := encounterSealedFields(encounter)
+	plain := make([]string, len(fields))
+	for i, field := range fields {
+		plain[i] = *field
+	}
+	key, err := r.dataKey(encounter)
+	if err != nil {
+		return err
+	}
+	if err := r.seal(encounter, key); err != nil {
+		return err
+	}
+	err = fn()
+	for i, field := range fields {
+		*field = plain[i]
+	}
+	return err
+}
+
+// encounterSealedFields lists the encounter fields that are encrypted at rest.
+func encounterSealedFields(encounter *domain.Encounter) []*string {
+	return []*string{&encounter.Subjective, &encounter.Objective, &encounter.Assessment, &encounter.Plan}
+}
+
+// encounterSealedColumns are the columns rewritten when an encounter is re-encrypted.
+var encounterSealedColumns = []string{"subjective", "objective", "assessment", "plan", "data_key", "key_id"}
+
+func (r *encounterRepository) seal(encounter *domain.Encounter, key *encryption.DataKey) error {
+	var err error
+	for _, field := range encounterSealedFields(encounter) {
+		if *field, err = r.cipher.Encrypt(key, *field); err != nil {
+			return fmt.Errorf("failed to encrypt encounter: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *encounterRepository) open(encounter *domain.Encounter) error {
+	key, err := r.cipher.OpenDataKey(encounter.DataKey, encounter.KeyID)
+	if err != nil {
+		return fmt.Errorf("encounter %d: %w", encounter.ID, err)
+	}
+	for _, field := range encounterSealedFields(encounter) {
+		if *field, err = r.cipher.Decrypt(key, *field); err != nil {
+			return fmt.Errorf("failed to decrypt encounter %d: %w", encounter.ID, err)
+		}
+	}
+	for i := range encounter.Addenda {
+		addendum := &encounter.Addenda[i]
+		if addendum.Text, err = r.cipher.Decrypt(key, addendum.Text); err != nil {
+			return fmt.Errorf("failed to decrypt addendum %d: %w", addendum.ID, err)
+		}
+	}
+	return nil
+}
+
+// dataKey reuses the encounter's existing data key or generates a new one.
+func (r *encounterRepository) dataKey(encounter *domain.Encounter) (*encryption.DataKey, error) {
+	if encounter.DataKey != "" {
+		return r.cipher.OpenDataKey(encounter.DataKey, encounter.KeyID)
+	}
+	key, err := r.cipher.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	encounter.DataKey, encounter.KeyID = key.Wrapped, key.KeyID
+	return key, nil
+}
+
+func (r *encounterRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "encounters")
+}
@@ -0,0 +1,63 @@
+// internal/repository/fee_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FeeRepository interface {
+	// List returns the fee schedule by appointment type.
+	List(ctx context.Context) ([]domain.Fee, error)
+	// FindByType returns the fee of an appointment type, or nil when it has none.
+	FindByType(ctx context.Context, appointmentType string) (*domain.Fee, error)
+	// Save creates the fee of its appointment type or replaces it.
+	Save(ctx context.Context, fee *domain.Fee) error
+	DeleteByType(ctx context.Context, appointmentType string) error
+}
+
+type feeRepository struct {
+	db *gorm.DB
+}
+
+func NewFeeRepository(db *gorm.DB) FeeRepository {
+	return &feeRepository{db: db}
+}
+
+func (r *feeRepository) List(ctx context.Context) ([]domain.Fee, error) {
+	fees := []domain.Fee{}
+	err := conn(ctx, r.db).Order("appointment_type").Find(&fees).Error
+	return fees, err
+}
+
+func (r *feeRepository) FindByType(ctx context.Context, appointmentType string) (*domain.Fee, error) {
+	var fees []domain.Fee
+	if err := conn(ctx, r.db).Where("appointment_type = ?", appointmentType).Limit(1).Find(&fees).Error; err != nil {
+		return nil, err
+	}
+	if len(fees) == 0 {
+		return nil, nil
+	}
+	return &fees[0], nil
+}
+
+func (r *feeRepository) Save(ctx context.Context, fee *domain.Fee) error {
+	return conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "appointment_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "code", "unit_price_cents", "tax_rate", "updated_at"}),
+	}).Create(fee).Error
+}
+
+func (r *feeRepository) DeleteByType(ctx context.Context, appointmentType string) error {
+	result := conn(ctx, r.db).Where("appointment_type = ?", appointmentType).Delete(&domain.Fee{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("fee", appointmentType)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// internal/repository/insurance_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type InsuranceRepository interface {
+	CreatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error
+	GetPolicy(ctx context.Context, id uint) (*domain.InsurancePolicy, error)
+	// ListPolicies returns the patient's policies by priority, then newest first.
+	ListPolicies(ctx context.Context, patientID uint) ([]domain.InsurancePolicy, error)
+	UpdatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error
+	// DeletePolicy archives a policy, keeping its eligibility checks.
+	DeletePolicy(ctx context.Context, id uint) error
+	PatientRecords
+
+	CreateCheck(ctx context.Context, check *domain.EligibilityCheck) error
+	UpdateCheck(ctx context.Context, check *domain.EligibilityCheck) error
+	// ListChecks returns eligibility checks on any of the patient's
+	// policies, archived ones included, newest first.
+	ListChecks(ctx context.Context, patientID uint, limit int) ([]domain.EligibilityCheck, error)
+	// HasCheck reports whether the policy has an eligibility check for the
+	// YYYY-MM-DD service date that the clearinghouse answered.
+	HasCheck(ctx context.Context, policyID uint, serviceDate string) (bool, error)
+
+	// RotateKeys re-encrypts, in batches, every policy and eligibility
+	// check whose data key is not wrapped by the active master key.
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+}
+
+// Member IDs, subscriber details and the raw X12 interchanges, which name
+// the patient, are encrypted at rest like patient PII.
+type insuranceRepository struct {
+	db     *gorm.DB
+	cipher *encryption.Envelope
+}
+
+func NewInsuranceRepository(db *gorm.DB, cipher *encryption.Envelope) InsuranceRepository {
+	return &insuranceRepository{db: db, cipher: cipher}
+}
+
+func policyRow(policy *domain.InsurancePolicy) sealedRow {
+	return sealedRow{
+		DataKey: &policy.DataKey,
+		KeyID:   &policy.KeyID,
+		Fields:  []*string{&policy.MemberID, &policy.SubscriberName, &policy.SubscriberDateOfBirth},
+	}
+}
+
+var policySealedColumns = []string{"member_id", "subscriber_name", "subscriber_date_of_birth"}
+
+func checkRow(check *domain.EligibilityCheck) sealedRow {
+	return sealedRow{DataKey: &check.DataKey, KeyID: &check.KeyID, Fields: []*string{&check.Request, &check.Response}}
+}
+
+var checkSealedColumns = []string{"request", "response"}
+
+func (r *insuranceRepository) CreatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error {
+	return withSealedRow(r.cipher, policyRow(policy), func() error {
+		return conn(ctx, r.db).Create(policy).Error
+	})
+}
+
+func (r *insuranceRepository) GetPolicy(ctx context.Context, id uint) (*domain.InsurancePolicy, error) {
+	var policy domain.InsurancePolicy
+	if err := r.scoped(ctx).First(&policy, id).Error; err != nil {
+		return nil, notFound(err, "insurance_policy", id)
+	}
+	if err := openRow(r.cipher, policyRow(&policy)); err != nil {
+		return nil, fmt.Errorf("insurance policy %d: %w", policy.ID, err)
+	}
+	return &policy, nil
+}
+
+func (r *insuranceRepository) ListPolicies(ctx context.Context, patientID uint) ([]domain.InsurancePolicy, error) {
+	var policies []domain.InsurancePolicy
+	err := r.scoped(ctx).Where("patient_id = ?", patientID).
+		Order("CASE priority WHEN 'primary' THEN 1 WHEN 'secondary' THEN 2 ELSE 3 END").
+		Order("effective_from DESC").Find(&policies).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range policies {
+		if err := openRow(r.cipher, policyRow(&policies[i])); err != nil {
+			return nil, fmt.Errorf("insurance policy %d: %w", policies[i].ID, err)
+		}
+	}
+	return policies, nil
+}
+
+func (r *insuranceRepository) UpdatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error {
+	return withSealedRow(r.cipher, policyRow(policy), func() error {
+		return conn(ctx, r.db).Save(policy).Error
+	})
+}
+
+func (r *insuranceRepository) DeletePolicy(ctx context.Context, id uint) error {
+	result := r.scoped(ctx).Delete(&domain.InsurancePolicy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("insurance_policy", id)
+	}
+	return nil
+}
+
+func (r *insuranceRepository) RecordType() string {
+	return "insurance_policies"
+}
+
+func (r *insuranceRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Unscoped().Model(&domain.InsurancePolicy{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Unscoped().Model(&domain.InsurancePolicy{}).Where("id IN ?", movedIDs).
+		Update("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *insuranceRepository) CreateCheck(ctx context.Context, check *domain.EligibilityCheck) error {
+	return withSealedRow(r.cipher, checkRow(check), func() error {
+		return conn(ctx, r.db).Create(check).Error
+	})
+}
+
+func (r *insuranceRepository) UpdateCheck(ctx context.Context, check *domain.EligibilityCheck) error {
+	return withSealedRow(r.cipher, checkRow(check), func() error {
+		return conn(ctx, r.db).Save(check).Error
+	})
+}
+
+func (r *insuranceRepository) ListChecks(ctx context.Context, patientID uint, limit int) ([]domain.EligibilityCheck, error) {
+	var checks []domain.EligibilityCheck
+	err := conn(ctx, r.db).
+		Where("policy_id IN (?)", r.scoped(ctx).Unscoped().Model(&domain.InsurancePolicy{}).
+			Select("id").Where("patient_id = ?", patientID)).
+		Order("checked_at DESC").Order("id DESC").Limit(limit).Find(&checks).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range checks {
+		if err := openRow(r.cipher, checkRow(&checks[i])); err != nil {
+			return nil, fmt.Errorf("eligibility check %d: %w", checks[i].ID, err)
+		}
+	}
+	return checks, nil
+}
+
+func (r *insuranceRepository) HasCheck(ctx context.Context, policyID uint, serviceDate string) (bool, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&domain.EligibilityCheck{}).
+		Where("policy_id = ? AND service_date = ? AND status NOT IN ?", policyID, serviceDate,
+			[]string{domain.EligibilityPending, domain.EligibilityError}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *insuranceRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	policies, err := rotateSealedRows(ctx, r.db, r.cipher, batchSize, policySealedColumns,
+		func(p *domain.InsurancePolicy) uint { return p.ID }, policyRow)
+	if err != nil {
+		return policies, err
+	}
+	checks, err := rotateSealedRows(ctx, r.db, r.cipher, batchSize, checkSealedColumns,
+		func(c *domain.EligibilityCheck) uint { return c.ID }, checkRow)
+	return policies + checks, err
+}
+
+func (r *insuranceRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "insurance_policies")
+}
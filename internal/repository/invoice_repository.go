@@ -0,0 +1,183 @@
+// internal/repository/invoice_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository interface {
+	// Create stores an invoice and its lines.
+	Create(ctx context.Context, invoice *domain.Invoice) error
+	// GetByID returns an invoice with its lines and payments.
+	GetByID(ctx context.Context, id uint) (*domain.Invoice, error)
+	// GetForUpdate is GetByID, locking the invoice until the transaction ends.
+	GetForUpdate(ctx context.Context, id uint) (*domain.Invoice, error)
+	// FindByAppointment returns the invoice of an appointment that isn't
+	// void, or nil when there is none.
+	FindByAppointment(ctx context.Context, appointmentID uint) (*domain.Invoice, error)
+	// List returns a page of matching invoices without lines or payments,
+	// newest first, and the number of matches.
+	List(ctx context.Context, search domain.InvoiceSearch) ([]domain.Invoice, int64, error)
+	// ListOpen returns the patient's issued invoices, oldest due first.
+	ListOpen(ctx context.Context, patientID uint) ([]domain.Invoice, error)
+	// Update stores the invoice; with lines set, they replace the stored ones.
+	Update(ctx context.Context, invoice *domain.Invoice, lines bool) error
+	CreatePayment(ctx context.Context, payment *domain.Payment) error
+	// NextNumber returns the next invoice number counter of a scope, starting at 1.
+	NextNumber(ctx context.Context, scope string) (int64, error)
+	PatientRecords
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{db: db}
+}
+
+func (r *invoiceRepository) Create(ctx context.Context, invoice *domain.Invoice) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		return createLines(tx, invoice)
+	})
+}
+
+func createLines(tx *gorm.DB, invoice *domain.Invoice) error {
+	if len(invoice.Lines) == 0 {
+		return nil
+	}
+	for i := range invoice.Lines {
+		invoice.Lines[i].ID = 0
+		invoice.Lines[i].InvoiceID = invoice.ID
+		invoice.Lines[i].Position = i + 1
+	}
+	return tx.Create(&invoice.Lines).Error
+}
+
+func (r *invoiceRepository) GetByID(ctx context.Context, id uint) (*domain.Invoice, error) {
+	return r.get(ctx, r.scoped(ctx), id)
+}
+
+func (r *invoiceRepository) GetForUpdate(ctx context.Context, id uint) (*domain.Invoice, error) {
+	return r.get(ctx, r.scoped(ctx).Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func (r *invoiceRepository) get(ctx context.Context, query *gorm.DB, id uint) (*domain.Invoice, error) {
+	var invoice domain.Invoice
+	if err := query.First(&invoice, id).Error; err != nil {
+		return nil, notFound(err, "invoice", id)
+	}
+	invoice.Lines = []domain.InvoiceLine{}
+	if err := conn(ctx, r.db).Where("invoice_id = ?", id).Order("position").Find(&invoice.Lines).Error; err != nil {
+		return nil, err
+	}
+	invoice.Payments = []domain.Payment{}
+	if err := conn(ctx, r.db).Where("invoice_id = ?", id).Order("id").Find(&invoice.Payments).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *invoiceRepository) FindByAppointment(ctx context.Context, appointmentID uint) (*domain.Invoice, error) {
+	var ids []uint
+	err := r.scoped(ctx).Model(&domain.Invoice{}).
+		Where("appointment_id = ? AND status <> ?", appointmentID, domain.InvoiceVoid).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return r.GetByID(ctx, ids[0])
+}
+
+func (r *invoiceRepository) List(ctx context.Context, search domain.InvoiceSearch) ([]domain.Invoice, int64, error) {
+	query := r.scoped(ctx).Model(&domain.Invoice{})
+	if search.PatientID != nil {
+		query = query.Where("patient_id = ?", *search.PatientID)
+	}
+	if len(search.Statuses) > 0 {
+		query = query.Where("status IN ?", search.Statuses)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	invoices := []domain.Invoice{}
+	err := query.Order("created_at DESC").Order("id DESC").Limit(search.Limit).Offset(search.Offset).Find(&invoices).Error
+	return invoices, total, err
+}
+
+func (r *invoiceRepository) ListOpen(ctx context.Context, patientID uint) ([]domain.Invoice, error) {
+	invoices := []domain.Invoice{}
+	err := r.scoped(ctx).Where("patient_id = ? AND status = ?", patientID, domain.InvoiceIssued).
+		Order("due_date").Order("id").Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *invoiceRepository) Update(ctx context.Context, invoice *domain.Invoice, lines bool) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(invoice).Error; err != nil {
+			return err
+		}
+		if !lines {
+			return nil
+		}
+		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&domain.InvoiceLine{}).Error; err != nil {
+			return err
+		}
+		return createLines(tx, invoice)
+	})
+}
+
+func (r *invoiceRepository) CreatePayment(ctx context.Context, payment *domain.Payment) error {
+	return conn(ctx, r.db).Create(payment).Error
+}
+
+func (r *invoiceRepository) NextNumber(ctx context.Context, scope string) (int64, error) {
+	var value int64
+	err := conn(ctx, r.db).Raw(`
+INSERT INTO invoice_sequences (scope, next_value) VALUES (?, 2)
+ON CONFLICT (scope) DO UPDATE SET next_value = invoice_sequences.next_value + 1
+RETURNING next_value - 1`, scope).Scan(&value).Error
+	return value, err
+}
+
+func (r *invoiceRepository) RecordType() string {
+	return "invoices"
+}
+
+// ReassignPatient moves invoices together with their payments.
+func (r *invoiceRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.Invoice{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	// UpdateColumn keeps updated_at: moving an invoice is not an edit of it.
+	err := conn(ctx, r.db).Model(&domain.Invoice{}).Where("id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	if err != nil {
+		return nil, err
+	}
+	err = conn(ctx, r.db).Model(&domain.Payment{}).Where("invoice_id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *invoiceRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "invoices")
+}
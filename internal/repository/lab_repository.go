@@ -0,0 +1,203 @@
+// internal/repository/lab_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LabRepository interface {
+	// CreateOrder stores an order without its placer order number, which
+	// the caller derives from the new ID and sets with UpdateOrder.
+	CreateOrder(ctx context.Context, order *domain.LabOrder) error
+	// GetOrder returns an order with its results.
+	GetOrder(ctx context.Context, id uint) (*domain.LabOrder, error)
+	// FindOrderByPlacerNumber returns the order with the given placer
+	// order number, or nil when there is none.
+	FindOrderByPlacerNumber(ctx context.Context, number string) (*domain.LabOrder, error)
+	// ListOrdersByPatient returns the patient's orders, newest first.
+	ListOrdersByPatient(ctx context.Context, patientID uint) ([]domain.LabOrder, error)
+	// ListOrdersByAppointment returns the orders of an appointment in the
+	// order they were made.
+	ListOrdersByAppointment(ctx context.Context, appointmentID uint) ([]domain.LabOrder, error)
+	UpdateOrder(ctx context.Context, order *domain.LabOrder) error
+	// SaveResults stores results, replacing those the order already has
+	// for the same codes.
+	SaveResults(ctx context.Context, results []domain.LabResult) error
+	ListResults(ctx context.Context, orderID uint) ([]domain.LabResult, error)
+	LogMessage(ctx context.Context, message *domain.HL7Message) error
+	// FindMessage returns the latest message with the given direction and
+	// control ID, or nil when there is none.
+	FindMessage(ctx context.Context, direction, controlID string) (*domain.HL7Message, error)
+	// RotateKeys re-encrypts, in batches, every logged message whose data
+	// key is not wrapped by the active master key.
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+	PatientRecords
+}
+
+type labRepository struct {
+	db     *gorm.DB
+	cipher *encryption.Envelope
+}
+
+func NewLabRepository(db *gorm.DB, cipher *encryption.Envelope) LabRepository {
+	return &labRepository{db: db, cipher: cipher}
+}
+
+func (r *labRepository) CreateOrder(ctx context.Context, order *domain.LabOrder) error {
+	return conn(ctx, r.db).Omit("placer_order_number").Create(order).Error
+}
+
+func (r *labRepository) GetOrder(ctx context.Context, id uint) (*domain.LabOrder, error) {
+	var order domain.LabOrder
+	if err := r.scoped(ctx).First(&order, id).Error; err != nil {
+		return nil, notFound(err, "lab order", id)
+	}
+	results, err := r.ListResults(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	order.Results = results
+	return &order, nil
+}
+
+func (r *labRepository) FindOrderByPlacerNumber(ctx context.Context, number string) (*domain.LabOrder, error) {
+	var orders []domain.LabOrder
+	if err := r.scoped(ctx).Where("placer_order_number = ?", number).Limit(1).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	return &orders[0], nil
+}
+
+func (r *labRepository) ListOrdersByPatient(ctx context.Context, patientID uint) ([]domain.LabOrder, error) {
+	var orders []domain.LabOrder
+	err := r.scoped(ctx).Where("patient_id = ?", patientID).
+		Order("created_at DESC").Order("id DESC").Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, r.attachResults(ctx, orders)
+}
+
+func (r *labRepository) ListOrdersByAppointment(ctx context.Context, appointmentID uint) ([]domain.LabOrder, error) {
+	var orders []domain.LabOrder
+	if err := r.scoped(ctx).Where("appointment_id = ?", appointmentID).Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, r.attachResults(ctx, orders)
+}
+
+// attachResults loads the results of orders in one query.
+func (r *labRepository) attachResults(ctx context.Context, orders []domain.LabOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(orders))
+	index := make(map[uint]int, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		index[order.ID] = i
+		orders[i].Results = []domain.LabResult{}
+	}
+
+	var results []domain.LabResult
+	if err := conn(ctx, r.db).Where("lab_order_id IN ?", ids).Order("id").Find(&results).Error; err != nil {
+		return err
+	}
+	for _, result := range results {
+		i := index[result.LabOrderID]
+		orders[i].Results = append(orders[i].Results, result)
+	}
+	return nil
+}
+
+func (r *labRepository) UpdateOrder(ctx context.Context, order *domain.LabOrder) error {
+	return conn(ctx, r.db).Save(order).Error
+}
+
+func (r *labRepository) SaveResults(ctx context.Context, results []domain.LabResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "lab_order_id"}, {Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "value_type", "value", "units", "reference_range",
+			"flag", "status", "observed_at", "received_at"}),
+	}).Create(&results).Error
+}
+
+func (r *labRepository) ListResults(ctx context.Context, orderID uint) ([]domain.LabResult, error) {
+	results := []domain.LabResult{}
+	err := forPatientTenant(ctx, conn(ctx, r.db), "lab_results").Where("lab_order_id = ?", orderID).Order("id").Find(&results).Error
+	return results, err
+}
+
+func messageRow(message *domain.HL7Message) sealedRow {
+	return sealedRow{DataKey: &message.DataKey, KeyID: &message.KeyID, Fields: []*string{&message.Payload}}
+}
+
+func (r *labRepository) LogMessage(ctx context.Context, message *domain.HL7Message) error {
+	return withSealedRow(r.cipher, messageRow(message), func() error {
+		return conn(ctx, r.db).Create(message).Error
+	})
+}
+
+func (r *labRepository) FindMessage(ctx context.Context, direction, controlID string) (*domain.HL7Message, error) {
+	var messages []domain.HL7Message
+	err := conn(ctx, r.db).Where("direction = ? AND control_id = ?", direction, controlID).
+		Order("id DESC").Limit(1).Find(&messages).Error
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	if err := openRow(r.cipher, messageRow(&messages[0])); err != nil {
+		return nil, fmt.Errorf("HL7 message %d: %w", messages[0].ID, err)
+	}
+	return &messages[0], nil
+}
+
+func (r *labRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	return rotateSealedRows(ctx, r.db, r.cipher, batchSize, []string{"payload"},
+		func(m *domain.HL7Message) uint { return m.ID }, messageRow)
+}
+
+func (r *labRepository) RecordType() string {
+	return "lab_orders"
+}
+
+func (r *labRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.LabOrder{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	// Results follow their order. UpdateColumn keeps updated_at: moving
+	// an order is not an edit of it.
+	err := conn(ctx, r.db).Model(&domain.LabOrder{}).Where("id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	if err != nil {
+		return nil, err
+	}
+	err = conn(ctx, r.db).Model(&domain.LabResult{}).Where("lab_order_id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *labRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "lab_orders")
+}
@@ -0,0 +1,93 @@
+// internal/repository/medication_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type MedicationRepository interface {
+	Create(ctx context.Context, medication *domain.Medication) error
+	GetByID(ctx context.Context, id uint) (*domain.Medication, error)
+	// ListByPatient returns the patient's medications, active ones first and
+	// then by name; only those with status when it is set.
+	ListByPatient(ctx context.Context, patientID uint, status string) ([]domain.Medication, error)
+	Update(ctx context.Context, medication *domain.Medication) error
+	// Delete marks a medication as entered in error.
+	Delete(ctx context.Context, id uint) error
+	PatientRecords
+}
+
+type medicationRepository struct {
+	db *gorm.DB
+}
+
+func NewMedicationRepository(db *gorm.DB) MedicationRepository {
+	return &medicationRepository{db: db}
+}
+
+func (r *medicationRepository) Create(ctx context.Context, medication *domain.Medication) error {
+	return conn(ctx, r.db).Create(medication).Error
+}
+
+func (r *medicationRepository) GetByID(ctx context.Context, id uint) (*domain.Medication, error) {
+	var medication domain.Medication
+	if err := r.scoped(ctx).First(&medication, id).Error; err != nil {
+		return nil, notFound(err, "medication", id)
+	}
+	return &medication, nil
+}
+
+func (r *medicationRepository) ListByPatient(ctx context.Context, patientID uint, status string) ([]domain.Medication, error) {
+	query := r.scoped(ctx).Where("patient_id = ?", patientID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var medications []domain.Medication
+	err := query.Order("status").Order("lower(name)").Order("id").Find(&medications).Error
+	return medications, err
+}
+
+func (r *medicationRepository) Update(ctx context.Context, medication *domain.Medication) error {
+	return conn(ctx, r.db).Save(medication).Error
+}
+
+func (r *medicationRepository) Delete(ctx context.Context, id uint) error {
+	result := r.scoped(ctx).Delete(&domain.Medication{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("medication", id)
+	}
+	return nil
+}
+
+func (r *medicationRepository) RecordType() string {
+	return "medications"
+}
+
+func (r *medicationRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Unscoped().Model(&domain.Medication{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Medication{}).Where("id IN ?", movedIDs).
+		Update("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *medicationRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "patient_medications")
+}
@@ -0,0 +1,164 @@
+// internal/repository/outbox_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, message *domain.OutboxMessage) error
+	// LockPending returns up to limit unpublished messages, oldest first,
+	// locked until the transaction ends. Messages locked by another
+	// publisher are skipped.
+	LockPending(ctx context.Context, limit int) ([]domain.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id uint, at time.Time) error
+	// MarkFailed counts a failed publish of a message and records why.
+	MarkFailed(ctx context.Context, id uint, reason string) error
+	// List returns the newest messages, only unpublished ones if pending.
+	List(ctx context.Context, pending bool, limit int) ([]domain.OutboxMessage, error)
+
+	AddDeadLetter(ctx context.Context, letter *domain.DeadLetter) error
+	// ListDeadLetters returns the newest dead letters, only ones not
+	// replayed yet if pending.
+	ListDeadLetters(ctx context.Context, pending bool, limit int) ([]domain.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id uint) (*domain.DeadLetter, error)
+	// GetDeadLetterForUpdate is GetDeadLetter, locking the row until the
+	// transaction ends.
+	GetDeadLetterForUpdate(ctx context.Context, id uint) (*domain.DeadLetter, error)
+	MarkReplayed(ctx context.Context, letter *domain.DeadLetter) error
+	// RotateKeys re-encrypts, in batches, every outbox message and dead
+	// letter whose data key is not wrapped by the active master key.
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+}
+
+type outboxRepository struct {
+	db     *gorm.DB
+	cipher *encryption.Envelope
+}
+
+func NewOutboxRepository(db *gorm.DB, cipher *encryption.Envelope) OutboxRepository {
+	return &outboxRepository{db: db, cipher: cipher}
+}
+
+func outboxRow(message *domain.OutboxMessage) sealedRow {
+	return sealedRow{DataKey: &message.DataKey, KeyID: &message.KeyID, Fields: []*string{&message.Payload}}
+}
+
+func deadLetterRow(letter *domain.DeadLetter) sealedRow {
+	return sealedRow{DataKey: &letter.DataKey, KeyID: &letter.KeyID, Fields: []*string{&letter.Payload}}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, message *domain.OutboxMessage) error {
+	return withSealedRow(r.cipher, outboxRow(message), func() error {
+		return conn(ctx, r.db).Create(message).Error
+	})
+}
+
+func (r *outboxRepository) LockPending(ctx context.Context, limit int) ([]domain.OutboxMessage, error) {
+	var messages []domain.OutboxMessage
+	err := conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL").Order("id").Limit(limit).Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, r.openMessages(messages)
+}
+
+func (r *outboxRepository) openMessages(messages []domain.OutboxMessage) error {
+	for i := range messages {
+		if err := openRow(r.cipher, outboxRow(&messages[i])); err != nil {
+			return fmt.Errorf("outbox message %d: %w", messages[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	return conn(ctx, r.db).Model(&domain.OutboxMessage{}).Where("id = ?", id).
+		Update("published_at", at).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint, reason string) error {
+	return conn(ctx, r.db).Model(&domain.OutboxMessage{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "last_error": reason}).Error
+}
+
+func (r *outboxRepository) List(ctx context.Context, pending bool, limit int) ([]domain.OutboxMessage, error) {
+	query := conn(ctx, r.db)
+	if pending {
+		query = query.Where("published_at IS NULL")
+	}
+	var messages []domain.OutboxMessage
+	if err := query.Order("id DESC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, r.openMessages(messages)
+}
+
+func (r *outboxRepository) AddDeadLetter(ctx context.Context, letter *domain.DeadLetter) error {
+	return withSealedRow(r.cipher, deadLetterRow(letter), func() error {
+		return conn(ctx, r.db).Create(letter).Error
+	})
+}
+
+func (r *outboxRepository) ListDeadLetters(ctx context.Context, pending bool, limit int) ([]domain.DeadLetter, error) {
+	query := conn(ctx, r.db)
+	if pending {
+		query = query.Where("replayed_at IS NULL")
+	}
+	var letters []domain.DeadLetter
+	if err := query.Order("id DESC").Limit(limit).Find(&letters).Error; err != nil {
+		return nil, err
+	}
+	for i := range letters {
+		if err := openRow(r.cipher, deadLetterRow(&letters[i])); err != nil {
+			return nil, fmt.Errorf("dead letter %d: %w", letters[i].ID, err)
+		}
+	}
+	return letters, nil
+}
+
+func (r *outboxRepository) GetDeadLetter(ctx context.Context, id uint) (*domain.DeadLetter, error) {
+	var letter domain.DeadLetter
+	if err := conn(ctx, r.db).First(&letter, id).Error; err != nil {
+		return nil, notFound(err, "dead letter", id)
+	}
+	if err := openRow(r.cipher, deadLetterRow(&letter)); err != nil {
+		return nil, fmt.Errorf("dead letter %d: %w", id, err)
+	}
+	return &letter, nil
+}
+
+func (r *outboxRepository) GetDeadLetterForUpdate(ctx context.Context, id uint) (*domain.DeadLetter, error) {
+	var letter domain.DeadLetter
+	err := conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).First(&letter, id).Error
+	if err != nil {
+		return nil, notFound(err, "dead letter", id)
+	}
+	if err := openRow(r.cipher, deadLetterRow(&letter)); err != nil {
+		return nil, fmt.Errorf("dead letter %d: %w", id, err)
+	}
+	return &letter, nil
+}
+
+func (r *outboxRepository) MarkReplayed(ctx context.Context, letter *domain.DeadLetter) error {
+	return conn(ctx, r.db).Model(letter).Select("replayed_at", "outbox_id").Updates(letter).Error
+}
+
+func (r *outboxRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	messages, err := rotateSealedRows(ctx, r.db, r.cipher, batchSize, []string{"payload"},
+		func(m *domain.OutboxMessage) uint { return m.ID }, outboxRow)
+	if err != nil {
+		return messages, err
+	}
+	letters, err := rotateSealedRows(ctx, r.db, r.cipher, batchSize, []string{"payload"},
+		func(l *domain.DeadLetter) uint { return l.ID }, deadLetterRow)
+	return messages + letters, err
+}
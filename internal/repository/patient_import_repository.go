@@ -0,0 +1,136 @@
+// internal/repository/patient_import_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PatientImportRepository interface {
+	Create(ctx context.Context, imp *domain.PatientImport) error
+	GetByID(ctx context.Context, id uint) (*domain.PatientImport, error)
+	// Update stores the progress or outcome of an import.
+	Update(ctx context.Context, imp *domain.PatientImport) error
+	// List returns imports, most recent first. Like GetByID, it only sees
+	// the tenant ctx acts for.
+	List(ctx context.Context, page, pageSize int) ([]domain.PatientImport, int64, error)
+	// Claim marks the oldest queued import, or a running one not updated
+	// since staleBefore (its worker died), as running and returns it. It
+	// returns nil when there is nothing to do. Concurrent workers never
+	// claim the same import.
+	Claim(ctx context.Context, staleBefore time.Time) (*domain.PatientImport, error)
+	CreateUpload(ctx context.Context, upload *domain.PatientImportUpload) error
+	GetUpload(ctx context.Context, id uint) (*domain.PatientImportUpload, error)
+	// DeleteUpload drops the rows of a finished import.
+	DeleteUpload(ctx context.Context, id uint) error
+	// RotateKeys re-encrypts, in batches, the rows of every unfinished
+	// import whose data key is not wrapped by the active master key.
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+}
+
+type patientImportRepository struct {
+	db     *gorm.DB
+	cipher *encryption.Envelope
+}
+
+func NewPatientImportRepository(db *gorm.DB, cipher *encryption.Envelope) PatientImportRepository {
+	return &patientImportRepository{db: db, cipher: cipher}
+}
+
+func (r *patientImportRepository) Create(ctx context.Context, imp *domain.PatientImport) error {
+	return conn(ctx, r.db).Create(imp).Error
+}
+
+func (r *patientImportRepository) GetByID(ctx context.Context, id uint) (*domain.PatientImport, error) {
+	var imp domain.PatientImport
+	if err := forTenant(ctx, conn(ctx, r.db), "patient_imports").First(&imp, id).Error; err != nil {
+		return nil, notFound(err, "patient import", id)
+	}
+	return &imp, nil
+}
+
+func (r *patientImportRepository) Update(ctx context.Context, imp *domain.PatientImport) error {
+	return conn(ctx, r.db).Save(imp).Error
+}
+
+func (r *patientImportRepository) List(ctx context.Context, page, pageSize int) ([]domain.PatientImport, int64, error) {
+	var total int64
+	if err := forTenant(ctx, conn(ctx, r.db), "patient_imports").Model(&domain.PatientImport{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var imports []domain.PatientImport
+	err := forTenant(ctx, conn(ctx, r.db), "patient_imports").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&imports).Error
+	return imports, total, err
+}
+
+func (r *patientImportRepository) Claim(ctx context.Context, staleBefore time.Time) (*domain.PatientImport, error) {
+	var claimed *domain.PatientImport
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var imports []domain.PatientImport
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND updated_at < ?)",
+				domain.PatientImportQueued, domain.PatientImportRunning, staleBefore).
+			Order("id").Limit(1).Find(&imports).Error
+		if err != nil || len(imports) == 0 {
+			return err
+		}
+
+		imp := &imports[0]
+		imp.Status = domain.PatientImportRunning
+		if err := tx.Save(imp).Error; err != nil {
+			return err
+		}
+		claimed = imp
+		return nil
+	})
+	return claimed, err
+}
+
+func uploadRow(upload *domain.PatientImportUpload) sealedRow {
+	return sealedRow{DataKey: &upload.DataKey, KeyID: &upload.KeyID, Fields: []*string{&upload.RowsSealed}}
+}
+
+func (r *patientImportRepository) CreateUpload(ctx context.Context, upload *domain.PatientImportUpload) error {
+	rows, err := json.Marshal(upload.Rows)
+	if err != nil {
+		return err
+	}
+	upload.RowsSealed = string(rows)
+	defer func() { upload.RowsSealed = "" }()
+	return withSealedRow(r.cipher, uploadRow(upload), func() error {
+		return conn(ctx, r.db).Create(upload).Error
+	})
+}
+
+func (r *patientImportRepository) GetUpload(ctx context.Context, id uint) (*domain.PatientImportUpload, error) {
+	var upload domain.PatientImportUpload
+	if err := conn(ctx, r.db).First(&upload, id).Error; err != nil {
+		return nil, notFound(err, "patient import upload", id)
+	}
+	if err := openRow(r.cipher, uploadRow(&upload)); err != nil {
+		return nil, fmt.Errorf("patient import upload %d: %w", id, err)
+	}
+	if err := json.Unmarshal([]byte(upload.RowsSealed), &upload.Rows); err != nil {
+		return nil, fmt.Errorf("patient import upload %d: %w", id, err)
+	}
+	upload.RowsSealed = ""
+	return &upload, nil
+}
+
+func (r *patientImportRepository) DeleteUpload(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&domain.PatientImportUpload{}, id).Error
+}
+
+func (r *patientImportRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	return rotateSealedRows(ctx, r.db, r.cipher, batchSize, []string{"rows"},
+		func(u *domain.PatientImportUpload) uint { return u.ID }, uploadRow)
+}
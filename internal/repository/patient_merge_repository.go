@@ -0,0 +1,63 @@
+// internal/repository/patient_merge_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// PatientRecords is a repository of records belonging to a patient. Merging
+// patients moves every kind of record from the duplicate to the survivor.
+type PatientRecords interface {
+	// RecordType names the records in merge audits; it is their table name.
+	RecordType() string
+	// ReassignPatient moves records, archived ones included, from one
+	// patient to another and returns the IDs moved. A nil ids moves all of
+	// fromID's records, otherwise only those listed.
+	ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error)
+}
+
+type PatientMergeRepository interface {
+	Create(ctx context.Context, merge *domain.PatientMerge) error
+	GetByID(ctx context.Context, id uint) (*domain.PatientMerge, error)
+	// List returns merges involving the patient, or all merges when
+	// patientID is zero, newest first.
+	List(ctx context.Context, patientID uint) ([]domain.PatientMerge, error)
+	Update(ctx context.Context, merge *domain.PatientMerge) error
+}
+
+type patientMergeRepository struct {
+	db *gorm.DB
+}
+
+func NewPatientMergeRepository(db *gorm.DB) PatientMergeRepository {
+	return &patientMergeRepository{db: db}
+}
+
+func (r *patientMergeRepository) Create(ctx context.Context, merge *domain.PatientMerge) error {
+	return conn(ctx, r.db).Create(merge).Error
+}
+
+func (r *patientMergeRepository) GetByID(ctx context.Context, id uint) (*domain.PatientMerge, error) {
+	var merge domain.PatientMerge
+	if err := conn(ctx, r.db).First(&merge, id).Error; err != nil {
+		return nil, notFound(err, "patient_merge", id)
+	}
+	return &merge, nil
+}
+
+func (r *patientMergeRepository) List(ctx context.Context, patientID uint) ([]domain.PatientMerge, error) {
+	var merges []domain.PatientMerge
+	query := conn(ctx, r.db).Order("id DESC")
+	if patientID != 0 {
+		query = query.Where("survivor_id = ? OR duplicate_id = ?", patientID, patientID)
+	}
+	err := query.Find(&merges).Error
+	return merges, err
+}
+
+func (r *patientMergeRepository) Update(ctx context.Context, merge *domain.PatientMerge) error {
+	return conn(ctx, r.db).Save(merge).Error
+}
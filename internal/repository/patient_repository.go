@@ -4,60 +4,546 @@ package repository
 import (
 	"context"
 	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"doctors/pkg/fuzzy"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// Lookups and listings only see the tenant the context acts for, if any;
+// see WithTenant.
 type PatientRepository interface {
 	Create(ctx context.Context, patient *domain.Patient) error
 	GetByID(ctx context.Context, id uint) (*domain.Patient, error)
+	GetByEmail(ctx context.Context, email string) ([]domain.Patient, error)
+	// GetByIDs returns the listed patients that exist, archived ones included.
+	GetByIDs(ctx context.Context, ids []uint) ([]domain.Patient, error)
 	Update(ctx context.Context, patient *domain.Patient) error
+	// Delete archives the patient; Restore brings it back.
 	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context, page, pageSize int) ([]domain.Patient, int64, error)
+	Restore(ctx context.Context, id uint) (*domain.Patient, error)
+	List(ctx context.Context, page, pageSize int, includeArchived bool) ([]domain.Patient, int64, error)
+	// Search returns up to limit candidates for a search, roughly best first.
+	// Callers rank the decrypted results; token matches may include false
+	// positives.
+	Search(ctx context.Context, search domain.PatientSearch, limit int) ([]domain.Patient, error)
+	// DuplicateCandidates returns up to limit pairs of active patients, lower
+	// ID first, that share an email, a phone number, or a date of birth and
+	// some name trigrams. Callers score the decrypted pairs.
+	DuplicateCandidates(ctx context.Context, limit int) ([][2]uint, error)
+	// Merge archives a duplicate and records the patient it was merged
+	// into; Unmerge brings it back.
+	Merge(ctx context.Context, duplicateID, survivorID uint) error
+	Unmerge(ctx context.Context, id uint) error
+	// Purge permanently deletes patients archived before the cutoff,
+	// together with all of their appointments. Patients with clinical or
+	// financial records are kept.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// RotateKeys re-encrypts, in batches, every row whose data key is not
+	// wrapped by the active master key (including legacy plaintext rows).
+	RotateKeys(ctx context.Context, batchSize int) (int, error)
+	// ReindexSearch rebuilds the search tokens of every patient in batches.
+	ReindexSearch(ctx context.Context, batchSize int) (int, error)
+	// NextMRNSequence returns the next medical record number counter of a
+	// scope, starting at 1.
+	NextMRNSequence(ctx context.Context, scope string) (int64, error)
+	// IDsWithoutMRN returns up to limit patients, archived included, that
+	// have no medical record number yet.
+	IDsWithoutMRN(ctx context.Context, limit int) ([]uint, error)
+	// TenantOf returns the tenant of a patient, archived or not.
+	TenantOf(ctx context.Context, id uint) (uint, error)
+	SetMRN(ctx context.Context, id uint, mrn string) error
+	// CountChanged counts active patients last updated in (since, until];
+	// a zero since counts all of them up to until.
+	CountChanged(ctx context.Context, since, until time.Time) (int64, error)
+	// ListChanged returns up to limit of the patients CountChanged counts,
+	// by ID, after afterID.
+	ListChanged(ctx context.Context, since, until time.Time, afterID uint, limit int) ([]domain.Patient, error)
 }
 
+// patientSearchToken links a patient to a keyed hash of one name or phone trigram.
+type patientSearchToken struct {
+	Token     string `gorm:"primaryKey"`
+	PatientID uint   `gorm:"primaryKey"`
+}
+
+func (patientSearchToken) TableName() string {
+	return "patient_search_tokens"
+}
+
+// minNameMatch is the share of the query's name trigrams a candidate must have.
+const minNameMatch = 0.4
+
 type patientRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cipher *encryption.Envelope
 }
 
-func NewPatientRepository(db *gorm.DB) PatientRepository {
-	return &patientRepository{db: db}
+func NewPatientRepository(db *gorm.DB, cipher *encryption.Envelope) PatientRepository {
+	return &patientRepository{db: db, cipher: cipher}
 }
 
 func (r *patientRepository) Create(ctx context.Context, patient *domain.Patient) error {
-	return r.db.WithContext(ctx).Create(patient).Error
+	patient.Version = 1
+	tokens := r.searchTokens(patient)
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := r.withSealed(patient, func() error { return tx.Create(patient).Error }); err != nil {
+			return mrnTaken(err)
+		}
+		return replaceSearchTokens(tx, patient.ID, tokens)
+	})
 }
 
 func (r *patientRepository) GetByID(ctx context.Context, id uint) (*domain.Patient, error) {
 	var patient domain.Patient
-	err := r.db.WithContext(ctx).First(&patient, id).Error
-	return &patient, err
+	if err := r.scoped(ctx).First(&patient, id).Error; err != nil {
+		return nil, notFound(err, "patient", id)
+	}
+	return &patient, r.open(&patient)
+}
+
+func (r *patientRepository) GetByEmail(ctx context.Context, email string) ([]domain.Patient, error) {
+	var patients []domain.Patient
+	err := r.scoped(ctx).Where("email_index = ?", r.cipher.BlindIndex(normalizeEmail(email))).
+		Find(&patients).Error
+	if err != nil {
+		return nil, err
+	}
+	return patients, r.openAll(patients)
+}
+
+func (r *patientRepository) GetByIDs(ctx context.Context, ids []uint) ([]domain.Patient, error) {
+	var patients []domain.Patient
+	if err := r.scoped(ctx).Unscoped().Where("id IN ?", ids).Order("id").Find(&patients).Error; err != nil {
+		return nil, err
+	}
+	return patients, r.openAll(patients)
 }
 
 func (r *patientRepository) Update(ctx context.Context, patient *domain.Patient) error {
-	return r.db.WithContext(ctx).Save(patient).Error
+	tokens := r.searchTokens(patient)
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := r.withSealed(patient, func() error {
+			return updateVersioned(tx, patient, &patient.Version, "patient")
+		})
+		if err != nil {
+			return err
+		}
+		return replaceSearchTokens(tx, patient.ID, tokens)
+	})
 }
 
 func (r *patientRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Patient{}, id).Error
+	result := r.scoped(ctx).Delete(&domain.Patient{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("patient", id)
+	}
+	return nil
 }
 
-func (r *patientRepository) List(ctx context.Context, page, pageSize int) ([]domain.Patient, int64, error) {
+func (r *patientRepository) Restore(ctx context.Context, id uint) (*domain.Patient, error) {
+	if err := restore(r.scoped(ctx), &domain.Patient{}, id, "patient"); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *patientRepository) List(ctx context.Context, page, pageSize int, includeArchived bool) ([]domain.Patient, int64, error) {
 	var patients []domain.Patient
 	var totalCount int64
 
 	offset := (page - 1) * pageSize
 
 	// Count total number of patients
-	if err := r.db.WithContext(ctx).Model(&domain.Patient{}).Count(&totalCount).Error; err != nil {
+	if err := withArchived(r.scoped(ctx), includeArchived).Model(&domain.Patient{}).Count(&totalCount).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Retrieve patients with pagination
-	err := r.db.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&patients).Error
+	err := withArchived(r.scoped(ctx), includeArchived).Order("id").Offset(offset).Limit(pageSize).Find(&patients).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return patients, totalCount, nil
+	return patients, totalCount, r.openAll(patients)
+}
+
+func (r *patientRepository) Search(ctx context.Context, search domain.PatientSearch, limit int) ([]domain.Patient, error) {
+	query := withArchived(r.scoped(ctx), search.IncludeArchived).Model(&domain.Patient{})
+
+	if search.Email != "" {
+		query = query.Where("email_index = ?", r.cipher.BlindIndex(normalizeEmail(search.Email)))
+	}
+	if search.Phone != "" {
+		query = query.Where("phone_index = ?", r.cipher.BlindIndex(normalizePhone(search.Phone)))
+	}
+	if search.DateOfBirth != "" {
+		query = query.Where("dob_index = ?", r.cipher.BlindIndex(search.DateOfBirth))
+	}
+	if search.MRN != "" {
+		query = query.Where("mrn = ?", search.MRN)
+	}
+
+	order := "patients.id"
+	if grams := fuzzy.DigitTrigrams(search.PhoneFragment); len(grams) > 0 {
+		tokens := r.tokens("p:", grams)
+		matches := conn(ctx, r.db).Model(&patientSearchToken{}).Select("patient_id").
+			Where("token IN ?", tokens).Group("patient_id").Having("COUNT(*) = ?", len(tokens))
+		query = query.Where("patients.id IN (?)", matches)
+	}
+	if grams := fuzzy.PrefixTrigrams(search.Name); len(grams) > 0 {
+		tokens := r.tokens("n:", grams)
+		minHits := int(float64(len(tokens))*minNameMatch + 0.5)
+		if minHits < 1 {
+			minHits = 1
+		}
+		matches := conn(ctx, r.db).Model(&patientSearchToken{}).Select("patient_id, COUNT(*) AS hits").
+			Where("token IN ?", tokens).Group("patient_id").Having("COUNT(*) >= ?", minHits)
+		query = query.Joins("JOIN (?) AS matches ON matches.patient_id = patients.id", matches)
+		order = "matches.hits DESC, patients.id"
+	}
+
+	var patients []domain.Patient
+	if err := query.Order(order).Limit(limit).Find(&patients).Error; err != nil {
+		return nil, err
+	}
+	return patients, r.openAll(patients)
+}
+
+func (r *patientRepository) DuplicateCandidates(ctx context.Context, limit int) ([][2]uint, error) {
+	// Each branch is an equi-join on an indexed blind index. Patients born
+	// on the same day are common, so those pairs must also share at least
+	// three name or phone trigrams. Patients of different tenants are
+	// never duplicates; a tenant of 0 means every tenant.
+	const query = `
+SELECT a.id AS patient_id, b.id AS duplicate_id
+  FROM patients a JOIN patients b ON b.email_index = a.email_index AND b.id > a.id AND b.tenant_id = a.tenant_id
+ WHERE a.email_index <> '' AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+   AND (@tenant = 0 OR a.tenant_id = @tenant)
+UNION
+SELECT a.id, b.id
+  FROM patients a JOIN patients b ON b.phone_index = a.phone_index AND b.id > a.id AND b.tenant_id = a.tenant_id
+ WHERE a.phone_index <> '' AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+   AND (@tenant = 0 OR a.tenant_id = @tenant)
+UNION
+SELECT a.id, b.id
+  FROM patients a JOIN patients b ON b.dob_index = a.dob_index AND b.id > a.id AND b.tenant_id = a.tenant_id
+ WHERE a.dob_index <> '' AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+   AND (@tenant = 0 OR a.tenant_id = @tenant)
+   AND (SELECT COUNT(*) FROM patient_search_tokens ta
+          JOIN patient_search_tokens tb ON tb.token = ta.token AND tb.patient_id = b.id
+         WHERE ta.patient_id = a.id) >= 3
+ORDER BY 1, 2
+LIMIT @limit`
+	tenant, _ := ContextTenant(ctx)
+
+	var rows []struct {
+		PatientID   uint
+		DuplicateID uint
+	}
+	args := map[string]interface{}{"tenant": tenant, "limit": limit}
+	if err := conn(ctx, r.db).Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	pairs := make([][2]uint, len(rows))
+	for i, row := range rows {
+		pairs[i] = [2]uint{row.PatientID, row.DuplicateID}
+	}
+	return pairs, nil
+}
+
+func (r *patientRepository) Merge(ctx context.Context, duplicateID, survivorID uint) error {
+	result := conn(ctx, r.db).Model(&domain.Patient{}).Where("id = ?", duplicateID).
+		Updates(map[string]interface{}{
+			"merged_into_id": survivorID,
+			"deleted_at":     gorm.Expr("NOW()"),
+			"version":        gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("patient", duplicateID)
+	}
+	return nil
+}
+
+func (r *patientRepository) Unmerge(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Unscoped().Model(&domain.Patient{}).
+		Where("id = ? AND merged_into_id IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"merged_into_id": nil,
+			"deleted_at":     nil,
+			"version":        gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		notMerged := domain.NewNotFoundError("patient", id)
+		notMerged.Message = fmt.Sprintf("no merged patient with id %d", id)
+		return notMerged
+	}
+	return nil
+}
+
+func (r *patientRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		expired := func() *gorm.DB {
+			query := tx.Unscoped().Model(&domain.Patient{}).Select("id").Where("deleted_at < ?", before)
+			query = withoutRecords(query, "patients", "patient_id", patientRecordTables)
+			// Coded diagnoses and procedures reference the appointment only.
+			for _, t := range []string{"appointment_diagnoses", "appointment_procedures"} {
+				query = query.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM appointments JOIN %s ON %s.appointment_id = appointments.id"+
+					" WHERE appointments.patient_id = patients.id)", t, t))
+			}
+			return query
+		}
+		if err := tx.Unscoped().Where("patient_id IN (?)", expired()).Delete(&domain.Appointment{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN (?)", expired()).Delete(&domain.Patient{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+func (r *patientRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	return rotateSealedRows(ctx, r.db, r.cipher, batchSize, patientSealedColumns,
+		func(p *domain.Patient) uint { return p.ID }, r.row)
+}
+
+func (r *patientRepository) ReindexSearch(ctx context.Context, batchSize int) (int, error) {
+	reindexed := 0
+	var lastID uint
+
+	for {
+		var batch []domain.Patient
+		err := conn(ctx, r.db).Unscoped().Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&batch).Error
+		if err != nil {
+			return reindexed, err
+		}
+		if len(batch) == 0 {
+			return reindexed, nil
+		}
+
+		err = conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+			for i := range batch {
+				patient := &batch[i]
+				if err := r.open(patient); err != nil {
+					return err
+				}
+				if err := replaceSearchTokens(tx, patient.ID, r.searchTokens(patient)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return reindexed, err
+		}
+
+		reindexed += len(batch)
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+func (r *patientRepository) NextMRNSequence(ctx context.Context, scope string) (int64, error) {
+	var value int64
+	err := conn(ctx, r.db).Raw(`
+INSERT INTO mrn_sequences (scope, next_value) VALUES (?, 2)
+ON CONFLICT (scope) DO UPDATE SET next_value = mrn_sequences.next_value + 1
+RETURNING next_value - 1`, scope).Scan(&value).Error
+	return value, err
+}
+
+func (r *patientRepository) IDsWithoutMRN(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Patient{}).
+		Where("mrn IS NULL OR mrn = ''").Order("id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *patientRepository) TenantOf(ctx context.Context, id uint) (uint, error) {
+	var tenantIDs []uint
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Patient{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(tenantIDs) == 0 {
+		return 0, domain.NewNotFoundError("patient", id)
+	}
+	return tenantIDs[0], nil
+}
+
+func (r *patientRepository) SetMRN(ctx context.Context, id uint, mrn string) error {
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Patient{}).Where("id = ?", id).
+		UpdateColumn("mrn", mrn).Error
+	return mrnTaken(err)
+}
+
+func (r *patientRepository) CountChanged(ctx context.Context, since, until time.Time) (int64, error) {
+	var count int64
+	err := changedBetween(r.scoped(ctx), since, until).Model(&domain.Patient{}).Count(&count).Error
+	return count, err
+}
+
+func (r *patientRepository) ListChanged(ctx context.Context, since, until time.Time, afterID uint, limit int) ([]domain.Patient, error) {
+	var patients []domain.Patient
+	err := changedBetween(r.scoped(ctx), since, until).Where("id > ?", afterID).
+		Order("id").Limit(limit).Find(&patients).Error
+	if err != nil {
+		return nil, err
+	}
+	return patients, r.openAll(patients)
+}
+
+// scoped returns the connection for ctx limited to the patients of the
+// tenant ctx acts for.
+func (r *patientRepository) scoped(ctx context.Context) *gorm.DB {
+	return forTenant(ctx, conn(ctx, r.db), "patients")
+}
+
+func mrnTaken(err error) error {
+	if isUniqueViolation(err, "idx_patients_mrn") {
+		return domain.NewConflictError("patient_mrn_taken", "another patient already has this medical record number")
+	}
+	return err
+}
+
+// searchTokens derives the search tokens of a patient from its plaintext.
+// Preferred names are indexed too, so "Bob" finds a patient named Robert.
+func (r *patientRepository) searchTokens(patient *domain.Patient) []string {
+	tokens := r.tokens("n:", fuzzy.Trigrams(patient.Name+" "+patient.PreferredName))
+	return append(tokens, r.tokens("p:", fuzzy.DigitTrigrams(patient.Phone))...)
+}
+
+// tokens hashes trigrams under a namespace so name and phone trigrams never collide.
+func (r *patientRepository) tokens(namespace string, grams []string) []string {
+	tokens := make([]string, len(grams))
+	for i, gram := range grams {
+		tokens[i] = r.cipher.SearchToken(namespace + gram)
+	}
+	return tokens
+}
+
+func replaceSearchTokens(tx *gorm.DB, patientID uint, tokens []string) error {
+	if err := tx.Where("patient_id = ?", patientID).Delete(&patientSearchToken{}).Error; err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	rows := make([]patientSearchToken, 0, len(tokens))
+	seen := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			rows = append(rows, patientSearchToken{Token: token, PatientID: patientID})
+		}
+	}
+	return tx.Create(&rows).Error
+}
+
+// withSealed encrypts the patient in place for the duration of fn and
+// restores the plaintext fields afterwards, so callers never see ciphertext.
+func (r *patientRepository) withSealed(patient *domain.Patient, fn func() error) error {
+	patient.EmergencyContactsSealed = ""
+	if len(patient.EmergencyContacts) > 0 {
+		contacts, err := json.Marshal(patient.EmergencyContacts)
+		if err != nil {
+			return err
+		}
+		patient.EmergencyContactsSealed = string(contacts)
+	}
+	return withSealedRow(r.cipher, r.row(patient), fn)
+}
+
+// row describes the encrypted fields of a patient. Sealing it refreshes
+// the blind indexes used for exact lookups.
+func (r *patientRepository) row(patient *domain.Patient) sealedRow {
+	return sealedRow{
+		DataKey: &patient.DataKey,
+		KeyID:   &patient.KeyID,
+		Fields: []*string{
+			&patient.Name, &patient.Email, &patient.Phone, &patient.DateOfBirth,
+			&patient.NationalID, &patient.PreferredName, &patient.GenderIdentity,
+			&patient.Address.Line1, &patient.Address.Line2, &patient.Address.City, &patient.Address.PostalCode,
+			&patient.EmergencyContactsSealed,
+		},
+		Derive: func() {
+			patient.EmailIndex = r.cipher.BlindIndex(normalizeEmail(patient.Email))
+			patient.PhoneIndex = r.cipher.BlindIndex(normalizePhone(patient.Phone))
+			patient.DOBIndex = r.cipher.BlindIndex(patient.DateOfBirth)
+			patient.NationalIDIndex = r.cipher.BlindIndex(normalizeNationalID(patient.NationalID))
+		},
+	}
+}
+
+// patientSealedColumns are the columns rewritten when a patient is
+// re-encrypted: the sealed fields and the blind indexes.
+var patientSealedColumns = []string{
+	"name", "email", "phone", "date_of_birth",
+	"national_id", "preferred_name", "gender_identity",
+	"address_line1", "address_line2", "address_city", "address_postal_code",
+	"emergency_contacts",
+	"email_index", "phone_index", "dob_index", "national_id_index",
+}
+
+func (r *patientRepository) open(patient *domain.Patient) error {
+	if err := openRow(r.cipher, r.row(patient)); err != nil {
+		return fmt.Errorf("patient %d: %w", patient.ID, err)
+	}
+
+	patient.EmergencyContacts = []domain.EmergencyContact{}
+	if patient.EmergencyContactsSealed != "" {
+		if err := json.Unmarshal([]byte(patient.EmergencyContactsSealed), &patient.EmergencyContacts); err != nil {
+			return fmt.Errorf("failed to read emergency contacts of patient %d: %w", patient.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *patientRepository) openAll(patients []domain.Patient) error {
+	for i := range patients {
+		if err := r.open(&patients[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizeNationalID drops spacing and punctuation so "123-45-6789" and
+// "123 45 6789" index the same.
+func normalizeNationalID(id string) string {
+	var b strings.Builder
+	for _, ch := range strings.ToUpper(id) {
+		if (ch >= '0' && ch <= '9') || (ch >= 'A' && ch <= 'Z') {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for i, ch := range strings.TrimSpace(phone) {
+		if (ch >= '0' && ch <= '9') || (ch == '+' && i == 0) {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
 }
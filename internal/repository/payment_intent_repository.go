@@ -0,0 +1,164 @@
+// internal/repository/payment_intent_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentIntentRepository interface {
+	Create(ctx context.Context, intent *domain.PaymentIntent) error
+	GetByID(ctx context.Context, id uint) (*domain.PaymentIntent, error)
+	// GetForUpdate is GetByID, locking the intent until the transaction ends.
+	GetForUpdate(ctx context.Context, id uint) (*domain.PaymentIntent, error)
+	// FindByProviderIntent returns the intent with the provider's ID,
+	// locked until the transaction ends, or nil when there is none.
+	FindByProviderIntent(ctx context.Context, provider, providerIntentID string) (*domain.PaymentIntent, error)
+	// FindByIdempotencyKey returns the intent a request created, or nil.
+	FindByIdempotencyKey(ctx context.Context, key string) (*domain.PaymentIntent, error)
+	// List returns a page of matching intents, newest first, and the number of matches.
+	List(ctx context.Context, search domain.PaymentIntentSearch) ([]domain.PaymentIntent, int64, error)
+	// ListPending returns up to limit intents of a provider, created in
+	// [from, to), that have not succeeded or been canceled, oldest first.
+	ListPending(ctx context.Context, provider string, from, to time.Time, limit int) ([]domain.PaymentIntent, error)
+	// ListUnappliedDeposits returns the succeeded deposits of an appointment
+	// not yet posted to an invoice, oldest first.
+	ListUnappliedDeposits(ctx context.Context, appointmentID uint) ([]domain.PaymentIntent, error)
+	Update(ctx context.Context, intent *domain.PaymentIntent) error
+	// RecordEvent remembers a webhook event. It returns false when the
+	// event was recorded before.
+	RecordEvent(ctx context.Context, provider, eventID, eventType string) (bool, error)
+	PatientRecords
+}
+
+type paymentIntentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentIntentRepository(db *gorm.DB) PaymentIntentRepository {
+	return &paymentIntentRepository{db: db}
+}
+
+func (r *paymentIntentRepository) Create(ctx context.Context, intent *domain.PaymentIntent) error {
+	err := conn(ctx, r.db).Create(intent).Error
+	// Both indexes catch a request sent twice at once.
+	if isUniqueViolation(err, "idx_payment_intents_idempotency_key") || isUniqueViolation(err, "idx_payment_intents_provider_intent") {
+		return domain.NewConflictError("idempotency_key_in_use", "a request with this Idempotency-Key is in progress; retry it")
+	}
+	return err
+}
+
+func (r *paymentIntentRepository) GetByID(ctx context.Context, id uint) (*domain.PaymentIntent, error) {
+	var intent domain.PaymentIntent
+	if err := r.scoped(ctx).First(&intent, id).Error; err != nil {
+		return nil, notFound(err, "payment_intent", id)
+	}
+	return &intent, nil
+}
+
+func (r *paymentIntentRepository) GetForUpdate(ctx context.Context, id uint) (*domain.PaymentIntent, error) {
+	var intent domain.PaymentIntent
+	if err := r.scoped(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&intent, id).Error; err != nil {
+		return nil, notFound(err, "payment_intent", id)
+	}
+	return &intent, nil
+}
+
+func (r *paymentIntentRepository) FindByProviderIntent(ctx context.Context, provider, providerIntentID string) (*domain.PaymentIntent, error) {
+	var intents []domain.PaymentIntent
+	err := conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND provider_intent_id = ?", provider, providerIntentID).Limit(1).Find(&intents).Error
+	if err != nil || len(intents) == 0 {
+		return nil, err
+	}
+	return &intents[0], nil
+}
+
+func (r *paymentIntentRepository) FindByIdempotencyKey(ctx context.Context, key string) (*domain.PaymentIntent, error) {
+	var intents []domain.PaymentIntent
+	err := conn(ctx, r.db).Where("idempotency_key = ?", key).Limit(1).Find(&intents).Error
+	if err != nil || len(intents) == 0 {
+		return nil, err
+	}
+	return &intents[0], nil
+}
+
+func (r *paymentIntentRepository) List(ctx context.Context, search domain.PaymentIntentSearch) ([]domain.PaymentIntent, int64, error) {
+	query := r.scoped(ctx).Model(&domain.PaymentIntent{})
+	if search.PatientID != nil {
+		query = query.Where("patient_id = ?", *search.PatientID)
+	}
+	if search.InvoiceID != nil {
+		query = query.Where("invoice_id = ?", *search.InvoiceID)
+	}
+	if search.AppointmentID != nil {
+		query = query.Where("appointment_id = ?", *search.AppointmentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	intents := []domain.PaymentIntent{}
+	err := query.Order("created_at DESC").Order("id DESC").Limit(search.Limit).Offset(search.Offset).Find(&intents).Error
+	return intents, total, err
+}
+
+func (r *paymentIntentRepository) ListPending(ctx context.Context, provider string, from, to time.Time, limit int) ([]domain.PaymentIntent, error) {
+	intents := []domain.PaymentIntent{}
+	err := conn(ctx, r.db).
+		Where("provider = ? AND status NOT IN ?", provider, []string{domain.PaymentIntentSucceeded, domain.PaymentIntentCanceled}).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Order("created_at").Limit(limit).Find(&intents).Error
+	return intents, err
+}
+
+func (r *paymentIntentRepository) ListUnappliedDeposits(ctx context.Context, appointmentID uint) ([]domain.PaymentIntent, error) {
+	intents := []domain.PaymentIntent{}
+	err := r.scoped(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("appointment_id = ? AND purpose = ? AND status = ? AND payment_id IS NULL",
+			appointmentID, domain.IntentPurposeDeposit, domain.PaymentIntentSucceeded).
+		Order("id").Find(&intents).Error
+	return intents, err
+}
+
+func (r *paymentIntentRepository) Update(ctx context.Context, intent *domain.PaymentIntent) error {
+	return conn(ctx, r.db).Save(intent).Error
+}
+
+func (r *paymentIntentRepository) RecordEvent(ctx context.Context, provider, eventID, eventType string) (bool, error) {
+	result := conn(ctx, r.db).Exec(`
+INSERT INTO payment_webhook_events (provider, event_id, type) VALUES (?, ?, ?)
+ON CONFLICT (provider, event_id) DO NOTHING`, provider, eventID, eventType)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *paymentIntentRepository) RecordType() string {
+	return "payment_intents"
+}
+
+func (r *paymentIntentRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.PaymentIntent{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+	err := conn(ctx, r.db).Model(&domain.PaymentIntent{}).Where("id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *paymentIntentRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "payment_intents")
+}
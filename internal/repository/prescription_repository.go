@@ -0,0 +1,86 @@
+// internal/repository/prescription_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type PrescriptionRepository interface {
+	Create(ctx context.Context, prescription *domain.Prescription) error
+	GetByID(ctx context.Context, id uint) (*domain.Prescription, error)
+	// ListByPatient returns the patient's prescriptions, newest first.
+	ListByPatient(ctx context.Context, patientID uint) ([]domain.Prescription, error)
+	// ListByEncounter returns the prescriptions of an encounter in the
+	// order they were written.
+	ListByEncounter(ctx context.Context, encounterID uint) ([]domain.Prescription, error)
+	Update(ctx context.Context, prescription *domain.Prescription) error
+	PatientRecords
+}
+
+type prescriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewPrescriptionRepository(db *gorm.DB) PrescriptionRepository {
+	return &prescriptionRepository{db: db}
+}
+
+func (r *prescriptionRepository) Create(ctx context.Context, prescription *domain.Prescription) error {
+	return conn(ctx, r.db).Create(prescription).Error
+}
+
+func (r *prescriptionRepository) GetByID(ctx context.Context, id uint) (*domain.Prescription, error) {
+	var prescription domain.Prescription
+	if err := r.scoped(ctx).First(&prescription, id).Error; err != nil {
+		return nil, notFound(err, "prescription", id)
+	}
+	return &prescription, nil
+}
+
+func (r *prescriptionRepository) ListByPatient(ctx context.Context, patientID uint) ([]domain.Prescription, error) {
+	var prescriptions []domain.Prescription
+	err := r.scoped(ctx).Where("patient_id = ?", patientID).
+		Order("created_at DESC").Order("id DESC").Find(&prescriptions).Error
+	return prescriptions, err
+}
+
+func (r *prescriptionRepository) ListByEncounter(ctx context.Context, encounterID uint) ([]domain.Prescription, error) {
+	var prescriptions []domain.Prescription
+	err := r.scoped(ctx).Where("encounter_id = ?", encounterID).Order("id").Find(&prescriptions).Error
+	return prescriptions, err
+}
+
+func (r *prescriptionRepository) Update(ctx context.Context, prescription *domain.Prescription) error {
+	return conn(ctx, r.db).Save(prescription).Error
+}
+
+func (r *prescriptionRepository) RecordType() string {
+	return "prescriptions"
+}
+
+func (r *prescriptionRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.Prescription{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	// UpdateColumn keeps updated_at: moving a prescription is not an edit of it.
+	err := conn(ctx, r.db).Model(&domain.Prescription{}).Where("id IN ?", movedIDs).
+		UpdateColumn("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *prescriptionRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "prescriptions")
+}
@@ -0,0 +1,93 @@
+// internal/repository/problem_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type ProblemRepository interface {
+	Create(ctx context.Context, problem *domain.Problem) error
+	GetByID(ctx context.Context, id uint) (*domain.Problem, error)
+	// ListByPatient returns the patient's problems, active ones first and
+	// then by onset, most recent first; only those with status when it is set.
+	ListByPatient(ctx context.Context, patientID uint, status string) ([]domain.Problem, error)
+	Update(ctx context.Context, problem *domain.Problem) error
+	// Delete marks a problem as entered in error.
+	Delete(ctx context.Context, id uint) error
+	PatientRecords
+}
+
+type problemRepository struct {
+	db *gorm.DB
+}
+
+func NewProblemRepository(db *gorm.DB) ProblemRepository {
+	return &problemRepository{db: db}
+}
+
+func (r *problemRepository) Create(ctx context.Context, problem *domain.Problem) error {
+	return conn(ctx, r.db).Create(problem).Error
+}
+
+func (r *problemRepository) GetByID(ctx context.Context, id uint) (*domain.Problem, error) {
+	var problem domain.Problem
+	if err := r.scoped(ctx).First(&problem, id).Error; err != nil {
+		return nil, notFound(err, "problem", id)
+	}
+	return &problem, nil
+}
+
+func (r *problemRepository) ListByPatient(ctx context.Context, patientID uint, status string) ([]domain.Problem, error) {
+	query := r.scoped(ctx).Where("patient_id = ?", patientID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var problems []domain.Problem
+	err := query.Order("status").Order("onset_date DESC").Order("id DESC").Find(&problems).Error
+	return problems, err
+}
+
+func (r *problemRepository) Update(ctx context.Context, problem *domain.Problem) error {
+	return conn(ctx, r.db).Save(problem).Error
+}
+
+func (r *problemRepository) Delete(ctx context.Context, id uint) error {
+	result := r.scoped(ctx).Delete(&domain.Problem{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("problem", id)
+	}
+	return nil
+}
+
+func (r *problemRepository) RecordType() string {
+	return "problems"
+}
+
+func (r *problemRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Unscoped().Model(&domain.Problem{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Unscoped().Model(&domain.Problem{}).Where("id IN ?", movedIDs).
+		Update("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *problemRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "patient_problems")
+}
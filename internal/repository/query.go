@@ -0,0 +1,108 @@
+// internal/repository/query.go
+package repository
+
+import (
+	"doctors/internal/domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// notFound converts gorm's missing-record error into a domain error.
+func notFound(err error, resource string, id interface{}) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.NewNotFoundError(resource, id)
+	}
+	return err
+}
+
+// updateVersioned saves every column of model if its stored version still
+// equals model's Version, then bumps the version. It reports a conflict
+// when another writer got there first.
+func updateVersioned(db *gorm.DB, model interface{}, version *uint, resource string) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(model).Where("version = ?", expected).
+		Select("*").Omit("id", "created_at").Updates(model)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return domain.NewConflictError(resource+"_version_conflict", resource+" was modified concurrently")
+	}
+	return nil
+}
+
+// withArchived includes soft-deleted rows in a query when asked to.
+func withArchived(db *gorm.DB, includeArchived bool) *gorm.DB {
+	if includeArchived {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// restore clears deleted_at on an archived row.
+func restore(db *gorm.DB, model interface{}, id uint, resource string) error {
+	result := db.Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		notArchived := domain.NewNotFoundError(resource, id)
+		notArchived.Message = fmt.Sprintf("no archived %s with id %d", resource, id)
+		return notArchived
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique violation on the named index.
+func isUniqueViolation(err error, index string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == index
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key violation on the named constraint.
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == constraint
+}
+
+// changedBetween keeps rows last updated in (since, until]; a zero since
+// keeps everything updated up to until.
+func changedBetween(db *gorm.DB, since, until time.Time) *gorm.DB {
+	if !since.IsZero() {
+		db = db.Where("updated_at > ?", since)
+	}
+	return db.Where("updated_at <= ?", until)
+}
+
+// Clinical and financial records are kept for as long as they must be,
+// whatever happens to the patient or appointment they belong to. Their
+// foreign keys restrict deletes, so purges skip rows that still have any.
+var (
+	patientRecordTables = []string{
+		"encounters", "vital_signs", "patient_allergies", "patient_medications", "patient_problems",
+		"prescriptions", "lab_orders", "lab_results", "invoices", "invoice_payments", "payment_intents", "claims",
+	}
+	appointmentRecordTables = []string{
+		"encounters", "appointment_diagnoses", "appointment_procedures", "lab_orders",
+		"vital_signs", "invoices", "payment_intents", "claims",
+	}
+)
+
+// withoutRecords narrows a query of table to rows that no row of tables
+// references through column.
+func withoutRecords(query *gorm.DB, table, column string, tables []string) *gorm.DB {
+	for _, t := range tables {
+		query = query.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.id)", t, t, column, table))
+	}
+	return query
+}
@@ -0,0 +1,132 @@
+// internal/repository/relationship_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type RelationshipRepository interface {
+	Create(ctx context.Context, rel *domain.PatientRelationship) error
+	GetByID(ctx context.Context, id uint) (*domain.PatientRelationship, error)
+	// Between returns the relationship linking the two patients in either
+	// direction, if any.
+	Between(ctx context.Context, a, b uint) (*domain.PatientRelationship, error)
+	// ListByPatient returns relationships where the patient is either side.
+	ListByPatient(ctx context.Context, patientID uint) ([]domain.PatientRelationship, error)
+	// ListResponsible returns the relationships making others responsible for the patient.
+	ListResponsible(ctx context.Context, patientID uint) ([]domain.PatientRelationship, error)
+	// ListDependents returns the relationships making the patient responsible for others.
+	ListDependents(ctx context.Context, patientID uint) ([]domain.PatientRelationship, error)
+	Update(ctx context.Context, rel *domain.PatientRelationship) error
+	Delete(ctx context.Context, id uint) error
+	// PatientRecords replaces fromID by toID on either side of relationships.
+	PatientRecords
+}
+
+type relationshipRepository struct {
+	db *gorm.DB
+}
+
+func NewRelationshipRepository(db *gorm.DB) RelationshipRepository {
+	return &relationshipRepository{db: db}
+}
+
+func (r *relationshipRepository) Create(ctx context.Context, rel *domain.PatientRelationship) error {
+	err := conn(ctx, r.db).Create(rel).Error
+	if isUniqueViolation(err, "idx_patient_relationships_pair") {
+		return domain.NewConflictError("relationship_exists", "these patients are already related")
+	}
+	return err
+}
+
+func (r *relationshipRepository) GetByID(ctx context.Context, id uint) (*domain.PatientRelationship, error) {
+	var rel domain.PatientRelationship
+	if err := r.scoped(ctx).First(&rel, id).Error; err != nil {
+		return nil, notFound(err, "relationship", id)
+	}
+	return &rel, nil
+}
+
+func (r *relationshipRepository) Between(ctx context.Context, a, b uint) (*domain.PatientRelationship, error) {
+	var rel domain.PatientRelationship
+	err := r.scoped(ctx).
+		Where("(patient_id = ? AND related_patient_id = ?) OR (patient_id = ? AND related_patient_id = ?)", a, b, b, a).
+		First(&rel).Error
+	if err != nil {
+		return nil, notFound(err, "relationship", fmt.Sprintf("between patients %d and %d", a, b))
+	}
+	return &rel, nil
+}
+
+func (r *relationshipRepository) ListByPatient(ctx context.Context, patientID uint) ([]domain.PatientRelationship, error) {
+	var rels []domain.PatientRelationship
+	err := r.scoped(ctx).Where("patient_id = ? OR related_patient_id = ?", patientID, patientID).
+		Order("id").Find(&rels).Error
+	return rels, err
+}
+
+func (r *relationshipRepository) ListResponsible(ctx context.Context, patientID uint) ([]domain.PatientRelationship, error) {
+	var rels []domain.PatientRelationship
+	err := r.scoped(ctx).Where("patient_id = ?", patientID).Order("id").Find(&rels).Error
+	return rels, err
+}
+
+func (r *relationshipRepository) ListDependents(ctx context.Context, patientID uint) ([]domain.PatientRelationship, error) {
+	var rels []domain.PatientRelationship
+	err := r.scoped(ctx).Where("related_patient_id = ?", patientID).Order("id").Find(&rels).Error
+	return rels, err
+}
+
+func (r *relationshipRepository) Update(ctx context.Context, rel *domain.PatientRelationship) error {
+	return conn(ctx, r.db).Save(rel).Error
+}
+
+func (r *relationshipRepository) Delete(ctx context.Context, id uint) error {
+	result := r.scoped(ctx).Delete(&domain.PatientRelationship{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("relationship", id)
+	}
+	return nil
+}
+
+func (r *relationshipRepository) RecordType() string {
+	return "patient_relationships"
+}
+
+func (r *relationshipRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Model(&domain.PatientRelationship{}).
+		Where("patient_id = ? OR related_patient_id = ?", fromID, fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Model(&domain.PatientRelationship{}).Where("id IN ?", movedIDs).
+		Updates(map[string]interface{}{
+			"patient_id":         gorm.Expr("CASE WHEN patient_id = ? THEN ? ELSE patient_id END", fromID, toID),
+			"related_patient_id": gorm.Expr("CASE WHEN related_patient_id = ? THEN ? ELSE related_patient_id END", fromID, toID),
+		}).Error
+	if isUniqueViolation(err, "idx_patient_relationships_pair") {
+		return nil, domain.NewConflictError("relationship_exists",
+			fmt.Sprintf("patient %d already has a relationship with the same person", toID))
+	}
+	return movedIDs, err
+}
+
+func (r *relationshipRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "patient_relationships")
+}
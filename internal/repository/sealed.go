@@ -0,0 +1,134 @@
+// internal/repository/sealed.go
+package repository
+
+import (
+	"context"
+	"doctors/pkg/encryption"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sealedRow describes a row whose sensitive fields are encrypted with a
+// data key of its own, stored wrapped next to them.
+type sealedRow struct {
+	DataKey *string
+	KeyID   *string
+	Fields  []*string
+	// Derive, when set, updates columns computed from the plaintext, such
+	// as blind indexes, just before the fields are encrypted.
+	Derive func()
+}
+
+// withSealedRow encrypts the row's fields in place for the duration of fn,
+// generating its data key on first write, and restores the plaintext
+// afterwards.
+func withSealedRow(cipher *encryption.Envelope, row sealedRow, fn func() error) error {
+	plain := make([]string, len(row.Fields))
+	for i, field := range row.Fields {
+		plain[i] = *field
+	}
+	err := sealRow(cipher, row)
+	if err == nil {
+		err = fn()
+	}
+	for i, field := range row.Fields {
+		*field = plain[i]
+	}
+	return err
+}
+
+func sealRow(cipher *encryption.Envelope, row sealedRow) error {
+	if row.Derive != nil {
+		row.Derive()
+	}
+	var key *encryption.DataKey
+	var err error
+	if *row.DataKey != "" {
+		key, err = cipher.OpenDataKey(*row.DataKey, *row.KeyID)
+	} else {
+		key, err = cipher.NewDataKey()
+		if err == nil {
+			*row.DataKey, *row.KeyID = key.Wrapped, key.KeyID
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, field := range row.Fields {
+		if *field, err = cipher.Encrypt(key, *field); err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+	}
+	return nil
+}
+
+// openRow decrypts the row's fields in place. Rows without a data key were
+// written before their table was encrypted and are read as they are until
+// rotate-keys seals them.
+func openRow(cipher *encryption.Envelope, row sealedRow) error {
+	if *row.DataKey == "" {
+		return nil
+	}
+	key, err := cipher.OpenDataKey(*row.DataKey, *row.KeyID)
+	if err != nil {
+		return err
+	}
+	for _, field := range row.Fields {
+		if *field, err = cipher.Decrypt(key, *field); err != nil {
+			return fmt.Errorf("failed to decrypt: %w", err)
+		}
+	}
+	return nil
+}
+
+// rotateSealedRows re-encrypts, in batches, the rows of T whose data key is
+// not wrapped by the active master key, unencrypted rows included. columns
+// are the sealed columns; data_key and key_id are rewritten with them. Each
+// batch is read and locked in the transaction that rewrites it, so writes
+// made meanwhile wait instead of being overwritten.
+func rotateSealedRows[T any](ctx context.Context, db *gorm.DB, cipher *encryption.Envelope, batchSize int,
+	columns []string, id func(*T) uint, row func(*T) sealedRow) (int, error) {
+	activeKeyID := cipher.ActiveKeyID()
+	columns = append([]string{"data_key", "key_id"}, columns...)
+	rotated := 0
+	var lastID uint
+
+	for {
+		var batch []T
+		err := conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+			err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id > ? AND (key_id IS NULL OR key_id <> ?)", lastID, activeKeyID).
+				Order("id").Limit(batchSize).Find(&batch).Error
+			if err != nil {
+				return err
+			}
+			for i := range batch {
+				record := &batch[i]
+				sealed := row(record)
+				if err := openRow(cipher, sealed); err != nil {
+					return fmt.Errorf("row %d: %w", id(record), err)
+				}
+				// Force a fresh data key under the active master key.
+				*sealed.DataKey, *sealed.KeyID = "", ""
+				if err := sealRow(cipher, sealed); err != nil {
+					return fmt.Errorf("row %d: %w", id(record), err)
+				}
+				if err := tx.Unscoped().Model(record).Select(columns).UpdateColumns(record).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return rotated, err
+		}
+		if len(batch) == 0 {
+			return rotated, nil
+		}
+
+		rotated += len(batch)
+		lastID = id(&batch[len(batch)-1])
+	}
+}
@@ -0,0 +1,289 @@
+// internal/repository/sealed_test.go
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sealedNote is a minimal sealed table for exercising rotateSealedRows.
+type sealedNote struct {
+	ID      uint
+	Content string
+	DataKey string
+	KeyID   string
+}
+
+func sealedNoteRow(note *sealedNote) sealedRow {
+	return sealedRow{DataKey: &note.DataKey, KeyID: &note.KeyID, Fields: []*string{&note.Content}}
+}
+
+// noteStore is an in-memory sealed_notes table behind a database/sql
+// driver. It answers the batch query of rotateSealedRows and applies the
+// updates that follow it.
+type noteStore struct {
+	rows    map[uint]sealedNote
+	queries []string
+}
+
+func (s *noteStore) Connect(context.Context) (driver.Conn, error) { return noteConn{s}, nil }
+func (s *noteStore) Driver() driver.Driver                        { return nil }
+
+type noteConn struct{ store *noteStore }
+
+var (
+	noteLimit = regexp.MustCompile(`LIMIT (\d+)`)
+	noteSet   = regexp.MustCompile(`"(\w+)"\s?=\s?\$(\d+)`)
+)
+
+func (c noteConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.store.queries = append(c.store.queries, query)
+	lastID := uint(args[0].Value.(int64))
+	activeKeyID := args[1].Value.(string)
+	limit := 0
+	if m := noteLimit.FindStringSubmatch(query); m != nil {
+		limit, _ = strconv.Atoi(m[1])
+	} else {
+		limit = int(args[2].Value.(int64))
+	}
+
+	var ids []uint
+	for id, note := range c.store.rows {
+		if id > lastID && note.KeyID != activeKeyID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	rows := &noteRows{}
+	for _, id := range ids {
+		note := c.store.rows[id]
+		rows.values = append(rows.values, []driver.Value{int64(note.ID), note.Content, note.DataKey, note.KeyID})
+	}
+	return rows, nil
+}
+
+func (c noteConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.HasPrefix(query, `UPDATE "sealed_notes"`) {
+		return nil, errors.New("noteStore: unexpected statement " + query)
+	}
+	values := map[string]driver.Value{}
+	for _, m := range noteSet.FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(m[2])
+		values[m[1]] = args[n-1].Value
+	}
+	id := uint(values["id"].(int64))
+	note := c.store.rows[id]
+	note.DataKey, note.KeyID, note.Content = values["data_key"].(string), values["key_id"].(string), values["content"].(string)
+	c.store.rows[id] = note
+	return driver.RowsAffected(1), nil
+}
+
+func (noteConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("noteStore: prepare") }
+func (noteConn) Close() error                        { return nil }
+func (noteConn) Begin() (driver.Tx, error)           { return noteConn{}, nil }
+func (noteConn) Commit() error                       { return nil }
+func (noteConn) Rollback() error                     { return nil }
+
+type noteRows struct{ values [][]driver.Value }
+
+func (*noteRows) Columns() []string { return []string{"id", "content", "data_key", "key_id"} }
+func (*noteRows) Close() error      { return nil }
+
+func (r *noteRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newTestCipher(t *testing.T, activeID string, ids ...string) *encryption.Envelope {
+	t.Helper()
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+	kms, err := encryption.NewLocalKMS(keys, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewEnvelope(kms, bytes.Repeat([]byte{0xAA}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+func sealNote(t *testing.T, cipher *encryption.Envelope, id uint, content string) sealedNote {
+	t.Helper()
+	note := sealedNote{ID: id, Content: content}
+	if err := sealRow(cipher, sealedNoteRow(&note)); err != nil {
+		t.Fatal(err)
+	}
+	return note
+}
+
+func TestRotateSealedRows(t *testing.T) {
+	v1 := newTestCipher(t, "v1", "v1", "v2")
+	v2 := newTestCipher(t, "v2", "v1", "v2")
+	current := sealNote(t, v2, 3, "already rotated")
+	store := &noteStore{rows: map[uint]sealedNote{
+		1: {ID: 1, Content: "written before encryption"},
+		2: sealNote(t, v1, 2, "sealed under v1"),
+		3: current,
+		4: sealNote(t, v1, 4, "also under v1"),
+		5: sealNote(t, v1, 5, ""),
+	}}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(store)}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := rotateSealedRows(context.Background(), db, v2, 2, []string{"content"},
+		func(note *sealedNote) uint { return note.ID }, sealedNoteRow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated != 4 {
+		t.Errorf("rotated = %d, want 4", rotated)
+	}
+
+	want := map[uint]string{
+		1: "written before encryption", 2: "sealed under v1", 3: "already rotated", 4: "also under v1", 5: "",
+	}
+	for id, content := range want {
+		note := store.rows[id]
+		if note.KeyID != "v2" {
+			t.Errorf("note %d: key_id = %q, want v2", id, note.KeyID)
+		}
+		if content != "" && note.Content == content {
+			t.Errorf("note %d is stored in plaintext", id)
+		}
+		if err := openRow(v2, sealedNoteRow(&note)); err != nil || note.Content != content {
+			t.Errorf("note %d: opened %q, %v, want %q", id, note.Content, err, content)
+		}
+	}
+	if store.rows[3] != current {
+		t.Error("a row already under the active key was rewritten")
+	}
+
+	for _, query := range store.queries {
+		if !strings.Contains(query, "FOR UPDATE") {
+			t.Errorf("batch read without a row lock: %s", query)
+		}
+	}
+	if len(store.queries) != 3 {
+		t.Errorf("%d batch queries, want 3 for 4 rows in batches of 2", len(store.queries))
+	}
+}
+
+func TestRotateSealedRowsStopsAtUnreadableRow(t *testing.T) {
+	v1 := newTestCipher(t, "v1", "v1", "v2")
+	v2 := newTestCipher(t, "v2", "v1", "v2")
+	broken := sealNote(t, v1, 7, "tampered")
+	broken.Content = "bm90IHNlYWxlZCBieSB0aGlzIGtleQ=="
+	store := &noteStore{rows: map[uint]sealedNote{7: broken}}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(store)}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = rotateSealedRows(context.Background(), db, v2, 10, []string{"content"},
+		func(note *sealedNote) uint { return note.ID }, sealedNoteRow)
+	if err == nil || !strings.Contains(err.Error(), "row 7") {
+		t.Fatalf("err = %v, want a failure naming row 7", err)
+	}
+	if store.rows[7] != broken {
+		t.Error("an unreadable row was rewritten")
+	}
+}
+
+func TestOpenRowReadsUnencryptedRows(t *testing.T) {
+	note := sealedNote{ID: 1, Content: "written before encryption"}
+	if err := openRow(newTestCipher(t, "v1", "v1"), sealedNoteRow(&note)); err != nil {
+		t.Fatal(err)
+	}
+	if note.Content != "written before encryption" {
+		t.Errorf("content = %q", note.Content)
+	}
+}
+
+func TestWithSealedRowRestoresPlaintext(t *testing.T) {
+	cipher := newTestCipher(t, "v1", "v1")
+	note := sealedNote{ID: 1, Content: "Jane Doe"}
+
+	var stored sealedNote
+	err := withSealedRow(cipher, sealedNoteRow(&note), func() error {
+		stored = note
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Content != "Jane Doe" {
+		t.Errorf("content after write = %q, want the plaintext back", note.Content)
+	}
+	if stored.Content == "Jane Doe" || stored.DataKey == "" || stored.KeyID != "v1" {
+		t.Errorf("stored row = %+v, want it sealed", stored)
+	}
+	if err := openRow(cipher, sealedNoteRow(&stored)); err != nil || stored.Content != "Jane Doe" {
+		t.Errorf("opened %q, %v", stored.Content, err)
+	}
+}
+
+func TestPatientBlindIndexesNormalizeInput(t *testing.T) {
+	repo := &patientRepository{cipher: newTestCipher(t, "v1", "v1")}
+	index := func(patient domain.Patient) domain.Patient {
+		repo.row(&patient).Derive()
+		return patient
+	}
+
+	a := index(domain.Patient{Email: " Jane.Doe@Example.COM ", Phone: "+1 (555) 010-2030", NationalID: "123-45-6789"})
+	b := index(domain.Patient{Email: "jane.doe@example.com", Phone: "+15550102030", NationalID: "123 45 6789"})
+	if a.EmailIndex != b.EmailIndex {
+		t.Error("email indexes differ by case and spacing")
+	}
+	if a.PhoneIndex != b.PhoneIndex {
+		t.Error("phone indexes differ by punctuation")
+	}
+	if a.NationalIDIndex != b.NationalIDIndex {
+		t.Error("national ID indexes differ by punctuation")
+	}
+	if again := index(domain.Patient{Email: "jane.doe@example.com"}); again.EmailIndex != b.EmailIndex {
+		t.Error("email index is not stable")
+	}
+
+	c := index(domain.Patient{Email: "john.doe@example.com", Phone: "15550102030"})
+	if c.EmailIndex == b.EmailIndex {
+		t.Error("different emails share an index")
+	}
+	if c.PhoneIndex == b.PhoneIndex {
+		t.Error("a leading + is dropped from phone indexes")
+	}
+}
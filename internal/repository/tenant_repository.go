@@ -0,0 +1,79 @@
+// internal/repository/tenant_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type TenantRepository interface {
+	Create(ctx context.Context, tenant *domain.Tenant) error
+	GetBySlug(ctx context.Context, slug string) (*domain.Tenant, error)
+	List(ctx context.Context) ([]domain.Tenant, error)
+}
+
+type tenantRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantRepository(db *gorm.DB) TenantRepository {
+	return &tenantRepository{db: db}
+}
+
+func (r *tenantRepository) Create(ctx context.Context, tenant *domain.Tenant) error {
+	err := conn(ctx, r.db).Create(tenant).Error
+	if isUniqueViolation(err, "idx_tenants_slug") {
+		return domain.NewConflictError("tenant_slug_taken", "a tenant with this slug already exists")
+	}
+	return err
+}
+
+func (r *tenantRepository) GetBySlug(ctx context.Context, slug string) (*domain.Tenant, error) {
+	var tenant domain.Tenant
+	if err := conn(ctx, r.db).Where("slug = ?", slug).First(&tenant).Error; err != nil {
+		return nil, notFound(err, "tenant", slug)
+	}
+	return &tenant, nil
+}
+
+func (r *tenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
+	var tenants []domain.Tenant
+	err := conn(ctx, r.db).Order("id").Find(&tenants).Error
+	return tenants, err
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context acting for a tenant. Patients and their
+// records, imports, bulk exports, claim files and remittances read with it
+// are limited to the tenant; contexts without one, such as background jobs and the admin
+// CLI, see every tenant.
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// ContextTenant returns the tenant ctx acts for, if any.
+func ContextTenant(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(tenantKey{}).(uint)
+	return id, ok && id != 0
+}
+
+// forTenant limits a query of a table with a tenant_id column to the
+// tenant ctx acts for.
+func forTenant(ctx context.Context, db *gorm.DB, table string) *gorm.DB {
+	if id, ok := ContextTenant(ctx); ok {
+		return db.Where(table+".tenant_id = ?", id)
+	}
+	return db
+}
+
+// forPatientTenant limits a query of a table with a patient_id column to
+// the rows of patients, archived or not, of the tenant ctx acts for.
+func forPatientTenant(ctx context.Context, db *gorm.DB, table string) *gorm.DB {
+	if id, ok := ContextTenant(ctx); ok {
+		return db.Where(table+".patient_id IN (SELECT id FROM patients WHERE tenant_id = ?)", id)
+	}
+	return db
+}
@@ -0,0 +1,215 @@
+// internal/repository/tenant_repository_test.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"doctors/internal/domain"
+	"doctors/pkg/encryption"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// tenantDB stands in for a database in which every record belongs to a
+// patient of tenant 1: queries limited to another tenant find nothing,
+// lookups of child rows such as addenda find nothing either, and every
+// other query finds one row with ID 1.
+type tenantDB struct{}
+
+func init() {
+	sql.Register("tenantdb", tenantDB{})
+}
+
+func (tenantDB) Open(string) (driver.Conn, error) { return tenantConn{}, nil }
+
+type tenantConn struct{}
+
+var (
+	tenantParam = regexp.MustCompile(`tenant_id = \$(\d+)`)
+	childRows   = regexp.MustCompile(`_id IN \(\$`)
+)
+
+func (tenantConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if m := tenantParam.FindStringSubmatch(query); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if args[n-1].Value != int64(1) {
+			return &tenantRows{columns: []string{"id"}}, nil
+		}
+	}
+	if childRows.MatchString(query) {
+		return &tenantRows{columns: []string{"id"}}, nil
+	}
+	if strings.Contains(query, "count(") {
+		return &tenantRows{columns: []string{"count"}, values: [][]driver.Value{{int64(1)}}}, nil
+	}
+	return &tenantRows{columns: []string{"id", "patient_id"}, values: [][]driver.Value{{int64(1), int64(1)}}}, nil
+}
+
+func (tenantConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("tenantdb: prepare") }
+func (tenantConn) Close() error                        { return nil }
+func (tenantConn) Begin() (driver.Tx, error)           { return tenantConn{}, nil }
+func (tenantConn) Commit() error                       { return nil }
+func (tenantConn) Rollback() error                     { return nil }
+
+type tenantRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *tenantRows) Columns() []string { return r.columns }
+func (r *tenantRows) Close() error      { return nil }
+
+func (r *tenantRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func openTenantDB(t *testing.T) (*gorm.DB, *encryption.Envelope) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "tenantdb"}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.Repeat("k", 32)
+	cipher, err := encryption.NewEnvelope(mustLocalKMS(t, map[string][]byte{"v1": []byte(key)}, "v1"), []byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, cipher
+}
+
+func mustLocalKMS(t *testing.T, keys map[string][]byte, activeID string) *encryption.LocalKMS {
+	t.Helper()
+	kms, err := encryption.NewLocalKMS(keys, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kms
+}
+
+func TestReadsAreScopedToTheTenant(t *testing.T) {
+	db, cipher := openTenantDB(t)
+	reads := []struct {
+		name string
+		read func(ctx context.Context) error
+	}{
+		{"patient", func(ctx context.Context) error {
+			_, err := NewPatientRepository(db, cipher).GetByID(ctx, 1)
+			return err
+		}},
+		{"appointment", func(ctx context.Context) error {
+			_, err := NewAppointmentRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"encounter", func(ctx context.Context) error {
+			_, err := NewEncounterRepository(db, cipher).GetByID(ctx, 1)
+			return err
+		}},
+		{"encounter of an appointment", func(ctx context.Context) error {
+			_, err := NewEncounterRepository(db, cipher).GetByAppointment(ctx, 1)
+			return err
+		}},
+		{"allergy", func(ctx context.Context) error {
+			_, err := NewAllergyRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"medication", func(ctx context.Context) error {
+			_, err := NewMedicationRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"problem", func(ctx context.Context) error {
+			_, err := NewProblemRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"prescription", func(ctx context.Context) error {
+			_, err := NewPrescriptionRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"lab order", func(ctx context.Context) error {
+			_, err := NewLabRepository(db, cipher).GetOrder(ctx, 1)
+			return err
+		}},
+		{"insurance policy", func(ctx context.Context) error {
+			_, err := NewInsuranceRepository(db, cipher).GetPolicy(ctx, 1)
+			return err
+		}},
+		{"relationship", func(ctx context.Context) error {
+			_, err := NewRelationshipRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"invoice", func(ctx context.Context) error {
+			_, err := NewInvoiceRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"payment intent", func(ctx context.Context) error {
+			_, err := NewPaymentIntentRepository(db).GetByID(ctx, 1)
+			return err
+		}},
+		{"claim", func(ctx context.Context) error {
+			_, err := NewClaimRepository(db, cipher).GetByID(ctx, 1)
+			return err
+		}},
+		{"claim file", func(ctx context.Context) error {
+			_, err := NewClaimRepository(db, cipher).GetFile(ctx, 1)
+			return err
+		}},
+		{"remittance", func(ctx context.Context) error {
+			_, err := NewClaimRepository(db, cipher).GetRemittance(ctx, 1)
+			return err
+		}},
+	}
+
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read(WithTenant(context.Background(), 2))
+			if !errors.Is(err, domain.ErrNotFound) {
+				t.Errorf("read by another tenant: error = %v, want not found", err)
+			}
+			for name, ctx := range map[string]context.Context{
+				"owning tenant": WithTenant(context.Background(), 1),
+				"no tenant":     context.Background(),
+			} {
+				if err := tt.read(ctx); errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("read by %s: error = %v, want the record", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestListsAreScopedToTheTenant(t *testing.T) {
+	db, _ := openTenantDB(t)
+	patientID := uint(1)
+
+	ctx := WithTenant(context.Background(), 2)
+	invoices, total, err := NewInvoiceRepository(db).List(ctx, domain.InvoiceSearch{PatientID: &patientID, Limit: 10})
+	if err != nil || total != 0 || len(invoices) != 0 {
+		t.Errorf("invoices of another tenant = %v, %d, %v, want none", invoices, total, err)
+	}
+	prescriptions, err := NewPrescriptionRepository(db).ListByPatient(ctx, patientID)
+	if err != nil || len(prescriptions) != 0 {
+		t.Errorf("prescriptions of another tenant = %v, %v, want none", prescriptions, err)
+	}
+
+	ctx = WithTenant(context.Background(), 1)
+	invoices, total, err = NewInvoiceRepository(db).List(ctx, domain.InvoiceSearch{PatientID: &patientID, Limit: 10})
+	if err != nil || total != 1 || len(invoices) != 1 {
+		t.Errorf("invoices of the owning tenant = %v, %d, %v, want one", invoices, total, err)
+	}
+}
@@ -0,0 +1,48 @@
+// internal/repository/user_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *domain.User) error
+	GetByAPIKeyHash(ctx context.Context, hash string) (*domain.User, error)
+	List(ctx context.Context) ([]domain.User, error)
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
+	err := conn(ctx, r.db).Create(user).Error
+	if isUniqueViolation(err, "idx_users_email") {
+		return domain.NewConflictError("user_email_taken", "a user with this email already exists")
+	}
+	if isForeignKeyViolation(err, "fk_users_patient") {
+		return domain.NewValidationError(domain.FieldError{Field: "patient_id", Message: "patient does not exist"})
+	}
+	return err
+}
+
+func (r *userRepository) GetByAPIKeyHash(ctx context.Context, hash string) (*domain.User, error) {
+	var user domain.User
+	if err := conn(ctx, r.db).Where("api_key_hash = ?", hash).First(&user).Error; err != nil {
+		return nil, notFound(err, "user", "for API key")
+	}
+	return &user, nil
+}
+
+func (r *userRepository) List(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+	err := conn(ctx, r.db).Order("id").Find(&users).Error
+	return users, err
+}
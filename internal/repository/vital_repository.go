@@ -0,0 +1,98 @@
+// internal/repository/vital_repository.go
+package repository
+
+import (
+	"context"
+	"doctors/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type VitalRepository interface {
+	// CreateBatch records measurements taken together, all or none.
+	CreateBatch(ctx context.Context, vitals []domain.VitalSign) error
+	// ListByPatient returns up to limit of the patient's latest
+	// measurements, only those of the appointment when appointmentID is set.
+	ListByPatient(ctx context.Context, patientID uint, appointmentID *uint, limit int) ([]domain.VitalSign, error)
+	// Series returns the patient's measurements matching query, oldest first.
+	Series(ctx context.Context, patientID uint, query domain.VitalQuery) ([]domain.VitalSign, error)
+	// Delete marks one of the patient's measurements as entered in error.
+	Delete(ctx context.Context, patientID, id uint) error
+	PatientRecords
+}
+
+type vitalRepository struct {
+	db *gorm.DB
+}
+
+func NewVitalRepository(db *gorm.DB) VitalRepository {
+	return &vitalRepository{db: db}
+}
+
+func (r *vitalRepository) CreateBatch(ctx context.Context, vitals []domain.VitalSign) error {
+	return conn(ctx, r.db).Create(&vitals).Error
+}
+
+func (r *vitalRepository) ListByPatient(ctx context.Context, patientID uint, appointmentID *uint, limit int) ([]domain.VitalSign, error) {
+	query := r.scoped(ctx).Where("patient_id = ?", patientID)
+	if appointmentID != nil {
+		query = query.Where("appointment_id = ?", *appointmentID)
+	}
+	var vitals []domain.VitalSign
+	err := query.Order("measured_at DESC").Order("id DESC").Limit(limit).Find(&vitals).Error
+	return vitals, err
+}
+
+func (r *vitalRepository) Series(ctx context.Context, patientID uint, query domain.VitalQuery) ([]domain.VitalSign, error) {
+	db := r.scoped(ctx).Where("patient_id = ?", patientID)
+	if len(query.Types) > 0 {
+		db = db.Where("type IN ?", query.Types)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("measured_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("measured_at < ?", query.To)
+	}
+	var vitals []domain.VitalSign
+	err := db.Order("measured_at").Order("id").Find(&vitals).Error
+	return vitals, err
+}
+
+func (r *vitalRepository) Delete(ctx context.Context, patientID, id uint) error {
+	result := r.scoped(ctx).Where("patient_id = ?", patientID).Delete(&domain.VitalSign{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewNotFoundError("vital_sign", id)
+	}
+	return nil
+}
+
+func (r *vitalRepository) RecordType() string {
+	return "vital_signs"
+}
+
+func (r *vitalRepository) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	query := conn(ctx, r.db).Unscoped().Model(&domain.VitalSign{}).Where("patient_id = ?", fromID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+
+	var movedIDs []uint
+	if err := query.Order("id").Pluck("id", &movedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, nil
+	}
+
+	err := conn(ctx, r.db).Unscoped().Model(&domain.VitalSign{}).Where("id IN ?", movedIDs).
+		Update("patient_id", toID).Error
+	return movedIDs, err
+}
+
+func (r *vitalRepository) scoped(ctx context.Context) *gorm.DB {
+	return forPatientTenant(ctx, conn(ctx, r.db), "vital_signs")
+}
@@ -6,108 +6,390 @@ import (
 	"doctors/internal/domain"
 	"doctors/internal/repository"
 	"doctors/pkg/email"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
 type AppointmentUseCase interface {
 	CreateAppointment(ctx context.Context, appointment *domain.Appointment) error
 	GetAppointment(ctx context.Context, id uint) (*domain.Appointment, error)
-	UpdateAppointment(ctx context.Context, appointment *domain.Appointment) error
+	// UpdateAppointment replaces all client-editable fields. ifMatch, when
+	// set, is the version the client last saw.
+	UpdateAppointment(ctx context.Context, appointment *domain.Appointment, ifMatch *uint) error
+	// PatchAppointment applies a JSON Merge Patch (RFC 7386).
+	PatchAppointment(ctx context.Context, id uint, patch []byte, ifMatch *uint) (*domain.Appointment, error)
+	// DeleteAppointment archives an appointment.
 	DeleteAppointment(ctx context.Context, id uint) error
-	GetAppointmentsByDate(ctx context.Context, date time.Time) ([]domain.Appointment, error)
+	RestoreAppointment(ctx context.Context, id uint) (*domain.Appointment, error)
+	GetAppointmentsByDate(ctx context.Context, date time.Time, includeArchived bool) ([]domain.Appointment, error)
+	// SearchAppointments returns a page of matching appointments, by time,
+	// and the number of matches.
+	SearchAppointments(ctx context.Context, search domain.AppointmentSearch) ([]domain.Appointment, int64, error)
 	SendReminders(ctx context.Context) error
+	// SendRemindersForDate emails a reminder for every scheduled appointment on
+	// date to the patient and to guardians who receive their notifications,
+	// and returns the number of emails sent.
+	SendRemindersForDate(ctx context.Context, date time.Time) (int, error)
+	// ResendConfirmation emails the appointment confirmation again.
+	ResendConfirmation(ctx context.Context, id uint) error
+}
+
+// AppointmentBilling bills completed appointments.
+type AppointmentBilling interface {
+	DraftInvoice(ctx context.Context, appointment *domain.Appointment) error
 }
 
 type appointmentUseCase struct {
-	appointmentRepo repository.AppointmentRepository
-	patientRepo     repository.PatientRepository
-	doctorRepo      repository.DoctorRepository
-	emailSender     email.Sender
+	transactor       repository.Transactor
+	appointmentRepo  repository.AppointmentRepository
+	patientRepo      repository.PatientRepository
+	doctorRepo       repository.DoctorRepository
+	relationshipRepo repository.RelationshipRepository
+	emailSender      email.Sender
+	billing          AppointmentBilling
+	bookingHorizon   time.Duration
+	now              func() time.Time
 }
 
 func NewAppointmentUseCase(
+	transactor repository.Transactor,
 	appointmentRepo repository.AppointmentRepository,
 	patientRepo repository.PatientRepository,
 	doctorRepo repository.DoctorRepository,
+	relationshipRepo repository.RelationshipRepository,
 	emailSender email.Sender,
+	billing AppointmentBilling,
+	bookingHorizon time.Duration,
 ) AppointmentUseCase {
 	return &appointmentUseCase{
-		appointmentRepo: appointmentRepo,
-		patientRepo:     patientRepo,
-		doctorRepo:      doctorRepo,
-		emailSender:     emailSender,
+		transactor:       transactor,
+		appointmentRepo:  appointmentRepo,
+		patientRepo:      patientRepo,
+		doctorRepo:       doctorRepo,
+		relationshipRepo: relationshipRepo,
+		emailSender:      emailSender,
+		billing:          billing,
+		bookingHorizon:   bookingHorizon,
+		now:              time.Now,
 	}
 }
 
 func (uc *appointmentUseCase) CreateAppointment(ctx context.Context, appointment *domain.Appointment) error {
-	// Get the default doctor
-	defaultDoctor, err := uc.doctorRepo.GetDefaultDoctor(ctx)
+	if err := uc.validateAppointment(appointment, true); err != nil {
+		return err
+	}
+
+	var patient *domain.Patient
+	var defaultDoctor *domain.Doctor
+
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Get the patient first so a missing one never leaves an appointment behind
+		var err error
+		patient, err = uc.patientRepo.GetByID(ctx, appointment.PatientID)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewValidationError(domain.FieldError{Field: "patient_id", Message: "patient does not exist"})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get patient: %w", err)
+		}
+
+		// Get the default doctor
+		defaultDoctor, err = uc.doctorRepo.GetDefaultDoctor(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get default doctor: %w", err)
+		}
+		appointment.DoctorID = defaultDoctor.ID
+
+		// Create the appointment
+		if err := uc.appointmentRepo.Create(ctx, appointment); err != nil {
+			return fmt.Errorf("failed to create appointment: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get default doctor: %w", err)
+		return err
 	}
-	appointment.DoctorID = defaultDoctor.ID
 
-	// Create the appointment
-	if err := uc.appointmentRepo.Create(ctx, appointment); err != nil {
-		return fmt.Errorf("failed to create appointment: %w", err)
+	if err := uc.sendConfirmation(ctx, patient, defaultDoctor, appointment); err != nil {
+		// Log the error but don't fail the appointment creation
+		fmt.Printf("Failed to send confirmation email: %v\n", err)
 	}
 
-	// Get the patient
+	return nil
+}
+
+func (uc *appointmentUseCase) ResendConfirmation(ctx context.Context, id uint) error {
+	appointment, err := uc.appointmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if appointment.Status != domain.AppointmentStatusScheduled {
+		return domain.NewConflictError("appointment_not_scheduled", "only scheduled appointments can be confirmed")
+	}
 	patient, err := uc.patientRepo.GetByID(ctx, appointment.PatientID)
 	if err != nil {
 		return fmt.Errorf("failed to get patient: %w", err)
 	}
+	doctor, err := uc.doctorRepo.GetByID(ctx, appointment.DoctorID)
+	if err != nil {
+		return fmt.Errorf("failed to get doctor: %w", err)
+	}
+	return uc.sendConfirmation(ctx, patient, doctor, appointment)
+}
+
+func (uc *appointmentUseCase) sendConfirmation(ctx context.Context, patient *domain.Patient, doctor *domain.Doctor, appointment *domain.Appointment) error {
+	recipients, err := uc.recipients(ctx, patient)
+	if err != nil {
+		return err
+	}
 
-	// Send email
 	subject := "Appointment Confirmation"
-	body := fmt.Sprintf("Dear %s,\n\nYour appointment with Dr. %s is confirmed for %s.\n\nNotes: %s\n\nBest regards,\nDoctor SaaS Team",
-		patient.Name, defaultDoctor.Name, appointment.DateTime.Format(time.RFC1123), appointment.Notes)
+	var errs []error
+	for _, to := range recipients {
+		body := fmt.Sprintf("Dear %s,\n\n%s with Dr. %s is confirmed for %s.\n\nNotes: %s\n\nBest regards,\nDoctor SaaS Team",
+			to.Name, to.whose(patient), doctor.Name, appointment.DateTime.Format(time.RFC1123), appointment.Notes)
+		if err := uc.emailSender.Send(to.Email, subject, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", to.Email, err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	if err := uc.emailSender.Send(patient.Email, subject, body); err != nil {
-		// Log the error but don't fail the appointment creation
-		fmt.Printf("Failed to send confirmation email: %v\n", err)
+// recipient is someone notified about a patient's appointments.
+type recipient struct {
+	Name  string
+	Email string
+	// Self is set when the recipient is the patient.
+	Self bool
+}
+
+// whose names the appointment from the recipient's point of view.
+func (r recipient) whose(patient *domain.Patient) string {
+	if r.Self {
+		return "Your appointment"
 	}
+	return patient.Name + "'s appointment"
+}
 
-	return nil
+// recipients routes a patient's notifications: to the patient when they
+// have an email, and to every guardian or caregiver who receives them.
+func (uc *appointmentUseCase) recipients(ctx context.Context, patient *domain.Patient) ([]recipient, error) {
+	var recipients []recipient
+	seen := map[string]bool{}
+	if patient.Email != "" {
+		recipients = append(recipients, recipient{Name: patient.Name, Email: patient.Email, Self: true})
+		seen[strings.ToLower(patient.Email)] = true
+	}
+
+	rels, err := uc.relationshipRepo.ListResponsible(ctx, patient.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guardians: %w", err)
+	}
+	for _, rel := range rels {
+		if !rel.ReceivesNotifications {
+			continue
+		}
+		guardian, err := uc.patientRepo.GetByID(ctx, rel.RelatedPatientID)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get guardian: %w", err)
+		}
+		// A parent who shares their email with a child gets one message.
+		if guardian.Email == "" || seen[strings.ToLower(guardian.Email)] {
+			continue
+		}
+		seen[strings.ToLower(guardian.Email)] = true
+		recipients = append(recipients, recipient{Name: guardian.Name, Email: guardian.Email})
+	}
+	return recipients, nil
 }
 
 func (uc *appointmentUseCase) GetAppointment(ctx context.Context, id uint) (*domain.Appointment, error) {
 	return uc.appointmentRepo.GetByID(ctx, id)
 }
 
-func (uc *appointmentUseCase) UpdateAppointment(ctx context.Context, appointment *domain.Appointment) error {
-	// Add any business logic here
-	return uc.appointmentRepo.Update(ctx, appointment)
+func (uc *appointmentUseCase) UpdateAppointment(ctx context.Context, appointment *domain.Appointment, ifMatch *uint) error {
+	existing, err := uc.appointmentRepo.GetByID(ctx, appointment.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion("appointment", existing.Version, ifMatch); err != nil {
+		return err
+	}
+	keepAppointmentSystemFields(appointment, existing)
+
+	return versionConflict(uc.saveAppointment(ctx, appointment, existing), "appointment", ifMatch)
+}
+
+func (uc *appointmentUseCase) PatchAppointment(ctx context.Context, id uint, patch []byte, ifMatch *uint) (*domain.Appointment, error) {
+	existing, err := uc.appointmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion("appointment", existing.Version, ifMatch); err != nil {
+		return nil, err
+	}
+
+	var appointment domain.Appointment
+	if err := applyMergePatch(existing, patch, &appointment); err != nil {
+		return nil, err
+	}
+	keepAppointmentSystemFields(&appointment, existing)
+
+	if err := uc.saveAppointment(ctx, &appointment, existing); err != nil {
+		return nil, versionConflict(err, "appointment", ifMatch)
+	}
+	return &appointment, nil
+}
+
+// saveAppointment validates an edited appointment against its stored state and persists it.
+func (uc *appointmentUseCase) saveAppointment(ctx context.Context, appointment, existing *domain.Appointment) error {
+	// Only a rescheduled appointment has to move into the future.
+	if err := uc.validateAppointment(appointment, !appointment.DateTime.Equal(existing.DateTime)); err != nil {
+		return err
+	}
+	if _, err := uc.patientRepo.GetByID(ctx, appointment.PatientID); errors.Is(err, domain.ErrNotFound) {
+		return domain.NewValidationError(domain.FieldError{Field: "patient_id", Message: "patient does not exist"})
+	} else if err != nil {
+		return err
+	}
+
+	completed := appointment.Status == domain.AppointmentStatusCompleted && existing.Status != domain.AppointmentStatusCompleted
+	if !completed || uc.billing == nil {
+		return uc.appointmentRepo.Update(ctx, appointment)
+	}
+	// The draft invoice is created with the status change, so a completed
+	// appointment is never left unbilled.
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.appointmentRepo.Update(ctx, appointment); err != nil {
+			return err
+		}
+		if err := uc.billing.DraftInvoice(ctx, appointment); err != nil {
+			return fmt.Errorf("failed to draft invoice: %w", err)
+		}
+		return nil
+	})
 }
 
 func (uc *appointmentUseCase) DeleteAppointment(ctx context.Context, id uint) error {
 	return uc.appointmentRepo.Delete(ctx, id)
 }
 
-func (uc *appointmentUseCase) GetAppointmentsByDate(ctx context.Context, date time.Time) ([]domain.Appointment, error) {
-	return uc.appointmentRepo.GetByDate(ctx, date)
+func (uc *appointmentUseCase) RestoreAppointment(ctx context.Context, id uint) (*domain.Appointment, error) {
+	var appointment *domain.Appointment
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		appointment, err = uc.appointmentRepo.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		// An appointment can't come back for a patient who is still archived.
+		if _, err := uc.patientRepo.GetByID(ctx, appointment.PatientID); errors.Is(err, domain.ErrNotFound) {
+			return domain.NewConflictError("patient_archived", "restore the patient before restoring their appointments")
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+func (uc *appointmentUseCase) GetAppointmentsByDate(ctx context.Context, date time.Time, includeArchived bool) ([]domain.Appointment, error) {
+	return uc.appointmentRepo.GetByDate(ctx, date, includeArchived)
+}
+
+func (uc *appointmentUseCase) SearchAppointments(ctx context.Context, search domain.AppointmentSearch) ([]domain.Appointment, int64, error) {
+	if search.Limit < 1 {
+		search.Limit = defaultSearchLimit
+	}
+	if search.Limit > maxSearchLimit {
+		search.Limit = maxSearchLimit
+	}
+	if search.Offset < 0 {
+		search.Offset = 0
+	}
+	return uc.appointmentRepo.Search(ctx, search)
 }
 
 func (uc *appointmentUseCase) SendReminders(ctx context.Context) error {
-	tomorrow := time.Now().AddDate(0, 0, 1)
-	appointments, err := uc.GetAppointmentsByDate(ctx, tomorrow)
+	_, err := uc.SendRemindersForDate(ctx, uc.now().AddDate(0, 0, 1))
+	return err
+}
+
+func (uc *appointmentUseCase) SendRemindersForDate(ctx context.Context, date time.Time) (int, error) {
+	appointments, err := uc.GetAppointmentsByDate(ctx, date, false)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	sent := 0
 	for _, apt := range appointments {
+		if apt.Status != domain.AppointmentStatusScheduled {
+			continue
+		}
 		patient, err := uc.patientRepo.GetByID(ctx, apt.PatientID)
 		if err != nil {
 			continue
 		}
-
-		err = uc.emailSender.Send(patient.Email, "Appointment Reminder", "You have an appointment tomorrow at "+apt.DateTime.Format("15:04"))
+		recipients, err := uc.recipients(ctx, patient)
 		if err != nil {
-			// Log the error, but continue sending other reminders
-			// TODO: Implement proper error logging
+			fmt.Printf("Failed to send reminder for appointment %d: %v\n", apt.ID, err)
+			continue
+		}
+
+		for _, to := range recipients {
+			body := to.whose(patient) + " is on " + apt.DateTime.Format("Monday, January 2 at 15:04")
+			if err := uc.emailSender.Send(to.Email, "Appointment Reminder", body); err != nil {
+				// Log the error, but continue sending other reminders
+				fmt.Printf("Failed to send reminder for appointment %d to %s: %v\n", apt.ID, to.Email, err)
+				continue
+			}
+			sent++
 		}
 	}
 
-	return nil
+	return sent, nil
+}
+
+// validateAppointment checks field rules and, when scheduling, that the
+// appointment falls between now and the end of the booking horizon.
+func (uc *appointmentUseCase) validateAppointment(appointment *domain.Appointment, scheduling bool) error {
+	var extra []domain.FieldError
+	if scheduling && !appointment.DateTime.IsZero() {
+		now := uc.now()
+		switch {
+		case !appointment.DateTime.After(now):
+			extra = append(extra, domain.FieldError{Field: "date_time", Message: "must be in the future"})
+		case uc.bookingHorizon > 0 && appointment.DateTime.After(now.Add(uc.bookingHorizon)):
+			extra = append(extra, domain.FieldError{
+				Field:   "date_time",
+				Message: fmt.Sprintf("must be within %d days from now", int(uc.bookingHorizon.Hours()/24)),
+			})
+		}
+	}
+	appointment.Type = strings.TrimSpace(appointment.Type)
+	if appointment.Status == "" {
+		appointment.Status = domain.AppointmentStatusScheduled
+	}
+	if appointment.Status == domain.AppointmentStatusCompleted && appointment.DateTime.After(uc.now()) {
+		extra = append(extra, domain.FieldError{Field: "status", Message: "an appointment can't be completed before it starts"})
+	}
+	return validateStruct(appointment, extra...)
+}
+
+// keepAppointmentSystemFields copies fields clients may not change from the stored appointment.
+func keepAppointmentSystemFields(appointment, existing *domain.Appointment) {
+	appointment.ID = existing.ID
+	appointment.DoctorID = existing.DoctorID
+	appointment.EligibilityCheckID = existing.EligibilityCheckID
+	appointment.CreatedAt = existing.CreatedAt
+	appointment.DeletedAt = existing.DeletedAt
+	appointment.Version = existing.Version
 }
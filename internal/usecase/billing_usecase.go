@@ -0,0 +1,808 @@
+// internal/usecase/billing_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/pdf"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BillingUseCase interface {
+	ListFees(ctx context.Context) ([]domain.Fee, error)
+	// SetFee creates or replaces the fee of an appointment type.
+	SetFee(ctx context.Context, fee *domain.Fee) error
+	DeleteFee(ctx context.Context, appointmentType string) error
+	// DraftInvoice creates the draft invoice of a completed appointment,
+	// priced from the fee schedule, unless the appointment has one already.
+	DraftInvoice(ctx context.Context, appointment *domain.Appointment) error
+	CreateInvoice(ctx context.Context, invoice *domain.Invoice, actor *domain.User) error
+	GetInvoice(ctx context.Context, id uint) (*domain.Invoice, error)
+	ListInvoices(ctx context.Context, search domain.InvoiceSearch) ([]domain.Invoice, int64, error)
+	// UpdateInvoice replaces the lines, due date and notes of a draft.
+	UpdateInvoice(ctx context.Context, invoice *domain.Invoice) error
+	// IssueInvoice numbers a draft and makes it payable.
+	IssueInvoice(ctx context.Context, id uint) (*domain.Invoice, error)
+	// VoidInvoice cancels an invoice that holds no money.
+	VoidInvoice(ctx context.Context, id uint, reason string) (*domain.Invoice, error)
+	// RecordPayment records a payment of up to the balance of an issued invoice.
+	RecordPayment(ctx context.Context, payment *domain.Payment, actor *domain.User) (*domain.Invoice, error)
+	// RefundPayment gives back up to what is left of a payment.
+	RefundPayment(ctx context.Context, refund *domain.Payment, actor *domain.User) (*domain.Invoice, error)
+	// PostInsurance records an insurer's payment and adjustment of an
+	// issued invoice, each up to the balance left.
+	PostInsurance(ctx context.Context, posting *domain.InsurancePosting) (*domain.Invoice, error)
+	// ApplyDeposits posts the card deposits paid for the appointment of an
+	// issued invoice as payments of it. Issuing an invoice does this too.
+	ApplyDeposits(ctx context.Context, invoiceID uint) (*domain.Invoice, error)
+	// PatientBalance returns what a patient owes on issued invoices.
+	PatientBalance(ctx context.Context, patientID uint) (*domain.PatientBalance, error)
+	// RenderInvoice returns an invoice as a printable PDF.
+	RenderInvoice(ctx context.Context, id uint) ([]byte, error)
+}
+
+// BillingSettings configure invoices.
+type BillingSettings struct {
+	// Currency is the ISO 4217 code of every amount, e.g. "USD".
+	Currency string
+	// NumberFormat shapes invoice numbers, with the placeholders of MRN
+	// formats, e.g. "INV-{yyyy}-{seq:6}".
+	NumberFormat string
+	// DueDays is how many days after issue an invoice is due.
+	DueDays      int
+	ProviderName string
+	ProviderNPI  string
+}
+
+// DefaultInvoiceNumberFormat yields numbers such as "INV-2030-000042".
+const DefaultInvoiceNumberFormat = "INV-{yyyy}-{seq:6}"
+
+// ValidateInvoiceNumberFormat checks an invoice number format. It has the
+// placeholders of MRN formats.
+func ValidateInvoiceNumberFormat(format string) error {
+	return validateNumberFormat("invoice number", format)
+}
+
+type billingUseCase struct {
+	transactor      repository.Transactor
+	invoiceRepo     repository.InvoiceRepository
+	feeRepo         repository.FeeRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	intentRepo      repository.PaymentIntentRepository
+	settings        BillingSettings
+	now             func() time.Time
+}
+
+func NewBillingUseCase(
+	transactor repository.Transactor,
+	invoiceRepo repository.InvoiceRepository,
+	feeRepo repository.FeeRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	intentRepo repository.PaymentIntentRepository,
+	settings BillingSettings,
+) BillingUseCase {
+	if settings.NumberFormat == "" {
+		settings.NumberFormat = DefaultInvoiceNumberFormat
+	}
+	return &billingUseCase{
+		transactor:      transactor,
+		invoiceRepo:     invoiceRepo,
+		feeRepo:         feeRepo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		intentRepo:      intentRepo,
+		settings:        settings,
+		now:             time.Now,
+	}
+}
+
+func (uc *billingUseCase) ListFees(ctx context.Context) ([]domain.Fee, error) {
+	return uc.feeRepo.List(ctx)
+}
+
+func (uc *billingUseCase) SetFee(ctx context.Context, fee *domain.Fee) error {
+	fee.AppointmentType = strings.TrimSpace(fee.AppointmentType)
+	fee.Description = strings.TrimSpace(fee.Description)
+	fee.Code = strings.ToUpper(strings.TrimSpace(fee.Code))
+	fee.TaxRate = math.Round(fee.TaxRate*100) / 100
+	if err := validateStruct(fee); err != nil {
+		return err
+	}
+	return uc.feeRepo.Save(ctx, fee)
+}
+
+func (uc *billingUseCase) DeleteFee(ctx context.Context, appointmentType string) error {
+	return uc.feeRepo.DeleteByType(ctx, appointmentType)
+}
+
+func (uc *billingUseCase) DraftInvoice(ctx context.Context, appointment *domain.Appointment) error {
+	existing, err := uc.invoiceRepo.FindByAppointment(ctx, appointment.ID)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	appointmentID := appointment.ID
+	invoice := &domain.Invoice{
+		PatientID:     appointment.PatientID,
+		AppointmentID: &appointmentID,
+		Status:        domain.InvoiceDraft,
+		Currency:      uc.settings.Currency,
+		Lines:         []domain.InvoiceLine{},
+	}
+	// An appointment without a priced type gets an empty draft for billing
+	// staff to fill in.
+	if appointment.Type != "" {
+		fee, err := uc.feeRepo.FindByType(ctx, appointment.Type)
+		if err != nil {
+			return fmt.Errorf("failed to get fee: %w", err)
+		}
+		if fee != nil {
+			invoice.Lines = append(invoice.Lines, domain.InvoiceLine{
+				Description:    fee.Description,
+				Code:           fee.Code,
+				Quantity:       1,
+				UnitPriceCents: fee.UnitPriceCents,
+				TaxRate:        fee.TaxRate,
+			})
+		}
+	}
+	if err := computeInvoice(invoice); err != nil {
+		return err
+	}
+	return uc.invoiceRepo.Create(ctx, invoice)
+}
+
+func (uc *billingUseCase) CreateInvoice(ctx context.Context, invoice *domain.Invoice, actor *domain.User) error {
+	invoice.ID = 0
+	invoice.Number = ""
+	invoice.Status = domain.InvoiceDraft
+	invoice.Currency = uc.settings.Currency
+	invoice.PaidCents, invoice.AdjustedCents = 0, 0
+	invoice.Payments = nil
+	invoice.CreatedByUserID = actorID(actor)
+	invoice.IssuedAt, invoice.PaidAt, invoice.VoidedAt, invoice.VoidReason = nil, nil, nil, ""
+	if err := uc.validateInvoice(invoice); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := uc.patientRepo.GetByID(ctx, invoice.PatientID); errors.Is(err, domain.ErrNotFound) {
+			return domain.NewValidationError(domain.FieldError{Field: "patient_id", Message: "patient does not exist"})
+		} else if err != nil {
+			return err
+		}
+		if invoice.AppointmentID != nil {
+			appointment, err := uc.appointmentRepo.GetByID(ctx, *invoice.AppointmentID)
+			if errors.Is(err, domain.ErrNotFound) || (err == nil && appointment.PatientID != invoice.PatientID) {
+				return domain.NewValidationError(domain.FieldError{Field: "appointment_id", Message: "is not an appointment of the patient"})
+			} else if err != nil {
+				return err
+			}
+			existing, err := uc.invoiceRepo.FindByAppointment(ctx, appointment.ID)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return domain.NewConflictError("appointment_already_invoiced",
+					fmt.Sprintf("appointment %d is billed on invoice %d", appointment.ID, existing.ID))
+			}
+		}
+		return uc.invoiceRepo.Create(ctx, invoice)
+	})
+}
+
+func (uc *billingUseCase) GetInvoice(ctx context.Context, id uint) (*domain.Invoice, error) {
+	return uc.invoiceRepo.GetByID(ctx, id)
+}
+
+func (uc *billingUseCase) ListInvoices(ctx context.Context, search domain.InvoiceSearch) ([]domain.Invoice, int64, error) {
+	if search.Limit < 1 {
+		search.Limit = defaultSearchLimit
+	}
+	if search.Limit > maxSearchLimit {
+		search.Limit = maxSearchLimit
+	}
+	if search.Offset < 0 {
+		search.Offset = 0
+	}
+	return uc.invoiceRepo.List(ctx, search)
+}
+
+func (uc *billingUseCase) UpdateInvoice(ctx context.Context, invoice *domain.Invoice) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		existing, err := uc.invoiceRepo.GetForUpdate(ctx, invoice.ID)
+		if err != nil {
+			return err
+		}
+		if existing.Status != domain.InvoiceDraft {
+			return domain.NewConflictError("invoice_not_draft", "only draft invoices can be edited; void the invoice and bill again")
+		}
+
+		existing.Lines = invoice.Lines
+		existing.DueDate = invoice.DueDate
+		existing.Notes = invoice.Notes
+		if err := uc.validateInvoice(existing); err != nil {
+			return err
+		}
+		if err := uc.invoiceRepo.Update(ctx, existing, true); err != nil {
+			return err
+		}
+		*invoice = *existing
+		return nil
+	})
+}
+
+func (uc *billingUseCase) IssueInvoice(ctx context.Context, id uint) (*domain.Invoice, error) {
+	var invoice *domain.Invoice
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if invoice, err = uc.invoiceRepo.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if invoice.Status != domain.InvoiceDraft {
+			return domain.NewConflictError("invoice_not_draft", "the invoice was issued already")
+		}
+		if len(invoice.Lines) == 0 {
+			return domain.NewConflictError("invoice_empty", "add a line before issuing the invoice")
+		}
+
+		// Each tenant numbers its invoices on its own.
+		tenantID, err := uc.patientRepo.TenantOf(ctx, invoice.PatientID)
+		if err != nil {
+			return err
+		}
+		seq, err := uc.invoiceRepo.NextNumber(ctx, tenantScope(tenantID))
+		if err != nil {
+			return fmt.Errorf("failed to number invoice: %w", err)
+		}
+		now := uc.now()
+		invoice.Number = formatNumber(uc.settings.NumberFormat, seq, now)
+		invoice.Status = domain.InvoiceIssued
+		invoice.IssuedAt = &now
+		if invoice.DueDate == "" {
+			invoice.DueDate = now.AddDate(0, 0, uc.settings.DueDays).Format("2006-01-02")
+		}
+		uc.settle(invoice)
+		if err := uc.invoiceRepo.Update(ctx, invoice, false); err != nil {
+			return err
+		}
+		return uc.applyDeposits(ctx, invoice)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (uc *billingUseCase) ApplyDeposits(ctx context.Context, invoiceID uint) (*domain.Invoice, error) {
+	var invoice *domain.Invoice
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if invoice, err = uc.invoiceRepo.GetForUpdate(ctx, invoiceID); err != nil {
+			return err
+		}
+		return uc.applyDeposits(ctx, invoice)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// applyDeposits posts the unapplied deposits of the invoice's appointment,
+// up to the balance. A deposit worth more than the balance is posted in
+// part, and the rest noted on it for staff to refund.
+func (uc *billingUseCase) applyDeposits(ctx context.Context, invoice *domain.Invoice) error {
+	if invoice.AppointmentID == nil || invoice.Status != domain.InvoiceIssued {
+		return nil
+	}
+	deposits, err := uc.intentRepo.ListUnappliedDeposits(ctx, *invoice.AppointmentID)
+	if err != nil || len(deposits) == 0 {
+		return err
+	}
+
+	for i := range deposits {
+		deposit := &deposits[i]
+		amount := deposit.AmountCents
+		if amount > invoice.BalanceCents {
+			amount = invoice.BalanceCents
+		}
+		if amount <= 0 {
+			break
+		}
+
+		payment := &domain.Payment{
+			InvoiceID:   invoice.ID,
+			Kind:        domain.PaymentKindPayment,
+			AmountCents: amount,
+			Method:      domain.PaymentMethodCard,
+			Reference:   deposit.ProviderIntentID,
+			Note:        "Appointment deposit",
+		}
+		if deposit.SucceededAt != nil {
+			payment.ReceivedAt = *deposit.SucceededAt
+		}
+		if err := uc.recordMovement(ctx, invoice, payment, nil); err != nil {
+			return err
+		}
+		invoice.PaidCents += amount
+		uc.settle(invoice)
+
+		deposit.InvoiceID = &invoice.ID
+		deposit.PaymentID = &payment.ID
+		if amount < deposit.AmountCents {
+			deposit.FailureReason = fmt.Sprintf("%s of the deposit was more than the invoice and was not posted",
+				formatCents(deposit.AmountCents-amount))
+		}
+		if err := uc.intentRepo.Update(ctx, deposit); err != nil {
+			return err
+		}
+	}
+	return uc.invoiceRepo.Update(ctx, invoice, false)
+}
+
+func (uc *billingUseCase) VoidInvoice(ctx context.Context, id uint, reason string) (*domain.Invoice, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "reason", Message: "is required"})
+	}
+
+	var invoice *domain.Invoice
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if invoice, err = uc.invoiceRepo.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if invoice.Status == domain.InvoiceVoid {
+			return domain.NewConflictError("invoice_void", "the invoice is void already")
+		}
+		if invoice.PaidCents != 0 {
+			return domain.NewConflictError("invoice_has_payments", "refund the payments before voiding the invoice")
+		}
+
+		now := uc.now()
+		invoice.Status = domain.InvoiceVoid
+		invoice.VoidReason = reason
+		invoice.VoidedAt = &now
+		invoice.BalanceCents = 0
+		return uc.invoiceRepo.Update(ctx, invoice, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (uc *billingUseCase) RecordPayment(ctx context.Context, payment *domain.Payment, actor *domain.User) (*domain.Invoice, error) {
+	payment.ID = 0
+	payment.Kind = domain.PaymentKindPayment
+	payment.RefundOfID = nil
+	if err := validateStruct(payment); err != nil {
+		return nil, err
+	}
+
+	var invoice *domain.Invoice
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if invoice, err = uc.invoiceRepo.GetForUpdate(ctx, payment.InvoiceID); err != nil {
+			return err
+		}
+		switch invoice.Status {
+		case domain.InvoiceDraft:
+			return domain.NewConflictError("invoice_not_issued", "issue the invoice before taking payments")
+		case domain.InvoicePaid:
+			return domain.NewConflictError("invoice_paid", "the invoice is paid in full")
+		case domain.InvoiceVoid:
+			return domain.NewConflictError("invoice_void", "the invoice is void")
+		}
+		if payment.AmountCents > invoice.BalanceCents {
+			return domain.NewConflictError("payment_exceeds_balance",
+				fmt.Sprintf("the payment is more than the balance of %s", formatCents(invoice.BalanceCents)))
+		}
+
+		if err := uc.recordMovement(ctx, invoice, payment, actor); err != nil {
+			return err
+		}
+		invoice.PaidCents += payment.AmountCents
+		uc.settle(invoice)
+		return uc.invoiceRepo.Update(ctx, invoice, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (uc *billingUseCase) RefundPayment(ctx context.Context, refund *domain.Payment, actor *domain.User) (*domain.Invoice, error) {
+	refund.ID = 0
+	refund.Kind = domain.PaymentKindRefund
+	if refund.RefundOfID == nil {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "payment_id", Message: "is required"})
+	}
+
+	var invoice *domain.Invoice
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if invoice, err = uc.invoiceRepo.GetForUpdate(ctx, refund.InvoiceID); err != nil {
+			return err
+		}
+
+		var original *domain.Payment
+		var refunded int64
+		for i, p := range invoice.Payments {
+			if p.ID == *refund.RefundOfID && p.Kind == domain.PaymentKindPayment {
+				original = &invoice.Payments[i]
+			}
+			if p.Kind == domain.PaymentKindRefund && p.RefundOfID != nil && *p.RefundOfID == *refund.RefundOfID {
+				refunded += p.AmountCents
+			}
+		}
+		if original == nil {
+			return domain.NewValidationError(domain.FieldError{Field: "payment_id", Message: "is not a payment of the invoice"})
+		}
+		if refund.Method == "" {
+			refund.Method = original.Method
+		}
+		if err := validateStruct(refund); err != nil {
+			return err
+		}
+		if left := original.AmountCents - refunded; refund.AmountCents > left {
+			return domain.NewConflictError("refund_exceeds_payment",
+				fmt.Sprintf("only %s of payment %d is left to refund", formatCents(left), original.ID))
+		}
+
+		if err := uc.recordMovement(ctx, invoice, refund, actor); err != nil {
+			return err
+		}
+		invoice.PaidCents -= refund.AmountCents
+		uc.settle(invoice)
+		return uc.invoiceRepo.Update(ctx, invoice, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (uc *billingUseCase) PostInsurance(ctx context.Context, posting *domain.InsurancePosting) (*domain.Invoice, error) {
+	var invoice *domain.Invoice
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if invoice, err = uc.invoiceRepo.GetForUpdate(ctx, posting.InvoiceID); err != nil {
+			return err
+		}
+		switch invoice.Status {
+		case domain.InvoiceDraft:
+			return domain.NewConflictError("invoice_not_issued", "issue the invoice before taking payments")
+		case domain.InvoiceVoid:
+			return domain.NewConflictError("invoice_void", "the invoice is void")
+		}
+
+		// The payment is posted first; the adjustment writes off what it
+		// leaves of the balance.
+		posting.PaidCents = upTo(posting.PaidCents, invoice.BalanceCents)
+		if posting.PaidCents > 0 {
+			payment := &domain.Payment{
+				InvoiceID:   invoice.ID,
+				Kind:        domain.PaymentKindPayment,
+				AmountCents: posting.PaidCents,
+				Method:      domain.PaymentMethodInsurance,
+				Reference:   posting.Reference,
+				Note:        posting.Note,
+				ReceivedAt:  posting.ReceivedAt,
+			}
+			if err := uc.recordMovement(ctx, invoice, payment, nil); err != nil {
+				return err
+			}
+			invoice.PaidCents += payment.AmountCents
+			uc.settle(invoice)
+		}
+		posting.AdjustedCents = upTo(posting.AdjustedCents, invoice.BalanceCents)
+		if posting.AdjustedCents > 0 {
+			adjustment := &domain.Payment{
+				InvoiceID:   invoice.ID,
+				Kind:        domain.PaymentKindAdjustment,
+				AmountCents: posting.AdjustedCents,
+				Method:      domain.PaymentMethodInsurance,
+				Reference:   posting.Reference,
+				Note:        posting.Note,
+				ReceivedAt:  posting.ReceivedAt,
+			}
+			if err := uc.recordMovement(ctx, invoice, adjustment, nil); err != nil {
+				return err
+			}
+			invoice.AdjustedCents += adjustment.AmountCents
+			uc.settle(invoice)
+		}
+		return uc.invoiceRepo.Update(ctx, invoice, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// upTo limits an amount to [0, limit].
+func upTo(amount, limit int64) int64 {
+	if amount > limit {
+		amount = limit
+	}
+	if amount < 0 {
+		return 0
+	}
+	return amount
+}
+
+// recordMovement stores a payment, refund or adjustment of an invoice.
+func (uc *billingUseCase) recordMovement(ctx context.Context, invoice *domain.Invoice, payment *domain.Payment, actor *domain.User) error {
+	payment.PatientID = invoice.PatientID
+	payment.RecordedByUserID = actorID(actor)
+	if payment.ReceivedAt.IsZero() {
+		payment.ReceivedAt = uc.now()
+	}
+	if err := uc.invoiceRepo.CreatePayment(ctx, payment); err != nil {
+		return err
+	}
+	invoice.Payments = append(invoice.Payments, *payment)
+	return nil
+}
+
+// settle updates the balance of an issued invoice, and whether it is paid.
+func (uc *billingUseCase) settle(invoice *domain.Invoice) {
+	invoice.BalanceCents = invoice.TotalCents - invoice.PaidCents - invoice.AdjustedCents
+	switch {
+	case invoice.Status == domain.InvoiceIssued && invoice.BalanceCents <= 0:
+		now := uc.now()
+		invoice.Status = domain.InvoicePaid
+		invoice.PaidAt = &now
+	case invoice.Status == domain.InvoicePaid && invoice.BalanceCents > 0:
+		invoice.Status = domain.InvoiceIssued
+		invoice.PaidAt = nil
+	}
+}
+
+func (uc *billingUseCase) PatientBalance(ctx context.Context, patientID uint) (*domain.PatientBalance, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	invoices, err := uc.invoiceRepo.ListOpen(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &domain.PatientBalance{PatientID: patientID, Currency: uc.settings.Currency, Invoices: invoices}
+	for _, invoice := range invoices {
+		balance.BalanceCents += invoice.BalanceCents
+	}
+	return balance, nil
+}
+
+// validateInvoice normalizes an invoice, computes its totals and checks it.
+func (uc *billingUseCase) validateInvoice(invoice *domain.Invoice) error {
+	invoice.DueDate = strings.TrimSpace(invoice.DueDate)
+	invoice.Notes = strings.TrimSpace(invoice.Notes)
+	if invoice.Lines == nil {
+		invoice.Lines = []domain.InvoiceLine{}
+	}
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		line.Description = strings.TrimSpace(line.Description)
+		line.Code = strings.ToUpper(strings.TrimSpace(line.Code))
+		if line.Quantity == 0 {
+			line.Quantity = 1
+		}
+	}
+	if err := validateStruct(invoice); err != nil {
+		return err
+	}
+	return computeInvoice(invoice)
+}
+
+// computeInvoice works out the amounts of the lines and the totals.
+// Taxes are rounded per line, half away from zero.
+func computeInvoice(invoice *domain.Invoice) error {
+	var fields []domain.FieldError
+	invoice.SubtotalCents, invoice.DiscountCents, invoice.TaxCents, invoice.TotalCents = 0, 0, 0, 0
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		gross := int64(line.Quantity) * line.UnitPriceCents
+		if line.DiscountCents > gross {
+			fields = append(fields, domain.FieldError{Field: fmt.Sprintf("lines[%d].discount_cents", i), Message: "must not exceed the line amount"})
+			continue
+		}
+		net := gross - line.DiscountCents
+		line.TaxRate = math.Round(line.TaxRate*100) / 100
+		line.TaxCents = int64(math.Round(float64(net) * line.TaxRate / 100))
+		line.TotalCents = net + line.TaxCents
+
+		invoice.SubtotalCents += gross
+		invoice.DiscountCents += line.DiscountCents
+		invoice.TaxCents += line.TaxCents
+		invoice.TotalCents += line.TotalCents
+	}
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+	invoice.BalanceCents = invoice.TotalCents - invoice.PaidCents - invoice.AdjustedCents
+	return nil
+}
+
+// formatCents writes an amount of cents as "1,234.56".
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	units := strconv.FormatInt(cents/100, 10)
+	for i := len(units) - 3; i > 0; i -= 3 {
+		units = units[:i] + "," + units[i:]
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, units, cents%100)
+}
+
+func (uc *billingUseCase) RenderInvoice(ctx context.Context, id uint) ([]byte, error) {
+	invoice, err := uc.invoiceRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := uc.patientRepo.GetByID(ctx, invoice.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	return invoicePDF(invoice, patient, uc.settings), nil
+}
+
+// invoicePDF lays out an invoice, continuing its lines on further pages.
+func invoicePDF(invoice *domain.Invoice, patient *domain.Patient, settings BillingSettings) []byte {
+	const left, right = 72.0, pdf.PageWidth - 72
+	const bottom = pdf.PageHeight - 72
+	title := "Invoice " + invoice.Number
+	if invoice.Number == "" {
+		title = fmt.Sprintf("Draft invoice %d", invoice.ID)
+	}
+	doc := pdf.New(title)
+	doc.Created = invoice.CreatedAt
+	page := doc.AddPage()
+
+	y := 80.0
+	page.Text(left, y, pdf.Bold, 16, settings.ProviderName)
+	page.TextRight(right, y, pdf.Bold, 14, title)
+	y += 16
+	if settings.ProviderNPI != "" {
+		page.Text(left, y, pdf.Regular, 10, "NPI "+settings.ProviderNPI)
+	}
+	if invoice.IssuedAt != nil {
+		page.TextRight(right, y, pdf.Regular, 10, "Issued "+invoice.IssuedAt.Format("January 2, 2006"))
+	}
+	if invoice.DueDate != "" {
+		if due, err := time.Parse("2006-01-02", invoice.DueDate); err == nil {
+			page.TextRight(right, y+14, pdf.Regular, 10, "Due "+due.Format("January 2, 2006"))
+		}
+	}
+	y += 26
+	page.Line(left, y, right, y, 1)
+
+	y += 24
+	page.Text(left, y, pdf.Bold, 11, "Bill to")
+	y += 16
+	page.Text(left, y, pdf.Regular, 11, patient.Name)
+	address := patient.Address
+	for _, line := range []string{
+		address.Line1,
+		address.Line2,
+		strings.TrimSpace(strings.Join(nonEmpty(address.City, address.Region, address.PostalCode), " ")),
+		labeled("MRN: ", patient.MRN),
+	} {
+		if line != "" {
+			y += 14
+			page.Text(left, y, pdf.Regular, 10, line)
+		}
+	}
+
+	// Columns are right-aligned at these positions, after the description.
+	columns := []struct {
+		x     float64
+		title string
+	}{{290, "Code"}, {325, "Qty"}, {390, "Unit price"}, {445, "Discount"}, {485, "Tax"}, {right, "Amount"}}
+	header := func() {
+		page.Text(left, y, pdf.Bold, 10, "Description")
+		for _, column := range columns {
+			page.TextRight(column.x, y, pdf.Bold, 10, column.title)
+		}
+		y += 6
+		page.Line(left, y, right, y, 0.5)
+		y += 16
+	}
+
+	y += 36
+	header()
+	for _, line := range invoice.Lines {
+		description := pdf.Wrap(pdf.Regular, 10, columns[0].x-left-40, line.Description)
+		if y+float64(len(description))*13 > bottom {
+			page = doc.AddPage()
+			y = 80
+			header()
+		}
+		page.TextRight(columns[0].x, y, pdf.Regular, 10, line.Code)
+		page.TextRight(columns[1].x, y, pdf.Regular, 10, strconv.Itoa(line.Quantity))
+		page.TextRight(columns[2].x, y, pdf.Regular, 10, formatCents(line.UnitPriceCents))
+		if line.DiscountCents > 0 {
+			page.TextRight(columns[3].x, y, pdf.Regular, 10, "-"+formatCents(line.DiscountCents))
+		}
+		if line.TaxCents > 0 {
+			page.TextRight(columns[4].x, y, pdf.Regular, 10, formatCents(line.TaxCents))
+		}
+		page.TextRight(columns[5].x, y, pdf.Regular, 10, formatCents(line.TotalCents))
+		for _, text := range description {
+			page.Text(left, y, pdf.Regular, 10, text)
+			y += 13
+		}
+		y += 3
+	}
+
+	totals := [][2]string{
+		{"Subtotal", formatCents(invoice.SubtotalCents)},
+		{"Discounts", "-" + formatCents(invoice.DiscountCents)},
+		{"Tax", formatCents(invoice.TaxCents)},
+		{"Total " + invoice.Currency, formatCents(invoice.TotalCents)},
+		{"Paid", formatCents(invoice.PaidCents)},
+	}
+	if invoice.AdjustedCents != 0 {
+		totals = append(totals, [2]string{"Insurance adjustments", "-" + formatCents(invoice.AdjustedCents)})
+	}
+	totals = append(totals, [2]string{"Balance due " + invoice.Currency, formatCents(invoice.BalanceCents)})
+	if y+float64(len(totals))*15+40 > bottom {
+		page = doc.AddPage()
+		y = 80
+	}
+	page.Line(left, y, right, y, 0.5)
+	y += 18
+	for i, total := range totals {
+		font := pdf.Regular
+		if i == 3 || i == len(totals)-1 {
+			font = pdf.Bold
+		}
+		page.TextRight(420, y, font, 10, total[0])
+		page.TextRight(right, y, font, 10, total[1])
+		y += 15
+	}
+
+	if invoice.Status == domain.InvoiceVoid && invoice.VoidedAt != nil {
+		y += 16
+		page.Text(left, y, pdf.Bold, 14, "VOID "+invoice.VoidedAt.Format("January 2, 2006"))
+		for _, line := range pdf.Wrap(pdf.Regular, 10, right-left, invoice.VoidReason) {
+			y += 14
+			page.Text(left, y, pdf.Regular, 10, line)
+		}
+	}
+	if invoice.Notes != "" {
+		y += 24
+		for _, line := range pdf.Wrap(pdf.Regular, 10, right-left, invoice.Notes) {
+			if y > bottom {
+				page = doc.AddPage()
+				y = 80
+			}
+			page.Text(left, y, pdf.Regular, 10, line)
+			y += 13
+		}
+	}
+	return doc.Bytes()
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,499 @@
+// internal/usecase/billing_usecase_test.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+var billingNow = time.Date(2030, 3, 14, 9, 30, 0, 0, time.UTC)
+
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeInvoiceRepo holds one invoice. Methods the billing use case doesn't
+// call panic through the nil embedded interface.
+type fakeInvoiceRepo struct {
+	repository.InvoiceRepository
+	invoice  *domain.Invoice
+	payments []domain.Payment
+	numbers  map[string]int64
+}
+
+func (r *fakeInvoiceRepo) GetForUpdate(ctx context.Context, id uint) (*domain.Invoice, error) {
+	if r.invoice == nil || r.invoice.ID != id {
+		return nil, domain.NewNotFoundError("invoice", id)
+	}
+	return r.invoice, nil
+}
+
+func (r *fakeInvoiceRepo) Update(ctx context.Context, invoice *domain.Invoice, lines bool) error {
+	r.invoice = invoice
+	return nil
+}
+
+func (r *fakeInvoiceRepo) CreatePayment(ctx context.Context, payment *domain.Payment) error {
+	payment.ID = uint(100 + len(r.payments))
+	r.payments = append(r.payments, *payment)
+	return nil
+}
+
+func (r *fakeInvoiceRepo) NextNumber(ctx context.Context, scope string) (int64, error) {
+	if r.numbers == nil {
+		r.numbers = map[string]int64{}
+	}
+	r.numbers[scope]++
+	return r.numbers[scope], nil
+}
+
+type fakeBillingPatientRepo struct {
+	repository.PatientRepository
+}
+
+func (fakeBillingPatientRepo) TenantOf(ctx context.Context, id uint) (uint, error) {
+	return domain.DefaultTenantID, nil
+}
+
+type fakeIntentRepo struct {
+	repository.PaymentIntentRepository
+	deposits []domain.PaymentIntent
+	updated  []domain.PaymentIntent
+}
+
+func (r *fakeIntentRepo) ListUnappliedDeposits(ctx context.Context, appointmentID uint) ([]domain.PaymentIntent, error) {
+	var out []domain.PaymentIntent
+	for _, d := range r.deposits {
+		if d.AppointmentID != nil && *d.AppointmentID == appointmentID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeIntentRepo) Update(ctx context.Context, intent *domain.PaymentIntent) error {
+	r.updated = append(r.updated, *intent)
+	return nil
+}
+
+func newTestBilling(invoice *domain.Invoice, deposits ...domain.PaymentIntent) (*billingUseCase, *fakeInvoiceRepo, *fakeIntentRepo) {
+	invoices := &fakeInvoiceRepo{invoice: invoice}
+	intents := &fakeIntentRepo{deposits: deposits}
+	uc := NewBillingUseCase(fakeTransactor{}, invoices, nil, fakeBillingPatientRepo{}, nil, intents,
+		BillingSettings{Currency: "USD", DueDays: 30}).(*billingUseCase)
+	uc.now = func() time.Time { return billingNow }
+	return uc, invoices, intents
+}
+
+// errorCode returns the code of a domain error, or "" for any other.
+func errorCode(err error) string {
+	var derr *domain.Error
+	if errors.As(err, &derr) {
+		return derr.Code
+	}
+	return ""
+}
+
+func TestComputeInvoice(t *testing.T) {
+	tests := []struct {
+		name    string
+		invoice domain.Invoice
+		want    domain.Invoice
+		lines   [][2]int64 // tax and total of each line
+		field   string
+	}{
+		{
+			name: "discount comes off before tax",
+			invoice: domain.Invoice{Lines: []domain.InvoiceLine{
+				{Quantity: 2, UnitPriceCents: 1000, DiscountCents: 150, TaxRate: 8.25},
+			}},
+			want:  domain.Invoice{SubtotalCents: 2000, DiscountCents: 150, TaxCents: 153, TotalCents: 2003, BalanceCents: 2003},
+			lines: [][2]int64{{153, 2003}},
+		},
+		{
+			name: "tax is rounded per line half away from zero",
+			invoice: domain.Invoice{Lines: []domain.InvoiceLine{
+				{Quantity: 1, UnitPriceCents: 10, TaxRate: 5},
+				{Quantity: 1, UnitPriceCents: 10, TaxRate: 5},
+				{Quantity: 1, UnitPriceCents: 10, TaxRate: 4.9},
+			}},
+			want:  domain.Invoice{SubtotalCents: 30, TaxCents: 2, TotalCents: 32, BalanceCents: 32},
+			lines: [][2]int64{{1, 11}, {1, 11}, {0, 10}},
+		},
+		{
+			name: "tax rate is kept to two decimals",
+			invoice: domain.Invoice{Lines: []domain.InvoiceLine{
+				{Quantity: 1, UnitPriceCents: 100000, TaxRate: 8.254},
+			}},
+			want:  domain.Invoice{SubtotalCents: 100000, TaxCents: 8250, TotalCents: 108250, BalanceCents: 108250},
+			lines: [][2]int64{{8250, 108250}},
+		},
+		{
+			name: "balance is net of payments and adjustments",
+			invoice: domain.Invoice{PaidCents: 300, AdjustedCents: 200, Lines: []domain.InvoiceLine{
+				{Quantity: 1, UnitPriceCents: 1000},
+			}},
+			want:  domain.Invoice{SubtotalCents: 1000, TotalCents: 1000, PaidCents: 300, AdjustedCents: 200, BalanceCents: 500},
+			lines: [][2]int64{{0, 1000}},
+		},
+		{
+			name: "discount over the line amount",
+			invoice: domain.Invoice{Lines: []domain.InvoiceLine{
+				{Quantity: 1, UnitPriceCents: 1000},
+				{Quantity: 2, UnitPriceCents: 100, DiscountCents: 201},
+			}},
+			field: "lines[1].discount_cents",
+		},
+		{
+			name:    "no lines",
+			invoice: domain.Invoice{Lines: []domain.InvoiceLine{}},
+			want:    domain.Invoice{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := tt.invoice
+			err := computeInvoice(&invoice)
+			if tt.field != "" {
+				var derr *domain.Error
+				if !errors.As(err, &derr) || derr.Kind != domain.KindValidation {
+					t.Fatalf("computeInvoice() error = %v, want a validation error", err)
+				}
+				if len(derr.Fields) != 1 || derr.Fields[0].Field != tt.field {
+					t.Errorf("computeInvoice() fields = %+v, want %s", derr.Fields, tt.field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("computeInvoice() error = %v", err)
+			}
+			got := [6]int64{invoice.SubtotalCents, invoice.DiscountCents, invoice.TaxCents, invoice.TotalCents, invoice.PaidCents, invoice.BalanceCents}
+			want := [6]int64{tt.want.SubtotalCents, tt.want.DiscountCents, tt.want.TaxCents, tt.want.TotalCents, tt.want.PaidCents, tt.want.BalanceCents}
+			if got != want {
+				t.Errorf("computeInvoice() subtotal, discount, tax, total, paid, balance = %v, want %v", got, want)
+			}
+			for i, line := range invoice.Lines {
+				if got := [2]int64{line.TaxCents, line.TotalCents}; got != tt.lines[i] {
+					t.Errorf("line %d tax, total = %v, want %v", i, got, tt.lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSettle(t *testing.T) {
+	paidAt := billingNow.Add(-time.Hour)
+	tests := []struct {
+		name        string
+		invoice     domain.Invoice
+		wantStatus  string
+		wantBalance int64
+		wantPaidAt  *time.Time
+	}{
+		{
+			name:       "issued invoice paid in full",
+			invoice:    domain.Invoice{Status: domain.InvoiceIssued, TotalCents: 1000, PaidCents: 1000},
+			wantStatus: domain.InvoicePaid, wantBalance: 0, wantPaidAt: &billingNow,
+		},
+		{
+			name:       "adjustment settles the rest",
+			invoice:    domain.Invoice{Status: domain.InvoiceIssued, TotalCents: 1000, PaidCents: 700, AdjustedCents: 300},
+			wantStatus: domain.InvoicePaid, wantBalance: 0, wantPaidAt: &billingNow,
+		},
+		{
+			name:       "issued invoice paid in part",
+			invoice:    domain.Invoice{Status: domain.InvoiceIssued, TotalCents: 1000, PaidCents: 400},
+			wantStatus: domain.InvoiceIssued, wantBalance: 600,
+		},
+		{
+			name:       "refund reopens a paid invoice",
+			invoice:    domain.Invoice{Status: domain.InvoicePaid, TotalCents: 1000, PaidCents: 600, PaidAt: &paidAt},
+			wantStatus: domain.InvoiceIssued, wantBalance: 400,
+		},
+		{
+			name:       "paid invoice stays paid",
+			invoice:    domain.Invoice{Status: domain.InvoicePaid, TotalCents: 1000, PaidCents: 1000, PaidAt: &paidAt},
+			wantStatus: domain.InvoicePaid, wantBalance: 0, wantPaidAt: &paidAt,
+		},
+		{
+			name:       "draft is never paid",
+			invoice:    domain.Invoice{Status: domain.InvoiceDraft},
+			wantStatus: domain.InvoiceDraft, wantBalance: 0,
+		},
+	}
+
+	uc, _, _ := newTestBilling(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := tt.invoice
+			uc.settle(&invoice)
+			if invoice.Status != tt.wantStatus || invoice.BalanceCents != tt.wantBalance {
+				t.Errorf("settle() status, balance = %s, %d, want %s, %d",
+					invoice.Status, invoice.BalanceCents, tt.wantStatus, tt.wantBalance)
+			}
+			switch {
+			case tt.wantPaidAt == nil && invoice.PaidAt != nil:
+				t.Errorf("settle() paid at = %v, want nil", *invoice.PaidAt)
+			case tt.wantPaidAt != nil && (invoice.PaidAt == nil || !invoice.PaidAt.Equal(*tt.wantPaidAt)):
+				t.Errorf("settle() paid at = %v, want %v", invoice.PaidAt, *tt.wantPaidAt)
+			}
+		})
+	}
+}
+
+func TestIssueInvoice(t *testing.T) {
+	appointmentID := uint(3)
+	succeededAt := billingNow.Add(-48 * time.Hour)
+	invoice := &domain.Invoice{
+		ID: 1, PatientID: 7, AppointmentID: &appointmentID, Status: domain.InvoiceDraft,
+		Lines:      []domain.InvoiceLine{{Quantity: 1, UnitPriceCents: 5000}},
+		TotalCents: 5000, BalanceCents: 5000,
+	}
+	deposit := domain.PaymentIntent{
+		ID: 9, AppointmentID: &appointmentID, AmountCents: 6000,
+		ProviderIntentID: "pi_1", SucceededAt: &succeededAt,
+	}
+	uc, invoices, intents := newTestBilling(invoice, deposit)
+
+	got, err := uc.IssueInvoice(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("IssueInvoice() error = %v", err)
+	}
+	if got.Number != "INV-2030-000001" {
+		t.Errorf("number = %q, want INV-2030-000001", got.Number)
+	}
+	if invoices.numbers["default"] != 1 {
+		t.Errorf("numbers = %v, want the default tenant counted once", invoices.numbers)
+	}
+	if got.DueDate != "2030-04-13" {
+		t.Errorf("due date = %q, want 2030-04-13", got.DueDate)
+	}
+	if got.Status != domain.InvoicePaid || got.PaidCents != 5000 || got.BalanceCents != 0 {
+		t.Errorf("status, paid, balance = %s, %d, %d, want paid, 5000, 0", got.Status, got.PaidCents, got.BalanceCents)
+	}
+
+	if len(invoices.payments) != 1 {
+		t.Fatalf("payments = %+v, want the deposit posted once", invoices.payments)
+	}
+	payment := invoices.payments[0]
+	if payment.AmountCents != 5000 || payment.Method != domain.PaymentMethodCard ||
+		payment.Reference != "pi_1" || !payment.ReceivedAt.Equal(succeededAt) {
+		t.Errorf("payment = %+v, want 5000 by card for pi_1 received when the deposit succeeded", payment)
+	}
+
+	if len(intents.updated) != 1 {
+		t.Fatalf("updated deposits = %+v, want one", intents.updated)
+	}
+	applied := intents.updated[0]
+	if applied.InvoiceID == nil || *applied.InvoiceID != 1 || applied.PaymentID == nil || *applied.PaymentID != payment.ID {
+		t.Errorf("deposit invoice, payment = %v, %v, want 1, %d", applied.InvoiceID, applied.PaymentID, payment.ID)
+	}
+	if want := "10.00 of the deposit was more than the invoice and was not posted"; applied.FailureReason != want {
+		t.Errorf("deposit failure reason = %q, want %q", applied.FailureReason, want)
+	}
+
+	if _, err := uc.IssueInvoice(context.Background(), 1); errorCode(err) != "invoice_not_draft" {
+		t.Errorf("IssueInvoice() again error = %v, want invoice_not_draft", err)
+	}
+}
+
+func TestIssueInvoiceEmpty(t *testing.T) {
+	uc, _, _ := newTestBilling(&domain.Invoice{ID: 1, Status: domain.InvoiceDraft})
+	if _, err := uc.IssueInvoice(context.Background(), 1); errorCode(err) != "invoice_empty" {
+		t.Errorf("IssueInvoice() error = %v, want invoice_empty", err)
+	}
+}
+
+func TestRecordPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		amount      int64
+		wantCode    string
+		wantStatus  string
+		wantBalance int64
+	}{
+		{name: "part payment", status: domain.InvoiceIssued, amount: 400, wantStatus: domain.InvoiceIssued, wantBalance: 600},
+		{name: "full payment", status: domain.InvoiceIssued, amount: 1000, wantStatus: domain.InvoicePaid, wantBalance: 0},
+		{name: "over the balance", status: domain.InvoiceIssued, amount: 1001, wantCode: "payment_exceeds_balance"},
+		{name: "draft", status: domain.InvoiceDraft, amount: 100, wantCode: "invoice_not_issued"},
+		{name: "paid", status: domain.InvoicePaid, amount: 100, wantCode: "invoice_paid"},
+		{name: "void", status: domain.InvoiceVoid, amount: 100, wantCode: "invoice_void"},
+		{name: "no amount", status: domain.InvoiceIssued, amount: 0, wantCode: "validation_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, invoices, _ := newTestBilling(&domain.Invoice{
+				ID: 1, PatientID: 7, Status: tt.status, TotalCents: 1000, BalanceCents: 1000,
+			})
+			payment := &domain.Payment{InvoiceID: 1, AmountCents: tt.amount, Method: domain.PaymentMethodCash}
+			got, err := uc.RecordPayment(context.Background(), payment, &domain.User{ID: 5})
+			if tt.wantCode != "" {
+				if errorCode(err) != tt.wantCode {
+					t.Errorf("RecordPayment() error = %v, want %s", err, tt.wantCode)
+				}
+				if len(invoices.payments) != 0 {
+					t.Errorf("payments = %+v, want none", invoices.payments)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RecordPayment() error = %v", err)
+			}
+			if got.Status != tt.wantStatus || got.BalanceCents != tt.wantBalance {
+				t.Errorf("status, balance = %s, %d, want %s, %d", got.Status, got.BalanceCents, tt.wantStatus, tt.wantBalance)
+			}
+			stored := invoices.payments[0]
+			if stored.PatientID != 7 || stored.RecordedByUserID == nil || *stored.RecordedByUserID != 5 ||
+				!stored.ReceivedAt.Equal(billingNow) {
+				t.Errorf("payment = %+v, want patient 7 recorded by user 5 now", stored)
+			}
+		})
+	}
+}
+
+func TestRefundPayment(t *testing.T) {
+	paymentID, refundedID := uint(1), uint(1)
+	invoice := func() *domain.Invoice {
+		return &domain.Invoice{
+			ID: 1, Status: domain.InvoicePaid, TotalCents: 1000, PaidCents: 1000,
+			Payments: []domain.Payment{
+				{ID: 1, Kind: domain.PaymentKindPayment, AmountCents: 600, Method: domain.PaymentMethodCard},
+				{ID: 2, Kind: domain.PaymentKindPayment, AmountCents: 700, Method: domain.PaymentMethodCash},
+				{ID: 3, Kind: domain.PaymentKindRefund, AmountCents: 300, RefundOfID: &refundedID},
+			},
+		}
+	}
+	tests := []struct {
+		name        string
+		refundOf    *uint
+		amount      int64
+		wantCode    string
+		wantStatus  string
+		wantBalance int64
+	}{
+		{name: "rest of a refunded payment", refundOf: &paymentID, amount: 300, wantStatus: domain.InvoiceIssued, wantBalance: 300},
+		{name: "more than is left", refundOf: &paymentID, amount: 301, wantCode: "refund_exceeds_payment"},
+		{name: "not a payment of the invoice", refundOf: func() *uint { id := uint(3); return &id }(), amount: 100, wantCode: "validation_failed"},
+		{name: "no payment", amount: 100, wantCode: "validation_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, invoices, _ := newTestBilling(invoice())
+			refund := &domain.Payment{InvoiceID: 1, AmountCents: tt.amount, RefundOfID: tt.refundOf}
+			got, err := uc.RefundPayment(context.Background(), refund, nil)
+			if tt.wantCode != "" {
+				if errorCode(err) != tt.wantCode {
+					t.Errorf("RefundPayment() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RefundPayment() error = %v", err)
+			}
+			if got.Status != tt.wantStatus || got.BalanceCents != tt.wantBalance || got.PaidAt != nil {
+				t.Errorf("status, balance, paid at = %s, %d, %v, want %s, %d, nil",
+					got.Status, got.BalanceCents, got.PaidAt, tt.wantStatus, tt.wantBalance)
+			}
+			if stored := invoices.payments[0]; stored.Kind != domain.PaymentKindRefund || stored.Method != domain.PaymentMethodCard {
+				t.Errorf("refund = %+v, want a card refund like the payment", stored)
+			}
+		})
+	}
+}
+
+func TestPostInsurance(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		paid         int64
+		adjusted     int64
+		wantPaid     int64
+		wantAdjusted int64
+		wantStatus   string
+		wantCode     string
+	}{
+		{
+			name:   "payment and adjustment within the balance",
+			status: domain.InvoiceIssued, paid: 500, adjusted: 100,
+			wantPaid: 500, wantAdjusted: 100, wantStatus: domain.InvoiceIssued,
+		},
+		{
+			name:   "adjustment capped at what the payment leaves",
+			status: domain.InvoiceIssued, paid: 600, adjusted: 500,
+			wantPaid: 600, wantAdjusted: 200, wantStatus: domain.InvoicePaid,
+		},
+		{
+			name:   "payment capped at the balance",
+			status: domain.InvoiceIssued, paid: 900, adjusted: 100,
+			wantPaid: 800, wantAdjusted: 0, wantStatus: domain.InvoicePaid,
+		},
+		{
+			name:   "negative amounts post nothing",
+			status: domain.InvoiceIssued, paid: -100, adjusted: -5,
+			wantPaid: 0, wantAdjusted: 0, wantStatus: domain.InvoiceIssued,
+		},
+		{name: "draft", status: domain.InvoiceDraft, paid: 100, wantCode: "invoice_not_issued"},
+		{name: "void", status: domain.InvoiceVoid, paid: 100, wantCode: "invoice_void"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, invoices, _ := newTestBilling(&domain.Invoice{
+				ID: 1, Status: tt.status, TotalCents: 1000, PaidCents: 200, BalanceCents: 800,
+			})
+			posting := &domain.InsurancePosting{InvoiceID: 1, PaidCents: tt.paid, AdjustedCents: tt.adjusted, Reference: "EFT-1"}
+			got, err := uc.PostInsurance(context.Background(), posting)
+			if tt.wantCode != "" {
+				if errorCode(err) != tt.wantCode {
+					t.Errorf("PostInsurance() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PostInsurance() error = %v", err)
+			}
+			if posting.PaidCents != tt.wantPaid || posting.AdjustedCents != tt.wantAdjusted {
+				t.Errorf("posted paid, adjusted = %d, %d, want %d, %d",
+					posting.PaidCents, posting.AdjustedCents, tt.wantPaid, tt.wantAdjusted)
+			}
+			if got.PaidCents != 200+tt.wantPaid || got.AdjustedCents != tt.wantAdjusted || got.Status != tt.wantStatus {
+				t.Errorf("invoice paid, adjusted, status = %d, %d, %s, want %d, %d, %s",
+					got.PaidCents, got.AdjustedCents, got.Status, 200+tt.wantPaid, tt.wantAdjusted, tt.wantStatus)
+			}
+			var movements int
+			for _, amount := range []int64{tt.wantPaid, tt.wantAdjusted} {
+				if amount > 0 {
+					movements++
+				}
+			}
+			if len(invoices.payments) != movements {
+				t.Errorf("movements = %+v, want %d", invoices.payments, movements)
+			}
+		})
+	}
+}
+
+func TestFormatCents(t *testing.T) {
+	tests := map[int64]string{
+		0:         "0.00",
+		5:         "0.05",
+		123456:    "1,234.56",
+		100000000: "1,000,000.00",
+		-250:      "-2.50",
+	}
+	for cents, want := range tests {
+		if got := formatCents(cents); got != want {
+			t.Errorf("formatCents(%d) = %q, want %q", cents, got, want)
+		}
+	}
+}
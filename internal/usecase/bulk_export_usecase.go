@@ -0,0 +1,403 @@
+// internal/usecase/bulk_export_usecase.go
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type BulkExportUseCase interface {
+	// StartExport accepts an export of the given level for the export
+	// worker. Types defaults to every type the level covers.
+	StartExport(ctx context.Context, export *domain.BulkExport, actor *domain.User) error
+	// GetExport returns an export until it expires.
+	GetExport(ctx context.Context, id uint) (*domain.BulkExport, error)
+	// CancelExport stops an export and deletes it with its files.
+	CancelExport(ctx context.Context, id uint) error
+	// RunPending runs accepted exports until there are none left and
+	// returns how many it ran.
+	RunPending(ctx context.Context) (int, error)
+	// PurgeExpired deletes expired exports with their files.
+	PurgeExpired(ctx context.Context) (int, error)
+	// SignFile returns the expiry and signature of a download link for a
+	// file of a completed export.
+	SignFile(export *domain.BulkExport, name string) (expires time.Time, signature string)
+	// OpenFile checks a signed download link and returns the path of the
+	// file it points to.
+	OpenFile(ctx context.Context, id uint, name string, expires int64, signature string) (string, error)
+}
+
+// BulkExportSettings say where export files are written and for how long
+// they and their download links are valid.
+type BulkExportSettings struct {
+	Dir string
+	// Retention is how long a finished export is kept.
+	Retention time.Duration
+	// LinkTTL is how long a download link is valid, at most until the
+	// export expires.
+	LinkTTL    time.Duration
+	SigningKey []byte
+}
+
+// bulkExportTypes are the FHIR resource types each export level covers.
+var bulkExportTypes = map[string][]string{
+	domain.BulkExportSystem:  {"Patient", "Practitioner", "Appointment"},
+	domain.BulkExportPatient: {"Patient", "Appointment"},
+}
+
+const (
+	// bulkExportBatch is how many resources are read and written at a time.
+	bulkExportBatch = 500
+	// bulkExportStaleAfter is how long an export in progress may go without
+	// progress before another worker takes it over.
+	bulkExportStaleAfter = 10 * time.Minute
+)
+
+type bulkExportUseCase struct {
+	exportRepo      repository.BulkExportRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	doctorRepo      repository.DoctorRepository
+	settings        BulkExportSettings
+	now             func() time.Time
+}
+
+func NewBulkExportUseCase(
+	exportRepo repository.BulkExportRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	settings BulkExportSettings,
+) BulkExportUseCase {
+	return &bulkExportUseCase{
+		exportRepo:      exportRepo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		doctorRepo:      doctorRepo,
+		settings:        settings,
+		now:             time.Now,
+	}
+}
+
+func (uc *bulkExportUseCase) StartExport(ctx context.Context, export *domain.BulkExport, actor *domain.User) error {
+	allowed, ok := bulkExportTypes[export.Level]
+	if !ok {
+		return fmt.Errorf("unknown bulk export level %q", export.Level)
+	}
+
+	var fields []domain.FieldError
+	if len(export.Types) == 0 {
+		export.Types = allowed
+	}
+	for _, resourceType := range export.Types {
+		if !contains(allowed, resourceType) {
+			fields = append(fields, domain.FieldError{Field: "_type", Message: resourceType + " can't be exported at this level"})
+		}
+	}
+	now := uc.now()
+	if export.Since != nil && export.Since.After(now) {
+		fields = append(fields, domain.FieldError{Field: "_since", Message: "must not be in the future"})
+	}
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	export.Status = domain.BulkExportAccepted
+	export.TransactionTime = now
+	export.Files = []domain.BulkExportFile{}
+	export.RequestedByUserID = actorID(actor)
+	export.TenantID = tenantOf(ctx)
+	return uc.exportRepo.Create(ctx, export)
+}
+
+func (uc *bulkExportUseCase) GetExport(ctx context.Context, id uint) (*domain.BulkExport, error) {
+	export, err := uc.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.ExpiresAt != nil && !export.ExpiresAt.After(uc.now()) {
+		return nil, domain.NewNotFoundError("bulk_export", id)
+	}
+	return export, nil
+}
+
+func (uc *bulkExportUseCase) CancelExport(ctx context.Context, id uint) error {
+	if _, err := uc.GetExport(ctx, id); err != nil {
+		return err
+	}
+	if err := uc.exportRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	// A worker running the export stops at its next batch and cleans up
+	// after itself; files of a finished export go now.
+	return os.RemoveAll(uc.exportDir(id))
+}
+
+func (uc *bulkExportUseCase) RunPending(ctx context.Context) (int, error) {
+	ran := 0
+	for {
+		export, err := uc.exportRepo.Claim(ctx, uc.now().Add(-bulkExportStaleAfter))
+		if err != nil {
+			return ran, err
+		}
+		if export == nil {
+			return ran, nil
+		}
+
+		if err := uc.run(ctx, export); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				// Cancelled while it ran.
+				_ = os.RemoveAll(uc.exportDir(export.ID))
+				continue
+			}
+			fmt.Printf("Failed to run bulk export %d: %v\n", export.ID, err)
+			_ = os.RemoveAll(uc.exportDir(export.ID))
+			if err := uc.finish(ctx, export, domain.BulkExportFailed, err.Error()); err != nil && !errors.Is(err, domain.ErrNotFound) {
+				return ran, err
+			}
+		}
+		ran++
+	}
+}
+
+// run writes an export's files, one per resource type that has changes.
+func (uc *bulkExportUseCase) run(ctx context.Context, export *domain.BulkExport) error {
+	ctx = WithTenant(ctx, export.TenantID)
+	since := time.Time{}
+	if export.Since != nil {
+		since = *export.Since
+	}
+	until := export.TransactionTime
+
+	export.Total = 0
+	for _, resourceType := range export.Types {
+		n, err := uc.count(ctx, resourceType, since, until)
+		if err != nil {
+			return err
+		}
+		export.Total += n
+	}
+	export.Written = 0
+	if err := uc.exportRepo.UpdateProgress(ctx, export.ID, export.Total, 0); err != nil {
+		return err
+	}
+
+	dir := uc.exportDir(export.ID)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	export.Files = []domain.BulkExportFile{}
+	for _, resourceType := range export.Types {
+		file, err := uc.writeFile(ctx, export, resourceType, since, until)
+		if err != nil {
+			return err
+		}
+		if file.Count > 0 {
+			export.Files = append(export.Files, file)
+		}
+	}
+	return uc.finish(ctx, export, domain.BulkExportCompleted, "")
+}
+
+func (uc *bulkExportUseCase) finish(ctx context.Context, export *domain.BulkExport, status, reason string) error {
+	now := uc.now()
+	expiresAt := now.Add(uc.settings.Retention)
+	export.Status = status
+	export.Error = reason
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	return uc.exportRepo.Finish(ctx, export)
+}
+
+// writeFile writes the resources of one type as NDJSON, recording
+// progress after every batch.
+func (uc *bulkExportUseCase) writeFile(ctx context.Context, export *domain.BulkExport, resourceType string, since, until time.Time) (domain.BulkExportFile, error) {
+	file := domain.BulkExportFile{Type: resourceType, Name: resourceType + ".ndjson"}
+	path := filepath.Join(uc.exportDir(export.ID), file.Name)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+
+	var afterID uint
+	for {
+		resources, lastID, err := uc.page(ctx, resourceType, since, until, afterID)
+		if err != nil {
+			return file, err
+		}
+		if len(resources) == 0 {
+			break
+		}
+		for _, resource := range resources {
+			if err := encoder.Encode(resource); err != nil {
+				return file, err
+			}
+		}
+		file.Count += len(resources)
+		export.Written += len(resources)
+		if err := uc.exportRepo.UpdateProgress(ctx, export.ID, export.Total, export.Written); err != nil {
+			return file, err
+		}
+		afterID = lastID
+	}
+
+	if err := w.Flush(); err != nil {
+		return file, err
+	}
+	if err := f.Close(); err != nil {
+		return file, err
+	}
+	if file.Count == 0 {
+		return file, os.Remove(path)
+	}
+	return file, nil
+}
+
+func (uc *bulkExportUseCase) count(ctx context.Context, resourceType string, since, until time.Time) (int, error) {
+	switch resourceType {
+	case "Patient":
+		n, err := uc.patientRepo.CountChanged(ctx, since, until)
+		return int(n), err
+	case "Appointment":
+		n, err := uc.appointmentRepo.CountChanged(ctx, since, until)
+		return int(n), err
+	case "Practitioner":
+		doctors, err := uc.changedDoctors(ctx, since, until, 0)
+		return len(doctors), err
+	}
+	return 0, fmt.Errorf("bulk export of %s is not supported", resourceType)
+}
+
+// page returns the next batch of resources of a type changed in (since,
+// until] after afterID, and the ID of the last one.
+func (uc *bulkExportUseCase) page(ctx context.Context, resourceType string, since, until time.Time, afterID uint) ([]interface{}, uint, error) {
+	var resources []interface{}
+	var lastID uint
+
+	switch resourceType {
+	case "Patient":
+		patients, err := uc.patientRepo.ListChanged(ctx, since, until, afterID, bulkExportBatch)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range patients {
+			resources = append(resources, FHIRPatient(&patients[i]))
+			lastID = patients[i].ID
+		}
+	case "Appointment":
+		appointments, err := uc.appointmentRepo.ListChanged(ctx, since, until, afterID, bulkExportBatch)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range appointments {
+			resources = append(resources, FHIRAppointment(&appointments[i]))
+			lastID = appointments[i].ID
+		}
+	case "Practitioner":
+		doctors, err := uc.changedDoctors(ctx, since, until, afterID)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range doctors {
+			resources = append(resources, FHIRPractitioner(&doctors[i]))
+			lastID = doctors[i].ID
+		}
+	default:
+		return nil, 0, fmt.Errorf("bulk export of %s is not supported", resourceType)
+	}
+	return resources, lastID, nil
+}
+
+// changedDoctors filters the practice's few doctors in memory.
+func (uc *bulkExportUseCase) changedDoctors(ctx context.Context, since, until time.Time, afterID uint) ([]domain.Doctor, error) {
+	doctors, err := uc.doctorRepo.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var changed []domain.Doctor
+	for _, doctor := range doctors {
+		if doctor.ID > afterID && doctor.UpdatedAt.After(since) && !doctor.UpdatedAt.After(until) {
+			changed = append(changed, doctor)
+		}
+	}
+	return changed, nil
+}
+
+func (uc *bulkExportUseCase) PurgeExpired(ctx context.Context) (int, error) {
+	exports, err := uc.exportRepo.ListExpired(ctx, uc.now())
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, export := range exports {
+		if err := os.RemoveAll(uc.exportDir(export.ID)); err != nil {
+			return purged, err
+		}
+		if err := uc.exportRepo.Delete(ctx, export.ID); err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (uc *bulkExportUseCase) SignFile(export *domain.BulkExport, name string) (time.Time, string) {
+	expires := uc.now().Add(uc.settings.LinkTTL).Truncate(time.Second)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expires) {
+		expires = export.ExpiresAt.Truncate(time.Second)
+	}
+	return expires, uc.signature(export.ID, name, expires.Unix())
+}
+
+func (uc *bulkExportUseCase) OpenFile(ctx context.Context, id uint, name string, expires int64, signature string) (string, error) {
+	if !hmac.Equal([]byte(signature), []byte(uc.signature(id, name, expires))) {
+		return "", domain.NewForbiddenError("the download link is not valid")
+	}
+	if uc.now().Unix() >= expires {
+		return "", domain.NewForbiddenError("the download link has expired")
+	}
+
+	export, err := uc.GetExport(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if export.Status == domain.BulkExportCompleted {
+		for _, file := range export.Files {
+			if file.Name == name {
+				return filepath.Join(uc.exportDir(id), name), nil
+			}
+		}
+	}
+	return "", domain.NewNotFoundError("bulk_export_file", name)
+}
+
+// signature authenticates a download link for a file until expires.
+func (uc *bulkExportUseCase) signature(id uint, name string, expires int64) string {
+	mac := hmac.New(sha256.New, uc.settings.SigningKey)
+	fmt.Fprintf(mac, "%d/%s/%d", id, name, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (uc *bulkExportUseCase) exportDir(id uint) string {
+	return filepath.Join(uc.settings.Dir, strconv.FormatUint(uint64(id), 10))
+}
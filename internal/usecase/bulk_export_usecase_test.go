@@ -0,0 +1,278 @@
+// internal/usecase/bulk_export_usecase_test.go
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeExportRepo hands out accepted exports in ID order.
+type fakeExportRepo struct {
+	repository.BulkExportRepository
+	exports  map[uint]*domain.BulkExport
+	progress [][2]int
+}
+
+func (r *fakeExportRepo) GetByID(ctx context.Context, id uint) (*domain.BulkExport, error) {
+	export, ok := r.exports[id]
+	if !ok {
+		return nil, domain.NewNotFoundError("bulk_export", id)
+	}
+	return export, nil
+}
+
+func (r *fakeExportRepo) Claim(ctx context.Context, staleBefore time.Time) (*domain.BulkExport, error) {
+	var next *domain.BulkExport
+	for _, export := range r.exports {
+		if export.Status == domain.BulkExportAccepted && (next == nil || export.ID < next.ID) {
+			next = export
+		}
+	}
+	if next != nil {
+		next.Status = domain.BulkExportInProgress
+	}
+	return next, nil
+}
+
+func (r *fakeExportRepo) UpdateProgress(ctx context.Context, id uint, total, written int) error {
+	r.progress = append(r.progress, [2]int{total, written})
+	return nil
+}
+
+func (r *fakeExportRepo) Finish(ctx context.Context, export *domain.BulkExport) error {
+	r.exports[export.ID] = export
+	return nil
+}
+
+// changedIn reports whether updated lies in (since, until].
+func changedIn(updated, since, until time.Time) bool {
+	return updated.After(since) && !updated.After(until)
+}
+
+type fakeExportPatientRepo struct {
+	repository.PatientRepository
+	patients []domain.Patient
+}
+
+func (r *fakeExportPatientRepo) CountChanged(ctx context.Context, since, until time.Time) (int64, error) {
+	list, err := r.ListChanged(ctx, since, until, 0, len(r.patients))
+	return int64(len(list)), err
+}
+
+func (r *fakeExportPatientRepo) ListChanged(ctx context.Context, since, until time.Time, afterID uint, limit int) ([]domain.Patient, error) {
+	var list []domain.Patient
+	for _, p := range r.patients {
+		if p.ID > afterID && changedIn(p.UpdatedAt, since, until) && len(list) < limit {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+type fakeExportAppointmentRepo struct {
+	repository.AppointmentRepository
+	appointments []domain.Appointment
+}
+
+func (r *fakeExportAppointmentRepo) CountChanged(ctx context.Context, since, until time.Time) (int64, error) {
+	list, err := r.ListChanged(ctx, since, until, 0, len(r.appointments))
+	return int64(len(list)), err
+}
+
+func (r *fakeExportAppointmentRepo) ListChanged(ctx context.Context, since, until time.Time, afterID uint, limit int) ([]domain.Appointment, error) {
+	var list []domain.Appointment
+	for _, a := range r.appointments {
+		if a.ID > afterID && changedIn(a.UpdatedAt, since, until) && len(list) < limit {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+type fakeExportDoctorRepo struct {
+	repository.DoctorRepository
+	doctors []domain.Doctor
+}
+
+func (r *fakeExportDoctorRepo) List(ctx context.Context, name string) ([]domain.Doctor, error) {
+	return r.doctors, nil
+}
+
+var exportSigningKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestBulkExport(t *testing.T, exports ...*domain.BulkExport) (*bulkExportUseCase, *fakeExportRepo) {
+	t.Helper()
+	repo := &fakeExportRepo{exports: map[uint]*domain.BulkExport{}}
+	for _, export := range exports {
+		repo.exports[export.ID] = export
+	}
+	old, recent := billingNow.Add(-48*time.Hour), billingNow.Add(-time.Hour)
+	patients := &fakeExportPatientRepo{patients: []domain.Patient{
+		{ID: 1, Name: "Jane Doe", UpdatedAt: old},
+		{ID: 2, Name: "John Roe", UpdatedAt: recent},
+		{ID: 3, Name: "Mary Major", UpdatedAt: billingNow.Add(time.Hour)},
+	}}
+	appointments := &fakeExportAppointmentRepo{appointments: []domain.Appointment{
+		{ID: 4, PatientID: 1, DoctorID: 9, DateTime: recent, Status: domain.AppointmentStatusScheduled, UpdatedAt: old},
+	}}
+	doctors := &fakeExportDoctorRepo{doctors: []domain.Doctor{{ID: 9, Name: "Dr. Who", UpdatedAt: old}}}
+
+	uc := NewBulkExportUseCase(repo, patients, appointments, doctors, BulkExportSettings{
+		Dir: t.TempDir(), Retention: 24 * time.Hour, LinkTTL: 10 * time.Minute, SigningKey: exportSigningKey,
+	}).(*bulkExportUseCase)
+	uc.now = func() time.Time { return billingNow }
+	return uc, repo
+}
+
+// readNDJSON returns the resource type and ID of each line of an export
+// file.
+func readNDJSON(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var resource struct{ ResourceType, ID string }
+		if err := json.Unmarshal(scanner.Bytes(), &resource); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, resource.ResourceType+"/"+resource.ID)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestRunPendingWritesNDJSON(t *testing.T) {
+	since := billingNow.Add(-24 * time.Hour)
+	uc, repo := newTestBulkExport(t,
+		&domain.BulkExport{ID: 1, Level: domain.BulkExportSystem, Types: []string{"Patient", "Practitioner", "Appointment"},
+			Status: domain.BulkExportAccepted, TransactionTime: billingNow},
+		&domain.BulkExport{ID: 2, Level: domain.BulkExportSystem, Types: []string{"Patient", "Practitioner"},
+			Since: &since, Status: domain.BulkExportAccepted, TransactionTime: billingNow},
+	)
+
+	ran, err := uc.RunPending(context.Background())
+	if err != nil {
+		t.Fatalf("RunPending() error = %v", err)
+	}
+	if ran != 2 {
+		t.Errorf("ran = %d, want 2", ran)
+	}
+
+	full := repo.exports[1]
+	wantFiles := []domain.BulkExportFile{
+		{Type: "Patient", Name: "Patient.ndjson", Count: 2},
+		{Type: "Practitioner", Name: "Practitioner.ndjson", Count: 1},
+		{Type: "Appointment", Name: "Appointment.ndjson", Count: 1},
+	}
+	if full.Status != domain.BulkExportCompleted || full.Total != 4 || full.Written != 4 || !reflect.DeepEqual(full.Files, wantFiles) {
+		t.Errorf("export = %s %d/%d %+v, want completed 4/4 %+v", full.Status, full.Written, full.Total, full.Files, wantFiles)
+	}
+	if want := billingNow.Add(24 * time.Hour); full.ExpiresAt == nil || !full.ExpiresAt.Equal(want) {
+		t.Errorf("expires at = %v, want %v", full.ExpiresAt, want)
+	}
+	dir := filepath.Join(uc.settings.Dir, "1")
+	if got := readNDJSON(t, filepath.Join(dir, "Patient.ndjson")); !reflect.DeepEqual(got, []string{"Patient/1", "Patient/2"}) {
+		t.Errorf("Patient.ndjson = %v, want the patients changed up to the transaction time", got)
+	}
+	if got := readNDJSON(t, filepath.Join(dir, "Appointment.ndjson")); !reflect.DeepEqual(got, []string{"Appointment/4"}) {
+		t.Errorf("Appointment.ndjson = %v", got)
+	}
+
+	incremental := repo.exports[2]
+	wantFiles = []domain.BulkExportFile{{Type: "Patient", Name: "Patient.ndjson", Count: 1}}
+	if incremental.Status != domain.BulkExportCompleted || !reflect.DeepEqual(incremental.Files, wantFiles) {
+		t.Errorf("incremental export = %s %+v, want completed %+v", incremental.Status, incremental.Files, wantFiles)
+	}
+	if _, err := os.Stat(filepath.Join(uc.settings.Dir, "2", "Practitioner.ndjson")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty Practitioner.ndjson: stat error = %v, want it removed", err)
+	}
+	wantProgress := [][2]int{{4, 0}, {4, 2}, {4, 3}, {4, 4}, {1, 0}, {1, 1}}
+	if !reflect.DeepEqual(repo.progress, wantProgress) {
+		t.Errorf("progress = %v, want %v", repo.progress, wantProgress)
+	}
+}
+
+func TestSignedDownloadLinks(t *testing.T) {
+	expiresAt := billingNow.Add(time.Hour)
+	completed := &domain.BulkExport{ID: 1, Status: domain.BulkExportCompleted, ExpiresAt: &expiresAt,
+		Files: []domain.BulkExportFile{{Type: "Patient", Name: "Patient.ndjson", Count: 2}}}
+	running := &domain.BulkExport{ID: 2, Status: domain.BulkExportInProgress}
+	uc, _ := newTestBulkExport(t, completed, running)
+	ctx := context.Background()
+
+	expires, signature := uc.SignFile(completed, "Patient.ndjson")
+	if want := billingNow.Add(10 * time.Minute); !expires.Equal(want) {
+		t.Errorf("SignFile() expires = %v, want now plus the link TTL %v", expires, want)
+	}
+	path, err := uc.OpenFile(ctx, 1, "Patient.ndjson", expires.Unix(), signature)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if want := filepath.Join(uc.settings.Dir, "1", "Patient.ndjson"); path != want {
+		t.Errorf("OpenFile() = %q, want %q", path, want)
+	}
+
+	runningExpires, runningSignature := uc.SignFile(running, "Patient.ndjson")
+	unlistedExpires, unlistedSignature := uc.SignFile(completed, "Practitioner.ndjson")
+	tests := []struct {
+		name      string
+		id        uint
+		file      string
+		expires   int64
+		signature string
+		now       time.Time
+		want      string
+	}{
+		{"tampered signature", 1, "Patient.ndjson", expires.Unix(), signature[:len(signature)-1] + "0", billingNow, "forbidden"},
+		{"another file", 1, "Appointment.ndjson", expires.Unix(), signature, billingNow, "forbidden"},
+		{"another export", 2, "Patient.ndjson", expires.Unix(), signature, billingNow, "forbidden"},
+		{"extended expiry", 1, "Patient.ndjson", expires.Add(time.Hour).Unix(), signature, billingNow, "forbidden"},
+		{"expired link", 1, "Patient.ndjson", expires.Unix(), signature, expires, "forbidden"},
+		{"export not completed", 2, "Patient.ndjson", runningExpires.Unix(), runningSignature, billingNow, "bulk_export_file_not_found"},
+		{"file not in export", 1, "Practitioner.ndjson", unlistedExpires.Unix(), unlistedSignature, billingNow, "bulk_export_file_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc.now = func() time.Time { return tt.now }
+			t.Cleanup(func() { uc.now = func() time.Time { return billingNow } })
+			if _, err := uc.OpenFile(ctx, tt.id, tt.file, tt.expires, tt.signature); errorCode(err) != tt.want {
+				t.Errorf("OpenFile() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignFileStopsAtExportExpiry(t *testing.T) {
+	expiresAt := billingNow.Add(5*time.Minute + 500*time.Millisecond)
+	export := &domain.BulkExport{ID: 1, Status: domain.BulkExportCompleted, ExpiresAt: &expiresAt,
+		Files: []domain.BulkExportFile{{Type: "Patient", Name: "Patient.ndjson", Count: 1}}}
+	uc, _ := newTestBulkExport(t, export)
+
+	expires, _ := uc.SignFile(export, "Patient.ndjson")
+	if want := billingNow.Add(5 * time.Minute); !expires.Equal(want) {
+		t.Errorf("SignFile() expires = %v, want the export expiry %v", expires, want)
+	}
+
+	// Once the export itself expires it is gone, whatever the link says.
+	uc.now = func() time.Time { return expiresAt }
+	later := expiresAt.Add(time.Minute).Unix()
+	if _, err := uc.OpenFile(context.Background(), 1, "Patient.ndjson", later, uc.signature(1, "Patient.ndjson", later)); errorCode(err) != "bulk_export_not_found" {
+		t.Errorf("OpenFile() error = %v, want bulk_export_not_found", err)
+	}
+}
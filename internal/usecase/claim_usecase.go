@@ -0,0 +1,677 @@
+// internal/usecase/claim_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/x12"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ClaimUseCase interface {
+	// CreateClaim bills a completed appointment to insurance: its coded
+	// diagnoses and procedures, priced from its issued invoice, claimed
+	// from the given policy, or the patient's primary policy in effect on
+	// the visit date when policyID is nil.
+	CreateClaim(ctx context.Context, appointmentID uint, policyID *uint, actor *domain.User) (*domain.Claim, error)
+	GetClaim(ctx context.Context, id uint) (*domain.Claim, error)
+	ListClaims(ctx context.Context, search domain.ClaimSearch) ([]domain.Claim, int64, error)
+	// SetClaimStatus records what the clearinghouse or payer said about a
+	// claim outside a remittance, e.g. that it was rejected.
+	SetClaimStatus(ctx context.Context, id uint, status, note string, actor *domain.User) (*domain.Claim, error)
+	// ExportClaims writes the claims waiting to be exported to an 837P
+	// file and marks them submitted.
+	ExportClaims(ctx context.Context, actor *domain.User) (*domain.ClaimFile, error)
+	// GetClaimFile returns a claim file with its content.
+	GetClaimFile(ctx context.Context, id uint) (*domain.ClaimFile, error)
+	ListClaimFiles(ctx context.Context, limit, offset int) ([]domain.ClaimFile, int64, error)
+	// ImportRemittance reads an 835 and posts its payments and adjustments
+	// to the invoices of the claims it covers.
+	ImportRemittance(ctx context.Context, data []byte, actor *domain.User) (*domain.Remittance, error)
+	GetRemittance(ctx context.Context, id uint) (*domain.Remittance, error)
+	ListRemittances(ctx context.Context, limit, offset int) ([]domain.Remittance, int64, error)
+}
+
+// ClaimSettings identify this practice as the billing provider of claims.
+type ClaimSettings struct {
+	SenderID      string
+	ReceiverID    string
+	Production    bool
+	ProviderName  string
+	ProviderNPI   string
+	ProviderTaxID string
+	// ProviderAddress is where payers send paper correspondence; Region is
+	// the two-letter state.
+	ProviderAddress domain.Address
+	ProviderPhone   string
+	// PlaceOfService is the CMS place of service code of visits, e.g. "11"
+	// for an office.
+	PlaceOfService string
+	// FilingIndicator is the kind of plan claims are filed to, e.g. "CI"
+	// for commercial insurance.
+	FilingIndicator string
+}
+
+const (
+	// claimScope names the claim number counter.
+	claimScope = "claim"
+	// claimFileScope names the 837P file counter. File control numbers
+	// start at claimFileControlBase so they never collide with those of
+	// eligibility inquiries, which are numbered by check ID.
+	claimFileScope       = "claim_file"
+	claimFileControlBase = 900000000
+	// maxClaimsPerFile bounds an export; the rest wait for the next one.
+	maxClaimsPerFile = 500
+)
+
+// claimTransitions are the status changes staff may record by hand.
+// Remittances also move claims to paid or denied.
+var claimTransitions = map[string][]string{
+	domain.ClaimReady:     {domain.ClaimVoid},
+	domain.ClaimSubmitted: {domain.ClaimReady, domain.ClaimAccepted, domain.ClaimRejected, domain.ClaimDenied, domain.ClaimVoid},
+	domain.ClaimAccepted:  {domain.ClaimRejected, domain.ClaimDenied, domain.ClaimVoid},
+	domain.ClaimRejected:  {domain.ClaimVoid},
+	domain.ClaimDenied:    {domain.ClaimVoid},
+}
+
+type claimUseCase struct {
+	transactor      repository.Transactor
+	claimRepo       repository.ClaimRepository
+	invoiceRepo     repository.InvoiceRepository
+	appointmentRepo repository.AppointmentRepository
+	patientRepo     repository.PatientRepository
+	insuranceRepo   repository.InsuranceRepository
+	codingRepo      repository.CodingRepository
+	billing         BillingUseCase
+	settings        ClaimSettings
+	now             func() time.Time
+}
+
+func NewClaimUseCase(
+	transactor repository.Transactor,
+	claimRepo repository.ClaimRepository,
+	invoiceRepo repository.InvoiceRepository,
+	appointmentRepo repository.AppointmentRepository,
+	patientRepo repository.PatientRepository,
+	insuranceRepo repository.InsuranceRepository,
+	codingRepo repository.CodingRepository,
+	billing BillingUseCase,
+	settings ClaimSettings,
+) ClaimUseCase {
+	if settings.PlaceOfService == "" {
+		settings.PlaceOfService = "11"
+	}
+	if settings.FilingIndicator == "" {
+		settings.FilingIndicator = "CI"
+	}
+	return &claimUseCase{
+		transactor:      transactor,
+		claimRepo:       claimRepo,
+		invoiceRepo:     invoiceRepo,
+		appointmentRepo: appointmentRepo,
+		patientRepo:     patientRepo,
+		insuranceRepo:   insuranceRepo,
+		codingRepo:      codingRepo,
+		billing:         billing,
+		settings:        settings,
+		now:             time.Now,
+	}
+}
+
+func (uc *claimUseCase) CreateClaim(ctx context.Context, appointmentID uint, policyID *uint, actor *domain.User) (*domain.Claim, error) {
+	var claim *domain.Claim
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		appointment, err := uc.appointmentRepo.GetByID(ctx, appointmentID)
+		if err != nil {
+			return err
+		}
+		if appointment.Status != domain.AppointmentStatusCompleted {
+			return domain.NewConflictError("appointment_not_completed", "only completed appointments can be claimed")
+		}
+		invoice, err := uc.invoiceRepo.FindByAppointment(ctx, appointment.ID)
+		if err != nil {
+			return err
+		}
+		if invoice == nil || invoice.Status == domain.InvoiceDraft {
+			return domain.NewConflictError("invoice_not_issued", "issue the appointment's invoice before claiming it")
+		}
+
+		serviceDate := appointment.DateTime.Format("2006-01-02")
+		policy, err := uc.coverage(ctx, appointment.PatientID, policyID, serviceDate)
+		if err != nil {
+			return err
+		}
+		diagnoses, err := uc.codingRepo.ListDiagnoses(ctx, appointment.ID)
+		if err != nil {
+			return err
+		}
+		procedures, err := uc.codingRepo.ListProcedures(ctx, appointment.ID)
+		if err != nil {
+			return err
+		}
+		if len(diagnoses) == 0 || len(procedures) == 0 {
+			return domain.NewConflictError("visit_not_coded", "record the visit's diagnoses and procedures before claiming it")
+		}
+		lines, err := claimLines(procedures, len(diagnoses), invoice)
+		if err != nil {
+			return err
+		}
+
+		seq, err := uc.invoiceRepo.NextNumber(ctx, claimScope)
+		if err != nil {
+			return fmt.Errorf("failed to number claim: %w", err)
+		}
+		claim = &domain.Claim{
+			Number:          fmt.Sprintf("CLM%08d", seq),
+			PatientID:       appointment.PatientID,
+			AppointmentID:   &appointment.ID,
+			InvoiceID:       invoice.ID,
+			PolicyID:        policy.ID,
+			PayerName:       policy.PayerName,
+			PayerID:         policy.PayerID,
+			ServiceDate:     serviceDate,
+			Diagnoses:       make([]string, len(diagnoses)),
+			Lines:           lines,
+			Status:          domain.ClaimReady,
+			CreatedByUserID: actorID(actor),
+		}
+		for i, diagnosis := range diagnoses {
+			claim.Diagnoses[i] = diagnosis.Code
+		}
+		for _, line := range lines {
+			claim.ChargeCents += line.ChargeCents
+		}
+		if err := uc.claimRepo.Create(ctx, claim); err != nil {
+			return err
+		}
+		return uc.changeStatus(ctx, claim, domain.ClaimReady, "", nil, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// coverage picks the policy a visit is claimed from.
+func (uc *claimUseCase) coverage(ctx context.Context, patientID uint, policyID *uint, serviceDate string) (*domain.InsurancePolicy, error) {
+	if policyID != nil {
+		policy, err := uc.insuranceRepo.GetPolicy(ctx, *policyID)
+		if errors.Is(err, domain.ErrNotFound) || (err == nil && policy.PatientID != patientID) {
+			return nil, domain.NewValidationError(domain.FieldError{Field: "policy_id", Message: "is not a policy of the patient"})
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !policy.ActiveOn(serviceDate) {
+			return nil, domain.NewConflictError("policy_not_active", "the policy was not in effect on "+serviceDate)
+		}
+		return policy, nil
+	}
+
+	policies, err := uc.insuranceRepo.ListPolicies(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range policies {
+		if policies[i].Priority == domain.InsurancePrimary && policies[i].ActiveOn(serviceDate) {
+			return &policies[i], nil
+		}
+	}
+	return nil, domain.NewConflictError("no_active_coverage", "the patient had no primary insurance in effect on "+serviceDate)
+}
+
+// claimLines prices the visit's procedures from the invoice lines with
+// the same code. Each procedure points at the first four diagnoses, as
+// visits don't record which diagnosis a procedure treats.
+func claimLines(procedures []domain.Procedure, diagnoses int, invoice *domain.Invoice) ([]domain.ClaimLine, error) {
+	var pointers []int
+	for i := 1; i <= diagnoses && i <= 4; i++ {
+		pointers = append(pointers, i)
+	}
+
+	used := make([]bool, len(invoice.Lines))
+	lines := make([]domain.ClaimLine, 0, len(procedures))
+	for _, procedure := range procedures {
+		charge := int64(-1)
+		for i, line := range invoice.Lines {
+			if !used[i] && line.Code == procedure.Code {
+				used[i] = true
+				charge = line.TotalCents
+				break
+			}
+		}
+		if charge < 0 {
+			return nil, domain.NewConflictError("procedure_not_invoiced",
+				fmt.Sprintf("procedure %s has no line on invoice %s to price it", procedure.Code, invoice.Number))
+		}
+		units := procedure.Units
+		if units < 1 {
+			units = 1
+		}
+		lines = append(lines, domain.ClaimLine{
+			Code:              procedure.Code,
+			Description:       procedure.Description,
+			Modifiers:         procedure.Modifiers,
+			Units:             units,
+			ChargeCents:       charge,
+			DiagnosisPointers: pointers,
+		})
+	}
+	return lines, nil
+}
+
+func (uc *claimUseCase) GetClaim(ctx context.Context, id uint) (*domain.Claim, error) {
+	return uc.claimRepo.GetByID(ctx, id)
+}
+
+func (uc *claimUseCase) ListClaims(ctx context.Context, search domain.ClaimSearch) ([]domain.Claim, int64, error) {
+	search.Limit, search.Offset = pageBounds(search.Limit, search.Offset)
+	return uc.claimRepo.List(ctx, search)
+}
+
+// pageBounds applies the default and maximum page size.
+func pageBounds(limit, offset int) (int, int) {
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (uc *claimUseCase) SetClaimStatus(ctx context.Context, id uint, status, note string, actor *domain.User) (*domain.Claim, error) {
+	note = strings.TrimSpace(note)
+	if status == domain.ClaimVoid && note == "" {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "note", Message: "is required to void a claim"})
+	}
+
+	var claim *domain.Claim
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if claim, err = uc.claimRepo.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if !contains(claimTransitions[claim.Status], status) {
+			return domain.NewConflictError("invalid_claim_status",
+				fmt.Sprintf("a %s claim can't be marked %s", claim.Status, status))
+		}
+		claim.Status = status
+		if err := uc.claimRepo.Update(ctx, claim); err != nil {
+			return err
+		}
+		return uc.changeStatus(ctx, claim, status, note, nil, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// changeStatus adds an entry to the claim's status history.
+func (uc *claimUseCase) changeStatus(ctx context.Context, claim *domain.Claim, status, note string, remittanceID *uint, actor *domain.User) error {
+	change := &domain.ClaimStatusChange{
+		ClaimID:         claim.ID,
+		Status:          status,
+		Note:            note,
+		RemittanceID:    remittanceID,
+		ChangedByUserID: actorID(actor),
+	}
+	if err := uc.claimRepo.AddStatusChange(ctx, change); err != nil {
+		return err
+	}
+	claim.History = append(claim.History, *change)
+	return nil
+}
+
+// missingSettings lists the billing provider settings 837P files need
+// that are not configured.
+func (uc *claimUseCase) missingSettings() []string {
+	var missing []string
+	for _, setting := range []struct{ name, value string }{
+		{"PROVIDER_NPI", uc.settings.ProviderNPI},
+		{"PROVIDER_TAX_ID", uc.settings.ProviderTaxID},
+		{"PROVIDER_ADDRESS", uc.settings.ProviderAddress.Line1},
+		{"PROVIDER_CITY", uc.settings.ProviderAddress.City},
+		{"PROVIDER_STATE", uc.settings.ProviderAddress.Region},
+		{"PROVIDER_POSTAL_CODE", uc.settings.ProviderAddress.PostalCode},
+		{"PROVIDER_PHONE", uc.settings.ProviderPhone},
+	} {
+		if strings.TrimSpace(setting.value) == "" {
+			missing = append(missing, setting.name)
+		}
+	}
+	return missing
+}
+
+func (uc *claimUseCase) ExportClaims(ctx context.Context, actor *domain.User) (*domain.ClaimFile, error) {
+	if missing := uc.missingSettings(); len(missing) > 0 {
+		return nil, domain.NewConflictError("billing_provider_incomplete",
+			"set "+strings.Join(missing, ", ")+" before exporting claims")
+	}
+
+	var file *domain.ClaimFile
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		claims, err := uc.claimRepo.ListReady(ctx, maxClaimsPerFile)
+		if err != nil {
+			return err
+		}
+		if len(claims) == 0 {
+			return domain.NewConflictError("no_claims_ready", "no claims are waiting to be exported")
+		}
+		seq, err := uc.invoiceRepo.NextNumber(ctx, claimFileScope)
+		if err != nil {
+			return fmt.Errorf("failed to number claim file: %w", err)
+		}
+
+		now := uc.now()
+		file = &domain.ClaimFile{
+			ControlNumber:   claimFileControlBase + int(seq),
+			ClaimCount:      len(claims),
+			TenantID:        tenantOf(ctx),
+			CreatedByUserID: actorID(actor),
+			CreatedAt:       now,
+		}
+		batch := x12.ClaimBatch{
+			Envelope: x12.Envelope{
+				SenderID:      uc.settings.SenderID,
+				ReceiverID:    uc.settings.ReceiverID,
+				ControlNumber: file.ControlNumber,
+				Production:    uc.settings.Production,
+				Time:          now,
+			},
+			ReferenceID:  strconv.Itoa(file.ControlNumber),
+			ReceiverName: uc.settings.ReceiverID,
+			BillingProvider: x12.BillingProvider{
+				Name:    uc.settings.ProviderName,
+				NPI:     uc.settings.ProviderNPI,
+				TaxID:   uc.settings.ProviderTaxID,
+				Address: x12Address(uc.settings.ProviderAddress),
+				Phone:   uc.settings.ProviderPhone,
+			},
+		}
+		for i := range claims {
+			claim, err := uc.x12Claim(ctx, &claims[i])
+			if err != nil {
+				return err
+			}
+			batch.Claims = append(batch.Claims, claim)
+			file.ChargeCents += claims[i].ChargeCents
+		}
+		file.Content = string(x12.Build837P(batch))
+		if err := uc.claimRepo.CreateFile(ctx, file); err != nil {
+			return err
+		}
+
+		for i := range claims {
+			claim := &claims[i]
+			claim.Status = domain.ClaimSubmitted
+			claim.FileID = &file.ID
+			claim.SubmittedAt = &now
+			if err := uc.claimRepo.Update(ctx, claim); err != nil {
+				return err
+			}
+			if err := uc.changeStatus(ctx, claim, domain.ClaimSubmitted, "Exported in "+file.FileName(), nil, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// x12Claim fills in a claim for an 837P with the current details of the
+// patient and the policy.
+func (uc *claimUseCase) x12Claim(ctx context.Context, claim *domain.Claim) (x12.Claim, error) {
+	policy, err := uc.insuranceRepo.GetPolicy(ctx, claim.PolicyID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return x12.Claim{}, domain.NewConflictError("claim_policy_archived",
+			fmt.Sprintf("the insurance policy of claim %s was archived; void the claim and claim the visit again", claim.Number))
+	}
+	if err != nil {
+		return x12.Claim{}, err
+	}
+	patient, err := uc.patientRepo.GetByID(ctx, claim.PatientID)
+	if err != nil {
+		return x12.Claim{}, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	first, last := splitName(patient.Name)
+	patientParty := x12.ClaimParty{
+		Person:  x12.Person{FirstName: first, LastName: last, MemberID: policy.MemberID, DateOfBirth: patient.DateOfBirth},
+		Address: x12Address(patient.Address),
+		Gender:  x12Gender(patient.Sex),
+	}
+	out := x12.Claim{
+		Number:          claim.Number,
+		Responsibility:  x12Responsibility(policy.Priority),
+		PayerName:       policy.PayerName,
+		PayerID:         policy.PayerID,
+		FilingIndicator: uc.settings.FilingIndicator,
+		GroupNumber:     policy.GroupNumber,
+		Subscriber:      patientParty,
+		PlaceOfService:  uc.settings.PlaceOfService,
+		Diagnoses:       claim.Diagnoses,
+		ServiceDate:     claim.ServiceDate,
+	}
+	// Policies don't record the subscriber's address; dependents are
+	// taken to live with them.
+	if policy.SubscriberRelationship != domain.SubscriberSelf {
+		first, last := splitName(policy.SubscriberName)
+		out.Subscriber = x12.ClaimParty{
+			Person:  x12.Person{FirstName: first, LastName: last, MemberID: policy.MemberID, DateOfBirth: policy.SubscriberDateOfBirth},
+			Address: patientParty.Address,
+		}
+		out.Patient = &patientParty
+		out.Relationship = x12Relationship(policy.SubscriberRelationship)
+	}
+	for _, line := range claim.Lines {
+		out.Lines = append(out.Lines, x12.ServiceLine{
+			Code:              line.Code,
+			Modifiers:         line.Modifiers,
+			ChargeCents:       line.ChargeCents,
+			Units:             line.Units,
+			DiagnosisPointers: line.DiagnosisPointers,
+		})
+	}
+	return out, nil
+}
+
+func x12Address(a domain.Address) x12.Address {
+	return x12.Address{Line1: a.Line1, Line2: a.Line2, City: a.City, State: a.Region, PostalCode: a.PostalCode}
+}
+
+func x12Gender(sex string) string {
+	switch sex {
+	case domain.SexFemale:
+		return "F"
+	case domain.SexMale:
+		return "M"
+	}
+	return "U"
+}
+
+func x12Responsibility(priority string) string {
+	switch priority {
+	case domain.InsuranceSecondary:
+		return "S"
+	case domain.InsuranceTertiary:
+		return "T"
+	}
+	return "P"
+}
+
+func x12Relationship(relationship string) string {
+	switch relationship {
+	case domain.SubscriberSpouse:
+		return "01"
+	case domain.SubscriberChild:
+		return "19"
+	}
+	return "G8"
+}
+
+func (uc *claimUseCase) GetClaimFile(ctx context.Context, id uint) (*domain.ClaimFile, error) {
+	return uc.claimRepo.GetFile(ctx, id)
+}
+
+func (uc *claimUseCase) ListClaimFiles(ctx context.Context, limit, offset int) ([]domain.ClaimFile, int64, error) {
+	limit, offset = pageBounds(limit, offset)
+	return uc.claimRepo.ListFiles(ctx, limit, offset)
+}
+
+func (uc *claimUseCase) ImportRemittance(ctx context.Context, data []byte, actor *domain.User) (*domain.Remittance, error) {
+	parsed, err := x12.Parse835(data)
+	if err != nil {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "file", Message: "is not a readable 835: " + err.Error()})
+	}
+
+	remittance := &domain.Remittance{
+		TraceNumber:      parsed.TraceNumber,
+		PayerID:          parsed.PayerID,
+		PayerName:        parsed.PayerName,
+		PaymentCents:     parsed.PaymentCents,
+		PaymentMethod:    parsed.PaymentMethod,
+		PaymentDate:      parsed.PaymentDate,
+		Claims:           []domain.RemittanceClaim{},
+		TenantID:         tenantOf(ctx),
+		Content:          string(data),
+		ReceivedByUserID: actorID(actor),
+	}
+	receivedAt := uc.now()
+	if date, err := time.Parse("2006-01-02", parsed.PaymentDate); err == nil {
+		receivedAt = date
+	}
+
+	// The whole file is posted in one transaction: a failure leaves
+	// nothing half-posted, and the file can simply be uploaded again.
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.claimRepo.CreateRemittance(ctx, remittance); err != nil {
+			return err
+		}
+		for _, paid := range parsed.Claims {
+			result, err := uc.postClaimPayment(ctx, remittance, paid, receivedAt, actor)
+			if err != nil {
+				return err
+			}
+			remittance.Claims = append(remittance.Claims, result)
+		}
+		return uc.claimRepo.UpdateRemittance(ctx, remittance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return remittance, nil
+}
+
+// postClaimPayment applies what a remittance says about one claim: the
+// payment and the write-offs go to the claim's invoice, and the claim
+// becomes paid or denied. Patient responsibility stays on the invoice.
+func (uc *claimUseCase) postClaimPayment(ctx context.Context, remittance *domain.Remittance, paid x12.PaidClaim, receivedAt time.Time, actor *domain.User) (domain.RemittanceClaim, error) {
+	result := domain.RemittanceClaim{
+		Number:                     paid.Number,
+		Status:                     paid.Status,
+		Description:                x12.ClaimStatusName(paid.Status),
+		ChargeCents:                paid.ChargeCents,
+		PaidCents:                  paid.PaidCents,
+		PatientResponsibilityCents: paid.PatientResponsibilityCents,
+		PayerClaimNumber:           paid.PayerClaimNumber,
+	}
+	var writeOff int64
+	for _, adjustment := range paid.AllAdjustments() {
+		result.Adjustments = append(result.Adjustments, domain.ClaimAdjustment{
+			Group:       adjustment.Group,
+			Reason:      adjustment.Reason,
+			AmountCents: adjustment.AmountCents,
+		})
+		if adjustment.Group != x12.AdjustmentPatient {
+			writeOff += adjustment.AmountCents
+		}
+	}
+
+	claim, err := uc.claimRepo.FindByNumber(ctx, paid.Number)
+	if err != nil {
+		return result, err
+	}
+	if claim == nil {
+		result.Note = "no claim has this number"
+		return result, nil
+	}
+	result.ClaimID = &claim.ID
+	switch {
+	case paid.Status == x12.ClaimStatusReversal:
+		result.Note = "the payer took back an earlier payment; refund it on the invoice by hand"
+		return result, nil
+	case claim.Status == domain.ClaimVoid:
+		result.Note = "the claim is void; nothing was posted"
+		return result, nil
+	}
+
+	status := domain.ClaimPaid
+	switch paid.Status {
+	case x12.ClaimStatusDenied:
+		status = domain.ClaimDenied
+	case x12.ClaimStatusNotOurs:
+		status = domain.ClaimRejected
+	}
+
+	if status == domain.ClaimPaid && (paid.PaidCents > 0 || writeOff > 0) {
+		posting := &domain.InsurancePosting{
+			InvoiceID:     claim.InvoiceID,
+			PaidCents:     paid.PaidCents,
+			AdjustedCents: writeOff,
+			Reference:     remittance.TraceNumber,
+			Note:          fmt.Sprintf("%s, claim %s", remittance.PayerName, claim.Number),
+			ReceivedAt:    receivedAt,
+		}
+		_, err := uc.billing.PostInsurance(ctx, posting)
+		switch {
+		case errors.Is(err, domain.ErrConflict):
+			result.Note = "not posted to the invoice: " + err.Error()
+		case err != nil:
+			return result, err
+		default:
+			result.PostedPaymentCents, result.PostedAdjustmentCents = posting.PaidCents, posting.AdjustedCents
+			if posting.PaidCents < paid.PaidCents || posting.AdjustedCents < writeOff {
+				// E.g. the patient paid the whole invoice at the desk.
+				result.Note = "more than the invoice balance; the rest was not posted"
+			}
+		}
+	}
+
+	claim.PaidCents += result.PostedPaymentCents
+	claim.AdjustedCents += result.PostedAdjustmentCents
+	claim.PatientResponsibilityCents = paid.PatientResponsibilityCents
+	if paid.PayerClaimNumber != "" {
+		claim.PayerClaimNumber = paid.PayerClaimNumber
+	}
+	claim.Status = status
+	if err := uc.claimRepo.Update(ctx, claim); err != nil {
+		return result, err
+	}
+	note := result.Description
+	if result.Note != "" {
+		note += "; " + result.Note
+	}
+	return result, uc.changeStatus(ctx, claim, status, note, &remittance.ID, actor)
+}
+
+func (uc *claimUseCase) GetRemittance(ctx context.Context, id uint) (*domain.Remittance, error) {
+	return uc.claimRepo.GetRemittance(ctx, id)
+}
+
+func (uc *claimUseCase) ListRemittances(ctx context.Context, limit, offset int) ([]domain.Remittance, int64, error) {
+	limit, offset = pageBounds(limit, offset)
+	return uc.claimRepo.ListRemittances(ctx, limit, offset)
+}
@@ -0,0 +1,460 @@
+// internal/usecase/claim_usecase_test.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/x12"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClaimRepo holds claims by number and remittances by payer and
+// trace number, which are unique like idx_remittances_trace.
+type fakeClaimRepo struct {
+	repository.ClaimRepository
+	claims      map[string]*domain.Claim
+	remittances map[[2]string]*domain.Remittance
+	history     []domain.ClaimStatusChange
+}
+
+func (r *fakeClaimRepo) Create(ctx context.Context, claim *domain.Claim) error {
+	if r.claims == nil {
+		r.claims = map[string]*domain.Claim{}
+	}
+	claim.ID = uint(len(r.claims) + 1)
+	r.claims[claim.Number] = claim
+	return nil
+}
+
+func (r *fakeClaimRepo) FindByNumber(ctx context.Context, number string) (*domain.Claim, error) {
+	return r.claims[number], nil
+}
+
+func (r *fakeClaimRepo) Update(ctx context.Context, claim *domain.Claim) error {
+	r.claims[claim.Number] = claim
+	return nil
+}
+
+func (r *fakeClaimRepo) AddStatusChange(ctx context.Context, change *domain.ClaimStatusChange) error {
+	r.history = append(r.history, *change)
+	return nil
+}
+
+func (r *fakeClaimRepo) CreateRemittance(ctx context.Context, remittance *domain.Remittance) error {
+	if r.remittances == nil {
+		r.remittances = map[[2]string]*domain.Remittance{}
+	}
+	key := [2]string{remittance.PayerID, remittance.TraceNumber}
+	if _, ok := r.remittances[key]; ok {
+		return domain.NewConflictError("remittance_already_posted", "a remittance with this trace number was posted already")
+	}
+	remittance.ID = uint(len(r.remittances) + 1)
+	r.remittances[key] = remittance
+	return nil
+}
+
+func (r *fakeClaimRepo) UpdateRemittance(ctx context.Context, remittance *domain.Remittance) error {
+	return nil
+}
+
+func (r *fakeInvoiceRepo) FindByAppointment(ctx context.Context, appointmentID uint) (*domain.Invoice, error) {
+	if r.invoice == nil || r.invoice.AppointmentID == nil || *r.invoice.AppointmentID != appointmentID {
+		return nil, nil
+	}
+	return r.invoice, nil
+}
+
+type fakeClaimAppointmentRepo struct {
+	repository.AppointmentRepository
+	appointment domain.Appointment
+}
+
+func (r fakeClaimAppointmentRepo) GetByID(ctx context.Context, id uint) (*domain.Appointment, error) {
+	if r.appointment.ID != id {
+		return nil, domain.NewNotFoundError("appointment", id)
+	}
+	appointment := r.appointment
+	return &appointment, nil
+}
+
+type fakeClaimInsuranceRepo struct {
+	repository.InsuranceRepository
+	policies []domain.InsurancePolicy
+}
+
+func (r fakeClaimInsuranceRepo) GetPolicy(ctx context.Context, id uint) (*domain.InsurancePolicy, error) {
+	for i := range r.policies {
+		if r.policies[i].ID == id {
+			return &r.policies[i], nil
+		}
+	}
+	return nil, domain.NewNotFoundError("insurance policy", id)
+}
+
+func (r fakeClaimInsuranceRepo) ListPolicies(ctx context.Context, patientID uint) ([]domain.InsurancePolicy, error) {
+	var out []domain.InsurancePolicy
+	for _, policy := range r.policies {
+		if policy.PatientID == patientID {
+			out = append(out, policy)
+		}
+	}
+	return out, nil
+}
+
+type fakeCodingRepo struct {
+	repository.CodingRepository
+	diagnoses  []domain.Diagnosis
+	procedures []domain.Procedure
+}
+
+func (r fakeCodingRepo) ListDiagnoses(ctx context.Context, appointmentID uint) ([]domain.Diagnosis, error) {
+	return r.diagnoses, nil
+}
+
+func (r fakeCodingRepo) ListProcedures(ctx context.Context, appointmentID uint) ([]domain.Procedure, error) {
+	return r.procedures, nil
+}
+
+// claimFixture is a completed visit on 2030-03-10, coded and invoiced,
+// of a patient with a primary policy in effect that day.
+type claimFixture struct {
+	appointment domain.Appointment
+	invoice     *domain.Invoice
+	policies    []domain.InsurancePolicy
+	coding      fakeCodingRepo
+}
+
+func newClaimFixture() claimFixture {
+	appointmentID := uint(3)
+	return claimFixture{
+		appointment: domain.Appointment{
+			ID: appointmentID, PatientID: 7, Status: domain.AppointmentStatusCompleted,
+			DateTime: time.Date(2030, 3, 10, 14, 0, 0, 0, time.UTC),
+		},
+		invoice: &domain.Invoice{
+			ID: 1, Number: "INV-2030-000001", PatientID: 7, AppointmentID: &appointmentID, Status: domain.InvoiceIssued,
+			Lines: []domain.InvoiceLine{
+				{Code: "99213", Quantity: 1, UnitPriceCents: 12000, TotalCents: 12000},
+				{Code: "81002", Quantity: 1, UnitPriceCents: 3000, TotalCents: 3000},
+			},
+			TotalCents: 15000, BalanceCents: 15000,
+		},
+		policies: []domain.InsurancePolicy{
+			{ID: 20, PatientID: 7, PayerName: "OLD HEALTH", PayerID: "OLD01", Priority: domain.InsurancePrimary,
+				EffectiveFrom: "2025-01-01", EffectiveTo: "2029-12-31"},
+			{ID: 21, PatientID: 7, PayerName: "ACME HEALTH", PayerID: "ACME1", Priority: domain.InsurancePrimary,
+				EffectiveFrom: "2030-01-01"},
+			{ID: 22, PatientID: 7, PayerName: "BACKUP CARE", PayerID: "BKUP1", Priority: domain.InsuranceSecondary,
+				EffectiveFrom: "2030-01-01"},
+			{ID: 30, PatientID: 8, PayerName: "ACME HEALTH", PayerID: "ACME1", Priority: domain.InsurancePrimary,
+				EffectiveFrom: "2030-01-01"},
+		},
+		coding: fakeCodingRepo{
+			diagnoses: []domain.Diagnosis{{Code: "J06.9", Rank: 1}, {Code: "R05.9", Rank: 2}},
+			procedures: []domain.Procedure{
+				{Code: "99213", Description: "Office visit", Modifiers: []string{"25"}, Units: 1},
+				{Code: "81002", Description: "Urinalysis"},
+			},
+		},
+	}
+}
+
+func (f claimFixture) useCase() (*claimUseCase, *fakeClaimRepo, *fakeInvoiceRepo) {
+	billing, invoices, _ := newTestBilling(f.invoice)
+	claims := &fakeClaimRepo{}
+	uc := NewClaimUseCase(fakeTransactor{}, claims, invoices, fakeClaimAppointmentRepo{appointment: f.appointment},
+		nil, fakeClaimInsuranceRepo{policies: f.policies}, f.coding, billing, ClaimSettings{}).(*claimUseCase)
+	uc.now = func() time.Time { return billingNow }
+	return uc, claims, invoices
+}
+
+func TestCreateClaim(t *testing.T) {
+	uc, claims, _ := newClaimFixture().useCase()
+
+	claim, err := uc.CreateClaim(context.Background(), 3, nil, &domain.User{ID: 5})
+	if err != nil {
+		t.Fatalf("CreateClaim() error = %v", err)
+	}
+	if claim.Number != "CLM00000001" || claim.Status != domain.ClaimReady {
+		t.Errorf("number, status = %s, %s, want CLM00000001, ready", claim.Number, claim.Status)
+	}
+	if claim.PolicyID != 21 || claim.PayerID != "ACME1" || claim.ServiceDate != "2030-03-10" {
+		t.Errorf("policy, payer, service date = %d, %s, %s, want the primary policy in effect on the visit",
+			claim.PolicyID, claim.PayerID, claim.ServiceDate)
+	}
+	if claim.InvoiceID != 1 || claim.ChargeCents != 15000 {
+		t.Errorf("invoice, charge = %d, %d, want 1, 15000", claim.InvoiceID, claim.ChargeCents)
+	}
+	if !reflect.DeepEqual(claim.Diagnoses, []string{"J06.9", "R05.9"}) {
+		t.Errorf("diagnoses = %v", claim.Diagnoses)
+	}
+	wantLines := []domain.ClaimLine{
+		{Code: "99213", Description: "Office visit", Modifiers: []string{"25"}, Units: 1, ChargeCents: 12000, DiagnosisPointers: []int{1, 2}},
+		{Code: "81002", Description: "Urinalysis", Units: 1, ChargeCents: 3000, DiagnosisPointers: []int{1, 2}},
+	}
+	if !reflect.DeepEqual(claim.Lines, wantLines) {
+		t.Errorf("lines = %+v, want %+v", claim.Lines, wantLines)
+	}
+	if len(claims.history) != 1 || claims.history[0].Status != domain.ClaimReady {
+		t.Errorf("history = %+v, want one ready entry", claims.history)
+	}
+}
+
+func TestCreateClaimRefused(t *testing.T) {
+	policyOf := func(id uint) *uint { return &id }
+	tests := []struct {
+		name     string
+		change   func(f *claimFixture)
+		policyID *uint
+		wantCode string
+	}{
+		{
+			name:     "appointment not completed",
+			change:   func(f *claimFixture) { f.appointment.Status = domain.AppointmentStatusScheduled },
+			wantCode: "appointment_not_completed",
+		},
+		{
+			name:     "invoice still a draft",
+			change:   func(f *claimFixture) { f.invoice.Status = domain.InvoiceDraft },
+			wantCode: "invoice_not_issued",
+		},
+		{
+			name:     "no invoice",
+			change:   func(f *claimFixture) { f.invoice.AppointmentID = nil },
+			wantCode: "invoice_not_issued",
+		},
+		{
+			name:     "no primary policy in effect",
+			change:   func(f *claimFixture) { f.appointment.DateTime = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC) },
+			wantCode: "no_active_coverage",
+		},
+		{
+			name:     "policy of another patient",
+			policyID: policyOf(30),
+			wantCode: "validation_failed",
+		},
+		{
+			name:     "policy not in effect on the visit",
+			policyID: policyOf(20),
+			wantCode: "policy_not_active",
+		},
+		{
+			name:     "visit not coded",
+			change:   func(f *claimFixture) { f.coding.diagnoses = nil },
+			wantCode: "visit_not_coded",
+		},
+		{
+			name: "procedure missing from the invoice",
+			change: func(f *claimFixture) {
+				f.coding.procedures = append(f.coding.procedures, domain.Procedure{Code: "99213"})
+			},
+			wantCode: "procedure_not_invoiced",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newClaimFixture()
+			if tt.change != nil {
+				tt.change(&f)
+			}
+			uc, claims, _ := f.useCase()
+			_, err := uc.CreateClaim(context.Background(), 3, tt.policyID, nil)
+			if errorCode(err) != tt.wantCode {
+				t.Errorf("CreateClaim() error = %v, want %s", err, tt.wantCode)
+			}
+			if len(claims.claims) != 0 {
+				t.Errorf("claims = %+v, want none", claims.claims)
+			}
+		})
+	}
+}
+
+func TestClaimLinesPointAtTheFirstFourDiagnoses(t *testing.T) {
+	invoice := &domain.Invoice{Lines: []domain.InvoiceLine{{Code: "99213", TotalCents: 100}}}
+	lines, err := claimLines([]domain.Procedure{{Code: "99213", Units: 2}}, 6, invoice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines[0].DiagnosisPointers, []int{1, 2, 3, 4}) || lines[0].Units != 2 {
+		t.Errorf("pointers, units = %v, %d, want [1 2 3 4], 2", lines[0].DiagnosisPointers, lines[0].Units)
+	}
+}
+
+// remittanceFor returns an 835 from the fixture's payer paying the claims.
+func remittanceFor(trace string, claims ...x12.PaidClaim) []byte {
+	r := x12.Remittance{
+		Envelope:    x12.Envelope{SenderID: "ACME1", ReceiverID: "CLINIC", ControlNumber: 1, Time: billingNow},
+		TraceNumber: trace, PayerID: "1512345678", PayerName: "ACME HEALTH", PayeeName: "CLINIC",
+		PaymentMethod: "ACH", PaymentDate: "2030-03-20", Claims: claims,
+	}
+	for _, claim := range claims {
+		r.PaymentCents += claim.PaidCents
+	}
+	return x12.Build835(r)
+}
+
+func TestImportRemittance(t *testing.T) {
+	uc, claims, invoices := newClaimFixture().useCase()
+	claim, err := uc.CreateClaim(context.Background(), 3, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := remittanceFor("EFT100",
+		x12.PaidClaim{
+			Number: claim.Number, Status: "1", ChargeCents: 15000, PaidCents: 9000, PatientResponsibilityCents: 2000,
+			PayerClaimNumber: "PCN-9",
+			Adjustments: []x12.Adjustment{
+				{Group: x12.AdjustmentContractual, Reason: "45", AmountCents: 4000},
+				{Group: x12.AdjustmentPatient, Reason: "3", AmountCents: 2000},
+			},
+		},
+		x12.PaidClaim{Number: "CLM99999999", Status: "1", ChargeCents: 500, PaidCents: 500},
+	)
+	remittance, err := uc.ImportRemittance(context.Background(), data, &domain.User{ID: 5})
+	if err != nil {
+		t.Fatalf("ImportRemittance() error = %v", err)
+	}
+	if remittance.TraceNumber != "EFT100" || remittance.PaymentCents != 9500 || len(remittance.Claims) != 2 {
+		t.Fatalf("remittance = %+v", remittance)
+	}
+
+	posted := remittance.Claims[0]
+	if posted.ClaimID == nil || *posted.ClaimID != claim.ID || posted.PostedPaymentCents != 9000 ||
+		posted.PostedAdjustmentCents != 4000 || posted.Note != "" {
+		t.Errorf("posted claim = %+v, want 9000 paid and 4000 written off", posted)
+	}
+	if unknown := remittance.Claims[1]; unknown.ClaimID != nil || unknown.Note != "no claim has this number" {
+		t.Errorf("unknown claim = %+v", unknown)
+	}
+
+	invoice := invoices.invoice
+	if invoice.PaidCents != 9000 || invoice.AdjustedCents != 4000 || invoice.BalanceCents != 2000 {
+		t.Errorf("invoice paid, adjusted, balance = %d, %d, %d, want 9000, 4000, 2000",
+			invoice.PaidCents, invoice.AdjustedCents, invoice.BalanceCents)
+	}
+	if len(invoices.payments) != 2 || invoices.payments[0].Reference != "EFT100" ||
+		!invoices.payments[0].ReceivedAt.Equal(time.Date(2030, 3, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("invoice movements = %+v, want the payment and write-off received on the payment date", invoices.payments)
+	}
+
+	stored := claims.claims[claim.Number]
+	if stored.Status != domain.ClaimPaid || stored.PaidCents != 9000 || stored.AdjustedCents != 4000 ||
+		stored.PatientResponsibilityCents != 2000 || stored.PayerClaimNumber != "PCN-9" {
+		t.Errorf("claim = %+v, want paid with the remittance's amounts", stored)
+	}
+	last := claims.history[len(claims.history)-1]
+	if last.Status != domain.ClaimPaid || last.RemittanceID == nil || *last.RemittanceID != remittance.ID {
+		t.Errorf("last status change = %+v, want paid by the remittance", last)
+	}
+
+	// The same payment uploaded again is refused and posts nothing.
+	again, err := uc.ImportRemittance(context.Background(), data, nil)
+	if errorCode(err) != "remittance_already_posted" || again != nil {
+		t.Errorf("ImportRemittance() again = %v, %v, want remittance_already_posted", again, err)
+	}
+	if invoices.invoice.PaidCents != 9000 || len(invoices.payments) != 2 {
+		t.Errorf("invoice paid = %d with %d movements after the duplicate, want unchanged",
+			invoices.invoice.PaidCents, len(invoices.payments))
+	}
+}
+
+func TestImportRemittanceClaimOutcomes(t *testing.T) {
+	tests := []struct {
+		name        string
+		claimStatus string
+		invoice     string
+		paid        x12.PaidClaim
+		wantStatus  string
+		wantPaid    int64
+		wantNote    string
+	}{
+		{
+			name:       "denied",
+			paid:       x12.PaidClaim{Status: x12.ClaimStatusDenied, ChargeCents: 15000},
+			wantStatus: domain.ClaimDenied,
+		},
+		{
+			name:       "not the payer's patient",
+			paid:       x12.PaidClaim{Status: x12.ClaimStatusNotOurs, ChargeCents: 15000},
+			wantStatus: domain.ClaimRejected,
+		},
+		{
+			name:       "reversal is left for staff",
+			paid:       x12.PaidClaim{Status: x12.ClaimStatusReversal, ChargeCents: -15000, PaidCents: -9000},
+			wantStatus: domain.ClaimSubmitted,
+			wantNote:   "the payer took back an earlier payment; refund it on the invoice by hand",
+		},
+		{
+			name:        "void claim",
+			claimStatus: domain.ClaimVoid,
+			paid:        x12.PaidClaim{Status: "1", ChargeCents: 15000, PaidCents: 9000},
+			wantStatus:  domain.ClaimVoid,
+			wantNote:    "the claim is void; nothing was posted",
+		},
+		{
+			name:       "payment over the balance",
+			invoice:    "paid at the desk",
+			paid:       x12.PaidClaim{Status: "1", ChargeCents: 15000, PaidCents: 9000},
+			wantStatus: domain.ClaimPaid, wantPaid: 5000,
+			wantNote: "more than the invoice balance; the rest was not posted",
+		},
+		{
+			name:       "void invoice",
+			invoice:    domain.InvoiceVoid,
+			paid:       x12.PaidClaim{Status: "1", ChargeCents: 15000, PaidCents: 9000},
+			wantStatus: domain.ClaimPaid,
+			wantNote:   "not posted to the invoice: the invoice is void",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newClaimFixture()
+			uc, claims, invoices := f.useCase()
+			claim, err := uc.CreateClaim(context.Background(), 3, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claim.Status = domain.ClaimSubmitted
+			if tt.claimStatus != "" {
+				claim.Status = tt.claimStatus
+			}
+			switch tt.invoice {
+			case "paid at the desk":
+				invoices.invoice.PaidCents, invoices.invoice.BalanceCents = 10000, 5000
+			case domain.InvoiceVoid:
+				invoices.invoice.Status = domain.InvoiceVoid
+			}
+
+			paid := tt.paid
+			paid.Number = claim.Number
+			remittance, err := uc.ImportRemittance(context.Background(), remittanceFor("EFT200", paid), nil)
+			if err != nil {
+				t.Fatalf("ImportRemittance() error = %v", err)
+			}
+			got := remittance.Claims[0]
+			if got.PostedPaymentCents != tt.wantPaid || got.Note != tt.wantNote {
+				t.Errorf("posted, note = %d, %q, want %d, %q", got.PostedPaymentCents, got.Note, tt.wantPaid, tt.wantNote)
+			}
+			if stored := claims.claims[claim.Number]; stored.Status != tt.wantStatus || stored.PaidCents != tt.wantPaid {
+				t.Errorf("claim status, paid = %s, %d, want %s, %d", stored.Status, stored.PaidCents, tt.wantStatus, tt.wantPaid)
+			}
+		})
+	}
+}
+
+func TestImportRemittanceUnreadable(t *testing.T) {
+	uc, claims, _ := newClaimFixture().useCase()
+	_, err := uc.ImportRemittance(context.Background(), []byte("not an 835"), nil)
+	if errorCode(err) != "validation_failed" {
+		t.Errorf("ImportRemittance() error = %v, want validation_failed", err)
+	}
+	if len(claims.remittances) != 0 {
+		t.Errorf("remittances = %+v, want none", claims.remittances)
+	}
+}
@@ -0,0 +1,319 @@
+// internal/usecase/clinical_history_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ClinicalHistoryUseCase keeps a patient's allergies, medications and
+// problem list. Entries are resolved rather than deleted; deleting one
+// marks it as entered in error. Updates replace the entry but keep who
+// recorded it.
+type ClinicalHistoryUseCase interface {
+	CreateAllergy(ctx context.Context, allergy *domain.Allergy, actor *domain.User) error
+	// ListAllergies returns the patient's allergies, only those with status when set.
+	ListAllergies(ctx context.Context, patientID uint, status string) ([]domain.Allergy, error)
+	UpdateAllergy(ctx context.Context, patientID uint, allergy *domain.Allergy) error
+	DeleteAllergy(ctx context.Context, patientID, id uint) error
+
+	CreateMedication(ctx context.Context, medication *domain.Medication, actor *domain.User) error
+	ListMedications(ctx context.Context, patientID uint, status string) ([]domain.Medication, error)
+	UpdateMedication(ctx context.Context, patientID uint, medication *domain.Medication) error
+	DeleteMedication(ctx context.Context, patientID, id uint) error
+
+	// CreateProblem adds to the problem list. A code must be an active
+	// ICD-10-CM code of the catalog unless the problem comes from an
+	// external record.
+	CreateProblem(ctx context.Context, problem *domain.Problem, actor *domain.User) error
+	ListProblems(ctx context.Context, patientID uint, status string) ([]domain.Problem, error)
+	UpdateProblem(ctx context.Context, patientID uint, problem *domain.Problem) error
+	DeleteProblem(ctx context.Context, patientID, id uint) error
+
+	// GetSummary returns the patient's active allergies, medications and
+	// problems together with their last encounter.
+	GetSummary(ctx context.Context, patientID uint) (*domain.PatientSummary, error)
+}
+
+type clinicalHistoryUseCase struct {
+	allergyRepo    repository.AllergyRepository
+	medicationRepo repository.MedicationRepository
+	problemRepo    repository.ProblemRepository
+	patientRepo    repository.PatientRepository
+	encounterRepo  repository.EncounterRepository
+	codeRepo       repository.CodeRepository
+	now            func() time.Time
+}
+
+func NewClinicalHistoryUseCase(
+	allergyRepo repository.AllergyRepository,
+	medicationRepo repository.MedicationRepository,
+	problemRepo repository.ProblemRepository,
+	patientRepo repository.PatientRepository,
+	encounterRepo repository.EncounterRepository,
+	codeRepo repository.CodeRepository,
+) ClinicalHistoryUseCase {
+	return &clinicalHistoryUseCase{
+		allergyRepo:    allergyRepo,
+		medicationRepo: medicationRepo,
+		problemRepo:    problemRepo,
+		patientRepo:    patientRepo,
+		encounterRepo:  encounterRepo,
+		codeRepo:       codeRepo,
+		now:            time.Now,
+	}
+}
+
+func (uc *clinicalHistoryUseCase) CreateAllergy(ctx context.Context, allergy *domain.Allergy, actor *domain.User) error {
+	allergy.ID = 0
+	allergy.RecordedByUserID = actorID(actor)
+	if err := uc.validateAllergy(ctx, allergy); err != nil {
+		return err
+	}
+	return uc.allergyRepo.Create(ctx, allergy)
+}
+
+func (uc *clinicalHistoryUseCase) ListAllergies(ctx context.Context, patientID uint, status string) ([]domain.Allergy, error) {
+	if err := uc.checkListing(ctx, patientID, status); err != nil {
+		return nil, err
+	}
+	return uc.allergyRepo.ListByPatient(ctx, patientID, status)
+}
+
+func (uc *clinicalHistoryUseCase) UpdateAllergy(ctx context.Context, patientID uint, allergy *domain.Allergy) error {
+	existing, err := uc.allergyRepo.GetByID(ctx, allergy.ID)
+	if err != nil {
+		return err
+	}
+	if existing.PatientID != patientID {
+		return domain.NewNotFoundError("allergy", allergy.ID)
+	}
+	allergy.PatientID = existing.PatientID
+	allergy.RecordedByUserID = existing.RecordedByUserID
+	allergy.CreatedAt = existing.CreatedAt
+	if err := uc.validateAllergy(ctx, allergy); err != nil {
+		return err
+	}
+	return uc.allergyRepo.Update(ctx, allergy)
+}
+
+func (uc *clinicalHistoryUseCase) DeleteAllergy(ctx context.Context, patientID, id uint) error {
+	allergy, err := uc.allergyRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if allergy.PatientID != patientID {
+		return domain.NewNotFoundError("allergy", id)
+	}
+	return uc.allergyRepo.Delete(ctx, id)
+}
+
+func (uc *clinicalHistoryUseCase) validateAllergy(ctx context.Context, allergy *domain.Allergy) error {
+	allergy.Substance = strings.TrimSpace(allergy.Substance)
+	allergy.Reaction = strings.TrimSpace(allergy.Reaction)
+	extra := uc.checkDates(allergy.Status, allergy.OnsetDate, &allergy.ResolvedOn)
+	if err := validateStruct(allergy, extra...); err != nil {
+		return err
+	}
+	return uc.checkProvenance(ctx, allergy.PatientID, allergy.Provenance)
+}
+
+func (uc *clinicalHistoryUseCase) CreateMedication(ctx context.Context, medication *domain.Medication, actor *domain.User) error {
+	medication.ID = 0
+	medication.RecordedByUserID = actorID(actor)
+	if err := uc.validateMedication(ctx, medication); err != nil {
+		return err
+	}
+	return uc.medicationRepo.Create(ctx, medication)
+}
+
+func (uc *clinicalHistoryUseCase) ListMedications(ctx context.Context, patientID uint, status string) ([]domain.Medication, error) {
+	if err := uc.checkListing(ctx, patientID, status); err != nil {
+		return nil, err
+	}
+	return uc.medicationRepo.ListByPatient(ctx, patientID, status)
+}
+
+func (uc *clinicalHistoryUseCase) UpdateMedication(ctx context.Context, patientID uint, medication *domain.Medication) error {
+	existing, err := uc.medicationRepo.GetByID(ctx, medication.ID)
+	if err != nil {
+		return err
+	}
+	if existing.PatientID != patientID {
+		return domain.NewNotFoundError("medication", medication.ID)
+	}
+	medication.PatientID = existing.PatientID
+	medication.RecordedByUserID = existing.RecordedByUserID
+	medication.CreatedAt = existing.CreatedAt
+	if err := uc.validateMedication(ctx, medication); err != nil {
+		return err
+	}
+	return uc.medicationRepo.Update(ctx, medication)
+}
+
+func (uc *clinicalHistoryUseCase) DeleteMedication(ctx context.Context, patientID, id uint) error {
+	medication, err := uc.medicationRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if medication.PatientID != patientID {
+		return domain.NewNotFoundError("medication", id)
+	}
+	return uc.medicationRepo.Delete(ctx, id)
+}
+
+func (uc *clinicalHistoryUseCase) validateMedication(ctx context.Context, medication *domain.Medication) error {
+	medication.Name = strings.TrimSpace(medication.Name)
+	medication.RxNormCode = strings.TrimSpace(medication.RxNormCode)
+	medication.Dose = strings.TrimSpace(medication.Dose)
+	medication.Route = strings.TrimSpace(medication.Route)
+	medication.Frequency = strings.TrimSpace(medication.Frequency)
+	extra := uc.checkDates(medication.Status, medication.OnsetDate, &medication.ResolvedOn)
+	if err := validateStruct(medication, extra...); err != nil {
+		return err
+	}
+	return uc.checkProvenance(ctx, medication.PatientID, medication.Provenance)
+}
+
+func (uc *clinicalHistoryUseCase) CreateProblem(ctx context.Context, problem *domain.Problem, actor *domain.User) error {
+	problem.ID = 0
+	problem.RecordedByUserID = actorID(actor)
+	if err := uc.validateProblem(ctx, problem); err != nil {
+		return err
+	}
+	return uc.problemRepo.Create(ctx, problem)
+}
+
+func (uc *clinicalHistoryUseCase) ListProblems(ctx context.Context, patientID uint, status string) ([]domain.Problem, error) {
+	if err := uc.checkListing(ctx, patientID, status); err != nil {
+		return nil, err
+	}
+	return uc.problemRepo.ListByPatient(ctx, patientID, status)
+}
+
+func (uc *clinicalHistoryUseCase) UpdateProblem(ctx context.Context, patientID uint, problem *domain.Problem) error {
+	existing, err := uc.problemRepo.GetByID(ctx, problem.ID)
+	if err != nil {
+		return err
+	}
+	if existing.PatientID != patientID {
+		return domain.NewNotFoundError("problem", problem.ID)
+	}
+	problem.PatientID = existing.PatientID
+	problem.RecordedByUserID = existing.RecordedByUserID
+	problem.CreatedAt = existing.CreatedAt
+	if err := uc.validateProblem(ctx, problem); err != nil {
+		return err
+	}
+	return uc.problemRepo.Update(ctx, problem)
+}
+
+func (uc *clinicalHistoryUseCase) DeleteProblem(ctx context.Context, patientID, id uint) error {
+	problem, err := uc.problemRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if problem.PatientID != patientID {
+		return domain.NewNotFoundError("problem", id)
+	}
+	return uc.problemRepo.Delete(ctx, id)
+}
+
+func (uc *clinicalHistoryUseCase) validateProblem(ctx context.Context, problem *domain.Problem) error {
+	problem.Description = strings.TrimSpace(problem.Description)
+	if problem.Code != "" {
+		problem.Code = normalizeCode(domain.CodeSystemICD10CM, problem.Code)
+	}
+	extra := uc.checkDates(problem.Status, problem.OnsetDate, &problem.ResolvedOn)
+	if err := validateStruct(problem, extra...); err != nil {
+		return err
+	}
+
+	if problem.Code != "" && problem.Source != domain.SourceExternal {
+		code, err := uc.codeRepo.Get(ctx, domain.CodeSystemICD10CM, problem.Code)
+		if errors.Is(err, domain.ErrNotFound) || (err == nil && !code.Active) {
+			return domain.NewValidationError(domain.FieldError{Field: "code", Message: "is not an active ICD-10-CM code"})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return uc.checkProvenance(ctx, problem.PatientID, problem.Provenance)
+}
+
+func (uc *clinicalHistoryUseCase) GetSummary(ctx context.Context, patientID uint) (*domain.PatientSummary, error) {
+	patient, err := uc.patientRepo.GetByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &domain.PatientSummary{Patient: *patient}
+
+	if summary.Allergies, err = uc.allergyRepo.ListByPatient(ctx, patientID, domain.ClinicalStatusActive); err != nil {
+		return nil, err
+	}
+	if summary.Medications, err = uc.medicationRepo.ListByPatient(ctx, patientID, domain.ClinicalStatusActive); err != nil {
+		return nil, err
+	}
+	if summary.Problems, err = uc.problemRepo.ListByPatient(ctx, patientID, domain.ClinicalStatusActive); err != nil {
+		return nil, err
+	}
+	if summary.LastEncounter, err = uc.encounterRepo.LatestByPatient(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// checkListing checks the patient exists and the status filter is valid.
+func (uc *clinicalHistoryUseCase) checkListing(ctx context.Context, patientID uint, status string) error {
+	if status != "" && status != domain.ClinicalStatusActive && status != domain.ClinicalStatusResolved {
+		return domain.NewValidationError(domain.FieldError{Field: "status", Message: "must be one of: active resolved"})
+	}
+	_, err := uc.patientRepo.GetByID(ctx, patientID)
+	return err
+}
+
+// checkDates clears the resolution date of active entries, dates resolved
+// ones today unless told otherwise, and checks the dates are in order.
+func (uc *clinicalHistoryUseCase) checkDates(status, onsetDate string, resolvedOn *string) []domain.FieldError {
+	today := uc.now().Format("2006-01-02")
+	switch status {
+	case domain.ClinicalStatusActive:
+		*resolvedOn = ""
+	case domain.ClinicalStatusResolved:
+		if *resolvedOn == "" {
+			*resolvedOn = today
+		}
+	}
+
+	// Dates in another format are reported by validateStruct.
+	var fields []domain.FieldError
+	if onsetDate > today {
+		fields = append(fields, domain.FieldError{Field: "onset_date", Message: "must not be in the future"})
+	}
+	if *resolvedOn > today {
+		fields = append(fields, domain.FieldError{Field: "resolved_on", Message: "must not be in the future"})
+	} else if *resolvedOn != "" && *resolvedOn < onsetDate {
+		fields = append(fields, domain.FieldError{Field: "resolved_on", Message: "must not be before onset_date"})
+	}
+	return fields
+}
+
+// checkProvenance checks the patient exists and that the encounter an
+// entry was recorded in, if any, is one of theirs.
+func (uc *clinicalHistoryUseCase) checkProvenance(ctx context.Context, patientID uint, provenance domain.Provenance) error {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return err
+	}
+	if provenance.EncounterID == nil {
+		return nil
+	}
+	encounter, err := uc.encounterRepo.GetByID(ctx, *provenance.EncounterID)
+	if errors.Is(err, domain.ErrNotFound) || (err == nil && encounter.PatientID != patientID) {
+		return domain.NewValidationError(domain.FieldError{Field: "encounter_id", Message: "is not an encounter of this patient"})
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+// internal/usecase/code_catalog_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/codeset"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type CodeCatalogUseCase interface {
+	// ImportCodes loads a release of a code set from its flat file. Codes of
+	// the system that are not in the file are deactivated. An empty format
+	// uses the usual file of the system.
+	ImportCodes(ctx context.Context, system, release string, format codeset.Format, r io.Reader) (*CodeImport, error)
+	SearchCodes(ctx context.Context, search domain.CodeSearch, limit int) ([]domain.MedicalCode, error)
+	GetCode(ctx context.Context, system, code string) (*domain.MedicalCode, error)
+}
+
+// CodeImport summarizes an import.
+type CodeImport struct {
+	System      string `json:"system"`
+	Release     string `json:"release"`
+	Imported    int    `json:"imported"`
+	Deactivated int64  `json:"deactivated"`
+}
+
+// codeImportBatch is the number of codes upserted per statement.
+const codeImportBatch = 1000
+
+// codeSystemFormats is the flat file each code set is usually published in.
+var codeSystemFormats = map[string]codeset.Format{
+	domain.CodeSystemICD10CM: codeset.ICD10CM,
+	domain.CodeSystemCPT:     codeset.Tabular,
+	domain.CodeSystemHCPCS:   codeset.HCPCS,
+}
+
+type codeCatalogUseCase struct {
+	transactor repository.Transactor
+	codeRepo   repository.CodeRepository
+	now        func() time.Time
+}
+
+func NewCodeCatalogUseCase(transactor repository.Transactor, codeRepo repository.CodeRepository) CodeCatalogUseCase {
+	return &codeCatalogUseCase{transactor: transactor, codeRepo: codeRepo, now: time.Now}
+}
+
+func (uc *codeCatalogUseCase) ImportCodes(ctx context.Context, system, release string, format codeset.Format, r io.Reader) (*CodeImport, error) {
+	var fields []domain.FieldError
+	defaultFormat, ok := codeSystemFormats[system]
+	if !ok {
+		fields = append(fields, domain.FieldError{Field: "system", Message: "must be one of: ICD-10-CM CPT HCPCS"})
+	}
+	if release = strings.TrimSpace(release); release == "" {
+		fields = append(fields, domain.FieldError{Field: "release", Message: "is required"})
+	}
+	if len(fields) > 0 {
+		return nil, domain.NewValidationError(fields...)
+	}
+	if format == "" {
+		format = defaultFormat
+	}
+
+	result := &CodeImport{System: system, Release: release}
+	now := uc.now()
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		batch := make([]domain.MedicalCode, 0, codeImportBatch)
+		flush := func() error {
+			if err := uc.codeRepo.Upsert(ctx, batch); err != nil {
+				return err
+			}
+			result.Imported += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+
+		err := codeset.Read(r, format, func(entry codeset.Entry) error {
+			batch = append(batch, domain.MedicalCode{
+				System:           system,
+				Code:             entry.Code,
+				Description:      entry.Description,
+				ShortDescription: entry.ShortDescription,
+				Billable:         entry.Billable,
+				Active:           true,
+				Release:          release,
+				UpdatedAt:        now,
+			})
+			if len(batch) == codeImportBatch {
+				return flush()
+			}
+			return nil
+		})
+		if errors.Is(err, codeset.ErrUnknownFormat) {
+			return domain.NewValidationError(domain.FieldError{Field: "format", Message: fmt.Sprintf("must be one of: %v", codeset.Formats)})
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s file: %w", system, err)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		// An empty or wrong file must not retire the whole catalog.
+		if result.Imported == 0 {
+			return domain.NewBadRequestError("empty_code_file", "the file contains no "+system+" codes")
+		}
+
+		result.Deactivated, err = uc.codeRepo.DeactivateMissing(ctx, system, release)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (uc *codeCatalogUseCase) SearchCodes(ctx context.Context, search domain.CodeSearch, limit int) ([]domain.MedicalCode, error) {
+	if search.System != "" {
+		if _, ok := codeSystemFormats[search.System]; !ok {
+			return nil, domain.NewValidationError(domain.FieldError{Field: "system", Message: "must be one of: ICD-10-CM CPT HCPCS"})
+		}
+	}
+	return uc.codeRepo.Search(ctx, search, limit)
+}
+
+func (uc *codeCatalogUseCase) GetCode(ctx context.Context, system, code string) (*domain.MedicalCode, error) {
+	return uc.codeRepo.Get(ctx, system, normalizeCode(system, code))
+}
+
+// normalizeCode writes a code the way the catalog stores it.
+func normalizeCode(system, code string) string {
+	if system == domain.CodeSystemICD10CM {
+		return codeset.FormatICD10(code)
+	}
+	return strings.ToUpper(strings.TrimSpace(code))
+}
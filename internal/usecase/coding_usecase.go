@@ -0,0 +1,198 @@
+// internal/usecase/coding_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"errors"
+	"strings"
+)
+
+type CodingUseCase interface {
+	// GetVisitCoding returns the diagnoses and procedures of an appointment.
+	GetVisitCoding(ctx context.Context, appointmentID uint) (*domain.VisitCoding, error)
+	// AddDiagnosis records a billable, active ICD-10-CM code. A zero rank
+	// appends the diagnosis after the existing ones.
+	AddDiagnosis(ctx context.Context, diagnosis *domain.Diagnosis, actor *domain.User) error
+	RemoveDiagnosis(ctx context.Context, appointmentID, id uint) error
+	// AddProcedure records an active CPT or HCPCS code. The system is
+	// inferred from the code when empty, and units default to 1.
+	AddProcedure(ctx context.Context, procedure *domain.Procedure, actor *domain.User) error
+	RemoveProcedure(ctx context.Context, appointmentID, id uint) error
+	// EncounterAppointment returns the appointment an encounter documents,
+	// which is where codes entered on the encounter are recorded.
+	EncounterAppointment(ctx context.Context, encounterID uint) (uint, error)
+}
+
+// maxDiagnoses is the number of diagnoses a professional claim can carry.
+const maxDiagnoses = 12
+
+type codingUseCase struct {
+	transactor      repository.Transactor
+	codingRepo      repository.CodingRepository
+	codeRepo        repository.CodeRepository
+	appointmentRepo repository.AppointmentRepository
+	encounterRepo   repository.EncounterRepository
+}
+
+func NewCodingUseCase(
+	transactor repository.Transactor,
+	codingRepo repository.CodingRepository,
+	codeRepo repository.CodeRepository,
+	appointmentRepo repository.AppointmentRepository,
+	encounterRepo repository.EncounterRepository,
+) CodingUseCase {
+	return &codingUseCase{
+		transactor:      transactor,
+		codingRepo:      codingRepo,
+		codeRepo:        codeRepo,
+		appointmentRepo: appointmentRepo,
+		encounterRepo:   encounterRepo,
+	}
+}
+
+func (uc *codingUseCase) GetVisitCoding(ctx context.Context, appointmentID uint) (*domain.VisitCoding, error) {
+	if _, err := uc.appointmentRepo.GetByID(ctx, appointmentID); err != nil {
+		return nil, err
+	}
+	diagnoses, err := uc.codingRepo.ListDiagnoses(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	procedures, err := uc.codingRepo.ListProcedures(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.VisitCoding{AppointmentID: appointmentID, Diagnoses: diagnoses, Procedures: procedures}, nil
+}
+
+func (uc *codingUseCase) AddDiagnosis(ctx context.Context, diagnosis *domain.Diagnosis, actor *domain.User) error {
+	diagnosis.ID = 0
+	diagnosis.System = domain.CodeSystemICD10CM
+	diagnosis.Code = normalizeCode(diagnosis.System, diagnosis.Code)
+	diagnosis.CreatedByUserID = actorID(actor)
+	if err := validateStruct(diagnosis); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := uc.appointmentRepo.GetByID(ctx, diagnosis.AppointmentID); err != nil {
+			return err
+		}
+		code, err := uc.catalogCode(ctx, diagnosis.System, diagnosis.Code)
+		if err != nil {
+			return err
+		}
+		if !code.Billable {
+			return domain.NewValidationError(domain.FieldError{
+				Field:   "code",
+				Message: "is a category; choose one of its more specific codes",
+			})
+		}
+
+		if diagnosis.Rank == 0 {
+			existing, err := uc.codingRepo.ListDiagnoses(ctx, diagnosis.AppointmentID)
+			if err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				diagnosis.Rank = existing[len(existing)-1].Rank + 1
+			} else {
+				diagnosis.Rank = 1
+			}
+			if diagnosis.Rank > maxDiagnoses {
+				return domain.NewConflictError("too_many_diagnoses", "an appointment can have at most 12 diagnoses")
+			}
+		}
+
+		if err := uc.codingRepo.CreateDiagnosis(ctx, diagnosis); err != nil {
+			return err
+		}
+		diagnosis.Description = code.Description
+		return nil
+	})
+}
+
+func (uc *codingUseCase) RemoveDiagnosis(ctx context.Context, appointmentID, id uint) error {
+	if _, err := uc.appointmentRepo.GetByID(ctx, appointmentID); err != nil {
+		return err
+	}
+	return uc.codingRepo.DeleteDiagnosis(ctx, appointmentID, id)
+}
+
+func (uc *codingUseCase) AddProcedure(ctx context.Context, procedure *domain.Procedure, actor *domain.User) error {
+	procedure.ID = 0
+	procedure.Code = strings.ToUpper(strings.TrimSpace(procedure.Code))
+	if procedure.System == "" {
+		procedure.System = procedureSystem(procedure.Code)
+	}
+	if procedure.Modifiers == nil {
+		procedure.Modifiers = []string{}
+	}
+	for i, modifier := range procedure.Modifiers {
+		procedure.Modifiers[i] = strings.ToUpper(strings.TrimSpace(modifier))
+	}
+	if procedure.Units == 0 {
+		procedure.Units = 1
+	}
+	procedure.CreatedByUserID = actorID(actor)
+	if err := validateStruct(procedure); err != nil {
+		return err
+	}
+
+	if _, err := uc.appointmentRepo.GetByID(ctx, procedure.AppointmentID); err != nil {
+		return err
+	}
+	code, err := uc.catalogCode(ctx, procedure.System, procedure.Code)
+	if err != nil {
+		return err
+	}
+	if err := uc.codingRepo.CreateProcedure(ctx, procedure); err != nil {
+		return err
+	}
+	procedure.Description = code.Description
+	return nil
+}
+
+func (uc *codingUseCase) RemoveProcedure(ctx context.Context, appointmentID, id uint) error {
+	if _, err := uc.appointmentRepo.GetByID(ctx, appointmentID); err != nil {
+		return err
+	}
+	return uc.codingRepo.DeleteProcedure(ctx, appointmentID, id)
+}
+
+func (uc *codingUseCase) EncounterAppointment(ctx context.Context, encounterID uint) (uint, error) {
+	encounter, err := uc.encounterRepo.GetByID(ctx, encounterID)
+	if err != nil {
+		return 0, err
+	}
+	return encounter.AppointmentID, nil
+}
+
+// catalogCode looks up a code that may be recorded: known and active.
+func (uc *codingUseCase) catalogCode(ctx context.Context, system, code string) (*domain.MedicalCode, error) {
+	medicalCode, err := uc.codeRepo.Get(ctx, system, code)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "code", Message: "is not a known " + system + " code"})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !medicalCode.Active {
+		return nil, domain.NewValidationError(domain.FieldError{
+			Field:   "code",
+			Message: "was retired after the " + medicalCode.Release + " release",
+		})
+	}
+	return medicalCode, nil
+}
+
+// procedureSystem tells HCPCS level II codes, which start with a letter
+// (J1100), from CPT codes, which start with a digit (99213, 0001F).
+func procedureSystem(code string) string {
+	if code != "" && code[0] >= 'A' && code[0] <= 'Z' {
+		return domain.CodeSystemHCPCS
+	}
+	return domain.CodeSystemCPT
+}
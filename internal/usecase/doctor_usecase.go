@@ -0,0 +1,32 @@
+// internal/usecase/doctor_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"strings"
+)
+
+type DoctorUseCase interface {
+	GetDoctor(ctx context.Context, id uint) (*domain.Doctor, error)
+	// ListDoctors returns the doctors whose name contains name, all of
+	// them when it is empty.
+	ListDoctors(ctx context.Context, name string) ([]domain.Doctor, error)
+}
+
+type doctorUseCase struct {
+	doctorRepo repository.DoctorRepository
+}
+
+func NewDoctorUseCase(doctorRepo repository.DoctorRepository) DoctorUseCase {
+	return &doctorUseCase{doctorRepo: doctorRepo}
+}
+
+func (uc *doctorUseCase) GetDoctor(ctx context.Context, id uint) (*domain.Doctor, error) {
+	return uc.doctorRepo.GetByID(ctx, id)
+}
+
+func (uc *doctorUseCase) ListDoctors(ctx context.Context, name string) ([]domain.Doctor, error) {
+	return uc.doctorRepo.List(ctx, strings.TrimSpace(name))
+}
@@ -0,0 +1,153 @@
+// internal/usecase/drug_catalog_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/drugdata"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type DrugCatalogUseCase interface {
+	// ImportDrugs loads a release of the drug catalog from a drug file.
+	// Drugs that are not in the file are deactivated.
+	ImportDrugs(ctx context.Context, release string, r io.Reader) (*DrugImport, error)
+	// ImportInteractions replaces the interaction dataset with the content
+	// of an interaction file and returns the number of interactions.
+	ImportInteractions(ctx context.Context, r io.Reader) (int, error)
+	SearchDrugs(ctx context.Context, query string, limit int) ([]domain.Drug, error)
+	GetDrug(ctx context.Context, rxcui string) (*domain.Drug, error)
+}
+
+// DrugImport summarizes a drug catalog import.
+type DrugImport struct {
+	Release     string `json:"release"`
+	Imported    int    `json:"imported"`
+	Deactivated int64  `json:"deactivated"`
+}
+
+type drugCatalogUseCase struct {
+	transactor repository.Transactor
+	drugRepo   repository.DrugRepository
+	now        func() time.Time
+}
+
+func NewDrugCatalogUseCase(transactor repository.Transactor, drugRepo repository.DrugRepository) DrugCatalogUseCase {
+	return &drugCatalogUseCase{transactor: transactor, drugRepo: drugRepo, now: time.Now}
+}
+
+func (uc *drugCatalogUseCase) ImportDrugs(ctx context.Context, release string, r io.Reader) (*DrugImport, error) {
+	if release = strings.TrimSpace(release); release == "" {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "release", Message: "is required"})
+	}
+
+	result := &DrugImport{Release: release}
+	now := uc.now()
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		batch := make([]domain.Drug, 0, codeImportBatch)
+		flush := func() error {
+			if err := uc.drugRepo.Upsert(ctx, batch); err != nil {
+				return err
+			}
+			result.Imported += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+
+		err := drugdata.ReadDrugs(r, func(drug drugdata.Drug) error {
+			batch = append(batch, domain.Drug{
+				RxCUI:       drug.RxCUI,
+				Name:        drug.Name,
+				Ingredients: drug.Ingredients,
+				Strength:    drug.Strength,
+				DoseForm:    drug.DoseForm,
+				Classes:     drug.Classes,
+				Active:      true,
+				Release:     release,
+				UpdatedAt:   now,
+			})
+			if len(batch) == codeImportBatch {
+				return flush()
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read drug file: %w", err)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		// An empty or wrong file must not retire the whole catalog.
+		if result.Imported == 0 {
+			return domain.NewBadRequestError("empty_drug_file", "the file contains no drugs")
+		}
+
+		result.Deactivated, err = uc.drugRepo.DeactivateMissing(ctx, release)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (uc *drugCatalogUseCase) ImportInteractions(ctx context.Context, r io.Reader) (int, error) {
+	// Read the whole file first: a pair listed twice keeps its last entry,
+	// and a file that fails to parse leaves the current dataset alone.
+	var interactions []domain.DrugInteraction
+	index := map[[2]string]int{}
+	err := drugdata.ReadInteractions(r, func(interaction drugdata.Interaction) error {
+		entry := domain.DrugInteraction{
+			IngredientA: interaction.A,
+			IngredientB: interaction.B,
+			Severity:    interaction.Severity,
+			Description: interaction.Description,
+		}
+		key := [2]string{interaction.A, interaction.B}
+		if i, ok := index[key]; ok {
+			interactions[i] = entry
+			return nil
+		}
+		index[key] = len(interactions)
+		interactions = append(interactions, entry)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read interaction file: %w", err)
+	}
+	if len(interactions) == 0 {
+		return 0, domain.NewBadRequestError("empty_interaction_file", "the file contains no interactions")
+	}
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.drugRepo.DeleteInteractions(ctx); err != nil {
+			return err
+		}
+		for start := 0; start < len(interactions); start += codeImportBatch {
+			end := start + codeImportBatch
+			if end > len(interactions) {
+				end = len(interactions)
+			}
+			if err := uc.drugRepo.CreateInteractions(ctx, interactions[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(interactions), nil
+}
+
+func (uc *drugCatalogUseCase) SearchDrugs(ctx context.Context, query string, limit int) ([]domain.Drug, error) {
+	return uc.drugRepo.Search(ctx, query, limit)
+}
+
+func (uc *drugCatalogUseCase) GetDrug(ctx context.Context, rxcui string) (*domain.Drug, error) {
+	return uc.drugRepo.Get(ctx, strings.TrimSpace(rxcui))
+}
@@ -0,0 +1,161 @@
+// internal/usecase/encounter_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"fmt"
+	"time"
+)
+
+type EncounterUseCase interface {
+	// CreateEncounter starts a draft encounter documenting a completed appointment.
+	CreateEncounter(ctx context.Context, encounter *domain.Encounter, actor *domain.User) error
+	GetEncounter(ctx context.Context, id uint) (*domain.Encounter, error)
+	GetAppointmentEncounter(ctx context.Context, appointmentID uint) (*domain.Encounter, error)
+	ListPatientEncounters(ctx context.Context, patientID uint) ([]domain.Encounter, error)
+	// UpdateEncounter replaces the SOAP sections of a draft encounter.
+	// ifMatch, when set, is the version the client last saw.
+	UpdateEncounter(ctx context.Context, encounter *domain.Encounter, ifMatch *uint) error
+	// SignEncounter makes a draft encounter final. Only doctors sign.
+	SignEncounter(ctx context.Context, id uint, ifMatch *uint, actor *domain.User) (*domain.Encounter, error)
+	// AddAddendum appends a correction to a signed encounter.
+	AddAddendum(ctx context.Context, addendum *domain.EncounterAddendum, actor *domain.User) error
+}
+
+type encounterUseCase struct {
+	encounterRepo   repository.EncounterRepository
+	appointmentRepo repository.AppointmentRepository
+	patientRepo     repository.PatientRepository
+	now             func() time.Time
+}
+
+func NewEncounterUseCase(
+	encounterRepo repository.EncounterRepository,
+	appointmentRepo repository.AppointmentRepository,
+	patientRepo repository.PatientRepository,
+) EncounterUseCase {
+	return &encounterUseCase{
+		encounterRepo:   encounterRepo,
+		appointmentRepo: appointmentRepo,
+		patientRepo:     patientRepo,
+		now:             time.Now,
+	}
+}
+
+func (uc *encounterUseCase) CreateEncounter(ctx context.Context, encounter *domain.Encounter, actor *domain.User) error {
+	if err := validateStruct(encounter); err != nil {
+		return err
+	}
+	appointment, err := uc.appointmentRepo.GetByID(ctx, encounter.AppointmentID)
+	if err != nil {
+		return err
+	}
+	if appointment.Status != domain.AppointmentStatusCompleted {
+		return domain.NewConflictError("appointment_not_completed",
+			fmt.Sprintf("appointment %d is %s; only completed appointments can be documented", appointment.ID, appointment.Status))
+	}
+
+	encounter.ID = 0
+	encounter.PatientID = appointment.PatientID
+	encounter.DoctorID = appointment.DoctorID
+	encounter.Status = domain.EncounterDraft
+	encounter.AuthorUserID = actorID(actor)
+	encounter.SignedByUserID, encounter.SignedAt = nil, nil
+	encounter.Addenda = []domain.EncounterAddendum{}
+	return uc.encounterRepo.Create(ctx, encounter)
+}
+
+func (uc *encounterUseCase) GetEncounter(ctx context.Context, id uint) (*domain.Encounter, error) {
+	return uc.encounterRepo.GetByID(ctx, id)
+}
+
+func (uc *encounterUseCase) GetAppointmentEncounter(ctx context.Context, appointmentID uint) (*domain.Encounter, error) {
+	if _, err := uc.appointmentRepo.GetByID(ctx, appointmentID); err != nil {
+		return nil, err
+	}
+	return uc.encounterRepo.GetByAppointment(ctx, appointmentID)
+}
+
+func (uc *encounterUseCase) ListPatientEncounters(ctx context.Context, patientID uint) ([]domain.Encounter, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return uc.encounterRepo.ListByPatient(ctx, patientID)
+}
+
+func (uc *encounterUseCase) UpdateEncounter(ctx context.Context, encounter *domain.Encounter, ifMatch *uint) error {
+	existing, err := uc.editableEncounter(ctx, encounter.ID, ifMatch)
+	if err != nil {
+		return err
+	}
+	if err := validateStruct(encounter); err != nil {
+		return err
+	}
+
+	// Everything but the SOAP sections is kept from the stored encounter.
+	subjective, objective, assessment, plan := encounter.Subjective, encounter.Objective, encounter.Assessment, encounter.Plan
+	*encounter = *existing
+	encounter.Subjective, encounter.Objective, encounter.Assessment, encounter.Plan = subjective, objective, assessment, plan
+
+	return versionConflict(uc.encounterRepo.Update(ctx, encounter), "encounter", ifMatch)
+}
+
+func (uc *encounterUseCase) SignEncounter(ctx context.Context, id uint, ifMatch *uint, actor *domain.User) (*domain.Encounter, error) {
+	if actor == nil || !actor.HasRole(domain.RoleDoctor) {
+		return nil, domain.NewForbiddenError("only doctors can sign encounters")
+	}
+	encounter, err := uc.editableEncounter(ctx, id, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.Empty() {
+		return nil, domain.NewValidationError(domain.FieldError{
+			Field:   "assessment",
+			Message: "at least one SOAP section is required before signing",
+		})
+	}
+
+	signedAt := uc.now()
+	encounter.Status = domain.EncounterSigned
+	encounter.SignedByUserID = actorID(actor)
+	encounter.SignedAt = &signedAt
+	if err := uc.encounterRepo.Update(ctx, encounter); err != nil {
+		return nil, versionConflict(err, "encounter", ifMatch)
+	}
+	return encounter, nil
+}
+
+func (uc *encounterUseCase) AddAddendum(ctx context.Context, addendum *domain.EncounterAddendum, actor *domain.User) error {
+	if err := validateStruct(addendum); err != nil {
+		return err
+	}
+	encounter, err := uc.encounterRepo.GetByID(ctx, addendum.EncounterID)
+	if err != nil {
+		return err
+	}
+	if encounter.Status != domain.EncounterSigned {
+		return domain.NewConflictError("encounter_not_signed", "a draft encounter is edited directly; addenda are for signed encounters")
+	}
+
+	addendum.ID = 0
+	addendum.AuthorUserID = actorID(actor)
+	return uc.encounterRepo.AddAddendum(ctx, encounter, addendum)
+}
+
+// editableEncounter loads an encounter that may still be changed: a draft
+// whose version matches ifMatch.
+func (uc *encounterUseCase) editableEncounter(ctx context.Context, id uint, ifMatch *uint) (*domain.Encounter, error) {
+	encounter, err := uc.encounterRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion("encounter", encounter.Version, ifMatch); err != nil {
+		return nil, err
+	}
+	if encounter.Status == domain.EncounterSigned {
+		return nil, domain.NewConflictError("encounter_signed", "a signed encounter can't be changed; add an addendum instead")
+	}
+	return encounter, nil
+}
@@ -0,0 +1,250 @@
+// internal/usecase/fhir_mapping.go
+package usecase
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"doctors/internal/domain"
+	"doctors/pkg/fhir"
+)
+
+// MRNSystem identifies the practice's medical record numbers in FHIR identifiers.
+const MRNSystem = "urn:doctorsaas:mrn"
+
+// SlotLength is the time an appointment is shown to take. Appointments
+// have no duration of their own; each one is exposed as a Slot of this
+// length on its doctor's schedule.
+const SlotLength = 30 * time.Minute
+
+var genderBySex = map[string]string{
+	domain.SexFemale:   "female",
+	domain.SexMale:     "male",
+	domain.SexIntersex: "other",
+	domain.SexUnknown:  "unknown",
+}
+
+var fhirAppointmentStatuses = map[string]string{
+	domain.AppointmentStatusScheduled: fhir.AppointmentBooked,
+	domain.AppointmentStatusCompleted: fhir.AppointmentFulfilled,
+	domain.AppointmentStatusCancelled: fhir.AppointmentCancelled,
+}
+
+func fhirID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func fhirMeta(version uint, updatedAt time.Time) *fhir.Meta {
+	meta := &fhir.Meta{}
+	if version > 0 {
+		meta.VersionID = fhirID(version)
+	}
+	if !updatedAt.IsZero() {
+		t := updatedAt.UTC()
+		meta.LastUpdated = &t
+	}
+	if *meta == (fhir.Meta{}) {
+		return nil
+	}
+	return meta
+}
+
+// humanName splits a name into given names and a family name at the last
+// word, which is right more often than not.
+func humanName(name string) fhir.HumanName {
+	hn := fhir.HumanName{Use: "official", Text: name}
+	words := strings.Fields(name)
+	if len(words) > 0 {
+		hn.Family = words[len(words)-1]
+		hn.Given = words[:len(words)-1]
+	}
+	return hn
+}
+
+// FHIRPatient maps a patient to a FHIR Patient.
+func FHIRPatient(p *domain.Patient) fhir.Patient {
+	active := !p.DeletedAt.Valid
+	resource := fhir.Patient{
+		ResourceType: "Patient",
+		ID:           fhirID(p.ID),
+		Meta:         fhirMeta(p.Version, p.UpdatedAt),
+		Active:       &active,
+		Name:         []fhir.HumanName{humanName(p.Name)},
+		Gender:       genderBySex[p.Sex],
+		BirthDate:    p.DateOfBirth,
+	}
+	if p.MRN != "" {
+		resource.Identifier = []fhir.Identifier{{
+			Use: "usual",
+			Type: &fhir.CodeableConcept{Coding: []fhir.Coding{{
+				System: fhir.IdentifierTypeSystem, Code: "MR", Display: "Medical record number",
+			}}},
+			System: MRNSystem,
+			Value:  p.MRN,
+		}}
+	}
+	if p.Phone != "" {
+		resource.Telecom = append(resource.Telecom, fhir.ContactPoint{System: "phone", Value: p.Phone})
+	}
+	if p.Email != "" {
+		resource.Telecom = append(resource.Telecom, fhir.ContactPoint{System: "email", Value: p.Email})
+	}
+	if a := p.Address; a != (domain.Address{}) {
+		address := fhir.Address{City: a.City, State: a.Region, PostalCode: a.PostalCode, Country: a.Country}
+		for _, line := range []string{a.Line1, a.Line2} {
+			if line != "" {
+				address.Line = append(address.Line, line)
+			}
+		}
+		resource.Address = []fhir.Address{address}
+	}
+	if p.PreferredLanguage != "" {
+		resource.Communication = []fhir.Communication{{
+			Language: fhir.CodeableConcept{Coding: []fhir.Coding{{
+				System: fhir.LanguageSystem, Code: p.PreferredLanguage,
+			}}},
+			Preferred: true,
+		}}
+	}
+	return resource
+}
+
+// PatientFromFHIR reads the elements of a Patient the practice records.
+// Elements it doesn't record are ignored.
+func PatientFromFHIR(resource fhir.Patient) domain.Patient {
+	var patient domain.Patient
+
+	if len(resource.Name) > 0 {
+		name := resource.Name[0]
+		patient.Name = strings.TrimSpace(name.Text)
+		if patient.Name == "" {
+			patient.Name = strings.TrimSpace(strings.Join(append(append([]string{}, name.Given...), name.Family), " "))
+		}
+	}
+	for _, identifier := range resource.Identifier {
+		if identifier.System == MRNSystem {
+			patient.MRN = identifier.Value
+		}
+	}
+	for _, telecom := range resource.Telecom {
+		switch {
+		case telecom.System == "phone" && patient.Phone == "":
+			patient.Phone = telecom.Value
+		case telecom.System == "email" && patient.Email == "":
+			patient.Email = telecom.Value
+		}
+	}
+	for sex, gender := range genderBySex {
+		if resource.Gender == gender {
+			patient.Sex = sex
+		}
+	}
+	patient.DateOfBirth = resource.BirthDate
+
+	if len(resource.Address) > 0 {
+		a := resource.Address[0]
+		patient.Address = domain.Address{City: a.City, Region: a.State, PostalCode: a.PostalCode, Country: a.Country}
+		if len(a.Line) > 0 {
+			patient.Address.Line1 = a.Line[0]
+		}
+		if len(a.Line) > 1 {
+			patient.Address.Line2 = strings.Join(a.Line[1:], ", ")
+		}
+	}
+	for _, communication := range resource.Communication {
+		if len(communication.Language.Coding) == 0 {
+			continue
+		}
+		if patient.PreferredLanguage == "" || communication.Preferred {
+			patient.PreferredLanguage = communication.Language.Coding[0].Code
+		}
+	}
+	return patient
+}
+
+// FHIRPractitioner maps a doctor to a FHIR Practitioner.
+func FHIRPractitioner(d *domain.Doctor) fhir.Practitioner {
+	active := true
+	resource := fhir.Practitioner{
+		ResourceType: "Practitioner",
+		ID:           fhirID(d.ID),
+		Meta:         fhirMeta(0, d.UpdatedAt),
+		Active:       &active,
+		Name:         []fhir.HumanName{humanName(d.Name)},
+	}
+	if d.Email != "" {
+		resource.Telecom = []fhir.ContactPoint{{System: "email", Value: d.Email, Use: "work"}}
+	}
+	return resource
+}
+
+// FHIRAppointment maps an appointment to a FHIR Appointment.
+func FHIRAppointment(a *domain.Appointment) fhir.Appointment {
+	start := a.DateTime.UTC()
+	end := start.Add(SlotLength)
+	created := a.CreatedAt.UTC()
+
+	participantStatus := "accepted"
+	if a.Status == domain.AppointmentStatusCancelled {
+		participantStatus = "declined"
+	}
+	return fhir.Appointment{
+		ResourceType:    "Appointment",
+		ID:              fhirID(a.ID),
+		Meta:            fhirMeta(a.Version, a.UpdatedAt),
+		Status:          fhirAppointmentStatuses[a.Status],
+		Start:           &start,
+		End:             &end,
+		MinutesDuration: int(SlotLength / time.Minute),
+		Slot:            []fhir.Reference{{Reference: "Slot/" + fhirID(a.ID)}},
+		Created:         &created,
+		Comment:         a.Notes,
+		Participant: []fhir.AppointmentParticipant{
+			{Actor: &fhir.Reference{Reference: "Patient/" + fhirID(a.PatientID)}, Required: "required", Status: participantStatus},
+			{Actor: &fhir.Reference{Reference: "Practitioner/" + fhirID(a.DoctorID)}, Required: "required", Status: participantStatus},
+		},
+	}
+}
+
+// FHIRSlot exposes the time an appointment takes on its doctor's
+// schedule. The slot is free again once the appointment is cancelled.
+func FHIRSlot(a *domain.Appointment) fhir.Slot {
+	status := fhir.SlotBusy
+	if a.Status == domain.AppointmentStatusCancelled {
+		status = fhir.SlotFree
+	}
+	start := a.DateTime.UTC()
+	return fhir.Slot{
+		ResourceType: "Slot",
+		ID:           fhirID(a.ID),
+		Meta:         fhirMeta(a.Version, a.UpdatedAt),
+		Schedule:     fhir.Reference{Reference: "Schedule/" + fhirID(a.DoctorID)},
+		Status:       status,
+		Start:        start,
+		End:          start.Add(SlotLength),
+	}
+}
+
+// AppointmentStatusesForFHIR returns the appointment statuses shown as a
+// FHIR Appointment status, none for a status the practice doesn't use.
+func AppointmentStatusesForFHIR(status string) []string {
+	for domainStatus, fhirStatus := range fhirAppointmentStatuses {
+		if status == fhirStatus {
+			return []string{domainStatus}
+		}
+	}
+	return nil
+}
+
+// SlotStatusesForFHIR returns the appointment statuses whose slots have a
+// FHIR Slot status.
+func SlotStatusesForFHIR(status string) []string {
+	switch status {
+	case fhir.SlotBusy:
+		return []string{domain.AppointmentStatusScheduled, domain.AppointmentStatusCompleted}
+	case fhir.SlotFree:
+		return []string{domain.AppointmentStatusCancelled}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// internal/usecase/fhir_mapping_test.go
+package usecase
+
+import (
+	"doctors/internal/domain"
+	"doctors/pkg/fhir"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestFHIRPatient(t *testing.T) {
+	patient := &domain.Patient{
+		ID: 7, Name: "Mary Ann Smith", Email: "mary@example.com", Phone: "+14155552671",
+		DateOfBirth: "1980-04-02", MRN: "00000042", Sex: domain.SexIntersex, PreferredLanguage: "es-MX",
+		Address: domain.Address{Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"},
+		Version: 3, UpdatedAt: time.Date(2030, 3, 14, 9, 30, 0, 0, time.FixedZone("CET", 3600)),
+	}
+
+	got, err := json.Marshal(FHIRPatient(patient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"resourceType":"Patient","id":"7","meta":{"versionId":"3","lastUpdated":"2030-03-14T08:30:00Z"},` +
+		`"identifier":[{"use":"usual","type":{"coding":[{"system":"http://terminology.hl7.org/CodeSystem/v2-0203","code":"MR","display":"Medical record number"}]},"system":"urn:doctorsaas:mrn","value":"00000042"}],` +
+		`"active":true,"name":[{"use":"official","text":"Mary Ann Smith","family":"Smith","given":["Mary","Ann"]}],` +
+		`"telecom":[{"system":"phone","value":"+14155552671"},{"system":"email","value":"mary@example.com"}],` +
+		`"gender":"other","birthDate":"1980-04-02",` +
+		`"address":[{"line":["1 Main St"],"city":"Springfield","state":"IL","postalCode":"62701","country":"US"}],` +
+		`"communication":[{"language":{"coding":[{"system":"urn:ietf:bcp:47","code":"es-MX"}]},"preferred":true}]}`
+	if string(got) != want {
+		t.Errorf("FHIRPatient() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFHIRPatientMinimal(t *testing.T) {
+	patient := &domain.Patient{ID: 8, Name: "Cher", DeletedAt: gorm.DeletedAt{Time: billingNow, Valid: true}}
+	got, err := json.Marshal(FHIRPatient(patient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"resourceType":"Patient","id":"8","active":false,"name":[{"use":"official","text":"Cher","family":"Cher"}]}`
+	if string(got) != want {
+		t.Errorf("FHIRPatient() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPatientFromFHIR(t *testing.T) {
+	patient := domain.Patient{
+		Name: "Mary Ann Smith", Email: "mary@example.com", Phone: "+14155552671",
+		DateOfBirth: "1980-04-02", MRN: "00000042", Sex: domain.SexFemale, PreferredLanguage: "es-MX",
+		Address: domain.Address{Line1: "1 Main St", Line2: "Apt 2", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"},
+	}
+	if got := PatientFromFHIR(FHIRPatient(&patient)); !reflect.DeepEqual(got, patient) {
+		t.Errorf("PatientFromFHIR(FHIRPatient()) = %+v\nwant %+v", got, patient)
+	}
+
+	resource := fhir.Patient{
+		Name:    []fhir.HumanName{{Given: []string{"Mary", "Ann"}, Family: "Smith"}},
+		Telecom: []fhir.ContactPoint{{System: "phone", Value: "+1"}, {System: "phone", Value: "+2"}},
+		Address: []fhir.Address{{Line: []string{"1 Main St", "Building B", "Apt 2"}}},
+		Communication: []fhir.Communication{
+			{Language: fhir.CodeableConcept{Coding: []fhir.Coding{{Code: "en"}}}},
+			{Language: fhir.CodeableConcept{Coding: []fhir.Coding{{Code: "fr"}}}, Preferred: true},
+		},
+		Identifier: []fhir.Identifier{{System: "urn:other", Value: "X"}},
+		Gender:     "other",
+	}
+	got := PatientFromFHIR(resource)
+	want := domain.Patient{
+		Name: "Mary Ann Smith", Phone: "+1", Sex: domain.SexIntersex, PreferredLanguage: "fr",
+		Address: domain.Address{Line1: "1 Main St", Line2: "Building B, Apt 2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PatientFromFHIR() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFHIRAppointmentAndSlot(t *testing.T) {
+	appointment := &domain.Appointment{
+		ID: 5, PatientID: 7, DoctorID: 2, Notes: "Follow-up",
+		DateTime:  time.Date(2030, 3, 20, 10, 0, 0, 0, time.FixedZone("EST", -5*3600)),
+		CreatedAt: time.Date(2030, 3, 1, 8, 0, 0, 0, time.UTC),
+		Status:    domain.AppointmentStatusScheduled,
+	}
+
+	got, err := json.Marshal(FHIRAppointment(appointment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"resourceType":"Appointment","id":"5","status":"booked",` +
+		`"start":"2030-03-20T15:00:00Z","end":"2030-03-20T15:30:00Z","minutesDuration":30,` +
+		`"slot":[{"reference":"Slot/5"}],"created":"2030-03-01T08:00:00Z","comment":"Follow-up",` +
+		`"participant":[{"actor":{"reference":"Patient/7"},"required":"required","status":"accepted"},` +
+		`{"actor":{"reference":"Practitioner/2"},"required":"required","status":"accepted"}]}`
+	if string(got) != want {
+		t.Errorf("FHIRAppointment() =\n%s\nwant\n%s", got, want)
+	}
+
+	slot := FHIRSlot(appointment)
+	if slot.Status != fhir.SlotBusy || slot.Schedule.Reference != "Schedule/2" ||
+		!slot.Start.Equal(appointment.DateTime) || slot.End.Sub(slot.Start) != SlotLength {
+		t.Errorf("FHIRSlot() = %+v", slot)
+	}
+
+	appointment.Status = domain.AppointmentStatusCancelled
+	if got := FHIRAppointment(appointment); got.Status != fhir.AppointmentCancelled || got.Participant[0].Status != "declined" {
+		t.Errorf("cancelled FHIRAppointment() status = %s, participant %s", got.Status, got.Participant[0].Status)
+	}
+	if got := FHIRSlot(appointment); got.Status != fhir.SlotFree {
+		t.Errorf("cancelled FHIRSlot() status = %s, want free", got.Status)
+	}
+}
+
+func TestStatusesForFHIR(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"booked", AppointmentStatusesForFHIR(fhir.AppointmentBooked), []string{domain.AppointmentStatusScheduled}},
+		{"fulfilled", AppointmentStatusesForFHIR(fhir.AppointmentFulfilled), []string{domain.AppointmentStatusCompleted}},
+		{"proposed", AppointmentStatusesForFHIR(fhir.AppointmentProposed), nil},
+		{"busy", SlotStatusesForFHIR(fhir.SlotBusy), []string{domain.AppointmentStatusScheduled, domain.AppointmentStatusCompleted}},
+		{"free", SlotStatusesForFHIR(fhir.SlotFree), []string{domain.AppointmentStatusCancelled}},
+		{"unknown slot", SlotStatusesForFHIR("tentative"), nil},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: statuses = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
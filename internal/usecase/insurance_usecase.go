@@ -0,0 +1,354 @@
+// internal/usecase/insurance_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/clearinghouse"
+	"doctors/pkg/x12"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type InsuranceUseCase interface {
+	CreatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error
+	ListPolicies(ctx context.Context, patientID uint) ([]domain.InsurancePolicy, error)
+	// UpdatePolicy replaces all client-editable fields of one of the patient's policies.
+	UpdatePolicy(ctx context.Context, patientID uint, policy *domain.InsurancePolicy) error
+	DeletePolicy(ctx context.Context, patientID, policyID uint) error
+	// CheckEligibility asks the payer whether the policy covers the patient
+	// on serviceDate (YYYY-MM-DD, today when empty). Clearinghouse failures
+	// are recorded on the returned check rather than returned as errors.
+	CheckEligibility(ctx context.Context, patientID, policyID uint, serviceDate string, actor *domain.User) (*domain.EligibilityCheck, error)
+	ListEligibilityChecks(ctx context.Context, patientID uint, limit int) ([]domain.EligibilityCheck, error)
+	// CheckEligibilityForDate checks the primary policy of every patient
+	// with a scheduled appointment on date and returns the number of checks.
+	// Policies the clearinghouse already answered for that date are skipped.
+	CheckEligibilityForDate(ctx context.Context, date time.Time) (int, error)
+}
+
+// EligibilitySettings identify this practice in X12 interchanges.
+type EligibilitySettings struct {
+	SenderID     string
+	ReceiverID   string
+	ProviderName string
+	ProviderNPI  string
+	Production   bool
+}
+
+type insuranceUseCase struct {
+	transactor      repository.Transactor
+	insuranceRepo   repository.InsuranceRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	clearinghouse   clearinghouse.Client
+	settings        EligibilitySettings
+	now             func() time.Time
+}
+
+func NewInsuranceUseCase(
+	transactor repository.Transactor,
+	insuranceRepo repository.InsuranceRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	clearinghouse clearinghouse.Client,
+	settings EligibilitySettings,
+) InsuranceUseCase {
+	return &insuranceUseCase{
+		transactor:      transactor,
+		insuranceRepo:   insuranceRepo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		clearinghouse:   clearinghouse,
+		settings:        settings,
+		now:             time.Now,
+	}
+}
+
+func (uc *insuranceUseCase) CreatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error {
+	policy.ID = 0
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := uc.patientRepo.GetByID(ctx, policy.PatientID); err != nil {
+			return err
+		}
+		if err := uc.validatePolicy(ctx, policy); err != nil {
+			return err
+		}
+		return uc.insuranceRepo.CreatePolicy(ctx, policy)
+	})
+}
+
+func (uc *insuranceUseCase) ListPolicies(ctx context.Context, patientID uint) ([]domain.InsurancePolicy, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return uc.insuranceRepo.ListPolicies(ctx, patientID)
+}
+
+func (uc *insuranceUseCase) UpdatePolicy(ctx context.Context, patientID uint, policy *domain.InsurancePolicy) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		existing, err := uc.getPolicy(ctx, patientID, policy.ID)
+		if err != nil {
+			return err
+		}
+		policy.PatientID = existing.PatientID
+		policy.CreatedAt = existing.CreatedAt
+		if err := uc.validatePolicy(ctx, policy); err != nil {
+			return err
+		}
+		return uc.insuranceRepo.UpdatePolicy(ctx, policy)
+	})
+}
+
+func (uc *insuranceUseCase) DeletePolicy(ctx context.Context, patientID, policyID uint) error {
+	if _, err := uc.getPolicy(ctx, patientID, policyID); err != nil {
+		return err
+	}
+	return uc.insuranceRepo.DeletePolicy(ctx, policyID)
+}
+
+// getPolicy loads a policy, treating another patient's policy as missing.
+func (uc *insuranceUseCase) getPolicy(ctx context.Context, patientID, policyID uint) (*domain.InsurancePolicy, error) {
+	policy, err := uc.insuranceRepo.GetPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+	if policy.PatientID != patientID {
+		return nil, domain.NewNotFoundError("insurance_policy", policyID)
+	}
+	return policy, nil
+}
+
+// validatePolicy normalizes and checks a policy, including that no other
+// policy of the patient has the same priority at the same time.
+func (uc *insuranceUseCase) validatePolicy(ctx context.Context, policy *domain.InsurancePolicy) error {
+	policy.PayerName = strings.TrimSpace(policy.PayerName)
+	policy.PayerID = strings.TrimSpace(policy.PayerID)
+	policy.MemberID = strings.TrimSpace(policy.MemberID)
+	policy.GroupNumber = strings.TrimSpace(policy.GroupNumber)
+	policy.SubscriberName = strings.TrimSpace(policy.SubscriberName)
+	if policy.SubscriberRelationship == domain.SubscriberSelf {
+		policy.SubscriberName = ""
+		policy.SubscriberDateOfBirth = ""
+	}
+
+	var extra []domain.FieldError
+	if policy.SubscriberRelationship != "" && policy.SubscriberRelationship != domain.SubscriberSelf && policy.SubscriberName == "" {
+		extra = append(extra, domain.FieldError{Field: "subscriber_name", Message: "is required unless the patient is the subscriber"})
+	}
+	if policy.EffectiveTo != "" && policy.EffectiveTo < policy.EffectiveFrom {
+		extra = append(extra, domain.FieldError{Field: "effective_to", Message: "must not be before effective_from"})
+	}
+	if err := validateStruct(policy, extra...); err != nil {
+		return err
+	}
+
+	others, err := uc.insuranceRepo.ListPolicies(ctx, policy.PatientID)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.ID != policy.ID && other.Priority == policy.Priority && other.Overlaps(*policy) {
+			return domain.NewConflictError("insurance_priority_overlap",
+				fmt.Sprintf("policy %d is already the patient's %s insurance for part of this period", other.ID, policy.Priority))
+		}
+	}
+	return nil
+}
+
+func (uc *insuranceUseCase) CheckEligibility(ctx context.Context, patientID, policyID uint, serviceDate string, actor *domain.User) (*domain.EligibilityCheck, error) {
+	if serviceDate == "" {
+		serviceDate = uc.now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", serviceDate); err != nil {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "service_date", Message: "must be a date in YYYY-MM-DD format"})
+	}
+	policy, err := uc.getPolicy(ctx, patientID, policyID)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := uc.patientRepo.GetByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The check is saved before the inquiry is sent so its ID can number
+	// the interchange. No transaction is held open while the payer answers.
+	check := &domain.EligibilityCheck{
+		PolicyID:        policy.ID,
+		ServiceDate:     serviceDate,
+		Status:          domain.EligibilityPending,
+		Benefits:        []domain.EligibilityBenefit{},
+		CheckedByUserID: actorID(actor),
+		CheckedAt:       uc.now(),
+	}
+	if err := uc.insuranceRepo.CreateCheck(ctx, check); err != nil {
+		return nil, err
+	}
+
+	request := x12.Build270(uc.eligibilityRequest(check, policy, patient))
+	check.Request = string(request)
+	if response, err := uc.clearinghouse.CheckEligibility(ctx, request); err != nil {
+		check.Status = domain.EligibilityError
+		check.Error = err.Error()
+	} else {
+		check.Response = string(response)
+		applyEligibilityResponse(check, response)
+	}
+	check.CheckedAt = uc.now()
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.insuranceRepo.UpdateCheck(ctx, check); err != nil {
+			return err
+		}
+		// A failed inquiry says nothing about coverage, so appointments keep
+		// the previous result.
+		if policy.Priority != domain.InsurancePrimary || check.Status == domain.EligibilityError {
+			return nil
+		}
+		return uc.appointmentRepo.AttachEligibility(ctx, patient.ID, check.ID, uc.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+func (uc *insuranceUseCase) eligibilityRequest(check *domain.EligibilityCheck, policy *domain.InsurancePolicy, patient *domain.Patient) x12.EligibilityRequest {
+	first, last := splitName(patient.Name)
+	patientPerson := x12.Person{FirstName: first, LastName: last, MemberID: policy.MemberID, DateOfBirth: patient.DateOfBirth}
+
+	req := x12.EligibilityRequest{
+		Envelope: x12.Envelope{
+			SenderID:      uc.settings.SenderID,
+			ReceiverID:    uc.settings.ReceiverID,
+			ControlNumber: int(check.ID),
+			Production:    uc.settings.Production,
+			Time:          check.CheckedAt,
+		},
+		TraceNumber:  strconv.FormatUint(uint64(check.ID), 10),
+		PayerName:    policy.PayerName,
+		PayerID:      policy.PayerID,
+		ProviderName: uc.settings.ProviderName,
+		ProviderNPI:  uc.settings.ProviderNPI,
+		Subscriber:   patientPerson,
+		GroupNumber:  policy.GroupNumber,
+		ServiceDate:  check.ServiceDate,
+	}
+	if policy.SubscriberRelationship != domain.SubscriberSelf {
+		first, last := splitName(policy.SubscriberName)
+		req.Subscriber = x12.Person{FirstName: first, LastName: last, MemberID: policy.MemberID, DateOfBirth: policy.SubscriberDateOfBirth}
+		patientPerson.MemberID = ""
+		req.Dependent = &patientPerson
+	}
+	return req
+}
+
+// applyEligibilityResponse records what a 271 says on the check.
+func applyEligibilityResponse(check *domain.EligibilityCheck, response []byte) {
+	resp, err := x12.Parse271(response)
+	if err != nil {
+		check.Status = domain.EligibilityError
+		check.Error = "unreadable 271 response: " + err.Error()
+		return
+	}
+
+	check.PlanBegin, check.PlanEnd = resp.PlanBegin, resp.PlanEnd
+	for _, b := range resp.Benefits {
+		check.Benefits = append(check.Benefits, domain.EligibilityBenefit{
+			Code:        b.Code,
+			Name:        x12.BenefitName(b.Code),
+			ServiceType: b.ServiceType,
+			Coverage:    b.CoverageLevel,
+			Plan:        b.PlanDescription,
+			Period:      b.Period,
+			Amount:      b.Amount,
+			Percent:     b.Percent,
+			InNetwork:   b.InNetwork,
+			Messages:    b.Messages,
+		})
+	}
+	for _, rej := range resp.Rejections {
+		check.Rejections = append(check.Rejections, rej.Code+": "+x12.RejectionReason(rej.Code))
+	}
+
+	switch {
+	case len(resp.Rejections) > 0:
+		check.Status = domain.EligibilityRejected
+	case resp.Active():
+		check.Status = domain.EligibilityActive
+	case resp.Inactive():
+		check.Status = domain.EligibilityInactive
+	default:
+		check.Status = domain.EligibilityUnknown
+	}
+}
+
+// splitName splits a full name into X12's first and last name: the last
+// word is the last name.
+func splitName(name string) (first, last string) {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return "", ""
+	}
+	return strings.Join(words[:len(words)-1], " "), words[len(words)-1]
+}
+
+func (uc *insuranceUseCase) ListEligibilityChecks(ctx context.Context, patientID uint, limit int) ([]domain.EligibilityCheck, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return uc.insuranceRepo.ListChecks(ctx, patientID, limit)
+}
+
+func (uc *insuranceUseCase) CheckEligibilityForDate(ctx context.Context, date time.Time) (int, error) {
+	appointments, err := uc.appointmentRepo.GetByDate(ctx, date, false)
+	if err != nil {
+		return 0, err
+	}
+
+	day := date.Format("2006-01-02")
+	checked := 0
+	seen := map[uint]bool{}
+	for _, apt := range appointments {
+		if apt.Status != domain.AppointmentStatusScheduled || seen[apt.PatientID] {
+			continue
+		}
+		seen[apt.PatientID] = true
+
+		policies, err := uc.insuranceRepo.ListPolicies(ctx, apt.PatientID)
+		if err != nil {
+			return checked, err
+		}
+		for _, policy := range policies {
+			if policy.Priority != domain.InsurancePrimary || !policy.ActiveOn(day) {
+				continue
+			}
+			// A rerun of the day, e.g. after a restart, doesn't ask again.
+			done, err := uc.insuranceRepo.HasCheck(ctx, policy.ID, day)
+			if err != nil {
+				return checked, err
+			}
+			if done {
+				break
+			}
+			check, err := uc.CheckEligibility(ctx, apt.PatientID, policy.ID, day, nil)
+			if errors.Is(err, domain.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return checked, err
+			}
+			if check.Status == domain.EligibilityError {
+				fmt.Printf("Eligibility check %d for patient %d failed: %s\n", check.ID, apt.PatientID, check.Error)
+			}
+			checked++
+			break
+		}
+	}
+	return checked, nil
+}
@@ -0,0 +1,517 @@
+// internal/usecase/lab_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/email"
+	"doctors/pkg/hl7"
+	"doctors/pkg/lab"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LabUseCase interface {
+	// CreateOrder orders tests for an appointment and sends the order to
+	// the laboratory. An order the laboratory doesn't take is kept with
+	// status failed and can be sent again.
+	CreateOrder(ctx context.Context, order *domain.LabOrder, actor *domain.User) error
+	// SendOrder sends a pending or failed order again.
+	SendOrder(ctx context.Context, id uint) (*domain.LabOrder, error)
+	GetOrder(ctx context.Context, id uint) (*domain.LabOrder, error)
+	ListPatientOrders(ctx context.Context, patientID uint) ([]domain.LabOrder, error)
+	ListAppointmentOrders(ctx context.Context, appointmentID uint) ([]domain.LabOrder, error)
+	// ReceiveMessage processes a message from the laboratory, normally
+	// ORU^R01 results, and returns the ACK to answer it with.
+	ReceiveMessage(ctx context.Context, message []byte) []byte
+}
+
+// LabSettings identify this practice and the laboratory in HL7 messages.
+type LabSettings struct {
+	Application    string
+	Facility       string
+	LabApplication string
+	LabFacility    string
+	Production     bool
+}
+
+type labUseCase struct {
+	transactor      repository.Transactor
+	labRepo         repository.LabRepository
+	appointmentRepo repository.AppointmentRepository
+	patientRepo     repository.PatientRepository
+	doctorRepo      repository.DoctorRepository
+	lab             lab.Client
+	emailSender     email.Sender
+	settings        LabSettings
+	now             func() time.Time
+}
+
+func NewLabUseCase(
+	transactor repository.Transactor,
+	labRepo repository.LabRepository,
+	appointmentRepo repository.AppointmentRepository,
+	patientRepo repository.PatientRepository,
+	doctorRepo repository.DoctorRepository,
+	lab lab.Client,
+	emailSender email.Sender,
+	settings LabSettings,
+) LabUseCase {
+	return &labUseCase{
+		transactor:      transactor,
+		labRepo:         labRepo,
+		appointmentRepo: appointmentRepo,
+		patientRepo:     patientRepo,
+		doctorRepo:      doctorRepo,
+		lab:             lab,
+		emailSender:     emailSender,
+		settings:        settings,
+		now:             time.Now,
+	}
+}
+
+// loincCode matches the form of a LOINC code: up to seven digits, a dash
+// and a check digit.
+var loincCode = regexp.MustCompile(`^[0-9]{1,7}-[0-9]$`)
+
+func (uc *labUseCase) CreateOrder(ctx context.Context, order *domain.LabOrder, actor *domain.User) error {
+	appointment, err := uc.appointmentRepo.GetByID(ctx, order.AppointmentID)
+	if err != nil {
+		return err
+	}
+	if appointment.Status == domain.AppointmentStatusCancelled {
+		return domain.NewConflictError("appointment_cancelled", "tests can't be ordered for a cancelled appointment")
+	}
+
+	if order.Priority == "" {
+		order.Priority = domain.LabPriorityRoutine
+	}
+	order.ClinicalInfo = strings.TrimSpace(order.ClinicalInfo)
+	var extra []domain.FieldError
+	seen := map[string]bool{}
+	for i := range order.Tests {
+		test := &order.Tests[i]
+		test.Code, test.Name = strings.TrimSpace(test.Code), strings.TrimSpace(test.Name)
+		field := fmt.Sprintf("tests[%d].code", i)
+		switch {
+		case test.Code != "" && !loincCode.MatchString(test.Code):
+			extra = append(extra, domain.FieldError{Field: field, Message: "must be a LOINC code such as 2345-7"})
+		case seen[test.Code]:
+			extra = append(extra, domain.FieldError{Field: field, Message: "is ordered twice"})
+		}
+		seen[test.Code] = true
+	}
+	if err := validateStruct(order, extra...); err != nil {
+		return err
+	}
+
+	order.ID = 0
+	order.PatientID = appointment.PatientID
+	order.DoctorID = appointment.DoctorID
+	order.Status = domain.LabOrderPending
+	order.OrderedByUserID = actorID(actor)
+	order.ReportedTests = []string{}
+	order.LastError, order.FillerOrderNumber = "", ""
+	order.SentAt, order.ResultedAt = nil, nil
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.labRepo.CreateOrder(ctx, order); err != nil {
+			return err
+		}
+		order.PlacerOrderNumber = fmt.Sprintf("LAB%08d", order.ID)
+		return uc.labRepo.UpdateOrder(ctx, order)
+	})
+	if err != nil {
+		return err
+	}
+
+	// No transaction is held open while the laboratory answers.
+	if err := uc.send(ctx, order); err != nil {
+		return err
+	}
+	order.Results = []domain.LabResult{}
+	return nil
+}
+
+func (uc *labUseCase) SendOrder(ctx context.Context, id uint) (*domain.LabOrder, error) {
+	order, err := uc.labRepo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.LabOrderPending && order.Status != domain.LabOrderFailed {
+		return nil, domain.NewConflictError("lab_order_already_sent", "the laboratory already acknowledged the order")
+	}
+	if err := uc.send(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// send sends an order as ORM^O01 and records how the laboratory answered.
+// Failing to reach the laboratory is recorded on the order, not returned.
+func (uc *labUseCase) send(ctx context.Context, order *domain.LabOrder) error {
+	patient, err := uc.patientRepo.GetByID(ctx, order.PatientID)
+	if err != nil {
+		return err
+	}
+	doctor, err := uc.doctorRepo.GetByID(ctx, order.DoctorID)
+	if err != nil {
+		return err
+	}
+
+	now := uc.now()
+	message := hl7.BuildORM(uc.ormMessage(order, patient, doctor, now))
+	logged := &domain.HL7Message{
+		Direction:  domain.HL7Outbound,
+		Type:       "ORM^O01",
+		ControlID:  uc.controlID(now),
+		LabOrderID: &order.ID,
+		Payload:    string(message),
+		AckCode:    hl7.AckAccept,
+	}
+
+	if err := uc.lab.SendOrder(ctx, message); err != nil {
+		order.Status = domain.LabOrderFailed
+		order.LastError = err.Error()
+		logged.AckCode, logged.Error = "", err.Error()
+		if errors.Is(err, lab.ErrRejected) {
+			logged.AckCode = hl7.AckError
+		}
+	} else {
+		order.Status = domain.LabOrderSent
+		order.LastError = ""
+		order.SentAt = &now
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.labRepo.LogMessage(ctx, logged); err != nil {
+			return err
+		}
+		return uc.labRepo.UpdateOrder(ctx, order)
+	})
+}
+
+func (uc *labUseCase) ormMessage(order *domain.LabOrder, patient *domain.Patient, doctor *domain.Doctor, now time.Time) hl7.Order {
+	doctorFirst, doctorLast := splitName(doctor.Name)
+	msg := hl7.Order{
+		Header:            uc.header(uc.controlID(now), now),
+		Patient:           hl7Patient(patient),
+		PlacerOrderNumber: order.PlacerOrderNumber,
+		Provider:          hl7.Provider{ID: strconv.FormatUint(uint64(doctor.ID), 10), FamilyName: doctorLast, GivenName: doctorFirst},
+		Stat:              order.Priority == domain.LabPriorityStat,
+		ClinicalInfo:      order.ClinicalInfo,
+	}
+	for _, test := range order.Tests {
+		msg.Tests = append(msg.Tests, hl7.Test{Code: test.Code, Name: test.Name, System: "LN"})
+	}
+	return msg
+}
+
+// header is the envelope of a message from this practice to the laboratory.
+func (uc *labUseCase) header(controlID string, now time.Time) hl7.Header {
+	return hl7.Header{
+		SendingApplication:   uc.settings.Application,
+		SendingFacility:      uc.settings.Facility,
+		ReceivingApplication: uc.settings.LabApplication,
+		ReceivingFacility:    uc.settings.LabFacility,
+		Time:                 now,
+		ControlID:            controlID,
+		Production:           uc.settings.Production,
+	}
+}
+
+// controlID numbers an outgoing message; MSH-10 holds up to 20 characters.
+func (uc *labUseCase) controlID(now time.Time) string {
+	return strconv.FormatInt(now.UnixNano(), 10)
+}
+
+// hl7Sexes map recorded sex to HL7 table 0001.
+var hl7Sexes = map[string]string{
+	domain.SexFemale:   "F",
+	domain.SexMale:     "M",
+	domain.SexIntersex: "A",
+}
+
+func hl7Patient(patient *domain.Patient) hl7.Patient {
+	first, last := splitName(patient.Name)
+	sex, ok := hl7Sexes[patient.Sex]
+	if !ok {
+		sex = "U"
+	}
+	return hl7.Patient{ID: patient.MRN, FamilyName: last, GivenName: first, DateOfBirth: patient.DateOfBirth, Sex: sex}
+}
+
+func (uc *labUseCase) GetOrder(ctx context.Context, id uint) (*domain.LabOrder, error) {
+	return uc.labRepo.GetOrder(ctx, id)
+}
+
+func (uc *labUseCase) ListPatientOrders(ctx context.Context, patientID uint) ([]domain.LabOrder, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return uc.labRepo.ListOrdersByPatient(ctx, patientID)
+}
+
+func (uc *labUseCase) ListAppointmentOrders(ctx context.Context, appointmentID uint) ([]domain.LabOrder, error) {
+	if _, err := uc.appointmentRepo.GetByID(ctx, appointmentID); err != nil {
+		return nil, err
+	}
+	return uc.labRepo.ListOrdersByAppointment(ctx, appointmentID)
+}
+
+// errLabMessage is a problem with a message the laboratory sent, told
+// back to it in the ACK.
+type errLabMessage struct {
+	code string
+	text string
+}
+
+func (e *errLabMessage) Error() string {
+	return e.text
+}
+
+func (uc *labUseCase) ReceiveMessage(ctx context.Context, message []byte) []byte {
+	now := uc.now()
+	logged := &domain.HL7Message{Direction: domain.HL7Inbound, Payload: string(message)}
+	ack := hl7.Ack{Header: uc.header(uc.controlID(now), now), Code: hl7.AckAccept}
+
+	var orders []*domain.LabOrder
+	msg, err := hl7.Parse(message)
+	if err == nil {
+		logged.Type, logged.ControlID = msg.Type(), msg.Header().ControlID
+		ack.Header = msg.Header().Reply(ack.ControlID, now)
+		ack.AckedControlID = logged.ControlID
+		orders, err = uc.receiveResults(ctx, msg)
+	}
+
+	var msgErr *errLabMessage
+	switch {
+	case err == nil:
+	case errors.As(err, &msgErr):
+		ack.Code, ack.Text = msgErr.code, msgErr.text
+	case msg == nil:
+		ack.Code, ack.Text = hl7.AckReject, err.Error()
+	default:
+		// Storage failed: the laboratory should send the message again.
+		fmt.Printf("Failed to process HL7 message %s: %v\n", logged.ControlID, err)
+		ack.Code, ack.Text = hl7.AckError, "internal error, please resend"
+	}
+	logged.AckCode = ack.Code
+	if err != nil {
+		logged.Error = err.Error()
+	}
+	if len(orders) > 0 {
+		logged.LabOrderID = &orders[0].ID
+	}
+	if logged.Type == "" {
+		logged.Type = "unknown"
+	}
+	if err := uc.labRepo.LogMessage(ctx, logged); err != nil {
+		fmt.Printf("Failed to log HL7 message %s: %v\n", logged.ControlID, err)
+	}
+
+	for _, order := range orders {
+		if err := uc.notifyResults(ctx, order); err != nil {
+			fmt.Printf("Failed to notify results of lab order %d: %v\n", order.ID, err)
+		}
+	}
+	return hl7.BuildACK(ack)
+}
+
+// receiveResults stores the results of an ORU^R01 message and returns
+// the orders they belong to.
+func (uc *labUseCase) receiveResults(ctx context.Context, msg *hl7.Message) ([]*domain.LabOrder, error) {
+	if msg.Type() != "ORU^R01" {
+		return nil, &errLabMessage{hl7.AckReject, "unsupported message type " + msg.Type()}
+	}
+	// A laboratory that missed our ACK sends the message again.
+	controlID := msg.Header().ControlID
+	previous, err := uc.labRepo.FindMessage(ctx, domain.HL7Inbound, controlID)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.AckCode == hl7.AckAccept {
+		return nil, nil
+	}
+
+	results, err := hl7.ReadORU(msg)
+	if err != nil {
+		return nil, &errLabMessage{hl7.AckReject, err.Error()}
+	}
+
+	now := uc.now()
+	var orders []*domain.LabOrder
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		orders = nil
+		byNumber := map[string]*domain.LabOrder{}
+		for _, report := range results.Reports {
+			order, ok := byNumber[report.PlacerOrderNumber]
+			if !ok {
+				if order, err = uc.matchOrder(ctx, report.PlacerOrderNumber, results.Patient); err != nil {
+					return err
+				}
+				byNumber[report.PlacerOrderNumber] = order
+				orders = append(orders, order)
+			}
+			if err := uc.labRepo.SaveResults(ctx, labResults(order, report, now)); err != nil {
+				return err
+			}
+			applyReport(order, report, now)
+		}
+		for _, order := range orders {
+			if err := uc.labRepo.UpdateOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// matchOrder finds the order results are for and checks they are about
+// its patient.
+func (uc *labUseCase) matchOrder(ctx context.Context, placerNumber string, patient hl7.Patient) (*domain.LabOrder, error) {
+	order, err := uc.labRepo.FindOrderByPlacerNumber(ctx, placerNumber)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, &errLabMessage{hl7.AckError, fmt.Sprintf("unknown placer order number %q", placerNumber)}
+	}
+	stored, err := uc.patientRepo.GetByID(ctx, order.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	if patient.ID != "" && patient.ID != stored.MRN {
+		return nil, &errLabMessage{hl7.AckError, fmt.Sprintf("order %s is not for patient %s", placerNumber, patient.ID)}
+	}
+	return order, nil
+}
+
+// labResults converts the observations of a report. When a code is
+// reported twice the last observation wins.
+func labResults(order *domain.LabOrder, report hl7.Report, now time.Time) []domain.LabResult {
+	var results []domain.LabResult
+	index := map[string]int{}
+	for _, obs := range report.Observations {
+		code := obs.Test.Code
+		if code == "" {
+			continue
+		}
+		result := domain.LabResult{
+			LabOrderID:     order.ID,
+			PatientID:      order.PatientID,
+			Code:           code,
+			Name:           obs.Test.Name,
+			ValueType:      obs.ValueType,
+			Value:          obs.Value,
+			Units:          obs.Units,
+			ReferenceRange: obs.ReferenceRange,
+			Flag:           labFlag(obs.Flag),
+			Status:         labResultStatus(obs.Status),
+			ReceivedAt:     now,
+		}
+		if observed := obs.Time; !observed.IsZero() || !report.Time.IsZero() {
+			if observed.IsZero() {
+				observed = report.Time
+			}
+			result.ObservedAt = &observed
+		}
+		if i, ok := index[code]; ok {
+			results[i] = result
+			continue
+		}
+		index[code] = len(results)
+		results = append(results, result)
+	}
+	return results
+}
+
+// applyReport updates an order's status with a report of one of its tests.
+func applyReport(order *domain.LabOrder, report hl7.Report, now time.Time) {
+	if report.FillerOrderNumber != "" {
+		order.FillerOrderNumber = report.FillerOrderNumber
+	}
+	if report.Status == hl7.StatusFinal || report.Status == hl7.StatusCorrected {
+		if !contains(order.ReportedTests, report.Test.Code) {
+			order.ReportedTests = append(order.ReportedTests, report.Test.Code)
+		}
+	}
+
+	order.Status = domain.LabOrderFinal
+	for _, test := range order.Tests {
+		if !contains(order.ReportedTests, test.Code) {
+			order.Status = domain.LabOrderPreliminary
+		}
+	}
+	if order.Status == domain.LabOrderFinal {
+		order.ResultedAt = &now
+	}
+}
+
+// labFlag normalizes an OBX-8 abnormal flag. Flags other than the usual
+// ones, such as susceptibility codes, are kept as abnormal.
+func labFlag(flag string) string {
+	switch flag = strings.ToUpper(strings.TrimSpace(flag)); flag {
+	case "", domain.FlagNormal, domain.FlagLow, domain.FlagHigh, domain.FlagCriticalLow, domain.FlagCriticalHigh,
+		domain.FlagAbnormal, domain.FlagCriticalAbnormal:
+		return flag
+	default:
+		return domain.FlagAbnormal
+	}
+}
+
+func labResultStatus(status string) string {
+	switch status {
+	case hl7.StatusFinal:
+		return domain.LabResultFinal
+	case hl7.StatusCorrected:
+		return domain.LabResultCorrected
+	default:
+		return domain.LabResultPreliminary
+	}
+}
+
+// notifyResults emails the ordering doctor that results arrived. The
+// email says how many are abnormal but no values or patient details.
+func (uc *labUseCase) notifyResults(ctx context.Context, order *domain.LabOrder) error {
+	doctor, err := uc.doctorRepo.GetByID(ctx, order.DoctorID)
+	if err != nil {
+		return err
+	}
+	if doctor.Email == "" {
+		return nil
+	}
+	results, err := uc.labRepo.ListResults(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	abnormal, critical := 0, 0
+	for _, result := range results {
+		if result.Abnormal() {
+			abnormal++
+		}
+		if result.Critical() {
+			critical++
+		}
+	}
+	subject := "Lab results available"
+	if critical > 0 {
+		subject = "Critical lab results"
+	}
+	state := "Final"
+	if order.Status != domain.LabOrderFinal {
+		state = "Preliminary"
+	}
+	body := fmt.Sprintf("Dear Dr. %s,\n\n%s results for lab order %s are available: %d result(s), %d abnormal, %d critical.\n\nSign in to review them.\n\nBest regards,\nDoctor SaaS Team",
+		doctor.Name, state, order.PlacerOrderNumber, len(results), abnormal, critical)
+	return uc.emailSender.Send(doctor.Email, subject, body)
+}
@@ -0,0 +1,99 @@
+// internal/usecase/mrn.go
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMRNFormat yields zero-padded numbers such as "00000042".
+const DefaultMRNFormat = "{seq:8}"
+
+var mrnPlaceholder = regexp.MustCompile(`\{(seq(?::(\d+))?|yyyy|yy)\}`)
+
+// ValidateMRNFormat checks an MRN format. Formats are literal text with
+// placeholders: {seq} or {seq:N} (the counter, zero-padded to N digits),
+// {yyyy} and {yy} (the year of registration). {seq} is required so every
+// MRN is unique.
+func ValidateMRNFormat(format string) error {
+	return validateNumberFormat("MRN", format)
+}
+
+// validateNumberFormat checks a format of generated numbers, which have
+// the placeholders of MRN formats.
+func validateNumberFormat(kind, format string) error {
+	hasSeq := false
+	for _, m := range mrnPlaceholder.FindAllStringSubmatch(format, -1) {
+		if strings.HasPrefix(m[1], "seq") {
+			hasSeq = true
+		}
+		if m[2] != "" {
+			if n, _ := strconv.Atoi(m[2]); n < 1 || n > 20 {
+				return fmt.Errorf("%s format %q: {seq:N} needs N between 1 and 20", kind, format)
+			}
+		}
+	}
+	if !hasSeq {
+		return fmt.Errorf("%s format %q must contain {seq} or {seq:N}", kind, format)
+	}
+	return nil
+}
+
+// formatNumber fills in the placeholders of an MRN or invoice number
+// format for the counter value seq.
+func formatNumber(format string, seq int64, now time.Time) string {
+	return mrnPlaceholder.ReplaceAllStringFunc(format, func(placeholder string) string {
+		m := mrnPlaceholder.FindStringSubmatch(placeholder)
+		switch m[1] {
+		case "yyyy":
+			return now.Format("2006")
+		case "yy":
+			return now.Format("06")
+		}
+		if m[2] != "" {
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+		return strconv.FormatInt(seq, 10)
+	})
+}
+
+// nextMRN numbers a patient of a tenant; every tenant counts on its own.
+func (uc *patientUseCase) nextMRN(ctx context.Context, tenantID uint) (string, error) {
+	seq, err := uc.patientRepo.NextMRNSequence(ctx, tenantScope(tenantID))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate MRN: %w", err)
+	}
+	return formatNumber(uc.mrnFormat, seq, uc.now()), nil
+}
+
+func (uc *patientUseCase) AssignMissingMRNs(ctx context.Context, batchSize int) (int, error) {
+	assigned := 0
+	for {
+		ids, err := uc.patientRepo.IDsWithoutMRN(ctx, batchSize)
+		if err != nil {
+			return assigned, err
+		}
+		if len(ids) == 0 {
+			return assigned, nil
+		}
+		for _, id := range ids {
+			tenantID, err := uc.patientRepo.TenantOf(ctx, id)
+			if err != nil {
+				return assigned, fmt.Errorf("patient %d: %w", id, err)
+			}
+			mrn, err := uc.nextMRN(ctx, tenantID)
+			if err != nil {
+				return assigned, err
+			}
+			if err := uc.patientRepo.SetMRN(ctx, id, mrn); err != nil {
+				return assigned, fmt.Errorf("patient %d: %w", id, err)
+			}
+			assigned++
+		}
+	}
+}
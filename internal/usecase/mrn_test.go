@@ -0,0 +1,130 @@
+// internal/usecase/mrn_test.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatNumber(t *testing.T) {
+	now := time.Date(2030, 3, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		seq    int64
+		want   string
+	}{
+		{format: DefaultMRNFormat, seq: 42, want: "00000042"},
+		{format: "{seq}", seq: 42, want: "42"},
+		{format: "MRN-{yyyy}-{seq:5}", seq: 7, want: "MRN-2030-00007"},
+		{format: "{yy}{seq:3}", seq: 12, want: "30012"},
+		{format: "{seq:2}", seq: 1234, want: "1234"},
+		{format: DefaultInvoiceNumberFormat, seq: 42, want: "INV-2030-000042"},
+		{format: "{SEQ}-{seq}", seq: 3, want: "{SEQ}-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := formatNumber(tt.format, tt.seq, now); got != tt.want {
+				t.Errorf("formatNumber(%q, %d) = %q, want %q", tt.format, tt.seq, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMRNFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr string
+	}{
+		{format: DefaultMRNFormat},
+		{format: "{seq}"},
+		{format: "P{yyyy}{seq:20}"},
+		{format: "{yyyy}-{yy}", wantErr: "must contain {seq}"},
+		{format: "", wantErr: "must contain {seq}"},
+		{format: "{seq:0}", wantErr: "between 1 and 20"},
+		{format: "{seq:21}", wantErr: "between 1 and 20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			err := ValidateMRNFormat(tt.format)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("ValidateMRNFormat(%q) error = %v", tt.format, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ValidateMRNFormat(%q) error = %v, want %q", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := ValidateInvoiceNumberFormat("INV-{yyyy}"); err == nil || !strings.HasPrefix(err.Error(), "invoice number format") {
+		t.Errorf("ValidateInvoiceNumberFormat() error = %v, want one naming invoice numbers", err)
+	}
+}
+
+// fakeMRNPatientRepo holds the tenant of each patient without an MRN and
+// counts MRNs per scope.
+type fakeMRNPatientRepo struct {
+	repository.PatientRepository
+	tenants  map[uint]uint
+	counters map[string]int64
+	mrns     map[uint]string
+}
+
+func (r *fakeMRNPatientRepo) IDsWithoutMRN(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	for id := range r.tenants {
+		if _, ok := r.mrns[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *fakeMRNPatientRepo) TenantOf(ctx context.Context, id uint) (uint, error) {
+	return r.tenants[id], nil
+}
+
+func (r *fakeMRNPatientRepo) NextMRNSequence(ctx context.Context, scope string) (int64, error) {
+	r.counters[scope]++
+	return r.counters[scope], nil
+}
+
+func (r *fakeMRNPatientRepo) SetMRN(ctx context.Context, id uint, mrn string) error {
+	r.mrns[id] = mrn
+	return nil
+}
+
+func TestAssignMissingMRNs(t *testing.T) {
+	patients := &fakeMRNPatientRepo{
+		tenants:  map[uint]uint{1: domain.DefaultTenantID, 2: 2, 3: domain.DefaultTenantID, 4: 2, 5: 3},
+		counters: map[string]int64{"default": 40},
+		mrns:     map[uint]string{},
+	}
+	uc := NewPatientUseCase(fakeTransactor{}, patients, nil, nil, nil, nil, "", "", "P{yy}-{seq:4}").(*patientUseCase)
+	uc.now = func() time.Time { return billingNow }
+
+	assigned, err := uc.AssignMissingMRNs(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("AssignMissingMRNs() error = %v", err)
+	}
+	if assigned != 5 {
+		t.Errorf("assigned = %d, want 5", assigned)
+	}
+	want := map[uint]string{1: "P30-0041", 2: "P30-0001", 3: "P30-0042", 4: "P30-0002", 5: "P30-0001"}
+	if !reflect.DeepEqual(patients.mrns, want) {
+		t.Errorf("MRNs = %v, want %v", patients.mrns, want)
+	}
+	wantCounters := map[string]int64{"default": 42, "tenant:2": 2, "tenant:3": 1}
+	if !reflect.DeepEqual(patients.counters, wantCounters) {
+		t.Errorf("counters = %v, want each tenant counted on its own: %v", patients.counters, wantCounters)
+	}
+}
@@ -0,0 +1,110 @@
+// internal/usecase/outbox_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"time"
+)
+
+type OutboxUseCase interface {
+	// PublishPending publishes unpublished outbox messages in order with
+	// publish, until none are left or one fails, and returns how many it
+	// published. A failure is recorded on the message, which is retried
+	// on the next run.
+	PublishPending(ctx context.Context, publish func(ctx context.Context, message *domain.OutboxMessage) error) (int, error)
+	ListOutbox(ctx context.Context, pending bool, limit int) ([]domain.OutboxMessage, error)
+	// RecordDeadLetter keeps a consumed message that could not be processed.
+	RecordDeadLetter(ctx context.Context, letter *domain.DeadLetter) error
+	ListDeadLetters(ctx context.Context, pending bool, limit int) ([]domain.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id uint) (*domain.DeadLetter, error)
+	// ReplayDeadLetter puts a dead letter back on the outbox, to be
+	// published to its topic and consumed again.
+	ReplayDeadLetter(ctx context.Context, id uint) (*domain.DeadLetter, error)
+}
+
+// outboxBatch is how many outbox messages are locked and published at a time.
+const outboxBatch = 100
+
+type outboxUseCase struct {
+	transactor repository.Transactor
+	outboxRepo repository.OutboxRepository
+	now        func() time.Time
+}
+
+func NewOutboxUseCase(transactor repository.Transactor, outboxRepo repository.OutboxRepository) OutboxUseCase {
+	return &outboxUseCase{transactor: transactor, outboxRepo: outboxRepo, now: time.Now}
+}
+
+func (uc *outboxUseCase) PublishPending(ctx context.Context, publish func(ctx context.Context, message *domain.OutboxMessage) error) (int, error) {
+	published := 0
+	for {
+		var batch int
+		var failed bool
+		err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			messages, err := uc.outboxRepo.LockPending(ctx, outboxBatch)
+			if err != nil {
+				return err
+			}
+			batch = len(messages)
+			for i := range messages {
+				// Later messages wait for this one, so they keep their order.
+				if err := publish(ctx, &messages[i]); err != nil {
+					failed = true
+					return uc.outboxRepo.MarkFailed(ctx, messages[i].ID, err.Error())
+				}
+				if err := uc.outboxRepo.MarkPublished(ctx, messages[i].ID, uc.now()); err != nil {
+					return err
+				}
+				published++
+			}
+			return nil
+		})
+		if err != nil || failed || batch < outboxBatch {
+			return published, err
+		}
+	}
+}
+
+func (uc *outboxUseCase) ListOutbox(ctx context.Context, pending bool, limit int) ([]domain.OutboxMessage, error) {
+	return uc.outboxRepo.List(ctx, pending, limit)
+}
+
+func (uc *outboxUseCase) RecordDeadLetter(ctx context.Context, letter *domain.DeadLetter) error {
+	return uc.outboxRepo.AddDeadLetter(ctx, letter)
+}
+
+func (uc *outboxUseCase) ListDeadLetters(ctx context.Context, pending bool, limit int) ([]domain.DeadLetter, error) {
+	return uc.outboxRepo.ListDeadLetters(ctx, pending, limit)
+}
+
+func (uc *outboxUseCase) GetDeadLetter(ctx context.Context, id uint) (*domain.DeadLetter, error) {
+	return uc.outboxRepo.GetDeadLetter(ctx, id)
+}
+
+func (uc *outboxUseCase) ReplayDeadLetter(ctx context.Context, id uint) (*domain.DeadLetter, error) {
+	var letter *domain.DeadLetter
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if letter, err = uc.outboxRepo.GetDeadLetterForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if letter.ReplayedAt != nil {
+			return domain.NewConflictError("dead_letter_replayed", "the message was replayed already")
+		}
+
+		message := &domain.OutboxMessage{Topic: letter.Topic, Payload: letter.Payload}
+		if err := uc.outboxRepo.Enqueue(ctx, message); err != nil {
+			return err
+		}
+		now := uc.now()
+		letter.ReplayedAt = &now
+		letter.OutboxID = &message.ID
+		return uc.outboxRepo.MarkReplayed(ctx, letter)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
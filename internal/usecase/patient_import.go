@@ -0,0 +1,661 @@
+// internal/usecase/patient_import.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PatientImportOptions describes how to read an import file.
+type PatientImportOptions struct {
+	FileName string
+	// Columns maps headers of the file to patient fields, overriding the
+	// mapping guessed from the header names. An empty field ignores the column.
+	Columns map[string]string
+	// DryRun checks every row without importing any.
+	DryRun bool
+	// OnDuplicate is ImportDuplicateSkip (the default) or ImportDuplicateCreate.
+	OnDuplicate string
+	// DateFormat is how dates of birth are written: YYYY-MM-DD (the
+	// default), MM/DD/YYYY or DD/MM/YYYY. ISO dates are always accepted.
+	DateFormat string
+}
+
+const (
+	// MaxImportRows is the most data rows an import file may have.
+	MaxImportRows = 50000
+	// importChunkSize is how many rows are inserted per transaction.
+	importChunkSize = 100
+	// maxImportIssues bounds the row issues kept per import.
+	maxImportIssues = 1000
+	// importStaleAfter is how long a running import may go without progress
+	// before another worker takes it over.
+	importStaleAfter = 10 * time.Minute
+	// exportBatchSize is how many patients an export reads at a time.
+	exportBatchSize = 500
+)
+
+var importDateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD/MM/YYYY": "02/01/2006",
+}
+
+// patientColumn reads or writes a patient field as text. Columns without
+// get are import-only; columns without set are export-only.
+type patientColumn struct {
+	get func(p *domain.Patient) string
+	set func(p *domain.Patient, value string)
+}
+
+var patientColumns = map[string]patientColumn{
+	"id": {get: func(p *domain.Patient) string { return strconv.FormatUint(uint64(p.ID), 10) }},
+	"mrn": {
+		get: func(p *domain.Patient) string { return p.MRN },
+		set: func(p *domain.Patient, v string) { p.MRN = v },
+	},
+	"name": {
+		get: func(p *domain.Patient) string { return p.Name },
+		set: func(p *domain.Patient, v string) { p.Name = v },
+	},
+	// first_name and last_name are joined into the name.
+	"first_name": {set: func(p *domain.Patient, v string) { p.Name = strings.TrimSpace(v + " " + p.Name) }},
+	"last_name":  {set: func(p *domain.Patient, v string) { p.Name = strings.TrimSpace(p.Name + " " + v) }},
+	"preferred_name": {
+		get: func(p *domain.Patient) string { return p.PreferredName },
+		set: func(p *domain.Patient, v string) { p.PreferredName = v },
+	},
+	"date_of_birth": {
+		get: func(p *domain.Patient) string { return p.DateOfBirth },
+		set: func(p *domain.Patient, v string) { p.DateOfBirth = v },
+	},
+	"sex": {
+		get: func(p *domain.Patient) string { return p.Sex },
+		set: func(p *domain.Patient, v string) { p.Sex = normalizeSex(v) },
+	},
+	"gender_identity": {
+		get: func(p *domain.Patient) string { return p.GenderIdentity },
+		set: func(p *domain.Patient, v string) { p.GenderIdentity = v },
+	},
+	"pronouns": {
+		get: func(p *domain.Patient) string { return p.Pronouns },
+		set: func(p *domain.Patient, v string) { p.Pronouns = v },
+	},
+	"email": {
+		get: func(p *domain.Patient) string { return p.Email },
+		set: func(p *domain.Patient, v string) { p.Email = v },
+	},
+	"phone": {
+		get: func(p *domain.Patient) string { return p.Phone },
+		set: func(p *domain.Patient, v string) { p.Phone = v },
+	},
+	"national_id": {
+		get: func(p *domain.Patient) string { return p.NationalID },
+		set: func(p *domain.Patient, v string) { p.NationalID = v },
+	},
+	"preferred_language": {
+		get: func(p *domain.Patient) string { return p.PreferredLanguage },
+		set: func(p *domain.Patient, v string) { p.PreferredLanguage = v },
+	},
+	"preferred_contact_channel": {
+		get: func(p *domain.Patient) string { return p.PreferredContactChannel },
+		set: func(p *domain.Patient, v string) { p.PreferredContactChannel = strings.ToLower(v) },
+	},
+	"address_line1": {
+		get: func(p *domain.Patient) string { return p.Address.Line1 },
+		set: func(p *domain.Patient, v string) { p.Address.Line1 = v },
+	},
+	"address_line2": {
+		get: func(p *domain.Patient) string { return p.Address.Line2 },
+		set: func(p *domain.Patient, v string) { p.Address.Line2 = v },
+	},
+	"address_city": {
+		get: func(p *domain.Patient) string { return p.Address.City },
+		set: func(p *domain.Patient, v string) { p.Address.City = v },
+	},
+	"address_region": {
+		get: func(p *domain.Patient) string { return p.Address.Region },
+		set: func(p *domain.Patient, v string) { p.Address.Region = v },
+	},
+	"address_postal_code": {
+		get: func(p *domain.Patient) string { return p.Address.PostalCode },
+		set: func(p *domain.Patient, v string) { p.Address.PostalCode = v },
+	},
+	"address_country": {
+		get: func(p *domain.Patient) string { return p.Address.Country },
+		set: func(p *domain.Patient, v string) { p.Address.Country = v },
+	},
+	"created_at": {get: func(p *domain.Patient) string { return p.CreatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// PatientExportColumns are the columns of a patient export, in order.
+var PatientExportColumns = []string{
+	"id", "mrn", "name", "preferred_name", "date_of_birth", "sex", "gender_identity", "pronouns",
+	"email", "phone", "national_id", "preferred_language", "preferred_contact_channel",
+	"address_line1", "address_line2", "address_city", "address_region", "address_postal_code",
+	"address_country", "created_at",
+}
+
+// columnAliases maps common header names, normalized by headerKey, to
+// the field they hold.
+var columnAliases = map[string]string{
+	"full_name": "name", "patient_name": "name", "patient": "name",
+	"firstname": "first_name", "given_name": "first_name", "forename": "first_name",
+	"lastname": "last_name", "surname": "last_name", "family_name": "last_name",
+	"e_mail": "email", "email_address": "email",
+	"phone_number": "phone", "mobile": "phone", "mobile_phone": "phone", "cell": "phone", "telephone": "phone",
+	"dob": "date_of_birth", "birth_date": "date_of_birth", "birthdate": "date_of_birth",
+	"medical_record_number": "mrn", "chart_number": "mrn",
+	"gender": "sex", "language": "preferred_language",
+	"address": "address_line1", "street": "address_line1", "address1": "address_line1", "address_1": "address_line1",
+	"address2": "address_line2", "address_2": "address_line2",
+	"city": "address_city", "town": "address_city",
+	"state": "address_region", "province": "address_region", "region": "address_region",
+	"zip": "address_postal_code", "zip_code": "address_postal_code", "postcode": "address_postal_code",
+	"postal_code": "address_postal_code", "country": "address_country",
+}
+
+// headerKey normalizes a header: "Date of Birth" becomes "date_of_birth".
+func headerKey(header string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(header)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
+func normalizeSex(value string) string {
+	switch strings.ToLower(value) {
+	case "f", "female", "woman":
+		return domain.SexFemale
+	case "m", "male", "man":
+		return domain.SexMale
+	case "x", "i", "intersex":
+		return domain.SexIntersex
+	case "u", "unknown":
+		return domain.SexUnknown
+	}
+	return strings.ToLower(value)
+}
+
+// mapColumns decides which field each column of the header holds. It
+// returns the field per column ("" for ignored ones) and the mapping as
+// the import records it.
+func mapColumns(header []string, explicit map[string]string) ([]string, map[string]string, []string, error) {
+	var fields []domain.FieldError
+	index := map[string]int{}
+	for i, h := range header {
+		if _, dup := index[strings.TrimSpace(h)]; !dup {
+			index[strings.TrimSpace(h)] = i
+		}
+	}
+	for h, field := range explicit {
+		if _, ok := index[strings.TrimSpace(h)]; !ok {
+			fields = append(fields, domain.FieldError{Field: "columns." + h, Message: "is not a column of the file"})
+		} else if column, ok := patientColumns[field]; field != "" && (!ok || column.set == nil) {
+			fields = append(fields, domain.FieldError{Field: "columns." + h, Message: fmt.Sprintf("%q is not a patient field that can be imported", field)})
+		}
+	}
+
+	columns := make([]string, len(header))
+	mapping := map[string]string{}
+	var ignored []string
+	mappedFrom := map[string]string{}
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		field, ok := explicit[h]
+		if !ok {
+			key := headerKey(h)
+			if column, known := patientColumns[key]; known && column.set != nil {
+				field = key
+			} else {
+				field = columnAliases[key]
+			}
+		}
+		if field == "" || index[h] != i {
+			if h != "" {
+				ignored = append(ignored, h)
+			}
+			continue
+		}
+		if other, dup := mappedFrom[field]; dup {
+			fields = append(fields, domain.FieldError{Field: "columns." + h,
+				Message: fmt.Sprintf("maps to %s, which column %q already holds", field, other)})
+			continue
+		}
+		mappedFrom[field] = h
+		columns[i] = field
+		mapping[h] = field
+	}
+
+	if mappedFrom["name"] == "" && mappedFrom["first_name"] == "" && mappedFrom["last_name"] == "" {
+		fields = append(fields, domain.FieldError{Field: "columns", Message: "must map a column to name, or to first_name and last_name"})
+	}
+	if len(fields) > 0 {
+		return nil, nil, nil, domain.NewValidationError(fields...)
+	}
+	return columns, mapping, ignored, nil
+}
+
+// rowOutcome is what an import does with a row.
+type rowOutcome int
+
+const (
+	rowImport rowOutcome = iota
+	rowSkip
+	rowFail
+)
+
+// rowResult is a checked row.
+type rowResult struct {
+	patient domain.Patient
+	issue   domain.PatientImportIssue
+	outcome rowOutcome
+}
+
+// importRun is the state of an import while it runs.
+type importRun struct {
+	imp     *domain.PatientImport
+	columns []string
+	layout  string
+	// firstRow holds, per identity key, the first row that had it.
+	firstRow map[string]int
+	// mrnRow holds, per MRN, the first row that had it.
+	mrnRow map[string]int
+}
+
+func (uc *patientUseCase) StartImport(ctx context.Context, table [][]string, opts PatientImportOptions, actor *domain.User) (*domain.PatientImport, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = domain.ImportDuplicateSkip
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = "YYYY-MM-DD"
+	}
+	var fields []domain.FieldError
+	switch opts.OnDuplicate {
+	case domain.ImportDuplicateSkip:
+	case domain.ImportDuplicateCreate:
+		if uc.duplicatePolicy == PatientDuplicateBlock {
+			fields = append(fields, domain.FieldError{Field: "on_duplicate", Message: "must be skip while duplicate patients are blocked"})
+		}
+	default:
+		fields = append(fields, domain.FieldError{Field: "on_duplicate", Message: "must be one of: skip create"})
+	}
+	layout, ok := importDateFormats[opts.DateFormat]
+	if !ok {
+		fields = append(fields, domain.FieldError{Field: "date_format", Message: "must be one of: YYYY-MM-DD MM/DD/YYYY DD/MM/YYYY"})
+	}
+	if len(table) == 0 {
+		fields = append(fields, domain.FieldError{Field: "file", Message: "must have a header row"})
+	}
+	if len(fields) > 0 {
+		return nil, domain.NewValidationError(fields...)
+	}
+
+	columns, mapping, ignored, err := mapColumns(table[0], opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []domain.PatientImportRow
+	for i, values := range table[1:] {
+		if !blankRow(values) {
+			rows = append(rows, domain.PatientImportRow{Number: i + 2, Values: values})
+		}
+	}
+	if len(rows) == 0 {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "file", Message: "has no data rows"})
+	}
+	if len(rows) > MaxImportRows {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "file",
+			Message: fmt.Sprintf("has %d rows; split it into files of at most %d", len(rows), MaxImportRows)})
+	}
+
+	imp := &domain.PatientImport{
+		FileName:          opts.FileName,
+		DryRun:            opts.DryRun,
+		OnDuplicate:       opts.OnDuplicate,
+		Columns:           mapping,
+		IgnoredColumns:    ignored,
+		Status:            domain.PatientImportQueued,
+		Rows:              len(rows),
+		RequestedByUserID: actorID(actor),
+		TenantID:          tenantOf(ctx),
+	}
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.importRepo.Create(ctx, imp); err != nil {
+			return err
+		}
+		return uc.importRepo.CreateUpload(ctx, &domain.PatientImportUpload{ID: imp.ID, Fields: columns, DateLayout: layout, Rows: rows})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient import: %w", err)
+	}
+	return imp, nil
+}
+
+func blankRow(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (uc *patientUseCase) RunPendingImports(ctx context.Context) (int, error) {
+	ran := 0
+	for {
+		imp, err := uc.importRepo.Claim(ctx, uc.now().Add(-importStaleAfter))
+		if err != nil {
+			return ran, err
+		}
+		if imp == nil {
+			return ran, nil
+		}
+		if err := uc.runImport(ctx, imp); err != nil {
+			return ran, fmt.Errorf("patient import %d: %w", imp.ID, err)
+		}
+		ran++
+	}
+}
+
+// runImport checks and inserts the rows of a claimed import chunk by
+// chunk, starting after the rows processed already. A chunk whose insert
+// fails is rolled back and its rows counted as failed; an error reading
+// the database stops the import, keeping the chunks already done.
+func (uc *patientUseCase) runImport(ctx context.Context, imp *domain.PatientImport) error {
+	// Duplicate checks only look at the patients of the import's tenant.
+	ctx = WithTenant(ctx, imp.TenantID)
+	upload, err := uc.importRepo.GetUpload(ctx, imp.ID)
+	if err != nil {
+		return err
+	}
+	run := &importRun{imp: imp, columns: upload.Fields, layout: upload.DateLayout, firstRow: map[string]int{}, mrnRow: map[string]int{}}
+	rows := upload.Rows
+	if imp.Processed > len(rows) {
+		imp.Processed = len(rows)
+	}
+	// A resumed import still spots rows repeating the ones done already.
+	for _, row := range rows[:imp.Processed] {
+		uc.rememberImportRow(run, row)
+	}
+
+	for start := imp.Processed; start < len(rows); start += importChunkSize {
+		end := start + importChunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := uc.importChunk(ctx, run, rows[start:end]); err != nil {
+			imp.Status = domain.PatientImportFailed
+			imp.Error = fmt.Sprintf("row %d: %v", rows[start].Number, err)
+			break
+		}
+	}
+
+	if imp.Status == domain.PatientImportRunning {
+		imp.Status = domain.PatientImportCompleted
+	}
+	now := uc.now()
+	imp.CompletedAt = &now
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.importRepo.Update(ctx, imp); err != nil {
+			return err
+		}
+		return uc.importRepo.DeleteUpload(ctx, imp.ID)
+	})
+}
+
+// rememberImportRow records the MRN and identity keys of a row processed
+// by an earlier run of the import, as checkImportRow does for valid rows.
+func (uc *patientUseCase) rememberImportRow(run *importRun, row domain.PatientImportRow) {
+	patient := buildImportPatient(run, row)
+	if uc.validatePatient(&patient) != nil {
+		return
+	}
+	if _, ok := run.mrnRow[patient.MRN]; !ok && patient.MRN != "" {
+		run.mrnRow[patient.MRN] = row.Number
+	}
+	for _, key := range identityKeys(&patient) {
+		if _, ok := run.firstRow[key]; !ok {
+			run.firstRow[key] = row.Number
+		}
+	}
+}
+
+func (uc *patientUseCase) importChunk(ctx context.Context, run *importRun, rows []domain.PatientImportRow) error {
+	results := make([]rowResult, len(rows))
+	for i, row := range rows {
+		result, err := uc.checkImportRow(ctx, run, row)
+		if err != nil {
+			return err
+		}
+		results[i] = result
+	}
+
+	// The chunk's rows and the progress they make are saved together.
+	next := *run.imp
+	tallyImportChunk(&next, results)
+	failed := -1
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i := range results {
+			if run.imp.DryRun || results[i].outcome != rowImport {
+				continue
+			}
+			if err := uc.insertImported(ctx, run.imp.TenantID, &results[i].patient); err != nil {
+				failed = i
+				return err
+			}
+		}
+		return uc.importRepo.Update(ctx, &next)
+	})
+	if err != nil && failed < 0 {
+		return err
+	}
+	if err != nil {
+		for i := range results {
+			if results[i].outcome != rowImport {
+				continue
+			}
+			message := fmt.Sprintf("not imported because row %d failed", rows[failed].Number)
+			if i == failed {
+				message = "could not be saved: " + err.Error()
+			}
+			results[i].outcome = rowFail
+			results[i].issue.Errors = append(results[i].issue.Errors, domain.FieldError{Field: "row", Message: message})
+		}
+		next = *run.imp
+		tallyImportChunk(&next, results)
+		if err := uc.importRepo.Update(ctx, &next); err != nil {
+			return err
+		}
+	}
+	*run.imp = next
+	return nil
+}
+
+// tallyImportChunk adds the outcomes of a chunk's rows to an import.
+func tallyImportChunk(imp *domain.PatientImport, results []rowResult) {
+	// Don't append to the issues of the import being copied.
+	imp.Issues = append([]domain.PatientImportIssue{}, imp.Issues...)
+	for _, result := range results {
+		switch result.outcome {
+		case rowImport:
+			imp.Imported++
+		case rowSkip:
+			imp.Skipped++
+		case rowFail:
+			imp.Failed++
+		}
+		issue := result.issue
+		if (len(issue.Errors) > 0 || len(issue.Duplicates) > 0 || issue.DuplicateOfRow > 0) && len(imp.Issues) < maxImportIssues {
+			imp.Issues = append(imp.Issues, issue)
+		}
+	}
+	imp.Processed += len(results)
+}
+
+// checkImportRow builds the patient of a row and checks it: field
+// validation, MRNs already in use, and duplicates of existing patients or
+// of earlier rows.
+func (uc *patientUseCase) checkImportRow(ctx context.Context, run *importRun, row domain.PatientImportRow) (rowResult, error) {
+	result := rowResult{patient: buildImportPatient(run, row), issue: domain.PatientImportIssue{Row: row.Number}}
+	patient := &result.patient
+
+	if err := uc.validatePatient(patient); err != nil {
+		var derr *domain.Error
+		if !errors.As(err, &derr) || derr.Kind != domain.KindValidation {
+			return result, err
+		}
+		result.issue.Errors = derr.Fields
+	}
+	if patient.MRN != "" {
+		if first, ok := run.mrnRow[patient.MRN]; ok {
+			result.issue.Errors = append(result.issue.Errors, domain.FieldError{Field: "mrn", Message: fmt.Sprintf("repeats row %d", first)})
+		} else {
+			existing, err := uc.patientRepo.Search(ctx, domain.PatientSearch{MRN: patient.MRN, IncludeArchived: true}, 1)
+			if err != nil {
+				return result, err
+			}
+			if len(existing) > 0 {
+				result.issue.Errors = append(result.issue.Errors, domain.FieldError{Field: "mrn",
+					Message: fmt.Sprintf("is already used by patient %d", existing[0].ID)})
+			}
+		}
+	}
+	if len(result.issue.Errors) > 0 {
+		result.outcome = rowFail
+		return result, nil
+	}
+	if patient.MRN != "" {
+		run.mrnRow[patient.MRN] = row.Number
+	}
+
+	for _, key := range identityKeys(patient) {
+		if first, ok := run.firstRow[key]; ok {
+			if result.issue.DuplicateOfRow == 0 {
+				result.issue.DuplicateOfRow = first
+			}
+		} else {
+			run.firstRow[key] = row.Number
+		}
+	}
+	duplicates, err := uc.findDuplicates(ctx, patient)
+	if err != nil {
+		return result, fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	for i := range duplicates {
+		// Issues are stored; they keep no patient data.
+		duplicates[i].Name = ""
+	}
+	result.issue.Duplicates = duplicates
+
+	if (len(duplicates) > 0 || result.issue.DuplicateOfRow > 0) && run.imp.OnDuplicate == domain.ImportDuplicateSkip {
+		result.outcome = rowSkip
+	}
+	return result, nil
+}
+
+// buildImportPatient sets the fields of a patient from the cells of a row.
+func buildImportPatient(run *importRun, row domain.PatientImportRow) domain.Patient {
+	var patient domain.Patient
+	for i, field := range run.columns {
+		if field == "" || i >= len(row.Values) {
+			continue
+		}
+		if value := strings.TrimSpace(row.Values[i]); value != "" {
+			patientColumns[field].set(&patient, value)
+		}
+	}
+	if dob, err := time.Parse(run.layout, patient.DateOfBirth); err == nil {
+		patient.DateOfBirth = dob.Format("2006-01-02")
+	}
+	return patient
+}
+
+// identityKeys are the values two rows of the same person probably share.
+func identityKeys(patient *domain.Patient) []string {
+	var keys []string
+	if patient.Email != "" {
+		keys = append(keys, "email:"+strings.ToLower(patient.Email))
+	}
+	if patient.Phone != "" {
+		keys = append(keys, "phone:"+patient.Phone)
+	}
+	if patient.DateOfBirth != "" {
+		keys = append(keys, "name:"+strings.ToLower(strings.Join(strings.Fields(patient.Name), " "))+"|"+patient.DateOfBirth)
+	}
+	return keys
+}
+
+func (uc *patientUseCase) insertImported(ctx context.Context, tenantID uint, patient *domain.Patient) error {
+	patient.TenantID = tenantID
+	if patient.MRN == "" {
+		mrn, err := uc.nextMRN(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		patient.MRN = mrn
+	}
+	return uc.patientRepo.Create(ctx, patient)
+}
+
+func (uc *patientUseCase) GetImport(ctx context.Context, id uint) (*domain.PatientImport, error) {
+	return uc.importRepo.GetByID(ctx, id)
+}
+
+func (uc *patientUseCase) ListImports(ctx context.Context, page, pageSize int) ([]domain.PatientImport, int64, error) {
+	return uc.importRepo.List(ctx, page, pageSize)
+}
+
+func (uc *patientUseCase) ExportPatients(ctx context.Context, columns []string, write func(row []string) error) error {
+	if len(columns) == 0 {
+		columns = PatientExportColumns
+	}
+	for _, column := range columns {
+		if c, ok := patientColumns[column]; !ok || c.get == nil {
+			return domain.NewValidationError(domain.FieldError{Field: "columns",
+				Message: fmt.Sprintf("%q is not a column; use %s", column, strings.Join(PatientExportColumns, ", "))})
+		}
+	}
+
+	if err := write(columns); err != nil {
+		return err
+	}
+	until := uc.now()
+	var afterID uint
+	for {
+		patients, err := uc.patientRepo.ListChanged(ctx, time.Time{}, until, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range patients {
+			row := make([]string, len(columns))
+			for j, column := range columns {
+				row[j] = patientColumns[column].get(&patients[i])
+			}
+			if err := write(row); err != nil {
+				return err
+			}
+			afterID = patients[i].ID
+		}
+		if len(patients) < exportBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// internal/usecase/patient_merge.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/fuzzy"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Policies for creating a patient that looks like an existing one.
+const (
+	PatientDuplicateWarn  = "warn"
+	PatientDuplicateBlock = "block"
+)
+
+const (
+	// duplicateThreshold is the score from which a patient is reported as a
+	// probable duplicate on create.
+	duplicateThreshold = 0.65
+	// differentNameCap caps the score of patients whose names are clearly
+	// different, so family members sharing contact details are not flagged
+	// as probable duplicates.
+	differentNameCap = 0.6
+	// maxDuplicateScan bounds the candidate pairs scored by one report.
+	maxDuplicateScan = 5000
+)
+
+// duplicateScore compares two patients. A similar name counts for up to
+// 0.6, and each matching date of birth, email or phone adds 0.25.
+func duplicateScore(a, b domain.Patient) (float64, []string) {
+	var reasons []string
+	name := nameSimilarity(a.Name, b.Name)
+	score := 0.6 * name
+	if name >= 0.8 {
+		reasons = append(reasons, domain.DuplicateSimilarName)
+	}
+
+	if a.DateOfBirth != "" && b.DateOfBirth != "" {
+		if a.DateOfBirth == b.DateOfBirth {
+			score += 0.25
+			reasons = append(reasons, domain.DuplicateSameDateOfBirth)
+		} else {
+			// Different birth dates are strong evidence of different people.
+			score *= 0.5
+		}
+	}
+	if a.Email != "" && strings.EqualFold(a.Email, b.Email) {
+		score += 0.25
+		reasons = append(reasons, domain.DuplicateSameEmail)
+	}
+	if a.Phone != "" && normalizePhone(a.Phone) == normalizePhone(b.Phone) {
+		score += 0.25
+		reasons = append(reasons, domain.DuplicateSamePhone)
+	}
+
+	if name < 0.6 && score > differentNameCap {
+		score = differentNameCap
+	}
+	return math.Round(math.Min(score, 1)*1000) / 1000, reasons
+}
+
+// nameSimilarity is the symmetric version of nameScore, so "Jon Smith"
+// and "Jonathan Smith" compare the same either way round.
+func nameSimilarity(a, b string) float64 {
+	ab, _ := nameScore(b, fuzzy.Words(a))
+	ba, _ := nameScore(a, fuzzy.Words(b))
+	return (ab + ba) / 2
+}
+
+// findDuplicates returns existing patients that probably are the same
+// person as patient: same email or phone, or a similar name with the same
+// date of birth.
+func (uc *patientUseCase) findDuplicates(ctx context.Context, patient *domain.Patient) ([]domain.DuplicateMatch, error) {
+	var searches []domain.PatientSearch
+	if patient.Email != "" {
+		searches = append(searches, domain.PatientSearch{Email: patient.Email})
+	}
+	if patient.Phone != "" {
+		searches = append(searches, domain.PatientSearch{Phone: patient.Phone})
+	}
+	if patient.DateOfBirth != "" {
+		searches = append(searches, domain.PatientSearch{Name: patient.Name, DateOfBirth: patient.DateOfBirth})
+	}
+
+	seen := map[uint]bool{}
+	var matches []domain.DuplicateMatch
+	for _, search := range searches {
+		candidates, err := uc.patientRepo.Search(ctx, search, 20)
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range candidates {
+			if seen[candidate.ID] || candidate.ID == patient.ID {
+				continue
+			}
+			seen[candidate.ID] = true
+			if score, reasons := duplicateScore(*patient, candidate); score >= duplicateThreshold {
+				matches = append(matches, domain.DuplicateMatch{
+					PatientID: candidate.ID,
+					Name:      candidate.Name,
+					Score:     score,
+					Reasons:   reasons,
+				})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+func duplicateConflict(matches []domain.DuplicateMatch) error {
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = fmt.Sprint(m.PatientID)
+	}
+	return domain.NewConflictError("patient_possible_duplicate",
+		fmt.Sprintf("patient looks like existing patient(s) %s; use the existing record or merge them", strings.Join(ids, ", ")))
+}
+
+func (uc *patientUseCase) FindDuplicatePairs(ctx context.Context, minScore float64, limit int) ([]domain.DuplicatePair, error) {
+	candidates, err := uc.patientRepo.DuplicateCandidates(ctx, maxDuplicateScan)
+	if err != nil {
+		return nil, err
+	}
+
+	idSet := map[uint]bool{}
+	for _, pair := range candidates {
+		idSet[pair[0]], idSet[pair[1]] = true, true
+	}
+	ids := make([]uint, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	patients, err := uc.patientRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]domain.Patient, len(patients))
+	for _, p := range patients {
+		byID[p.ID] = p
+	}
+
+	var pairs []domain.DuplicatePair
+	for _, pair := range candidates {
+		a, okA := byID[pair[0]]
+		b, okB := byID[pair[1]]
+		if !okA || !okB {
+			continue
+		}
+		if score, reasons := duplicateScore(a, b); score >= minScore {
+			pairs = append(pairs, domain.DuplicatePair{Patient: a, Duplicate: b, Score: score, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+	if len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs, nil
+}
+
+// MergePatients folds duplicateID into survivorID: the duplicate's
+// records (appointments, relationships and every other kind passed to
+// NewPatientUseCase) move to the survivor and the duplicate is archived
+// with a pointer to it. The survivor's own fields are left as they are.
+func (uc *patientUseCase) MergePatients(ctx context.Context, survivorID, duplicateID uint, actor *domain.User) (*domain.PatientMerge, error) {
+	if survivorID == duplicateID {
+		return nil, domain.NewBadRequestError("patient_merge_same_patient", "a patient cannot be merged into itself")
+	}
+
+	merge := &domain.PatientMerge{SurvivorID: survivorID, DuplicateID: duplicateID, MergedByUserID: actorID(actor)}
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		survivor, err := uc.patientRepo.GetByID(ctx, survivorID)
+		if err != nil {
+			return err
+		}
+		duplicate, err := uc.patientRepo.GetByID(ctx, duplicateID)
+		if err != nil {
+			return err
+		}
+		if survivor.TenantID != duplicate.TenantID {
+			return domain.NewConflictError("patient_merge_other_tenant",
+				fmt.Sprintf("patients %d and %d belong to different tenants", survivorID, duplicateID))
+		}
+		// A guardian and their dependent are two people, and the link between
+		// them couldn't point a patient at themselves.
+		if rel, err := uc.relationshipRepo.Between(ctx, survivorID, duplicateID); err == nil {
+			return domain.NewConflictError("patient_merge_related",
+				fmt.Sprintf("patients %d and %d are linked by relationship %d; remove it before merging", survivorID, duplicateID, rel.ID))
+		} else if !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+
+		merge.MovedRecords = map[string][]uint{}
+		for _, records := range uc.records {
+			moved, err := records.ReassignPatient(ctx, duplicateID, survivorID, nil)
+			if err != nil {
+				return fmt.Errorf("failed to move %s: %w", records.RecordType(), err)
+			}
+			if len(moved) > 0 {
+				merge.MovedRecords[records.RecordType()] = moved
+			}
+		}
+
+		if err := uc.patientRepo.Merge(ctx, duplicateID, survivorID); err != nil {
+			return err
+		}
+		merge.MergedAt = uc.now()
+		return uc.mergeRepo.Create(ctx, merge)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// RevertMerge undoes a merge: the duplicate is restored and gets back the
+// records that were moved from it. Merges must be reverted newest
+// first when the survivor has since been merged itself.
+func (uc *patientUseCase) RevertMerge(ctx context.Context, mergeID uint, actor *domain.User) (*domain.PatientMerge, error) {
+	var merge *domain.PatientMerge
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		merge, err = uc.mergeRepo.GetByID(ctx, mergeID)
+		if err != nil {
+			return err
+		}
+		if merge.RevertedAt != nil {
+			return domain.NewConflictError("patient_merge_already_reverted", "this merge has already been reverted")
+		}
+
+		if _, err := uc.patientRepo.GetByID(ctx, merge.SurvivorID); errors.Is(err, domain.ErrNotFound) {
+			return domain.NewConflictError("patient_merge_survivor_archived",
+				fmt.Sprintf("surviving patient %d is archived or merged; restore or unmerge it first", merge.SurvivorID))
+		} else if err != nil {
+			return err
+		}
+
+		if err := uc.patientRepo.Unmerge(ctx, merge.DuplicateID); errors.Is(err, domain.ErrNotFound) {
+			return domain.NewConflictError("patient_merge_not_reversible",
+				fmt.Sprintf("patient %d no longer exists or is not merged", merge.DuplicateID))
+		} else if err != nil {
+			return err
+		}
+
+		for _, records := range uc.records {
+			ids := merge.MovedRecords[records.RecordType()]
+			if len(ids) == 0 {
+				continue
+			}
+			if _, err := records.ReassignPatient(ctx, merge.SurvivorID, merge.DuplicateID, ids); err != nil {
+				return fmt.Errorf("failed to move %s back: %w", records.RecordType(), err)
+			}
+		}
+
+		now := uc.now()
+		merge.RevertedAt = &now
+		merge.RevertedByUserID = actorID(actor)
+		return uc.mergeRepo.Update(ctx, merge)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+func (uc *patientUseCase) ListMerges(ctx context.Context, patientID uint) ([]domain.PatientMerge, error) {
+	return uc.mergeRepo.List(ctx, patientID)
+}
+
+// actorID is the ID recorded for audit, or nil for the bootstrap admin,
+// which has no user row.
+func actorID(actor *domain.User) *uint {
+	if actor == nil || actor.ID == 0 {
+		return nil
+	}
+	id := actor.ID
+	return &id
+}
@@ -0,0 +1,306 @@
+// internal/usecase/patient_merge_test.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDuplicateScore(t *testing.T) {
+	jane := domain.Patient{Name: "Jane Doe", DateOfBirth: "1980-04-02", Email: "jane@example.com", Phone: "+1 555 010 2030"}
+	tests := []struct {
+		name    string
+		a, b    domain.Patient
+		want    float64
+		reasons []string
+	}{
+		{
+			name: "same name and date of birth",
+			a:    jane, b: domain.Patient{Name: "Jane Doe", DateOfBirth: "1980-04-02"},
+			want: 0.85, reasons: []string{domain.DuplicateSimilarName, domain.DuplicateSameDateOfBirth},
+		},
+		{
+			name: "everything matches, capped at one",
+			a:    jane, b: jane,
+			want: 1, reasons: []string{domain.DuplicateSimilarName, domain.DuplicateSameDateOfBirth, domain.DuplicateSameEmail, domain.DuplicateSamePhone},
+		},
+		{
+			name: "different date of birth halves the name score",
+			a:    jane, b: domain.Patient{Name: "Jane Doe", DateOfBirth: "1981-04-02"},
+			want: 0.3, reasons: []string{domain.DuplicateSimilarName},
+		},
+		{
+			name: "different date of birth is halved before contact matches",
+			a:    jane, b: domain.Patient{Name: "Jane Doe", DateOfBirth: "1981-04-02", Email: "jane@example.com"},
+			want: 0.55, reasons: []string{domain.DuplicateSimilarName, domain.DuplicateSameEmail},
+		},
+		{
+			name: "missing date of birth neither adds nor halves",
+			a:    jane, b: domain.Patient{Name: "Jane Doe"},
+			want: 0.6, reasons: []string{domain.DuplicateSimilarName},
+		},
+		{
+			name: "email matches regardless of case",
+			a:    jane, b: domain.Patient{Name: "Jane Doe", Email: "JANE@Example.com"},
+			want: 0.85, reasons: []string{domain.DuplicateSimilarName, domain.DuplicateSameEmail},
+		},
+		{
+			name: "phone matches regardless of formatting",
+			a:    jane, b: domain.Patient{Name: "Jane Doe", Phone: "+1 (555) 010-2030"},
+			want: 0.85, reasons: []string{domain.DuplicateSimilarName, domain.DuplicateSamePhone},
+		},
+		{
+			name: "different name caps shared contact details",
+			a:    jane, b: domain.Patient{Name: "Robert Smith", DateOfBirth: "1980-04-02", Email: "jane@example.com", Phone: "+15550102030"},
+			want: differentNameCap, reasons: []string{domain.DuplicateSameDateOfBirth, domain.DuplicateSameEmail, domain.DuplicateSamePhone},
+		},
+		{
+			name: "empty contact details don't match",
+			a:    domain.Patient{Name: "Jane Doe"}, b: domain.Patient{Name: "Robert Smith"},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := duplicateScore(tt.a, tt.b)
+			if score != tt.want {
+				t.Errorf("score = %v, want %v", score, tt.want)
+			}
+			if len(reasons) != 0 || len(tt.reasons) != 0 {
+				if !reflect.DeepEqual(reasons, tt.reasons) {
+					t.Errorf("reasons = %v, want %v", reasons, tt.reasons)
+				}
+			}
+			if swapped, _ := duplicateScore(tt.b, tt.a); swapped != score {
+				t.Errorf("score is not symmetric: %v, then %v", score, swapped)
+			}
+		})
+	}
+}
+
+// fakeRecords is one kind of patient record, held as the patient each
+// record ID belongs to.
+type fakeRecords struct {
+	table string
+	owner map[uint]uint
+}
+
+func (r *fakeRecords) RecordType() string { return r.table }
+
+func (r *fakeRecords) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	var moved []uint
+	for id, patientID := range r.owner {
+		if patientID != fromID || (ids != nil && !containsID(ids, id)) {
+			continue
+		}
+		r.owner[id] = toID
+		moved = append(moved, id)
+	}
+	sort.Slice(moved, func(i, j int) bool { return moved[i] < moved[j] })
+	return moved, nil
+}
+
+func (r *fakeRecords) of(patientID uint) []uint {
+	var ids []uint
+	for id, owner := range r.owner {
+		if owner == patientID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeMergeAppointmentRepo struct {
+	repository.AppointmentRepository
+	records *fakeRecords
+}
+
+func (r fakeMergeAppointmentRepo) RecordType() string { return r.records.RecordType() }
+
+func (r fakeMergeAppointmentRepo) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	return r.records.ReassignPatient(ctx, fromID, toID, ids)
+}
+
+type fakeMergeRelationshipRepo struct {
+	repository.RelationshipRepository
+	records *fakeRecords
+}
+
+func (r fakeMergeRelationshipRepo) RecordType() string { return r.records.RecordType() }
+
+func (r fakeMergeRelationshipRepo) ReassignPatient(ctx context.Context, fromID, toID uint, ids []uint) ([]uint, error) {
+	return r.records.ReassignPatient(ctx, fromID, toID, ids)
+}
+
+func (r fakeMergeRelationshipRepo) Between(ctx context.Context, a, b uint) (*domain.PatientRelationship, error) {
+	return nil, domain.NewNotFoundError("relationship", 0)
+}
+
+// fakeMergePatientRepo holds patients by ID; merged ones are archived.
+type fakeMergePatientRepo struct {
+	repository.PatientRepository
+	patients map[uint]*domain.Patient
+}
+
+func (r *fakeMergePatientRepo) GetByID(ctx context.Context, id uint) (*domain.Patient, error) {
+	patient, ok := r.patients[id]
+	if !ok || patient.MergedIntoID != nil {
+		return nil, domain.NewNotFoundError("patient", id)
+	}
+	return patient, nil
+}
+
+func (r *fakeMergePatientRepo) Merge(ctx context.Context, duplicateID, survivorID uint) error {
+	r.patients[duplicateID].MergedIntoID = &survivorID
+	return nil
+}
+
+func (r *fakeMergePatientRepo) Unmerge(ctx context.Context, id uint) error {
+	patient, ok := r.patients[id]
+	if !ok || patient.MergedIntoID == nil {
+		return domain.NewNotFoundError("patient", id)
+	}
+	patient.MergedIntoID = nil
+	return nil
+}
+
+type fakeMergeRepo struct {
+	repository.PatientMergeRepository
+	merges map[uint]*domain.PatientMerge
+}
+
+func (r *fakeMergeRepo) Create(ctx context.Context, merge *domain.PatientMerge) error {
+	merge.ID = uint(len(r.merges) + 1)
+	r.merges[merge.ID] = merge
+	return nil
+}
+
+func (r *fakeMergeRepo) GetByID(ctx context.Context, id uint) (*domain.PatientMerge, error) {
+	merge, ok := r.merges[id]
+	if !ok {
+		return nil, domain.NewNotFoundError("patient_merge", id)
+	}
+	copied := *merge
+	return &copied, nil
+}
+
+func (r *fakeMergeRepo) Update(ctx context.Context, merge *domain.PatientMerge) error {
+	r.merges[merge.ID] = merge
+	return nil
+}
+
+func TestMergeAndRevertPatients(t *testing.T) {
+	// Patient 1 survives, patient 2 is the duplicate and patient 3 is
+	// someone else.
+	appointments := &fakeRecords{table: "appointments", owner: map[uint]uint{10: 1, 11: 2, 12: 2, 13: 3}}
+	relationships := &fakeRecords{table: "patient_relationships", owner: map[uint]uint{20: 1, 21: 3}}
+	vitals := &fakeRecords{table: "vital_signs", owner: map[uint]uint{30: 2}}
+	patients := &fakeMergePatientRepo{patients: map[uint]*domain.Patient{
+		1: {ID: 1, Name: "Jane Doe", TenantID: 1},
+		2: {ID: 2, Name: "Jane Doe", TenantID: 1},
+		3: {ID: 3, Name: "John Roe", TenantID: 1},
+	}}
+	merges := &fakeMergeRepo{merges: map[uint]*domain.PatientMerge{}}
+	uc := NewPatientUseCase(fakeTransactor{}, patients, fakeMergeAppointmentRepo{records: appointments}, merges,
+		fakeMergeRelationshipRepo{records: relationships}, nil, "", "", "", vitals).(*patientUseCase)
+	uc.now = func() time.Time { return billingNow }
+
+	merge, err := uc.MergePatients(context.Background(), 1, 2, &domain.User{ID: 5})
+	if err != nil {
+		t.Fatalf("MergePatients() error = %v", err)
+	}
+	wantMoved := map[string][]uint{"appointments": {11, 12}, "vital_signs": {30}}
+	if !reflect.DeepEqual(merge.MovedRecords, wantMoved) {
+		t.Errorf("moved records = %v, want %v", merge.MovedRecords, wantMoved)
+	}
+	if got := appointments.of(1); !reflect.DeepEqual(got, []uint{10, 11, 12}) {
+		t.Errorf("survivor's appointments = %v, want its own and the duplicate's", got)
+	}
+	if patients.patients[2].MergedIntoID == nil || *patients.patients[2].MergedIntoID != 1 {
+		t.Error("duplicate not archived into the survivor")
+	}
+
+	// Records the survivor gets after the merge stay with it on revert.
+	appointments.owner[14] = 1
+	vitals.owner[31] = 1
+
+	reverted, err := uc.RevertMerge(context.Background(), merge.ID, &domain.User{ID: 6})
+	if err != nil {
+		t.Fatalf("RevertMerge() error = %v", err)
+	}
+	if reverted.RevertedAt == nil || reverted.RevertedByUserID == nil || *reverted.RevertedByUserID != 6 {
+		t.Errorf("revert = %+v, want reverted by user 6", reverted)
+	}
+	if patients.patients[2].MergedIntoID != nil {
+		t.Error("duplicate still merged")
+	}
+	for _, check := range []struct {
+		records   *fakeRecords
+		patientID uint
+		want      []uint
+	}{
+		{appointments, 1, []uint{10, 14}},
+		{appointments, 2, []uint{11, 12}},
+		{appointments, 3, []uint{13}},
+		{relationships, 1, []uint{20}},
+		{relationships, 3, []uint{21}},
+		{vitals, 1, []uint{31}},
+		{vitals, 2, []uint{30}},
+	} {
+		if got := check.records.of(check.patientID); !reflect.DeepEqual(got, check.want) {
+			t.Errorf("%s of patient %d = %v, want %v", check.records.table, check.patientID, got, check.want)
+		}
+	}
+
+	if _, err := uc.RevertMerge(context.Background(), merge.ID, nil); errorCode(err) != "patient_merge_already_reverted" {
+		t.Errorf("RevertMerge() again error = %v, want patient_merge_already_reverted", err)
+	}
+}
+
+func TestMergePatientsRefused(t *testing.T) {
+	tests := []struct {
+		name                    string
+		survivorID, duplicateID uint
+		wantCode                string
+	}{
+		{name: "same patient", survivorID: 1, duplicateID: 1, wantCode: "patient_merge_same_patient"},
+		{name: "other tenant", survivorID: 1, duplicateID: 4, wantCode: "patient_merge_other_tenant"},
+		{name: "unknown duplicate", survivorID: 1, duplicateID: 9, wantCode: "patient_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointments := &fakeRecords{table: "appointments", owner: map[uint]uint{10: 4}}
+			patients := &fakeMergePatientRepo{patients: map[uint]*domain.Patient{
+				1: {ID: 1, TenantID: 1},
+				4: {ID: 4, TenantID: 2},
+			}}
+			merges := &fakeMergeRepo{merges: map[uint]*domain.PatientMerge{}}
+			uc := NewPatientUseCase(fakeTransactor{}, patients, fakeMergeAppointmentRepo{records: appointments}, merges,
+				fakeMergeRelationshipRepo{records: &fakeRecords{}}, nil, "", "", "")
+
+			if _, err := uc.MergePatients(context.Background(), tt.survivorID, tt.duplicateID, nil); errorCode(err) != tt.wantCode {
+				t.Errorf("MergePatients() error = %v, want %s", err, tt.wantCode)
+			}
+			if len(merges.merges) != 0 || !reflect.DeepEqual(appointments.of(4), []uint{10}) {
+				t.Error("a refused merge moved records")
+			}
+		})
+	}
+}
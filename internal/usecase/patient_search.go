@@ -0,0 +1,208 @@
+// internal/usecase/patient_search.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/pkg/fuzzy"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PatientQuery is a search as typed by a user. Query is classified as an
+// email (contains "@"), a phone number (digits and punctuation only) or a name.
+type PatientQuery struct {
+	Query           string
+	MRN             string
+	DateOfBirth     string
+	IncludeArchived bool
+	Limit           int
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// searchCandidates is how many token matches are ranked per search.
+	searchCandidates = 200
+	// minNameScore drops candidates whose name is only a weak match.
+	minNameScore = 0.3
+	// minPhoneDigits is the shortest phone fragment that is searched.
+	minPhoneDigits = 3
+)
+
+func (uc *patientUseCase) SearchPatients(ctx context.Context, q PatientQuery) ([]domain.PatientMatch, error) {
+	search, err := parsePatientQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := uc.patientRepo.Search(ctx, search, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]domain.PatientMatch, 0, len(candidates))
+	for _, patient := range candidates {
+		match, ok := rankPatient(patient, search)
+		if ok {
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return strings.ToLower(matches[i].Patient.Name) < strings.ToLower(matches[j].Patient.Name)
+	})
+	if len(matches) > search.Limit {
+		matches = matches[:search.Limit]
+	}
+	return matches, nil
+}
+
+func parsePatientQuery(q PatientQuery) (domain.PatientSearch, error) {
+	search := domain.PatientSearch{
+		MRN:             strings.TrimSpace(q.MRN),
+		DateOfBirth:     strings.TrimSpace(q.DateOfBirth),
+		IncludeArchived: q.IncludeArchived,
+		Limit:           q.Limit,
+	}
+	if search.Limit < 1 {
+		search.Limit = defaultSearchLimit
+	}
+	if search.Limit > maxSearchLimit {
+		search.Limit = maxSearchLimit
+	}
+
+	var fields []domain.FieldError
+	if search.DateOfBirth != "" {
+		if _, err := time.Parse("2006-01-02", search.DateOfBirth); err != nil {
+			fields = append(fields, domain.FieldError{Field: "dob", Message: "must be a date in YYYY-MM-DD format"})
+		}
+	}
+
+	query := strings.TrimSpace(q.Query)
+	switch {
+	case query == "":
+		if search.DateOfBirth == "" && search.MRN == "" {
+			fields = append(fields, domain.FieldError{Field: "q", Message: "is required unless mrn or dob is given"})
+		}
+	case strings.Contains(query, "@"):
+		search.Email = query
+	case isPhoneQuery(query):
+		if len(fuzzy.Digits(query)) < minPhoneDigits {
+			fields = append(fields, domain.FieldError{Field: "q", Message: "must contain at least 3 digits of a phone number"})
+		}
+		search.PhoneFragment = query
+	default:
+		if len(fuzzy.Words(query)) == 0 {
+			fields = append(fields, domain.FieldError{Field: "q", Message: "must contain letters or digits"})
+		}
+		search.Name = query
+	}
+
+	if len(fields) > 0 {
+		return search, domain.NewValidationError(fields...)
+	}
+	return search, nil
+}
+
+func isPhoneQuery(query string) bool {
+	for _, r := range query {
+		if !strings.ContainsRune("0123456789+-(). ", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// rankPatient scores a decrypted candidate against the search and drops
+// false positives from the token prefilter.
+func rankPatient(patient domain.Patient, search domain.PatientSearch) (domain.PatientMatch, bool) {
+	match := domain.PatientMatch{Patient: patient, Score: 1}
+
+	if search.PhoneFragment != "" {
+		highlight, ok := phoneHighlight(patient.Phone, fuzzy.Digits(search.PhoneFragment))
+		if !ok {
+			return match, false
+		}
+		match.Highlights = append(match.Highlights, highlight)
+	}
+	if search.Email != "" {
+		match.Highlights = append(match.Highlights,
+			domain.Highlight{Field: "email", Start: 0, End: len([]rune(patient.Email))})
+	}
+	if search.Name != "" {
+		terms := fuzzy.Words(search.Name)
+		score, highlights := nameScore(patient.Name, terms)
+		if preferred, preferredHighlights := nameScore(patient.PreferredName, terms); preferred > score {
+			score, highlights = preferred, preferredHighlights
+			for i := range highlights {
+				highlights[i].Field = "preferred_name"
+			}
+		}
+		if score < minNameScore {
+			return match, false
+		}
+		match.Score = math.Round(score*1000) / 1000
+		match.Highlights = append(match.Highlights, highlights...)
+	}
+	return match, true
+}
+
+// nameScore averages, over the query terms, how well each term matches its
+// best name word: 1 for a prefix ("smi" in "Smith"), otherwise a trigram
+// similarity, which tolerates typos ("jonh" for "john").
+func nameScore(name string, terms []fuzzy.Word) (float64, []domain.Highlight) {
+	words := fuzzy.Words(name)
+	if len(terms) == 0 || len(words) == 0 {
+		return 0, nil
+	}
+
+	var total float64
+	var highlights []domain.Highlight
+	for _, term := range terms {
+		best, bestHighlight := 0.0, domain.Highlight{}
+		for _, word := range words {
+			if strings.HasPrefix(word.Text, term.Text) {
+				best = 1
+				bestHighlight = domain.Highlight{Field: "name", Start: word.Start, End: word.Start + len([]rune(term.Text))}
+				break
+			}
+			// A fuzzy prefix match ranks below an exact one.
+			sim := math.Max(fuzzy.Similarity(term.Text, word.Text), 0.9*fuzzy.PrefixSimilarity(term.Text, word.Text))
+			if sim > best {
+				best = sim
+				bestHighlight = domain.Highlight{Field: "name", Start: word.Start, End: word.End}
+			}
+		}
+		total += best
+		if best >= minNameScore {
+			highlights = append(highlights, bestHighlight)
+		}
+	}
+	sort.Slice(highlights, func(i, j int) bool { return highlights[i].Start < highlights[j].Start })
+	return total / float64(len(terms)), highlights
+}
+
+// phoneHighlight finds digits in phone, skipping formatting characters.
+func phoneHighlight(phone, digits string) (domain.Highlight, bool) {
+	runes := []rune(phone)
+	var positions []int
+	for i, r := range runes {
+		if r >= '0' && r <= '9' {
+			positions = append(positions, i)
+		}
+	}
+	at := strings.Index(fuzzy.Digits(phone), digits)
+	if at < 0 || digits == "" {
+		return domain.Highlight{}, false
+	}
+	return domain.Highlight{
+		Field: "phone",
+		Start: positions[at],
+		End:   positions[at+len(digits)-1] + 1,
+	}, true
+}
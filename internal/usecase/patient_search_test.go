@@ -0,0 +1,112 @@
+// internal/usecase/patient_search_test.go
+package usecase
+
+import (
+	"doctors/internal/domain"
+	"doctors/pkg/fuzzy"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNameScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		patient    string
+		query      string
+		want       float64
+		highlights []domain.Highlight
+	}{
+		{
+			name:    "prefixes match exactly",
+			patient: "John Smith", query: "jo smi", want: 1,
+			highlights: []domain.Highlight{{Field: "name", Start: 0, End: 2}, {Field: "name", Start: 5, End: 8}},
+		},
+		{
+			name:    "terms in any order",
+			patient: "John Smith", query: "smith john", want: 1,
+			highlights: []domain.Highlight{{Field: "name", Start: 0, End: 4}, {Field: "name", Start: 5, End: 10}},
+		},
+		{
+			name:    "typo scores below a prefix",
+			patient: "John Smith", query: "jonh smith", want: 0.725,
+			highlights: []domain.Highlight{{Field: "name", Start: 0, End: 4}, {Field: "name", Start: 5, End: 10}},
+		},
+		{
+			name:    "weak matches are not highlighted",
+			patient: "John Smith", query: "smyth", want: 0.36,
+			highlights: []domain.Highlight{{Field: "name", Start: 5, End: 10}},
+		},
+		{
+			name:    "accents are ignored",
+			patient: "José Núñez", query: "jose nunez", want: 1,
+			highlights: []domain.Highlight{{Field: "name", Start: 0, End: 4}, {Field: "name", Start: 5, End: 10}},
+		},
+		{name: "no match", patient: "John Smith", query: "garcia", want: 0},
+		{name: "empty name", patient: "", query: "john", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, highlights := nameScore(tt.patient, fuzzy.Words(tt.query))
+			if math.Abs(score-tt.want) > 1e-9 {
+				t.Errorf("score = %v, want %v", score, tt.want)
+			}
+			if len(highlights) == 0 && len(tt.highlights) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(highlights, tt.highlights) {
+				t.Errorf("highlights = %+v, want %+v", highlights, tt.highlights)
+			}
+		})
+	}
+}
+
+func TestRankPatientOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		candidates []domain.Patient
+		want       []string
+	}{
+		{
+			name:  "exact, then typos, then weak matches dropped",
+			query: "john smith",
+			candidates: []domain.Patient{
+				{Name: "Maria Garcia"},
+				{Name: "Johanna Smyth"},
+				{Name: "Jon Smithers"},
+				{Name: "John Smith"},
+			},
+			want: []string{"John Smith", "Jon Smithers", "Johanna Smyth"},
+		},
+		{
+			name:  "preferred name counts when it matches better",
+			query: "bob smi",
+			candidates: []domain.Patient{
+				{Name: "Bobby Smart"},
+				{Name: "Robert Smith", PreferredName: "Bob Smith"},
+				{Name: "Robert Jones"},
+			},
+			want: []string{"Robert Smith", "Bobby Smart"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var matches []domain.PatientMatch
+			for _, p := range tt.candidates {
+				if match, ok := rankPatient(p, domain.PatientSearch{Name: tt.query}); ok {
+					matches = append(matches, match)
+				}
+			}
+			sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+			got := make([]string, len(matches))
+			for i, m := range matches {
+				got[i] = m.Patient.Name
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranking = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
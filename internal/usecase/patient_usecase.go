@@ -5,42 +5,272 @@ import (
 	"context"
 	"doctors/internal/domain"
 	"doctors/internal/repository"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type PatientUseCase interface {
-	CreatePatient(ctx context.Context, patient *domain.Patient) error
+	// CreatePatient saves a new patient and returns existing patients that
+	// probably are the same person. Under the block policy such a match
+	// fails the create instead.
+	CreatePatient(ctx context.Context, patient *domain.Patient) ([]domain.DuplicateMatch, error)
 	GetPatient(ctx context.Context, id uint) (*domain.Patient, error)
-	UpdatePatient(ctx context.Context, patient *domain.Patient) error
+	FindPatientsByEmail(ctx context.Context, email string) ([]domain.Patient, error)
+	// UpdatePatient replaces all client-editable fields. ifMatch, when set,
+	// is the version the client last saw.
+	UpdatePatient(ctx context.Context, patient *domain.Patient, ifMatch *uint) error
+	// PatchPatient applies a JSON Merge Patch (RFC 7386).
+	PatchPatient(ctx context.Context, id uint, patch []byte, ifMatch *uint) (*domain.Patient, error)
+	// DeletePatient archives a patient, applying the configured policy to
+	// their upcoming appointments.
 	DeletePatient(ctx context.Context, id uint) error
-	ListPatients(ctx context.Context, page, pageSize int) ([]domain.Patient, int64, error)
+	RestorePatient(ctx context.Context, id uint) (*domain.Patient, error)
+	ListPatients(ctx context.Context, page, pageSize int, includeArchived bool) ([]domain.Patient, int64, error)
+	// SearchPatients finds patients by a free-text query (fuzzy name, email
+	// or phone digits) and/or exact date of birth, best matches first.
+	SearchPatients(ctx context.Context, search PatientQuery) ([]domain.PatientMatch, error)
+	// FindDuplicatePairs reports probable duplicates scoring at least minScore.
+	FindDuplicatePairs(ctx context.Context, minScore float64, limit int) ([]domain.DuplicatePair, error)
+	MergePatients(ctx context.Context, survivorID, duplicateID uint, actor *domain.User) (*domain.PatientMerge, error)
+	RevertMerge(ctx context.Context, mergeID uint, actor *domain.User) (*domain.PatientMerge, error)
+	ListMerges(ctx context.Context, patientID uint) ([]domain.PatientMerge, error)
+	// AssignMissingMRNs gives a medical record number to patients created
+	// before MRNs existed.
+	AssignMissingMRNs(ctx context.Context, batchSize int) (int, error)
+	// ValidatePatient normalizes and checks a patient without saving it.
+	ValidatePatient(patient *domain.Patient) error
+	// StartImport maps the header (table[0]) of a table of patients to
+	// patient fields and queues its rows to be imported, or only checked
+	// for a dry run, by RunPendingImports.
+	StartImport(ctx context.Context, table [][]string, opts PatientImportOptions, actor *domain.User) (*domain.PatientImport, error)
+	// RunPendingImports runs queued imports, and takes over ones whose
+	// worker went away, until there are none left. It returns how many it ran.
+	RunPendingImports(ctx context.Context) (int, error)
+	GetImport(ctx context.Context, id uint) (*domain.PatientImport, error)
+	ListImports(ctx context.Context, page, pageSize int) ([]domain.PatientImport, int64, error)
+	// ExportPatients writes a header and then a row per active patient with
+	// the given columns, PatientExportColumns when none are given.
+	ExportPatients(ctx context.Context, columns []string, write func(row []string) error) error
 }
 
+// Policies for upcoming appointments when their patient is deleted.
+const (
+	PatientDeleteBlock  = "block"
+	PatientDeleteCancel = "cancel"
+)
+
 type patientUseCase struct {
-	patientRepo repository.PatientRepository
+	transactor       repository.Transactor
+	patientRepo      repository.PatientRepository
+	appointmentRepo  repository.AppointmentRepository
+	mergeRepo        repository.PatientMergeRepository
+	relationshipRepo repository.RelationshipRepository
+	importRepo       repository.PatientImportRepository
+	deletePolicy     string
+	duplicatePolicy  string
+	mrnFormat        string
+	// records are moved from the duplicate to the survivor on merge.
+	records []repository.PatientRecords
+	now     func() time.Time
 }
 
-func NewPatientUseCase(patientRepo repository.PatientRepository) PatientUseCase {
-	return &patientUseCase{patientRepo: patientRepo}
+func NewPatientUseCase(
+	transactor repository.Transactor,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	mergeRepo repository.PatientMergeRepository,
+	relationshipRepo repository.RelationshipRepository,
+	importRepo repository.PatientImportRepository,
+	deletePolicy string,
+	duplicatePolicy string,
+	mrnFormat string,
+	// records lists repositories of patient records besides appointments
+	// and relationships, which merges must move too.
+	records ...repository.PatientRecords,
+) PatientUseCase {
+	if mrnFormat == "" {
+		mrnFormat = DefaultMRNFormat
+	}
+	return &patientUseCase{
+		transactor:       transactor,
+		patientRepo:      patientRepo,
+		appointmentRepo:  appointmentRepo,
+		mergeRepo:        mergeRepo,
+		relationshipRepo: relationshipRepo,
+		importRepo:       importRepo,
+		deletePolicy:     deletePolicy,
+		duplicatePolicy:  duplicatePolicy,
+		mrnFormat:        mrnFormat,
+		records:          append([]repository.PatientRecords{appointmentRepo, relationshipRepo}, records...),
+		now:              time.Now,
+	}
 }
 
-func (uc *patientUseCase) CreatePatient(ctx context.Context, patient *domain.Patient) error {
-	// Add any business logic here
-	return uc.patientRepo.Create(ctx, patient)
+func (uc *patientUseCase) CreatePatient(ctx context.Context, patient *domain.Patient) ([]domain.DuplicateMatch, error) {
+	if err := uc.validatePatient(patient); err != nil {
+		return nil, err
+	}
+
+	duplicates, err := uc.findDuplicates(ctx, patient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if len(duplicates) > 0 && uc.duplicatePolicy == PatientDuplicateBlock {
+		return nil, duplicateConflict(duplicates)
+	}
+
+	patient.TenantID = tenantOf(ctx)
+	// Patients imported from another system may keep their existing MRN.
+	if patient.MRN == "" {
+		if patient.MRN, err = uc.nextMRN(ctx, patient.TenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := uc.patientRepo.Create(ctx, patient); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
 }
 
 func (uc *patientUseCase) GetPatient(ctx context.Context, id uint) (*domain.Patient, error) {
 	return uc.patientRepo.GetByID(ctx, id)
 }
 
-func (uc *patientUseCase) UpdatePatient(ctx context.Context, patient *domain.Patient) error {
-	// Add any business logic here
-	return uc.patientRepo.Update(ctx, patient)
+func (uc *patientUseCase) FindPatientsByEmail(ctx context.Context, email string) ([]domain.Patient, error) {
+	return uc.patientRepo.GetByEmail(ctx, email)
+}
+
+func (uc *patientUseCase) UpdatePatient(ctx context.Context, patient *domain.Patient, ifMatch *uint) error {
+	if err := uc.validatePatient(patient); err != nil {
+		return err
+	}
+
+	existing, err := uc.patientRepo.GetByID(ctx, patient.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion("patient", existing.Version, ifMatch); err != nil {
+		return err
+	}
+	keepPatientSystemFields(patient, existing)
+
+	return versionConflict(uc.patientRepo.Update(ctx, patient), "patient", ifMatch)
+}
+
+func (uc *patientUseCase) PatchPatient(ctx context.Context, id uint, patch []byte, ifMatch *uint) (*domain.Patient, error) {
+	existing, err := uc.patientRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion("patient", existing.Version, ifMatch); err != nil {
+		return nil, err
+	}
+
+	var patient domain.Patient
+	if err := applyMergePatch(existing, patch, &patient); err != nil {
+		return nil, err
+	}
+	keepPatientSystemFields(&patient, existing)
+	if err := uc.validatePatient(&patient); err != nil {
+		return nil, err
+	}
+
+	if err := uc.patientRepo.Update(ctx, &patient); err != nil {
+		return nil, versionConflict(err, "patient", ifMatch)
+	}
+	return &patient, nil
 }
 
 func (uc *patientUseCase) DeletePatient(ctx context.Context, id uint) error {
-	return uc.patientRepo.Delete(ctx, id)
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := uc.patientRepo.GetByID(ctx, id); err != nil {
+			return err
+		}
+
+		upcoming, err := uc.appointmentRepo.GetUpcomingByPatient(ctx, id, uc.now())
+		if err != nil {
+			return err
+		}
+		if len(upcoming) > 0 {
+			if uc.deletePolicy != PatientDeleteCancel {
+				return domain.NewConflictError("patient_has_upcoming_appointments",
+					fmt.Sprintf("patient has %d upcoming appointments; cancel them first", len(upcoming)))
+			}
+			for i := range upcoming {
+				upcoming[i].Status = domain.AppointmentStatusCancelled
+				if err := uc.appointmentRepo.Update(ctx, &upcoming[i]); err != nil {
+					return fmt.Errorf("failed to cancel appointment %d: %w", upcoming[i].ID, err)
+				}
+			}
+		}
+
+		return uc.patientRepo.Delete(ctx, id)
+	})
+}
+
+func (uc *patientUseCase) RestorePatient(ctx context.Context, id uint) (*domain.Patient, error) {
+	return uc.patientRepo.Restore(ctx, id)
+}
+
+func (uc *patientUseCase) ListPatients(ctx context.Context, page, pageSize int, includeArchived bool) ([]domain.Patient, int64, error) {
+	return uc.patientRepo.List(ctx, page, pageSize, includeArchived)
+}
+
+func (uc *patientUseCase) ValidatePatient(patient *domain.Patient) error {
+	return uc.validatePatient(patient)
+}
+
+// validatePatient normalizes user-entered fields and checks them.
+func (uc *patientUseCase) validatePatient(patient *domain.Patient) error {
+	patient.Name = strings.TrimSpace(patient.Name)
+	patient.Email = strings.TrimSpace(patient.Email)
+	patient.Phone = normalizePhone(patient.Phone)
+	patient.DateOfBirth = strings.TrimSpace(patient.DateOfBirth)
+	patient.MRN = strings.TrimSpace(patient.MRN)
+	patient.NationalID = strings.TrimSpace(patient.NationalID)
+	patient.PreferredName = strings.TrimSpace(patient.PreferredName)
+	patient.Address.Country = strings.ToUpper(strings.TrimSpace(patient.Address.Country))
+	for i := range patient.EmergencyContacts {
+		contact := &patient.EmergencyContacts[i]
+		contact.Name = strings.TrimSpace(contact.Name)
+		contact.Phone = normalizePhone(contact.Phone)
+		contact.Email = strings.TrimSpace(contact.Email)
+	}
+	if patient.PreferredContactChannel == "" {
+		patient.PreferredContactChannel = domain.ContactChannelEmail
+	}
+
+	var extra []domain.FieldError
+	if dob, err := time.Parse("2006-01-02", patient.DateOfBirth); err == nil && dob.After(uc.now()) {
+		extra = append(extra, domain.FieldError{Field: "date_of_birth", Message: "must not be in the future"})
+	}
+	switch patient.PreferredContactChannel {
+	case domain.ContactChannelSMS, domain.ContactChannelPhone:
+		if patient.Phone == "" {
+			extra = append(extra, domain.FieldError{Field: "preferred_contact_channel", Message: "requires a phone number"})
+		}
+	case domain.ContactChannelMail:
+		if patient.Address.Line1 == "" || patient.Address.City == "" {
+			extra = append(extra, domain.FieldError{Field: "preferred_contact_channel", Message: "requires an address"})
+		}
+	}
+	if len(patient.MRN) > 50 {
+		extra = append(extra, domain.FieldError{Field: "mrn", Message: "must be at most 50 characters"})
+	}
+	return validateStruct(patient, extra...)
 }
 
-func (uc *patientUseCase) ListPatients(ctx context.Context, page, pageSize int) ([]domain.Patient, int64, error) {
-	return uc.patientRepo.List(ctx, page, pageSize)
+// keepPatientSystemFields copies fields clients may not change from the stored patient.
+func keepPatientSystemFields(patient, existing *domain.Patient) {
+	patient.ID = existing.ID
+	patient.CreatedAt = existing.CreatedAt
+	patient.DeletedAt = existing.DeletedAt
+	patient.Version = existing.Version
+	patient.DataKey = existing.DataKey
+	patient.KeyID = existing.KeyID
+	patient.MergedIntoID = existing.MergedIntoID
+	patient.MRN = existing.MRN
+	patient.TenantID = existing.TenantID
 }
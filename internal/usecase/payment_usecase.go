@@ -0,0 +1,406 @@
+// internal/usecase/payment_usecase.go
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/gateway"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type PaymentUseCase interface {
+	// PayInvoice starts a card payment of an issued invoice, of its whole
+	// balance when amountCents is 0.
+	PayInvoice(ctx context.Context, invoiceID uint, amountCents int64, idempotencyKey string, actor *domain.User) (*domain.PaymentIntent, error)
+	// PayDeposit starts a card payment of a deposit for a scheduled
+	// appointment, of the configured deposit when amountCents is 0.
+	PayDeposit(ctx context.Context, appointmentID uint, amountCents int64, idempotencyKey string, actor *domain.User) (*domain.PaymentIntent, error)
+	GetIntent(ctx context.Context, id uint) (*domain.PaymentIntent, error)
+	ListIntents(ctx context.Context, search domain.PaymentIntentSearch) ([]domain.PaymentIntent, int64, error)
+	CancelIntent(ctx context.Context, id uint) (*domain.PaymentIntent, error)
+	// HandleWebhook verifies a webhook delivery and applies its event.
+	// Events delivered again are acknowledged without effect.
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+	// ReconcilePending asks the provider about intents still pending after
+	// a while, in case their webhooks were lost, and returns the number
+	// that changed.
+	ReconcilePending(ctx context.Context) (int, error)
+}
+
+// PaymentSettings configure card payments.
+type PaymentSettings struct {
+	// Currency is the billing currency.
+	Currency string
+	// DepositCents is the deposit asked for an appointment when a request
+	// names no amount; 0 makes the amount required.
+	DepositCents int64
+}
+
+const (
+	// reconcileAfter is how long an intent may stay pending before the
+	// provider is asked about it.
+	reconcileAfter = 10 * time.Minute
+	// reconcileWindow is how far back pending intents are reconciled.
+	// Older ones were abandoned by the patient.
+	reconcileWindow = 7 * 24 * time.Hour
+	// reconcileBatch is how many intents one reconciliation looks at.
+	reconcileBatch = 100
+)
+
+type paymentUseCase struct {
+	transactor      repository.Transactor
+	intentRepo      repository.PaymentIntentRepository
+	invoiceRepo     repository.InvoiceRepository
+	appointmentRepo repository.AppointmentRepository
+	billing         BillingUseCase
+	provider        gateway.PaymentProvider
+	settings        PaymentSettings
+	now             func() time.Time
+}
+
+func NewPaymentUseCase(
+	transactor repository.Transactor,
+	intentRepo repository.PaymentIntentRepository,
+	invoiceRepo repository.InvoiceRepository,
+	appointmentRepo repository.AppointmentRepository,
+	billing BillingUseCase,
+	provider gateway.PaymentProvider,
+	settings PaymentSettings,
+) PaymentUseCase {
+	return &paymentUseCase{
+		transactor:      transactor,
+		intentRepo:      intentRepo,
+		invoiceRepo:     invoiceRepo,
+		appointmentRepo: appointmentRepo,
+		billing:         billing,
+		provider:        provider,
+		settings:        settings,
+		now:             time.Now,
+	}
+}
+
+func (uc *paymentUseCase) PayInvoice(ctx context.Context, invoiceID uint, amountCents int64, idempotencyKey string, actor *domain.User) (*domain.PaymentIntent, error) {
+	invoice, err := uc.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if amountCents == 0 {
+		amountCents = invoice.BalanceCents
+	}
+	intent := &domain.PaymentIntent{
+		Purpose:         domain.IntentPurposeInvoice,
+		PatientID:       invoice.PatientID,
+		InvoiceID:       &invoice.ID,
+		AmountCents:     amountCents,
+		Currency:        invoice.Currency,
+		CreatedByUserID: actorID(actor),
+	}
+	// A retry is answered before the checks, which the first request may
+	// have changed the outcome of.
+	if existing, err := uc.findRetry(ctx, idempotencyKey, intent); existing != nil || err != nil {
+		return existing, err
+	}
+
+	switch {
+	case invoice.Status != domain.InvoiceIssued:
+		return nil, domain.NewConflictError("invoice_not_payable", fmt.Sprintf("the invoice is %s; only issued invoices can be paid", invoice.Status))
+	case amountCents < 0:
+		return nil, domain.NewValidationError(domain.FieldError{Field: "amount_cents", Message: "must be positive"})
+	case amountCents > invoice.BalanceCents:
+		return nil, domain.NewConflictError("payment_exceeds_balance",
+			fmt.Sprintf("the payment is more than the balance of %s", formatCents(invoice.BalanceCents)))
+	}
+
+	description := "Invoice " + invoice.Number
+	return uc.startIntent(ctx, intent, description, idempotencyKey, map[string]string{"invoice_id": strconv.FormatUint(uint64(invoice.ID), 10)})
+}
+
+func (uc *paymentUseCase) PayDeposit(ctx context.Context, appointmentID uint, amountCents int64, idempotencyKey string, actor *domain.User) (*domain.PaymentIntent, error) {
+	appointment, err := uc.appointmentRepo.GetByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if amountCents == 0 {
+		amountCents = uc.settings.DepositCents
+	}
+	intent := &domain.PaymentIntent{
+		Purpose:         domain.IntentPurposeDeposit,
+		PatientID:       appointment.PatientID,
+		AppointmentID:   &appointment.ID,
+		AmountCents:     amountCents,
+		Currency:        uc.settings.Currency,
+		CreatedByUserID: actorID(actor),
+	}
+	if existing, err := uc.findRetry(ctx, idempotencyKey, intent); existing != nil || err != nil {
+		return existing, err
+	}
+
+	if appointment.Status != domain.AppointmentStatusScheduled {
+		return nil, domain.NewConflictError("appointment_not_scheduled", "deposits are only taken for scheduled appointments")
+	}
+	if amountCents <= 0 {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "amount_cents", Message: "must be positive"})
+	}
+
+	description := "Deposit for the appointment on " + appointment.DateTime.Format("January 2, 2006")
+	return uc.startIntent(ctx, intent, description, idempotencyKey, map[string]string{"appointment_id": strconv.FormatUint(uint64(appointment.ID), 10)})
+}
+
+// findRetry returns the intent an earlier request with the idempotency key
+// created, after checking it was for the same payment.
+func (uc *paymentUseCase) findRetry(ctx context.Context, idempotencyKey string, intent *domain.PaymentIntent) (*domain.PaymentIntent, error) {
+	if idempotencyKey == "" {
+		return nil, nil
+	}
+	existing, err := uc.intentRepo.FindByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if existing.Purpose != intent.Purpose || !sameID(existing.InvoiceID, intent.InvoiceID) ||
+		!sameID(existing.AppointmentID, intent.AppointmentID) || existing.AmountCents != intent.AmountCents {
+		return nil, domain.NewConflictError("idempotency_key_reused", "the Idempotency-Key was used for a different payment")
+	}
+	return existing, nil
+}
+
+func sameID(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// startIntent creates the intent at the provider and stores it. The
+// provider gets the same idempotency key, so when storing fails, a retry
+// of the request picks up the intent the provider made the first time.
+func (uc *paymentUseCase) startIntent(ctx context.Context, intent *domain.PaymentIntent, description, idempotencyKey string, metadata map[string]string) (*domain.PaymentIntent, error) {
+	if idempotencyKey == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		idempotencyKey = hex.EncodeToString(b)
+	}
+	metadata["purpose"] = intent.Purpose
+	metadata["patient_id"] = strconv.FormatUint(uint64(intent.PatientID), 10)
+
+	remote, err := uc.provider.CreateIntent(ctx, gateway.IntentParams{
+		AmountCents:    intent.AmountCents,
+		Currency:       intent.Currency,
+		Description:    description,
+		Metadata:       metadata,
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	intent.Provider = uc.provider.Name()
+	intent.ProviderIntentID = remote.ID
+	intent.ClientSecret = remote.ClientSecret
+	intent.Status = remote.Status
+	intent.FailureReason = remote.LastError
+	intent.IdempotencyKey = idempotencyKey
+	if err := uc.intentRepo.Create(ctx, intent); err != nil {
+		// The same request, sent twice at once, got there first.
+		if errors.Is(err, domain.ErrConflict) {
+			if existing, findErr := uc.intentRepo.FindByIdempotencyKey(ctx, idempotencyKey); findErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	return intent, nil
+}
+
+// providerError turns a provider failure into a domain error.
+func providerError(err error) error {
+	switch {
+	case errors.Is(err, gateway.ErrRejected):
+		return domain.NewConflictError("payment_provider_rejected", err.Error())
+	case errors.Is(err, gateway.ErrUnavailable):
+		return domain.NewUnavailableError("payment_provider_unavailable",
+			"The payment provider is unavailable; retry with the same Idempotency-Key", err)
+	}
+	return err
+}
+
+func (uc *paymentUseCase) GetIntent(ctx context.Context, id uint) (*domain.PaymentIntent, error) {
+	return uc.intentRepo.GetByID(ctx, id)
+}
+
+func (uc *paymentUseCase) ListIntents(ctx context.Context, search domain.PaymentIntentSearch) ([]domain.PaymentIntent, int64, error) {
+	if search.Limit < 1 {
+		search.Limit = defaultSearchLimit
+	}
+	if search.Limit > maxSearchLimit {
+		search.Limit = maxSearchLimit
+	}
+	if search.Offset < 0 {
+		search.Offset = 0
+	}
+	return uc.intentRepo.List(ctx, search)
+}
+
+func (uc *paymentUseCase) CancelIntent(ctx context.Context, id uint) (*domain.PaymentIntent, error) {
+	intent, err := uc.intentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if intentFinal(intent) {
+		return nil, domain.NewConflictError("payment_intent_final", fmt.Sprintf("the payment intent has %s", intent.Status))
+	}
+	if intent.Provider != uc.provider.Name() {
+		return nil, domain.NewConflictError("payment_provider_changed", "the payment intent belongs to another payment provider")
+	}
+
+	remote, err := uc.provider.CancelIntent(ctx, intent.ProviderIntentID)
+	if err != nil {
+		return nil, providerError(err)
+	}
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if intent, err = uc.intentRepo.GetForUpdate(ctx, id); err != nil {
+			return err
+		}
+		_, err := uc.apply(ctx, intent, remote)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+func (uc *paymentUseCase) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := uc.provider.ParseWebhook(payload, signature)
+	if errors.Is(err, gateway.ErrInvalidSignature) {
+		return domain.NewBadRequestError("invalid_signature", "The webhook signature is not valid")
+	}
+	if err != nil {
+		return domain.NewBadRequestError("invalid_event", err.Error())
+	}
+
+	// The event is recorded in the same transaction that applies it, so a
+	// delivery that fails halfway is applied in full when it is retried.
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		fresh, err := uc.intentRepo.RecordEvent(ctx, uc.provider.Name(), event.ID, event.Type)
+		if err != nil || !fresh || event.Intent == nil {
+			return err
+		}
+		intent, err := uc.intentRepo.FindByProviderIntent(ctx, uc.provider.Name(), event.Intent.ID)
+		if err != nil {
+			return err
+		}
+		if intent == nil {
+			// Intents made outside this system, e.g. in the provider's dashboard.
+			return nil
+		}
+		_, err = uc.apply(ctx, intent, event.Intent)
+		return err
+	})
+}
+
+func (uc *paymentUseCase) ReconcilePending(ctx context.Context) (int, error) {
+	now := uc.now()
+	pending, err := uc.intentRepo.ListPending(ctx, uc.provider.Name(), now.Add(-reconcileWindow), now.Add(-reconcileAfter), reconcileBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, p := range pending {
+		remote, err := uc.provider.GetIntent(ctx, p.ProviderIntentID)
+		if err != nil {
+			fmt.Printf("Failed to reconcile payment intent %d: %v\n", p.ID, err)
+			continue
+		}
+		err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			intent, err := uc.intentRepo.GetForUpdate(ctx, p.ID)
+			if err != nil {
+				return err
+			}
+			updated, err := uc.apply(ctx, intent, remote)
+			if updated {
+				changed++
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Failed to reconcile payment intent %d: %v\n", p.ID, err)
+		}
+	}
+	return changed, nil
+}
+
+func intentFinal(intent *domain.PaymentIntent) bool {
+	return intent.Status == domain.PaymentIntentSucceeded || intent.Status == domain.PaymentIntentCanceled
+}
+
+// apply brings an intent up to date with the provider's view of it, and
+// posts the payment once it succeeds. Final states stick: webhooks may
+// arrive out of order, and an intent is only posted once.
+func (uc *paymentUseCase) apply(ctx context.Context, intent *domain.PaymentIntent, remote *gateway.Intent) (bool, error) {
+	if intentFinal(intent) || (remote.Status == intent.Status && remote.LastError == intent.FailureReason) {
+		return false, nil
+	}
+	intent.Status = remote.Status
+	intent.FailureReason = remote.LastError
+	if remote.Status == gateway.IntentSucceeded {
+		now := uc.now()
+		intent.SucceededAt = &now
+	}
+	if err := uc.intentRepo.Update(ctx, intent); err != nil {
+		return false, err
+	}
+	if remote.Status != gateway.IntentSucceeded {
+		return true, nil
+	}
+	return true, uc.post(ctx, intent)
+}
+
+// post records a successful intent as a payment. A deposit waits for its
+// appointment's invoice to be issued.
+func (uc *paymentUseCase) post(ctx context.Context, intent *domain.PaymentIntent) error {
+	switch intent.Purpose {
+	case domain.IntentPurposeInvoice:
+		if intent.InvoiceID == nil {
+			return nil
+		}
+		payment := &domain.Payment{
+			InvoiceID:   *intent.InvoiceID,
+			AmountCents: intent.AmountCents,
+			Method:      domain.PaymentMethodCard,
+			Reference:   intent.ProviderIntentID,
+			Note:        "Paid online",
+			ReceivedAt:  *intent.SucceededAt,
+		}
+		_, err := uc.billing.RecordPayment(ctx, payment, nil)
+		if errors.Is(err, domain.ErrConflict) {
+			// The money was taken but the invoice no longer wants it, e.g.
+			// it was paid at the desk meanwhile. Staff refund it.
+			fmt.Printf("Failed to post payment intent %d: %v\n", intent.ID, err)
+			intent.FailureReason = "not posted to the invoice: " + err.Error()
+			return uc.intentRepo.Update(ctx, intent)
+		}
+		if err != nil {
+			return err
+		}
+		intent.PaymentID = &payment.ID
+		return uc.intentRepo.Update(ctx, intent)
+
+	case domain.IntentPurposeDeposit:
+		if intent.AppointmentID == nil {
+			return nil
+		}
+		invoice, err := uc.invoiceRepo.FindByAppointment(ctx, *intent.AppointmentID)
+		if err != nil || invoice == nil || invoice.Status != domain.InvoiceIssued {
+			return err
+		}
+		_, err = uc.billing.ApplyDeposits(ctx, invoice.ID)
+		return err
+	}
+	return nil
+}
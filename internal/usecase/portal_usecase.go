@@ -0,0 +1,196 @@
+// internal/usecase/portal_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"fmt"
+	"time"
+)
+
+// PortalUseCase serves patient portal users: a patient acting for
+// themselves and for the dependents they are guardian or caregiver of.
+type PortalUseCase interface {
+	GetAccount(ctx context.Context, user *domain.User) (*domain.PortalAccount, error)
+	// ListAppointments returns the user's appointments and those of the
+	// dependents whose records or bookings they manage.
+	ListAppointments(ctx context.Context, user *domain.User) ([]domain.Appointment, error)
+	BookAppointment(ctx context.Context, user *domain.User, appointment *domain.Appointment) error
+	CancelAppointment(ctx context.Context, user *domain.User, id uint) (*domain.Appointment, error)
+	RescheduleAppointment(ctx context.Context, user *domain.User, id uint, dateTime time.Time) (*domain.Appointment, error)
+}
+
+type portalUseCase struct {
+	patientRepo        repository.PatientRepository
+	appointmentRepo    repository.AppointmentRepository
+	relationshipRepo   repository.RelationshipRepository
+	appointmentUseCase AppointmentUseCase
+}
+
+func NewPortalUseCase(
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	relationshipRepo repository.RelationshipRepository,
+	appointmentUseCase AppointmentUseCase,
+) PortalUseCase {
+	return &portalUseCase{
+		patientRepo:        patientRepo,
+		appointmentRepo:    appointmentRepo,
+		relationshipRepo:   relationshipRepo,
+		appointmentUseCase: appointmentUseCase,
+	}
+}
+
+func (uc *portalUseCase) GetAccount(ctx context.Context, user *domain.User) (*domain.PortalAccount, error) {
+	self, err := portalPatientID(user)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := uc.patientRepo.GetByID(ctx, self)
+	if err != nil {
+		return nil, err
+	}
+
+	rels, err := uc.relationshipRepo.ListDependents(ctx, self)
+	if err != nil {
+		return nil, err
+	}
+	// Like ListAppointments, dependents the user can neither book for nor
+	// view are left out.
+	var ids []uint
+	for _, rel := range rels {
+		if rel.CanBook || rel.CanViewRecords {
+			ids = append(ids, rel.PatientID)
+		}
+	}
+	dependents, err := uc.patientRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]domain.Patient, len(dependents))
+	for _, p := range dependents {
+		byID[p.ID] = p
+	}
+
+	account := &domain.PortalAccount{Patient: *patient, Dependents: []domain.PortalDependent{}}
+	for _, rel := range rels {
+		dependent, ok := byID[rel.PatientID]
+		if !ok {
+			continue
+		}
+		entry := domain.PortalDependent{
+			Relationship: rel,
+			Identity:     domain.PatientIdentity{ID: dependent.ID, Name: dependent.Name, PreferredName: dependent.PreferredName},
+		}
+		if rel.CanViewRecords {
+			entry.Patient = &dependent
+		}
+		account.Dependents = append(account.Dependents, entry)
+	}
+	return account, nil
+}
+
+func (uc *portalUseCase) ListAppointments(ctx context.Context, user *domain.User) ([]domain.Appointment, error) {
+	self, err := portalPatientID(user)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := uc.relationshipRepo.ListDependents(ctx, self)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint{self}
+	for _, rel := range rels {
+		if rel.CanBook || rel.CanViewRecords {
+			ids = append(ids, rel.PatientID)
+		}
+	}
+	return uc.appointmentRepo.ListByPatients(ctx, ids)
+}
+
+func (uc *portalUseCase) BookAppointment(ctx context.Context, user *domain.User, appointment *domain.Appointment) error {
+	if appointment.PatientID == 0 {
+		self, err := portalPatientID(user)
+		if err != nil {
+			return err
+		}
+		appointment.PatientID = self
+	}
+	if err := uc.authorizeBooking(ctx, user, appointment.PatientID); err != nil {
+		return err
+	}
+	appointment.ID = 0
+	appointment.Status = domain.AppointmentStatusScheduled
+	return uc.appointmentUseCase.CreateAppointment(ctx, appointment)
+}
+
+func (uc *portalUseCase) CancelAppointment(ctx context.Context, user *domain.User, id uint) (*domain.Appointment, error) {
+	appointment, err := uc.scheduledAppointment(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+	appointment.Status = domain.AppointmentStatusCancelled
+	if err := uc.appointmentUseCase.UpdateAppointment(ctx, appointment, nil); err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+func (uc *portalUseCase) RescheduleAppointment(ctx context.Context, user *domain.User, id uint, dateTime time.Time) (*domain.Appointment, error) {
+	appointment, err := uc.scheduledAppointment(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+	appointment.DateTime = dateTime
+	if err := uc.appointmentUseCase.UpdateAppointment(ctx, appointment, nil); err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// scheduledAppointment loads an appointment the user may manage. Other
+// people's appointments are reported as missing rather than forbidden.
+func (uc *portalUseCase) scheduledAppointment(ctx context.Context, user *domain.User, id uint) (*domain.Appointment, error) {
+	appointment, err := uc.appointmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.authorizeBooking(ctx, user, appointment.PatientID); err != nil {
+		return nil, domain.NewNotFoundError("appointment", id)
+	}
+	if appointment.Status != domain.AppointmentStatusScheduled {
+		return nil, domain.NewConflictError("appointment_not_scheduled", "only scheduled appointments can be changed")
+	}
+	return appointment, nil
+}
+
+// authorizeBooking allows users to book for themselves and for dependents
+// whose relationship grants booking.
+func (uc *portalUseCase) authorizeBooking(ctx context.Context, user *domain.User, patientID uint) error {
+	self, err := portalPatientID(user)
+	if err != nil {
+		return err
+	}
+	if patientID == self {
+		return nil
+	}
+	rels, err := uc.relationshipRepo.ListDependents(ctx, self)
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		if rel.PatientID == patientID && rel.CanBook {
+			return nil
+		}
+	}
+	return domain.NewForbiddenError(fmt.Sprintf("you may not book appointments for patient %d", patientID))
+}
+
+func portalPatientID(user *domain.User) (uint, error) {
+	if user == nil || user.PatientID == nil {
+		return 0, domain.NewForbiddenError("this account is not linked to a patient")
+	}
+	return *user.PatientID, nil
+}
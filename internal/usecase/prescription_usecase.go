@@ -0,0 +1,495 @@
+// internal/usecase/prescription_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/drugdata"
+	"doctors/pkg/pdf"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PrescriptionUseCase interface {
+	// CheckPrescription runs the safety checks of a prescription against the
+	// patient's active medications and allergies without issuing it.
+	CheckPrescription(ctx context.Context, prescription *domain.Prescription) ([]domain.PrescriptionWarning, error)
+	// CreatePrescription issues a prescription in an encounter and adds the
+	// drug to the patient's medications. Only doctors prescribe. Major or
+	// contraindicated warnings are only accepted with an override reason.
+	CreatePrescription(ctx context.Context, prescription *domain.Prescription, actor *domain.User) error
+	GetPrescription(ctx context.Context, id uint) (*domain.Prescription, error)
+	ListPatientPrescriptions(ctx context.Context, patientID uint) ([]domain.Prescription, error)
+	ListEncounterPrescriptions(ctx context.Context, encounterID uint) ([]domain.Prescription, error)
+	// CancelPrescription voids a prescription and resolves the medication it added.
+	CancelPrescription(ctx context.Context, id uint, reason string, actor *domain.User) (*domain.Prescription, error)
+	// RenderPrescription returns a prescription as a printable PDF.
+	RenderPrescription(ctx context.Context, id uint) ([]byte, error)
+}
+
+// PrescriptionSettings identify this practice on printed prescriptions.
+type PrescriptionSettings struct {
+	ProviderName string
+	ProviderNPI  string
+}
+
+type prescriptionUseCase struct {
+	transactor       repository.Transactor
+	prescriptionRepo repository.PrescriptionRepository
+	drugRepo         repository.DrugRepository
+	encounterRepo    repository.EncounterRepository
+	patientRepo      repository.PatientRepository
+	doctorRepo       repository.DoctorRepository
+	allergyRepo      repository.AllergyRepository
+	medicationRepo   repository.MedicationRepository
+	settings         PrescriptionSettings
+	now              func() time.Time
+}
+
+func NewPrescriptionUseCase(
+	transactor repository.Transactor,
+	prescriptionRepo repository.PrescriptionRepository,
+	drugRepo repository.DrugRepository,
+	encounterRepo repository.EncounterRepository,
+	patientRepo repository.PatientRepository,
+	doctorRepo repository.DoctorRepository,
+	allergyRepo repository.AllergyRepository,
+	medicationRepo repository.MedicationRepository,
+	settings PrescriptionSettings,
+) PrescriptionUseCase {
+	return &prescriptionUseCase{
+		transactor:       transactor,
+		prescriptionRepo: prescriptionRepo,
+		drugRepo:         drugRepo,
+		encounterRepo:    encounterRepo,
+		patientRepo:      patientRepo,
+		doctorRepo:       doctorRepo,
+		allergyRepo:      allergyRepo,
+		medicationRepo:   medicationRepo,
+		settings:         settings,
+		now:              time.Now,
+	}
+}
+
+func (uc *prescriptionUseCase) CheckPrescription(ctx context.Context, prescription *domain.Prescription) ([]domain.PrescriptionWarning, error) {
+	drug, err := uc.prepare(ctx, prescription)
+	if err != nil {
+		return nil, err
+	}
+	return uc.safetyChecks(ctx, prescription.PatientID, drug)
+}
+
+func (uc *prescriptionUseCase) CreatePrescription(ctx context.Context, prescription *domain.Prescription, actor *domain.User) error {
+	if actor == nil || !actor.HasRole(domain.RoleDoctor) {
+		return domain.NewForbiddenError("only doctors can prescribe")
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		drug, err := uc.prepare(ctx, prescription)
+		if err != nil {
+			return err
+		}
+		if prescription.Warnings, err = uc.safetyChecks(ctx, prescription.PatientID, drug); err != nil {
+			return err
+		}
+		if serious := seriousWarnings(prescription.Warnings); len(serious) > 0 && prescription.OverrideReason == "" {
+			return domain.NewConflictError("prescription_override_required",
+				fmt.Sprintf("%s; give an override_reason to prescribe anyway (%d serious warning(s))", serious[0].Message, len(serious)))
+		}
+
+		prescription.ID = 0
+		prescription.Status = domain.PrescriptionActive
+		prescription.PrescribedByUserID = actorID(actor)
+		prescription.CancelReason, prescription.CancelledAt = "", nil
+		if err := uc.prescriptionRepo.Create(ctx, prescription); err != nil {
+			return err
+		}
+
+		encounterID := prescription.EncounterID
+		return uc.medicationRepo.Create(ctx, &domain.Medication{
+			PatientID:  prescription.PatientID,
+			Name:       prescription.DrugName,
+			RxNormCode: prescription.RxCUI,
+			Dose:       prescription.Strength,
+			Frequency:  prescription.Dosage,
+			Status:     domain.ClinicalStatusActive,
+			OnsetDate:  uc.now().Format("2006-01-02"),
+			Provenance: domain.Provenance{
+				Source:           domain.SourceClinician,
+				EncounterID:      &encounterID,
+				RecordedByUserID: prescription.PrescribedByUserID,
+			},
+			PrescriptionID: &prescription.ID,
+		})
+	})
+}
+
+// prepare fills in a prescription from its encounter and drug and
+// validates it.
+func (uc *prescriptionUseCase) prepare(ctx context.Context, prescription *domain.Prescription) (*domain.Drug, error) {
+	encounter, err := uc.encounterRepo.GetByID(ctx, prescription.EncounterID)
+	if err != nil {
+		return nil, err
+	}
+	prescription.PatientID = encounter.PatientID
+	prescription.DoctorID = encounter.DoctorID
+	prescription.RxCUI = strings.TrimSpace(prescription.RxCUI)
+	prescription.Dosage = strings.TrimSpace(prescription.Dosage)
+	prescription.QuantityUnit = strings.TrimSpace(prescription.QuantityUnit)
+	prescription.Instructions = strings.TrimSpace(prescription.Instructions)
+	prescription.OverrideReason = strings.TrimSpace(prescription.OverrideReason)
+
+	var extra []domain.FieldError
+	var drug *domain.Drug
+	if prescription.RxCUI != "" {
+		drug, err = uc.drugRepo.Get(ctx, prescription.RxCUI)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			extra = append(extra, domain.FieldError{Field: "rxcui", Message: "is not a drug of the catalog"})
+		case err != nil:
+			return nil, err
+		case !drug.Active:
+			extra = append(extra, domain.FieldError{Field: "rxcui", Message: "was withdrawn after the " + drug.Release + " release"})
+		default:
+			prescription.DrugName = drug.Name
+			prescription.Strength = drug.Strength
+			prescription.DoseForm = drug.DoseForm
+		}
+	}
+	if err := validateStruct(prescription, extra...); err != nil {
+		return nil, err
+	}
+	return drug, nil
+}
+
+// safetyChecks compares a drug with the patient's active medications and
+// allergies. Warnings are ordered most serious first.
+func (uc *prescriptionUseCase) safetyChecks(ctx context.Context, patientID uint, drug *domain.Drug) ([]domain.PrescriptionWarning, error) {
+	warnings := []domain.PrescriptionWarning{}
+
+	medications, err := uc.medicationRepo.ListByPatient(ctx, patientID, domain.ClinicalStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	takenIn := map[string][]domain.Medication{}
+	var taken []string
+	for _, medication := range medications {
+		ingredients, err := uc.medicationIngredients(ctx, medication)
+		if err != nil {
+			return nil, err
+		}
+		for _, ingredient := range ingredients {
+			if _, ok := takenIn[ingredient]; !ok {
+				taken = append(taken, ingredient)
+			}
+			takenIn[ingredient] = append(takenIn[ingredient], medication)
+		}
+	}
+
+	for _, ingredient := range drug.Ingredients {
+		for _, medication := range takenIn[ingredient] {
+			id := medication.ID
+			warnings = append(warnings, domain.PrescriptionWarning{
+				Type:         domain.WarningDuplicateTherapy,
+				Severity:     drugdata.SeverityModerate,
+				Message:      fmt.Sprintf("the patient already takes %s (%s)", ingredient, medication.Name),
+				MedicationID: &id,
+			})
+		}
+	}
+
+	interactions, err := uc.drugRepo.Interactions(ctx, drug.Ingredients, taken)
+	if err != nil {
+		return nil, err
+	}
+	for _, interaction := range interactions {
+		pairs := [][2]string{{interaction.IngredientA, interaction.IngredientB}, {interaction.IngredientB, interaction.IngredientA}}
+		for _, pair := range pairs {
+			if !contains(drug.Ingredients, pair[0]) {
+				continue
+			}
+			for _, medication := range takenIn[pair[1]] {
+				id := medication.ID
+				warnings = append(warnings, domain.PrescriptionWarning{
+					Type:         domain.WarningInteraction,
+					Severity:     interaction.Severity,
+					Message:      fmt.Sprintf("%s interacts with %s (%s): %s", pair[0], pair[1], medication.Name, interaction.Description),
+					MedicationID: &id,
+				})
+			}
+		}
+	}
+
+	allergies, err := uc.allergyRepo.ListByPatient(ctx, patientID, domain.ClinicalStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string{drugdata.Normalize(drug.Name)}, drug.Ingredients...)
+	names = append(names, drug.Classes...)
+	for _, allergy := range allergies {
+		if allergy.Category != "medication" && allergy.Category != "other" {
+			continue
+		}
+		substance := drugdata.Normalize(allergy.Substance)
+		for _, name := range names {
+			if !sameSubstance(substance, name) {
+				continue
+			}
+			severity := drugdata.SeverityMajor
+			if allergy.Severity == domain.SeveritySevere {
+				severity = drugdata.SeverityContraindicated
+			}
+			message := "the patient is allergic to " + allergy.Substance
+			if allergy.Reaction != "" {
+				message += " (" + allergy.Reaction + ")"
+			}
+			id := allergy.ID
+			warnings = append(warnings, domain.PrescriptionWarning{
+				Type:      domain.WarningAllergy,
+				Severity:  severity,
+				Message:   message,
+				AllergyID: &id,
+			})
+			break
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return drugdata.SeverityRank(warnings[i].Severity) > drugdata.SeverityRank(warnings[j].Severity)
+	})
+	return warnings, nil
+}
+
+// medicationIngredients finds what a medication on the patient's list
+// contains: from the catalog by RxNorm code or name, else its name.
+func (uc *prescriptionUseCase) medicationIngredients(ctx context.Context, medication domain.Medication) ([]string, error) {
+	if medication.RxNormCode != "" {
+		drug, err := uc.drugRepo.Get(ctx, medication.RxNormCode)
+		if err == nil {
+			return drug.Ingredients, nil
+		}
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+	}
+	drug, err := uc.drugRepo.FindByName(ctx, medication.Name)
+	if err != nil {
+		return nil, err
+	}
+	if drug != nil {
+		return drug.Ingredients, nil
+	}
+	return []string{drugdata.Normalize(medication.Name)}, nil
+}
+
+func (uc *prescriptionUseCase) GetPrescription(ctx context.Context, id uint) (*domain.Prescription, error) {
+	return uc.prescriptionRepo.GetByID(ctx, id)
+}
+
+func (uc *prescriptionUseCase) ListPatientPrescriptions(ctx context.Context, patientID uint) ([]domain.Prescription, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return uc.prescriptionRepo.ListByPatient(ctx, patientID)
+}
+
+func (uc *prescriptionUseCase) ListEncounterPrescriptions(ctx context.Context, encounterID uint) ([]domain.Prescription, error) {
+	if _, err := uc.encounterRepo.GetByID(ctx, encounterID); err != nil {
+		return nil, err
+	}
+	return uc.prescriptionRepo.ListByEncounter(ctx, encounterID)
+}
+
+func (uc *prescriptionUseCase) CancelPrescription(ctx context.Context, id uint, reason string, actor *domain.User) (*domain.Prescription, error) {
+	if actor == nil || !actor.HasRole(domain.RoleDoctor) {
+		return nil, domain.NewForbiddenError("only doctors can cancel prescriptions")
+	}
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "reason", Message: "is required"})
+	}
+
+	var prescription *domain.Prescription
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if prescription, err = uc.prescriptionRepo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if prescription.Status == domain.PrescriptionCancelled {
+			return domain.NewConflictError("prescription_cancelled", fmt.Sprintf("prescription %d is already cancelled", id))
+		}
+		now := uc.now()
+		prescription.Status = domain.PrescriptionCancelled
+		prescription.CancelReason = reason
+		prescription.CancelledAt = &now
+		if err := uc.prescriptionRepo.Update(ctx, prescription); err != nil {
+			return err
+		}
+
+		medications, err := uc.medicationRepo.ListByPatient(ctx, prescription.PatientID, domain.ClinicalStatusActive)
+		if err != nil {
+			return err
+		}
+		for _, medication := range medications {
+			if medication.PrescriptionID != nil && *medication.PrescriptionID == prescription.ID {
+				medication.Status = domain.ClinicalStatusResolved
+				medication.ResolvedOn = now.Format("2006-01-02")
+				if err := uc.medicationRepo.Update(ctx, &medication); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prescription, nil
+}
+
+func (uc *prescriptionUseCase) RenderPrescription(ctx context.Context, id uint) ([]byte, error) {
+	prescription, err := uc.prescriptionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := uc.patientRepo.GetByID(ctx, prescription.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	prescriber := fmt.Sprintf("Doctor %d", prescription.DoctorID)
+	if doctor, err := uc.doctorRepo.GetByID(ctx, prescription.DoctorID); err == nil {
+		prescriber = doctor.Name
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	return prescriptionPDF(prescription, patient, prescriber, uc.settings), nil
+}
+
+// prescriptionPDF lays out a prescription on one page.
+func prescriptionPDF(p *domain.Prescription, patient *domain.Patient, prescriber string, settings PrescriptionSettings) []byte {
+	const left, right = 72.0, pdf.PageWidth - 72
+	doc := pdf.New(fmt.Sprintf("Prescription %d", p.ID))
+	doc.Created = p.CreatedAt
+	page := doc.AddPage()
+
+	y := 80.0
+	page.Text(left, y, pdf.Bold, 16, settings.ProviderName)
+	page.TextRight(right, y, pdf.Bold, 12, fmt.Sprintf("Prescription #%d", p.ID))
+	y += 16
+	if settings.ProviderNPI != "" {
+		page.Text(left, y, pdf.Regular, 10, "NPI "+settings.ProviderNPI)
+	}
+	page.TextRight(right, y, pdf.Regular, 10, p.CreatedAt.Format("January 2, 2006"))
+	y += 12
+	page.Line(left, y, right, y, 1)
+
+	y += 24
+	page.Text(left, y, pdf.Bold, 11, "Patient")
+	y += 16
+	page.Text(left, y, pdf.Regular, 11, patient.Name)
+	for _, line := range []string{
+		labeled("Date of birth: ", patient.DateOfBirth),
+		labeled("MRN: ", patient.MRN),
+		patient.Address.Line1,
+		patient.Address.Line2,
+		strings.TrimSpace(strings.Join([]string{patient.Address.City, patient.Address.Region, patient.Address.PostalCode}, " ")),
+	} {
+		if line != "" {
+			y += 14
+			page.Text(left, y, pdf.Regular, 10, line)
+		}
+	}
+
+	y += 40
+	page.Text(left, y, pdf.Bold, 28, "Rx")
+	for _, line := range pdf.Wrap(pdf.Bold, 13, right-left-50, drugLabel(p)) {
+		page.Text(left+50, y, pdf.Bold, 13, line)
+		y += 16
+	}
+	y += 8
+	quantity := fmt.Sprintf("%d", p.Quantity)
+	if p.QuantityUnit != "" {
+		quantity += " " + p.QuantityUnit
+	}
+	for _, field := range [][2]string{
+		{"Sig", p.Dosage},
+		{"Quantity", quantity},
+		{"Refills", fmt.Sprintf("%d", p.Refills)},
+		{"Instructions", p.Instructions},
+	} {
+		if field[1] == "" {
+			continue
+		}
+		page.Text(left+50, y, pdf.Bold, 11, field[0]+":")
+		for _, line := range pdf.Wrap(pdf.Regular, 11, right-left-140, field[1]) {
+			page.Text(left+140, y, pdf.Regular, 11, line)
+			y += 15
+		}
+		y += 4
+	}
+
+	if p.Status == domain.PrescriptionCancelled && p.CancelledAt != nil {
+		y += 16
+		page.Text(left, y, pdf.Bold, 14, "CANCELLED "+p.CancelledAt.Format("January 2, 2006"))
+		for _, line := range pdf.Wrap(pdf.Regular, 10, right-left, p.CancelReason) {
+			y += 14
+			page.Text(left, y, pdf.Regular, 10, line)
+		}
+	}
+
+	y = pdf.PageHeight - 120
+	page.Line(left, y, left+220, y, 0.5)
+	page.Text(left, y+14, pdf.Regular, 10, prescriber)
+	page.Text(left, y+28, pdf.Regular, 9, "Prescriber signature")
+	return doc.Bytes()
+}
+
+// drugLabel names the prescribed drug. Catalog names usually include the
+// strength and form already ("Amoxicillin 500 MG Oral Capsule").
+func drugLabel(p *domain.Prescription) string {
+	label := p.DrugName
+	for _, detail := range []string{p.Strength, p.DoseForm} {
+		if detail != "" && !strings.Contains(strings.ToLower(label), strings.ToLower(detail)) {
+			label += " " + detail
+		}
+	}
+	return label
+}
+
+// labeled prefixes a non-empty value with its label.
+func labeled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + value
+}
+
+// seriousWarnings returns the warnings that need an override: major or worse.
+func seriousWarnings(warnings []domain.PrescriptionWarning) []domain.PrescriptionWarning {
+	var serious []domain.PrescriptionWarning
+	for _, warning := range warnings {
+		if drugdata.SeverityRank(warning.Severity) >= drugdata.SeverityRank(drugdata.SeverityMajor) {
+			serious = append(serious, warning)
+		}
+	}
+	return serious
+}
+
+// sameSubstance compares normalized names, taking a plural class name
+// ("penicillins") to match its singular ("penicillin").
+func sameSubstance(a, b string) bool {
+	return a == b || a+"s" == b || a == b+"s"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
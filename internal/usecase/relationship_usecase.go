@@ -0,0 +1,162 @@
+// internal/usecase/relationship_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"errors"
+	"fmt"
+)
+
+type RelationshipUseCase interface {
+	// CreateRelationship makes rel.RelatedPatientID the guardian or
+	// caregiver of rel.PatientID.
+	CreateRelationship(ctx context.Context, rel *domain.PatientRelationship) error
+	// ListRelationships returns everyone related to the patient, each with
+	// the role they play for the patient.
+	ListRelationships(ctx context.Context, patientID uint) ([]domain.RelatedPatient, error)
+	// UpdateRelationship changes the type and permission flags of one of
+	// the patient's relationships.
+	UpdateRelationship(ctx context.Context, patientID uint, rel *domain.PatientRelationship) error
+	DeleteRelationship(ctx context.Context, patientID, id uint) error
+}
+
+type relationshipUseCase struct {
+	transactor       repository.Transactor
+	relationshipRepo repository.RelationshipRepository
+	patientRepo      repository.PatientRepository
+}
+
+func NewRelationshipUseCase(
+	transactor repository.Transactor,
+	relationshipRepo repository.RelationshipRepository,
+	patientRepo repository.PatientRepository,
+) RelationshipUseCase {
+	return &relationshipUseCase{
+		transactor:       transactor,
+		relationshipRepo: relationshipRepo,
+		patientRepo:      patientRepo,
+	}
+}
+
+func (uc *relationshipUseCase) CreateRelationship(ctx context.Context, rel *domain.PatientRelationship) error {
+	var extra []domain.FieldError
+	if rel.PatientID != 0 && rel.PatientID == rel.RelatedPatientID {
+		extra = append(extra, domain.FieldError{Field: "related_patient_id", Message: "must be a different patient"})
+	}
+	if err := validateStruct(rel, extra...); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var fields []domain.FieldError
+		sides := []struct {
+			field string
+			id    uint
+		}{{"patient_id", rel.PatientID}, {"related_patient_id", rel.RelatedPatientID}}
+		for _, side := range sides {
+			if _, err := uc.patientRepo.GetByID(ctx, side.id); errors.Is(err, domain.ErrNotFound) {
+				fields = append(fields, domain.FieldError{Field: side.field, Message: "patient does not exist"})
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(fields) > 0 {
+			return domain.NewValidationError(fields...)
+		}
+
+		// One link per pair: a guardian can't also be their dependent's dependent.
+		if existing, err := uc.relationshipRepo.Between(ctx, rel.PatientID, rel.RelatedPatientID); err == nil {
+			return domain.NewConflictError("relationship_exists",
+				fmt.Sprintf("patients %d and %d are already related (relationship %d)", rel.PatientID, rel.RelatedPatientID, existing.ID))
+		} else if !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+		return uc.relationshipRepo.Create(ctx, rel)
+	})
+}
+
+func (uc *relationshipUseCase) ListRelationships(ctx context.Context, patientID uint) ([]domain.RelatedPatient, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	rels, err := uc.relationshipRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rels))
+	for i, rel := range rels {
+		ids[i] = otherPatient(rel, patientID)
+	}
+	patients, err := uc.patientRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]domain.Patient, len(patients))
+	for _, p := range patients {
+		byID[p.ID] = p
+	}
+
+	related := make([]domain.RelatedPatient, 0, len(rels))
+	for _, rel := range rels {
+		other, ok := byID[otherPatient(rel, patientID)]
+		if !ok {
+			// The other side is archived.
+			continue
+		}
+		related = append(related, domain.RelatedPatient{Relationship: rel, Role: relationshipRole(rel, patientID), Patient: other})
+	}
+	return related, nil
+}
+
+func (uc *relationshipUseCase) UpdateRelationship(ctx context.Context, patientID uint, rel *domain.PatientRelationship) error {
+	existing, err := uc.getForPatient(ctx, patientID, rel.ID)
+	if err != nil {
+		return err
+	}
+	rel.PatientID = existing.PatientID
+	rel.RelatedPatientID = existing.RelatedPatientID
+	rel.CreatedAt = existing.CreatedAt
+	if err := validateStruct(rel); err != nil {
+		return err
+	}
+	return uc.relationshipRepo.Update(ctx, rel)
+}
+
+func (uc *relationshipUseCase) DeleteRelationship(ctx context.Context, patientID, id uint) error {
+	if _, err := uc.getForPatient(ctx, patientID, id); err != nil {
+		return err
+	}
+	return uc.relationshipRepo.Delete(ctx, id)
+}
+
+// getForPatient loads a relationship, treating one that doesn't involve
+// the patient as missing.
+func (uc *relationshipUseCase) getForPatient(ctx context.Context, patientID, id uint) (*domain.PatientRelationship, error) {
+	rel, err := uc.relationshipRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rel.PatientID != patientID && rel.RelatedPatientID != patientID {
+		return nil, domain.NewNotFoundError("relationship", id)
+	}
+	return rel, nil
+}
+
+// otherPatient is the patient on the far side of rel from patientID.
+func otherPatient(rel domain.PatientRelationship, patientID uint) uint {
+	if rel.PatientID == patientID {
+		return rel.RelatedPatientID
+	}
+	return rel.PatientID
+}
+
+// relationshipRole is what the other patient in rel is to patientID.
+func relationshipRole(rel domain.PatientRelationship, patientID uint) string {
+	if rel.PatientID == patientID {
+		return rel.Type
+	}
+	return domain.RelationshipDependent
+}
@@ -0,0 +1,52 @@
+// internal/usecase/retention_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/repository"
+	"fmt"
+	"time"
+)
+
+// RetentionUseCase permanently removes archived records once they are older
+// than the retention period. Patients and appointments with clinical or
+// financial records are kept with them.
+type RetentionUseCase interface {
+	PurgeArchived(ctx context.Context) (patients, appointments int64, err error)
+}
+
+type retentionUseCase struct {
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	retention       time.Duration
+	now             func() time.Time
+}
+
+func NewRetentionUseCase(
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	retention time.Duration,
+) RetentionUseCase {
+	return &retentionUseCase{
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		retention:       retention,
+		now:             time.Now,
+	}
+}
+
+func (uc *retentionUseCase) PurgeArchived(ctx context.Context) (int64, int64, error) {
+	cutoff := uc.now().Add(-uc.retention)
+
+	// Purging a patient also removes all of their appointments; patients
+	// with records that must be kept are skipped.
+	patients, err := uc.patientRepo.Purge(ctx, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to purge patients: %w", err)
+	}
+	appointments, err := uc.appointmentRepo.Purge(ctx, cutoff)
+	if err != nil {
+		return patients, 0, fmt.Errorf("failed to purge appointments: %w", err)
+	}
+	return patients, appointments, nil
+}
@@ -0,0 +1,73 @@
+// internal/usecase/tenant_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type TenantUseCase interface {
+	CreateTenant(ctx context.Context, tenant *domain.Tenant) error
+	GetTenantBySlug(ctx context.Context, slug string) (*domain.Tenant, error)
+	ListTenants(ctx context.Context) ([]domain.Tenant, error)
+}
+
+type tenantUseCase struct {
+	tenantRepo repository.TenantRepository
+}
+
+func NewTenantUseCase(tenantRepo repository.TenantRepository) TenantUseCase {
+	return &tenantUseCase{tenantRepo: tenantRepo}
+}
+
+var tenantSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func (uc *tenantUseCase) CreateTenant(ctx context.Context, tenant *domain.Tenant) error {
+	tenant.Slug = strings.ToLower(strings.TrimSpace(tenant.Slug))
+	tenant.Name = strings.TrimSpace(tenant.Name)
+	var extra []domain.FieldError
+	if tenant.Slug != "" && !tenantSlug.MatchString(tenant.Slug) {
+		extra = append(extra, domain.FieldError{Field: "slug", Message: "must be lowercase letters, digits and dashes"})
+	}
+	if err := validateStruct(tenant, extra...); err != nil {
+		return err
+	}
+	return uc.tenantRepo.Create(ctx, tenant)
+}
+
+func (uc *tenantUseCase) GetTenantBySlug(ctx context.Context, slug string) (*domain.Tenant, error) {
+	return uc.tenantRepo.GetBySlug(ctx, strings.ToLower(strings.TrimSpace(slug)))
+}
+
+func (uc *tenantUseCase) ListTenants(ctx context.Context) ([]domain.Tenant, error) {
+	return uc.tenantRepo.List(ctx)
+}
+
+// WithTenant returns a context acting for a tenant. Patients created with
+// it belong to the tenant, and only the tenant's patients and their
+// records, imports and exports can be read with it.
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return repository.WithTenant(ctx, tenantID)
+}
+
+// tenantOf returns the tenant a context acts for, the default tenant when
+// none was set.
+func tenantOf(ctx context.Context) uint {
+	if id, ok := repository.ContextTenant(ctx); ok {
+		return id
+	}
+	return domain.DefaultTenantID
+}
+
+// tenantScope names a tenant's MRN and invoice number counters. The
+// default tenant keeps the "default" counters from before tenants existed.
+func tenantScope(tenantID uint) string {
+	if tenantID == domain.DefaultTenantID {
+		return "default"
+	}
+	return fmt.Sprintf("tenant:%d", tenantID)
+}
@@ -0,0 +1,87 @@
+// internal/usecase/user_usecase.go
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+type UserUseCase interface {
+	// CreateUser stores a new user of the context's tenant and returns
+	// their API key. The key is not stored and cannot be retrieved again.
+	CreateUser(ctx context.Context, user *domain.User) (string, error)
+	ListUsers(ctx context.Context) ([]domain.User, error)
+	// Authenticate resolves an API key to a user.
+	Authenticate(ctx context.Context, apiKey string) (*domain.User, error)
+}
+
+type userUseCase struct {
+	userRepo repository.UserRepository
+	// bootstrapKey is the ADMIN_API_KEY from configuration, which acts as an
+	// admin before any users exist.
+	bootstrapKey string
+}
+
+// ErrInvalidAPIKey is returned by Authenticate for unknown keys.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+func NewUserUseCase(userRepo repository.UserRepository, bootstrapKey string) UserUseCase {
+	return &userUseCase{userRepo: userRepo, bootstrapKey: bootstrapKey}
+}
+
+func (uc *userUseCase) CreateUser(ctx context.Context, user *domain.User) (string, error) {
+	user.Name = strings.TrimSpace(user.Name)
+	user.Email = strings.TrimSpace(user.Email)
+	user.TenantID = tenantOf(ctx)
+	var extra []domain.FieldError
+	switch {
+	case user.Role == domain.RolePatient && user.PatientID == nil:
+		extra = append(extra, domain.FieldError{Field: "patient_id", Message: "is required for patient users"})
+	case user.Role != domain.RolePatient && user.PatientID != nil:
+		extra = append(extra, domain.FieldError{Field: "patient_id", Message: "is only allowed for patient users"})
+	}
+	if err := validateStruct(user, extra...); err != nil {
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	apiKey := "dk_" + base64.RawURLEncoding.EncodeToString(raw)
+	user.APIKeyHash = hashAPIKey(apiKey)
+
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		return "", err
+	}
+	return apiKey, nil
+}
+
+func (uc *userUseCase) ListUsers(ctx context.Context) ([]domain.User, error) {
+	return uc.userRepo.List(ctx)
+}
+
+func (uc *userUseCase) Authenticate(ctx context.Context, apiKey string) (*domain.User, error) {
+	if uc.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(uc.bootstrapKey)) == 1 {
+		return &domain.User{Name: "bootstrap admin", Role: domain.RoleAdmin, TenantID: domain.DefaultTenantID}, nil
+	}
+
+	user, err := uc.userRepo.GetByAPIKeyHash(ctx, hashAPIKey(apiKey))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	return user, err
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,106 @@
+// internal/usecase/validation.go
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"doctors/internal/domain"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the `validate` struct tags on domain types. Validation lives
+// in the use-case layer so every entry point (HTTP, Kafka) applies the same rules.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report JSON field names so errors match what clients sent.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// validateStruct returns a domain validation error listing every invalid field.
+func validateStruct(s interface{}, extra ...domain.FieldError) error {
+	fields := append([]domain.FieldError{}, extra...)
+
+	var verrs validator.ValidationErrors
+	if err := validate.Struct(s); errors.As(err, &verrs) {
+		for _, fe := range verrs {
+			fields = append(fields, domain.FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+	return nil
+}
+
+// fieldPath is the JSON path of a field without the struct name, e.g.
+// "address.country" or "emergency_contacts[0].phone".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "e164":
+		return "must be a phone number in E.164 format, e.g. +14155552671"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters"
+		}
+		if fe.Kind() == reflect.Slice {
+			return "must have at most " + fe.Param() + " items"
+		}
+		return "must be at most " + fe.Param()
+	case "len":
+		return "must be exactly " + fe.Param() + " characters"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "datetime":
+		if fe.Param() == "2006-01-02" {
+			return "must be a date in YYYY-MM-DD format"
+		}
+		return "must be a date in " + fe.Param() + " format"
+	case "bcp47_language_tag":
+		return "must be a language tag such as en or es-MX"
+	case "iso3166_1_alpha2":
+		return "must be a two-letter country code"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
+
+// normalizePhone strips common formatting characters ("+1 (415) 555-2671").
+func normalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(phone))
+}
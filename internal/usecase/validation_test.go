@@ -0,0 +1,129 @@
+// internal/usecase/validation_test.go
+package usecase
+
+import (
+	"doctors/internal/domain"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateStruct(t *testing.T) {
+	valid := func() domain.Patient {
+		return domain.Patient{Name: "Jane Doe", Email: "jane@example.com", Phone: "+14155552671"}
+	}
+	tests := []struct {
+		name   string
+		change func(p *domain.Patient)
+		extra  []domain.FieldError
+		want   []domain.FieldError
+	}{
+		{name: "valid", change: func(p *domain.Patient) {}},
+		{
+			name:   "JSON names of top-level fields",
+			change: func(p *domain.Patient) { p.Name, p.Email = "", "not-an-email" },
+			want: []domain.FieldError{
+				{Field: "name", Message: "is required"},
+				{Field: "email", Message: "must be a valid email address"},
+			},
+		},
+		{
+			name:   "nested fields",
+			change: func(p *domain.Patient) { p.Address.Country = "USA" },
+			want:   []domain.FieldError{{Field: "address.country", Message: "must be a two-letter country code"}},
+		},
+		{
+			name: "list items",
+			change: func(p *domain.Patient) {
+				p.EmergencyContacts = []domain.EmergencyContact{
+					{Name: "John Doe", Phone: "+14155552672"},
+					{Name: "J", Phone: "555-1234"},
+				}
+			},
+			want: []domain.FieldError{
+				{Field: "emergency_contacts[1].name", Message: "must be at least 2 characters"},
+				{Field: "emergency_contacts[1].phone", Message: "must be a phone number in E.164 format, e.g. +14155552671"},
+			},
+		},
+		{
+			name: "messages per tag",
+			change: func(p *domain.Patient) {
+				p.DateOfBirth, p.Sex, p.PreferredLanguage = "02/04/1980", "other", "not a tag!"
+			},
+			want: []domain.FieldError{
+				{Field: "date_of_birth", Message: "must be a date in YYYY-MM-DD format"},
+				{Field: "sex", Message: "must be one of: female male intersex unknown"},
+				{Field: "preferred_language", Message: "must be a language tag such as en or es-MX"},
+			},
+		},
+		{
+			name:   "too many items",
+			change: func(p *domain.Patient) { p.EmergencyContacts = make([]domain.EmergencyContact, 6) },
+			want:   []domain.FieldError{{Field: "emergency_contacts", Message: "must have at most 5 items"}},
+		},
+		{
+			name:   "extra errors come first",
+			change: func(p *domain.Patient) { p.Name = "" },
+			extra:  []domain.FieldError{{Field: "date_of_birth", Message: "must not be in the future"}},
+			want: []domain.FieldError{
+				{Field: "date_of_birth", Message: "must not be in the future"},
+				{Field: "name", Message: "is required"},
+			},
+		},
+		{
+			name:   "extra errors alone",
+			change: func(p *domain.Patient) {},
+			extra:  []domain.FieldError{{Field: "status", Message: "an appointment can't be completed before it starts"}},
+			want:   []domain.FieldError{{Field: "status", Message: "an appointment can't be completed before it starts"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patient := valid()
+			tt.change(&patient)
+			err := validateStruct(&patient, tt.extra...)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("validateStruct() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("validateStruct() error = %v, want a validation error", err)
+			}
+			if derr, _ := domain.AsError(err); !reflect.DeepEqual(derr.Fields, tt.want) {
+				t.Errorf("fields = %+v\nwant %+v", derr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePatientNormalizes(t *testing.T) {
+	uc := &patientUseCase{now: func() time.Time { return billingNow }}
+	patient := &domain.Patient{
+		Name: "  Jane Doe ", Phone: "+1 (415) 555-2671", Address: domain.Address{Country: " us "},
+		EmergencyContacts: []domain.EmergencyContact{{Name: " John Doe ", Phone: "+1 415.555.2672"}},
+	}
+	if err := uc.validatePatient(patient); err != nil {
+		t.Fatalf("validatePatient() error = %v", err)
+	}
+	if patient.Name != "Jane Doe" || patient.Phone != "+14155552671" || patient.Address.Country != "US" ||
+		patient.PreferredContactChannel != domain.ContactChannelEmail {
+		t.Errorf("patient = %+v, want trimmed fields and email contact", patient)
+	}
+	if contact := patient.EmergencyContacts[0]; contact.Name != "John Doe" || contact.Phone != "+14155552672" {
+		t.Errorf("emergency contact = %+v, want trimmed fields", contact)
+	}
+
+	future := &domain.Patient{Name: "Jane Doe", DateOfBirth: "2031-01-01", PreferredContactChannel: domain.ContactChannelSMS}
+	err := uc.validatePatient(future)
+	want := []domain.FieldError{
+		{Field: "date_of_birth", Message: "must not be in the future"},
+		{Field: "preferred_contact_channel", Message: "requires a phone number"},
+	}
+	if derr, ok := domain.AsError(err); !ok || !reflect.DeepEqual(derr.Fields, want) {
+		t.Errorf("validatePatient() error = %v, want fields %+v", err, want)
+	}
+}
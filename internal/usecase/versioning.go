@@ -0,0 +1,56 @@
+// internal/usecase/versioning.go
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+
+	"doctors/internal/domain"
+	"doctors/pkg/mergepatch"
+)
+
+// checkVersion enforces an If-Match precondition. A nil ifMatch means the
+// client did not send one and the write is unconditional.
+func checkVersion(resource string, current uint, ifMatch *uint) error {
+	if ifMatch != nil && *ifMatch != current {
+		return domain.NewPreconditionFailedError(resource)
+	}
+	return nil
+}
+
+// versionConflict reports a concurrent write detected by the repository as
+// a failed precondition when the client asked for one.
+func versionConflict(err error, resource string, ifMatch *uint) error {
+	if ifMatch != nil && errors.Is(err, domain.ErrConflict) {
+		return domain.NewPreconditionFailedError(resource)
+	}
+	return err
+}
+
+// applyMergePatch applies a JSON Merge Patch to original's JSON form and
+// decodes the result into patched, which should point to a zero value.
+func applyMergePatch(original interface{}, patch []byte, patched interface{}) error {
+	doc, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+
+	merged, err := mergepatch.Apply(doc, patch)
+	if errors.Is(err, mergepatch.ErrNotObject) {
+		return domain.NewBadRequestError("invalid_patch", err.Error())
+	}
+	if err != nil {
+		return domain.NewBadRequestError("malformed_request", "Patch is not valid JSON: "+err.Error())
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(merged, patched); errors.As(err, &typeErr) {
+		return domain.NewValidationError(domain.FieldError{
+			Field:   typeErr.Field,
+			Message: "must be of type " + typeErr.Type.String(),
+		})
+	} else if err != nil {
+		return domain.NewBadRequestError("invalid_patch", err.Error())
+	}
+	return nil
+}
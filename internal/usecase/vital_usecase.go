@@ -0,0 +1,254 @@
+// internal/usecase/vital_usecase.go
+package usecase
+
+import (
+	"context"
+	"doctors/internal/domain"
+	"doctors/internal/repository"
+	"doctors/pkg/units"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+type VitalUseCase interface {
+	// RecordVitals stores measurements taken together, optionally during
+	// an appointment. Each vital's EnteredValue and EnteredUnit are
+	// converted to the unit of its type and flagged against the reference
+	// ranges; an empty unit means the type's unit. A zero measuredAt means now.
+	RecordVitals(ctx context.Context, patientID uint, appointmentID *uint, measuredAt time.Time, vitals []domain.VitalSign, actor *domain.User) error
+	ListVitals(ctx context.Context, patientID uint, appointmentID *uint, limit int) ([]domain.VitalSign, error)
+	// VitalTrends returns one series per requested type (every type with
+	// measurements when none are requested), in metric or imperial units.
+	VitalTrends(ctx context.Context, patientID uint, query domain.VitalQuery, unitSystem string) ([]domain.VitalTrend, error)
+	// DeleteVital marks a measurement as entered in error.
+	DeleteVital(ctx context.Context, patientID, id uint) error
+}
+
+// Unit systems of VitalTrends.
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// maxVitalsClockSkew is how far in the future a measurement time may be,
+// to allow for devices whose clocks run slightly ahead.
+const maxVitalsClockSkew = 5 * time.Minute
+
+type vitalUseCase struct {
+	vitalRepo       repository.VitalRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	now             func() time.Time
+}
+
+func NewVitalUseCase(
+	vitalRepo repository.VitalRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+) VitalUseCase {
+	return &vitalUseCase{
+		vitalRepo:       vitalRepo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		now:             time.Now,
+	}
+}
+
+func (uc *vitalUseCase) RecordVitals(ctx context.Context, patientID uint, appointmentID *uint, measuredAt time.Time, vitals []domain.VitalSign, actor *domain.User) error {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return err
+	}
+
+	now := uc.now()
+	var fields []domain.FieldError
+	if len(vitals) == 0 {
+		fields = append(fields, domain.FieldError{Field: "measurements", Message: "is required"})
+	}
+	if measuredAt.IsZero() {
+		measuredAt = now
+	} else if measuredAt.After(now.Add(maxVitalsClockSkew)) {
+		fields = append(fields, domain.FieldError{Field: "measured_at", Message: "must not be in the future"})
+	}
+	if appointmentID != nil {
+		appointment, err := uc.appointmentRepo.GetByID(ctx, *appointmentID)
+		if errors.Is(err, domain.ErrNotFound) || (err == nil && appointment.PatientID != patientID) {
+			fields = append(fields, domain.FieldError{Field: "appointment_id", Message: "is not an appointment of this patient"})
+		} else if err != nil {
+			return err
+		}
+	}
+
+	for i := range vitals {
+		vital := &vitals[i]
+		vital.ID = 0
+		vital.PatientID = patientID
+		vital.AppointmentID = appointmentID
+		vital.MeasuredAt = measuredAt
+		vital.RecordedByUserID = actorID(actor)
+
+		path := fmt.Sprintf("measurements[%d].", i)
+		def, ok := domain.LookupVital(vital.Type)
+		if !ok {
+			fields = append(fields, domain.FieldError{Field: path + "type", Message: "must be one of: " + vitalTypes()})
+			continue
+		}
+		if fe := measure(vital, def); fe != nil {
+			fe.Field = path + fe.Field
+			fields = append(fields, *fe)
+		}
+	}
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	return uc.vitalRepo.CreateBatch(ctx, vitals)
+}
+
+// measure converts an entered value to the definition's unit and flags it.
+func measure(vital *domain.VitalSign, def domain.VitalDefinition) *domain.FieldError {
+	if vital.EnteredUnit == "" {
+		vital.EnteredUnit = def.Unit
+	}
+	unit, err := units.Normalize(vital.EnteredUnit)
+	if err != nil || (unit != def.Unit && unit != def.ImperialUnit) {
+		accepted := def.Unit
+		if def.ImperialUnit != "" {
+			accepted += " " + def.ImperialUnit
+		}
+		return &domain.FieldError{Field: "unit", Message: "must be one of: " + accepted}
+	}
+	value, err := units.Convert(vital.EnteredValue, unit, def.Unit)
+	if err != nil {
+		return &domain.FieldError{Field: "unit", Message: err.Error()}
+	}
+	value = roundVital(value)
+	if value < def.Min || value > def.Max {
+		return &domain.FieldError{
+			Field:   "value",
+			Message: fmt.Sprintf("must be between %g and %g %s", def.Min, def.Max, def.Unit),
+		}
+	}
+
+	vital.EnteredUnit = unit
+	vital.LOINC = def.LOINC
+	vital.Value = value
+	vital.Unit = def.Unit
+	vital.Flag = def.Flag(value)
+	return nil
+}
+
+func (uc *vitalUseCase) ListVitals(ctx context.Context, patientID uint, appointmentID *uint, limit int) ([]domain.VitalSign, error) {
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return uc.vitalRepo.ListByPatient(ctx, patientID, appointmentID, limit)
+}
+
+func (uc *vitalUseCase) VitalTrends(ctx context.Context, patientID uint, query domain.VitalQuery, unitSystem string) ([]domain.VitalTrend, error) {
+	var fields []domain.FieldError
+	for _, vitalType := range query.Types {
+		if _, ok := domain.LookupVital(vitalType); !ok {
+			fields = append(fields, domain.FieldError{Field: "types", Message: "must be one of: " + vitalTypes()})
+			break
+		}
+	}
+	if unitSystem == "" {
+		unitSystem = UnitsMetric
+	}
+	if unitSystem != UnitsMetric && unitSystem != UnitsImperial {
+		fields = append(fields, domain.FieldError{Field: "units", Message: "must be one of: metric imperial"})
+	}
+	if len(fields) > 0 {
+		return nil, domain.NewValidationError(fields...)
+	}
+
+	if _, err := uc.patientRepo.GetByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	vitals, err := uc.vitalRepo.Series(ctx, patientID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := map[string][]domain.VitalSign{}
+	for _, vital := range vitals {
+		byType[vital.Type] = append(byType[vital.Type], vital)
+	}
+	requested := map[string]bool{}
+	for _, vitalType := range query.Types {
+		requested[vitalType] = true
+	}
+
+	trends := []domain.VitalTrend{}
+	for _, def := range domain.VitalDefinitions {
+		series := byType[def.Type]
+		if len(series) == 0 && !requested[def.Type] {
+			continue
+		}
+		trends = append(trends, vitalTrend(def, series, unitSystem))
+	}
+	return trends, nil
+}
+
+// vitalTrend builds the chart series of one type from its measurements, oldest first.
+func vitalTrend(def domain.VitalDefinition, series []domain.VitalSign, unitSystem string) domain.VitalTrend {
+	unit := def.Unit
+	if unitSystem == UnitsImperial && def.ImperialUnit != "" {
+		unit = def.ImperialUnit
+	}
+	trend := domain.VitalTrend{Type: def.Type, LOINC: def.LOINC, Display: def.Display, Unit: unit, Points: []domain.VitalPoint{}}
+
+	for _, vital := range series {
+		value, err := units.Convert(vital.Value, vital.Unit, unit)
+		if err != nil {
+			// Stored values are always in the definition's unit.
+			value = vital.Value
+		}
+		value = roundVital(value)
+		trend.Points = append(trend.Points, domain.VitalPoint{
+			VitalID:       vital.ID,
+			MeasuredAt:    vital.MeasuredAt,
+			Value:         value,
+			Flag:          vital.Flag,
+			AppointmentID: vital.AppointmentID,
+		})
+		if trend.Min == nil || value < *trend.Min {
+			trend.Min = floatPtr(value)
+		}
+		if trend.Max == nil || value > *trend.Max {
+			trend.Max = floatPtr(value)
+		}
+	}
+
+	if n := len(trend.Points); n > 0 {
+		latest := trend.Points[n-1]
+		trend.Latest = &latest
+		trend.Change = floatPtr(roundVital(latest.Value - trend.Points[0].Value))
+	}
+	return trend
+}
+
+func (uc *vitalUseCase) DeleteVital(ctx context.Context, patientID, id uint) error {
+	return uc.vitalRepo.Delete(ctx, patientID, id)
+}
+
+// roundVital keeps two decimals, enough for any vital sign and free of
+// conversion noise such as 69.85322954.
+func roundVital(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func vitalTypes() string {
+	names := make([]string, len(domain.VitalDefinitions))
+	for i, def := range domain.VitalDefinitions {
+		names[i] = def.Type
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,20 @@
+// Package clearinghouse sends X12 transactions to payers through a
+// clearinghouse. Real clearinghouses differ in transport (HTTPS, SFTP),
+// so each is a Client implementation; FileClient stands in for one during
+// development.
+package clearinghouse
+
+import (
+	"context"
+	"errors"
+)
+
+// Client exchanges X12 interchanges with a clearinghouse.
+type Client interface {
+	// CheckEligibility sends a 270 inquiry and returns the payer's 271.
+	CheckEligibility(ctx context.Context, request []byte) ([]byte, error)
+}
+
+// ErrUnavailable is returned when the clearinghouse can't be reached or
+// doesn't answer in time; the request may be retried.
+var ErrUnavailable = errors.New("clearinghouse unavailable")
@@ -0,0 +1,87 @@
+// pkg/clearinghouse/file_client.go
+package clearinghouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"doctors/pkg/x12"
+)
+
+// FileClient is a local stand-in for a clearinghouse. Each request is
+// written to <dir>/outbox. The response is the canned 271 in
+// <dir>/responses/<member ID>.271 when one exists, which lets developers
+// script rejections and benefit details per member; otherwise it is a
+// 271 reporting active coverage. Every response is also written to
+// <dir>/inbox.
+type FileClient struct {
+	dir string
+	now func() time.Time
+}
+
+func NewFileClient(dir string) (*FileClient, error) {
+	for _, sub := range []string{"outbox", "inbox", "responses"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("clearinghouse directory: %w", err)
+		}
+	}
+	return &FileClient{dir: dir, now: time.Now}, nil
+}
+
+// unsafeFileChars are replaced when a member ID is used as a file name.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+func (c *FileClient) CheckEligibility(ctx context.Context, request []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req, err := x12.Parse270(request)
+	if err != nil {
+		return nil, fmt.Errorf("clearinghouse rejected the request: %w", err)
+	}
+
+	name := fmt.Sprintf("%09d", req.ControlNumber)
+	if err := os.WriteFile(filepath.Join(c.dir, "outbox", name+".270"), request, 0o640); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	memberFile := unsafeFileChars.ReplaceAllString(req.Subscriber.MemberID, "_") + ".271"
+	response, err := os.ReadFile(filepath.Join(c.dir, "responses", memberFile))
+	if errors.Is(err, os.ErrNotExist) {
+		response = x12.Build271(c.activeCoverage(req))
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(c.dir, "inbox", name+".271"), response, 0o640); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return response, nil
+}
+
+// activeCoverage answers a 270 the way a payer with the member on file would.
+func (c *FileClient) activeCoverage(req *x12.EligibilityRequest) x12.EligibilityResponse {
+	now := c.now()
+	return x12.EligibilityResponse{
+		Envelope: x12.Envelope{
+			SenderID:      req.ReceiverID,
+			ReceiverID:    req.SenderID,
+			ControlNumber: req.ControlNumber,
+			Time:          now,
+		},
+		TraceNumber: req.TraceNumber,
+		PayerName:   req.PayerName,
+		Subscriber:  req.Subscriber,
+		Dependent:   req.Dependent,
+		PlanBegin:   fmt.Sprintf("%d-01-01", now.Year()),
+		PlanEnd:     fmt.Sprintf("%d-12-31", now.Year()),
+		Benefits: []x12.Benefit{
+			{Code: x12.BenefitActive, CoverageLevel: "IND", ServiceType: x12.ServiceTypeHealthPlan, PlanDescription: "Test plan"},
+		},
+	}
+}
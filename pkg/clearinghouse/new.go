@@ -0,0 +1,15 @@
+// pkg/clearinghouse/new.go
+package clearinghouse
+
+import "fmt"
+
+// New returns the client named by kind. Only the "file" stand-in is
+// built in; dir is where it keeps its requests and responses.
+func New(kind, dir string) (Client, error) {
+	switch kind {
+	case "file", "":
+		return NewFileClient(dir)
+	default:
+		return nil, fmt.Errorf("unknown clearinghouse client %q", kind)
+	}
+}
@@ -0,0 +1,222 @@
+// Package codeset reads the flat files in which medical code sets are
+// published: the CMS ICD-10-CM code and order files, the CMS HCPCS annual
+// file, and tab-separated files such as the AMA CPT LONGULT.txt.
+package codeset
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format names a flat file layout.
+type Format string
+
+const (
+	// ICD10CM is either icd10cm_codes_YYYY.txt (code, spaces,
+	// description) or icd10cm_order_YYYY.txt (fixed width, with header
+	// rows that are not billable); the layout is detected per line.
+	ICD10CM Format = "icd10cm"
+	// HCPCS is the fixed width HCPC<YYYY>_ANWEB file. Long descriptions
+	// continue over several records; modifier records are skipped.
+	HCPCS Format = "hcpcs"
+	// Tabular is one code per line: code, tab, long description and
+	// optionally tab, short description.
+	Tabular Format = "tsv"
+)
+
+// Formats lists the supported layouts.
+var Formats = []Format{ICD10CM, HCPCS, Tabular}
+
+// Entry is one code read from a file.
+type Entry struct {
+	Code             string
+	Description      string
+	ShortDescription string
+	// Billable is false for ICD-10-CM category headers, which can't be
+	// used on a claim.
+	Billable bool
+}
+
+// ErrUnknownFormat is returned by Read for a format it doesn't know.
+var ErrUnknownFormat = errors.New("unknown code set format")
+
+// Read parses r in the given format and calls fn for every entry, in file
+// order. It stops at the first error from fn.
+func Read(r io.Reader, format Format, fn func(Entry) error) error {
+	var parse func(line string) (Entry, bool, error)
+	switch format {
+	case ICD10CM:
+		parse = parseICD10CM
+	case Tabular:
+		parse = parseTabular
+	case HCPCS:
+		return readHCPCS(r, fn)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+
+	return scanLines(r, func(n int, line string) error {
+		entry, ok, err := parse(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if !ok {
+			return nil
+		}
+		return fn(entry)
+	})
+}
+
+// FormatICD10 writes an ICD-10 code with its dot after the category, the
+// way clinicians read it: "E119" becomes "E11.9".
+func FormatICD10(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), ".", ""))
+	if len(code) <= 3 {
+		return code
+	}
+	return code[:3] + "." + code[3:]
+}
+
+// scanLines calls fn with every non-blank line and its 1-based number.
+func scanLines(r io.Reader, fn func(n int, line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r\n")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // byte order mark
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := fn(n, line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func parseICD10CM(line string) (Entry, bool, error) {
+	// The order file starts with a five-digit sequence number.
+	if len(line) > 16 && isDigits(line[:5]) && line[5] == ' ' {
+		entry := Entry{
+			Code:     FormatICD10(line[6:13]),
+			Billable: line[14] == '1',
+		}
+		short := line[16:]
+		if len(short) > 60 {
+			entry.Description = strings.TrimSpace(short[60:])
+			short = short[:60]
+		}
+		entry.ShortDescription = strings.TrimSpace(short)
+		if entry.Description == "" {
+			entry.Description = entry.ShortDescription
+		}
+		return entry, true, nil
+	}
+
+	code, description, ok := strings.Cut(line, " ")
+	description = strings.TrimSpace(description)
+	if !ok || code == "" || description == "" {
+		return Entry{}, false, fmt.Errorf("expected a code and a description, got %q", line)
+	}
+	// The codes file only lists billable codes.
+	return Entry{Code: FormatICD10(code), Description: description, Billable: true}, true, nil
+}
+
+func parseTabular(line string) (Entry, bool, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 2 {
+		return Entry{}, false, fmt.Errorf("expected tab-separated code and description, got %q", line)
+	}
+	code := strings.ToUpper(strings.TrimSpace(fields[0]))
+	// Skip a header row such as "CPT Code<TAB>Long Description".
+	if strings.ContainsRune(code, ' ') || strings.EqualFold(code, "code") {
+		return Entry{}, false, nil
+	}
+	entry := Entry{Code: code, Description: strings.TrimSpace(fields[1]), Billable: true}
+	if len(fields) > 2 {
+		entry.ShortDescription = strings.TrimSpace(fields[2])
+	}
+	if entry.Code == "" || entry.Description == "" {
+		return Entry{}, false, fmt.Errorf("expected a code and a description, got %q", line)
+	}
+	return entry, true, nil
+}
+
+// HCPCS record identification codes (column 11).
+const (
+	hcpcsProcedure             = '3'
+	hcpcsProcedureContinuation = '4'
+)
+
+func readHCPCS(r io.Reader, fn func(Entry) error) error {
+	var current *Entry
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		entry := *current
+		current = nil
+		return fn(entry)
+	}
+
+	err := scanLines(r, func(n int, line string) error {
+		if len(line) < 12 {
+			return fmt.Errorf("line %d: record is too short", n)
+		}
+		code := strings.TrimSpace(line[:5])
+		long := strings.TrimSpace(field(line, 11, 91))
+
+		switch line[10] {
+		case hcpcsProcedure:
+			if err := flush(); err != nil {
+				return err
+			}
+			current = &Entry{
+				Code:             code,
+				Description:      long,
+				ShortDescription: strings.TrimSpace(field(line, 91, 119)),
+				Billable:         true,
+			}
+		case hcpcsProcedureContinuation:
+			if current == nil || current.Code != code {
+				return fmt.Errorf("line %d: continuation of %s without its first record", n, code)
+			}
+			current.Description += " " + long
+		default:
+			// Modifier records.
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// field returns line[from:to], clipped to the line's length.
+func field(line string, from, to int) string {
+	if from >= len(line) {
+		return ""
+	}
+	if to > len(line) {
+		to = len(line)
+	}
+	return line[from:to]
+}
+
+func isDigits(s string) bool {
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
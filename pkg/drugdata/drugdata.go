@@ -0,0 +1,166 @@
+// Package drugdata reads the tab-separated files a drug catalog and its
+// interaction dataset are loaded from. Lines starting with # are comments,
+// and a first line naming the columns is skipped.
+//
+// A drug file has one product per line:
+//
+//	rxcui	name	ingredients	strength	dose form	[classes]
+//
+// where ingredients and the optional classes (such as "penicillins") are
+// separated by semicolons. An interaction file has one pair of
+// interacting ingredients per line:
+//
+//	ingredient	ingredient	severity	description
+package drugdata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Interaction severities, from least to most serious.
+const (
+	SeverityMinor           = "minor"
+	SeverityModerate        = "moderate"
+	SeverityMajor           = "major"
+	SeverityContraindicated = "contraindicated"
+)
+
+// Severities lists the interaction severities, least serious first.
+var Severities = []string{SeverityMinor, SeverityModerate, SeverityMajor, SeverityContraindicated}
+
+// Drug is one product of a drug file.
+type Drug struct {
+	RxCUI       string
+	Name        string
+	Ingredients []string
+	Strength    string
+	DoseForm    string
+	Classes     []string
+}
+
+// Interaction is one line of an interaction file. A and B are normalized
+// and in alphabetical order.
+type Interaction struct {
+	A           string
+	B           string
+	Severity    string
+	Description string
+}
+
+// ReadDrugs parses a drug file and calls fn for every drug, in file order.
+// It stops at the first error from fn.
+func ReadDrugs(r io.Reader, fn func(Drug) error) error {
+	return scanRows(r, "rxcui", func(n int, cols []string) error {
+		if len(cols) < 5 {
+			return fmt.Errorf("line %d: expected at least 5 columns, got %d", n, len(cols))
+		}
+		drug := Drug{
+			RxCUI:       strings.TrimSpace(cols[0]),
+			Name:        strings.TrimSpace(cols[1]),
+			Ingredients: splitList(cols[2]),
+			Strength:    strings.TrimSpace(cols[3]),
+			DoseForm:    strings.TrimSpace(cols[4]),
+			Classes:     []string{},
+		}
+		if len(cols) > 5 {
+			drug.Classes = splitList(cols[5])
+		}
+		if drug.RxCUI == "" || drug.Name == "" {
+			return fmt.Errorf("line %d: rxcui and name are required", n)
+		}
+		if len(drug.Ingredients) == 0 {
+			return fmt.Errorf("line %d: %s has no ingredients", n, drug.RxCUI)
+		}
+		return fn(drug)
+	})
+}
+
+// ReadInteractions parses an interaction file and calls fn for every
+// interaction, in file order. It stops at the first error from fn.
+func ReadInteractions(r io.Reader, fn func(Interaction) error) error {
+	return scanRows(r, "ingredient", func(n int, cols []string) error {
+		if len(cols) < 4 {
+			return fmt.Errorf("line %d: expected 4 columns, got %d", n, len(cols))
+		}
+		interaction := Interaction{
+			A:           Normalize(cols[0]),
+			B:           Normalize(cols[1]),
+			Severity:    strings.ToLower(strings.TrimSpace(cols[2])),
+			Description: strings.TrimSpace(cols[3]),
+		}
+		if interaction.A == "" || interaction.B == "" || interaction.A == interaction.B {
+			return fmt.Errorf("line %d: expected two different ingredients", n)
+		}
+		if !isSeverity(interaction.Severity) {
+			return fmt.Errorf("line %d: severity must be one of %v", n, Severities)
+		}
+		if interaction.B < interaction.A {
+			interaction.A, interaction.B = interaction.B, interaction.A
+		}
+		return fn(interaction)
+	})
+}
+
+// Normalize writes an ingredient, class or allergen name the way it is
+// compared: lower case with single spaces.
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// SeverityRank orders severities; unknown ones rank below minor.
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func isSeverity(severity string) bool {
+	return SeverityRank(severity) > 0
+}
+
+// splitList splits a semicolon-separated column into normalized names.
+func splitList(col string) []string {
+	names := []string{}
+	for _, name := range strings.Split(col, ";") {
+		if name = Normalize(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// scanRows calls fn with the tab-separated columns of every data line and
+// its 1-based number, skipping blank lines, comments and a first line
+// whose first column is header.
+func scanRows(r io.Reader, header string, fn func(n int, cols []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n, first := 0, true
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r\n")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // byte order mark
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(cols[0]), header) {
+				continue
+			}
+		}
+		if err := fn(n, cols); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,17 @@
+// pkg/email/log_sender.go
+package email
+
+import "github.com/sirupsen/logrus"
+
+// LogSender logs emails instead of sending them. Useful for demo data and
+// local development.
+type LogSender struct{}
+
+func NewLogSender() Sender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(to, subject, body string) error {
+	logrus.Printf("Email to %s: %s", to, subject)
+	return nil
+}
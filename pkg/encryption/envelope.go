@@ -0,0 +1,130 @@
+// pkg/encryption/envelope.go
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Envelope implements envelope encryption: each record gets its own AES-256
+// data key, which is stored wrapped by the KeyManager's master key.
+type Envelope struct {
+	kms      KeyManager
+	indexKey []byte
+}
+
+// DataKey is a record's plaintext data key together with its wrapped form.
+type DataKey struct {
+	Plain   []byte
+	Wrapped string
+	KeyID   string
+}
+
+func NewEnvelope(kms KeyManager, indexKey []byte) (*Envelope, error) {
+	if len(indexKey) < 32 {
+		return nil, errors.New("blind index key must be at least 32 bytes")
+	}
+	return &Envelope{kms: kms, indexKey: indexKey}, nil
+}
+
+// ActiveKeyID is the master key new data keys are wrapped with.
+func (e *Envelope) ActiveKeyID() string {
+	return e.kms.ActiveKeyID()
+}
+
+// NewDataKey generates a fresh data key wrapped by the active master key.
+func (e *Envelope) NewDataKey() (*DataKey, error) {
+	plain := make([]byte, 32)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, keyID, err := e.kms.Wrap(plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return &DataKey{Plain: plain, Wrapped: base64.StdEncoding.EncodeToString(wrapped), KeyID: keyID}, nil
+}
+
+// OpenDataKey unwraps a stored data key.
+func (e *Envelope) OpenDataKey(wrapped, keyID string) (*DataKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped data key: %w", err)
+	}
+	plain, err := e.kms.Unwrap(raw, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return &DataKey{Plain: plain, Wrapped: wrapped, KeyID: keyID}, nil
+}
+
+// Encrypt encrypts a field value with the data key. Empty values stay empty.
+func (e *Envelope) Encrypt(key *DataKey, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	ciphertext, err := seal(key.Plain, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt reverses Encrypt.
+func (e *Envelope) Decrypt(key *DataKey, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(key.Plain, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns a keyed hash of an already normalized value, allowing
+// exact-match lookups without storing the plaintext.
+func (e *Envelope) BlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SearchToken is a BlindIndex truncated to 96 bits. Search tables hold many
+// tokens per row, and occasional collisions are filtered out after decryption.
+func (e *Envelope) SearchToken(value string) string {
+	index := e.BlindIndex(value)
+	if len(index) > 16 {
+		return index[:16]
+	}
+	return index
+}
+
+// NewLocalEnvelope builds an Envelope backed by LocalKMS from configuration
+// strings: masterKeys as "id:base64key,...", indexKey as base64.
+func NewLocalEnvelope(masterKeys, activeKeyID, indexKey string) (*Envelope, error) {
+	keys, err := ParseMasterKeys(masterKeys)
+	if err != nil {
+		return nil, err
+	}
+	kms, err := NewLocalKMS(keys, activeKeyID)
+	if err != nil {
+		return nil, err
+	}
+	rawIndexKey, err := base64.StdEncoding.DecodeString(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %w", err)
+	}
+	return NewEnvelope(kms, rawIndexKey)
+}
@@ -0,0 +1,183 @@
+// pkg/encryption/envelope_test.go
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func newTestEnvelope(t *testing.T, activeID string, ids ...string) *Envelope {
+	t.Helper()
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = testKey(byte(i + 1))
+	}
+	kms, err := NewLocalKMS(keys, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEnvelope(kms, testKey(0xAA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	e := newTestEnvelope(t, "v1", "v1")
+	key, err := e.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.KeyID != "v1" || len(key.Plain) != 32 || key.Wrapped == "" {
+		t.Fatalf("data key = %+v", key)
+	}
+
+	for _, value := range []string{"Jane Doe", "jane@example.com", "ünïcødé ✓", ""} {
+		sealed, err := e.Encrypt(key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != "" && sealed == value {
+			t.Errorf("Encrypt(%q) returned the plaintext", value)
+		}
+		got, err := e.Decrypt(key, sealed)
+		if err != nil || got != value {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", value, got, err)
+		}
+	}
+
+	first, _ := e.Encrypt(key, "Jane Doe")
+	second, _ := e.Encrypt(key, "Jane Doe")
+	if first == second {
+		t.Error("two encryptions of the same value are identical")
+	}
+
+	opened, err := e.OpenDataKey(key.Wrapped, key.KeyID)
+	if err != nil || !bytes.Equal(opened.Plain, key.Plain) {
+		t.Errorf("OpenDataKey = %+v, %v", opened, err)
+	}
+}
+
+func TestEnvelopeRejectsWrongKeyAndTampering(t *testing.T) {
+	e := newTestEnvelope(t, "v1", "v1")
+	key, _ := e.NewDataKey()
+	other, _ := e.NewDataKey()
+	sealed, err := e.Encrypt(key, "Jane Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Decrypt(other, sealed); err == nil {
+		t.Error("Decrypt with another data key succeeded")
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	raw[len(raw)-1] ^= 1
+	if _, err := e.Decrypt(key, base64.StdEncoding.EncodeToString(raw)); err == nil {
+		t.Error("Decrypt of tampered ciphertext succeeded")
+	}
+	if _, err := e.Decrypt(key, "not base64!"); err == nil {
+		t.Error("Decrypt of malformed ciphertext succeeded")
+	}
+
+	if _, err := e.OpenDataKey(key.Wrapped, "v2"); err == nil {
+		t.Error("OpenDataKey with an unknown master key succeeded")
+	}
+	stranger := newTestEnvelope(t, "v1", "v9", "v1")
+	if _, err := stranger.OpenDataKey(key.Wrapped, key.KeyID); err == nil {
+		t.Error("OpenDataKey with a different master key of the same ID succeeded")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	e := newTestEnvelope(t, "v1", "v1")
+	index := e.BlindIndex("jane@example.com")
+
+	if index == "" || index == "jane@example.com" {
+		t.Fatalf("BlindIndex = %q", index)
+	}
+	if again := e.BlindIndex("jane@example.com"); again != index {
+		t.Errorf("BlindIndex is not stable: %q, then %q", index, again)
+	}
+	// Rotating master keys must not change indexes, which depend only on
+	// the index key.
+	if rotated := newTestEnvelope(t, "v2", "v1", "v2").BlindIndex("jane@example.com"); rotated != index {
+		t.Errorf("BlindIndex changed with the master key: %q, then %q", index, rotated)
+	}
+	if e.BlindIndex("john@example.com") == index {
+		t.Error("different values share a blind index")
+	}
+	if got := e.BlindIndex(""); got != "" {
+		t.Errorf("BlindIndex(\"\") = %q, want empty", got)
+	}
+
+	if token := e.SearchToken("jan"); len(token) != 16 || token != e.BlindIndex("jan")[:16] {
+		t.Errorf("SearchToken = %q", token)
+	}
+
+	kms, _ := NewLocalKMS(map[string][]byte{"v1": testKey(1)}, "v1")
+	if _, err := NewEnvelope(kms, testKey(0xAA)[:16]); err == nil {
+		t.Error("NewEnvelope accepted a short index key")
+	}
+}
+
+func TestEnvelopeKeyRotation(t *testing.T) {
+	before := newTestEnvelope(t, "v1", "v1")
+	key, _ := before.NewDataKey()
+	sealed, err := before.Encrypt(key, "Jane Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After v2 becomes active, rows sealed under v1 still open, and
+	// re-wrapping gives them a data key under v2.
+	after := newTestEnvelope(t, "v2", "v1", "v2")
+	if after.ActiveKeyID() != "v2" {
+		t.Fatalf("ActiveKeyID = %q", after.ActiveKeyID())
+	}
+	old, err := after.OpenDataKey(key.Wrapped, key.KeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := after.Decrypt(old, sealed)
+	if err != nil || plain != "Jane Doe" {
+		t.Fatalf("Decrypt of a v1 row = %q, %v", plain, err)
+	}
+
+	fresh, err := after.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.KeyID != "v2" {
+		t.Errorf("new data key wrapped by %q, want v2", fresh.KeyID)
+	}
+	resealed, _ := after.Encrypt(fresh, plain)
+	reopened, err := after.OpenDataKey(fresh.Wrapped, fresh.KeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := after.Decrypt(reopened, resealed); err != nil || got != "Jane Doe" {
+		t.Errorf("Decrypt of the rotated row = %q, %v", got, err)
+	}
+}
+
+func TestNewLocalEnvelope(t *testing.T) {
+	masterKeys := "v1:" + base64.StdEncoding.EncodeToString(testKey(1))
+	indexKey := base64.StdEncoding.EncodeToString(testKey(0xAA))
+
+	e, err := NewLocalEnvelope(masterKeys, "v1", indexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ActiveKeyID() != "v1" {
+		t.Errorf("ActiveKeyID = %q", e.ActiveKeyID())
+	}
+	if _, err := NewLocalEnvelope(masterKeys, "v2", indexKey); err == nil {
+		t.Error("NewLocalEnvelope accepted an unconfigured active key")
+	}
+	if _, err := NewLocalEnvelope(masterKeys, "v1", "not base64!"); err == nil {
+		t.Error("NewLocalEnvelope accepted a malformed index key")
+	}
+}
@@ -0,0 +1,112 @@
+// pkg/encryption/kms.go
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyManager wraps and unwraps data keys with a master key it never exposes.
+// LocalKMS is a stand-in for a cloud KMS; any implementation can be plugged in.
+type KeyManager interface {
+	ActiveKeyID() string
+	Wrap(dataKey []byte) (wrapped []byte, keyID string, err error)
+	Unwrap(wrapped []byte, keyID string) ([]byte, error)
+}
+
+// LocalKMS holds master keys in memory, typically loaded from configuration.
+type LocalKMS struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+func NewLocalKMS(keys map[string][]byte, activeID string) (*LocalKMS, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not configured", activeID)
+	}
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("master key %q must be 32 bytes, got %d", id, len(key))
+		}
+	}
+	return &LocalKMS{keys: keys, activeID: activeID}, nil
+}
+
+// ParseMasterKeys parses "id:base64key,id:base64key".
+func ParseMasterKeys(s string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid master key entry %q: expected id:base64key", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no master keys configured")
+	}
+	return keys, nil
+}
+
+func (k *LocalKMS) ActiveKeyID() string {
+	return k.activeID
+}
+
+func (k *LocalKMS) Wrap(dataKey []byte) ([]byte, string, error) {
+	wrapped, err := seal(k.keys[k.activeID], dataKey)
+	return wrapped, k.activeID, err
+}
+
+func (k *LocalKMS) Unwrap(wrapped []byte, keyID string) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyID)
+	}
+	return open(key, wrapped)
+}
+
+// seal encrypts plaintext with AES-GCM, prefixing the random nonce.
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, data := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, data, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,95 @@
+// pkg/encryption/kms_test.go
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	v1 := base64.StdEncoding.EncodeToString(testKey(1))
+	v2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	keys, err := ParseMasterKeys(" v1:" + v1 + ", v2:" + v2 + ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys["v1"], testKey(1)) || !bytes.Equal(keys["v2"], testKey(2)) {
+		t.Errorf("keys = %v", keys)
+	}
+
+	for _, in := range []string{"", " , ", "v1", ":" + v1, "v1:not base64"} {
+		if _, err := ParseMasterKeys(in); err == nil {
+			t.Errorf("ParseMasterKeys(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestNewLocalKMS(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     map[string][]byte
+		activeID string
+		wantErr  string
+	}{
+		{name: "valid", keys: map[string][]byte{"v1": testKey(1), "v2": testKey(2)}, activeID: "v2"},
+		{name: "missing active key", keys: map[string][]byte{"v1": testKey(1)}, activeID: "v2", wantErr: "not configured"},
+		{name: "short key", keys: map[string][]byte{"v1": testKey(1), "v2": testKey(2)[:16]}, activeID: "v1", wantErr: "32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLocalKMS(tt.keys, tt.activeID)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalKMSWrap(t *testing.T) {
+	kms, err := NewLocalKMS(map[string][]byte{"v1": testKey(1), "v2": testKey(2)}, "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey := testKey(9)
+
+	wrapped, keyID, err := kms.Wrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID != "v2" {
+		t.Errorf("keyID = %q, want the active key", keyID)
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Error("wrapped data key contains the plaintext")
+	}
+
+	plain, err := kms.Unwrap(wrapped, "v2")
+	if err != nil || !bytes.Equal(plain, dataKey) {
+		t.Errorf("Unwrap = %x, %v, want %x", plain, err, dataKey)
+	}
+	if _, err := kms.Unwrap(wrapped, "v1"); err == nil {
+		t.Error("Unwrap with the wrong master key succeeded")
+	}
+	if _, err := kms.Unwrap(wrapped, "v3"); err == nil {
+		t.Error("Unwrap with an unknown master key succeeded")
+	}
+
+	tampered := bytes.Clone(wrapped)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := kms.Unwrap(tampered, "v2"); err == nil {
+		t.Error("Unwrap of a tampered data key succeeded")
+	}
+	if _, err := kms.Unwrap(wrapped[:4], "v2"); err == nil {
+		t.Error("Unwrap of a truncated data key succeeded")
+	}
+}
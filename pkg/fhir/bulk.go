@@ -0,0 +1,25 @@
+// pkg/fhir/bulk.go
+package fhir
+
+import "time"
+
+// NDJSONContentType is the media type of Bulk Data export files.
+const NDJSONContentType = "application/fhir+ndjson"
+
+// BulkOutput is a file of a completed Bulk Data export.
+type BulkOutput struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Count int    `json:"count,omitempty"`
+}
+
+// BulkManifest is the body of the status response of a completed Bulk
+// Data export. With RequiresAccessToken false the output URLs can be
+// fetched without credentials.
+type BulkManifest struct {
+	TransactionTime     time.Time    `json:"transactionTime"`
+	Request             string       `json:"request"`
+	RequiresAccessToken bool         `json:"requiresAccessToken"`
+	Output              []BulkOutput `json:"output"`
+	Error               []BulkOutput `json:"error"`
+}
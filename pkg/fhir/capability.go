@@ -0,0 +1,65 @@
+// pkg/fhir/capability.go
+package fhir
+
+import "time"
+
+// CapabilityStatement describes what a server supports; it is served at
+// GET [base]/metadata.
+type CapabilityStatement struct {
+	ResourceType   string                    `json:"resourceType"`
+	Status         string                    `json:"status"`
+	Date           time.Time                 `json:"date"`
+	Publisher      string                    `json:"publisher,omitempty"`
+	Kind           string                    `json:"kind"`
+	Software       *CapabilitySoftware       `json:"software,omitempty"`
+	Implementation *CapabilityImplementation `json:"implementation,omitempty"`
+	FHIRVersion    string                    `json:"fhirVersion"`
+	Format         []string                  `json:"format"`
+	Rest           []CapabilityRest          `json:"rest"`
+}
+
+type CapabilitySoftware struct {
+	Name string `json:"name"`
+}
+
+type CapabilityImplementation struct {
+	Description string `json:"description"`
+	URL         string `json:"url,omitempty"`
+}
+
+type CapabilityRest struct {
+	Mode     string               `json:"mode"`
+	Security *CapabilitySecurity  `json:"security,omitempty"`
+	Resource []CapabilityResource `json:"resource"`
+}
+
+type CapabilitySecurity struct {
+	Description string `json:"description"`
+}
+
+type CapabilityResource struct {
+	Type        string                  `json:"type"`
+	Interaction []CapabilityInteraction `json:"interaction"`
+	SearchParam []CapabilitySearchParam `json:"searchParam,omitempty"`
+}
+
+// CapabilityInteraction is a supported interaction such as "read",
+// "search-type" or "create".
+type CapabilityInteraction struct {
+	Code string `json:"code"`
+}
+
+type CapabilitySearchParam struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// Interactions lists interaction codes as capability entries.
+func Interactions(codes ...string) []CapabilityInteraction {
+	interactions := make([]CapabilityInteraction, len(codes))
+	for i, code := range codes {
+		interactions[i] = CapabilityInteraction{Code: code}
+	}
+	return interactions
+}
@@ -0,0 +1,222 @@
+// Package fhir holds the HL7 FHIR R4 resources this practice exposes and
+// the pieces of the RESTful API around them: search bundles, operation
+// outcomes, the capability statement and search parameter parsing. Only
+// the elements the practice records are modeled.
+package fhir
+
+import "time"
+
+// Version is the FHIR version served.
+const Version = "4.0.1"
+
+// ContentType is the media type of FHIR JSON.
+const ContentType = "application/fhir+json"
+
+// Code systems used in resources.
+const (
+	// IdentifierTypeSystem holds identifier types such as MR (medical record number).
+	IdentifierTypeSystem = "http://terminology.hl7.org/CodeSystem/v2-0203"
+	LanguageSystem       = "urn:ietf:bcp:47"
+)
+
+// Meta is the version and last update of a resource.
+type Meta struct {
+	VersionID   string     `json:"versionId,omitempty"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Identifier struct {
+	Use    string           `json:"use,omitempty"`
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value,omitempty"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint is a phone number or email address; System is "phone" or "email".
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Address struct {
+	Line       []string `json:"line,omitempty"`
+	City       string   `json:"city,omitempty"`
+	State      string   `json:"state,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty"`
+	Country    string   `json:"country,omitempty"`
+}
+
+// Reference points at another resource, e.g. "Patient/7".
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Communication struct {
+	Language  CodeableConcept `json:"language"`
+	Preferred bool            `json:"preferred,omitempty"`
+}
+
+type Patient struct {
+	ResourceType  string          `json:"resourceType"`
+	ID            string          `json:"id,omitempty"`
+	Meta          *Meta           `json:"meta,omitempty"`
+	Identifier    []Identifier    `json:"identifier,omitempty"`
+	Active        *bool           `json:"active,omitempty"`
+	Name          []HumanName     `json:"name,omitempty"`
+	Telecom       []ContactPoint  `json:"telecom,omitempty"`
+	Gender        string          `json:"gender,omitempty"`
+	BirthDate     string          `json:"birthDate,omitempty"`
+	Address       []Address       `json:"address,omitempty"`
+	Communication []Communication `json:"communication,omitempty"`
+}
+
+type Practitioner struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+}
+
+// Appointment statuses used by this practice.
+const (
+	AppointmentProposed  = "proposed"
+	AppointmentBooked    = "booked"
+	AppointmentFulfilled = "fulfilled"
+	AppointmentCancelled = "cancelled"
+)
+
+type AppointmentParticipant struct {
+	Actor    *Reference `json:"actor,omitempty"`
+	Required string     `json:"required,omitempty"`
+	Status   string     `json:"status"`
+}
+
+type Appointment struct {
+	ResourceType    string                   `json:"resourceType"`
+	ID              string                   `json:"id,omitempty"`
+	Meta            *Meta                    `json:"meta,omitempty"`
+	Status          string                   `json:"status"`
+	Description     string                   `json:"description,omitempty"`
+	Start           *time.Time               `json:"start,omitempty"`
+	End             *time.Time               `json:"end,omitempty"`
+	MinutesDuration int                      `json:"minutesDuration,omitempty"`
+	Slot            []Reference              `json:"slot,omitempty"`
+	Created         *time.Time               `json:"created,omitempty"`
+	Comment         string                   `json:"comment,omitempty"`
+	Participant     []AppointmentParticipant `json:"participant"`
+}
+
+// Slot statuses.
+const (
+	SlotBusy = "busy"
+	SlotFree = "free"
+)
+
+type Slot struct {
+	ResourceType string    `json:"resourceType"`
+	ID           string    `json:"id,omitempty"`
+	Meta         *Meta     `json:"meta,omitempty"`
+	Schedule     Reference `json:"schedule"`
+	Status       string    `json:"status"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Comment      string    `json:"comment,omitempty"`
+}
+
+// BundleLink is a link of a bundle, such as its "self" URL.
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleSearch struct {
+	Mode string `json:"mode"`
+}
+
+type BundleEntry struct {
+	FullURL  string        `json:"fullUrl,omitempty"`
+	Resource interface{}   `json:"resource"`
+	Search   *BundleSearch `json:"search,omitempty"`
+}
+
+// Bundle is a collection of resources; search results are a "searchset".
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+// NewSearchset returns an empty search result bundle for total matches;
+// the matches on the current page are added with Add.
+func NewSearchset(self string, total int) *Bundle {
+	return &Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Total:        &total,
+		Link:         []BundleLink{{Relation: "self", URL: self}},
+		Entry:        []BundleEntry{},
+	}
+}
+
+// Add appends a search match.
+func (b *Bundle) Add(fullURL string, resource interface{}) {
+	b.Entry = append(b.Entry, BundleEntry{FullURL: fullURL, Resource: resource, Search: &BundleSearch{Mode: "match"}})
+}
+
+// Issue severities and codes of an OperationOutcome.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	IssueInvalid      = "invalid"
+	IssueNotFound     = "not-found"
+	IssueConflict     = "conflict"
+	IssueForbidden    = "forbidden"
+	IssueLogin        = "login"
+	IssueNotSupported = "not-supported"
+	IssueThrottled    = "throttled"
+	IssueException    = "exception"
+	IssueBusinessRule = "business-rule"
+)
+
+type Issue struct {
+	Severity    string           `json:"severity"`
+	Code        string           `json:"code"`
+	Details     *CodeableConcept `json:"details,omitempty"`
+	Diagnostics string           `json:"diagnostics,omitempty"`
+	Expression  []string         `json:"expression,omitempty"`
+}
+
+// OperationOutcome reports errors and warnings of an interaction.
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+func NewOperationOutcome(issues ...Issue) *OperationOutcome {
+	return &OperationOutcome{ResourceType: "OperationOutcome", Issue: issues}
+}
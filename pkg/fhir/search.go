@@ -0,0 +1,112 @@
+// pkg/fhir/search.go
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidParam is returned for a search parameter value that can't be parsed.
+var ErrInvalidParam = errors.New("invalid search parameter")
+
+// dateLayouts are the precisions of a date search value, each with the
+// length of the period it covers.
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01-02T15:04Z07:00", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02T15:04:05Z07:00", func(t time.Time) time.Time { return t.Add(time.Second) }},
+}
+
+// DateRange intersects date search values such as "ge2030-09-01" and
+// "lt2030-10-01" into the half-open interval [from, to) of instants they
+// match. A zero bound is open. Supported prefixes are eq (the default),
+// gt, ge, lt, le, sa and eb; values without a time zone are UTC.
+func DateRange(values []string) (from, to time.Time, err error) {
+	for _, value := range values {
+		prefix := "eq"
+		if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' && value[2] >= '0' && value[2] <= '9' {
+			prefix, value = value[:2], value[2:]
+		}
+
+		start, end, err := datePeriod(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		var lower, upper time.Time
+		switch prefix {
+		case "eq":
+			lower, upper = start, end
+		case "gt", "sa":
+			lower = end
+		case "ge":
+			lower = start
+		case "lt", "eb":
+			upper = start
+		case "le":
+			upper = end
+		default:
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: date prefix %q is not supported", ErrInvalidParam, prefix)
+		}
+		if !lower.IsZero() && (from.IsZero() || lower.After(from)) {
+			from = lower
+		}
+		if !upper.IsZero() && (to.IsZero() || upper.Before(to)) {
+			to = upper
+		}
+	}
+	return from, to, nil
+}
+
+// datePeriod returns the period a date value of any precision covers.
+func datePeriod(value string) (start, end time.Time, err error) {
+	for _, l := range dateLayouts {
+		layout := l.layout
+		if strings.Contains(layout, "T") && !hasZone(value) {
+			layout = strings.TrimSuffix(layout, "Z07:00")
+		}
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, l.next(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w: %q is not a date", ErrInvalidParam, value)
+}
+
+func hasZone(value string) bool {
+	if strings.HasSuffix(value, "Z") {
+		return true
+	}
+	i := strings.IndexByte(value, 'T')
+	return i >= 0 && strings.ContainsAny(value[i:], "+-")
+}
+
+// ReferenceID returns the ID in a reference search value to a resource of
+// the given type: "Patient/7", a URL ending in "/Patient/7", or just "7".
+func ReferenceID(value, resourceType string) (string, error) {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndex(value, resourceType+"/"); i >= 0 && (i == 0 || value[i-1] == '/') {
+		value = value[i+len(resourceType)+1:]
+	} else if strings.Contains(value, "/") {
+		return "", fmt.Errorf("%w: %q is not a reference to a %s", ErrInvalidParam, value, resourceType)
+	}
+	if value == "" {
+		return "", fmt.Errorf("%w: empty %s reference", ErrInvalidParam, resourceType)
+	}
+	return value, nil
+}
+
+// Token splits a token search value "system|code" into its parts. A value
+// without "|" has an empty system.
+func Token(value string) (system, code string) {
+	if i := strings.IndexByte(value, '|'); i >= 0 {
+		return value[:i], value[i+1:]
+	}
+	return "", value
+}
@@ -0,0 +1,162 @@
+// Package fuzzy implements the trigram matching used by patient search.
+// Trigrams follow pg_trgm's conventions: text is lowercased, split into
+// words, and each word is padded with two leading and one trailing space.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Word is a normalized word with its rune offsets in the original text.
+type Word struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// folds maps accented Latin letters to their base letter. Every mapping is
+// one rune to one rune so Word offsets stay valid in the original text.
+var folds = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a', 'å': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o', 'ø': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ñ': 'n', 'ç': 'c', 'ý': 'y', 'ÿ': 'y',
+}
+
+func fold(r rune) rune {
+	r = unicode.ToLower(r)
+	if f, ok := folds[r]; ok {
+		return f
+	}
+	return r
+}
+
+// Words splits s into normalized words. Anything that is not a letter or
+// digit separates words, so "O'Brien-Smith" yields "o", "brien", "smith".
+func Words(s string) []Word {
+	var words []Word
+	var b strings.Builder
+	start := -1
+	i := 0
+	flush := func() {
+		if start >= 0 {
+			words = append(words, Word{Text: b.String(), Start: start, End: i})
+			b.Reset()
+			start = -1
+		}
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			b.WriteRune(fold(r))
+		} else {
+			flush()
+		}
+		i++
+	}
+	flush()
+	return words
+}
+
+// Trigrams returns the distinct trigrams of every word in s.
+func Trigrams(s string) []string {
+	var grams []string
+	for _, w := range Words(s) {
+		grams = append(grams, wordGrams("  "+w.Text+" ")...)
+	}
+	return dedupe(grams)
+}
+
+// PrefixTrigrams is like Trigrams but omits each word's end-of-word
+// trigram, so a typed prefix ("smi") matches the full word ("smith").
+func PrefixTrigrams(s string) []string {
+	var grams []string
+	for _, w := range Words(s) {
+		grams = append(grams, wordGrams("  "+w.Text)...)
+	}
+	return dedupe(grams)
+}
+
+// DigitTrigrams returns the distinct unpadded trigrams of the digits in s,
+// used for matching any part of a phone number.
+func DigitTrigrams(s string) []string {
+	return dedupe(wordGrams(Digits(s)))
+}
+
+// Digits returns only the ASCII digits of s.
+func Digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Similarity is the share of trigrams two words have in common (0 to 1),
+// the same measure as pg_trgm's similarity().
+func Similarity(a, b string) float64 {
+	ga, gb := Trigrams(a), Trigrams(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(ga))
+	for _, g := range ga {
+		set[g] = true
+	}
+	shared := 0
+	for _, g := range gb {
+		if set[g] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ga)+len(gb)-shared)
+}
+
+// PrefixSimilarity is the share of term's prefix trigrams found in word, so
+// a partly typed, slightly misspelled term ("smy") still scores against
+// "smith". Unlike Similarity it ignores the rest of word.
+func PrefixSimilarity(term, word string) float64 {
+	gt := PrefixTrigrams(term)
+	if len(gt) == 0 {
+		return 0
+	}
+	set := make(map[string]bool)
+	for _, g := range Trigrams(word) {
+		set[g] = true
+	}
+	shared := 0
+	for _, g := range gt {
+		if set[g] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(gt))
+}
+
+func wordGrams(s string) []string {
+	runes := []rune(s)
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+	return grams
+}
+
+func dedupe(grams []string) []string {
+	seen := make(map[string]bool, len(grams))
+	out := grams[:0]
+	for _, g := range grams {
+		if !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	return out
+}
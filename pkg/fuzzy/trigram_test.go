@@ -0,0 +1,103 @@
+// pkg/fuzzy/trigram_test.go
+package fuzzy
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Word
+	}{
+		{"", nil},
+		{"Smith", []Word{{"smith", 0, 5}}},
+		{"O'Brien-Smith", []Word{{"o", 0, 1}, {"brien", 2, 7}, {"smith", 8, 13}}},
+		{"  Ana   María ", []Word{{"ana", 2, 5}, {"maria", 8, 13}}},
+		{"José Núñez", []Word{{"jose", 0, 4}, {"nunez", 5, 10}}},
+		{"Room 101", []Word{{"room", 0, 4}, {"101", 5, 8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Words(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(string) []string
+		in   string
+		want []string
+	}{
+		{"word", Trigrams, "Ana", []string{"  a", " an", "ana", "na "}},
+		{"short word", Trigrams, "Li", []string{"  l", " li", "li "}},
+		{"accents fold", Trigrams, "Ñu", []string{"  n", " nu", "nu "}},
+		{"repeated trigrams once", Trigrams, "ana ana", []string{"  a", " an", "ana", "na "}},
+		{"no words", Trigrams, "--", nil},
+		{"prefix omits word end", PrefixTrigrams, "Smi", []string{"  s", " sm", "smi"}},
+		{"prefix of several words", PrefixTrigrams, "jo sm", []string{"  j", " jo", "  s", " sm"}},
+		{"digits", DigitTrigrams, "+1 (555) 123", []string{"155", "555", "551", "512", "123"}},
+		{"too few digits", DigitTrigrams, "55", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.fn(tt.in)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trigrams of %q = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"smith", "smith", 1},
+		{"Smith", "SMITH", 1},
+		{"smith", "smyth", 3.0 / 9},
+		{"jose", "José", 1},
+		{"smith", "garcia", 0},
+		{"", "smith", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := Similarity(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixSimilarity(t *testing.T) {
+	tests := []struct {
+		term, word string
+		want       float64
+	}{
+		{"smi", "smith", 1},
+		{"smith", "smith", 1},
+		{"smy", "smith", 2.0 / 3},
+		{"xyz", "smith", 0},
+		{"", "smith", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term+"/"+tt.word, func(t *testing.T) {
+			if got := PrefixSimilarity(tt.term, tt.word); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("PrefixSimilarity(%q, %q) = %v, want %v", tt.term, tt.word, got, tt.want)
+			}
+		})
+	}
+}
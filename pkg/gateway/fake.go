@@ -0,0 +1,207 @@
+// pkg/gateway/fake.go
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake is a local stand-in for a payment provider. It keeps intents in
+// memory and, after a delay, settles each one as though the patient had
+// paid: amounts ending in .02 are declined, like Stripe's test card
+// 4000 0000 0000 0002, and the others succeed. Each outcome is posted as
+// a signed webhook event to webhookURL, retried a few times, as a real
+// provider would.
+type Fake struct {
+	secret     string
+	webhookURL string
+	delay      time.Duration
+	client     *http.Client
+	now        func() time.Time
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+	byKey   map[string]string
+}
+
+// NewFake starts a Fake. Without a secret, a random one is used; the Fake
+// both signs and verifies its webhooks, so it never has to be shared.
+func NewFake(secret, webhookURL string, delay time.Duration) (*Fake, error) {
+	if secret == "" {
+		var err error
+		if secret, err = randomID("whsec_"); err != nil {
+			return nil, err
+		}
+	}
+	return &Fake{
+		secret:     secret,
+		webhookURL: webhookURL,
+		delay:      delay,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+		intents:    map[string]*Intent{},
+		byKey:      map[string]string{},
+	}, nil
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) CreateIntent(ctx context.Context, params IntentParams) (*Intent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params.AmountCents < 1 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrRejected)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id, ok := f.byKey[params.IdempotencyKey]; ok && params.IdempotencyKey != "" {
+		return f.copy(f.intents[id]), nil
+	}
+
+	id, err := randomID("pi_")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomID(id + "_secret_")
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]string, len(params.Metadata))
+	for k, v := range params.Metadata {
+		metadata[k] = v
+	}
+	intent := &Intent{
+		ID:           id,
+		Status:       IntentRequiresPaymentMethod,
+		AmountCents:  params.AmountCents,
+		Currency:     strings.ToUpper(params.Currency),
+		ClientSecret: secret,
+		Metadata:     metadata,
+	}
+	f.intents[id] = intent
+	if params.IdempotencyKey != "" {
+		f.byKey[params.IdempotencyKey] = id
+	}
+
+	time.AfterFunc(f.delay, func() { f.settle(id) })
+	return f.copy(intent), nil
+}
+
+func (f *Fake) GetIntent(ctx context.Context, id string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	intent, ok := f.intents[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no such payment intent %q", ErrRejected, id)
+	}
+	return f.copy(intent), nil
+}
+
+func (f *Fake) CancelIntent(ctx context.Context, id string) (*Intent, error) {
+	f.mu.Lock()
+	intent, ok := f.intents[id]
+	if !ok {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("%w: no such payment intent %q", ErrRejected, id)
+	}
+	if intent.Final() {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("%w: the payment intent is %s", ErrRejected, intent.Status)
+	}
+	intent.Status = IntentCanceled
+	canceled := f.copy(intent)
+	f.mu.Unlock()
+
+	go f.deliver(EventIntentCanceled, canceled)
+	return canceled, nil
+}
+
+func (f *Fake) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	return parseEvent(payload, signature, f.secret, f.now())
+}
+
+// settle pays an intent that is still waiting for payment.
+func (f *Fake) settle(id string) {
+	f.mu.Lock()
+	intent := f.intents[id]
+	if intent.Status != IntentRequiresPaymentMethod {
+		f.mu.Unlock()
+		return
+	}
+	eventType := EventIntentSucceeded
+	if intent.AmountCents%100 == 2 {
+		eventType = EventIntentPaymentFailed
+		intent.LastError = "Your card was declined."
+	} else {
+		intent.Status = IntentSucceeded
+		intent.AmountReceivedCents = intent.AmountCents
+	}
+	settled := f.copy(intent)
+	f.mu.Unlock()
+
+	f.deliver(eventType, settled)
+}
+
+// deliver posts an event to the webhook URL, trying up to three times.
+func (f *Fake) deliver(eventType string, intent *Intent) {
+	if f.webhookURL == "" {
+		return
+	}
+	id, err := randomID("evt_")
+	if err != nil {
+		log.Printf("fake payment provider: %v", err)
+		return
+	}
+	event := stripeEvent{ID: id, Type: eventType, Created: f.now().Unix()}
+	event.Data.Object, _ = json.Marshal(toStripeIntent(intent))
+	payload, _ := json.Marshal(event)
+
+	for attempt := 1; ; attempt++ {
+		req, _ := http.NewRequest(http.MethodPost, f.webhookURL, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, Sign(payload, f.secret, f.now()))
+		res, err := f.client.Do(req)
+		if err == nil {
+			res.Body.Close()
+			if res.StatusCode < 300 {
+				return
+			}
+			err = fmt.Errorf("status %d", res.StatusCode)
+		}
+		if attempt == 3 {
+			log.Printf("fake payment provider: delivering %s %s failed: %v", eventType, id, err)
+			return
+		}
+		time.Sleep(time.Duration(attempt) * f.delay)
+	}
+}
+
+func (f *Fake) copy(intent *Intent) *Intent {
+	c := *intent
+	c.Metadata = make(map[string]string, len(intent.Metadata))
+	for k, v := range intent.Metadata {
+		c.Metadata[k] = v
+	}
+	return &c
+}
+
+func randomID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+// Package gateway takes card payments through a payment provider. A
+// payment intent is created for an amount; the patient pays it in the
+// provider's checkout with the intent's client secret, and the provider
+// reports the outcome with signed webhook events. Stripe is the model:
+// StripeProvider talks to its API or any compatible one, and Fake stands
+// in for a provider during development.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PaymentProvider creates payment intents and verifies the webhooks that
+// report on them.
+type PaymentProvider interface {
+	// Name identifies the provider, e.g. "stripe".
+	Name() string
+	// CreateIntent starts collecting a payment. Calls with the same
+	// IdempotencyKey return the intent the first one created, so a
+	// request that timed out can be sent again safely.
+	CreateIntent(ctx context.Context, params IntentParams) (*Intent, error)
+	GetIntent(ctx context.Context, id string) (*Intent, error)
+	CancelIntent(ctx context.Context, id string) (*Intent, error)
+	// ParseWebhook checks the signature of a webhook delivery and decodes
+	// its event. It returns ErrInvalidSignature for deliveries that are
+	// not from the provider or are too old to be replayed.
+	ParseWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// Intent states, as Stripe names them. A failed attempt returns an intent
+// to IntentRequiresPaymentMethod with LastError set, so the patient can
+// try another card.
+const (
+	IntentRequiresPaymentMethod = "requires_payment_method"
+	IntentRequiresConfirmation  = "requires_confirmation"
+	IntentRequiresAction        = "requires_action"
+	IntentProcessing            = "processing"
+	IntentSucceeded             = "succeeded"
+	IntentCanceled              = "canceled"
+)
+
+// Event types about payment intents. Events of other types carry no intent.
+const (
+	EventIntentSucceeded      = "payment_intent.succeeded"
+	EventIntentPaymentFailed  = "payment_intent.payment_failed"
+	EventIntentProcessing     = "payment_intent.processing"
+	EventIntentRequiresAction = "payment_intent.requires_action"
+	EventIntentCanceled       = "payment_intent.canceled"
+)
+
+// IntentParams describe a payment to collect. Amounts are in the smallest
+// unit of the currency, e.g. cents.
+type IntentParams struct {
+	AmountCents int64
+	// Currency is an ISO 4217 code such as "USD".
+	Currency    string
+	Description string
+	// Metadata is kept on the intent and returned with it.
+	Metadata       map[string]string
+	IdempotencyKey string
+}
+
+// Intent is a payment intent as the provider reports it.
+type Intent struct {
+	ID                  string
+	Status              string
+	AmountCents         int64
+	AmountReceivedCents int64
+	Currency            string
+	// ClientSecret lets the provider's checkout pay the intent.
+	ClientSecret string
+	Metadata     map[string]string
+	// LastError explains why the last attempt to pay failed.
+	LastError string
+}
+
+// Final reports whether the intent can no longer change.
+func (i *Intent) Final() bool {
+	return i.Status == IntentSucceeded || i.Status == IntentCanceled
+}
+
+// Event is a webhook event.
+type Event struct {
+	ID      string
+	Type    string
+	Created time.Time
+	// Intent is the intent the event is about, as of the event.
+	Intent *Intent
+}
+
+// ErrUnavailable is returned when the provider can't be reached or fails;
+// the request may be retried with the same idempotency key.
+var ErrUnavailable = errors.New("payment provider unavailable")
+
+// ErrRejected is returned when the provider refuses a request, for
+// example an unsupported currency.
+var ErrRejected = errors.New("payment provider rejected the request")
+
+// ErrInvalidSignature is returned for webhook deliveries that fail the
+// signature check.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// New returns the provider named by kind. "stripe" calls the API at
+// baseURL with apiKey; "fake" starts a Fake that reports outcomes to
+// webhookURL. webhookSecret signs webhook deliveries. There is no default,
+// so a deployment that forgets to configure payments fails to start
+// rather than taking payments nobody is charged for.
+func New(kind, baseURL, apiKey, webhookSecret, webhookURL string) (PaymentProvider, error) {
+	switch kind {
+	case "stripe":
+		if apiKey == "" || webhookSecret == "" {
+			return nil, errors.New("the stripe payment provider needs an API key and a webhook secret")
+		}
+		return NewStripeProvider(baseURL, apiKey, webhookSecret), nil
+	case "fake":
+		return NewFake(webhookSecret, webhookURL, 2*time.Second)
+	case "":
+		return nil, errors.New(`no payment provider configured; set PAYMENT_PROVIDER to "stripe", or "fake" for development`)
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", kind)
+	}
+}
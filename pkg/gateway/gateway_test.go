@@ -0,0 +1,33 @@
+// pkg/gateway/gateway_test.go
+package gateway
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		kind          string
+		apiKey        string
+		webhookSecret string
+		wantName      string
+		wantErr       bool
+	}{
+		{name: "not configured", kind: "", wantErr: true},
+		{name: "fake", kind: "fake", wantName: "fake"},
+		{name: "stripe", kind: "stripe", apiKey: "sk_test", webhookSecret: "whsec_test", wantName: "stripe"},
+		{name: "stripe without an API key", kind: "stripe", webhookSecret: "whsec_test", wantErr: true},
+		{name: "stripe without a webhook secret", kind: "stripe", apiKey: "sk_test", wantErr: true},
+		{name: "unknown", kind: "paypal", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := New(tt.kind, "", tt.apiKey, tt.webhookSecret, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New(%q) error = %v, want error %v", tt.kind, err, tt.wantErr)
+			}
+			if err == nil && provider.Name() != tt.wantName {
+				t.Errorf("New(%q).Name() = %q, want %q", tt.kind, provider.Name(), tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// pkg/gateway/stripe.go
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStripeURL is the address of Stripe's API.
+const DefaultStripeURL = "https://api.stripe.com"
+
+// StripeProvider calls Stripe's payment intents API, or any API that
+// speaks it.
+type StripeProvider struct {
+	baseURL       string
+	apiKey        string
+	webhookSecret string
+	client        *http.Client
+	now           func() time.Time
+}
+
+func NewStripeProvider(baseURL, apiKey, webhookSecret string) *StripeProvider {
+	if baseURL == "" {
+		baseURL = DefaultStripeURL
+	}
+	return &StripeProvider{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		apiKey:        apiKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 30 * time.Second},
+		now:           time.Now,
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+func (p *StripeProvider) CreateIntent(ctx context.Context, params IntentParams) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(params.AmountCents, 10))
+	form.Set("currency", strings.ToLower(params.Currency))
+	form.Set("automatic_payment_methods[enabled]", "true")
+	if params.Description != "" {
+		form.Set("description", params.Description)
+	}
+	for key, value := range params.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+	return p.do(ctx, "/v1/payment_intents", form, params.IdempotencyKey)
+}
+
+func (p *StripeProvider) GetIntent(ctx context.Context, id string) (*Intent, error) {
+	return p.do(ctx, "/v1/payment_intents/"+url.PathEscape(id), nil, "")
+}
+
+func (p *StripeProvider) CancelIntent(ctx context.Context, id string) (*Intent, error) {
+	return p.do(ctx, "/v1/payment_intents/"+url.PathEscape(id)+"/cancel", url.Values{}, "")
+}
+
+func (p *StripeProvider) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	return parseEvent(payload, signature, p.webhookSecret, p.now())
+}
+
+// do sends a GET, or a form POST when form is not nil, and decodes the
+// intent in the response.
+func (p *StripeProvider) do(ctx context.Context, path string, form url.Values, idempotencyKey string) (*Intent, error) {
+	method, body := http.MethodGet, io.Reader(nil)
+	if form != nil {
+		method, body = http.MethodPost, strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	// Stripe answers 409 when an idempotent request is still running, and
+	// 429 when rate limited; both are worth retrying.
+	if res.StatusCode >= 500 || res.StatusCode == http.StatusConflict || res.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%w: status %d: %s", ErrUnavailable, res.StatusCode, errorMessage(data))
+	}
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("%w: status %d: %s", ErrRejected, res.StatusCode, errorMessage(data))
+	}
+
+	var intent stripeIntent
+	if err := json.Unmarshal(data, &intent); err != nil || intent.ID == "" {
+		return nil, fmt.Errorf("%w: unreadable response", ErrUnavailable)
+	}
+	return intent.intent(), nil
+}
+
+// errorMessage reads the message of a Stripe error response.
+func errorMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil || body.Error.Message == "" {
+		return strings.TrimSpace(string(data))
+	}
+	return body.Error.Message
+}
@@ -0,0 +1,149 @@
+// pkg/gateway/webhook.go
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the signature of webhook deliveries.
+const SignatureHeader = "Stripe-Signature"
+
+// SignatureTolerance is how old a webhook delivery may be. Older ones are
+// refused so a captured delivery can't be replayed later.
+const SignatureTolerance = 5 * time.Minute
+
+// Sign returns the signature header of a webhook delivery, in Stripe's
+// format: "t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<payload>">".
+func Sign(payload []byte, secret string, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(payload, secret, t)
+}
+
+func signature(payload []byte, secret, t string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature header made by Sign. Any of several
+// v1 signatures may match, as providers send one per secret while a
+// secret is being rolled.
+func VerifySignature(payload []byte, header, secret string, now time.Time) error {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	ts, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+
+	expected := []byte(signature(payload, secret, t))
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no matching signature", ErrInvalidSignature)
+}
+
+// stripeIntent is a payment intent in Stripe's JSON.
+type stripeIntent struct {
+	ID               string            `json:"id"`
+	Status           string            `json:"status"`
+	Amount           int64             `json:"amount"`
+	AmountReceived   int64             `json:"amount_received"`
+	Currency         string            `json:"currency"`
+	ClientSecret     string            `json:"client_secret"`
+	Metadata         map[string]string `json:"metadata"`
+	LastPaymentError *struct {
+		Message string `json:"message"`
+	} `json:"last_payment_error"`
+}
+
+func (s *stripeIntent) intent() *Intent {
+	intent := &Intent{
+		ID:                  s.ID,
+		Status:              s.Status,
+		AmountCents:         s.Amount,
+		AmountReceivedCents: s.AmountReceived,
+		Currency:            strings.ToUpper(s.Currency),
+		ClientSecret:        s.ClientSecret,
+		Metadata:            s.Metadata,
+	}
+	if s.LastPaymentError != nil {
+		intent.LastError = s.LastPaymentError.Message
+	}
+	return intent
+}
+
+func toStripeIntent(intent *Intent) stripeIntent {
+	s := stripeIntent{
+		ID:             intent.ID,
+		Status:         intent.Status,
+		Amount:         intent.AmountCents,
+		AmountReceived: intent.AmountReceivedCents,
+		Currency:       strings.ToLower(intent.Currency),
+		ClientSecret:   intent.ClientSecret,
+		Metadata:       intent.Metadata,
+	}
+	if intent.LastError != "" {
+		s.LastPaymentError = &struct {
+			Message string `json:"message"`
+		}{intent.LastError}
+	}
+	return s
+}
+
+// stripeEvent is a webhook event in Stripe's JSON.
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// parseEvent verifies and decodes a webhook delivery in Stripe's format.
+func parseEvent(payload []byte, header, secret string, now time.Time) (*Event, error) {
+	if err := VerifySignature(payload, header, secret, now); err != nil {
+		return nil, err
+	}
+	var raw stripeEvent
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("webhook event: %w", err)
+	}
+	if raw.ID == "" || raw.Type == "" {
+		return nil, fmt.Errorf("webhook event: missing id or type")
+	}
+
+	event := &Event{ID: raw.ID, Type: raw.Type, Created: time.Unix(raw.Created, 0)}
+	if strings.HasPrefix(raw.Type, "payment_intent.") {
+		var intent stripeIntent
+		if err := json.Unmarshal(raw.Data.Object, &intent); err != nil {
+			return nil, fmt.Errorf("webhook event %s: %w", raw.ID, err)
+		}
+		event.Intent = intent.intent()
+	}
+	return event, nil
+}
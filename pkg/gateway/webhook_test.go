@@ -0,0 +1,137 @@
+// pkg/gateway/webhook_test.go
+package gateway
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+var testPayload = []byte(`{"id":"evt_1","type":"payment_intent.succeeded","created":1915000000,` +
+	`"data":{"object":{"id":"pi_1","status":"succeeded","amount":2500,"amount_received":2500,"currency":"usd",` +
+	`"client_secret":"pi_1_secret","metadata":{"invoice_id":"7"}}}}`)
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Unix(1915000000, 0)
+	signed := Sign(testPayload, testSecret, now)
+	v1 := signed[strings.Index(signed, "v1=")+3:]
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		secret  string
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "just signed", header: signed, now: now},
+		{name: "at the tolerance", header: signed, now: now.Add(SignatureTolerance)},
+		{name: "past the tolerance", header: signed, now: now.Add(SignatureTolerance + time.Second), wantErr: true},
+		{name: "clock behind within the tolerance", header: signed, now: now.Add(-SignatureTolerance)},
+		{name: "clock behind past the tolerance", header: signed, now: now.Add(-SignatureTolerance - time.Second), wantErr: true},
+		{name: "replayed a day later", header: signed, now: now.Add(24 * time.Hour), wantErr: true},
+		{name: "timestamp changed", header: "t=" + strconv.FormatInt(now.Unix()+1, 10) + ",v1=" + v1, now: now, wantErr: true},
+		{name: "payload changed", payload: []byte(strings.Replace(string(testPayload), "2500", "250000", 1)), header: signed, now: now, wantErr: true},
+		{name: "other secret", header: signed, secret: "whsec_other", now: now, wantErr: true},
+		{name: "one of several signatures", header: "t=" + ts + ",v1=" + strings.Repeat("0", 64) + ",v1=" + v1, now: now},
+		{name: "other schemes are ignored", header: "t=" + ts + ",v0=" + v1 + ",v1=" + v1, now: now},
+		{name: "spaces around parts", header: " t=" + ts + " , v1=" + v1 + " ", now: now},
+		{name: "only other schemes", header: "t=" + ts + ",v0=" + v1, now: now, wantErr: true},
+		{name: "no timestamp", header: "v1=" + v1, now: now, wantErr: true},
+		{name: "bad timestamp", header: "t=yesterday,v1=" + v1, now: now, wantErr: true},
+		{name: "truncated signature", header: "t=" + ts + ",v1=" + v1[:32], now: now, wantErr: true},
+		{name: "empty header", header: "", now: now, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, secret := tt.payload, tt.secret
+			if payload == nil {
+				payload = testPayload
+			}
+			if secret == "" {
+				secret = testSecret
+			}
+			err := VerifySignature(payload, tt.header, secret, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySignature error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	now := time.Unix(1915000000, 0)
+	tests := []struct {
+		name       string
+		payload    string
+		wantErr    bool
+		wantIntent *Intent
+	}{
+		{
+			name:    "intent event",
+			payload: string(testPayload),
+			wantIntent: &Intent{ID: "pi_1", Status: IntentSucceeded, AmountCents: 2500, AmountReceivedCents: 2500,
+				Currency: "USD", ClientSecret: "pi_1_secret", Metadata: map[string]string{"invoice_id": "7"}},
+		},
+		{
+			name: "failed payment",
+			payload: `{"id":"evt_2","type":"payment_intent.payment_failed","created":1915000000,"data":{"object":` +
+				`{"id":"pi_2","status":"requires_payment_method","amount":1002,"currency":"eur","last_payment_error":{"message":"card declined"}}}}`,
+			wantIntent: &Intent{ID: "pi_2", Status: IntentRequiresPaymentMethod, AmountCents: 1002, Currency: "EUR", LastError: "card declined"},
+		},
+		{
+			name:    "event about something else",
+			payload: `{"id":"evt_3","type":"charge.refunded","created":1915000000,"data":{"object":{"id":"ch_1"}}}`,
+		},
+		{name: "not JSON", payload: `id=evt_4`, wantErr: true},
+		{name: "missing id", payload: `{"type":"payment_intent.succeeded","data":{"object":{}}}`, wantErr: true},
+		{name: "missing type", payload: `{"id":"evt_5","data":{"object":{}}}`, wantErr: true},
+		{name: "bad intent", payload: `{"id":"evt_6","type":"payment_intent.succeeded","data":{"object":{"amount":"lots"}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := []byte(tt.payload)
+			event, err := parseEvent(payload, Sign(payload, testSecret, now), testSecret, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEvent error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if event.Created.Unix() != 1915000000 {
+				t.Errorf("Created = %v, want %v", event.Created, now)
+			}
+			if !reflect.DeepEqual(event.Intent, tt.wantIntent) {
+				t.Errorf("Intent = %+v, want %+v", event.Intent, tt.wantIntent)
+			}
+		})
+	}
+}
+
+func TestParseEventRefusesBadSignatures(t *testing.T) {
+	now := time.Unix(1915000000, 0)
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "unsigned", header: ""},
+		{name: "other secret", header: Sign(testPayload, "whsec_other", now)},
+		{name: "too old", header: Sign(testPayload, testSecret, now.Add(-time.Hour))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseEvent(testPayload, tt.header, testSecret, now); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package hl7 reads and writes HL7 version 2 messages, the pipe-delimited
+// segments laboratories exchange. Parse splits any message into segments
+// and fields; the ORM^O01 orders, ORU^R01 results and ACKs this practice
+// uses have their own types.
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Version is the HL7 version written in MSH-12.
+const Version = "2.5.1"
+
+// Delimiters are the separators a message declares in MSH-1 and MSH-2.
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// DefaultDelimiters are the recommended |^~\& used in written messages.
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+// Segment is one line of a message. Segment[0] is the segment name and
+// Segment[n] is field n as written, so for MSH Segment[1] is the field
+// separator itself and Segment[2] the encoding characters.
+type Segment []string
+
+// Message is a parsed message.
+type Message struct {
+	Delimiters Delimiters
+	Segments   []Segment
+}
+
+// ErrMalformed is returned for data that is not an HL7 v2 message.
+var ErrMalformed = errors.New("malformed HL7 message")
+
+// ErrUnexpectedType is returned when a message is not of the expected type.
+var ErrUnexpectedType = errors.New("unexpected HL7 message type")
+
+// Parse splits a message into segments. Segments may end in CR, LF or CRLF.
+func Parse(data []byte) (*Message, error) {
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\r"), "\n", "\r")
+	text = strings.Trim(text, "\r\x00 ")
+	if !strings.HasPrefix(text, "MSH") || len(text) < 8 {
+		return nil, fmt.Errorf("%w: must start with an MSH segment", ErrMalformed)
+	}
+
+	d := Delimiters{Field: text[3], Component: text[4], Repetition: text[5], Escape: text[6], Subcomponent: text[7]}
+	msg := &Message{Delimiters: d}
+	for i, line := range strings.Split(text, "\r") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, string(d.Field))
+		if i == 0 {
+			// Put the field separator back as MSH-1 so field numbers match the standard.
+			fields = append([]string{fields[0], string(d.Field)}, fields[1:]...)
+		}
+		if len(fields[0]) != 3 {
+			return nil, fmt.Errorf("%w: bad segment name %q", ErrMalformed, fields[0])
+		}
+		msg.Segments = append(msg.Segments, Segment(fields))
+	}
+	return msg, nil
+}
+
+// Get returns the first segment with the given name, or nil.
+func (m *Message) Get(name string) Segment {
+	for _, seg := range m.Segments {
+		if seg[0] == name {
+			return seg
+		}
+	}
+	return nil
+}
+
+// Component returns a component (1-based) of the first repetition of a
+// field, unescaped. Missing fields and components are empty.
+func (m *Message) Component(seg Segment, field, component int) string {
+	if field >= len(seg) {
+		return ""
+	}
+	value := seg[field]
+	if seg[0] == "MSH" && field <= 2 {
+		return value
+	}
+	value = strings.SplitN(value, string(m.Delimiters.Repetition), 2)[0]
+	parts := strings.Split(value, string(m.Delimiters.Component))
+	if component > len(parts) {
+		return ""
+	}
+	return m.unescape(parts[component-1])
+}
+
+// Field returns the first component of a field, unescaped.
+func (m *Message) Field(seg Segment, field int) string {
+	return m.Component(seg, field, 1)
+}
+
+// Type returns the message type of MSH-9, such as "ORU^R01".
+func (m *Message) Type() string {
+	msh := m.Get("MSH")
+	return m.Component(msh, 9, 1) + "^" + m.Component(msh, 9, 2)
+}
+
+// Header returns the envelope of the message from its MSH segment.
+func (m *Message) Header() Header {
+	msh := m.Get("MSH")
+	t, _ := ParseTime(m.Field(msh, 7))
+	return Header{
+		SendingApplication:   m.Field(msh, 3),
+		SendingFacility:      m.Field(msh, 4),
+		ReceivingApplication: m.Field(msh, 5),
+		ReceivingFacility:    m.Field(msh, 6),
+		Time:                 t,
+		ControlID:            m.Field(msh, 10),
+		Production:           m.Field(msh, 11) == "P",
+	}
+}
+
+func (m *Message) unescape(s string) string {
+	esc := m.Delimiters.Escape
+	if strings.IndexByte(s, esc) < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != esc {
+			b.WriteByte(s[i])
+			continue
+		}
+		end := strings.IndexByte(s[i+1:], esc)
+		if end < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		switch seq := s[i+1 : i+1+end]; seq {
+		case "F":
+			b.WriteByte(m.Delimiters.Field)
+		case "S":
+			b.WriteByte(m.Delimiters.Component)
+		case "R":
+			b.WriteByte(m.Delimiters.Repetition)
+		case "T":
+			b.WriteByte(m.Delimiters.Subcomponent)
+		case "E":
+			b.WriteByte(esc)
+		case ".br":
+			b.WriteByte('\n')
+		default:
+			// Formatting and character set escapes are dropped.
+		}
+		i += end + 1
+	}
+	return b.String()
+}
+
+// Header is the envelope of a message, from its MSH segment.
+type Header struct {
+	SendingApplication   string
+	SendingFacility      string
+	ReceivingApplication string
+	ReceivingFacility    string
+	Time                 time.Time
+	// ControlID identifies the message; the receiver echoes it in its ACK.
+	ControlID string
+	// Production is false for test messages (MSH-11 "T").
+	Production bool
+}
+
+// Reply returns the header of an answer to a message with this header:
+// sender and receiver swapped.
+func (h Header) Reply(controlID string, t time.Time) Header {
+	return Header{
+		SendingApplication:   h.ReceivingApplication,
+		SendingFacility:      h.ReceivingFacility,
+		ReceivingApplication: h.SendingApplication,
+		ReceivingFacility:    h.SendingFacility,
+		Time:                 t,
+		ControlID:            controlID,
+		Production:           h.Production,
+	}
+}
+
+// msh writes the MSH segment of a message of the given type, e.g. "ORM^O01^ORM_O01".
+func (h Header) msh(messageType string) Segment {
+	processing := "T"
+	if h.Production {
+		processing = "P"
+	}
+	return Segment{"MSH", "|", `^~\&`, Escape(h.SendingApplication), Escape(h.SendingFacility),
+		Escape(h.ReceivingApplication), Escape(h.ReceivingFacility), FormatTime(h.Time), "",
+		messageType, Escape(h.ControlID), processing, Version}
+}
+
+// Encode writes segments with the default delimiters, each ending in CR.
+func Encode(segments ...Segment) []byte {
+	var b strings.Builder
+	for _, seg := range segments {
+		b.WriteString(seg[0])
+		start := 1
+		if seg[0] == "MSH" {
+			start = 2
+		}
+		for _, field := range seg[start:] {
+			b.WriteByte('|')
+			b.WriteString(field)
+		}
+		b.WriteByte('\r')
+	}
+	return []byte(b.String())
+}
+
+// Escape encodes delimiters in a value for the default delimiters.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+var escaper = strings.NewReplacer(`\`, `\E\`, `|`, `\F\`, `^`, `\S\`, `~`, `\R\`, `&`, `\T\`, "\r\n", `\.br\`, "\n", `\.br\`, "\r", `\.br\`)
+
+// Components joins escaped values into a field, dropping trailing empty components.
+func Components(values ...string) string {
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = Escape(v)
+	}
+	return strings.Join(escaped, "^")
+}
+
+// FormatTime writes a timestamp as YYYYMMDDHHMMSS with its UTC offset.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("20060102150405-0700")
+}
+
+// ParseTime reads a timestamp of any precision from a year
+// (YYYY) to fractions of a second, with or without a UTC offset.
+// Timestamps without an offset are taken as UTC.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	zone := ""
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		s, zone = s[:i], s[i:]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	layouts := map[int]string{4: "2006", 6: "200601", 8: "20060102", 10: "2006010215", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(s)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: bad timestamp %q", ErrMalformed, s+zone)
+	}
+	if zone != "" {
+		layout, s = layout+"-0700", s+zone
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: bad timestamp %q", ErrMalformed, s)
+	}
+	return t, nil
+}
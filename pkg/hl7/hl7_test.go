@@ -0,0 +1,203 @@
+// pkg/hl7/hl7_test.go
+package hl7
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		delimiters Delimiters
+		segments   []Segment
+	}{
+		{
+			name:       "CR separated",
+			data:       "MSH|^~\\&|LAB|LABFAC\rPID|1||123^^^^MR\r",
+			delimiters: DefaultDelimiters,
+			segments:   []Segment{{"MSH", "|", `^~\&`, "LAB", "LABFAC"}, {"PID", "1", "", "123^^^^MR"}},
+		},
+		{
+			name:       "LF and CRLF separated, blank lines and padding",
+			data:       "\r\n MSH|^~\\&|LAB\r\n\r\nPID|1\nOBX|1\n\x00",
+			delimiters: DefaultDelimiters,
+			segments:   []Segment{{"MSH", "|", `^~\&`, "LAB"}, {"PID", "1"}, {"OBX", "1"}},
+		},
+		{
+			name:       "custom delimiters",
+			data:       "MSH#$*!%#LAB#LABFAC\rPID#1##123$$$$MR",
+			delimiters: Delimiters{Field: '#', Component: '$', Repetition: '*', Escape: '!', Subcomponent: '%'},
+			segments:   []Segment{{"MSH", "#", "$*!%", "LAB", "LABFAC"}, {"PID", "1", "", "123$$$$MR"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if m.Delimiters != tt.delimiters {
+				t.Errorf("delimiters = %+v, want %+v", m.Delimiters, tt.delimiters)
+			}
+			if !reflect.DeepEqual(m.Segments, tt.segments) {
+				t.Errorf("segments = %q, want %q", m.Segments, tt.segments)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "whitespace", data: " \r\n"},
+		{name: "not HL7", data: "ISA*00*          *00*"},
+		{name: "no MSH", data: "PID|1||123\rOBX|1"},
+		{name: "truncated MSH", data: "MSH|^~"},
+		{name: "bad segment name", data: "MSH|^~\\&|LAB\rPIDX|1"},
+		{name: "short segment name", data: "MSH|^~\\&|LAB\rPI|1"},
+		{name: "truncated segment", data: "MSH|^~\\&|LAB\rP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); !errors.Is(err, ErrMalformed) {
+				t.Errorf("error = %v, want %v", err, ErrMalformed)
+			}
+		})
+	}
+}
+
+func TestComponent(t *testing.T) {
+	m, err := Parse([]byte("MSH|^~\\&|LAB\r" +
+		`OBX|1|ST|2345-7^Glucose^LN||Fasting \T\ random \F\ \S\ \R\ \E\ \H\bold\N\|mg/dL~mmol/L|70\.br\99|x\Y` + "\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msh, obx := m.Get("MSH"), m.Get("OBX")
+	tests := []struct {
+		name      string
+		seg       Segment
+		field     int
+		component int
+		want      string
+	}{
+		{name: "MSH-1 is the field separator", seg: msh, field: 1, component: 1, want: "|"},
+		{name: "MSH-2 is not split", seg: msh, field: 2, component: 1, want: `^~\&`},
+		{name: "first component", seg: obx, field: 3, component: 1, want: "2345-7"},
+		{name: "later component", seg: obx, field: 3, component: 3, want: "LN"},
+		{name: "missing component", seg: obx, field: 3, component: 4, want: ""},
+		{name: "missing field", seg: obx, field: 20, component: 1, want: ""},
+		{name: "escapes", seg: obx, field: 5, component: 1, want: `Fasting & random | ^ ~ \ bold`},
+		{name: "first repetition", seg: obx, field: 6, component: 1, want: "mg/dL"},
+		{name: "line break", seg: obx, field: 7, component: 1, want: "70\n99"},
+		{name: "unterminated escape", seg: obx, field: 8, component: 1, want: `x\Y`},
+		{name: "missing segment", seg: m.Get("PID"), field: 3, component: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Component(tt.seg, tt.field, tt.component); got != tt.want {
+				t.Errorf("Component(%d, %d) = %q, want %q", tt.field, tt.component, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeRoundTrip(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "plain", want: "plain"},
+		{value: `a|b^c~d\e&f`, want: `a|b^c~d\e&f`},
+		{value: `\F\`, want: `\F\`},
+		{value: "line 1\nline 2\r\nline 3\rline 4", want: "line 1\nline 2\nline 3\nline 4"},
+	}
+	for _, tt := range tests {
+		data := Encode(Segment{"MSH", "|", `^~\&`}, Segment{"NTE", "1", "", Escape(tt.value)})
+		m, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", data, err)
+		}
+		if len(m.Segments) != 2 {
+			t.Fatalf("escaped %q split into %d segments", tt.value, len(m.Segments))
+		}
+		if got := m.Field(m.Get("NTE"), 3); got != tt.want {
+			t.Errorf("round trip of %q = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestComponents(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{values: nil, want: ""},
+		{values: []string{"123", "", "", "", "MR"}, want: "123^^^^MR"},
+		{values: []string{"DOE", "JANE", "", ""}, want: "DOE^JANE"},
+		{values: []string{"O^BRIEN", "A&B"}, want: `O\S\BRIEN^A\T\B`},
+	}
+	for _, tt := range tests {
+		if got := Components(tt.values...); got != tt.want {
+			t.Errorf("Components(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "", want: time.Time{}},
+		{in: "2030", want: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "203009", want: time.Date(2030, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "20300916", want: time.Date(2030, 9, 16, 0, 0, 0, 0, time.UTC)},
+		{in: "2030091614", want: time.Date(2030, 9, 16, 14, 0, 0, 0, time.UTC)},
+		{in: "203009161430", want: time.Date(2030, 9, 16, 14, 30, 0, 0, time.UTC)},
+		{in: "20300916143015", want: time.Date(2030, 9, 16, 14, 30, 15, 0, time.UTC)},
+		{in: "20300916143015.1234", want: time.Date(2030, 9, 16, 14, 30, 15, 0, time.UTC)},
+		{in: "20300916143015-0500", want: time.Date(2030, 9, 16, 19, 30, 15, 0, time.UTC)},
+		{in: "203009161430+0200", want: time.Date(2030, 9, 16, 12, 30, 0, 0, time.UTC)},
+		{in: " 20300916 ", want: time.Date(2030, 9, 16, 0, 0, 0, 0, time.UTC)},
+		{in: "203", wantErr: true},
+		{in: "2030091", wantErr: true},
+		{in: "20301345", wantErr: true},
+		{in: "2030-09-16", wantErr: true},
+		{in: "20300916-05", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTime(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTimeRoundTrip(t *testing.T) {
+	tests := []time.Time{
+		time.Date(2030, 9, 16, 14, 30, 15, 0, time.UTC),
+		time.Date(2030, 9, 16, 14, 30, 15, 0, time.FixedZone("EST", -5*3600)),
+		time.Date(2030, 1, 1, 0, 0, 0, 0, time.FixedZone("IST", 5*3600+1800)),
+	}
+	for _, want := range tests {
+		got, err := ParseTime(FormatTime(want))
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTime(FormatTime(%v)) = %v, %v", want, got, err)
+		}
+	}
+	if s := FormatTime(time.Time{}); s != "" {
+		t.Errorf("FormatTime(zero) = %q, want empty", s)
+	}
+}
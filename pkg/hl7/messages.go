@@ -0,0 +1,261 @@
+// pkg/hl7/messages.go
+package hl7
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Acknowledgment codes of MSA-1.
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// Result statuses of OBR-25 and OBX-11.
+const (
+	StatusFinal       = "F"
+	StatusPreliminary = "P"
+	StatusCorrected   = "C"
+)
+
+// Patient identifies the patient of a message in its PID segment.
+type Patient struct {
+	// ID is the medical record number (PID-3).
+	ID         string
+	FamilyName string
+	GivenName  string
+	// DateOfBirth is YYYY-MM-DD; written as YYYYMMDD.
+	DateOfBirth string
+	// Sex is F, M, O or U (HL7 table 0001).
+	Sex string
+}
+
+// Provider is the ordering provider of ORC-12 and OBR-16.
+type Provider struct {
+	ID         string
+	FamilyName string
+	GivenName  string
+}
+
+// Test is an orderable test or an observation, coded in System ("LN" for LOINC).
+type Test struct {
+	Code   string
+	Name   string
+	System string
+}
+
+// Order is a new order (ORM^O01): one ORC/OBR pair per test, all under
+// the same placer order number.
+type Order struct {
+	Header
+	Patient           Patient
+	PlacerOrderNumber string
+	Provider          Provider
+	// Stat orders are written with priority S, others with R.
+	Stat         bool
+	ClinicalInfo string
+	Tests        []Test
+}
+
+// Observation is one OBX result.
+type Observation struct {
+	ValueType      string
+	Test           Test
+	Value          string
+	Units          string
+	ReferenceRange string
+	// Flag is the abnormal flag of OBX-8: N, L, H, LL, HH, A, AA or empty.
+	Flag   string
+	Status string
+	Time   time.Time
+}
+
+// Report is the result of one ordered test: an OBR and its OBX segments.
+type Report struct {
+	PlacerOrderNumber string
+	FillerOrderNumber string
+	Test              Test
+	// Status is the result status of OBR-25.
+	Status       string
+	Time         time.Time
+	Observations []Observation
+}
+
+// Results is an unsolicited observation message (ORU^R01).
+type Results struct {
+	Header
+	Patient Patient
+	Reports []Report
+}
+
+// Ack is the acknowledgment of a message.
+type Ack struct {
+	Header
+	Code string
+	// AckedControlID is the control ID of the acknowledged message.
+	AckedControlID string
+	Text           string
+}
+
+// Accepted reports whether the message was accepted (AA, or the
+// enhanced-mode CA).
+func (a *Ack) Accepted() bool {
+	return a.Code == AckAccept || a.Code == "CA"
+}
+
+// BuildORM writes an order.
+func BuildORM(o Order) []byte {
+	priority := "R"
+	if o.Stat {
+		priority = "S"
+	}
+	provider := Components(o.Provider.ID, o.Provider.FamilyName, o.Provider.GivenName)
+
+	segments := []Segment{o.Header.msh("ORM^O01^ORM_O01"), o.Patient.pid(), {"PV1", "1", "O"}}
+	for i, test := range o.Tests {
+		segments = append(segments,
+			Segment{"ORC", "NW", Escape(o.PlacerOrderNumber), "", "", "", "", Components("", "", "", "", "", priority),
+				"", FormatTime(o.Time), "", "", provider},
+			Segment{"OBR", strconv.Itoa(i + 1), Escape(o.PlacerOrderNumber), "", test.field(), priority, "",
+				"", "", "", "", "", "", Escape(o.ClinicalInfo), "", "", provider})
+	}
+	return Encode(segments...)
+}
+
+// ReadORM reads an order.
+func ReadORM(m *Message) (*Order, error) {
+	if m.Type() != "ORM^O01" {
+		return nil, fmt.Errorf("%w: %s, want ORM^O01", ErrUnexpectedType, m.Type())
+	}
+	o := &Order{Header: m.Header(), Patient: m.patient()}
+	for _, seg := range m.Segments {
+		if seg[0] != "OBR" {
+			continue
+		}
+		o.PlacerOrderNumber = m.Field(seg, 2)
+		o.Stat = m.Field(seg, 5) == "S"
+		o.ClinicalInfo = m.Field(seg, 13)
+		o.Provider = Provider{ID: m.Component(seg, 16, 1), FamilyName: m.Component(seg, 16, 2), GivenName: m.Component(seg, 16, 3)}
+		o.Tests = append(o.Tests, m.test(seg, 4))
+	}
+	if len(o.Tests) == 0 {
+		return nil, fmt.Errorf("%w: order without OBR segments", ErrMalformed)
+	}
+	return o, nil
+}
+
+// BuildORU writes results.
+func BuildORU(r Results) []byte {
+	segments := []Segment{r.Header.msh("ORU^R01^ORU_R01"), r.Patient.pid()}
+	for i, report := range r.Reports {
+		obr := Segment{"OBR", strconv.Itoa(i + 1), Escape(report.PlacerOrderNumber), Escape(report.FillerOrderNumber),
+			report.Test.field(), "", "", FormatTime(report.Time)}
+		for len(obr) < 25 {
+			obr = append(obr, "")
+		}
+		obr[22] = FormatTime(report.Time)
+		obr = append(obr, report.Status)
+		segments = append(segments, obr)
+
+		for j, obs := range report.Observations {
+			segments = append(segments, Segment{"OBX", strconv.Itoa(j + 1), obs.ValueType, obs.Test.field(), "",
+				Escape(obs.Value), Escape(obs.Units), Escape(obs.ReferenceRange), obs.Flag, "", "", obs.Status,
+				"", "", FormatTime(obs.Time)})
+		}
+	}
+	return Encode(segments...)
+}
+
+// ReadORU reads results. Observations belong to the OBR they follow.
+func ReadORU(m *Message) (*Results, error) {
+	if m.Type() != "ORU^R01" {
+		return nil, fmt.Errorf("%w: %s, want ORU^R01", ErrUnexpectedType, m.Type())
+	}
+	r := &Results{Header: m.Header(), Patient: m.patient()}
+	for _, seg := range m.Segments {
+		switch seg[0] {
+		case "OBR":
+			t, err := ParseTime(m.Field(seg, 7))
+			if err != nil {
+				return nil, err
+			}
+			r.Reports = append(r.Reports, Report{
+				PlacerOrderNumber: m.Field(seg, 2),
+				FillerOrderNumber: m.Field(seg, 3),
+				Test:              m.test(seg, 4),
+				Status:            m.Field(seg, 25),
+				Time:              t,
+			})
+		case "OBX":
+			if len(r.Reports) == 0 {
+				return nil, fmt.Errorf("%w: OBX before any OBR", ErrMalformed)
+			}
+			t, err := ParseTime(m.Field(seg, 14))
+			if err != nil {
+				return nil, err
+			}
+			report := &r.Reports[len(r.Reports)-1]
+			report.Observations = append(report.Observations, Observation{
+				ValueType:      m.Field(seg, 2),
+				Test:           m.test(seg, 3),
+				Value:          m.Field(seg, 5),
+				Units:          m.Field(seg, 6),
+				ReferenceRange: m.Field(seg, 7),
+				Flag:           m.Field(seg, 8),
+				Status:         m.Field(seg, 11),
+				Time:           t,
+			})
+		}
+	}
+	if len(r.Reports) == 0 {
+		return nil, fmt.Errorf("%w: results without OBR segments", ErrMalformed)
+	}
+	return r, nil
+}
+
+// BuildACK writes an acknowledgment. Its header is usually the Reply of
+// the acknowledged message's header.
+func BuildACK(a Ack) []byte {
+	return Encode(a.Header.msh("ACK"), Segment{"MSA", a.Code, Escape(a.AckedControlID), Escape(a.Text)})
+}
+
+// ReadACK reads an acknowledgment.
+func ReadACK(m *Message) (*Ack, error) {
+	msa := m.Get("MSA")
+	if m.Field(m.Get("MSH"), 9) != "ACK" || msa == nil {
+		return nil, fmt.Errorf("%w: %s, want ACK", ErrUnexpectedType, m.Type())
+	}
+	return &Ack{Header: m.Header(), Code: m.Field(msa, 1), AckedControlID: m.Field(msa, 2), Text: m.Field(msa, 3)}, nil
+}
+
+func (p Patient) pid() Segment {
+	return Segment{"PID", "1", "", Components(p.ID, "", "", "", "MR"), "", Components(p.FamilyName, p.GivenName),
+		"", strings.ReplaceAll(p.DateOfBirth, "-", ""), p.Sex}
+}
+
+func (m *Message) patient() Patient {
+	pid := m.Get("PID")
+	dob := m.Field(pid, 7)
+	if len(dob) >= 8 {
+		dob = dob[:4] + "-" + dob[4:6] + "-" + dob[6:8]
+	}
+	return Patient{
+		ID:          m.Field(pid, 3),
+		FamilyName:  m.Component(pid, 5, 1),
+		GivenName:   m.Component(pid, 5, 2),
+		DateOfBirth: dob,
+		Sex:         m.Field(pid, 8),
+	}
+}
+
+func (t Test) field() string {
+	return Components(t.Code, t.Name, t.System)
+}
+
+func (m *Message) test(seg Segment, field int) Test {
+	return Test{Code: m.Component(seg, field, 1), Name: m.Component(seg, field, 2), System: m.Component(seg, field, 3)}
+}
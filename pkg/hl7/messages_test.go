@@ -0,0 +1,248 @@
+// pkg/hl7/messages_test.go
+package hl7
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testHeader = Header{
+	SendingApplication:   "DOCTORSAAS",
+	SendingFacility:      "CLINIC",
+	ReceivingApplication: "LIS",
+	ReceivingFacility:    "LAB",
+	Time:                 time.Date(2030, 9, 16, 14, 30, 15, 0, time.UTC),
+	ControlID:            "MSG0001",
+}
+
+var testPatient = Patient{ID: "MRN-000123", FamilyName: "DOE", GivenName: "JANE", DateOfBirth: "1980-04-02", Sex: "F"}
+
+func TestORMRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+	}{
+		{
+			name: "routine order",
+			order: Order{
+				Header: testHeader, Patient: testPatient, PlacerOrderNumber: "LAB-1",
+				Provider: Provider{ID: "1234567893", FamilyName: "SMITH", GivenName: "ANNA"},
+				Tests:    []Test{{Code: "2345-7", Name: "Glucose", System: "LN"}},
+			},
+		},
+		{
+			name: "stat order with several tests",
+			order: Order{
+				Header: testHeader, Patient: testPatient, PlacerOrderNumber: "LAB-2",
+				Provider: Provider{ID: "1234567893", FamilyName: "SMITH", GivenName: "ANNA"},
+				Stat:     true, ClinicalInfo: "Fasting since 8pm",
+				Tests: []Test{
+					{Code: "2345-7", Name: "Glucose", System: "LN"},
+					{Code: "4548-4", Name: "Hemoglobin A1c/Hemoglobin.total", System: "LN"},
+				},
+			},
+		},
+		{
+			name: "delimiters in values",
+			order: Order{
+				Header: Header{SendingApplication: "A|B", SendingFacility: "C^D", ReceivingApplication: "LIS",
+					ReceivingFacility: "LAB", Time: testHeader.Time, ControlID: "MSG~2", Production: true},
+				Patient:           Patient{ID: "MRN&1", FamilyName: "O^BRIEN", GivenName: "SEAN", Sex: "M"},
+				PlacerOrderNumber: `LAB\3`,
+				Provider:          Provider{ID: "1", FamilyName: "SMITH|JONES"},
+				ClinicalInfo:      "line 1\nline 2",
+				Tests:             []Test{{Code: "X&Y", Name: "Panel ^ 2", System: "L"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(BuildORM(tt.order))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := ReadORM(m)
+			if err != nil {
+				t.Fatalf("ReadORM: %v", err)
+			}
+			got.Time = got.Time.UTC()
+			if !reflect.DeepEqual(*got, tt.order) {
+				t.Errorf("ReadORM(BuildORM()) =\n%+v\nwant\n%+v", *got, tt.order)
+			}
+		})
+	}
+}
+
+func TestORURoundTrip(t *testing.T) {
+	collected := time.Date(2030, 9, 16, 8, 0, 0, 0, time.UTC)
+	resulted := time.Date(2030, 9, 16, 11, 45, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		results Results
+	}{
+		{
+			name: "one report",
+			results: Results{
+				Header: testHeader, Patient: testPatient,
+				Reports: []Report{{
+					PlacerOrderNumber: "LAB-1", FillerOrderNumber: "F-9", Test: Test{Code: "2345-7", Name: "Glucose", System: "LN"},
+					Status: StatusFinal, Time: collected,
+					Observations: []Observation{{
+						ValueType: "NM", Test: Test{Code: "2345-7", Name: "Glucose", System: "LN"}, Value: "182",
+						Units: "mg/dL", ReferenceRange: "70-99", Flag: "H", Status: StatusFinal, Time: resulted,
+					}},
+				}},
+			},
+		},
+		{
+			name: "several reports and a text result",
+			results: Results{
+				Header: testHeader, Patient: testPatient,
+				Reports: []Report{
+					{
+						PlacerOrderNumber: "LAB-2", FillerOrderNumber: "F-10", Test: Test{Code: "24331-1", Name: "Lipid panel", System: "LN"},
+						Status: StatusPreliminary, Time: collected,
+						Observations: []Observation{
+							{ValueType: "NM", Test: Test{Code: "2093-3", Name: "Cholesterol", System: "LN"}, Value: "210",
+								Units: "mg/dL", ReferenceRange: "<200", Flag: "H", Status: StatusPreliminary, Time: resulted},
+							{ValueType: "NM", Test: Test{Code: "2571-8", Name: "Triglyceride", System: "LN"}, Value: "120",
+								Units: "mg/dL", ReferenceRange: "<150", Flag: "N", Status: StatusPreliminary},
+						},
+					},
+					{
+						PlacerOrderNumber: "LAB-2", FillerOrderNumber: "F-11", Test: Test{Code: "11502-2", Name: "Lab report", System: "LN"},
+						Status: StatusCorrected,
+						Observations: []Observation{
+							{ValueType: "TX", Test: Test{Code: "11502-2"}, Value: "Sample slightly hemolyzed.\nRepeat if | ^ ~ & \\ matter.",
+								Status: StatusCorrected},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "report without observations",
+			results: Results{
+				Header:  testHeader,
+				Patient: Patient{ID: "MRN-1"},
+				Reports: []Report{{PlacerOrderNumber: "LAB-3", Test: Test{Code: "2345-7"}, Status: "X"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(BuildORU(tt.results))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := ReadORU(m)
+			if err != nil {
+				t.Fatalf("ReadORU: %v", err)
+			}
+			normalizeResults(got)
+			if !reflect.DeepEqual(*got, tt.results) {
+				t.Errorf("ReadORU(BuildORU()) =\n%+v\nwant\n%+v", *got, tt.results)
+			}
+		})
+	}
+}
+
+func TestACKRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		ack          Ack
+		wantAccepted bool
+	}{
+		{name: "accepted", ack: Ack{Header: testHeader.Reply("ACK1", testHeader.Time), Code: AckAccept, AckedControlID: "MSG0001"}, wantAccepted: true},
+		{name: "error", ack: Ack{Header: testHeader.Reply("ACK2", testHeader.Time), Code: AckError, AckedControlID: "MSG0001",
+			Text: "Unknown test 2345-7|LN"}},
+		{name: "rejected", ack: Ack{Header: testHeader.Reply("ACK3", testHeader.Time), Code: AckReject, AckedControlID: "MSG0001",
+			Text: "Unsupported version"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(BuildACK(tt.ack))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := ReadACK(m)
+			if err != nil {
+				t.Fatalf("ReadACK: %v", err)
+			}
+			got.Time = got.Time.UTC()
+			if !reflect.DeepEqual(*got, tt.ack) {
+				t.Errorf("ReadACK(BuildACK()) = %+v, want %+v", *got, tt.ack)
+			}
+			if got.Accepted() != tt.wantAccepted {
+				t.Errorf("Accepted() = %v, want %v", got.Accepted(), tt.wantAccepted)
+			}
+			if got.ReceivingApplication != testHeader.SendingApplication || got.SendingFacility != testHeader.ReceivingFacility {
+				t.Errorf("reply header = %+v, want the sender and receiver of %+v swapped", got.Header, testHeader)
+			}
+		})
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	order := BuildORM(Order{Header: testHeader, Patient: testPatient, PlacerOrderNumber: "LAB-1", Tests: []Test{{Code: "2345-7"}}})
+	results := BuildORU(Results{Header: testHeader, Patient: testPatient, Reports: []Report{{PlacerOrderNumber: "LAB-1"}}})
+	msh := string(Encode(testHeader.msh("ORU^R01^ORU_R01")))
+	tests := []struct {
+		name    string
+		read    func(*Message) error
+		data    string
+		wantErr error
+	}{
+		{name: "ORM given an ORU", read: readORM, data: string(results), wantErr: ErrUnexpectedType},
+		{name: "ORM without OBR", read: readORM, data: strings.Split(string(order), "ORC|")[0], wantErr: ErrMalformed},
+		{name: "ORU given an ORM", read: readORU, data: string(order), wantErr: ErrUnexpectedType},
+		{name: "ORU without OBR", read: readORU, data: msh + "PID|1||MRN-1\r", wantErr: ErrMalformed},
+		{name: "ORU with OBX before OBR", read: readORU, data: msh + "OBX|1|NM|2345-7||182\rOBR|1|LAB-1\r", wantErr: ErrMalformed},
+		{name: "ORU with a bad OBR time", read: readORU, data: msh + "OBR|1|LAB-1||2345-7|||2030-09-16\r", wantErr: ErrMalformed},
+		{name: "ORU with a bad OBX time", read: readORU,
+			data: msh + "OBR|1|LAB-1||2345-7\rOBX|1|NM|2345-7||182|||||||||yesterday\r", wantErr: ErrMalformed},
+		{name: "ACK given an ORU", read: readACK, data: string(results), wantErr: ErrUnexpectedType},
+		{name: "ACK without MSA", read: readACK, data: string(Encode(testHeader.msh("ACK"))), wantErr: ErrUnexpectedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := tt.read(m); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// normalizeResults puts the parsed times in UTC, like those of the tests.
+func normalizeResults(r *Results) {
+	r.Time = r.Time.UTC()
+	for i := range r.Reports {
+		report := &r.Reports[i]
+		report.Time = report.Time.UTC()
+		for j := range report.Observations {
+			report.Observations[j].Time = report.Observations[j].Time.UTC()
+		}
+	}
+}
+
+func readORM(m *Message) error {
+	_, err := ReadORM(m)
+	return err
+}
+
+func readORU(m *Message) error {
+	_, err := ReadORU(m)
+	return err
+}
+
+func readACK(m *Message) error {
+	_, err := ReadACK(m)
+	return err
+}
@@ -0,0 +1,73 @@
+// Package lab sends orders to a reference laboratory. Laboratories take
+// HL7 v2 ORM^O01 orders over MLLP and send ORU^R01 results back to a
+// listener of ours; Loopback stands in for one during development.
+package lab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"doctors/pkg/hl7"
+	"doctors/pkg/mllp"
+)
+
+// Client sends orders to a laboratory.
+type Client interface {
+	// SendOrder sends an ORM^O01 message and waits for the laboratory's ACK.
+	SendOrder(ctx context.Context, message []byte) error
+}
+
+// ErrUnavailable is returned when the laboratory can't be reached or
+// doesn't answer in time; the order may be sent again.
+var ErrUnavailable = errors.New("laboratory unavailable")
+
+// ErrRejected is returned when the laboratory answers with a negative
+// acknowledgment.
+var ErrRejected = errors.New("laboratory rejected the order")
+
+// MLLPClient sends orders to a laboratory's MLLP endpoint.
+type MLLPClient struct {
+	addr string
+}
+
+func NewMLLPClient(addr string) *MLLPClient {
+	return &MLLPClient{addr: addr}
+}
+
+func (c *MLLPClient) SendOrder(ctx context.Context, message []byte) error {
+	reply, err := mllp.Send(ctx, c.addr, message)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	msg, err := hl7.Parse(reply)
+	if err != nil {
+		return fmt.Errorf("%w: unreadable acknowledgment: %v", ErrUnavailable, err)
+	}
+	ack, err := hl7.ReadACK(msg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if !ack.Accepted() {
+		return fmt.Errorf("%w: %s %s", ErrRejected, ack.Code, ack.Text)
+	}
+	return nil
+}
+
+// New returns the client of the given kind: "mllp" talks to the
+// laboratory at addr, "loopback" starts a Loopback stand-in that sends
+// its results to resultsAddr.
+func New(kind, addr, resultsAddr string) (Client, error) {
+	switch kind {
+	case "mllp":
+		if addr == "" {
+			return nil, errors.New("laboratory address is required")
+		}
+		return NewMLLPClient(addr), nil
+	case "loopback":
+		return NewLoopback(resultsAddr, 5*time.Second)
+	default:
+		return nil, fmt.Errorf("unknown laboratory client %q", kind)
+	}
+}
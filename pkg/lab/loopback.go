@@ -0,0 +1,115 @@
+// pkg/lab/loopback.go
+package lab
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"doctors/pkg/hl7"
+	"doctors/pkg/mllp"
+)
+
+// Loopback is a local stand-in for a laboratory. It listens on a loopback
+// port, accepts every well-formed order, and after a delay sends final
+// results for each test to resultsAddr: canned values for a few common
+// tests, one of them high so abnormal flags show up, and "Normal" for the
+// others.
+type Loopback struct {
+	*MLLPClient
+	listener    net.Listener
+	resultsAddr string
+	delay       time.Duration
+	cancel      context.CancelFunc
+	sequence    atomic.Int64
+}
+
+func NewLoopback(resultsAddr string, delay time.Duration) (*Loopback, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("loopback laboratory: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	lb := &Loopback{
+		MLLPClient:  NewMLLPClient(l.Addr().String()),
+		listener:    l,
+		resultsAddr: resultsAddr,
+		delay:       delay,
+		cancel:      cancel,
+	}
+	go func() {
+		if err := mllp.Serve(ctx, l, lb.handle); err != nil {
+			log.Printf("loopback laboratory stopped: %v", err)
+		}
+	}()
+	return lb, nil
+}
+
+// Close stops the listener.
+func (lb *Loopback) Close() error {
+	lb.cancel()
+	return nil
+}
+
+// cannedResults are the loopback results by LOINC code.
+var cannedResults = map[string]hl7.Observation{
+	"2345-7": {ValueType: "NM", Value: "92", Units: "mg/dL", ReferenceRange: "70-99", Flag: "N"},
+	"718-7":  {ValueType: "NM", Value: "13.9", Units: "g/dL", ReferenceRange: "12.0-17.5", Flag: "N"},
+	"2093-3": {ValueType: "NM", Value: "232", Units: "mg/dL", ReferenceRange: "<200", Flag: "H"},
+	"2160-0": {ValueType: "NM", Value: "0.9", Units: "mg/dL", ReferenceRange: "0.6-1.2", Flag: "N"},
+	"6690-2": {ValueType: "NM", Value: "6.4", Units: "10*3/uL", ReferenceRange: "4.5-11.0", Flag: "N"},
+}
+
+func (lb *Loopback) handle(ctx context.Context, message []byte) []byte {
+	ack := hl7.Ack{Code: hl7.AckAccept}
+	msg, err := hl7.Parse(message)
+	if err != nil {
+		ack.Code, ack.Text = hl7.AckReject, err.Error()
+		return hl7.BuildACK(ack)
+	}
+	ack.Header = msg.Header().Reply(lb.controlID(), time.Now())
+	ack.AckedControlID = msg.Header().ControlID
+
+	order, err := hl7.ReadORM(msg)
+	if err != nil {
+		ack.Code, ack.Text = hl7.AckReject, err.Error()
+		return hl7.BuildACK(ack)
+	}
+	time.AfterFunc(lb.delay, func() { lb.sendResults(order) })
+	return hl7.BuildACK(ack)
+}
+
+func (lb *Loopback) sendResults(order *hl7.Order) {
+	now := time.Now()
+	report := hl7.Report{
+		PlacerOrderNumber: order.PlacerOrderNumber,
+		FillerOrderNumber: "LB" + order.PlacerOrderNumber,
+		Status:            hl7.StatusFinal,
+		Time:              now,
+	}
+	results := hl7.Results{Header: order.Header.Reply(lb.controlID(), now), Patient: order.Patient}
+	for _, test := range order.Tests {
+		obs, ok := cannedResults[test.Code]
+		if !ok {
+			obs = hl7.Observation{ValueType: "ST", Value: "Normal", Flag: "N"}
+		}
+		obs.Test, obs.Status, obs.Time = test, hl7.StatusFinal, now
+		report.Test = test
+		report.Observations = []hl7.Observation{obs}
+		results.Reports = append(results.Reports, report)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mllp.DefaultTimeout)
+	defer cancel()
+	if _, err := mllp.Send(ctx, lb.resultsAddr, hl7.BuildORU(results)); err != nil {
+		log.Printf("loopback laboratory: failed to send results of %s: %v", order.PlacerOrderNumber, err)
+	}
+}
+
+func (lb *Loopback) controlID() string {
+	return "LB" + strconv.FormatInt(lb.sequence.Add(1), 10)
+}
@@ -0,0 +1,48 @@
+// pkg/mergepatch/mergepatch.go
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotObject is returned when a patch for a resource is not a JSON object.
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply applies an RFC 7396 JSON Merge Patch to doc: members of the patch
+// replace members of the document, null removes them, and nested objects
+// are merged recursively.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, ErrNotObject
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = merge(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
@@ -0,0 +1,79 @@
+// pkg/mergepatch/mergepatch_test.go
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	// Mostly the examples of RFC 7396, appendix A, with object patches.
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes member", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"null removes only that member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"null for a missing member", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"array is replaced", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"value becomes array", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"arrays are not merged", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"nested objects merge", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"nested null removes nested member", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null}}`, `{"a":{"b":"c"}}`},
+		{"object replaces scalar", `{"a":"b"}`, `{"a":{"c":"d"}}`, `{"a":{"c":"d"}}`},
+		{"nulls inside new objects are dropped", `{"e":null}`, `{"a":1,"b":{"c":null}}`, `{"e":null,"a":1,"b":{}}`},
+		{"document that isn't an object", `["a","b"]`, `{"a":"b"}`, `{"a":"b"}`},
+		{"empty document", ``, `{"a":"b"}`, `{"a":"b"}`},
+		{"empty patch", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			var gotValue, wantValue interface{}
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatalf("result %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("Apply = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		patch  string
+		wantIs error
+	}{
+		{"array patch", `{"a":"b"}`, `["c"]`, ErrNotObject},
+		{"scalar patch", `{"a":"b"}`, `"c"`, ErrNotObject},
+		{"null patch", `{"a":"b"}`, `null`, ErrNotObject},
+		{"invalid patch", `{"a":"b"}`, `{"a":`, nil},
+		{"invalid document", `{"a":`, `{"a":"b"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err == nil {
+				t.Fatal("Apply succeeded, want an error")
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("err = %v, want %v", err, tt.wantIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package mllp carries HL7 v2 messages over TCP with the Minimal Lower
+// Layer Protocol: each message is framed as <VT> message <FS><CR>, and
+// every message is answered on the same connection, usually with an ACK.
+package mllp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	startBlock = 0x0b
+	endBlock   = 0x1c
+	endData    = 0x0d
+)
+
+// MaxMessageSize bounds a frame so a peer can't exhaust memory.
+const MaxMessageSize = 1 << 20
+
+// DefaultTimeout bounds an exchange when the context has no deadline.
+const DefaultTimeout = 30 * time.Second
+
+// ErrFrame is returned for data that is not a valid frame.
+var ErrFrame = errors.New("invalid MLLP frame")
+
+// WriteFrame writes one framed message.
+func WriteFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, message...)
+	frame = append(frame, endBlock, endData)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads the next framed message. Bytes before the start block
+// are skipped, as some peers send line breaks between frames.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var message bytes.Buffer
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: connection closed inside a frame", ErrFrame)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == endBlock {
+			if next, err := r.ReadByte(); err != nil || next != endData {
+				return nil, fmt.Errorf("%w: end block not followed by carriage return", ErrFrame)
+			}
+			return message.Bytes(), nil
+		}
+		if message.Len() == MaxMessageSize {
+			return nil, fmt.Errorf("%w: message larger than %d bytes", ErrFrame, MaxMessageSize)
+		}
+		message.WriteByte(b)
+	}
+}
+
+// Send connects to addr, sends a message and returns the reply.
+func Send(ctx context.Context, addr string, message []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := WriteFrame(conn, message); err != nil {
+		return nil, err
+	}
+	return ReadFrame(bufio.NewReader(conn))
+}
+
+// Handler processes a received message and returns the reply to send.
+type Handler func(ctx context.Context, message []byte) []byte
+
+// Serve accepts connections on l and answers each message on them with
+// h until ctx is done, then closes l.
+func Serve(ctx context.Context, l net.Listener, h Handler) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+		go serveConn(ctx, conn, h)
+	}
+}
+
+// ListenAndServe listens on addr and calls Serve.
+func ListenAndServe(ctx context.Context, addr string, h Handler) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, l, h)
+}
+
+// idleTimeout closes connections that send nothing for this long.
+const idleTimeout = 5 * time.Minute
+
+func serveConn(ctx context.Context, conn net.Conn, h Handler) {
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
+			return
+		}
+		message, err := ReadFrame(r)
+		if err != nil {
+			return
+		}
+		reply := h(ctx, message)
+		if reply == nil {
+			continue
+		}
+		if err := conn.SetWriteDeadline(time.Now().Add(DefaultTimeout)); err != nil {
+			return
+		}
+		if err := WriteFrame(conn, reply); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// pkg/ratelimit/limiter.go
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens are added per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// Store keeps bucket state so that limits can be shared across replicas.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type Limiter struct {
+	store  Store
+	def    Limit
+	routes map[string]Limit
+}
+
+// NewLimiter creates a limiter using def for every route not listed in routes.
+// Route keys have the form "METHOD /path/:param", matching gin's FullPath.
+func NewLimiter(store Store, def Limit, routes map[string]Limit) *Limiter {
+	if routes == nil {
+		routes = map[string]Limit{}
+	}
+	return &Limiter{store: store, def: def, routes: routes}
+}
+
+// LimitFor returns the limit configured for a route.
+func (l *Limiter) LimitFor(route string) Limit {
+	if limit, ok := l.routes[route]; ok {
+		return limit
+	}
+	return l.def
+}
+
+// Allow takes a token for client on route.
+func (l *Limiter) Allow(ctx context.Context, client, route string) (Result, error) {
+	limit := l.LimitFor(route)
+	return l.store.Take(ctx, route+"|"+client, limit, time.Now())
+}
+
+// refill computes the bucket state after elapsed time and one take attempt.
+func refill(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	burst := float64(limit.Burst)
+	tokens = math.Min(burst, tokens+elapsed.Seconds()*limit.Rate)
+
+	res := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else if limit.Rate > 0 {
+		res.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	} else {
+		res.RetryAfter = time.Hour
+	}
+	res.Remaining = int(math.Floor(tokens))
+	if limit.Rate > 0 {
+		res.ResetAfter = time.Duration((burst - tokens) / limit.Rate * float64(time.Second))
+	}
+	return tokens, res
+}
+
+// idleFor is how long a bucket left in state res goes unused before it is
+// full again: forgetting it then changes nothing. Buckets that never
+// refill are kept for a day.
+func idleFor(limit Limit, res Result) time.Duration {
+	if limit.Rate <= 0 {
+		return 24 * time.Hour
+	}
+	return res.ResetAfter
+}
+
+// ParseLimit parses "rate:burst", e.g. "5:10" for 5 requests per second with bursts of 10.
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected rate:burst", s)
+	}
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || rate < 0 {
+		return Limit{}, fmt.Errorf("invalid rate in %q", s)
+	}
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("invalid burst in %q", s)
+	}
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseRoutes parses a comma separated list of "METHOD /path=rate:burst" entries.
+func ParseRoutes(s string) (map[string]Limit, error) {
+	routes := map[string]Limit{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		idx := strings.LastIndex(entry, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected METHOD /path=rate:burst", entry)
+		}
+		limit, err := ParseLimit(entry[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.Join(strings.Fields(entry[:idx]), " ")] = limit
+	}
+	return routes, nil
+}
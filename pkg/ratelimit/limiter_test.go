@@ -0,0 +1,150 @@
+// pkg/ratelimit/limiter_test.go
+package ratelimit
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRefill(t *testing.T) {
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		limit      Limit
+		wantTokens float64
+		want       Result
+	}{
+		{
+			name:       "full bucket allows",
+			tokens:     5,
+			limit:      Limit{Rate: 1, Burst: 5},
+			wantTokens: 4,
+			want:       Result{Allowed: true, Limit: 5, Remaining: 4, ResetAfter: time.Second},
+		},
+		{
+			name:       "refill is capped at burst",
+			tokens:     4,
+			elapsed:    time.Hour,
+			limit:      Limit{Rate: 1, Burst: 5},
+			wantTokens: 4,
+			want:       Result{Allowed: true, Limit: 5, Remaining: 4, ResetAfter: time.Second},
+		},
+		{
+			name:       "partial refill allows",
+			tokens:     0.5,
+			elapsed:    250 * time.Millisecond,
+			limit:      Limit{Rate: 2, Burst: 3},
+			wantTokens: 0,
+			want:       Result{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: 1500 * time.Millisecond},
+		},
+		{
+			name:       "empty bucket waits for the next token",
+			tokens:     0,
+			elapsed:    250 * time.Millisecond,
+			limit:      Limit{Rate: 2, Burst: 3},
+			wantTokens: 0.5,
+			want:       Result{Limit: 3, RetryAfter: 250 * time.Millisecond, ResetAfter: 1250 * time.Millisecond},
+		},
+		{
+			name:       "zero rate never refills",
+			tokens:     0,
+			elapsed:    time.Hour,
+			limit:      Limit{Rate: 0, Burst: 1},
+			wantTokens: 0,
+			want:       Result{Limit: 1, RetryAfter: time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, res := refill(tt.tokens, tt.elapsed, tt.limit)
+			if math.Abs(tokens-tt.wantTokens) > 1e-9 {
+				t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if !reflect.DeepEqual(res, tt.want) {
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdleFor(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+		res   Result
+		want  time.Duration
+	}{
+		{"refilling bucket", Limit{Rate: 2, Burst: 3}, Result{ResetAfter: 1500 * time.Millisecond}, 1500 * time.Millisecond},
+		{"full bucket", Limit{Rate: 2, Burst: 3}, Result{}, 0},
+		{"bucket that never refills", Limit{Rate: 0, Burst: 3}, Result{}, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idleFor(tt.limit, tt.res); got != tt.want {
+				t.Errorf("idleFor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "5:10", want: Limit{Rate: 5, Burst: 10}},
+		{in: " 0.5:1 ", want: Limit{Rate: 0.5, Burst: 1}},
+		{in: "0:1", want: Limit{Rate: 0, Burst: 1}},
+		{in: "5", wantErr: true},
+		{in: "-1:10", wantErr: true},
+		{in: "5:0", wantErr: true},
+		{in: "five:10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]Limit
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: map[string]Limit{}},
+		{
+			name: "several routes",
+			in:   "GET /api/v1/patients/=2:5, POST  /api/v1/appointments/=1:5,",
+			want: map[string]Limit{
+				"GET /api/v1/patients/":      {Rate: 2, Burst: 5},
+				"POST /api/v1/appointments/": {Rate: 1, Burst: 5},
+			},
+		},
+		{name: "missing limit", in: "GET /api/v1/patients/", wantErr: true},
+		{name: "bad limit", in: "GET /=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRoutes(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRoutes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
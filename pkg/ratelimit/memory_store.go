@@ -0,0 +1,61 @@
+// pkg/ratelimit/memory_store.go
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// expiresAt is when the bucket is full again if left alone.
+	expiresAt time.Time
+}
+
+// gcInterval is how often stores drop buckets that are full again.
+const gcInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Limits are per replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	lastGC  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gc(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, res := refill(b.tokens, now.Sub(b.updatedAt), limit)
+	b.tokens = tokens
+	b.updatedAt = now
+	b.expiresAt = now.Add(idleFor(limit, res))
+	return res, nil
+}
+
+// gc drops buckets that are full again so idle clients don't leak memory.
+func (s *MemoryStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < gcInterval {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.After(b.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastGC = now
+}
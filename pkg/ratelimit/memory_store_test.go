@@ -0,0 +1,105 @@
+// pkg/ratelimit/memory_store_test.go
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Date(2030, 9, 16, 9, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 2}
+
+	type take struct {
+		key         string
+		at          time.Duration
+		wantAllowed bool
+		wantLeft    int
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst then throttle",
+			takes: []take{
+				{"a", 0, true, 1},
+				{"a", 0, true, 0},
+				{"a", 0, false, 0},
+			},
+		},
+		{
+			name: "refills over time",
+			takes: []take{
+				{"a", 0, true, 1},
+				{"a", 0, true, 0},
+				{"a", time.Second, true, 0},
+				{"a", 3 * time.Second, true, 1},
+			},
+		},
+		{
+			name: "keys are independent",
+			takes: []take{
+				{"a", 0, true, 1},
+				{"a", 0, true, 0},
+				{"b", 0, true, 1},
+				{"a", 0, false, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, tk := range tt.takes {
+				res, err := store.Take(context.Background(), tk.key, limit, start.Add(tk.at))
+				if err != nil {
+					t.Fatalf("take %d: %v", i, err)
+				}
+				if res.Allowed != tk.wantAllowed || res.Remaining != tk.wantLeft {
+					t.Errorf("take %d = allowed %v, remaining %d; want %v, %d",
+						i, res.Allowed, res.Remaining, tk.wantAllowed, tk.wantLeft)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreForgetsRefilledBuckets(t *testing.T) {
+	start := time.Date(2030, 9, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		limit     Limit
+		idle      time.Duration
+		wantKept  bool
+		wantTaken bool
+	}{
+		{"refilled bucket is dropped", Limit{Rate: 1, Burst: 2}, 2 * gcInterval, false, true},
+		{"bucket is kept until the next collection", Limit{Rate: 1, Burst: 2}, gcInterval / 2, true, true},
+		{"bucket that never refills is kept for a day", Limit{Rate: 0, Burst: 1}, 2 * gcInterval, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			ctx := context.Background()
+			if _, err := store.Take(ctx, "a", tt.limit, start); err != nil {
+				t.Fatal(err)
+			}
+			// Another client's request runs the collection.
+			if _, err := store.Take(ctx, "b", tt.limit, start.Add(tt.idle)); err != nil {
+				t.Fatal(err)
+			}
+			if _, kept := store.buckets["a"]; kept != tt.wantKept {
+				t.Errorf("bucket kept = %v, want %v", kept, tt.wantKept)
+			}
+
+			res, err := store.Take(ctx, "a", tt.limit, start.Add(tt.idle))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Allowed != tt.wantTaken {
+				t.Errorf("allowed after idling = %v, want %v", res.Allowed, tt.wantTaken)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// pkg/ratelimit/postgres_store.go
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitBucket is the persisted state of a single token bucket.
+type RateLimitBucket struct {
+	Key       string `gorm:"primaryKey;size:512"`
+	Tokens    float64
+	UpdatedAt time.Time `gorm:"autoUpdateTime:false"`
+	// ExpiresAt is when the bucket is full again if left alone.
+	ExpiresAt time.Time
+}
+
+// PostgresStore shares buckets across replicas through a Postgres table.
+type PostgresStore struct {
+	db     *gorm.DB
+	mu     sync.Mutex
+	lastGC time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.gc(ctx, now)
+
+	var res Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists, then lock it for the read-modify-write.
+		initial := RateLimitBucket{Key: key, Tokens: float64(limit.Burst), UpdatedAt: now, ExpiresAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&initial).Error; err != nil {
+			return err
+		}
+
+		var b RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		elapsed := now.Sub(b.UpdatedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		var tokens float64
+		tokens, res = refill(b.Tokens, elapsed, limit)
+
+		return tx.Model(&RateLimitBucket{}).Where("key = ?", key).
+			Updates(map[string]interface{}{"tokens": tokens, "updated_at": now, "expires_at": now.Add(idleFor(limit, res))}).Error
+	})
+	return res, err
+}
+
+// gc deletes buckets that are full again so idle clients don't fill the
+// table. Each replica does so at most once per gcInterval.
+func (s *PostgresStore) gc(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastGC) < gcInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastGC = now
+	s.mu.Unlock()
+
+	if err := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&RateLimitBucket{}).Error; err != nil {
+		log.Printf("Failed to delete idle rate limit buckets: %v", err)
+	}
+}
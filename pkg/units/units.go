@@ -0,0 +1,79 @@
+// Package units converts clinical measurements between UCUM units, the
+// unit codes used with LOINC observations (kg, [lb_av], Cel, [degF]).
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UCUM codes of the supported units.
+const (
+	Kilogram          = "kg"
+	Pound             = "[lb_av]"
+	Celsius           = "Cel"
+	Fahrenheit        = "[degF]"
+	Centimeter        = "cm"
+	Inch              = "[in_i]"
+	MillimeterMercury = "mm[Hg]"
+	PerMinute         = "/min"
+	Percent           = "%"
+)
+
+// aliases maps the spellings people type to UCUM codes.
+var aliases = map[string]string{
+	"kg": Kilogram, "kgs": Kilogram, "kilogram": Kilogram, "kilograms": Kilogram,
+	"[lb_av]": Pound, "lb": Pound, "lbs": Pound, "pound": Pound, "pounds": Pound,
+	"cel": Celsius, "c": Celsius, "°c": Celsius, "degc": Celsius, "celsius": Celsius,
+	"[degf]": Fahrenheit, "f": Fahrenheit, "°f": Fahrenheit, "degf": Fahrenheit, "fahrenheit": Fahrenheit,
+	"cm": Centimeter, "[in_i]": Inch, "in": Inch, "inch": Inch, "inches": Inch,
+	"mm[hg]": MillimeterMercury, "mmhg": MillimeterMercury,
+	"/min": PerMinute, "bpm": PerMinute, "min-1": PerMinute, "/minute": PerMinute,
+	"%": Percent, "percent": Percent,
+}
+
+// ErrUnknownUnit and ErrIncompatible are returned by Convert.
+var (
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrIncompatible = errors.New("units measure different things")
+)
+
+// Normalize returns the UCUM code for a unit as typed, e.g. "lbs" or "°F".
+func Normalize(unit string) (string, error) {
+	code, ok := aliases[strings.ToLower(strings.TrimSpace(unit))]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownUnit, unit)
+	}
+	return code, nil
+}
+
+// conversions convert a value from the first unit to the second.
+var conversions = map[[2]string]func(float64) float64{
+	{Pound, Kilogram}:     func(v float64) float64 { return v * 0.45359237 },
+	{Kilogram, Pound}:     func(v float64) float64 { return v / 0.45359237 },
+	{Fahrenheit, Celsius}: func(v float64) float64 { return (v - 32) * 5 / 9 },
+	{Celsius, Fahrenheit}: func(v float64) float64 { return v*9/5 + 32 },
+	{Inch, Centimeter}:    func(v float64) float64 { return v * 2.54 },
+	{Centimeter, Inch}:    func(v float64) float64 { return v / 2.54 },
+}
+
+// Convert converts value between two units, given as UCUM codes or aliases.
+func Convert(value float64, from, to string) (float64, error) {
+	fromCode, err := Normalize(from)
+	if err != nil {
+		return 0, err
+	}
+	toCode, err := Normalize(to)
+	if err != nil {
+		return 0, err
+	}
+	if fromCode == toCode {
+		return value, nil
+	}
+	convert, ok := conversions[[2]string{fromCode, toCode}]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s and %s", ErrIncompatible, fromCode, toCode)
+	}
+	return convert(value), nil
+}
@@ -0,0 +1,107 @@
+// pkg/units/units_test.go
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: "kg", want: Kilogram},
+		{in: " LBS ", want: Pound},
+		{in: "[lb_av]", want: Pound},
+		{in: "°F", want: Fahrenheit},
+		{in: "Cel", want: Celsius},
+		{in: "inches", want: Inch},
+		{in: "mmHg", want: MillimeterMercury},
+		{in: "bpm", want: PerMinute},
+		{in: "percent", want: Percent},
+		{in: "stone", wantErr: ErrUnknownUnit},
+		{in: "", wantErr: ErrUnknownUnit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{"pounds to kilograms", 150, Pound, Kilogram, 68.0388555},
+		{"kilograms to pounds", 70, Kilogram, Pound, 154.3235835},
+		{"fahrenheit to celsius", 98.6, Fahrenheit, Celsius, 37},
+		{"celsius to fahrenheit", 38.5, Celsius, Fahrenheit, 101.3},
+		{"freezing point", 0, Celsius, Fahrenheit, 32},
+		{"inches to centimeters", 70, Inch, Centimeter, 177.8},
+		{"centimeters to inches", 180, Centimeter, Inch, 70.8661417},
+		{"same unit", 120, MillimeterMercury, MillimeterMercury, 120},
+		{"aliases", 212, "°F", "C", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.value, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Convert: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Convert(%v, %s, %s) = %v, want %v", tt.value, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	for pair := range conversions {
+		for _, value := range []float64{-40, 0, 1, 37.5, 98.6, 250} {
+			there, err := Convert(value, pair[0], pair[1])
+			if err != nil {
+				t.Fatalf("Convert(%v, %s, %s): %v", value, pair[0], pair[1], err)
+			}
+			back, err := Convert(there, pair[1], pair[0])
+			if err != nil {
+				t.Fatalf("Convert(%v, %s, %s): %v", there, pair[1], pair[0], err)
+			}
+			if math.Abs(back-value) > 1e-9 {
+				t.Errorf("%v %s -> %s -> %v", value, pair[0], pair[1], back)
+			}
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		wantErr  error
+	}{
+		{"unknown source unit", "stone", Kilogram, ErrUnknownUnit},
+		{"unknown target unit", Kilogram, "stone", ErrUnknownUnit},
+		{"weight to temperature", Kilogram, Celsius, ErrIncompatible},
+		{"rate to percent", PerMinute, Percent, ErrIncompatible},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Convert(1, tt.from, tt.to); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// pkg/x12/claim.go
+package x12
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ClaimVersion is the 5010 implementation guide for professional claims (837P).
+const ClaimVersion = "005010X222A1"
+
+// Address is a postal address, written as N3 and N4 segments. State is a
+// two-letter code.
+type Address struct {
+	Line1      string
+	Line2      string
+	City       string
+	State      string
+	PostalCode string
+}
+
+// BillingProvider is the practice the payer pays.
+type BillingProvider struct {
+	Name string
+	NPI  string
+	// TaxID is the employer identification number.
+	TaxID   string
+	Address Address
+	// Phone is where the payer calls about the claims.
+	Phone string
+}
+
+// ClaimParty is the subscriber or the patient of a claim.
+type ClaimParty struct {
+	Person
+	Address Address
+	// Gender is "F", "M" or "U".
+	Gender string
+}
+
+// ServiceLine is a procedure billed on a claim.
+type ServiceLine struct {
+	// Code is a CPT or HCPCS code, e.g. "99213".
+	Code        string
+	Modifiers   []string
+	ChargeCents int64
+	Units       int
+	// DiagnosisPointers are the 1-based positions in Claim.Diagnoses of
+	// the diagnoses the service treats; at most four are written.
+	DiagnosisPointers []int
+}
+
+// Claim is one claim of an 837P.
+type Claim struct {
+	// Number is the patient control number. Payers echo it in the 835.
+	Number string
+	// Responsibility is which of the patient's payers this one is: "P"
+	// primary, "S" secondary or "T" tertiary.
+	Responsibility string
+	PayerName      string
+	PayerID        string
+	// FilingIndicator is the kind of plan, e.g. "CI" commercial insurance.
+	FilingIndicator string
+	GroupNumber     string
+	Subscriber      ClaimParty
+	// Patient is set when the patient is not the subscriber; Relationship
+	// is then how they relate: "01" spouse, "19" child or "G8" other.
+	Patient      *ClaimParty
+	Relationship string
+	// PlaceOfService is a CMS place of service code, e.g. "11" for an office.
+	PlaceOfService string
+	// Diagnoses are ICD-10-CM codes, principal first; at most 12. They
+	// may be written with a dot, which is dropped.
+	Diagnoses []string
+	// ServiceDate is YYYY-MM-DD.
+	ServiceDate string
+	Lines       []ServiceLine
+}
+
+// ChargeCents is the total charge of the claim's lines.
+func (c Claim) ChargeCents() int64 {
+	var total int64
+	for _, line := range c.Lines {
+		total += line.ChargeCents
+	}
+	return total
+}
+
+// ClaimBatch is the content of an 837P: claims billed by one provider.
+type ClaimBatch struct {
+	Envelope
+	// ReferenceID identifies the batch to the receiver.
+	ReferenceID     string
+	ReceiverName    string
+	BillingProvider BillingProvider
+	Claims          []Claim
+}
+
+// Build837P writes a batch of professional claims.
+func Build837P(batch ClaimBatch) []byte {
+	provider := batch.BillingProvider
+	t := batch.Time.UTC()
+	body := []Segment{
+		{"BHT", "0019", "00", Escape(batch.ReferenceID), t.Format("20060102"), t.Format("1504"), "CH"},
+		{"NM1", "41", "2", Escape(provider.Name), "", "", "", "", "46", Escape(batch.SenderID)},
+		{"PER", "IC", Escape(provider.Name), "TE", digits(provider.Phone)},
+		{"NM1", "40", "2", Escape(batch.ReceiverName), "", "", "", "", "46", Escape(batch.ReceiverID)},
+		{"HL", "1", "", "20", "1"},
+		{"NM1", "85", "2", Escape(provider.Name), "", "", "", "", "XX", Escape(provider.NPI)},
+	}
+	body = append(body, address(provider.Address)...)
+	body = append(body, Segment{"REF", "EI", digits(provider.TaxID)})
+
+	hl := 1
+	for _, claim := range batch.Claims {
+		hl++
+		subscriberHL := hl
+		hasPatient := "0"
+		if claim.Patient != nil {
+			hasPatient = "1"
+		}
+		relationship := ""
+		if claim.Patient == nil {
+			relationship = "18"
+		}
+		body = append(body,
+			Segment{"HL", strconv.Itoa(subscriberHL), "1", "22", hasPatient},
+			Segment{"SBR", claim.Responsibility, relationship, Escape(claim.GroupNumber), "", "", "", "", "", claim.FilingIndicator},
+			personName("IL", claim.Subscriber.Person),
+		)
+		body = append(body, address(claim.Subscriber.Address)...)
+		body = append(body, demographics(claim.Subscriber)...)
+		body = append(body, Segment{"NM1", "PR", "2", Escape(claim.PayerName), "", "", "", "", "PI", Escape(claim.PayerID)})
+
+		if claim.Patient != nil {
+			hl++
+			patient := *claim.Patient
+			patient.MemberID = ""
+			body = append(body,
+				Segment{"HL", strconv.Itoa(hl), strconv.Itoa(subscriberHL), "23", "0"},
+				Segment{"PAT", claim.Relationship},
+				personName("QC", patient.Person),
+			)
+			body = append(body, address(patient.Address)...)
+			body = append(body, demographics(patient)...)
+		}
+		body = append(body, claimSegments(claim)...)
+	}
+	return Encode(batch.Envelope, "HC", "837", ClaimVersion, body)
+}
+
+// claimSegments writes the CLM loop: the claim, its diagnoses and its lines.
+func claimSegments(claim Claim) []Segment {
+	segments := []Segment{
+		// Signature on file, assigned benefits and release of information
+		// are attested once at registration.
+		{"CLM", Escape(claim.Number), formatAmount(claim.ChargeCents()), "", "",
+			composite(claim.PlaceOfService, "B", "1"), "Y", "A", "Y", "Y"},
+	}
+
+	hi := Segment{"HI"}
+	for i, code := range claim.Diagnoses {
+		if i == 12 {
+			break
+		}
+		qualifier := "ABF"
+		if i == 0 {
+			qualifier = "ABK"
+		}
+		hi = append(hi, composite(qualifier, diagnosisCode(code)))
+	}
+	if len(hi) > 1 {
+		segments = append(segments, hi)
+	}
+
+	serviceDate := compactDate(claim.ServiceDate)
+	for i, line := range claim.Lines {
+		units := line.Units
+		if units < 1 {
+			units = 1
+		}
+		procedure := append([]string{"HC", line.Code}, line.Modifiers...)
+		if len(procedure) > 6 {
+			procedure = procedure[:6]
+		}
+		var pointers []string
+		for _, p := range line.DiagnosisPointers {
+			if len(pointers) < 4 && p >= 1 && p <= len(hi)-1 {
+				pointers = append(pointers, strconv.Itoa(p))
+			}
+		}
+		segments = append(segments,
+			Segment{"LX", strconv.Itoa(i + 1)},
+			Segment{"SV1", composite(procedure...), formatAmount(line.ChargeCents), "UN", strconv.Itoa(units), "", "", composite(pointers...)},
+			Segment{"DTP", "472", "D8", serviceDate},
+		)
+	}
+	return segments
+}
+
+// address writes the N3 and N4 segments of an address, or nothing when
+// it has no street.
+func address(a Address) []Segment {
+	if a.Line1 == "" {
+		return nil
+	}
+	return []Segment{
+		{"N3", Escape(a.Line1), Escape(a.Line2)},
+		{"N4", Escape(a.City), Escape(a.State), strings.ReplaceAll(Escape(a.PostalCode), " ", "")},
+	}
+}
+
+func demographics(p ClaimParty) []Segment {
+	dob := compactDate(p.DateOfBirth)
+	if dob == "" {
+		return nil
+	}
+	gender := p.Gender
+	if gender == "" {
+		gender = "U"
+	}
+	return []Segment{{"DMG", "D8", dob, gender}}
+}
+
+// diagnosisCode writes an ICD-10-CM code the way X12 carries it, without
+// the dot: "E11.9" becomes "E119".
+func diagnosisCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(Escape(code), ".", ""))
+}
+
+// composite joins the components of a composite element, dropping
+// trailing empty ones.
+func composite(components ...string) string {
+	escaped := make([]string, len(components))
+	for i, c := range components {
+		escaped[i] = Escape(c)
+	}
+	end := len(escaped)
+	for end > 1 && escaped[end-1] == "" {
+		end--
+	}
+	return strings.Join(escaped[:end], string(DefaultDelimiters.Component))
+}
+
+// digits keeps only the digits of a phone number or tax ID.
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// formatAmount writes cents as an X12 decimal amount without trailing
+// zeros: 12500 becomes "125" and 12550 "125.5".
+func formatAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	amount := fmt.Sprintf("%d.%02d", cents/100, cents%100)
+	return sign + strings.TrimSuffix(strings.TrimRight(amount, "0"), ".")
+}
+
+// parseAmount reads an X12 decimal amount as cents. An empty amount is zero.
+func parseAmount(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("x12: invalid amount %q", s)
+	}
+	return int64(math.Round(n * 100)), nil
+}
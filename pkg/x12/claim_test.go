@@ -0,0 +1,131 @@
+// pkg/x12/claim_test.go
+package x12
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestBuild837P(t *testing.T) {
+	provider := BillingProvider{
+		Name: "SMITH CLINIC", NPI: "1234567893", TaxID: "12-3456789", Phone: "+1 (415) 555-2671",
+		Address: Address{Line1: "1 MAIN ST", City: "SPRINGFIELD", State: "IL", PostalCode: "62701 1234"},
+	}
+	subscriber := ClaimParty{
+		Person:  Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789", DateOfBirth: "1980-04-02"},
+		Address: Address{Line1: "2 ELM ST", City: "SPRINGFIELD", State: "IL", PostalCode: "62702"},
+		Gender:  "F",
+	}
+	tests := []struct {
+		name  string
+		claim Claim
+		// want maps a segment ID, and for some its first element, to the
+		// segment expected first under it.
+		want map[string]Segment
+		// wantCount counts segments by ID.
+		wantCount map[string]int
+	}{
+		{
+			name: "subscriber is the patient",
+			claim: Claim{
+				Number: "CLM-1", Responsibility: "P", PayerName: "ACME HEALTH", PayerID: "60054", FilingIndicator: "CI",
+				GroupNumber: "GRP1", Subscriber: subscriber, PlaceOfService: "11", Diagnoses: []string{"E11.9", "i10"},
+				ServiceDate: "2030-09-16",
+				Lines: []ServiceLine{
+					{Code: "99213", Modifiers: []string{"25"}, ChargeCents: 12500, Units: 1, DiagnosisPointers: []int{1, 2}},
+					{Code: "81002", ChargeCents: 1550, DiagnosisPointers: []int{2, 7}},
+				},
+			},
+			want: map[string]Segment{
+				"SBR":    {"SBR", "P", "18", "GRP1", "", "", "", "", "", "CI"},
+				"CLM":    {"CLM", "CLM-1", "140.5", "", "", "11:B:1", "Y", "A", "Y", "Y"},
+				"HI":     {"HI", "ABK:E119", "ABF:I10"},
+				"SV1":    {"SV1", "HC:99213:25", "125", "UN", "1", "", "", "1:2"},
+				"DTP472": {"DTP", "472", "D8", "20300916"},
+				"PER":    {"PER", "IC", "SMITH CLINIC", "TE", "14155552671"},
+				"REFEI":  {"REF", "EI", "123456789"},
+				"N4":     {"N4", "SPRINGFIELD", "IL", "627011234"},
+				"DMG":    {"DMG", "D8", "19800402", "F"},
+			},
+			wantCount: map[string]int{"CLM": 1, "LX": 2, "SV1": 2, "PAT": 0},
+		},
+		{
+			name: "dependent patient",
+			claim: Claim{
+				Number: "CLM-2", Responsibility: "S", PayerName: "ACME HEALTH", PayerID: "60054", FilingIndicator: "CI",
+				Subscriber: subscriber, Relationship: "19",
+				Patient: &ClaimParty{
+					Person: Person{FirstName: "JOHN", LastName: "DOE", MemberID: "W123456789-02", DateOfBirth: "2015-06-30"},
+				},
+				PlaceOfService: "11", Diagnoses: []string{"J06.9"}, ServiceDate: "2030-09-16",
+				Lines: []ServiceLine{{Code: "99212", ChargeCents: 9000, Units: 1, DiagnosisPointers: []int{1}}},
+			},
+			want: map[string]Segment{
+				"SBR":   {"SBR", "S", "", "", "", "", "", "", "", "CI"},
+				"PAT":   {"PAT", "19"},
+				"NM1QC": {"NM1", "QC", "1", "DOE", "JOHN"},
+				"CLM":   {"CLM", "CLM-2", "90", "", "", "11:B:1", "Y", "A", "Y", "Y"},
+				"HI":    {"HI", "ABK:J069"},
+			},
+			wantCount: map[string]int{"HL": 3, "DMG": 2, "LX": 1},
+		},
+		{
+			name: "lines and diagnoses past the limits are dropped",
+			claim: Claim{
+				Number: "CLM~3", Responsibility: "P", PayerName: "ACME*HEALTH", PayerID: "60054", FilingIndicator: "CI",
+				Subscriber: ClaimParty{Person: Person{FirstName: "JANE", LastName: "DOE", MemberID: "W1"}}, PlaceOfService: "02",
+				Diagnoses: []string{"A00", "A01", "A02", "A03", "A04", "A05", "A06", "A07", "A08", "A09", "B00", "B01", "B02", "B03"},
+				Lines: []ServiceLine{{Code: "99213", Modifiers: []string{"25", "59", "GT", "XE", "XS"}, ChargeCents: 100,
+					DiagnosisPointers: []int{0, 1, 2, 3, 4, 5, 13}}},
+			},
+			want: map[string]Segment{
+				"CLM":   {"CLM", "CLM 3", "1", "", "", "02:B:1", "Y", "A", "Y", "Y"},
+				"SV1":   {"SV1", "HC:99213:25:59:GT:XE", "1", "UN", "1", "", "", "1:2:3:4"},
+				"NM1PR": {"NM1", "PR", "2", "ACME HEALTH", "", "", "", "", "PI", "60054"},
+			},
+			wantCount: map[string]int{"DMG": 0, "N3": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Build837P(ClaimBatch{Envelope: testEnvelope, ReferenceID: "BATCH1", ReceiverName: "CLEARINGHOUSE",
+				BillingProvider: provider, Claims: []Claim{tt.claim}})
+			segments, _, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := expectTransaction(segments, "837"); err != nil {
+				t.Fatal(err)
+			}
+
+			first := map[string]Segment{}
+			count := map[string]int{}
+			stAt := -1
+			for i, seg := range segments {
+				count[seg.ID()]++
+				for _, key := range []string{seg.ID(), seg.ID() + seg.Element(1)} {
+					if _, ok := first[key]; !ok {
+						first[key] = seg
+					}
+				}
+				if seg.ID() == "ST" {
+					stAt = i
+				}
+				if seg.ID() == "SE" && seg.Element(1) != strconv.Itoa(i-stAt+1) {
+					t.Errorf("SE01 = %s, want %d", seg.Element(1), i-stAt+1)
+				}
+			}
+			for key, want := range tt.want {
+				if got := first[key]; !reflect.DeepEqual(got, trimEmpty(want)) {
+					t.Errorf("%s = %q, want %q", key, got, trimEmpty(want))
+				}
+			}
+			for id, want := range tt.wantCount {
+				if count[id] != want {
+					t.Errorf("%d %s segments, want %d", count[id], id, want)
+				}
+			}
+		})
+	}
+}
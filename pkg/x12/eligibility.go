@@ -0,0 +1,447 @@
+// pkg/x12/eligibility.go
+package x12
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EligibilityVersion is the 5010 implementation guide for 270/271.
+const EligibilityVersion = "005010X279A1"
+
+// ServiceTypeHealthPlan asks about the plan's coverage in general.
+const ServiceTypeHealthPlan = "30"
+
+// Person is a subscriber or dependent. DateOfBirth is YYYY-MM-DD.
+type Person struct {
+	FirstName   string
+	LastName    string
+	MemberID    string
+	DateOfBirth string
+}
+
+// EligibilityRequest is the content of a 270 inquiry.
+type EligibilityRequest struct {
+	Envelope
+	// TraceNumber is echoed back in the 271 so responses can be matched.
+	TraceNumber  string
+	PayerName    string
+	PayerID      string
+	ProviderName string
+	ProviderNPI  string
+	Subscriber   Person
+	GroupNumber  string
+	// Dependent is the patient when they are not the subscriber.
+	Dependent *Person
+	// ServiceDate is YYYY-MM-DD.
+	ServiceDate string
+	// ServiceTypes are X12 service type codes; ServiceTypeHealthPlan when empty.
+	ServiceTypes []string
+}
+
+// Benefit is an EB segment of a 271 and the MSG segments following it.
+type Benefit struct {
+	// Code is EB01, e.g. "1" for active coverage or "B" for a co-payment.
+	Code            string
+	CoverageLevel   string
+	ServiceType     string
+	PlanDescription string
+	// Period is the time period qualifier, e.g. "23" for calendar year.
+	Period string
+	Amount *float64
+	// Percent is a fraction, e.g. 0.2 for 20% co-insurance.
+	Percent *float64
+	// InNetwork is EB12: "Y", "N", or "W" when network doesn't apply.
+	InNetwork string
+	Messages  []string
+}
+
+// Rejection is an AAA segment: the payer could not answer the inquiry.
+type Rejection struct {
+	Code     string
+	FollowUp string
+}
+
+// EligibilityResponse is the content of a 271.
+type EligibilityResponse struct {
+	Envelope
+	TraceNumber string
+	PayerName   string
+	Subscriber  Person
+	Dependent   *Person
+	// PlanBegin and PlanEnd are YYYY-MM-DD when the payer reports them.
+	PlanBegin  string
+	PlanEnd    string
+	Benefits   []Benefit
+	Rejections []Rejection
+}
+
+// Eligibility benefit codes (EB01) with special meaning.
+const (
+	BenefitActive   = "1"
+	BenefitInactive = "6"
+)
+
+// Active reports whether the payer confirmed active coverage.
+func (r *EligibilityResponse) Active() bool {
+	for _, b := range r.Benefits {
+		if b.Code == BenefitActive {
+			return true
+		}
+	}
+	return false
+}
+
+// Inactive reports whether the payer reported the coverage as inactive.
+func (r *EligibilityResponse) Inactive() bool {
+	for _, b := range r.Benefits {
+		if b.Code == BenefitInactive {
+			return true
+		}
+	}
+	return false
+}
+
+var benefitNames = map[string]string{
+	"1": "Active coverage",
+	"6": "Inactive",
+	"A": "Co-insurance",
+	"B": "Co-payment",
+	"C": "Deductible",
+	"F": "Limitations",
+	"G": "Out of pocket (stop loss)",
+	"I": "Non-covered",
+	"R": "Other or additional payer",
+	"V": "Cannot process",
+}
+
+// BenefitName describes an EB01 code.
+func BenefitName(code string) string {
+	if name, ok := benefitNames[code]; ok {
+		return name
+	}
+	return "Benefit " + code
+}
+
+var rejectionReasons = map[string]string{
+	"15": "Required application data missing",
+	"41": "Authorization/access restrictions",
+	"42": "Unable to respond at current time",
+	"43": "Invalid/missing provider identification",
+	"58": "Invalid/missing date of birth",
+	"65": "Invalid/missing patient name",
+	"71": "Patient birth date does not match that for the patient on the database",
+	"72": "Invalid/missing subscriber/insured ID",
+	"73": "Invalid/missing subscriber/insured name",
+	"75": "Subscriber/insured not found",
+	"76": "Duplicate subscriber/insured ID number",
+	"79": "Invalid participant identification",
+}
+
+// RejectionReason describes an AAA03 code.
+func RejectionReason(code string) string {
+	if reason, ok := rejectionReasons[code]; ok {
+		return reason
+	}
+	return "Rejected with reason " + code
+}
+
+// Build270 writes an eligibility inquiry.
+func Build270(req EligibilityRequest) []byte {
+	serviceDate := compactDate(req.ServiceDate)
+	if serviceDate == "" {
+		serviceDate = req.Time.Format("20060102")
+	}
+	serviceTypes := req.ServiceTypes
+	if len(serviceTypes) == 0 {
+		serviceTypes = []string{ServiceTypeHealthPlan}
+	}
+
+	subscriberChild := "0"
+	if req.Dependent != nil {
+		subscriberChild = "1"
+	}
+	body := []Segment{
+		{"BHT", "0022", "13", Escape(req.TraceNumber), req.Time.UTC().Format("20060102"), req.Time.UTC().Format("1504")},
+		{"HL", "1", "", "20", "1"},
+		{"NM1", "PR", "2", Escape(req.PayerName), "", "", "", "", "PI", Escape(req.PayerID)},
+		{"HL", "2", "1", "21", "1"},
+		providerName(req),
+		{"HL", "3", "2", "22", subscriberChild},
+	}
+	if req.Dependent == nil {
+		body = append(body, Segment{"TRN", "1", Escape(req.TraceNumber), originatorID(req.SenderID)})
+	}
+	body = append(body, personName("IL", req.Subscriber))
+	if req.GroupNumber != "" {
+		body = append(body, Segment{"REF", "6P", Escape(req.GroupNumber)})
+	}
+	if dob := compactDate(req.Subscriber.DateOfBirth); dob != "" {
+		body = append(body, Segment{"DMG", "D8", dob})
+	}
+	if req.Dependent != nil {
+		body = append(body,
+			Segment{"HL", "4", "3", "23", "0"},
+			Segment{"TRN", "1", Escape(req.TraceNumber), originatorID(req.SenderID)},
+			personName("03", *req.Dependent),
+		)
+		if dob := compactDate(req.Dependent.DateOfBirth); dob != "" {
+			body = append(body, Segment{"DMG", "D8", dob})
+		}
+	}
+	body = append(body, Segment{"DTP", "291", "D8", serviceDate})
+	for _, serviceType := range serviceTypes {
+		body = append(body, Segment{"EQ", serviceType})
+	}
+	return Encode(req.Envelope, "HS", "270", EligibilityVersion, body)
+}
+
+func providerName(req EligibilityRequest) Segment {
+	seg := Segment{"NM1", "1P", "2", Escape(req.ProviderName)}
+	if req.ProviderNPI != "" {
+		seg = append(seg, "", "", "", "", "XX", Escape(req.ProviderNPI))
+	}
+	return seg
+}
+
+func personName(entity string, p Person) Segment {
+	seg := Segment{"NM1", entity, "1", Escape(p.LastName), Escape(p.FirstName)}
+	if p.MemberID != "" {
+		seg = append(seg, "", "", "", "MI", Escape(p.MemberID))
+	}
+	return seg
+}
+
+// Parse270 reads an eligibility inquiry, as a clearinghouse would.
+func Parse270(data []byte) (*EligibilityRequest, error) {
+	segments, _, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectTransaction(segments, "270"); err != nil {
+		return nil, err
+	}
+	env, err := ReadEnvelope(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &EligibilityRequest{Envelope: env}
+	for _, seg := range segments {
+		switch seg.ID() {
+		case "BHT":
+			req.TraceNumber = seg.Element(3)
+		case "TRN":
+			req.TraceNumber = seg.Element(2)
+		case "NM1":
+			switch seg.Element(1) {
+			case "PR":
+				req.PayerName, req.PayerID = seg.Element(3), seg.Element(9)
+			case "1P":
+				req.ProviderName, req.ProviderNPI = seg.Element(3), seg.Element(9)
+			case "IL":
+				req.Subscriber = readPerson(seg)
+			case "03":
+				dependent := readPerson(seg)
+				req.Dependent = &dependent
+			}
+		case "REF":
+			if seg.Element(1) == "6P" {
+				req.GroupNumber = seg.Element(2)
+			}
+		case "DMG":
+			if req.Dependent != nil {
+				req.Dependent.DateOfBirth = isoDate(seg.Element(2))
+			} else {
+				req.Subscriber.DateOfBirth = isoDate(seg.Element(2))
+			}
+		case "DTP":
+			if seg.Element(1) == "291" {
+				req.ServiceDate = isoDate(seg.Element(3))
+			}
+		case "EQ":
+			req.ServiceTypes = append(req.ServiceTypes, seg.Element(1))
+		}
+	}
+	return req, nil
+}
+
+// Build271 writes an eligibility response. It is used by test
+// clearinghouses standing in for a payer.
+func Build271(resp EligibilityResponse) []byte {
+	subscriberChild := "0"
+	if resp.Dependent != nil {
+		subscriberChild = "1"
+	}
+	body := []Segment{
+		{"BHT", "0022", "11", Escape(resp.TraceNumber), resp.Time.UTC().Format("20060102"), resp.Time.UTC().Format("1504")},
+		{"HL", "1", "", "20", "1"},
+		{"NM1", "PR", "2", Escape(resp.PayerName)},
+		{"HL", "2", "1", "21", "1"},
+		{"NM1", "1P", "2", "PROVIDER"},
+		{"HL", "3", "2", "22", subscriberChild},
+		{"TRN", "2", Escape(resp.TraceNumber), originatorID(resp.SenderID)},
+		personName("IL", resp.Subscriber),
+	}
+	if resp.Dependent != nil {
+		body = append(body, Segment{"HL", "4", "3", "23", "0"}, personName("03", *resp.Dependent))
+	}
+	for _, rej := range resp.Rejections {
+		body = append(body, Segment{"AAA", "N", "", rej.Code, rej.FollowUp})
+	}
+	if begin := compactDate(resp.PlanBegin); begin != "" {
+		body = append(body, Segment{"DTP", "346", "D8", begin})
+	}
+	if end := compactDate(resp.PlanEnd); end != "" {
+		body = append(body, Segment{"DTP", "347", "D8", end})
+	}
+	for _, b := range resp.Benefits {
+		body = append(body, Segment{"EB", b.Code, b.CoverageLevel, b.ServiceType, "", Escape(b.PlanDescription),
+			b.Period, formatNumber(b.Amount), formatNumber(b.Percent), "", "", "", b.InNetwork})
+		for _, msg := range b.Messages {
+			body = append(body, Segment{"MSG", Escape(msg)})
+		}
+	}
+	return Encode(resp.Envelope, "HB", "271", EligibilityVersion, body)
+}
+
+// Parse271 reads an eligibility response.
+func Parse271(data []byte) (*EligibilityResponse, error) {
+	segments, _, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectTransaction(segments, "271"); err != nil {
+		return nil, err
+	}
+	env, err := ReadEnvelope(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &EligibilityResponse{Envelope: env}
+	person := &resp.Subscriber
+	var benefit *Benefit
+	for _, seg := range segments {
+		switch seg.ID() {
+		case "TRN":
+			if seg.Element(1) == "2" {
+				resp.TraceNumber = seg.Element(2)
+			}
+		case "NM1":
+			benefit = nil
+			switch seg.Element(1) {
+			case "PR":
+				resp.PayerName = seg.Element(3)
+			case "IL":
+				resp.Subscriber = readPerson(seg)
+				person = &resp.Subscriber
+			case "03":
+				dependent := readPerson(seg)
+				resp.Dependent = &dependent
+				person = resp.Dependent
+			}
+		case "DMG":
+			person.DateOfBirth = isoDate(seg.Element(2))
+		case "AAA":
+			if seg.Element(1) == "N" {
+				resp.Rejections = append(resp.Rejections, Rejection{Code: seg.Element(3), FollowUp: seg.Element(4)})
+			}
+		case "DTP":
+			if benefit != nil {
+				continue
+			}
+			switch seg.Element(1) {
+			case "346":
+				resp.PlanBegin = isoDate(seg.Element(3))
+			case "347":
+				resp.PlanEnd = isoDate(seg.Element(3))
+			case "291", "307":
+				if begin, end, ok := strings.Cut(seg.Element(3), "-"); ok {
+					resp.PlanBegin, resp.PlanEnd = isoDate(begin), isoDate(end)
+				} else {
+					resp.PlanBegin = isoDate(begin)
+				}
+			}
+		case "EB":
+			resp.Benefits = append(resp.Benefits, Benefit{
+				Code:            seg.Element(1),
+				CoverageLevel:   seg.Element(2),
+				ServiceType:     seg.Element(3),
+				PlanDescription: seg.Element(5),
+				Period:          seg.Element(6),
+				Amount:          parseNumber(seg.Element(7)),
+				Percent:         parseNumber(seg.Element(8)),
+				InNetwork:       seg.Element(12),
+			})
+			benefit = &resp.Benefits[len(resp.Benefits)-1]
+		case "MSG":
+			if benefit != nil {
+				benefit.Messages = append(benefit.Messages, seg.Element(1))
+			}
+		}
+	}
+	return resp, nil
+}
+
+// expectTransaction checks the interchange carries the given transaction set.
+func expectTransaction(segments []Segment, set string) error {
+	for _, seg := range segments {
+		if seg.ID() == "ST" {
+			if seg.Element(1) != set {
+				return fmt.Errorf("x12: expected a %s transaction, got %s", set, seg.Element(1))
+			}
+			return nil
+		}
+	}
+	return errors.New("x12: interchange has no transaction set")
+}
+
+// originatorID identifies who assigned a trace number: "9" followed by an
+// identifier of the sender.
+func originatorID(senderID string) string {
+	id := strings.ReplaceAll(Escape(senderID), " ", "")
+	if len(id) > 9 {
+		id = id[:9]
+	}
+	return "9" + id
+}
+
+func readPerson(seg Segment) Person {
+	return Person{LastName: seg.Element(3), FirstName: seg.Element(4), MemberID: seg.Element(9)}
+}
+
+// compactDate turns YYYY-MM-DD into the CCYYMMDD used by X12.
+func compactDate(date string) string {
+	return strings.ReplaceAll(date, "-", "")
+}
+
+// isoDate turns an X12 CCYYMMDD date into YYYY-MM-DD.
+func isoDate(date string) string {
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func parseNumber(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func formatNumber(n *float64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*n, 'f', -1, 64)
+}
@@ -0,0 +1,216 @@
+// pkg/x12/eligibility_test.go
+package x12
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEligibilityRequestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		req  EligibilityRequest
+		want EligibilityRequest
+	}{
+		{
+			name: "subscriber",
+			req: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T-1", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC", ProviderNPI: "1234567893",
+				Subscriber:  Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789", DateOfBirth: "1980-04-02"},
+				GroupNumber: "GRP1", ServiceDate: "2030-09-16",
+			},
+			want: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T-1", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC", ProviderNPI: "1234567893",
+				Subscriber:  Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789", DateOfBirth: "1980-04-02"},
+				GroupNumber: "GRP1", ServiceDate: "2030-09-16", ServiceTypes: []string{ServiceTypeHealthPlan},
+			},
+		},
+		{
+			name: "dependent",
+			req: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T-2", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC",
+				Subscriber:   Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789", DateOfBirth: "1980-04-02"},
+				Dependent:    &Person{FirstName: "JOHN", LastName: "DOE", DateOfBirth: "2015-06-30"},
+				ServiceDate:  "2030-09-16", ServiceTypes: []string{"30", "98"},
+			},
+			want: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T-2", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC",
+				Subscriber:   Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789", DateOfBirth: "1980-04-02"},
+				Dependent:    &Person{FirstName: "JOHN", LastName: "DOE", DateOfBirth: "2015-06-30"},
+				ServiceDate:  "2030-09-16", ServiceTypes: []string{"30", "98"},
+			},
+		},
+		{
+			name: "service date defaults to the interchange date",
+			req: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T-3", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC", Subscriber: Person{FirstName: "JANE", LastName: "DOE"},
+			},
+			want: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T-3", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC", Subscriber: Person{FirstName: "JANE", LastName: "DOE"},
+				ServiceDate: "2030-09-15", ServiceTypes: []string{ServiceTypeHealthPlan},
+			},
+		},
+		{
+			name: "delimiters in values are escaped",
+			req: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T~4", PayerName: "ACME*HEALTH", PayerID: "60054",
+				ProviderName: "SMITH:CLINIC", Subscriber: Person{FirstName: "JANE", LastName: "O^DOE", MemberID: "W1~"},
+				ServiceDate: "2030-09-16",
+			},
+			want: EligibilityRequest{
+				Envelope: testEnvelope, TraceNumber: "T 4", PayerName: "ACME HEALTH", PayerID: "60054",
+				ProviderName: "SMITH CLINIC", Subscriber: Person{FirstName: "JANE", LastName: "O DOE", MemberID: "W1"},
+				ServiceDate: "2030-09-16", ServiceTypes: []string{ServiceTypeHealthPlan},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse270(Build270(tt.req))
+			if err != nil {
+				t.Fatalf("Parse270: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse270(Build270()) =\n%+v\nwant\n%+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEligibilityResponseRoundTrip(t *testing.T) {
+	copay, coinsurance := 25.0, 0.2
+	tests := []struct {
+		name         string
+		resp         EligibilityResponse
+		wantActive   bool
+		wantInactive bool
+	}{
+		{
+			name: "active coverage with benefits",
+			resp: EligibilityResponse{
+				Envelope: testEnvelope, TraceNumber: "T-1", PayerName: "ACME HEALTH",
+				Subscriber: Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789"},
+				PlanBegin:  "2030-01-01", PlanEnd: "2030-12-31",
+				Benefits: []Benefit{
+					{Code: BenefitActive, CoverageLevel: "IND", ServiceType: "30", PlanDescription: "GOLD PPO"},
+					{Code: "B", ServiceType: "98", Period: "27", Amount: &copay, InNetwork: "Y", Messages: []string{"OFFICE VISIT"}},
+					{Code: "A", ServiceType: "30", Percent: &coinsurance, InNetwork: "N", Messages: []string{"AFTER DEDUCTIBLE", "OUT OF NETWORK"}},
+				},
+			},
+			wantActive: true,
+		},
+		{
+			name: "inactive dependent",
+			resp: EligibilityResponse{
+				Envelope: testEnvelope, TraceNumber: "T-2", PayerName: "ACME HEALTH",
+				Subscriber: Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789"},
+				Dependent:  &Person{FirstName: "JOHN", LastName: "DOE"},
+				Benefits:   []Benefit{{Code: BenefitInactive, ServiceType: "30"}},
+			},
+			wantInactive: true,
+		},
+		{
+			name: "rejected inquiry",
+			resp: EligibilityResponse{
+				Envelope: testEnvelope, TraceNumber: "T-3", PayerName: "ACME HEALTH",
+				Subscriber: Person{FirstName: "JANE", LastName: "DOE", MemberID: "W0"},
+				Rejections: []Rejection{{Code: "72", FollowUp: "C"}, {Code: "73", FollowUp: "C"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse271(Build271(tt.resp))
+			if err != nil {
+				t.Fatalf("Parse271: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.resp) {
+				t.Errorf("Parse271(Build271()) =\n%+v\nwant\n%+v", *got, tt.resp)
+			}
+			if got.Active() != tt.wantActive || got.Inactive() != tt.wantInactive {
+				t.Errorf("Active, Inactive = %v, %v, want %v, %v", got.Active(), got.Inactive(), tt.wantActive, tt.wantInactive)
+			}
+		})
+	}
+}
+
+func TestParse271PlanDateRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		dtp       Segment
+		wantBegin string
+		wantEnd   string
+	}{
+		{name: "eligibility range", dtp: Segment{"DTP", "307", "RD8", "20300101-20301231"}, wantBegin: "2030-01-01", wantEnd: "2030-12-31"},
+		{name: "single eligibility date", dtp: Segment{"DTP", "291", "D8", "20300916"}, wantBegin: "2030-09-16"},
+		{name: "invalid date", dtp: Segment{"DTP", "346", "D8", "2030-01-01"}},
+		{name: "other qualifier", dtp: Segment{"DTP", "472", "D8", "20300916"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Encode(testEnvelope, "HB", "271", EligibilityVersion, []Segment{
+				{"TRN", "2", "T-1", "9DOCTORSAA"},
+				{"NM1", "IL", "1", "DOE", "JANE"},
+				tt.dtp,
+			})
+			got, err := Parse271(data)
+			if err != nil {
+				t.Fatalf("Parse271: %v", err)
+			}
+			if got.PlanBegin != tt.wantBegin || got.PlanEnd != tt.wantEnd {
+				t.Errorf("plan = %q to %q, want %q to %q", got.PlanBegin, got.PlanEnd, tt.wantBegin, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseEligibilityMalformed(t *testing.T) {
+	request := Build270(EligibilityRequest{Envelope: testEnvelope, TraceNumber: "T-1", Subscriber: Person{LastName: "DOE"}})
+	response := Build271(EligibilityResponse{Envelope: testEnvelope, TraceNumber: "T-1", Subscriber: Person{LastName: "DOE"}})
+	shortISA := strings.Replace(string(response), "*00501*", "*", 1)
+	tests := []struct {
+		name    string
+		parse   func([]byte) error
+		data    []byte
+		wantErr error
+	}{
+		{name: "270 empty", parse: parse270, data: nil, wantErr: ErrNotInterchange},
+		{name: "270 truncated", parse: parse270, data: request[:len(request)/2]},
+		{name: "270 given a 271", parse: parse270, data: response},
+		{name: "270 with an empty transaction set ID", parse: parse270, data: Encode(testEnvelope, "HS", "", "", nil)},
+		{name: "271 empty", parse: parse271, data: nil, wantErr: ErrNotInterchange},
+		{name: "271 truncated ISA", parse: parse271, data: response[:80], wantErr: ErrNotInterchange},
+		{name: "271 truncated", parse: parse271, data: response[:len(response)-20]},
+		{name: "271 given a 270", parse: parse271, data: request},
+		{name: "271 ISA shorter than its fixed width", parse: parse271, data: []byte(shortISA)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(tt.data)
+			if err == nil {
+				t.Fatal("parse succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func parse270(data []byte) error {
+	_, err := Parse270(data)
+	return err
+}
+
+func parse271(data []byte) error {
+	_, err := Parse271(data)
+	return err
+}
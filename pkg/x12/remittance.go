@@ -0,0 +1,268 @@
+// pkg/x12/remittance.go
+package x12
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RemittanceVersion is the 5010 implementation guide for payment advice (835).
+const RemittanceVersion = "005010X221A1"
+
+// Claim adjustment groups (CAS01): who the adjusted amount falls to.
+const (
+	// AdjustmentContractual is written off under the provider's contract.
+	AdjustmentContractual = "CO"
+	AdjustmentOther       = "OA"
+	// AdjustmentPayerInitiated is written off at the payer's discretion.
+	AdjustmentPayerInitiated = "PI"
+	// AdjustmentPatient is left for the patient to pay, e.g. a co-payment.
+	AdjustmentPatient = "PR"
+)
+
+// Claim statuses of an 835 (CLP02) with special meaning. The others say
+// the claim was processed, and by which of the patient's payers.
+const (
+	ClaimStatusDenied   = "4"
+	ClaimStatusReversal = "22"
+	// ClaimStatusNotOurs means the payer has no record of the patient.
+	ClaimStatusNotOurs = "23"
+)
+
+// Adjustment is one reason a payer paid other than the charge.
+type Adjustment struct {
+	Group string
+	// Reason is a claim adjustment reason code, e.g. "45" for a charge
+	// over the fee schedule.
+	Reason      string
+	AmountCents int64
+}
+
+// PaidService is what a payer decided about one line of a claim.
+type PaidService struct {
+	Code        string
+	Modifiers   []string
+	ChargeCents int64
+	PaidCents   int64
+	Units       int
+	Adjustments []Adjustment
+}
+
+// PaidClaim is what a payer decided about a claim.
+type PaidClaim struct {
+	// Number is the patient control number of the 837.
+	Number                     string
+	Status                     string
+	ChargeCents                int64
+	PaidCents                  int64
+	PatientResponsibilityCents int64
+	// PayerClaimNumber is the payer's own number for the claim.
+	PayerClaimNumber string
+	Patient          Person
+	// Adjustments are those of the whole claim; Services hold those of
+	// its lines.
+	Adjustments []Adjustment
+	Services    []PaidService
+}
+
+// AllAdjustments returns the adjustments of the claim and of its lines.
+func (c PaidClaim) AllAdjustments() []Adjustment {
+	all := append([]Adjustment(nil), c.Adjustments...)
+	for _, service := range c.Services {
+		all = append(all, service.Adjustments...)
+	}
+	return all
+}
+
+// Remittance is the content of an 835: one payment and the claims it pays.
+type Remittance struct {
+	Envelope
+	// TraceNumber is the check or EFT number; PayerID is the payer's
+	// company identifier that goes with it (TRN03).
+	TraceNumber  string
+	PayerID      string
+	PayerName    string
+	PayeeName    string
+	PayeeNPI     string
+	PaymentCents int64
+	// PaymentMethod is BPR04, e.g. "ACH", "CHK", or "NON" when nothing is paid.
+	PaymentMethod string
+	// PaymentDate is YYYY-MM-DD.
+	PaymentDate string
+	Claims      []PaidClaim
+}
+
+var claimStatusNames = map[string]string{
+	"1":  "Processed as primary",
+	"2":  "Processed as secondary",
+	"3":  "Processed as tertiary",
+	"4":  "Denied",
+	"19": "Processed as primary, forwarded to additional payer",
+	"20": "Processed as secondary, forwarded to additional payer",
+	"21": "Processed as tertiary, forwarded to additional payer",
+	"22": "Reversal of previous payment",
+	"23": "Not our claim, forwarded to additional payer",
+	"25": "Predetermination pricing only, no payment",
+}
+
+// ClaimStatusName describes a CLP02 code.
+func ClaimStatusName(code string) string {
+	if name, ok := claimStatusNames[code]; ok {
+		return name
+	}
+	return "Status " + code
+}
+
+// Parse835 reads a payment advice.
+func Parse835(data []byte) (*Remittance, error) {
+	segments, d, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectTransaction(segments, "835"); err != nil {
+		return nil, err
+	}
+	env, err := ReadEnvelope(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Remittance{Envelope: env}
+	var claim *PaidClaim
+	var service *PaidService
+	for _, seg := range segments {
+		switch seg.ID() {
+		case "BPR":
+			if r.PaymentCents, err = parseAmount(seg.Element(2)); err != nil {
+				return nil, err
+			}
+			r.PaymentMethod = seg.Element(4)
+			r.PaymentDate = isoDate(seg.Element(16))
+		case "TRN":
+			r.TraceNumber, r.PayerID = seg.Element(2), seg.Element(3)
+		case "N1":
+			switch seg.Element(1) {
+			case "PR":
+				r.PayerName = seg.Element(2)
+			case "PE":
+				r.PayeeName = seg.Element(2)
+				if seg.Element(3) == "XX" {
+					r.PayeeNPI = seg.Element(4)
+				}
+			}
+		case "CLP":
+			r.Claims = append(r.Claims, PaidClaim{
+				Number:           seg.Element(1),
+				Status:           seg.Element(2),
+				PayerClaimNumber: seg.Element(7),
+			})
+			claim, service = &r.Claims[len(r.Claims)-1], nil
+			amounts := []*int64{&claim.ChargeCents, &claim.PaidCents, &claim.PatientResponsibilityCents}
+			for i, amount := range amounts {
+				if *amount, err = parseAmount(seg.Element(3 + i)); err != nil {
+					return nil, fmt.Errorf("claim %s: %w", claim.Number, err)
+				}
+			}
+		case "NM1":
+			if claim != nil && seg.Element(1) == "QC" {
+				claim.Patient = readPerson(seg)
+			}
+		case "SVC":
+			if claim == nil {
+				continue
+			}
+			procedure := strings.Split(seg.Element(1), string(d.Component))
+			paid := PaidService{}
+			if len(procedure) > 1 {
+				paid.Code, paid.Modifiers = procedure[1], procedure[2:]
+			}
+			if paid.ChargeCents, err = parseAmount(seg.Element(2)); err != nil {
+				return nil, fmt.Errorf("claim %s: %w", claim.Number, err)
+			}
+			if paid.PaidCents, err = parseAmount(seg.Element(3)); err != nil {
+				return nil, fmt.Errorf("claim %s: %w", claim.Number, err)
+			}
+			paid.Units, _ = strconv.Atoi(seg.Element(5))
+			claim.Services = append(claim.Services, paid)
+			service = &claim.Services[len(claim.Services)-1]
+		case "CAS":
+			if claim == nil {
+				continue
+			}
+			adjustments, err := readAdjustments(seg)
+			if err != nil {
+				return nil, fmt.Errorf("claim %s: %w", claim.Number, err)
+			}
+			if service != nil {
+				service.Adjustments = append(service.Adjustments, adjustments...)
+			} else {
+				claim.Adjustments = append(claim.Adjustments, adjustments...)
+			}
+		case "PLB", "SE":
+			// Provider-level adjustments follow the claims.
+			claim, service = nil, nil
+		}
+	}
+	if r.TraceNumber == "" {
+		return nil, fmt.Errorf("x12: 835 has no TRN trace number")
+	}
+	return r, nil
+}
+
+// readAdjustments reads a CAS segment: a group followed by up to six
+// reason, amount and quantity triples.
+func readAdjustments(seg Segment) ([]Adjustment, error) {
+	var adjustments []Adjustment
+	for i := 2; i+1 < len(seg) && i <= 17; i += 3 {
+		if seg[i] == "" {
+			continue
+		}
+		amount, err := parseAmount(seg[i+1])
+		if err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, Adjustment{Group: seg.Element(1), Reason: seg[i], AmountCents: amount})
+	}
+	return adjustments, nil
+}
+
+// Build835 writes a payment advice. It is used by test clearinghouses
+// standing in for a payer.
+func Build835(r Remittance) []byte {
+	handling, method := "I", r.PaymentMethod
+	if r.PaymentCents == 0 {
+		handling, method = "H", "NON"
+	}
+	body := []Segment{
+		{"BPR", handling, formatAmount(r.PaymentCents), "C", method, "", "", "", "", "", "", "", "", "", "", "", compactDate(r.PaymentDate)},
+		{"TRN", "1", Escape(r.TraceNumber), Escape(r.PayerID)},
+		{"N1", "PR", Escape(r.PayerName)},
+		{"N1", "PE", Escape(r.PayeeName), "XX", Escape(r.PayeeNPI)},
+		{"LX", "1"},
+	}
+	for _, claim := range r.Claims {
+		body = append(body, Segment{"CLP", Escape(claim.Number), claim.Status, formatAmount(claim.ChargeCents),
+			formatAmount(claim.PaidCents), formatAmount(claim.PatientResponsibilityCents), "CI", Escape(claim.PayerClaimNumber)})
+		body = append(body, adjustmentSegments(claim.Adjustments)...)
+		if claim.Patient.LastName != "" {
+			body = append(body, personName("QC", claim.Patient))
+		}
+		for _, service := range claim.Services {
+			procedure := append([]string{"HC", service.Code}, service.Modifiers...)
+			body = append(body, Segment{"SVC", composite(procedure...), formatAmount(service.ChargeCents),
+				formatAmount(service.PaidCents), "", strconv.Itoa(service.Units)})
+			body = append(body, adjustmentSegments(service.Adjustments)...)
+		}
+	}
+	return Encode(r.Envelope, "HP", "835", RemittanceVersion, body)
+}
+
+// adjustmentSegments writes one CAS segment per adjustment.
+func adjustmentSegments(adjustments []Adjustment) []Segment {
+	segments := make([]Segment, 0, len(adjustments))
+	for _, a := range adjustments {
+		segments = append(segments, Segment{"CAS", a.Group, a.Reason, formatAmount(a.AmountCents)})
+	}
+	return segments
+}
@@ -0,0 +1,144 @@
+// pkg/x12/remittance_test.go
+package x12
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRemittanceRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Remittance
+		want Remittance
+	}{
+		{
+			name: "paid claim with adjustments",
+			r: Remittance{
+				Envelope: testEnvelope, TraceNumber: "EFT12345", PayerID: "1512345678", PayerName: "ACME HEALTH",
+				PayeeName: "SMITH CLINIC", PayeeNPI: "1234567893", PaymentCents: 8000, PaymentMethod: "ACH",
+				PaymentDate: "2030-10-01",
+				Claims: []PaidClaim{{
+					Number: "CLM-1", Status: "1", ChargeCents: 15000, PaidCents: 8000, PatientResponsibilityCents: 2500,
+					PayerClaimNumber: "PCN-9",
+					Patient:          Person{FirstName: "JANE", LastName: "DOE", MemberID: "W123456789"},
+					Adjustments:      []Adjustment{{Group: AdjustmentPatient, Reason: "3", AmountCents: 2500}},
+					Services: []PaidService{
+						{Code: "99213", Modifiers: []string{"25"}, ChargeCents: 10000, PaidCents: 6000, Units: 1,
+							Adjustments: []Adjustment{{Group: AdjustmentContractual, Reason: "45", AmountCents: 1500}}},
+						{Code: "81002", Modifiers: []string{}, ChargeCents: 5000, PaidCents: 2000, Units: 2,
+							Adjustments: []Adjustment{{Group: AdjustmentContractual, Reason: "45", AmountCents: 1000}}},
+					},
+				}},
+			},
+		},
+		{
+			name: "denied claim pays nothing",
+			r: Remittance{
+				Envelope: testEnvelope, TraceNumber: "0", PayerID: "1512345678", PayerName: "ACME HEALTH",
+				PayeeName: "SMITH CLINIC", PaymentMethod: "ACH", PaymentDate: "2030-10-01",
+				Claims: []PaidClaim{{
+					Number: "CLM-2", Status: ClaimStatusDenied, ChargeCents: 12550,
+					Adjustments: []Adjustment{{Group: AdjustmentContractual, Reason: "29", AmountCents: 12550}},
+				}},
+			},
+		},
+		{
+			name: "several claims",
+			r: Remittance{
+				Envelope: testEnvelope, TraceNumber: "CHK100", PayerID: "1512345678", PayerName: "ACME HEALTH",
+				PayeeName: "SMITH CLINIC", PaymentCents: 3000, PaymentMethod: "CHK", PaymentDate: "2030-10-01",
+				Claims: []PaidClaim{
+					{Number: "CLM-3", Status: "1", ChargeCents: 2000, PaidCents: 2000},
+					{Number: "CLM-4", Status: "2", ChargeCents: 1500, PaidCents: 1000,
+						Adjustments: []Adjustment{{Group: AdjustmentOther, Reason: "23", AmountCents: 500}}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse835(Build835(tt.r))
+			if err != nil {
+				t.Fatalf("Parse835: %v", err)
+			}
+			want := tt.r
+			if want.PaymentCents == 0 {
+				// Nothing paid is written as method NON.
+				want.PaymentMethod = "NON"
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("Parse835(Build835()) =\n%+v\nwant\n%+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestParse835Malformed(t *testing.T) {
+	trn := Segment{"TRN", "1", "EFT1", "1512345678"}
+	remittance := Build835(Remittance{Envelope: testEnvelope, TraceNumber: "EFT1", PaymentCents: 100, PaymentMethod: "ACH"})
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "empty", wantErr: ErrNotInterchange},
+		{name: "truncated ISA", data: remittance[:50], wantErr: ErrNotInterchange},
+		{name: "truncated", data: remittance[:len(remittance)/2]},
+		{name: "not an 835", data: Build270(EligibilityRequest{Envelope: testEnvelope})},
+		{name: "no trace number", data: Encode(testEnvelope, "HP", "835", RemittanceVersion, []Segment{
+			{"BPR", "I", "100", "C", "ACH"},
+		})},
+		{name: "invalid payment amount", data: Encode(testEnvelope, "HP", "835", RemittanceVersion, []Segment{
+			{"BPR", "I", "ONE", "C", "ACH"}, trn,
+		})},
+		{name: "invalid claim amount", data: Encode(testEnvelope, "HP", "835", RemittanceVersion, []Segment{
+			{"BPR", "I", "100", "C", "ACH"}, trn, {"CLP", "CLM-1", "1", "150", "1,00"},
+		})},
+		{name: "invalid service amount", data: Encode(testEnvelope, "HP", "835", RemittanceVersion, []Segment{
+			{"BPR", "I", "100", "C", "ACH"}, trn, {"CLP", "CLM-1", "1", "150", "100"}, {"SVC", "HC:99213", "150", "x"},
+		})},
+		{name: "invalid adjustment amount", data: Encode(testEnvelope, "HP", "835", RemittanceVersion, []Segment{
+			{"BPR", "I", "100", "C", "ACH"}, trn, {"CLP", "CLM-1", "1", "150", "100"}, {"CAS", "CO", "45", "fifty"},
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse835(tt.data)
+			if err == nil {
+				t.Fatal("Parse835 succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse835IgnoresSegmentsOutsideClaims(t *testing.T) {
+	tests := []struct {
+		name string
+		body []Segment
+	}{
+		{name: "service before any claim", body: []Segment{{"SVC", "HC:99213", "100", "80"}}},
+		{name: "adjustment before any claim", body: []Segment{{"CAS", "CO", "45", "20"}}},
+		{name: "provider adjustment after the claims", body: []Segment{
+			{"CLP", "CLM-1", "1", "100", "80"}, {"PLB", "1234567893", "20301231", "WO:1", "5"}, {"CAS", "CO", "45", "20"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := append([]Segment{{"BPR", "I", "80", "C", "ACH"}, {"TRN", "1", "EFT1", "1512345678"}}, tt.body...)
+			got, err := Parse835(Encode(testEnvelope, "HP", "835", RemittanceVersion, body))
+			if err != nil {
+				t.Fatalf("Parse835: %v", err)
+			}
+			for _, claim := range got.Claims {
+				if len(claim.AllAdjustments()) > 0 || len(claim.Services) > 0 {
+					t.Errorf("claim %s = %+v, want no services or adjustments", claim.Number, claim)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package x12 reads and writes ASC X12 5010 interchanges, the EDI format
+// used for insurance eligibility (270/271), professional claims (837P)
+// and payment advice (835). It handles the envelope and segment syntax;
+// the transaction files build and read specific sets.
+package x12
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is one segment: its ID followed by its elements, so Segment[1]
+// is the first element as numbered in the implementation guides.
+type Segment []string
+
+// ID returns the segment identifier, e.g. "NM1".
+func (s Segment) ID() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
+
+// Element returns element i (1-based), or "" when the segment is shorter.
+func (s Segment) Element(i int) string {
+	if i < len(s) {
+		return s[i]
+	}
+	return ""
+}
+
+// Delimiters are the separators of an interchange, declared in its ISA.
+type Delimiters struct {
+	Element    byte
+	Component  byte
+	Repetition byte
+	Segment    byte
+}
+
+// DefaultDelimiters are used for interchanges written by this package.
+var DefaultDelimiters = Delimiters{Element: '*', Component: ':', Repetition: '^', Segment: '~'}
+
+// isaLength is the length of the fixed-width ISA segment, terminator included.
+const isaLength = 106
+
+// Envelope identifies the parties and control number of an interchange.
+type Envelope struct {
+	SenderID   string
+	ReceiverID string
+	// ControlNumber numbers the interchange, group and transaction set; it
+	// must be unique per sender.
+	ControlNumber int
+	// Production selects "P" in ISA15; otherwise the interchange is a test.
+	Production bool
+	Time       time.Time
+}
+
+// ErrNotInterchange is returned for data that doesn't start with an ISA segment.
+var ErrNotInterchange = errors.New("x12: data is not an X12 interchange")
+
+// Parse splits an interchange into segments, reading the delimiters from
+// its ISA segment. Line breaks between segments are ignored.
+func Parse(data []byte) ([]Segment, Delimiters, error) {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) < isaLength || !bytes.HasPrefix(data, []byte("ISA")) {
+		return nil, Delimiters{}, ErrNotInterchange
+	}
+	d := Delimiters{Element: data[3], Component: data[104], Segment: data[105]}
+	if isa := strings.Split(string(data[:isaLength-1]), string(d.Element)); len(isa) == 17 && len(isa[11]) == 1 {
+		d.Repetition = isa[11][0]
+	}
+
+	var segments []Segment
+	for _, raw := range strings.Split(string(data), string(d.Segment)) {
+		raw = strings.Trim(raw, " \t\r\n")
+		if raw == "" {
+			continue
+		}
+		segments = append(segments, strings.Split(raw, string(d.Element)))
+	}
+	if len(segments) < 2 || segments[len(segments)-1].ID() != "IEA" {
+		return nil, d, errors.New("x12: interchange has no IEA trailer")
+	}
+	return segments, d, nil
+}
+
+// Encode writes a single transaction set wrapped in an interchange and a
+// functional group. body holds the segments between ST and SE, exclusive.
+func Encode(env Envelope, functionalID, transactionSet, version string, body []Segment) []byte {
+	d := DefaultDelimiters
+	control := fmt.Sprintf("%09d", env.ControlNumber%1000000000)
+	usage := "T"
+	if env.Production {
+		usage = "P"
+	}
+	t := env.Time.UTC()
+
+	segments := []Segment{
+		{"ISA", "00", pad("", 10), "00", pad("", 10),
+			"ZZ", pad(env.SenderID, 15), "ZZ", pad(env.ReceiverID, 15),
+			t.Format("060102"), t.Format("1504"), string(d.Repetition), "00501", control, "0", usage, string(d.Component)},
+		{"GS", functionalID, env.SenderID, env.ReceiverID, t.Format("20060102"), t.Format("1504"),
+			fmt.Sprint(env.ControlNumber), "X", version},
+		{"ST", transactionSet, "0001", version},
+	}
+	segments = append(segments, body...)
+	segments = append(segments,
+		Segment{"SE", fmt.Sprint(len(body) + 2), "0001"},
+		Segment{"GE", "1", fmt.Sprint(env.ControlNumber)},
+		Segment{"IEA", "1", control},
+	)
+
+	var buf bytes.Buffer
+	for _, seg := range segments {
+		buf.WriteString(strings.Join(trimEmpty(seg), string(d.Element)))
+		buf.WriteByte(d.Segment)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// ReadEnvelope returns the envelope of parsed segments.
+func ReadEnvelope(segments []Segment) (Envelope, error) {
+	if len(segments) == 0 || segments[0].ID() != "ISA" || len(segments[0]) < 17 {
+		return Envelope{}, ErrNotInterchange
+	}
+	isa := segments[0]
+	env := Envelope{
+		SenderID:   strings.TrimSpace(isa[6]),
+		ReceiverID: strings.TrimSpace(isa[8]),
+		Production: isa[15] == "P",
+	}
+	if n, err := strconv.Atoi(isa[13]); err == nil {
+		env.ControlNumber = n
+	}
+	if t, err := time.Parse("0601021504", isa[9]+isa[10]); err == nil {
+		env.Time = t
+	}
+	return env, nil
+}
+
+// Escape removes delimiter characters from a value so it can't break the
+// segment structure.
+func Escape(value string) string {
+	d := DefaultDelimiters
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case rune(d.Element), rune(d.Component), rune(d.Repetition), rune(d.Segment), '\n', '\r':
+			return ' '
+		}
+		return r
+	}, strings.TrimSpace(value))
+}
+
+// pad left-aligns s in a fixed-width ISA element.
+func pad(s string, width int) string {
+	if len(s) > width {
+		return s[:width]
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// trimEmpty drops trailing empty elements, which X12 forbids.
+func trimEmpty(seg Segment) Segment {
+	end := len(seg)
+	for end > 1 && seg[end-1] == "" {
+		end--
+	}
+	return seg[:end]
+}
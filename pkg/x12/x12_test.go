@@ -0,0 +1,229 @@
+// pkg/x12/x12_test.go
+package x12
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEnvelope = Envelope{
+	SenderID:      "DOCTORSAAS",
+	ReceiverID:    "CLEARINGHOUSE",
+	ControlNumber: 42,
+	Time:          time.Date(2030, 9, 15, 14, 30, 0, 0, time.UTC),
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		env  Envelope
+		body []Segment
+	}{
+		{
+			name: "test interchange",
+			env:  testEnvelope,
+			body: []Segment{{"BHT", "0022", "13", "TRACE1"}, {"EQ", "30"}},
+		},
+		{
+			name: "production interchange",
+			env: Envelope{SenderID: "S", ReceiverID: "R", ControlNumber: 1234567890, Production: true,
+				Time: time.Date(2031, 1, 2, 3, 4, 0, 0, time.UTC)},
+			body: []Segment{{"BHT", "0022", "13", "TRACE2"}},
+		},
+		{
+			name: "empty body",
+			env:  testEnvelope,
+		},
+		{
+			name: "trailing empty elements are dropped",
+			env:  testEnvelope,
+			body: []Segment{{"HL", "1", "", "20", "1", "", ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Encode(tt.env, "HS", "270", EligibilityVersion, tt.body)
+			segments, d, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if d != DefaultDelimiters {
+				t.Errorf("delimiters = %+v, want %+v", d, DefaultDelimiters)
+			}
+
+			ids := make([]string, len(segments))
+			for i, seg := range segments {
+				ids[i] = seg.ID()
+			}
+			wantIDs := []string{"ISA", "GS", "ST"}
+			for _, seg := range tt.body {
+				wantIDs = append(wantIDs, seg.ID())
+			}
+			wantIDs = append(wantIDs, "SE", "GE", "IEA")
+			if !reflect.DeepEqual(ids, wantIDs) {
+				t.Fatalf("segments = %v, want %v", ids, wantIDs)
+			}
+			for i, seg := range tt.body {
+				if got, want := segments[3+i], trimEmpty(seg); !reflect.DeepEqual(got, want) {
+					t.Errorf("segment %d = %q, want %q", i, got, want)
+				}
+			}
+			if se := segments[len(segments)-3]; se.Element(1) != strconv.Itoa(len(tt.body)+2) {
+				t.Errorf("SE01 = %q, want %d", se.Element(1), len(tt.body)+2)
+			}
+
+			env, err := ReadEnvelope(segments)
+			if err != nil {
+				t.Fatalf("ReadEnvelope: %v", err)
+			}
+			want := tt.env
+			want.ControlNumber %= 1000000000
+			if env != want {
+				t.Errorf("envelope = %+v, want %+v", env, want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	valid := string(Encode(testEnvelope, "HS", "270", EligibilityVersion, []Segment{{"EQ", "30"}}))
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "empty", data: "", wantErr: ErrNotInterchange},
+		{name: "not X12", data: strings.Repeat("MSH|^~\\&|", 20), wantErr: ErrNotInterchange},
+		{name: "truncated ISA", data: valid[:60], wantErr: ErrNotInterchange},
+		{name: "no ISA", data: valid[strings.Index(valid, "GS*"):], wantErr: ErrNotInterchange},
+		{name: "truncated before IEA", data: valid[:strings.Index(valid, "IEA*")]},
+		{name: "ISA only", data: valid[:isaLength]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse([]byte(tt.data))
+			if err == nil {
+				t.Fatal("Parse succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseToleratesWhitespace(t *testing.T) {
+	data := Encode(testEnvelope, "HS", "270", EligibilityVersion, []Segment{{"EQ", "30"}})
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "as written", data: string(data)},
+		{name: "leading blank lines", data: "\r\n\n  " + string(data)},
+		{name: "no line breaks", data: strings.ReplaceAll(string(data), "\n", "")},
+		{name: "CRLF line breaks", data: strings.ReplaceAll(string(data), "\n", "\r\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, _, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(segments) != 7 {
+				t.Errorf("got %d segments, want 7", len(segments))
+			}
+		})
+	}
+}
+
+func TestReadEnvelopeMalformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []Segment
+	}{
+		{name: "no segments"},
+		{name: "first segment not ISA", segments: []Segment{{"GS", "HS"}, {"IEA", "1", "1"}}},
+		{name: "short ISA", segments: []Segment{{"ISA", "00", "", "00"}, {"IEA", "1", "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadEnvelope(tt.segments); !errors.Is(err, ErrNotInterchange) {
+				t.Errorf("error = %v, want %v", err, ErrNotInterchange)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SMITH", "SMITH"},
+		{"  SMITH ", "SMITH"},
+		{"A*B", "A B"},
+		{"A~B", "A B"},
+		{"A:B^C", "A B C"},
+		{"LINE1\r\nLINE2", "LINE1  LINE2"},
+	}
+	for _, tt := range tests {
+		if got := Escape(tt.in); got != tt.want {
+			t.Errorf("Escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAmounts(t *testing.T) {
+	tests := []struct {
+		cents int64
+		text  string
+	}{
+		{0, "0"},
+		{5, "0.05"},
+		{50, "0.5"},
+		{100, "1"},
+		{12500, "125"},
+		{12550, "125.5"},
+		{12555, "125.55"},
+		{100000, "1000"},
+		{-2550, "-25.5"},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.cents); got != tt.text {
+			t.Errorf("formatAmount(%d) = %q, want %q", tt.cents, got, tt.text)
+		}
+		got, err := parseAmount(tt.text)
+		if err != nil || got != tt.cents {
+			t.Errorf("parseAmount(%q) = %d, %v, want %d", tt.text, got, err, tt.cents)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		text    string
+		cents   int64
+		wantErr bool
+	}{
+		{text: "", cents: 0},
+		{text: "125.00", cents: 12500},
+		{text: "0.1", cents: 10},
+		{text: "19.99", cents: 1999},
+		{text: "abc", wantErr: true},
+		{text: "12.5.0", wantErr: true},
+		{text: "$12", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAmount(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if got != tt.cents {
+			t.Errorf("parseAmount(%q) = %d, want %d", tt.text, got, tt.cents)
+		}
+	}
+}
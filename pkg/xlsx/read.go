@@ -0,0 +1,351 @@
+// Package xlsx reads and writes the first worksheet of Office Open XML
+// spreadsheets as rows of strings, enough to exchange tables with
+// spreadsheet programs. Formulas, formatting and other sheets are not
+// supported; cells formatted as dates read as "2006-01-02" or
+// "2006-01-02T15:04:05".
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned for data that isn't an XLSX workbook.
+var ErrInvalid = errors.New("not a valid XLSX file")
+
+// Limits on what is read, so a small compressed file can't expand into an
+// unbounded table.
+const (
+	MaxRows     = 1 << 20
+	MaxCells    = 5_000_000
+	MaxPartSize = 256 << 20
+)
+
+// Read returns the rows of the first worksheet. Rows are as long as their
+// last non-empty cell; empty rows in between are kept as empty rows.
+func Read(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	strs, err := sharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+	dates, err := dateStyles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := openPart(files, sheetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readSheet(r, strs, dates)
+}
+
+type relationships struct {
+	Relationship []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	}
+}
+
+// firstSheet finds the part of the first sheet listed in the workbook.
+func firstSheet(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: the workbook has no sheets", ErrInvalid)
+	}
+
+	var rels relationships
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationship {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("%w: the first sheet has no part", ErrInvalid)
+}
+
+// richText is a string item: plain text or runs of formatted text.
+type richText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	b.WriteString(t.T)
+	for _, run := range t.R {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+func sharedStrings(files map[string]*zip.File) ([]string, error) {
+	if _, ok := files["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+	var sst struct {
+		SI []richText `xml:"si"`
+	}
+	if err := decodePart(files, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(sst.SI))
+	for i, si := range sst.SI {
+		strs[i] = si.String()
+	}
+	return strs, nil
+}
+
+// builtinDateFormats are the built-in number formats that show dates.
+var builtinDateFormats = map[int]bool{14: true, 15: true, 16: true, 17: true, 18: true, 19: true,
+	20: true, 21: true, 22: true, 45: true, 46: true, 47: true}
+
+// dateStyles reports, for each cell style index, whether it shows a date.
+func dateStyles(files map[string]*zip.File) ([]bool, error) {
+	if _, ok := files["xl/styles.xml"]; !ok {
+		return nil, nil
+	}
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodePart(files, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+
+	custom := map[int]bool{}
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = isDateFormat(f.Code)
+	}
+	dates := make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		dates[i] = builtinDateFormats[xf.NumFmtID] || custom[xf.NumFmtID]
+	}
+	return dates, nil
+}
+
+// isDateFormat reports whether a number format code shows a date: it has
+// day, month or year placeholders outside quoted text and brackets.
+func isDateFormat(code string) bool {
+	quoted, bracketed := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			bracketed = true
+		case r == ']':
+			bracketed = false
+		case bracketed:
+		case r == 'd' || r == 'm' || r == 'y':
+			return true
+		}
+	}
+	return false
+}
+
+type cell struct {
+	Ref    string    `xml:"r,attr"`
+	Type   string    `xml:"t,attr"`
+	Style  int       `xml:"s,attr"`
+	Value  string    `xml:"v"`
+	Inline *richText `xml:"is"`
+}
+
+func readSheet(r io.Reader, strs []string, dates []bool) ([][]string, error) {
+	decoder := xml.NewDecoder(r)
+	var rows [][]string
+	cells := 0
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row struct {
+			Number int    `xml:"r,attr"`
+			Cells  []cell `xml:"c"`
+		}
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if row.Number == 0 {
+			row.Number = len(rows) + 1
+		}
+		if row.Number > MaxRows {
+			return nil, fmt.Errorf("%w: row %d is past the last row of a sheet", ErrInvalid, row.Number)
+		}
+		for len(rows) < row.Number-1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if col, err = columnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			value, err := cellValue(c, strs, dates)
+			if err != nil {
+				return nil, err
+			}
+			if value == "" {
+				continue
+			}
+			if cells += col + 1 - len(values); cells > MaxCells {
+				return nil, fmt.Errorf("%w: more than %d cells", ErrInvalid, MaxCells)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = value
+		}
+		rows = append(rows, values)
+	}
+
+	// Trailing empty rows are formatting leftovers.
+	for len(rows) > 0 && len(rows[len(rows)-1]) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+func cellValue(c cell, strs []string, dates []bool) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(strs) {
+			return "", fmt.Errorf("%w: cell %s refers to a missing string", ErrInvalid, c.Ref)
+		}
+		return strs[i], nil
+	case "inlineStr":
+		if c.Inline == nil {
+			return "", nil
+		}
+		return c.Inline.String(), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "n", "":
+		if c.Value != "" && c.Style >= 0 && c.Style < len(dates) && dates[c.Style] {
+			if serial, err := strconv.ParseFloat(c.Value, 64); err == nil {
+				return formatSerial(serial), nil
+			}
+		}
+	}
+	return c.Value, nil
+}
+
+// excelEpoch is day 0 of the 1900 date system, as spreadsheets count it
+// (including the phantom 29 February 1900).
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func formatSerial(serial float64) string {
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
+
+// columnIndex returns the zero-based column of a cell reference such as "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for i, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			col = col*26 + int(r-'A'+1)
+			continue
+		}
+		if i == 0 || col > 16384 {
+			break
+		}
+		return col - 1, nil
+	}
+	return 0, fmt.Errorf("%w: bad cell reference %q", ErrInvalid, ref)
+}
+
+// openPart opens a part of the package. The zip reader fails parts that
+// are longer than their header says, so checking the header is enough.
+func openPart(files map[string]*zip.File, name string) (io.ReadCloser, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalid, name)
+	}
+	if f.UncompressedSize64 > MaxPartSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrInvalid, name)
+	}
+	return f.Open()
+}
+
+func decodePart(files map[string]*zip.File, name string, v interface{}) error {
+	r, err := openPart(files, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := xml.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+	}
+	return nil
+}
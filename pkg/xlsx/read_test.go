@@ -0,0 +1,314 @@
+// pkg/xlsx/read_test.go
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testWorkbook = `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Patients" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	testWorkbookRels = `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`
+	testStyles       = `<styleSheet><numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/>` +
+		`<numFmt numFmtId="165" formatCode="&quot;day&quot; 0.00"/></numFmts>` +
+		`<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/><xf numFmtId="22"/></cellXfs></styleSheet>`
+)
+
+// testBook zips a workbook whose first sheet holds rows, adding or
+// replacing parts with extra.
+func testBook(t *testing.T, rows string, extra map[string]string) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testWorkbookRels,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData>` + rows + `</sheetData></worksheet>`,
+	}
+	for name, body := range extra {
+		parts[name] = body
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		if body == "" {
+			continue
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	withStrings := map[string]string{
+		"xl/sharedStrings.xml": `<sst><si><t>Name</t></si><si><r><t>Date of </t></r><r><t>birth</t></r></si><si><t>Jane</t></si></sst>`,
+	}
+	withStyles := map[string]string{"xl/styles.xml": testStyles}
+	tests := []struct {
+		name  string
+		rows  string
+		extra map[string]string
+		want  [][]string
+	}{
+		{
+			name:  "shared and rich text strings",
+			rows:  `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row><row r="2"><c r="A2" t="s"><v>2</v></c></row>`,
+			extra: withStrings,
+			want:  [][]string{{"Name", "Date of birth"}, {"Jane"}},
+		},
+		{
+			name: "inline strings, numbers and booleans",
+			rows: `<row r="1"><c r="A1" t="inlineStr"><is><t>MRN</t></is></c><c r="B1"><v>42.5</v></c>` +
+				`<c r="C1" t="b"><v>1</v></c><c r="D1" t="b"><v>0</v></c><c r="E1" t="inlineStr"/></row>`,
+			want: [][]string{{"MRN", "42.5", "TRUE", "FALSE"}},
+		},
+		{
+			name: "sparse cells and rows",
+			rows: `<row r="2"><c r="C2" t="inlineStr"><is><t>c</t></is></c></row>` +
+				`<row r="4"><c r="AA4" t="inlineStr"><is><t>aa</t></is></c></row>` +
+				`<row r="9"/>`,
+			want: [][]string{nil, {"", "", "c"}, nil, append(make([]string, 26), "aa")},
+		},
+		{
+			name: "rows and cells without references",
+			rows: `<row><c t="inlineStr"><is><t>a</t></is></c><c t="inlineStr"><is><t>b</t></is></c></row>` +
+				`<row><c t="inlineStr"><is><t>c</t></is></c></row>`,
+			want: [][]string{{"a", "b"}, {"c"}},
+		},
+		{
+			name: "dates by style",
+			rows: `<row r="1"><c r="A1" s="1"><v>29395</v></c><c r="B1" s="2"><v>29395</v></c>` +
+				`<c r="C1" s="3"><v>29395</v></c><c r="D1" s="4"><v>29395.5625</v></c>` +
+				`<c r="E1" s="0"><v>29395</v></c><c r="F1" s="9"><v>29395</v></c></row>`,
+			extra: withStyles,
+			want:  [][]string{{"1980-06-23", "1980-06-23", "29395", "1980-06-23T13:30:00", "29395", "29395"}},
+		},
+		{
+			name: "empty sheet",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(testBook(t, tt.rows, tt.extra))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	// Each row's cell in the last column counts as a whole row of cells.
+	lastColumn := func(rows int) string {
+		var b strings.Builder
+		for i := 1; i <= rows; i++ {
+			fmt.Fprintf(&b, `<row r="%d"><c r="XFD%d" t="inlineStr"><is><t>x</t></is></c></row>`, i, i)
+		}
+		return b.String()
+	}
+	rowsPerLimit := MaxCells / 16384
+
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		wantErr bool
+	}{
+		{
+			name: "last row of a sheet",
+			data: func(t *testing.T) []byte {
+				return testBook(t, fmt.Sprintf(`<row r="%d"><c r="A%d"><v>1</v></c></row>`, MaxRows, MaxRows), nil)
+			},
+		},
+		{
+			name: "row past the last row",
+			data: func(t *testing.T) []byte {
+				return testBook(t, fmt.Sprintf(`<row r="%d"><c r="A%d"><v>1</v></c></row>`, MaxRows+1, MaxRows+1), nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "last column",
+			data:    func(t *testing.T) []byte { return testBook(t, lastColumn(1), nil) },
+			wantErr: false,
+		},
+		{
+			name:    "column past the last column",
+			data:    func(t *testing.T) []byte { return testBook(t, `<row r="1"><c r="XFE1"><v>1</v></c></row>`, nil) },
+			wantErr: true,
+		},
+		{
+			name:    "cells up to the limit",
+			data:    func(t *testing.T) []byte { return testBook(t, lastColumn(rowsPerLimit), nil) },
+			wantErr: false,
+		},
+		{
+			name:    "cells past the limit",
+			data:    func(t *testing.T) []byte { return testBook(t, lastColumn(rowsPerLimit+1), nil) },
+			wantErr: true,
+		},
+		{
+			name: "part larger than the limit",
+			data: func(t *testing.T) []byte {
+				return rawBook(t, "xl/worksheets/sheet1.xml", []byte(`<worksheet><sheetData/></worksheet>`), MaxPartSize+1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "part larger than its header says",
+			data: func(t *testing.T) []byte {
+				sheet := `<worksheet><sheetData>` + strings.Repeat(`<row><c><v>1</v></c></row>`, 1000) + `</sheetData></worksheet>`
+				return rawBook(t, "xl/worksheets/sheet1.xml", []byte(sheet), 100)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(tt.data(t))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalid) {
+				t.Errorf("error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+// rawBook is a workbook whose part name holds body, deflated, with its
+// uncompressed size declared as size whatever the body's length.
+func rawBook(t *testing.T, name string, body []byte, size uint64) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for part, content := range map[string]string{"xl/workbook.xml": testWorkbook, "xl/_rels/workbook.xml.rels": testWorkbookRels} {
+		f, err := zw.Create(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(body),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: size,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(compressed.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data func(t *testing.T) []byte
+	}{
+		{name: "empty", data: func(t *testing.T) []byte { return nil }},
+		{name: "not a zip", data: func(t *testing.T) []byte { return []byte("name,date_of_birth\nJane,1980-04-02\n") }},
+		{name: "truncated zip", data: func(t *testing.T) []byte {
+			book := testBook(t, `<row r="1"><c r="A1"><v>1</v></c></row>`, nil)
+			return book[:len(book)/2]
+		}},
+		{name: "no workbook", data: func(t *testing.T) []byte {
+			return testBook(t, "", map[string]string{"xl/workbook.xml": ""})
+		}},
+		{name: "no sheets", data: func(t *testing.T) []byte {
+			return testBook(t, "", map[string]string{"xl/workbook.xml": `<workbook><sheets/></workbook>`})
+		}},
+		{name: "no relationship for the sheet", data: func(t *testing.T) []byte {
+			return testBook(t, "", map[string]string{"xl/_rels/workbook.xml.rels": `<Relationships/>`})
+		}},
+		{name: "missing sheet part", data: func(t *testing.T) []byte {
+			return testBook(t, "", map[string]string{"xl/worksheets/sheet1.xml": ""})
+		}},
+		{name: "truncated sheet XML", data: func(t *testing.T) []byte {
+			return testBook(t, "", map[string]string{"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1">`})
+		}},
+		{name: "bad workbook XML", data: func(t *testing.T) []byte {
+			return testBook(t, "", map[string]string{"xl/workbook.xml": `<workbook><sheets>`})
+		}},
+		{name: "missing shared string", data: func(t *testing.T) []byte {
+			return testBook(t, `<row r="1"><c r="A1" t="s"><v>3</v></c></row>`,
+				map[string]string{"xl/sharedStrings.xml": `<sst><si><t>only</t></si></sst>`})
+		}},
+		{name: "shared string without a table", data: func(t *testing.T) []byte {
+			return testBook(t, `<row r="1"><c r="A1" t="s"><v>0</v></c></row>`, nil)
+		}},
+		{name: "bad cell reference", data: func(t *testing.T) []byte {
+			return testBook(t, `<row r="1"><c r="12"><v>1</v></c></row>`, nil)
+		}},
+		{name: "cell reference without a row", data: func(t *testing.T) []byte {
+			return testBook(t, `<row r="1"><c r="AB"><v>1</v></c></row>`, nil)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(tt.data(t)); !errors.Is(err, ErrInvalid) {
+				t.Errorf("error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{code: "yyyy-mm-dd", want: true},
+		{code: "d/m/yy h:mm", want: true},
+		{code: "[$-409]mmmm d, yyyy", want: true},
+		{code: "0.00", want: false},
+		{code: "#,##0", want: false},
+		{code: `"days" 0`, want: false},
+		{code: "[Red]0.00", want: false},
+		{code: "[h]:ss", want: false},
+	}
+	for _, tt := range tests {
+		if got := isDateFormat(tt.code); got != tt.want {
+			t.Errorf("isDateFormat(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// pkg/xlsx/write_test.go
+package xlsx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		want [][]string
+	}{
+		{
+			name: "text keeps leading zeros",
+			rows: [][]string{{"mrn", "phone"}, {"000123", "+14155552671"}},
+			want: [][]string{{"mrn", "phone"}, {"000123", "+14155552671"}},
+		},
+		{
+			name: "markup and whitespace",
+			rows: [][]string{{`<b>"O'Brien" & co</b>`, "  padded  ", "line 1\nline 2", "tab\there"}},
+			want: [][]string{{`<b>"O'Brien" & co</b>`, "  padded  ", "line 1\nline 2", "tab\there"}},
+		},
+		{
+			name: "control characters are dropped",
+			rows: [][]string{{"a\x00b\x1bc"}},
+			want: [][]string{{"abc"}},
+		},
+		{
+			name: "empty cells and rows",
+			rows: [][]string{{"a", "", "c", ""}, {}, {"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "ab"}, {}},
+			want: [][]string{{"a", "", "c"}, nil, {"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "ab"}},
+		},
+		{
+			name: "no rows",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, "Patients & <more>")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range tt.rows {
+				if err := w.WriteRow(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Read(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		col  int
+		name string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{701, "ZZ"},
+		{702, "AAA"},
+		{16383, "XFD"},
+	}
+	for _, tt := range tests {
+		if got := columnName(tt.col); got != tt.name {
+			t.Errorf("columnName(%d) = %q, want %q", tt.col, got, tt.name)
+		}
+		if got, err := columnIndex(tt.name + "1"); err != nil || got != tt.col {
+			t.Errorf("columnIndex(%q) = %d, %v, want %d", tt.name+"1", got, err, tt.col)
+		}
+	}
+}